@@ -6,37 +6,49 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"expense-split-tracker/internal/config"
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/fx"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/metrics"
 	"expense-split-tracker/internal/middleware"
+	"expense-split-tracker/internal/notification"
+	"expense-split-tracker/internal/outbox"
 	"expense-split-tracker/internal/repository"
 	"expense-split-tracker/internal/routes"
 	"expense-split-tracker/internal/service"
+	"expense-split-tracker/internal/webhook"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 )
 
+// @title Expense Split Tracker API
+// @version 1.0.0
+// @description Split expenses within groups, track who owes whom and settle up.
+// @description Group-scoped endpoints identify the caller with the X-User-UUID header.
+// @BasePath /
 func main() {
+	// Load configuration; the logger depends on it, so failures here panic
+	cfg, err := config.Load()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load configuration: %v", err))
+	}
+
 	// Initialize logger
-	logger, err := initLogger()
+	logger, err := logging.New(cfg.Logging.Level, cfg.Server.Env)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
 	}
 	defer logger.Sync()
 
 	logger.Info("Starting Expense Split Tracker server")
-
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		logger.Fatal("Failed to load configuration", zap.Error(err))
-	}
-
 	logger.Info("Configuration loaded successfully",
 		zap.String("env", cfg.Server.Env),
 		zap.String("db_host", cfg.Database.Host),
@@ -50,49 +62,178 @@ func main() {
 	}
 	defer db.Close()
 
+	// "server migrate ..." manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), db, os.Args[2:]); err != nil {
+			logger.Fatal("Migration failed", zap.Error(err))
+		}
+		return
+	}
+
+	if cfg.Database.AutoMigrate {
+		count, err := db.Migrate(context.Background())
+		if err != nil {
+			logger.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+		logger.Info("Migrations applied", zap.Int("count", count))
+	}
+
 	// Initialize repositories
 	repos := &repository.Repositories{
-		User:        repository.NewUserRepository(db, logger),
-		Group:       repository.NewGroupRepository(db, logger),
-		Expense:     repository.NewExpenseRepository(db, logger),
-		Settlement:  repository.NewSettlementRepository(db, logger),
-		Balance:     repository.NewBalanceRepository(db, logger),
-		Idempotency: repository.NewIdempotencyRepository(db, logger),
+		User:           repository.NewUserRepository(db, logger),
+		Group:          repository.NewGroupRepository(db, logger),
+		Expense:        repository.NewExpenseRepository(db, logger),
+		Settlement:     repository.NewSettlementRepository(db, logger),
+		Balance:        repository.NewBalanceRepository(db, logger),
+		BalanceHistory: repository.NewBalanceHistoryRepository(db, logger),
+		Insights:       repository.NewInsightsRepository(db, logger),
+		Report:         repository.NewReportRepository(db, logger),
+		GroupLock:      repository.NewGroupLockRepository(db, logger),
+		Idempotency:    repository.NewIdempotencyRepository(db, logger),
+		Recurring:      repository.NewRecurringExpenseRepository(db, logger),
+		Webhook:        repository.NewWebhookRepository(db, logger),
+		Outbox:         repository.NewOutboxRepository(db, logger),
 	}
 
+	// Domain events; activity and audit writers subscribe here. Services also
+	// write every event to the outbox inside their transaction.
+	eventDispatcher := events.NewDispatcher(logger)
+	eventDispatcher.UseRecorder(outbox.NewWriter(repos.Outbox))
+
+	// Webhook deliveries are queued from published outbox events and sent in the background
+	webhookDispatcher := webhook.NewDispatcher(repos.Webhook, webhook.Options{
+		MaxAttempts: cfg.Features.WebhookMaxAttempts,
+		Backoff:     cfg.Features.WebhookRetryBackoff,
+		Timeout:     cfg.Features.WebhookTimeout,
+		Workers:     4,
+		QueueSize:   1000,
+	}, logger)
+
+	// Emails to users who owe money; without SMTP settings nothing is sent
+	var notifier notification.Notifier = notification.NopNotifier{}
+	if cfg.Notification.Enabled {
+		notifier = notification.NewSMTPNotifier(notification.SMTPConfig{
+			Host:     cfg.Notification.SMTPHost,
+			Port:     cfg.Notification.SMTPPort,
+			From:     cfg.Notification.From,
+			Username: cfg.Notification.SMTPUsername,
+			Password: cfg.Notification.SMTPPassword,
+		})
+	}
+	notificationDispatcher := notification.NewDispatcher(notifier, repos.User, repos.Group, notification.Options{
+		Workers:   2,
+		QueueSize: 1000,
+	}, logger)
+
+	// The relay publishes committed outbox events in order to its consumers
+	outboxRelay := outbox.NewRelay(repos.Outbox, outbox.Options{BatchSize: 100, MaxAttempts: 20}, logger)
+	outboxRelay.Subscribe("log", outbox.LoggingConsumer(logger))
+	outboxRelay.Subscribe("webhooks", webhookDispatcher.Consume)
+	outboxRelay.Subscribe("notifications", notificationDispatcher.Consume)
+
+	// Exchange rates for cross-currency expenses and converted balance views
+	var rates fx.ExchangeRateProvider = fx.NewStaticProvider(cfg.FX.BaseCurrency, cfg.FX.Rates)
+	if cfg.FX.Provider == "http" {
+		rates = fx.NewHTTPProvider(cfg.FX.URL, cfg.FX.Timeout)
+	}
+
+	// Metrics served at /metrics; the DB pool is sampled on each scrape
+	metricsRegistry := metrics.NewRegistry(db)
+
 	// Initialize services
+	groupLocks := service.NewGroupLockService(repos.GroupLock, db, cfg.Features.GroupLockTTL, logger)
 	services := &service.Services{
 		User:       service.NewUserService(repos.User, db, logger),
-		Group:      service.NewGroupService(repos.Group, repos.User, db, logger),
-		Expense:    service.NewExpenseService(repos.Expense, repos.Group, repos.User, repos.Balance, db, logger),
-		Settlement: service.NewSettlementService(repos.Settlement, repos.Group, repos.User, repos.Balance, db, logger),
-		Balance:    service.NewBalanceService(repos.Balance, repos.Group, repos.User, repos.Settlement, db, logger),
+		Group:      service.NewGroupService(repos.Group, repos.User, repos.Expense, repos.Balance, db, cfg.Features.MaxGroupSize, eventDispatcher, logger),
+		Expense:    service.NewExpenseService(repos.Expense, repos.Group, repos.User, repos.Balance, repos.BalanceHistory, repos.GroupLock, db, rates, eventDispatcher, metricsRegistry, logger),
+		Settlement: service.NewSettlementService(repos.Settlement, repos.Group, repos.User, repos.Balance, repos.BalanceHistory, repos.GroupLock, db, eventDispatcher, metricsRegistry, logger),
+		Balance:    service.NewBalanceService(repos.Balance, repos.Group, repos.User, repos.Settlement, repos.Expense, repos.BalanceHistory, groupLocks, db, rates, logger),
+		Insights:   service.NewInsightsService(repos.Insights, repos.User, logger),
+		Report:     service.NewReportService(repos.Report, repos.Group, repos.User, logger),
+		GroupLock:  groupLocks,
+		Export:     service.NewExportService(repos.Expense, repos.Settlement, repos.Group, logger),
 	}
+	services.Recurring = service.NewRecurringExpenseService(repos.Recurring, repos.Group, repos.User, services.Expense, logger)
+	services.Webhook = service.NewWebhookService(repos.Webhook, repos.Group, webhookDispatcher, logger)
 
 	// Initialize middleware
 	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(repos.Idempotency, cfg, logger)
-	transactionMiddleware := middleware.NewTransactionMiddleware(db, logger)
+	identityMiddleware := middleware.NewIdentityMiddleware(repos.User, cfg, logger)
+	rateLimiter := middleware.NewMemoryRateLimiter(middleware.RateLimit{
+		PerMinute: cfg.Features.RateLimitPerMinute,
+		Burst:     cfg.Features.RateLimitBurst,
+	})
+	rateLimitMiddleware := middleware.NewRateLimitMiddleware(rateLimiter, logger)
 
-	// Start idempotency cleanup goroutine
-	go idempotencyMiddleware.CleanupExpiredKeys()
+	// Start background workers: idempotency key cleanup, rate limiter
+	// bucket cleanup, the recurring expense scheduler, the outbox relay,
+	// webhook delivery and email notifications.
+	// They share one context that is cancelled during shutdown.
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	background.Add(6)
+	go func() {
+		defer background.Done()
+		idempotencyMiddleware.CleanupExpiredKeys(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		rateLimiter.CleanupIdleBuckets(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		services.Recurring.RunScheduler(backgroundCtx, cfg.Features.RecurringInterval)
+	}()
+	go func() {
+		defer background.Done()
+		outboxRelay.Run(backgroundCtx, cfg.Features.OutboxRelayInterval)
+	}()
+	go func() {
+		defer background.Done()
+		webhookDispatcher.Run(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		notificationDispatcher.Run(backgroundCtx)
+	}()
 
 	// Initialize Gin router
 	if cfg.Server.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	binding.EnableDecoderDisallowUnknownFields = cfg.Features.RejectUnknownJSONFields
 
 	router := gin.New()
 
+	corsPolicy := middleware.EffectiveCORSPolicy(cfg)
+	logger.Info("CORS policy",
+		zap.Bool("allow_all_origins", corsPolicy.AllowAllOrigins),
+		zap.Strings("allowed_origins", corsPolicy.AllowedOrigins),
+		zap.Bool("allow_credentials", corsPolicy.AllowCredentials))
+
 	// Add middleware
-	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.CORSMiddleware(cfg))
 	router.Use(middleware.StructuredLoggingMiddleware(logger))
+	router.Use(middleware.MetricsMiddleware(metricsRegistry))
 	router.Use(gin.Recovery())
+
+	// /metrics is registered before the rate limit, identity and idempotency
+	// middleware, so Prometheus scrapes need no user header and are never
+	// throttled
+	router.GET("/metrics", gin.WrapH(metricsRegistry.Handler()))
+
+	// Rate limiting runs before identity resolution so a flood of requests is
+	// turned away before it costs a user lookup
+	router.Use(rateLimitMiddleware.Handle())
+	router.Use(identityMiddleware.Handle())
 	router.Use(idempotencyMiddleware.Handle())
-	router.Use(transactionMiddleware.Handle())
 
 	// Setup routes
-	routes.SetupRoutes(router, services, logger)
-
+	routes.SetupRoutes(router, services, db, logger)
+	if cfg.Server.ServeSwagger() {
+		routes.SetupSwaggerRoutes(router)
+	}
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
@@ -126,18 +267,18 @@ func main() {
 	} else {
 		logger.Info("Server shutdown complete")
 	}
-}
 
-func initLogger() (*zap.Logger, error) {
-	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
-	config.EncoderConfig.TimeKey = "timestamp"
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-
-	logger, err := config.Build()
-	if err != nil {
-		return nil, err
+	// Let a recurring expense run or key cleanup in progress finish before
+	// closing the database
+	stopBackground()
+	backgroundDone := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(backgroundDone)
+	}()
+	select {
+	case <-backgroundDone:
+	case <-ctx.Done():
+		logger.Error("Background workers did not stop in time")
 	}
-
-	return logger, nil
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"expense-split-tracker/internal/database"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up                apply all pending migrations
+  down [steps]      revert the latest migrations, one unless steps is given
+  status            list migrations and whether they have been applied
+  baseline VERSION  record migrations up to VERSION as applied without running them`
+
+// runMigrate runs the migrate subcommand against db
+func runMigrate(ctx context.Context, db *database.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		count, err := db.Migrate(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", count)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		count, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted %d migration(s)\n", count)
+
+	case "status":
+		statuses, err := db.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+
+	case "baseline":
+		if len(args) < 2 {
+			return errors.New("baseline needs the version the database is at")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		count, err := db.Baseline(ctx, version)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Recorded %d migration(s) as applied\n", count)
+
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], migrateUsage)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+// Package swagger embeds the OpenAPI spec generated by swag from the
+// controllers' annotations and registers it with swag, where Swagger UI
+// reads it from. Regenerate it after changing an annotation:
+//
+//	go generate ./docs/swagger
+package swagger
+
+import (
+	_ "embed"
+
+	"github.com/swaggo/swag"
+)
+
+//go:generate go run github.com/swaggo/swag/cmd/swag@v1.16.2 init --dir ../.. --generalInfo cmd/server/main.go --output . --outputTypes json --parseInternal
+
+// Spec is the generated swagger.json
+//
+//go:embed swagger.json
+var Spec []byte
+
+// doc hands Spec to swag
+type doc struct{}
+
+func (doc) ReadDoc() string {
+	return string(Spec)
+}
+
+func init() {
+	swag.Register(swag.Name, doc{})
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+
+	"expense-split-tracker/internal/models"
+)
+
+// Identity is the caller of a request. A zero UserID marks an anonymous
+// caller, which is rejected wherever group membership is required.
+type Identity struct {
+	UserID   int64
+	UserUUID models.UserUUID
+}
+
+// IsAnonymous reports whether the request did not identify a user
+func (i *Identity) IsAnonymous() bool {
+	return i.UserID == 0
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the caller identity. Services only
+// enforce group membership for contexts that carry an identity, so internal
+// callers such as the recurring expense scheduler are unaffected.
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller identity stored in ctx, if any
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
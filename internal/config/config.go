@@ -4,36 +4,105 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
-	Security SecurityConfig
-	Logging  LoggingConfig
-	Features FeatureConfig
+	Database     DatabaseConfig
+	Server       ServerConfig
+	Security     SecurityConfig
+	Logging      LoggingConfig
+	Features     FeatureConfig
+	CORS         CORSConfig
+	Notification NotificationConfig
+	FX           FXConfig
 }
 
 type DatabaseConfig struct {
+	// Driver selects the database: mysql, postgres or sqlite
+	Driver   string
 	Host     string
 	Port     int
 	User     string
 	Password string
 	Name     string
-	DSN      string
+	// Path is the SQLite database file, or :memory:
+	Path string
+	DSN  string
+
+	// AutoMigrate applies pending migrations when the server starts
+	AutoMigrate bool
+
+	// StatementTimeout bounds the statements of each repository call so a
+	// slow query fails before the server's write timeout; zero disables it
+	StatementTimeout time.Duration
+}
+
+// SQLiteDSN returns the DSN for the SQLite database at path. The special path
+// :memory: selects an in-memory database, shared by the pool's connections
+// under name, that lasts until the last connection closes.
+func SQLiteDSN(path, name string) string {
+	params := "_busy_timeout=5000&_foreign_keys=on&_txlock=immediate"
+	if path == ":memory:" {
+		return fmt.Sprintf("file:%s?mode=memory&cache=shared&%s", name, params)
+	}
+	return fmt.Sprintf("file:%s?_journal_mode=WAL&%s", path, params)
 }
 
 type ServerConfig struct {
 	Host string
 	Port int
 	Env  string
+
+	// SwaggerEnabled serves the API spec and Swagger UI in production;
+	// other environments always serve them
+	SwaggerEnabled bool
+}
+
+// ServeSwagger reports whether /swagger should be mounted
+func (c ServerConfig) ServeSwagger() bool {
+	return c.Env != "production" || c.SwaggerEnabled
 }
 
 type SecurityConfig struct {
 	JWTSecret string
+
+	// EnforceGroupMembership restricts group-scoped reads and writes to
+	// members of the group, identified by the X-User-UUID header
+	EnforceGroupMembership bool
+}
+
+type CORSConfig struct {
+	// AllowedOrigins lists the origins browsers may call the API from; when
+	// empty, every origin is allowed outside production and none in it
+	AllowedOrigins   []string
+	AllowCredentials bool
+}
+
+// NotificationConfig configures email notifications. When Enabled is false
+// no mail is sent and the SMTP settings are ignored.
+type NotificationConfig struct {
+	Enabled      bool
+	SMTPHost     string
+	SMTPPort     int
+	From         string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// FXConfig configures exchange rates. The static provider serves Rates, the
+// units of each currency one BaseCurrency unit buys; the http provider
+// fetches rates from the Frankfurter-compatible API at URL.
+type FXConfig struct {
+	Provider     string
+	BaseCurrency string
+	Rates        map[string]decimal.Decimal
+	URL          string
+	Timeout      time.Duration
 }
 
 type LoggingConfig struct {
@@ -42,6 +111,35 @@ type LoggingConfig struct {
 
 type FeatureConfig struct {
 	IdempotencyTTL time.Duration
+	MaxGroupSize   int
+	GroupLockTTL   time.Duration
+
+	// RecurringInterval is how often due recurring expenses are created
+	RecurringInterval time.Duration
+
+	// RateLimitPerMinute and RateLimitBurst size the per-client token bucket
+	RateLimitPerMinute int
+	RateLimitBurst     int
+
+	// IdempotencyWait is how long a request waits for a concurrent request
+	// with the same Idempotency-Key before giving up with 409 PROCESSING
+	IdempotencyWait time.Duration
+
+	// IdempotencyCleanupInterval is how often expired idempotency keys are deleted
+	IdempotencyCleanupInterval time.Duration
+
+	// RejectUnknownJSONFields fails requests whose body has fields the
+	// endpoint does not accept instead of ignoring them
+	RejectUnknownJSONFields bool
+
+	// WebhookMaxAttempts is the number of tries per webhook delivery; retries
+	// wait WebhookRetryBackoff, doubling after each attempt
+	WebhookMaxAttempts  int
+	WebhookRetryBackoff time.Duration
+	WebhookTimeout      time.Duration
+
+	// OutboxRelayInterval is how often pending outbox events are published
+	OutboxRelayInterval time.Duration
 }
 
 func Load() (*Config, error) {
@@ -51,7 +149,18 @@ func Load() (*Config, error) {
 		fmt.Println("Warning: config.env file not found, using environment variables")
 	}
 
-	dbPort, err := strconv.Atoi(getEnv("DB_PORT", "3306"))
+	dbDriver := getEnv("DB_DRIVER", "mysql")
+	defaultDBPort := "3306"
+	switch dbDriver {
+	case "mysql":
+	case "postgres":
+		defaultDBPort = "5432"
+	case "sqlite":
+	default:
+		return nil, fmt.Errorf("invalid DB_DRIVER %q: must be mysql, postgres or sqlite", dbDriver)
+	}
+
+	dbPort, err := strconv.Atoi(getEnv("DB_PORT", defaultDBPort))
 	if err != nil {
 		return nil, fmt.Errorf("invalid DB_PORT: %v", err)
 	}
@@ -66,22 +175,204 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL_HOURS: %v", err)
 	}
 
+	idempotencyWaitMillis, err := strconv.Atoi(getEnv("IDEMPOTENCY_WAIT_MS", "2000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IDEMPOTENCY_WAIT_MS: %v", err)
+	}
+	if idempotencyWaitMillis < 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_WAIT_MS cannot be negative")
+	}
+
+	idempotencyCleanupMinutes, err := strconv.Atoi(getEnv("IDEMPOTENCY_CLEANUP_INTERVAL_MINUTES", "60"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IDEMPOTENCY_CLEANUP_INTERVAL_MINUTES: %v", err)
+	}
+	if idempotencyCleanupMinutes <= 0 {
+		return nil, fmt.Errorf("IDEMPOTENCY_CLEANUP_INTERVAL_MINUTES must be positive")
+	}
+
+	maxGroupSize, err := strconv.Atoi(getEnv("MAX_GROUP_SIZE", "50"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_GROUP_SIZE: %v", err)
+	}
+
+	groupLockTTLSeconds, err := strconv.Atoi(getEnv("GROUP_LOCK_TTL_SECONDS", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GROUP_LOCK_TTL_SECONDS: %v", err)
+	}
+
+	recurringIntervalSeconds, err := strconv.Atoi(getEnv("RECURRING_EXPENSE_INTERVAL_SECONDS", "60"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECURRING_EXPENSE_INTERVAL_SECONDS: %v", err)
+	}
+	if recurringIntervalSeconds <= 0 {
+		return nil, fmt.Errorf("RECURRING_EXPENSE_INTERVAL_SECONDS must be positive")
+	}
+
+	rateLimitPerMinute, err := strconv.Atoi(getEnv("RATE_LIMIT_PER_MINUTE", "120"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_PER_MINUTE: %v", err)
+	}
+	if rateLimitPerMinute <= 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_PER_MINUTE must be positive")
+	}
+
+	rateLimitBurst, err := strconv.Atoi(getEnv("RATE_LIMIT_BURST", "20"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_BURST: %v", err)
+	}
+	if rateLimitBurst <= 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_BURST must be positive")
+	}
+
+	corsAllowedOrigins, err := parseOrigins(getEnv("CORS_ALLOWED_ORIGINS", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	corsAllowCredentials, err := strconv.ParseBool(getEnv("CORS_ALLOW_CREDENTIALS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS: %v", err)
+	}
+
+	enforceGroupMembership, err := strconv.ParseBool(getEnv("ENFORCE_GROUP_MEMBERSHIP", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ENFORCE_GROUP_MEMBERSHIP: %v", err)
+	}
+
+	rejectUnknownJSONFields, err := strconv.ParseBool(getEnv("REJECT_UNKNOWN_JSON_FIELDS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REJECT_UNKNOWN_JSON_FIELDS: %v", err)
+	}
+
+	webhookMaxAttempts, err := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS: %v", err)
+	}
+	if webhookMaxAttempts <= 0 {
+		return nil, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be positive")
+	}
+
+	webhookRetryBackoffMillis, err := strconv.Atoi(getEnv("WEBHOOK_RETRY_BACKOFF_MS", "1000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_RETRY_BACKOFF_MS: %v", err)
+	}
+	if webhookRetryBackoffMillis < 0 {
+		return nil, fmt.Errorf("WEBHOOK_RETRY_BACKOFF_MS cannot be negative")
+	}
+
+	webhookTimeoutSeconds, err := strconv.Atoi(getEnv("WEBHOOK_TIMEOUT_SECONDS", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_TIMEOUT_SECONDS: %v", err)
+	}
+	if webhookTimeoutSeconds <= 0 {
+		return nil, fmt.Errorf("WEBHOOK_TIMEOUT_SECONDS must be positive")
+	}
+
+	outboxRelayIntervalMillis, err := strconv.Atoi(getEnv("OUTBOX_RELAY_INTERVAL_MS", "1000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OUTBOX_RELAY_INTERVAL_MS: %v", err)
+	}
+	if outboxRelayIntervalMillis <= 0 {
+		return nil, fmt.Errorf("OUTBOX_RELAY_INTERVAL_MS must be positive")
+	}
+
+	dbAutoMigrate, err := strconv.ParseBool(getEnv("DB_AUTO_MIGRATE", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_AUTO_MIGRATE: %v", err)
+	}
+
+	dbStatementTimeoutMillis, err := strconv.Atoi(getEnv("DB_STATEMENT_TIMEOUT_MS", "5000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DB_STATEMENT_TIMEOUT_MS: %v", err)
+	}
+	if dbStatementTimeoutMillis < 0 {
+		return nil, fmt.Errorf("DB_STATEMENT_TIMEOUT_MS cannot be negative")
+	}
+
+	swaggerEnabled, err := strconv.ParseBool(getEnv("SWAGGER_ENABLED", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SWAGGER_ENABLED: %v", err)
+	}
+
+	notificationsEnabled, err := strconv.ParseBool(getEnv("NOTIFICATIONS_ENABLED", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOTIFICATIONS_ENABLED: %v", err)
+	}
+
+	smtpPort, err := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT: %v", err)
+	}
+
+	notificationConfig := NotificationConfig{
+		Enabled:      notificationsEnabled,
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     smtpPort,
+		From:         getEnv("SMTP_FROM", ""),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+	}
+	if notificationConfig.Enabled && (notificationConfig.SMTPHost == "" || notificationConfig.From == "") {
+		return nil, fmt.Errorf("SMTP_HOST and SMTP_FROM are required when NOTIFICATIONS_ENABLED is true")
+	}
+
+	fxRates, err := parseRates(getEnv("FX_RATES", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	fxTimeoutSeconds, err := strconv.Atoi(getEnv("FX_TIMEOUT_SECONDS", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FX_TIMEOUT_SECONDS: %v", err)
+	}
+
+	fxConfig := FXConfig{
+		Provider:     getEnv("FX_PROVIDER", "static"),
+		BaseCurrency: strings.ToUpper(getEnv("FX_BASE_CURRENCY", "USD")),
+		Rates:        fxRates,
+		URL:          getEnv("FX_API_URL", "https://api.frankfurter.app"),
+		Timeout:      time.Duration(fxTimeoutSeconds) * time.Second,
+	}
+	if fxConfig.Provider != "static" && fxConfig.Provider != "http" {
+		return nil, fmt.Errorf("invalid FX_PROVIDER %q: must be static or http", fxConfig.Provider)
+	}
+
 	dbConfig := DatabaseConfig{
+		Driver:   dbDriver,
 		Host:     getEnv("DB_HOST", "localhost"),
 		Port:     dbPort,
 		User:     getEnv("DB_USER", "root"),
 		Password: getEnv("DB_PASSWORD", "password"),
 		Name:     getEnv("DB_NAME", "expense_split_tracker"),
+		Path:     getEnv("DB_PATH", "expense_split_tracker.db"),
+
+		AutoMigrate:      dbAutoMigrate,
+		StatementTimeout: time.Duration(dbStatementTimeoutMillis) * time.Millisecond,
 	}
 
 	// Create DSN
-	dbConfig.DSN = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		dbConfig.User,
-		dbConfig.Password,
-		dbConfig.Host,
-		dbConfig.Port,
-		dbConfig.Name,
-	)
+	switch dbConfig.Driver {
+	case "postgres":
+		dbConfig.DSN = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			dbConfig.Host,
+			dbConfig.Port,
+			dbConfig.User,
+			dbConfig.Password,
+			dbConfig.Name,
+			getEnv("DB_SSLMODE", "disable"),
+		)
+	case "sqlite":
+		dbConfig.DSN = SQLiteDSN(dbConfig.Path, dbConfig.Name)
+	default:
+		dbConfig.DSN = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			dbConfig.User,
+			dbConfig.Password,
+			dbConfig.Host,
+			dbConfig.Port,
+			dbConfig.Name,
+		)
+	}
 
 	config := &Config{
 		Database: dbConfig,
@@ -89,21 +380,87 @@ func Load() (*Config, error) {
 			Host: getEnv("SERVER_HOST", "localhost"),
 			Port: serverPort,
 			Env:  getEnv("ENV", "development"),
+
+			SwaggerEnabled: swaggerEnabled,
 		},
 		Security: SecurityConfig{
-			JWTSecret: getEnv("JWT_SECRET", "default-jwt-secret-change-in-production"),
+			JWTSecret:              getEnv("JWT_SECRET", "default-jwt-secret-change-in-production"),
+			EnforceGroupMembership: enforceGroupMembership,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   corsAllowedOrigins,
+			AllowCredentials: corsAllowCredentials,
 		},
 		Logging: LoggingConfig{
 			Level: getEnv("LOG_LEVEL", "info"),
 		},
 		Features: FeatureConfig{
 			IdempotencyTTL: time.Duration(idempotencyTTLHours) * time.Hour,
+			MaxGroupSize:   maxGroupSize,
+			GroupLockTTL:   time.Duration(groupLockTTLSeconds) * time.Second,
+
+			RecurringInterval: time.Duration(recurringIntervalSeconds) * time.Second,
+
+			RateLimitPerMinute: rateLimitPerMinute,
+			RateLimitBurst:     rateLimitBurst,
+
+			IdempotencyWait:            time.Duration(idempotencyWaitMillis) * time.Millisecond,
+			IdempotencyCleanupInterval: time.Duration(idempotencyCleanupMinutes) * time.Minute,
+
+			RejectUnknownJSONFields: rejectUnknownJSONFields,
+
+			WebhookMaxAttempts:  webhookMaxAttempts,
+			WebhookRetryBackoff: time.Duration(webhookRetryBackoffMillis) * time.Millisecond,
+			WebhookTimeout:      time.Duration(webhookTimeoutSeconds) * time.Second,
+
+			OutboxRelayInterval: time.Duration(outboxRelayIntervalMillis) * time.Millisecond,
 		},
+		Notification: notificationConfig,
+		FX:           fxConfig,
 	}
 
 	return config, nil
 }
 
+// parseOrigins splits a comma-separated origin list, requiring each origin to
+// carry its scheme as browsers send it in the Origin header
+func parseOrigins(value string) ([]string, error) {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return nil, fmt.Errorf("invalid CORS_ALLOWED_ORIGINS entry %q: origins must start with http:// or https://", origin)
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
+
+// parseRates reads a comma-separated list of CURRENCY=RATE pairs, e.g.
+// "EUR=0.92,GBP=0.79"; every rate must be positive
+func parseRates(value string) (map[string]decimal.Decimal, error) {
+	rates := make(map[string]decimal.Decimal)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		currency, rateValue, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid FX_RATES entry %q: expected CURRENCY=RATE", pair)
+		}
+		rate, err := decimal.NewFromString(strings.TrimSpace(rateValue))
+		if err != nil || !rate.IsPositive() {
+			return nil, fmt.Errorf("invalid FX_RATES entry %q: rate must be a positive number", pair)
+		}
+		rates[strings.ToUpper(strings.TrimSpace(currency))] = rate
+	}
+	return rates, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -1,7 +1,12 @@
 package controller
 
 import (
+	"strconv"
+	"time"
+
+	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
 	"expense-split-tracker/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -23,25 +28,26 @@ func NewBalanceController(balanceService service.BalanceService, logger *zap.Log
 
 // GetBalanceSheet handles retrieval of group balance sheet
 // @Summary Get group balance sheet
-// @Description Get complete balance sheet for a group showing all user balances
+// @Description Get complete balance sheet for a group showing all user balances. Without a currency the sheet lists the balances and summary of each currency under currencies; with one it covers only that currency.
 // @Tags balances
 // @Produce json
 // @Param uuid path string true "Group UUID"
+// @Param currency query string false "Only show balances in this currency"
+// @Param convert_to query string false "Also convert every balance into this currency, reporting the rates used and each member's converted total"
 // @Success 200 {object} response.APIResponse{data=models.BalanceSheet}
 // @Failure 400 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
 // @Failure 500 {object} response.APIResponse
 // @Router /api/v1/groups/{uuid}/balance-sheet [get]
 func (c *BalanceController) GetBalanceSheet(ctx *gin.Context) {
-	uuid := ctx.Param("uuid")
-	if uuid == "" {
-		response.BadRequest(ctx, "Group UUID is required")
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
 		return
 	}
 
-	balanceSheet, err := c.balanceService.GetGroupBalanceSheet(ctx.Request.Context(), uuid)
+	balanceSheet, err := c.balanceService.GetGroupBalanceSheet(ctx.Request.Context(), uuid, ctx.Query("currency"), ctx.Query("convert_to"))
 	if err != nil {
-		c.logger.Error("Failed to get balance sheet", zap.Error(err), zap.String("uuid", uuid))
+		c.logger.Error("Failed to get balance sheet", zap.Error(err), zap.String("uuid", uuid.String()))
 		response.Error(ctx, err)
 		return
 	}
@@ -56,29 +62,27 @@ func (c *BalanceController) GetBalanceSheet(ctx *gin.Context) {
 // @Produce json
 // @Param uuid path string true "Group UUID"
 // @Param userUuid path string true "User UUID"
+// @Param currency query string false "Currency (default: the group's default currency)"
 // @Success 200 {object} response.APIResponse{data=models.UserBalanceDetail}
 // @Failure 400 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
 // @Failure 500 {object} response.APIResponse
 // @Router /api/v1/groups/{uuid}/users/{userUuid}/balance [get]
 func (c *BalanceController) GetUserBalance(ctx *gin.Context) {
-	groupUuid := ctx.Param("uuid")
-	userUuid := ctx.Param("userUuid")
-
-	if groupUuid == "" {
-		response.BadRequest(ctx, "Group UUID is required")
+	groupUuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
 		return
 	}
 
-	if userUuid == "" {
-		response.BadRequest(ctx, "User UUID is required")
+	userUuid, ok := userUUIDParam(ctx, "userUuid")
+	if !ok {
 		return
 	}
 
-	userBalance, err := c.balanceService.GetUserBalance(ctx.Request.Context(), groupUuid, userUuid)
+	userBalance, err := c.balanceService.GetUserBalance(ctx.Request.Context(), groupUuid, userUuid, ctx.Query("currency"))
 	if err != nil {
 		c.logger.Error("Failed to get user balance", zap.Error(err),
-			zap.String("groupUuid", groupUuid), zap.String("userUuid", userUuid))
+			zap.String("groupUuid", groupUuid.String()), zap.String("userUuid", userUuid.String()))
 		response.Error(ctx, err)
 		return
 	}
@@ -86,30 +90,290 @@ func (c *BalanceController) GetUserBalance(ctx *gin.Context) {
 	response.Success(ctx, userBalance)
 }
 
+// GetUserSummary handles retrieval of a user's gross figures in a group
+// @Summary Get user summary in group
+// @Description Get what a user paid for, their share of expenses and the settlements they sent and received in a group, per currency, with the net balance these add up to next to the stored balance. Positive balances mean the user owes money.
+// @Tags balances
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param userUuid path string true "User UUID"
+// @Success 200 {object} response.APIResponse{data=models.UserGroupSummary}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/users/{userUuid}/summary [get]
+func (c *BalanceController) GetUserSummary(ctx *gin.Context) {
+	groupUuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	userUuid, ok := userUUIDParam(ctx, "userUuid")
+	if !ok {
+		return
+	}
+
+	summary, err := c.balanceService.GetUserSummary(ctx.Request.Context(), groupUuid, userUuid)
+	if err != nil {
+		c.logger.Error("Failed to get user summary", zap.Error(err),
+			zap.String("groupUuid", groupUuid.String()), zap.String("userUuid", userUuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, summary)
+}
+
 // GetDebtRelationships handles retrieval of debt relationships in a group
 // @Summary Get debt relationships
 // @Description Get debt relationships between users in a group
 // @Tags balances
 // @Produce json
 // @Param uuid path string true "Group UUID"
+// @Param currency query string false "Currency (default: the group's default currency)"
 // @Success 200 {object} response.APIResponse{data=[]models.DebtRelationship}
 // @Failure 400 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
 // @Failure 500 {object} response.APIResponse
 // @Router /api/v1/groups/{uuid}/debt-relationships [get]
 func (c *BalanceController) GetDebtRelationships(ctx *gin.Context) {
-	uuid := ctx.Param("uuid")
-	if uuid == "" {
-		response.BadRequest(ctx, "Group UUID is required")
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
 		return
 	}
 
-	relationships, err := c.balanceService.GetDebtRelationships(ctx.Request.Context(), uuid)
+	relationships, err := c.balanceService.GetDebtRelationships(ctx.Request.Context(), uuid, ctx.Query("currency"))
 	if err != nil {
-		c.logger.Error("Failed to get debt relationships", zap.Error(err), zap.String("uuid", uuid))
+		c.logger.Error("Failed to get debt relationships", zap.Error(err), zap.String("uuid", uuid.String()))
 		response.Error(ctx, err)
 		return
 	}
 
 	response.Success(ctx, relationships)
 }
+
+// GetUserCounterparties handles retrieval of who owes a user and whom they owe
+// @Summary Get a user's debtors and creditors
+// @Description Get the members who owe a user in a group and the members the user owes, with amounts, from the pairwise debts. Amounts below one cent are left out.
+// @Tags balances
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param userUuid path string true "User UUID"
+// @Param currency query string false "Currency (default: the group's default currency)"
+// @Success 200 {object} response.APIResponse{data=models.UserCounterparties}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/users/{userUuid}/creditors-debtors [get]
+func (c *BalanceController) GetUserCounterparties(ctx *gin.Context) {
+	groupUuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	userUuid, ok := userUUIDParam(ctx, "userUuid")
+	if !ok {
+		return
+	}
+
+	counterparties, err := c.balanceService.GetUserCounterparties(ctx.Request.Context(), groupUuid, userUuid, ctx.Query("currency"))
+	if err != nil {
+		c.logger.Error("Failed to get user counterparties", zap.Error(err),
+			zap.String("groupUuid", groupUuid.String()), zap.String("userUuid", userUuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, counterparties)
+}
+
+// GetBalanceHistory handles retrieval of a user's balance history in a group
+// @Summary Get user balance history
+// @Description Get every change to a user's balance in a group, oldest first, with the expense or settlement that caused it and the balance it left behind
+// @Tags balances
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param userUuid path string true "User UUID"
+// @Param from query string false "Include changes from this date (YYYY-MM-DD)"
+// @Param to query string false "Include changes up to and including this date (YYYY-MM-DD)"
+// @Param currency query string false "Only include changes in this currency"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} response.APIResponse{data=[]models.BalanceHistoryEntry,meta=response.Meta}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/users/{userUuid}/balance-history [get]
+func (c *BalanceController) GetBalanceHistory(ctx *gin.Context) {
+	groupUuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	userUuid, ok := userUUIDParam(ctx, "userUuid")
+	if !ok {
+		return
+	}
+
+	filter := &models.BalanceHistoryFilter{
+		Currency: ctx.Query("currency"),
+		Page:     1,
+		Limit:    10,
+	}
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &filter.FromDate}, {"to", &filter.ToDate}} {
+		value := ctx.Query(param.name)
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError(param.name, value))
+			return
+		}
+		*param.target = date
+	}
+
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			filter.Page = p
+		}
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			filter.Limit = l
+		}
+	}
+
+	entries, total, err := c.balanceService.GetBalanceHistory(ctx.Request.Context(), groupUuid, userUuid, filter)
+	if err != nil {
+		c.logger.Error("Failed to get balance history", zap.Error(err),
+			zap.String("groupUuid", groupUuid.String()), zap.String("userUuid", userUuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.SuccessWithMeta(ctx, entries, listMeta(ctx, filter.Page, filter.Limit, total))
+}
+
+// GetUserBalances handles retrieval of a user's balances across all groups
+// @Summary Get user balances across groups
+// @Description Get a user's balance in every group they belong to, grouped by currency with the net total per currency. A positive net means the user owes that much overall, a negative one that they are owed it.
+// @Tags balances
+// @Produce json
+// @Param uuid path string true "User UUID"
+// @Param currency query string false "Only include balances in this currency"
+// @Param convert_to query string false "Also convert each currency's net into this currency, reporting the rates used and the overall converted net"
+// @Success 200 {object} response.APIResponse{data=models.UserBalanceOverview}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/users/{uuid}/balances [get]
+func (c *BalanceController) GetUserBalances(ctx *gin.Context) {
+	uuid, ok := userUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	overview, err := c.balanceService.GetUserBalances(ctx.Request.Context(), uuid, ctx.Query("currency"), ctx.Query("convert_to"))
+	if err != nil {
+		c.logger.Error("Failed to get user balances", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, overview)
+}
+
+// GetPairwiseNet handles netting two users' debts across their shared groups
+// @Summary Net two users' debts across groups
+// @Description Net what a user and another user owe each other in every group they share, per currency. Each currency has the overall direction (owes, owed or settled, seen from the first user) and amount, and the groups it came from; groups where the two are square are left out.
+// @Tags balances
+// @Produce json
+// @Param uuid path string true "User UUID"
+// @Param otherUuid path string true "Other user UUID"
+// @Param currency query string false "Only net debts in this currency"
+// @Success 200 {object} response.APIResponse{data=models.PairwiseNet}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/users/{uuid}/net-with/{otherUuid} [get]
+func (c *BalanceController) GetPairwiseNet(ctx *gin.Context) {
+	uuid, ok := userUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	otherUuid, ok := userUUIDParam(ctx, "otherUuid")
+	if !ok {
+		return
+	}
+
+	net, err := c.balanceService.GetPairwiseNet(ctx.Request.Context(), uuid, otherUuid, ctx.Query("currency"))
+	if err != nil {
+		c.logger.Error("Failed to net user debts", zap.Error(err),
+			zap.String("uuid", uuid.String()), zap.String("otherUuid", otherUuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, net)
+}
+
+// RebuildGroupBalances handles recomputing a group's balances and debts from its history
+// @Summary Rebuild group balances
+// @Description Recompute every balance and pairwise debt of a group from its expense splits and confirmed settlements, replacing the stored rows and recording each corrected balance in the balance history. The group is locked for reconciliation meanwhile. The response lists each user's previous and recomputed balance and each pair's previous and recomputed debt per currency so drift can be audited.
+// @Tags balances
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Success 200 {object} response.APIResponse{data=models.BalanceRebuild}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 423 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/balances/rebuild [post]
+func (c *BalanceController) RebuildGroupBalances(ctx *gin.Context) {
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	rebuild, err := c.balanceService.RebuildGroupBalances(ctx.Request.Context(), uuid)
+	if err != nil {
+		c.logger.Error("Failed to rebuild group balances", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, rebuild)
+}
+
+// VerifyGroupBalances handles checking a group's stored balances against its history
+// @Summary Verify group balances
+// @Description Sum the stored balances of a group per currency and compare them with sums recomputed from its expense splits and confirmed settlements. Every currency should sum to zero; the report flags each currency as consistent or not and is returned with 200 either way.
+// @Tags balances
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Success 200 {object} response.APIResponse{data=models.BalanceVerification}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/balances/verify [get]
+func (c *BalanceController) VerifyGroupBalances(ctx *gin.Context) {
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	verification, err := c.balanceService.VerifyGroupBalances(ctx.Request.Context(), uuid)
+	if err != nil {
+		c.logger.Error("Failed to verify group balances", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, verification)
+}
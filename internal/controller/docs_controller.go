@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+type DocsController struct {
+	ui gin.HandlerFunc
+}
+
+// NewDocsController creates a controller serving the spec registered with
+// swag and Swagger UI for it. The UI's assets are the swagger-ui-dist files
+// embedded in the binary, so the page loads without reaching a CDN.
+func NewDocsController() *DocsController {
+	return &DocsController{
+		ui: ginSwagger.CustomWrapHandler(&ginSwagger.Config{
+			URL:                      "doc.json",
+			Title:                    "Expense Split Tracker API",
+			DocExpansion:             "list",
+			DefaultModelsExpandDepth: 1,
+			DeepLinking:              true,
+		}, swaggerFiles.Handler),
+	}
+}
+
+// Serve handles /swagger/*any: doc.json is the spec, index.html is Swagger
+// UI with its assets next to it, and the bare prefix redirects to index.html
+func (c *DocsController) Serve(ctx *gin.Context) {
+	if strings.TrimPrefix(ctx.Param("any"), "/") == "" {
+		ctx.Redirect(http.StatusMovedPermanently, "index.html")
+		return
+	}
+	c.ui(ctx)
+}
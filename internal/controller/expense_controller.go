@@ -2,13 +2,14 @@ package controller
 
 import (
 	"strconv"
-	"time"
 
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
 	"expense-split-tracker/pkg/response"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
@@ -27,7 +28,7 @@ func NewExpenseController(expenseService service.ExpenseService, logger *zap.Log
 
 // CreateExpense handles expense creation with splits
 // @Summary Create a new expense
-// @Description Create a new expense with different split types (equal, exact, percentage)
+// @Description Create a new expense with different split types (equal, exact, percentage, shares). An equal split with no splits is shared by all current group members. Set is_refund to record a refund that credits the participants and debits the payer.
 // @Tags expenses
 // @Accept json
 // @Produce json
@@ -39,9 +40,9 @@ func NewExpenseController(expenseService service.ExpenseService, logger *zap.Log
 // @Router /api/v1/expenses [post]
 func (c *ExpenseController) CreateExpense(ctx *gin.Context) {
 	var req models.CreateExpenseRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
+	if err := response.BindJSON(ctx, &req); err != nil {
 		c.logger.Error("Invalid request body", zap.Error(err))
-		response.BadRequest(ctx, "Invalid request body")
+		response.Error(ctx, err)
 		return
 	}
 
@@ -55,6 +56,176 @@ func (c *ExpenseController) CreateExpense(ctx *gin.Context) {
 	response.Created(ctx, expense)
 }
 
+// GetExpense handles expense retrieval by UUID
+// @Summary Get expense by UUID
+// @Description Get expense details by UUID, including splits, group and payer
+// @Tags expenses
+// @Produce json
+// @Param uuid path string true "Expense UUID"
+// @Success 200 {object} response.APIResponse{data=models.Expense}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/expenses/{uuid} [get]
+func (c *ExpenseController) GetExpense(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Expense UUID is required")
+		return
+	}
+
+	expense, err := c.expenseService.GetExpenseByUUID(ctx.Request.Context(), uuid)
+	if err != nil {
+		c.logger.Error("Failed to get expense", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, expense)
+}
+
+// UpdateExpense handles replacing an expense's amount and splits
+// @Summary Update an expense
+// @Description Replace an expense's amount, description, currency and splits; balances are recalculated
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Expense UUID"
+// @Param expense body models.UpdateExpenseRequest true "Expense update request"
+// @Success 200 {object} response.APIResponse{data=models.Expense}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 423 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/expenses/{uuid} [put]
+func (c *ExpenseController) UpdateExpense(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Expense UUID is required")
+		return
+	}
+
+	var req models.UpdateExpenseRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	expense, err := c.expenseService.UpdateExpense(ctx.Request.Context(), uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to update expense", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, expense)
+}
+
+// SetReceipt handles attaching a receipt to an existing expense
+// @Summary Attach a receipt to an expense
+// @Description Set or replace the receipt URL of an expense without changing its amount or splits; an empty receipt_url removes it
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Expense UUID"
+// @Param receipt body models.SetReceiptRequest true "Receipt URL (http or https, at most 2048 characters)"
+// @Success 200 {object} response.APIResponse{data=models.Expense}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/expenses/{uuid}/receipt [put]
+func (c *ExpenseController) SetReceipt(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Expense UUID is required")
+		return
+	}
+
+	var req models.SetReceiptRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	expense, err := c.expenseService.SetReceipt(ctx.Request.Context(), uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to set expense receipt", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, expense)
+}
+
+// DuplicateExpense handles creating a copy of an existing expense
+// @Summary Duplicate an expense
+// @Description Create a new expense with the payer, participants and split type of an existing one. amount, description and expense_date may be overridden; expense_date defaults to now. The copy is validated against the group's current members.
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Expense UUID"
+// @Param overrides body models.DuplicateExpenseRequest false "Optional overrides"
+// @Success 201 {object} response.APIResponse{data=models.Expense}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/expenses/{uuid}/duplicate [post]
+func (c *ExpenseController) DuplicateExpense(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Expense UUID is required")
+		return
+	}
+
+	// The body is optional; an empty one duplicates the expense as is
+	var req models.DuplicateExpenseRequest
+	if err := response.BindOptionalJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	expense, err := c.expenseService.DuplicateExpense(ctx.Request.Context(), uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to duplicate expense", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Created(ctx, expense)
+}
+
+// DeleteExpense handles expense deletion
+// @Summary Delete an expense
+// @Description Delete an expense, reversing its splits' effect on group balances. The expense is kept with deleted_at and deleted_by for auditing and only listed with include_deleted.
+// @Tags expenses
+// @Produce json
+// @Param uuid path string true "Expense UUID"
+// @Success 200 {object} response.APIResponse
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 409 {object} response.APIResponse
+// @Failure 423 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/expenses/{uuid} [delete]
+func (c *ExpenseController) DeleteExpense(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Expense UUID is required")
+		return
+	}
+
+	if err := c.expenseService.DeleteExpense(ctx.Request.Context(), uuid); err != nil {
+		c.logger.Error("Failed to delete expense", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, gin.H{"message": "Expense deleted successfully"})
+}
+
 // ListExpenses handles expense listing with filtering
 // @Summary List expenses
 // @Description Get paginated list of expenses with optional filtering
@@ -63,12 +234,21 @@ func (c *ExpenseController) CreateExpense(ctx *gin.Context) {
 // @Param group_uuid query string false "Filter by group UUID"
 // @Param user_uuid query string false "Filter by user UUID"
 // @Param currency query string false "Filter by currency"
-// @Param split_type query string false "Filter by split type"
-// @Param from_date query string false "Filter from date (YYYY-MM-DD)"
-// @Param to_date query string false "Filter to date (YYYY-MM-DD)"
+// @Param category query string false "Filter by category"
+// @Param q query string false "Case-insensitive search in description (at least 2 characters)"
+// @Param split_type query string false "Filter by split type" Enums(equal, exact, percentage, shares)
+// @Param min_amount query string false "Only expenses with amount >= min_amount"
+// @Param max_amount query string false "Only expenses with amount <= max_amount"
+// @Param from_date query string false "Filter from date (YYYY-MM-DD or RFC3339)"
+// @Param to_date query string false "Filter to date (YYYY-MM-DD or RFC3339)"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
-// @Success 200 {object} response.APIResponse{data=models.ExpenseListResponse}
+// @Param sort_by query string false "Sort field (default expense_date)" Enums(created_at, expense_date, amount, description)
+// @Param sort_dir query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Param include query string false "Split detail: splits, splits_summary or none" default(splits)
+// @Param viewer_uuid query string false "User whose share is reported by splits_summary"
+// @Param include_deleted query bool false "Include deleted expenses, for auditing" default(false)
+// @Success 200 {object} response.APIResponse{data=models.ExpenseListResponse,meta=response.Meta}
 // @Failure 400 {object} response.APIResponse
 // @Failure 500 {object} response.APIResponse
 // @Router /api/v1/expenses [get]
@@ -78,26 +258,52 @@ func (c *ExpenseController) ListExpenses(ctx *gin.Context) {
 		GroupUUID: ctx.Query("group_uuid"),
 		UserUUID:  ctx.Query("user_uuid"),
 		Currency:  ctx.Query("currency"),
+		Category:  ctx.Query("category"),
+		Query:     ctx.Query("q"),
 		Page:      1,
 		Limit:     10,
 	}
 
+	var ok bool
+	filter.ExpenseListOptions, ok = parseExpenseListOptions(ctx)
+	if !ok {
+		return
+	}
+
 	// Parse split type
 	if splitType := ctx.Query("split_type"); splitType != "" {
 		filter.SplitType = models.SplitType(splitType)
 	}
 
-	// Parse dates
-	if fromDateStr := ctx.Query("from_date"); fromDateStr != "" {
-		if fromDate, err := time.Parse("2006-01-02", fromDateStr); err == nil {
-			filter.FromDate = fromDate
+	sort, ok := listSortParams(ctx, models.ExpenseSortFields)
+	if !ok {
+		return
+	}
+	filter.ListSort = sort
+
+	// Parse amount range
+	if minAmountStr := ctx.Query("min_amount"); minAmountStr != "" {
+		minAmount, err := decimal.NewFromString(minAmountStr)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError("min_amount", minAmountStr))
+			return
 		}
+		filter.MinAmount = minAmount
 	}
 
-	if toDateStr := ctx.Query("to_date"); toDateStr != "" {
-		if toDate, err := time.Parse("2006-01-02", toDateStr); err == nil {
-			filter.ToDate = toDate
+	if maxAmountStr := ctx.Query("max_amount"); maxAmountStr != "" {
+		maxAmount, err := decimal.NewFromString(maxAmountStr)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError("max_amount", maxAmountStr))
+			return
 		}
+		filter.MaxAmount = maxAmount
+	}
+
+	// Parse dates
+	filter.FromDate, filter.ToDate, ok = dateRangeQuery(ctx)
+	if !ok {
+		return
 	}
 
 	// Parse pagination
@@ -120,7 +326,7 @@ func (c *ExpenseController) ListExpenses(ctx *gin.Context) {
 		return
 	}
 
-	response.Success(ctx, expenseResponse)
+	response.SuccessWithMeta(ctx, expenseResponse, listMeta(ctx, expenseResponse.Page, expenseResponse.Limit, expenseResponse.TotalCount))
 }
 
 // GetGroupExpenses handles retrieval of expenses for a specific group
@@ -131,6 +337,9 @@ func (c *ExpenseController) ListExpenses(ctx *gin.Context) {
 // @Param uuid path string true "Group UUID"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
+// @Param include query string false "Split detail: splits, splits_summary or none" default(splits)
+// @Param viewer_uuid query string false "User whose share is reported by splits_summary"
+// @Param include_deleted query bool false "Include deleted expenses, for auditing" default(false)
 // @Success 200 {object} response.APIResponse{data=[]models.Expense,meta=response.Meta}
 // @Failure 400 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
@@ -159,21 +368,130 @@ func (c *ExpenseController) GetGroupExpenses(ctx *gin.Context) {
 		}
 	}
 
-	expenses, err := c.expenseService.GetGroupExpenses(ctx.Request.Context(), uuid, page, limit)
+	opts, ok := parseExpenseListOptions(ctx)
+	if !ok {
+		return
+	}
+
+	expenses, total, err := c.expenseService.GetGroupExpenses(ctx.Request.Context(), uuid, page, limit, &opts)
 	if err != nil {
 		c.logger.Error("Failed to get group expenses", zap.Error(err), zap.String("uuid", uuid))
 		response.Error(ctx, err)
 		return
 	}
 
-	// Create meta information
-	meta := &response.Meta{
-		Page:  page,
-		Limit: limit,
-		Total: len(expenses),
+	response.SuccessWithMeta(ctx, expenses, listMeta(ctx, page, limit, total))
+}
+
+// GetTopGroupExpenses handles retrieval of a group's largest expenses
+// @Summary Get top group expenses
+// @Description Get a group's largest expenses by amount in a date range, largest first, with their payer and split detail. Amounts are compared as is, across currencies.
+// @Tags expenses
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param limit query int false "Number of expenses, capped at 100" default(10)
+// @Param from_date query string false "Include expenses from this date (YYYY-MM-DD or RFC3339)"
+// @Param to_date query string false "Include expenses up to and including this date (YYYY-MM-DD or RFC3339)"
+// @Param include query string false "Split detail: splits, splits_summary or none" default(splits)
+// @Param viewer_uuid query string false "User whose share is reported by splits_summary"
+// @Param include_deleted query bool false "Include deleted expenses, for auditing" default(false)
+// @Success 200 {object} response.APIResponse{data=[]models.Expense}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/expenses/top [get]
+func (c *ExpenseController) GetTopGroupExpenses(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Group UUID is required")
+		return
+	}
+
+	opts, ok := parseExpenseListOptions(ctx)
+	if !ok {
+		return
+	}
+
+	filter := &models.TopExpensesFilter{ExpenseListOptions: opts}
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			response.Error(ctx, errors.NewInvalidValueError("limit", limitStr))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	filter.FromDate, filter.ToDate, ok = dateRangeQuery(ctx)
+	if !ok {
+		return
+	}
+
+	expenses, err := c.expenseService.GetTopGroupExpenses(ctx.Request.Context(), uuid, filter)
+	if err != nil {
+		c.logger.Error("Failed to get top group expenses", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, expenses)
+}
+
+// ImportExpenses handles bulk creation of a group's expenses from a CSV upload
+// @Summary Import group expenses from CSV
+// @Description Import expenses from a CSV file with columns date, description, amount, currency, paid_by_email, split_type and participants. Invalid rows are skipped and reported; valid rows are created in one transaction. With dry_run=true rows are only validated.
+// @Tags expenses
+// @Accept multipart/form-data
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param file formData file true "CSV file"
+// @Param dry_run query bool false "Validate without creating expenses" default(false)
+// @Success 200 {object} response.APIResponse{data=models.ExpenseImportResult}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 423 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/expenses/import [post]
+func (c *ExpenseController) ImportExpenses(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Group UUID is required")
+		return
+	}
+
+	dryRun := false
+	if dryRunStr := ctx.Query("dry_run"); dryRunStr != "" {
+		parsed, err := strconv.ParseBool(dryRunStr)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError("dry_run", dryRunStr))
+			return
+		}
+		dryRun = parsed
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		response.BadRequest(ctx, "CSV file is required in the 'file' form field")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.logger.Error("Failed to open uploaded file", zap.Error(err))
+		response.BadRequest(ctx, "Could not read uploaded file")
+		return
 	}
+	defer file.Close()
 
-	response.SuccessWithMeta(ctx, expenses, meta)
+	result, err := c.expenseService.ImportExpenses(ctx.Request.Context(), uuid, file, dryRun)
+	if err != nil {
+		c.logger.Error("Failed to import expenses", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
 }
 
 // GetUserExpenses handles retrieval of expenses for a specific user
@@ -184,6 +502,9 @@ func (c *ExpenseController) GetGroupExpenses(ctx *gin.Context) {
 // @Param uuid path string true "User UUID"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
+// @Param include query string false "Split detail: splits, splits_summary or none" default(splits)
+// @Param viewer_uuid query string false "User whose share is reported by splits_summary"
+// @Param include_deleted query bool false "Include deleted expenses, for auditing" default(false)
 // @Success 200 {object} response.APIResponse{data=[]models.Expense,meta=response.Meta}
 // @Failure 400 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
@@ -212,19 +533,38 @@ func (c *ExpenseController) GetUserExpenses(ctx *gin.Context) {
 		}
 	}
 
-	expenses, err := c.expenseService.GetUserExpenses(ctx.Request.Context(), uuid, page, limit)
+	opts, ok := parseExpenseListOptions(ctx)
+	if !ok {
+		return
+	}
+
+	expenses, total, err := c.expenseService.GetUserExpenses(ctx.Request.Context(), uuid, page, limit, &opts)
 	if err != nil {
 		c.logger.Error("Failed to get user expenses", zap.Error(err), zap.String("uuid", uuid))
 		response.Error(ctx, err)
 		return
 	}
 
-	// Create meta information
-	meta := &response.Meta{
-		Page:  page,
-		Limit: limit,
-		Total: len(expenses),
+	response.SuccessWithMeta(ctx, expenses, listMeta(ctx, page, limit, total))
+}
+
+// parseExpenseListOptions reads the split detail and include_deleted options
+// shared by the expense list endpoints. It writes the error response and
+// returns false when include_deleted is not a boolean.
+func parseExpenseListOptions(ctx *gin.Context) (models.ExpenseListOptions, bool) {
+	opts := models.ExpenseListOptions{
+		Include:    models.SplitInclude(ctx.Query("include")),
+		ViewerUUID: ctx.Query("viewer_uuid"),
+	}
+
+	if includeDeletedStr := ctx.Query("include_deleted"); includeDeletedStr != "" {
+		includeDeleted, err := strconv.ParseBool(includeDeletedStr)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError("include_deleted", includeDeletedStr))
+			return opts, false
+		}
+		opts.IncludeDeleted = includeDeleted
 	}
 
-	response.SuccessWithMeta(ctx, expenses, meta)
+	return opts, true
 }
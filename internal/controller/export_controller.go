@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ExportController struct {
+	exportService service.ExportService
+	logger        *zap.Logger
+}
+
+// NewExportController creates a new export controller
+func NewExportController(exportService service.ExportService, logger *zap.Logger) *ExportController {
+	return &ExportController{
+		exportService: exportService,
+		logger:        logger,
+	}
+}
+
+// ExportGroup handles streaming a group's expenses and settlements
+// @Summary Export group expenses and settlements
+// @Description Stream every expense (with one split column per participant) and every settlement of a group as CSV
+// @Tags groups
+// @Produce text/csv
+// @Param uuid path string true "Group UUID"
+// @Param format query string false "Export format" Enums(csv) default(csv)
+// @Param from_date query string false "Include records from this date (YYYY-MM-DD)"
+// @Param to_date query string false "Include records up to and including this date (YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/export [get]
+func (c *ExportController) ExportGroup(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Group UUID is required")
+		return
+	}
+
+	format := ctx.DefaultQuery("format", models.ExportFormatCSV)
+	if format != models.ExportFormatCSV {
+		appErr := errors.NewInvalidValueError("format", format)
+		appErr.Details = map[string]string{"field": "format", "allowed": models.ExportFormatCSV}
+		response.Error(ctx, appErr)
+		return
+	}
+
+	filter := &models.ExportFilter{}
+	for _, param := range []struct {
+		name   string
+		target *time.Time
+	}{{"from_date", &filter.FromDate}, {"to_date", &filter.ToDate}} {
+		value := ctx.Query(param.name)
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError(param.name, value))
+			return
+		}
+		*param.target = date
+	}
+
+	filename := fmt.Sprintf("group-%s-%s.csv", uuid, time.Now().UTC().Format("20060102"))
+	writer := &streamingWriter{ctx: ctx, contentType: "text/csv; charset=utf-8", filename: filename}
+
+	err := c.exportService.ExportGroupCSV(ctx.Request.Context(), uuid, filter, writer)
+	if err != nil {
+		c.logger.Error("Failed to export group", zap.Error(err), zap.String("uuid", uuid))
+		// Once rows have been streamed the status is sent; just cut the response short
+		if writer.started {
+			ctx.Abort()
+			return
+		}
+		response.Error(ctx, err)
+		return
+	}
+
+	// An export always has a header row, but keep the response well formed anyway
+	if !writer.started {
+		writer.start()
+	}
+}
+
+// streamingWriter sends the download headers on the first write and flushes
+// every chunk to the client, so errors found before any output can still be
+// reported as JSON
+type streamingWriter struct {
+	ctx         *gin.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *streamingWriter) start() {
+	w.started = true
+	w.ctx.Header("Content-Type", w.contentType)
+	w.ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+	w.ctx.Status(http.StatusOK)
+}
+
+func (w *streamingWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.start()
+	}
+	n, err := w.ctx.Writer.Write(p)
+	if err != nil {
+		return n, err
+	}
+	w.ctx.Writer.Flush()
+	return n, nil
+}
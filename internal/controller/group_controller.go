@@ -5,6 +5,7 @@ import (
 
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
 	"expense-split-tracker/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -39,9 +40,9 @@ func NewGroupController(groupService service.GroupService, logger *zap.Logger) *
 // @Router /api/v1/groups [post]
 func (c *GroupController) CreateGroup(ctx *gin.Context) {
 	var req models.CreateGroupRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
+	if err := response.BindJSON(ctx, &req); err != nil {
 		c.logger.Error("Invalid request body", zap.Error(err))
-		response.BadRequest(ctx, "Invalid request body")
+		response.Error(ctx, err)
 		return
 	}
 
@@ -61,6 +62,37 @@ func (c *GroupController) CreateGroup(ctx *gin.Context) {
 	response.Created(ctx, group)
 }
 
+// BootstrapGroup handles creating a group together with its members
+// @Summary Bootstrap a group with members
+// @Description Create a group and add members from a contact list in one request. Members are matched by email; unknown emails are created as pending users.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param group body models.BootstrapGroupRequest true "Group bootstrap request"
+// @Success 201 {object} response.APIResponse{data=models.BootstrapGroupResponse}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 409 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/bootstrap [post]
+func (c *GroupController) BootstrapGroup(ctx *gin.Context) {
+	var req models.BootstrapGroupRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	result, err := c.groupService.BootstrapGroup(ctx.Request.Context(), &req)
+	if err != nil {
+		c.logger.Error("Failed to bootstrap group", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Created(ctx, result)
+}
+
 // GetGroup handles group retrieval by UUID
 // @Summary Get group by UUID
 // @Description Get group details by UUID
@@ -89,11 +121,148 @@ func (c *GroupController) GetGroup(ctx *gin.Context) {
 	response.Success(ctx, group)
 }
 
+// GetGroupSummary handles retrieval of a group's dashboard summary
+// @Summary Get group summary
+// @Description Get a group's member and expense counts, net expense totals per currency, current balances and the time of its latest expense (last_activity)
+// @Tags groups
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Success 200 {object} response.APIResponse{data=models.GroupSummary}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/summary [get]
+func (c *GroupController) GetGroupSummary(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Group UUID is required")
+		return
+	}
+
+	summary, err := c.groupService.GetGroupSummary(ctx.Request.Context(), uuid)
+	if err != nil {
+		c.logger.Error("Failed to get group summary", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, summary)
+}
+
+// UpdateGroup handles updating group settings
+// @Summary Update group settings
+// @Description Update a group's name, description or timezone. Omitted fields are left unchanged. acting_user_uuid must be a group admin. Returns the group with its members.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param group body models.UpdateGroupRequest true "Group settings"
+// @Success 200 {object} response.APIResponse{data=models.Group}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid} [put]
+// @Router /api/v1/groups/{uuid} [patch]
+func (c *GroupController) UpdateGroup(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Group UUID is required")
+		return
+	}
+
+	var req models.UpdateGroupRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	group, err := c.groupService.UpdateGroup(ctx.Request.Context(), uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to update group", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, group)
+}
+
+// ArchiveGroup handles archiving a group
+// @Summary Archive a group
+// @Description Soft-delete a group by archiving it. Only group admins can archive. Refused while any member has a non-zero balance; the error details list each unsettled member. Archived groups keep their history but reject new expenses and settlements.
+// @Tags groups
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param acting_user_uuid query string true "UUID of the admin making the change"
+// @Success 200 {object} response.APIResponse{data=models.Group}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 409 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid} [delete]
+func (c *GroupController) ArchiveGroup(ctx *gin.Context) {
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	actingUser, ok := actingUserQuery(ctx)
+	if !ok {
+		return
+	}
+
+	group, err := c.groupService.ArchiveGroup(ctx.Request.Context(), uuid, actingUser)
+	if err != nil {
+		c.logger.Error("Failed to archive group", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, group)
+}
+
+// UnarchiveGroup handles restoring an archived group
+// @Summary Unarchive a group
+// @Description Restore an archived group so it accepts expenses and settlements again. Only group admins can unarchive.
+// @Tags groups
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param acting_user_uuid query string true "UUID of the admin making the change"
+// @Success 200 {object} response.APIResponse{data=models.Group}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/unarchive [post]
+func (c *GroupController) UnarchiveGroup(ctx *gin.Context) {
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	actingUser, ok := actingUserQuery(ctx)
+	if !ok {
+		return
+	}
+
+	group, err := c.groupService.UnarchiveGroup(ctx.Request.Context(), uuid, actingUser)
+	if err != nil {
+		c.logger.Error("Failed to unarchive group", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, group)
+}
+
 // ListGroups handles group listing with pagination
 // @Summary List groups
-// @Description Get paginated list of groups
+// @Description Get paginated list of groups. Archived groups are left out unless include_archived is set.
 // @Tags groups
 // @Produce json
+// @Param include_archived query bool false "Include archived groups" default(false)
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Success 200 {object} response.APIResponse{data=[]models.Group,meta=response.Meta}
@@ -117,29 +286,33 @@ func (c *GroupController) ListGroups(ctx *gin.Context) {
 		}
 	}
 
-	groups, err := c.groupService.ListGroups(ctx.Request.Context(), page, limit)
+	includeArchived := false
+	if includeArchivedStr := ctx.Query("include_archived"); includeArchivedStr != "" {
+		parsed, err := strconv.ParseBool(includeArchivedStr)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError("include_archived", includeArchivedStr))
+			return
+		}
+		includeArchived = parsed
+	}
+
+	groups, total, err := c.groupService.ListGroups(ctx.Request.Context(), page, limit, includeArchived)
 	if err != nil {
 		c.logger.Error("Failed to list groups", zap.Error(err))
 		response.Error(ctx, err)
 		return
 	}
 
-	// Create meta information
-	meta := &response.Meta{
-		Page:  page,
-		Limit: limit,
-		Total: len(groups),
-	}
-
-	response.SuccessWithMeta(ctx, groups, meta)
+	response.SuccessWithMeta(ctx, groups, listMeta(ctx, page, limit, total))
 }
 
 // GetUserGroups handles retrieval of groups for a specific user
 // @Summary Get user's groups
-// @Description Get paginated list of groups that a user is a member of
+// @Description Get paginated list of groups that a user is a member of. Archived groups are left out unless include_archived is set.
 // @Tags groups
 // @Produce json
 // @Param uuid path string true "User UUID"
+// @Param include_archived query bool false "Include archived groups" default(false)
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Success 200 {object} response.APIResponse{data=[]models.Group,meta=response.Meta}
@@ -170,26 +343,29 @@ func (c *GroupController) GetUserGroups(ctx *gin.Context) {
 		}
 	}
 
-	groups, err := c.groupService.GetUserGroups(ctx.Request.Context(), uuid, page, limit)
+	includeArchived := false
+	if includeArchivedStr := ctx.Query("include_archived"); includeArchivedStr != "" {
+		parsed, err := strconv.ParseBool(includeArchivedStr)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError("include_archived", includeArchivedStr))
+			return
+		}
+		includeArchived = parsed
+	}
+
+	groups, total, err := c.groupService.GetUserGroups(ctx.Request.Context(), uuid, page, limit, includeArchived)
 	if err != nil {
 		c.logger.Error("Failed to get user groups", zap.Error(err), zap.String("uuid", uuid))
 		response.Error(ctx, err)
 		return
 	}
 
-	// Create meta information
-	meta := &response.Meta{
-		Page:  page,
-		Limit: limit,
-		Total: len(groups),
-	}
-
-	response.SuccessWithMeta(ctx, groups, meta)
+	response.SuccessWithMeta(ctx, groups, listMeta(ctx, page, limit, total))
 }
 
 // AddMember handles adding a member to a group
 // @Summary Add member to group
-// @Description Add a user as a member of a group
+// @Description Add a user as a member of a group. acting_user_uuid must be a group admin.
 // @Tags groups
 // @Accept json
 // @Produce json
@@ -197,6 +373,7 @@ func (c *GroupController) GetUserGroups(ctx *gin.Context) {
 // @Param member body models.AddMemberRequest true "Add member request"
 // @Success 200 {object} response.APIResponse
 // @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
 // @Failure 409 {object} response.APIResponse
 // @Failure 500 {object} response.APIResponse
@@ -209,9 +386,9 @@ func (c *GroupController) AddMember(ctx *gin.Context) {
 	}
 
 	var req models.AddMemberRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
+	if err := response.BindJSON(ctx, &req); err != nil {
 		c.logger.Error("Invalid request body", zap.Error(err))
-		response.BadRequest(ctx, "Invalid request body")
+		response.Error(ctx, err)
 		return
 	}
 
@@ -225,36 +402,78 @@ func (c *GroupController) AddMember(ctx *gin.Context) {
 	response.Success(ctx, gin.H{"message": "Member added successfully"})
 }
 
+// AddMembers handles adding several members to a group at once
+// @Summary Add members to group
+// @Description Add up to 50 users to a group in one transaction. Every UUID must be valid and belong to an existing user, otherwise nothing is added and the error details name each bad entry. Users already in the group are reported as already_member. acting_user_uuid must be a group admin.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param members body models.AddMembersRequest true "Add members request"
+// @Success 200 {object} response.APIResponse{data=models.AddMembersResponse}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/members/batch [post]
+func (c *GroupController) AddMembers(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Group UUID is required")
+		return
+	}
+
+	var req models.AddMembersRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	result, err := c.groupService.AddMembers(ctx.Request.Context(), uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to add members to group", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
 // RemoveMember handles removing a member from a group
 // @Summary Remove member from group
-// @Description Remove a user from a group
+// @Description Remove a user from a group. Only group admins can remove members. Refused with a validation error while the user has a non-zero balance in the group in any currency, or when they are the group's last admin.
 // @Tags groups
 // @Produce json
 // @Param uuid path string true "Group UUID"
 // @Param userUuid path string true "User UUID"
+// @Param acting_user_uuid query string true "UUID of the admin making the change"
 // @Success 200 {object} response.APIResponse
 // @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
 // @Failure 500 {object} response.APIResponse
 // @Router /api/v1/groups/{uuid}/members/{userUuid} [delete]
 func (c *GroupController) RemoveMember(ctx *gin.Context) {
-	uuid := ctx.Param("uuid")
-	userUuid := ctx.Param("userUuid")
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
 
-	if uuid == "" {
-		response.BadRequest(ctx, "Group UUID is required")
+	userUuid, ok := userUUIDParam(ctx, "userUuid")
+	if !ok {
 		return
 	}
 
-	if userUuid == "" {
-		response.BadRequest(ctx, "User UUID is required")
+	actingUser, ok := actingUserQuery(ctx)
+	if !ok {
 		return
 	}
 
-	err := c.groupService.RemoveMember(ctx.Request.Context(), uuid, userUuid)
+	err := c.groupService.RemoveMember(ctx.Request.Context(), uuid, userUuid, actingUser)
 	if err != nil {
 		c.logger.Error("Failed to remove member from group", zap.Error(err),
-			zap.String("groupUuid", uuid), zap.String("userUuid", userUuid))
+			zap.String("groupUuid", uuid.String()), zap.String("userUuid", userUuid.String()))
 		response.Error(ctx, err)
 		return
 	}
@@ -262,13 +481,130 @@ func (c *GroupController) RemoveMember(ctx *gin.Context) {
 	response.Success(ctx, gin.H{"message": "Member removed successfully"})
 }
 
+// LeaveGroup handles a user removing themselves from a group
+// @Summary Leave a group
+// @Description Remove the given user from the group. Refused with a validation error while the user has a non-zero balance in any currency; the error details list each amount and the simplify-debts path to settle up. The group's last admin must promote another member first.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param request body models.LeaveGroupRequest true "Leaving user"
+// @Success 200 {object} response.APIResponse
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/leave [post]
+func (c *GroupController) LeaveGroup(ctx *gin.Context) {
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	var req models.LeaveGroupRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	if err := c.groupService.LeaveGroup(ctx.Request.Context(), uuid, &req); err != nil {
+		c.logger.Error("Failed to leave group", zap.Error(err),
+			zap.String("groupUuid", uuid.String()), zap.String("userUuid", req.UserUUID))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, gin.H{"message": "Left group successfully"})
+}
+
+// UpdateMemberRole handles promoting or demoting a group member
+// @Summary Change a member's role
+// @Description Make a member an admin or a regular member. Only group admins can change roles, and the group's last admin cannot be demoted.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param userUuid path string true "User UUID"
+// @Param role body models.UpdateMemberRoleRequest true "New role"
+// @Success 200 {object} response.APIResponse{data=models.User}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/members/{userUuid}/role [put]
+func (c *GroupController) UpdateMemberRole(ctx *gin.Context) {
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	userUuid, ok := userUUIDParam(ctx, "userUuid")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateMemberRoleRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	member, err := c.groupService.UpdateMemberRole(ctx.Request.Context(), uuid, userUuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to update member role", zap.Error(err),
+			zap.String("groupUuid", uuid.String()), zap.String("userUuid", userUuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, member)
+}
+
+// TransferOwnership handles handing a group over to another member
+// @Summary Transfer group ownership
+// @Description Make another member the group's owner. Only the current owner can do this; the new owner is also made an admin.
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param transfer body models.TransferOwnershipRequest true "New owner"
+// @Success 200 {object} response.APIResponse{data=models.Group}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/transfer-ownership [post]
+func (c *GroupController) TransferOwnership(ctx *gin.Context) {
+	uuid, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	var req models.TransferOwnershipRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	group, err := c.groupService.TransferOwnership(ctx.Request.Context(), uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to transfer group ownership", zap.Error(err), zap.String("groupUuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, group)
+}
+
 // GetMembers handles retrieval of group members
 // @Summary Get group members
-// @Description Get all members of a group
+// @Description Get all members of a group with their role, joined_at and current balance in the group's default currency
 // @Tags groups
 // @Produce json
 // @Param uuid path string true "Group UUID"
-// @Success 200 {object} response.APIResponse{data=[]models.User}
+// @Success 200 {object} response.APIResponse{data=[]models.GroupMember}
 // @Failure 400 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
 // @Failure 500 {object} response.APIResponse
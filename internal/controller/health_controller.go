@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	serviceName    = "expense-split-tracker"
+	serviceVersion = "1.0.0"
+)
+
+// HealthChecker reports whether a dependency is reachable; *database.DB
+// implements it
+type HealthChecker interface {
+	Health() error
+}
+
+// HealthResponse is the body of the health endpoints. Components is only
+// reported by the readiness check.
+type HealthResponse struct {
+	Status     string            `json:"status" example:"ok"`
+	Service    string            `json:"service" example:"expense-split-tracker"`
+	Version    string            `json:"version" example:"1.0.0"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+type HealthController struct {
+	db     HealthChecker
+	logger *zap.Logger
+}
+
+// NewHealthController creates a new health controller
+func NewHealthController(db HealthChecker, logger *zap.Logger) *HealthController {
+	return &HealthController{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Readiness reports whether the instance can serve traffic
+// @Summary Readiness check
+// @Description Pings the database; returns 503 when it is unreachable so load balancers stop routing here
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Failure 503 {object} HealthResponse
+// @Router /health [get]
+func (c *HealthController) Readiness(ctx *gin.Context) {
+	status := http.StatusOK
+	overall := "ok"
+	database := "ok"
+
+	if err := c.db.Health(); err != nil {
+		c.logger.Error("Health check failed", zap.Error(err))
+		status = http.StatusServiceUnavailable
+		overall = "unavailable"
+		database = "unreachable"
+	}
+
+	ctx.JSON(status, HealthResponse{
+		Status:  overall,
+		Service: serviceName,
+		Version: serviceVersion,
+		Components: map[string]string{
+			"database": database,
+		},
+	})
+}
+
+// Liveness reports that the process is up without touching dependencies
+// @Summary Liveness check
+// @Description Always 200 while the process is running; use it for restart probes
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /health/live [get]
+func (c *HealthController) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, HealthResponse{
+		Status:  "ok",
+		Service: serviceName,
+		Version: serviceVersion,
+	})
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type InsightsController struct {
+	insightsService service.InsightsService
+	logger          *zap.Logger
+}
+
+// NewInsightsController creates a new insights controller
+func NewInsightsController(insightsService service.InsightsService, logger *zap.Logger) *InsightsController {
+	return &InsightsController{
+		insightsService: insightsService,
+		logger:          logger,
+	}
+}
+
+// GetUserInsights handles retrieval of a user's spending insights
+// @Summary Get user spending insights
+// @Description Get a user's spending dashboard across all groups for a month and the five months before it
+// @Tags insights
+// @Produce json
+// @Param uuid path string true "User UUID"
+// @Param month query string false "Month in YYYY-MM format (defaults to the current month)"
+// @Success 200 {object} response.APIResponse{data=models.UserInsights}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/users/{uuid}/insights [get]
+func (c *InsightsController) GetUserInsights(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "User UUID is required")
+		return
+	}
+
+	month := ctx.Query("month")
+
+	insights, err := c.insightsService.GetUserInsights(ctx.Request.Context(), uuid, month)
+	if err != nil {
+		c.logger.Error("Failed to get user insights", zap.Error(err),
+			zap.String("uuid", uuid), zap.String("month", month))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, insights)
+}
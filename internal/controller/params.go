@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// groupUUIDParam reads a group UUID path parameter, writing a 400 response when it is missing
+func groupUUIDParam(ctx *gin.Context, name string) (models.GroupUUID, bool) {
+	value := ctx.Param(name)
+	if value == "" {
+		response.BadRequest(ctx, "Group UUID is required")
+		return "", false
+	}
+	return models.GroupUUID(value), true
+}
+
+// userUUIDParam reads a user UUID path parameter, writing a 400 response when it is missing
+func userUUIDParam(ctx *gin.Context, name string) (models.UserUUID, bool) {
+	value := ctx.Param(name)
+	if value == "" {
+		response.BadRequest(ctx, "User UUID is required")
+		return "", false
+	}
+	return models.UserUUID(value), true
+}
+
+// recurringExpenseUUIDParam reads a recurring expense UUID path parameter,
+// writing a 400 response when it is missing
+func recurringExpenseUUIDParam(ctx *gin.Context, name string) (models.RecurringExpenseUUID, bool) {
+	value := ctx.Param(name)
+	if value == "" {
+		response.BadRequest(ctx, "Recurring expense UUID is required")
+		return "", false
+	}
+	return models.RecurringExpenseUUID(value), true
+}
+
+// webhookUUIDParam reads a webhook UUID path parameter, writing a 400 response
+// when it is missing
+func webhookUUIDParam(ctx *gin.Context, name string) (models.WebhookUUID, bool) {
+	value := ctx.Param(name)
+	if value == "" {
+		response.BadRequest(ctx, "Webhook UUID is required")
+		return "", false
+	}
+	return models.WebhookUUID(value), true
+}
+
+// webhookDeliveryUUIDParam reads a webhook delivery UUID path parameter,
+// writing a 400 response when it is missing
+func webhookDeliveryUUIDParam(ctx *gin.Context, name string) (models.WebhookDeliveryUUID, bool) {
+	value := ctx.Param(name)
+	if value == "" {
+		response.BadRequest(ctx, "Delivery UUID is required")
+		return "", false
+	}
+	return models.WebhookDeliveryUUID(value), true
+}
+
+// actingUserQuery reads the acting_user_uuid query parameter identifying who
+// makes a change, writing a 400 response when it is missing
+func actingUserQuery(ctx *gin.Context) (models.UserUUID, bool) {
+	value := ctx.Query("acting_user_uuid")
+	if value == "" {
+		response.BadRequest(ctx, "acting_user_uuid is required")
+		return "", false
+	}
+	return models.UserUUID(value), true
+}
+
+// listSortParams reads the sort_by and sort_dir query parameters, checking
+// them against the allowed fields and writing a 400 response when invalid
+func listSortParams(ctx *gin.Context, allowed []string) (models.ListSort, bool) {
+	sort := models.ListSort{
+		SortBy:  ctx.Query("sort_by"),
+		SortDir: strings.ToLower(ctx.Query("sort_dir")),
+	}
+
+	if sort.SortBy != "" {
+		valid := false
+		for _, field := range allowed {
+			if sort.SortBy == field {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			appErr := errors.NewInvalidValueError("sort_by", sort.SortBy)
+			appErr.Details = map[string]string{"field": "sort_by", "allowed": strings.Join(allowed, ",")}
+			response.Error(ctx, appErr)
+			return models.ListSort{}, false
+		}
+	}
+
+	if sort.SortDir != "" && sort.SortDir != models.SortAsc && sort.SortDir != models.SortDesc {
+		appErr := errors.NewInvalidValueError("sort_dir", sort.SortDir)
+		appErr.Details = map[string]string{"field": "sort_dir", "allowed": models.SortAsc + "," + models.SortDesc}
+		response.Error(ctx, appErr)
+		return models.ListSort{}, false
+	}
+
+	return sort, true
+}
+
+// dateQuery parses a date filter given either as YYYY-MM-DD or as an RFC3339
+// timestamp for intraday ranges, writing a 400 response when it is malformed
+func dateQuery(ctx *gin.Context, name string) (time.Time, bool) {
+	value := ctx.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, true
+	}
+	if timestamp, err := time.Parse(time.RFC3339, value); err == nil {
+		return timestamp, true
+	}
+	response.BadRequest(ctx, fmt.Sprintf("%s must be a date (YYYY-MM-DD) or an RFC3339 timestamp, got '%s'", name, value))
+	return time.Time{}, false
+}
+
+// dateRangeQuery reads the from_date and to_date filters, writing a 400
+// response when either is malformed or the range is reversed
+func dateRangeQuery(ctx *gin.Context) (from, to time.Time, ok bool) {
+	if from, ok = dateQuery(ctx, "from_date"); !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	if to, ok = dateQuery(ctx, "to_date"); !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		response.BadRequest(ctx, "from_date must not be after to_date")
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// listMeta builds the pagination meta of a list whose total size is known
+func listMeta(ctx *gin.Context, page, limit, total int) *response.Meta {
+	totalPages := 0
+	if limit > 0 {
+		totalPages = (total + limit - 1) / limit
+	}
+
+	return &response.Meta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+		Links:      response.BuildLinks(ctx, response.Pagination{Page: page, HasNext: page < totalPages}),
+	}
+}
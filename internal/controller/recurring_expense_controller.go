@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RecurringExpenseController struct {
+	recurringService service.RecurringExpenseService
+	logger           *zap.Logger
+}
+
+// NewRecurringExpenseController creates a new recurring expense controller
+func NewRecurringExpenseController(recurringService service.RecurringExpenseService, logger *zap.Logger) *RecurringExpenseController {
+	return &RecurringExpenseController{
+		recurringService: recurringService,
+		logger:           logger,
+	}
+}
+
+// CreateRecurringExpense handles creating a recurring expense
+// @Summary Create a recurring expense
+// @Description Create an expense template that is added to the group every week or month, starting at next_run_at (default now)
+// @Tags recurring-expenses
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param recurring body models.CreateRecurringExpenseRequest true "Recurring expense request"
+// @Success 201 {object} response.APIResponse{data=models.RecurringExpense}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/recurring-expenses [post]
+func (c *RecurringExpenseController) CreateRecurringExpense(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	var req models.CreateRecurringExpenseRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	recurring, err := c.recurringService.CreateRecurringExpense(ctx.Request.Context(), groupUUID, &req)
+	if err != nil {
+		c.logger.Error("Failed to create recurring expense", zap.Error(err), zap.String("groupUUID", groupUUID.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Created(ctx, recurring)
+}
+
+// ListRecurringExpenses handles listing a group's recurring expenses
+// @Summary List recurring expenses
+// @Description Get every recurring expense of a group, active or paused
+// @Tags recurring-expenses
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Success 200 {object} response.APIResponse{data=[]models.RecurringExpense}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/recurring-expenses [get]
+func (c *RecurringExpenseController) ListRecurringExpenses(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	recurring, err := c.recurringService.ListRecurringExpenses(ctx.Request.Context(), groupUUID)
+	if err != nil {
+		c.logger.Error("Failed to list recurring expenses", zap.Error(err), zap.String("groupUUID", groupUUID.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, recurring)
+}
+
+// GetRecurringExpense handles recurring expense retrieval by UUID
+// @Summary Get recurring expense
+// @Description Get a recurring expense of a group by UUID
+// @Tags recurring-expenses
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param recurringUuid path string true "Recurring expense UUID"
+// @Success 200 {object} response.APIResponse{data=models.RecurringExpense}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/recurring-expenses/{recurringUuid} [get]
+func (c *RecurringExpenseController) GetRecurringExpense(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+	uuid, ok := recurringExpenseUUIDParam(ctx, "recurringUuid")
+	if !ok {
+		return
+	}
+
+	recurring, err := c.recurringService.GetRecurringExpense(ctx.Request.Context(), groupUUID, uuid)
+	if err != nil {
+		c.logger.Error("Failed to get recurring expense", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, recurring)
+}
+
+// UpdateRecurringExpense handles replacing a recurring expense
+// @Summary Update recurring expense
+// @Description Replace the template of a recurring expense. next_run_at reschedules it and active pauses or resumes it; expenses already created are unchanged.
+// @Tags recurring-expenses
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param recurringUuid path string true "Recurring expense UUID"
+// @Param recurring body models.UpdateRecurringExpenseRequest true "Recurring expense update request"
+// @Success 200 {object} response.APIResponse{data=models.RecurringExpense}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/recurring-expenses/{recurringUuid} [put]
+func (c *RecurringExpenseController) UpdateRecurringExpense(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+	uuid, ok := recurringExpenseUUIDParam(ctx, "recurringUuid")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateRecurringExpenseRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	recurring, err := c.recurringService.UpdateRecurringExpense(ctx.Request.Context(), groupUUID, uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to update recurring expense", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, recurring)
+}
+
+// DeleteRecurringExpense handles deleting a recurring expense
+// @Summary Delete recurring expense
+// @Description Stop and delete a recurring expense; expenses it already created are kept
+// @Tags recurring-expenses
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param recurringUuid path string true "Recurring expense UUID"
+// @Success 200 {object} response.APIResponse
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/recurring-expenses/{recurringUuid} [delete]
+func (c *RecurringExpenseController) DeleteRecurringExpense(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+	uuid, ok := recurringExpenseUUIDParam(ctx, "recurringUuid")
+	if !ok {
+		return
+	}
+
+	err := c.recurringService.DeleteRecurringExpense(ctx.Request.Context(), groupUUID, uuid)
+	if err != nil {
+		c.logger.Error("Failed to delete recurring expense", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, gin.H{"message": "Recurring expense deleted successfully"})
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ReportController struct {
+	reportService service.ReportService
+	logger        *zap.Logger
+}
+
+// NewReportController creates a new report controller
+func NewReportController(reportService service.ReportService, logger *zap.Logger) *ReportController {
+	return &ReportController{
+		reportService: reportService,
+		logger:        logger,
+	}
+}
+
+// GetCategoryReport handles the category spending breakdown of a group
+// @Summary Get category spending breakdown
+// @Description Get a group's expense total, count and percentage of overall spend per category, for each currency, largest category first. Expenses without a category are reported as uncategorized.
+// @Tags reports
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param from_date query string false "Include expenses from this date (YYYY-MM-DD or RFC3339)"
+// @Param to_date query string false "Include expenses up to and including this date (YYYY-MM-DD or RFC3339)"
+// @Param currency query string false "Only report this currency"
+// @Success 200 {object} response.APIResponse{data=models.CategoryReport}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/reports/categories [get]
+func (c *ReportController) GetCategoryReport(ctx *gin.Context) {
+	groupUUID := ctx.Param("uuid")
+	if groupUUID == "" {
+		response.BadRequest(ctx, "Group UUID is required")
+		return
+	}
+
+	filter := &models.CategoryReportFilter{Currency: ctx.Query("currency")}
+	var ok bool
+	filter.FromDate, filter.ToDate, ok = dateRangeQuery(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := c.reportService.GetCategoryReport(ctx.Request.Context(), groupUUID, filter)
+	if err != nil {
+		c.logger.Error("Failed to get category report", zap.Error(err), zap.String("groupUUID", groupUUID))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, report)
+}
+
+// GetUserStats handles a user's spending statistics
+// @Summary Get user spending statistics
+// @Description Summarize a user's spending across all their groups, per currency: total paid, total share, the number of expenses they paid for or share in, confirmed settlements sent and received, their largest expense and the paid and share averages per calendar month
+// @Tags reports
+// @Produce json
+// @Param uuid path string true "User UUID"
+// @Param from_date query string false "Include activity from this date (YYYY-MM-DD or RFC3339)"
+// @Param to_date query string false "Include activity up to and including this date (YYYY-MM-DD or RFC3339)"
+// @Success 200 {object} response.APIResponse{data=models.UserStats}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/users/{uuid}/stats [get]
+func (c *ReportController) GetUserStats(ctx *gin.Context) {
+	userUUID := ctx.Param("uuid")
+	if userUUID == "" {
+		response.BadRequest(ctx, "User UUID is required")
+		return
+	}
+
+	filter := &models.UserStatsFilter{}
+	var ok bool
+	filter.FromDate, filter.ToDate, ok = dateRangeQuery(ctx)
+	if !ok {
+		return
+	}
+
+	stats, err := c.reportService.GetUserStats(ctx.Request.Context(), userUUID, filter)
+	if err != nil {
+		c.logger.Error("Failed to get user stats", zap.Error(err), zap.String("userUUID", userUUID))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, stats)
+}
@@ -2,13 +2,14 @@ package controller
 
 import (
 	"strconv"
-	"time"
 
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
 	"expense-split-tracker/pkg/response"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
@@ -27,7 +28,7 @@ func NewSettlementController(settlementService service.SettlementService, logger
 
 // CreateSettlement handles settlement creation
 // @Summary Create a new settlement
-// @Description Create a new settlement (debt payment) between users
+// @Description Create a new settlement (debt payment) between users. With require_confirmation the settlement stays pending and balances are unchanged until the receiver confirms it.
 // @Tags settlements
 // @Accept json
 // @Produce json
@@ -39,9 +40,9 @@ func NewSettlementController(settlementService service.SettlementService, logger
 // @Router /api/v1/settlements [post]
 func (c *SettlementController) CreateSettlement(ctx *gin.Context) {
 	var req models.CreateSettlementRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
+	if err := response.BindJSON(ctx, &req); err != nil {
 		c.logger.Error("Invalid request body", zap.Error(err))
-		response.BadRequest(ctx, "Invalid request body")
+		response.Error(ctx, err)
 		return
 	}
 
@@ -83,6 +84,115 @@ func (c *SettlementController) GetSettlement(ctx *gin.Context) {
 	response.Success(ctx, settlement)
 }
 
+// VoidSettlement handles voiding a settlement recorded by mistake
+// @Summary Void a settlement
+// @Description Mark a settlement as voided and reverse its effect on both users' balances. The settlement is kept for history but hidden from lists unless include_voided is set.
+// @Tags settlements
+// @Produce json
+// @Param uuid path string true "Settlement UUID"
+// @Success 200 {object} response.APIResponse{data=models.Settlement}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 409 {object} response.APIResponse
+// @Failure 423 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/settlements/{uuid} [delete]
+func (c *SettlementController) VoidSettlement(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Settlement UUID is required")
+		return
+	}
+
+	settlement, err := c.settlementService.VoidSettlement(ctx.Request.Context(), uuid)
+	if err != nil {
+		c.logger.Error("Failed to void settlement", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, settlement)
+}
+
+// ConfirmSettlement handles the receiver confirming a pending settlement
+// @Summary Confirm a settlement
+// @Description Confirm receipt of a pending settlement and apply it to both users' balances. Only the settlement's receiver may confirm it.
+// @Tags settlements
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Settlement UUID"
+// @Param response body models.RespondSettlementRequest true "Receiving user"
+// @Success 200 {object} response.APIResponse{data=models.Settlement}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 409 {object} response.APIResponse
+// @Failure 423 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/settlements/{uuid}/confirm [post]
+func (c *SettlementController) ConfirmSettlement(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Settlement UUID is required")
+		return
+	}
+
+	var req models.RespondSettlementRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	settlement, err := c.settlementService.ConfirmSettlement(ctx.Request.Context(), uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to confirm settlement", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, settlement)
+}
+
+// RejectSettlement handles the receiver rejecting a pending settlement
+// @Summary Reject a settlement
+// @Description Reject a pending settlement that was never received. Balances are left unchanged. Only the settlement's receiver may reject it.
+// @Tags settlements
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Settlement UUID"
+// @Param response body models.RespondSettlementRequest true "Receiving user"
+// @Success 200 {object} response.APIResponse{data=models.Settlement}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 409 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/settlements/{uuid}/reject [post]
+func (c *SettlementController) RejectSettlement(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Settlement UUID is required")
+		return
+	}
+
+	var req models.RespondSettlementRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	settlement, err := c.settlementService.RejectSettlement(ctx.Request.Context(), uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to reject settlement", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, settlement)
+}
+
 // ListSettlements handles settlement listing with filtering
 // @Summary List settlements
 // @Description Get paginated list of settlements with optional filtering
@@ -93,11 +203,16 @@ func (c *SettlementController) GetSettlement(ctx *gin.Context) {
 // @Param from_user_uuid query string false "Filter by from user UUID"
 // @Param to_user_uuid query string false "Filter by to user UUID"
 // @Param currency query string false "Filter by currency"
-// @Param from_date query string false "Filter from date (YYYY-MM-DD)"
-// @Param to_date query string false "Filter to date (YYYY-MM-DD)"
+// @Param from_date query string false "Filter from date (YYYY-MM-DD or RFC3339)"
+// @Param to_date query string false "Filter to date (YYYY-MM-DD or RFC3339)"
+// @Param status query string false "Filter by status" Enums(pending, confirmed, rejected)
+// @Param method query string false "Filter by payment method" Enums(cash, bank_transfer, upi, paypal, venmo, other)
+// @Param include_voided query bool false "Include voided settlements" default(false)
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
-// @Success 200 {object} response.APIResponse{data=models.SettlementListResponse}
+// @Param sort_by query string false "Sort field (default created_at)" Enums(created_at, amount, description)
+// @Param sort_dir query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Success 200 {object} response.APIResponse{data=models.SettlementListResponse,meta=response.Meta}
 // @Failure 400 {object} response.APIResponse
 // @Failure 500 {object} response.APIResponse
 // @Router /api/v1/settlements [get]
@@ -113,17 +228,41 @@ func (c *SettlementController) ListSettlements(ctx *gin.Context) {
 		Limit:        10,
 	}
 
+	sort, ok := listSortParams(ctx, models.SettlementSortFields)
+	if !ok {
+		return
+	}
+	filter.ListSort = sort
+
 	// Parse dates
-	if fromDateStr := ctx.Query("from_date"); fromDateStr != "" {
-		if fromDate, err := time.Parse("2006-01-02", fromDateStr); err == nil {
-			filter.FromDate = fromDate
+	filter.FromDate, filter.ToDate, ok = dateRangeQuery(ctx)
+	if !ok {
+		return
+	}
+
+	if status := ctx.Query("status"); status != "" {
+		filter.Status = models.SettlementStatus(status)
+		if err := filter.Status.Validate(); err != nil {
+			response.Error(ctx, err)
+			return
+		}
+	}
+
+	if method := ctx.Query("method"); method != "" {
+		filter.Method = models.SettlementMethod(method)
+		if err := filter.Method.Validate(); err != nil {
+			response.Error(ctx, err)
+			return
 		}
 	}
 
-	if toDateStr := ctx.Query("to_date"); toDateStr != "" {
-		if toDate, err := time.Parse("2006-01-02", toDateStr); err == nil {
-			filter.ToDate = toDate
+	if includeVoidedStr := ctx.Query("include_voided"); includeVoidedStr != "" {
+		includeVoided, err := strconv.ParseBool(includeVoidedStr)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError("include_voided", includeVoidedStr))
+			return
 		}
+		filter.IncludeVoided = includeVoided
 	}
 
 	// Parse pagination
@@ -146,7 +285,7 @@ func (c *SettlementController) ListSettlements(ctx *gin.Context) {
 		return
 	}
 
-	response.Success(ctx, settlementResponse)
+	response.SuccessWithMeta(ctx, settlementResponse, listMeta(ctx, settlementResponse.Page, settlementResponse.Limit, settlementResponse.TotalCount))
 }
 
 // GetGroupSettlements handles retrieval of settlements for a specific group
@@ -185,21 +324,14 @@ func (c *SettlementController) GetGroupSettlements(ctx *gin.Context) {
 		}
 	}
 
-	settlements, err := c.settlementService.GetGroupSettlements(ctx.Request.Context(), uuid, page, limit)
+	settlements, total, err := c.settlementService.GetGroupSettlements(ctx.Request.Context(), uuid, page, limit)
 	if err != nil {
 		c.logger.Error("Failed to get group settlements", zap.Error(err), zap.String("uuid", uuid))
 		response.Error(ctx, err)
 		return
 	}
 
-	// Create meta information
-	meta := &response.Meta{
-		Page:  page,
-		Limit: limit,
-		Total: len(settlements),
-	}
-
-	response.SuccessWithMeta(ctx, settlements, meta)
+	response.SuccessWithMeta(ctx, settlements, listMeta(ctx, page, limit, total))
 }
 
 // GetUserSettlements handles retrieval of settlements for a specific user
@@ -238,29 +370,25 @@ func (c *SettlementController) GetUserSettlements(ctx *gin.Context) {
 		}
 	}
 
-	settlements, err := c.settlementService.GetUserSettlements(ctx.Request.Context(), uuid, page, limit)
+	settlements, total, err := c.settlementService.GetUserSettlements(ctx.Request.Context(), uuid, page, limit)
 	if err != nil {
 		c.logger.Error("Failed to get user settlements", zap.Error(err), zap.String("uuid", uuid))
 		response.Error(ctx, err)
 		return
 	}
 
-	// Create meta information
-	meta := &response.Meta{
-		Page:  page,
-		Limit: limit,
-		Total: len(settlements),
-	}
-
-	response.SuccessWithMeta(ctx, settlements, meta)
+	response.SuccessWithMeta(ctx, settlements, listMeta(ctx, page, limit, total))
 }
 
 // SimplifyDebts handles debt simplification for a group
 // @Summary Simplify group debts
-// @Description Get debt simplification suggestions for a group
+// @Description Get debt simplification suggestions for a group, with a hash identifying them for the execute endpoint
 // @Tags settlements
 // @Produce json
 // @Param uuid path string true "Group UUID"
+// @Param currency query string false "Currency (default: the group's default currency)"
+// @Param mode query string false "Algorithm: greedy (default) or optimal, which finds the fewest transfers for up to 15 balances and falls back to greedy beyond that"
+// @Param min_amount query string false "Smallest suggestion to make (default: the currency's smallest unit, e.g. 0.01)"
 // @Success 200 {object} response.APIResponse{data=models.DebtSimplification}
 // @Failure 400 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
@@ -273,7 +401,20 @@ func (c *SettlementController) SimplifyDebts(ctx *gin.Context) {
 		return
 	}
 
-	simplification, err := c.settlementService.SimplifyDebts(ctx.Request.Context(), uuid)
+	req := &models.SimplifyDebtsRequest{
+		Currency: ctx.Query("currency"),
+		Mode:     models.SimplificationMode(ctx.Query("mode")),
+	}
+	if minAmountStr := ctx.Query("min_amount"); minAmountStr != "" {
+		minAmount, err := decimal.NewFromString(minAmountStr)
+		if err != nil {
+			response.Error(ctx, errors.NewInvalidValueError("min_amount", minAmountStr))
+			return
+		}
+		req.MinAmount = &minAmount
+	}
+
+	simplification, err := c.settlementService.SimplifyDebts(ctx.Request.Context(), uuid, req)
 	if err != nil {
 		c.logger.Error("Failed to simplify debts", zap.Error(err), zap.String("uuid", uuid))
 		response.Error(ctx, err)
@@ -282,3 +423,78 @@ func (c *SettlementController) SimplifyDebts(ctx *gin.Context) {
 
 	response.Success(ctx, simplification)
 }
+
+// ExecuteDebtSimplification handles recording all debt simplification suggestions
+// @Summary Execute debt simplification
+// @Description Recompute the group's debt simplification suggestions and record each one as a settlement in a single transaction. Pass the hash from the simplify-debts preview as expected_hash to get a 409 instead if balances have changed since.
+// @Tags settlements
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param execution body models.ExecuteSimplificationRequest false "Currency and expected suggestions"
+// @Success 201 {object} response.APIResponse{data=[]models.Settlement}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 409 {object} response.APIResponse
+// @Failure 423 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/simplify-debts/execute [post]
+func (c *SettlementController) ExecuteDebtSimplification(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	if uuid == "" {
+		response.BadRequest(ctx, "Group UUID is required")
+		return
+	}
+
+	// The body is optional; without expected_hash the current suggestions are executed
+	var req models.ExecuteSimplificationRequest
+	if err := response.BindOptionalJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	settlements, err := c.settlementService.ExecuteDebtSimplification(ctx.Request.Context(), uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to execute debt simplification", zap.Error(err), zap.String("uuid", uuid))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Created(ctx, settlements)
+}
+
+// SettleAllForUser handles paying off everything a user owes in a group
+// @Summary Settle all debts of a user
+// @Description Record one settlement from the user to each member they owe, for the full pairwise debt, and update balances in a single transaction. Fails with 400 if the user is owed money overall or owes nobody.
+// @Tags settlements
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param userUuid path string true "User UUID"
+// @Param currency query string false "Currency to settle (defaults to the group's default currency)"
+// @Success 201 {object} response.APIResponse{data=[]models.Settlement}
+// @Failure 400 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 423 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/users/{userUuid}/settle-all [post]
+func (c *SettlementController) SettleAllForUser(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+	userUUID, ok := userUUIDParam(ctx, "userUuid")
+	if !ok {
+		return
+	}
+
+	settlements, err := c.settlementService.SettleAllForUser(ctx.Request.Context(), groupUUID, userUUID, ctx.Query("currency"))
+	if err != nil {
+		c.logger.Error("Failed to settle all debts", zap.Error(err),
+			zap.String("groupUUID", groupUUID.String()), zap.String("userUUID", userUUID.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Created(ctx, settlements)
+}
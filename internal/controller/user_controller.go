@@ -38,9 +38,9 @@ func NewUserController(userService service.UserService, logger *zap.Logger) *Use
 // @Router /api/v1/users [post]
 func (c *UserController) CreateUser(ctx *gin.Context) {
 	var req models.CreateUserRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
+	if err := response.BindJSON(ctx, &req); err != nil {
 		c.logger.Error("Invalid request body", zap.Error(err))
-		response.BadRequest(ctx, "Invalid request body")
+		response.Error(ctx, err)
 		return
 	}
 
@@ -84,9 +84,10 @@ func (c *UserController) GetUser(ctx *gin.Context) {
 
 // ListUsers handles user listing with pagination
 // @Summary List users
-// @Description Get paginated list of users
+// @Description Get paginated list of users, optionally filtered by q
 // @Tags users
 // @Produce json
+// @Param q query string false "Case-insensitive name or email substring; shorter than 2 characters lists all users"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Success 200 {object} response.APIResponse{data=[]models.User,meta=response.Meta}
@@ -110,21 +111,43 @@ func (c *UserController) ListUsers(ctx *gin.Context) {
 		}
 	}
 
-	users, err := c.userService.ListUsers(ctx.Request.Context(), page, limit)
+	users, total, err := c.userService.SearchUsers(ctx.Request.Context(), ctx.Query("q"), page, limit)
 	if err != nil {
 		c.logger.Error("Failed to list users", zap.Error(err))
 		response.Error(ctx, err)
 		return
 	}
 
-	// Create meta information
-	meta := &response.Meta{
-		Page:  page,
-		Limit: limit,
-		Total: len(users), // This would ideally come from the service with a count query
+	response.SuccessWithMeta(ctx, users, listMeta(ctx, page, limit, total))
+}
+
+// LookupUsers handles fetching several users by UUID
+// @Summary Look up users
+// @Description Get several users by UUID in one request, in the order asked for. Unknown UUIDs are left out; at most 100 UUIDs per request.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param lookup body models.LookupUsersRequest true "User UUIDs"
+// @Success 200 {object} response.APIResponse{data=[]models.User}
+// @Failure 400 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/users/lookup [post]
+func (c *UserController) LookupUsers(ctx *gin.Context) {
+	var req models.LookupUsersRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	users, err := c.userService.LookupUsers(ctx.Request.Context(), req.UUIDs)
+	if err != nil {
+		c.logger.Error("Failed to look up users", zap.Error(err))
+		response.Error(ctx, err)
+		return
 	}
 
-	response.SuccessWithMeta(ctx, users, meta)
+	response.Success(ctx, users)
 }
 
 // GetUserByEmail handles user retrieval by email
@@ -132,7 +155,7 @@ func (c *UserController) ListUsers(ctx *gin.Context) {
 // @Description Get user details by email address
 // @Tags users
 // @Produce json
-// @Param email query string true "User email"
+// @Param email query string true "User email" format(email)
 // @Success 200 {object} response.APIResponse{data=models.User}
 // @Failure 400 {object} response.APIResponse
 // @Failure 404 {object} response.APIResponse
@@ -0,0 +1,268 @@
+package controller
+
+import (
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type WebhookController struct {
+	webhookService service.WebhookService
+	logger         *zap.Logger
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(webhookService service.WebhookService, logger *zap.Logger) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+		logger:         logger,
+	}
+}
+
+// CreateWebhook handles registering a webhook
+// @Summary Create a webhook
+// @Description Register a URL that receives signed JSON posts when the group's expenses or settlements are created or voided. The secret keys the X-Webhook-Signature HMAC-SHA256 header and is never returned.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param webhook body models.CreateWebhookRequest true "Webhook request"
+// @Success 201 {object} response.APIResponse{data=models.Webhook}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/webhooks [post]
+func (c *WebhookController) CreateWebhook(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	webhook, err := c.webhookService.CreateWebhook(ctx.Request.Context(), groupUUID, &req)
+	if err != nil {
+		c.logger.Error("Failed to create webhook", zap.Error(err), zap.String("groupUUID", groupUUID.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Created(ctx, webhook)
+}
+
+// ListWebhooks handles listing a group's webhooks
+// @Summary List webhooks
+// @Description Get every webhook of a group, active or not
+// @Tags webhooks
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Success 200 {object} response.APIResponse{data=[]models.Webhook}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/webhooks [get]
+func (c *WebhookController) ListWebhooks(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+
+	webhooks, err := c.webhookService.ListWebhooks(ctx.Request.Context(), groupUUID)
+	if err != nil {
+		c.logger.Error("Failed to list webhooks", zap.Error(err), zap.String("groupUUID", groupUUID.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, webhooks)
+}
+
+// GetWebhook handles webhook retrieval by UUID
+// @Summary Get webhook
+// @Description Get a webhook of a group by UUID with its latest 50 delivery attempts, newest first: the delivery, attempt number, response status_code, outcome and latency_ms
+// @Tags webhooks
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param webhookUuid path string true "Webhook UUID"
+// @Success 200 {object} response.APIResponse{data=models.Webhook}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/webhooks/{webhookUuid} [get]
+func (c *WebhookController) GetWebhook(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+	uuid, ok := webhookUUIDParam(ctx, "webhookUuid")
+	if !ok {
+		return
+	}
+
+	webhook, err := c.webhookService.GetWebhook(ctx.Request.Context(), groupUUID, uuid)
+	if err != nil {
+		c.logger.Error("Failed to get webhook", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, webhook)
+}
+
+// UpdateWebhook handles replacing a webhook
+// @Summary Update webhook
+// @Description Replace a webhook's URL and event types. An omitted secret keeps the current one; active pauses or resumes deliveries.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param webhookUuid path string true "Webhook UUID"
+// @Param webhook body models.UpdateWebhookRequest true "Webhook update request"
+// @Success 200 {object} response.APIResponse{data=models.Webhook}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/webhooks/{webhookUuid} [put]
+func (c *WebhookController) UpdateWebhook(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+	uuid, ok := webhookUUIDParam(ctx, "webhookUuid")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := response.BindJSON(ctx, &req); err != nil {
+		c.logger.Error("Invalid request body", zap.Error(err))
+		response.Error(ctx, err)
+		return
+	}
+
+	webhook, err := c.webhookService.UpdateWebhook(ctx.Request.Context(), groupUUID, uuid, &req)
+	if err != nil {
+		c.logger.Error("Failed to update webhook", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, webhook)
+}
+
+// DeleteWebhook handles deleting a webhook
+// @Summary Delete webhook
+// @Description Delete a webhook; deliveries already queued are still attempted
+// @Tags webhooks
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param webhookUuid path string true "Webhook UUID"
+// @Success 200 {object} response.APIResponse
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/webhooks/{webhookUuid} [delete]
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+	uuid, ok := webhookUUIDParam(ctx, "webhookUuid")
+	if !ok {
+		return
+	}
+
+	err := c.webhookService.DeleteWebhook(ctx.Request.Context(), groupUUID, uuid)
+	if err != nil {
+		c.logger.Error("Failed to delete webhook", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// ListDeadLetters handles listing a webhook's dead-lettered deliveries
+// @Summary List dead-lettered deliveries
+// @Description Get the deliveries of a webhook that failed every retry, newest first, with their payload and last error
+// @Tags webhooks
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param webhookUuid path string true "Webhook UUID"
+// @Success 200 {object} response.APIResponse{data=[]models.WebhookDelivery}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 500 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/webhooks/{webhookUuid}/dead-letters [get]
+func (c *WebhookController) ListDeadLetters(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+	uuid, ok := webhookUUIDParam(ctx, "webhookUuid")
+	if !ok {
+		return
+	}
+
+	deliveries, err := c.webhookService.ListDeadLetters(ctx.Request.Context(), groupUUID, uuid)
+	if err != nil {
+		c.logger.Error("Failed to list dead-lettered deliveries", zap.Error(err), zap.String("uuid", uuid.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, deliveries)
+}
+
+// RedeliverDeadLetter handles sending a dead-lettered delivery again
+// @Summary Redeliver a dead-lettered delivery
+// @Description Queue a dead-lettered delivery to be sent again with its original body and X-Webhook-Delivery id, signed with the webhook's current secret. The delivery is returned as pending and is dead-lettered again if every retry fails.
+// @Tags webhooks
+// @Produce json
+// @Param uuid path string true "Group UUID"
+// @Param webhookUuid path string true "Webhook UUID"
+// @Param deliveryUuid path string true "Delivery UUID"
+// @Success 200 {object} response.APIResponse{data=models.WebhookDelivery}
+// @Failure 400 {object} response.APIResponse
+// @Failure 403 {object} response.APIResponse
+// @Failure 404 {object} response.APIResponse
+// @Failure 409 {object} response.APIResponse
+// @Failure 503 {object} response.APIResponse
+// @Router /api/v1/groups/{uuid}/webhooks/{webhookUuid}/dead-letters/{deliveryUuid}/redeliver [post]
+func (c *WebhookController) RedeliverDeadLetter(ctx *gin.Context) {
+	groupUUID, ok := groupUUIDParam(ctx, "uuid")
+	if !ok {
+		return
+	}
+	uuid, ok := webhookUUIDParam(ctx, "webhookUuid")
+	if !ok {
+		return
+	}
+	deliveryUUID, ok := webhookDeliveryUUIDParam(ctx, "deliveryUuid")
+	if !ok {
+		return
+	}
+
+	delivery, err := c.webhookService.RedeliverDeadLetter(ctx.Request.Context(), groupUUID, uuid, deliveryUUID)
+	if err != nil {
+		c.logger.Error("Failed to redeliver webhook delivery", zap.Error(err), zap.String("delivery", deliveryUUID.String()))
+		response.Error(ctx, err)
+		return
+	}
+
+	response.Success(ctx, delivery)
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
@@ -16,11 +18,18 @@ import (
 type DB struct {
 	*sqlx.DB
 	logger *zap.Logger
+
+	// statementTimeout bounds each repository call; zero leaves it unbounded
+	statementTimeout time.Duration
+
+	// pinned keeps an in-memory SQLite database alive while the pool
+	// discards connections, as it does after a cancelled transaction
+	pinned *sql.Conn
 }
 
-// NewConnection creates a new database connection
+// NewConnection creates a new database connection using the configured driver
 func NewConnection(cfg *config.Config, logger *zap.Logger) (*DB, error) {
-	db, err := sqlx.Connect("mysql", cfg.Database.DSN)
+	db, err := sqlx.Connect(cfg.Database.Driver, cfg.Database.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -35,23 +44,46 @@ func NewConnection(cfg *config.Config, logger *zap.Logger) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	logger.Info("Database connection established successfully")
+	conn := &DB{
+		DB:               db,
+		logger:           logger,
+		statementTimeout: cfg.Database.StatementTimeout,
+	}
+	if cfg.Database.Driver == DriverSQLite {
+		if err := conn.openSQLite(context.Background()); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
 
-	return &DB{
-		DB:     db,
-		logger: logger,
-	}, nil
+	logger.Info("Database connection established successfully", zap.String("driver", cfg.Database.Driver))
+
+	return conn, nil
 }
 
 // Close closes the database connection
 func (db *DB) Close() error {
 	db.logger.Info("Closing database connection")
+	if db.pinned != nil {
+		db.pinned.Close()
+	}
 	return db.DB.Close()
 }
 
-// BeginTx starts a new transaction
-func (db *DB) BeginTx() (*Tx, error) {
-	tx, err := db.DB.Beginx()
+// StatementContext derives the context a repository method runs its
+// statements with, cancelled once the configured statement timeout passes.
+// Callers must call the returned cancel function when the method returns.
+func (db *DB) StatementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.statementTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.statementTimeout)
+}
+
+// BeginTx starts a new transaction that is rolled back if ctx is cancelled
+// before it commits
+func (db *DB) BeginTx(ctx context.Context) (*Tx, error) {
+	tx, err := db.DB.BeginTxx(ctx, nil)
 	if err != nil {
 		db.logger.Error("Failed to begin transaction", zap.Error(err))
 		return nil, err
@@ -91,9 +123,12 @@ func (tx *Tx) Rollback() error {
 	return err
 }
 
-// WithTransaction executes a function within a database transaction
-func (db *DB) WithTransaction(fn func(*Tx) error) error {
-	tx, err := db.BeginTx()
+// WithTransaction executes a function within a database transaction.
+// The transaction is committed only if fn returns nil; a failed commit is
+// reported to the caller just like an error returned from fn. Cancelling ctx,
+// as a client disconnecting does to a request's context, rolls it back.
+func (db *DB) WithTransaction(ctx context.Context, fn func(*Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx)
 	if err != nil {
 		return err
 	}
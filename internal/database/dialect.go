@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Supported values of DB_DRIVER
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Driver returns the name of the driver the connection was opened with
+func (db *DB) Driver() string {
+	return db.DriverName()
+}
+
+// Quote quotes an identifier for the driver, for table names such as groups
+// that are reserved words
+func (db *DB) Quote(identifier string) string {
+	if db.Driver() == DriverMySQL {
+		return "`" + identifier + "`"
+	}
+	return `"` + identifier + `"`
+}
+
+// OnConflictUpdate returns the clause that follows an INSERT's VALUES so an
+// insert clashing with an existing row on the unique key made of columns
+// updates that row instead. set is the comma-separated assignment list; it
+// refers to the values the INSERT proposed through Excluded and to the
+// existing row by qualifying columns with the table name.
+func (db *DB) OnConflictUpdate(columns []string, set string) string {
+	if db.Driver() == DriverMySQL {
+		return "ON DUPLICATE KEY UPDATE " + set
+	}
+	return "ON CONFLICT (" + strings.Join(columns, ", ") + ") DO UPDATE SET " + set
+}
+
+// Excluded refers to the value an upsert proposed for column
+func (db *DB) Excluded(column string) string {
+	if db.Driver() == DriverMySQL {
+		return "VALUES(" + column + ")"
+	}
+	return "EXCLUDED." + column
+}
+
+// ForUpdate returns the locking clause that ends a SELECT whose rows must
+// stay locked until the transaction ends, limited to tables when given.
+// SQLite has no row locks; its transactions take the database write lock
+// when they begin, which covers what FOR UPDATE would.
+func (db *DB) ForUpdate(tables ...string) string {
+	if db.Driver() == DriverSQLite {
+		return ""
+	}
+	if len(tables) > 0 {
+		return "FOR UPDATE OF " + strings.Join(tables, ", ")
+	}
+	return "FOR UPDATE"
+}
+
+// InsertReturningID runs an INSERT, inside tx when it is not nil, and returns
+// the id generated for the new row. Postgres has no LastInsertId, so it gets
+// a RETURNING clause instead.
+func (db *DB) InsertReturningID(ctx context.Context, tx *Tx, query string, args ...interface{}) (int64, error) {
+	if db.Driver() == DriverPostgres {
+		var id int64
+		query = strings.TrimRight(query, " \t\n") + " RETURNING id"
+
+		var row *sql.Row
+		if tx != nil {
+			row = tx.QueryRowContext(ctx, query, args...)
+		} else {
+			row = db.QueryRowContext(ctx, query, args...)
+		}
+		if err := row.Scan(&id); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, query, args...)
+	} else {
+		result, err = db.ExecContext(ctx, query, args...)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return id, nil
+}
+
+// ScanTime returns a Scanner that stores a timestamp into dest. Use it for
+// aggregates such as MIN(created_at): SQLite gives those no column type, so
+// they arrive as text rather than as a time.
+func ScanTime(dest *time.Time) sql.Scanner {
+	return timeScanner{dest: dest}
+}
+
+type timeScanner struct {
+	dest *time.Time
+}
+
+func (s timeScanner) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		*s.dest = v
+		return nil
+	case []byte:
+		return s.parse(string(v))
+	case string:
+		return s.parse(v)
+	case nil:
+		*s.dest = time.Time{}
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into a time", value)
+}
+
+func (s timeScanner) parse(value string) error {
+	value = strings.TrimSuffix(value, "Z")
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(format, value, time.UTC); err == nil {
+			*s.dest = t
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as a time", value)
+}
+
+// bindArgs adapts statement arguments to the driver. SQLite stores times as
+// text in the time's own zone, which would compare out of order with
+// CURRENT_TIMESTAMP, so they are bound in UTC.
+func bindArgs(driver string, args []interface{}) []interface{} {
+	if driver != DriverSQLite {
+		return args
+	}
+	bound := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			bound[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				bound[i] = v.UTC()
+			} else {
+				bound[i] = arg
+			}
+		default:
+			bound[i] = arg
+		}
+	}
+	return bound
+}
+
+// The statement methods below shadow sqlx's so repositories can write every
+// query with ? placeholders; they are rebound to the driver's bind style.
+
+// ExecContext executes a statement
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.Rebind(query), bindArgs(db.Driver(), args)...)
+}
+
+// QueryContext runs a query returning rows
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.Rebind(query), bindArgs(db.Driver(), args)...)
+}
+
+// QueryRowContext runs a query returning at most one row
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.Rebind(query), bindArgs(db.Driver(), args)...)
+}
+
+// GetContext scans a single row into dest
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return db.DB.GetContext(ctx, dest, db.Rebind(query), bindArgs(db.Driver(), args)...)
+}
+
+// SelectContext scans every row into the slice dest
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return db.DB.SelectContext(ctx, dest, db.Rebind(query), bindArgs(db.Driver(), args)...)
+}
+
+// ExecContext executes a statement in the transaction
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.Rebind(query), bindArgs(tx.DriverName(), args)...)
+}
+
+// QueryContext runs a query returning rows in the transaction
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, tx.Rebind(query), bindArgs(tx.DriverName(), args)...)
+}
+
+// QueryRowContext runs a query returning at most one row in the transaction
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.Rebind(query), bindArgs(tx.DriverName(), args)...)
+}
+
+// GetContext scans a single row into dest in the transaction
+func (tx *Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.Tx.GetContext(ctx, dest, tx.Rebind(query), bindArgs(tx.DriverName(), args)...)
+}
+
+// SelectContext scans every row into the slice dest in the transaction
+func (tx *Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.Tx.SelectContext(ctx, dest, tx.Rebind(query), bindArgs(tx.DriverName(), args)...)
+}
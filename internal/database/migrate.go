@@ -0,0 +1,341 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// migrationFiles holds the versioned migrations, named NNN_name.up.sql and
+// NNN_name.down.sql. They are written for MySQL.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+//go:embed schema/postgres.sql
+var postgresSchema string
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Migrations returns the embedded migrations in version order
+func Migrations() ([]*Migration, error) {
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, name := range names {
+		base := path.Base(name)
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s is neither .up.sql nor .down.sql", base)
+		}
+
+		stem := strings.TrimSuffix(base, "."+direction+".sql")
+		prefix, label, ok := strings.Cut(stem, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s does not start with a version number", base)
+		}
+
+		body, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: label}
+			byVersion[version] = migration
+		} else if migration.Name != label {
+			return nil, fmt.Errorf("migration version %03d is used by both %s and %s", version, migration.Name, label)
+		}
+		if direction == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %03d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies the pending migrations in order and returns how many ran.
+// Each one is recorded in schema_migrations once all its statements succeed;
+// MySQL commits DDL as it goes, so a migration that fails halfway has to be
+// cleaned up by hand before it is retried.
+//
+// The migrations are written for MySQL. Postgres and SQLite start from their
+// consolidated schema instead, which matches the latest migration: on an
+// empty database it is loaded and every migration is recorded as applied.
+// Later migrations have to be applied to them by hand and recorded with
+// Baseline.
+func (db *DB) Migrate(ctx context.Context) (int, error) {
+	migrations, applied, err := db.migrationState(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if db.Driver() != DriverMySQL {
+		return db.migrateFromSchema(ctx, migrations, applied)
+	}
+
+	count := 0
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		db.logger.Info("Applying migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+		err := db.WithTransaction(ctx, func(tx *Tx) error {
+			if err := execScript(ctx, tx, migration.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", migration.Version, migration.Name)
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %03d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// migrateFromSchema loads the consolidated schema of a Postgres or SQLite
+// database that has no migrations recorded yet
+func (db *DB) migrateFromSchema(ctx context.Context, migrations []*Migration, applied map[int]*time.Time) (int, error) {
+	if len(applied) > 0 {
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; !ok {
+				return 0, fmt.Errorf("migration %03d_%s is written for MySQL: apply it to %s by hand, then record it with baseline %d",
+					migration.Version, migration.Name, db.Driver(), migration.Version)
+			}
+		}
+		return 0, nil
+	}
+
+	schema := sqliteSchema
+	if db.Driver() == DriverPostgres {
+		schema = postgresSchema
+	}
+
+	db.logger.Info("Loading consolidated schema", zap.String("driver", db.Driver()))
+	err := db.WithTransaction(ctx, func(tx *Tx) error {
+		if err := execScript(ctx, tx, schema); err != nil {
+			return err
+		}
+		return recordMigrations(ctx, tx, migrations, applied)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to load %s schema: %w", db.Driver(), err)
+	}
+	return len(migrations), nil
+}
+
+// MigrateDown reverts the latest steps applied migrations and returns how
+// many were reverted. Only MySQL databases can be migrated down.
+func (db *DB) MigrateDown(ctx context.Context, steps int) (int, error) {
+	if db.Driver() != DriverMySQL {
+		return 0, fmt.Errorf("migrating down is not supported on %s", db.Driver())
+	}
+
+	migrations, applied, err := db.migrationState(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		db.logger.Info("Reverting migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+		err := db.WithTransaction(ctx, func(tx *Tx) error {
+			if err := execScript(ctx, tx, migration.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("reverting migration %03d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Baseline records every migration up to version as applied without running
+// it, for databases whose schema was created by hand
+func (db *DB) Baseline(ctx context.Context, version int) (int, error) {
+	migrations, applied, err := db.migrationState(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var baseline []*Migration
+	for _, migration := range migrations {
+		if migration.Version > version {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			baseline = append(baseline, migration)
+		}
+	}
+
+	err = db.WithTransaction(ctx, func(tx *Tx) error {
+		return recordMigrations(ctx, tx, baseline, applied)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to record baseline: %w", err)
+	}
+	return len(baseline), nil
+}
+
+// MigrationStatus lists every migration with whether it has been applied
+func (db *DB) MigrationStatus(ctx context.Context) ([]*MigrationStatus, error) {
+	migrations, applied, err := db.migrationState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, &MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// migrationState creates schema_migrations if needed and returns the
+// embedded migrations with the versions applied so far
+func (db *DB) migrationState(ctx context.Context) ([]*Migration, map[int]*time.Time, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]*time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, ScanTime(&appliedAt)); err != nil {
+			return nil, nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		applied[version] = &appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	return migrations, applied, nil
+}
+
+// recordMigrations marks migrations as applied
+func recordMigrations(ctx context.Context, tx *Tx, migrations []*Migration, applied map[int]*time.Time) error {
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", migration.Version, migration.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execScript runs a SQL script one statement at a time, since the MySQL
+// driver only accepts one per call. Statements end with a semicolon at the
+// end of a line; lines starting with -- are comments.
+func execScript(ctx context.Context, tx *Tx, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := tx.Tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
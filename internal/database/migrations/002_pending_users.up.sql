@@ -0,0 +1,3 @@
+-- Placeholder users added by email stay pending until they register
+ALTER TABLE users
+    ADD COLUMN is_pending BOOLEAN NOT NULL DEFAULT FALSE AFTER email;
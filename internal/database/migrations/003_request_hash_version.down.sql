@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_keys
+    MODIFY COLUMN request_hash VARCHAR(64) NOT NULL;
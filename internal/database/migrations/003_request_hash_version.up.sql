@@ -0,0 +1,3 @@
+-- Versioned request hashes carry a "v2:" prefix ahead of the SHA-256 hex digest
+ALTER TABLE idempotency_keys
+    MODIFY COLUMN request_hash VARCHAR(80) NOT NULL;
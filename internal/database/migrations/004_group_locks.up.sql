@@ -0,0 +1,10 @@
+-- Short-lived write locks held by settle-up and reconciliation
+CREATE TABLE group_locks (
+    group_id BIGINT PRIMARY KEY,
+    holder VARCHAR(36) NOT NULL,
+    purpose VARCHAR(50) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES `groups`(id) ON DELETE CASCADE,
+    INDEX idx_expires_at (expires_at)
+);
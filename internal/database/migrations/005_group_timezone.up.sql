@@ -0,0 +1,3 @@
+-- IANA timezone used to bucket group reports by local day and month
+ALTER TABLE `groups`
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC' AFTER description;
@@ -0,0 +1,2 @@
+ALTER TABLE expense_splits
+    DROP COLUMN shares;
@@ -0,0 +1,3 @@
+-- Share weight recorded for splits of type "shares"
+ALTER TABLE expense_splits
+    ADD COLUMN shares INT NOT NULL DEFAULT 0 AFTER percentage;
@@ -0,0 +1,3 @@
+ALTER TABLE expenses
+    DROP INDEX idx_group_category,
+    DROP COLUMN category;
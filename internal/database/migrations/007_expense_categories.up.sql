@@ -0,0 +1,4 @@
+-- Free-text expense category used for filtering and reports
+ALTER TABLE expenses
+    ADD COLUMN category VARCHAR(50) NOT NULL DEFAULT 'uncategorized' AFTER description,
+    ADD INDEX idx_group_category (group_id, category);
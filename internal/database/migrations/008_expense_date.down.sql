@@ -0,0 +1,3 @@
+ALTER TABLE expenses
+    DROP INDEX idx_group_expense_date,
+    DROP COLUMN expense_date;
@@ -0,0 +1,8 @@
+-- Date the expense was incurred; reports filter and sort by it instead of created_at
+ALTER TABLE expenses
+    ADD COLUMN expense_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER split_type;
+
+UPDATE expenses SET expense_date = created_at;
+
+ALTER TABLE expenses
+    ADD INDEX idx_group_expense_date (group_id, expense_date);
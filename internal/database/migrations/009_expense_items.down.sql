@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS expense_item_users;
+DROP TABLE IF EXISTS expense_items;
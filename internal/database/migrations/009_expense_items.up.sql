@@ -0,0 +1,22 @@
+-- Line items of itemized expenses; splits are derived from them
+CREATE TABLE expense_items (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    expense_id BIGINT NOT NULL,
+    position INT NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+    INDEX idx_expense_position (expense_id, position)
+);
+
+-- Each user's share of a line item
+CREATE TABLE expense_item_users (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    item_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    FOREIGN KEY (item_id) REFERENCES expense_items(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    UNIQUE KEY unique_item_user (item_id, user_id)
+);
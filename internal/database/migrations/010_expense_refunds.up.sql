@@ -0,0 +1,3 @@
+-- Refunds are stored with negative amounts and flagged explicitly
+ALTER TABLE expenses
+    ADD COLUMN is_refund BOOLEAN NOT NULL DEFAULT FALSE AFTER split_type;
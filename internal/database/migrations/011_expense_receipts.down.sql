@@ -0,0 +1,2 @@
+ALTER TABLE expenses
+    DROP COLUMN receipt_url;
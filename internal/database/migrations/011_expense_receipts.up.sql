@@ -0,0 +1,3 @@
+-- Link to a photographed receipt; empty when none is attached
+ALTER TABLE expenses
+    ADD COLUMN receipt_url VARCHAR(2048) NOT NULL DEFAULT '' AFTER is_refund;
@@ -0,0 +1,7 @@
+ALTER TABLE expenses
+    DROP FOREIGN KEY fk_expenses_recurring,
+    DROP INDEX unique_recurring_run,
+    DROP COLUMN recurring_run_at,
+    DROP COLUMN recurring_expense_id;
+
+DROP TABLE IF EXISTS recurring_expenses;
@@ -0,0 +1,33 @@
+-- Expense templates materialized on a schedule. splits holds the
+-- CreateExpenseSplitRequest list as JSON; starts_at anchors the day of month.
+CREATE TABLE recurring_expenses (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    uuid VARCHAR(36) UNIQUE NOT NULL,
+    group_id BIGINT NOT NULL,
+    paid_by BIGINT NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    description VARCHAR(255) NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT 'uncategorized',
+    split_type VARCHAR(20) NOT NULL,
+    splits JSON NOT NULL,
+    frequency VARCHAR(20) NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES `groups`(id) ON DELETE CASCADE,
+    FOREIGN KEY (paid_by) REFERENCES users(id),
+    INDEX idx_group_id (group_id),
+    INDEX idx_active_next_run (active, next_run_at)
+);
+
+-- Each run of a template creates at most one expense, so a run replayed
+-- after a restart is rejected as a duplicate
+ALTER TABLE expenses
+    ADD COLUMN recurring_expense_id BIGINT NULL AFTER receipt_url,
+    ADD COLUMN recurring_run_at TIMESTAMP NULL AFTER recurring_expense_id,
+    ADD CONSTRAINT fk_expenses_recurring FOREIGN KEY (recurring_expense_id) REFERENCES recurring_expenses(id) ON DELETE SET NULL,
+    ADD UNIQUE KEY unique_recurring_run (recurring_expense_id, recurring_run_at);
@@ -0,0 +1,3 @@
+-- Voided settlements are kept for history but no longer count towards balances
+ALTER TABLE settlements
+    ADD COLUMN voided_at TIMESTAMP NULL AFTER description;
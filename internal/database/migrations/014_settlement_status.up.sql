@@ -0,0 +1,3 @@
+-- Pending settlements wait for the receiver to confirm before they change balances
+ALTER TABLE settlements
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'confirmed' AFTER description;
@@ -0,0 +1,33 @@
+-- Pairwise debts between group members, kept alongside user_balances.
+-- Each pair is stored once with user_a_id < user_b_id; a positive amount means
+-- user A owes user B, a negative one that B owes A.
+CREATE TABLE user_debts (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    group_id BIGINT NOT NULL,
+    user_a_id BIGINT NOT NULL,
+    user_b_id BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    amount DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES `groups`(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_a_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_b_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_group_pair_currency (group_id, user_a_id, user_b_id, currency),
+    INDEX idx_group_currency (group_id, currency)
+);
+
+-- Backfill from existing expenses and confirmed settlements
+INSERT INTO user_debts (group_id, user_a_id, user_b_id, currency, amount)
+SELECT group_id, LEAST(debtor_id, creditor_id), GREATEST(debtor_id, creditor_id), currency,
+       SUM(CASE WHEN debtor_id < creditor_id THEN amount ELSE -amount END)
+FROM (
+    SELECT e.group_id, es.user_id AS debtor_id, e.paid_by AS creditor_id, e.currency, es.amount
+    FROM expense_splits es
+    JOIN expenses e ON es.expense_id = e.id
+    WHERE es.user_id <> e.paid_by
+    UNION ALL
+    SELECT s.group_id, s.from_user_id, s.to_user_id, s.currency, -s.amount
+    FROM settlements s
+    WHERE s.status = 'confirmed' AND s.voided_at IS NULL
+) AS movements
+GROUP BY group_id, LEAST(debtor_id, creditor_id), GREATEST(debtor_id, creditor_id), currency;
@@ -0,0 +1,2 @@
+ALTER TABLE settlements
+    DROP COLUMN method;
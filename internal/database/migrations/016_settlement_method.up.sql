@@ -0,0 +1,3 @@
+-- How a settlement was paid (cash, bank transfer, UPI, ...)
+ALTER TABLE settlements
+    ADD COLUMN method VARCHAR(20) NOT NULL DEFAULT 'other' AFTER status;
@@ -0,0 +1,40 @@
+-- One row per change to a user_balances row, written in the same transaction
+-- as the change itself. balance is the user's balance right after it.
+CREATE TABLE balance_history (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    group_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    delta DECIMAL(15,2) NOT NULL,
+    balance DECIMAL(15,2) NOT NULL,
+    source_type VARCHAR(20) NOT NULL,
+    source_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES `groups`(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_group_user_created (group_id, user_id, created_at),
+    INDEX idx_source (source_type, source_id)
+);
+
+-- Backfill from existing expenses and confirmed settlements, in the order they
+-- were recorded
+INSERT INTO balance_history (group_id, user_id, currency, delta, balance, source_type, source_id, created_at)
+SELECT group_id, user_id, currency, delta,
+       SUM(delta) OVER (PARTITION BY group_id, user_id, currency ORDER BY created_at, source_type, source_id, seq),
+       source_type, source_id, created_at
+FROM (
+    SELECT e.group_id, es.user_id, e.currency, es.amount AS delta, 'expense' AS source_type, e.id AS source_id, e.created_at, 0 AS seq
+    FROM expense_splits es
+    JOIN expenses e ON es.expense_id = e.id
+    UNION ALL
+    SELECT e.group_id, e.paid_by, e.currency, -e.amount, 'expense', e.id, e.created_at, 1
+    FROM expenses e
+    UNION ALL
+    SELECT s.group_id, s.from_user_id, s.currency, -s.amount, 'settlement', s.id, s.created_at, 0
+    FROM settlements s
+    WHERE s.status = 'confirmed' AND s.voided_at IS NULL
+    UNION ALL
+    SELECT s.group_id, s.to_user_id, s.currency, s.amount, 'settlement', s.id, s.created_at, 1
+    FROM settlements s
+    WHERE s.status = 'confirmed' AND s.voided_at IS NULL
+) AS changes;
@@ -0,0 +1,4 @@
+-- Archived groups are hidden from group lists and take no new expenses or
+-- settlements, but keep their history
+ALTER TABLE `groups`
+    ADD COLUMN archived_at TIMESTAMP NULL DEFAULT NULL AFTER updated_at;
@@ -0,0 +1,2 @@
+ALTER TABLE group_members
+    DROP COLUMN role;
@@ -0,0 +1,17 @@
+-- Admins manage a group's members and settings. Existing groups get their
+-- creator as admin, or their earliest member if the creator has left.
+ALTER TABLE group_members
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member' AFTER user_id;
+
+UPDATE group_members gm
+JOIN `groups` g ON g.id = gm.group_id AND g.created_by = gm.user_id
+SET gm.role = 'admin';
+
+UPDATE group_members gm
+JOIN (
+    SELECT group_id, MIN(id) AS first_member_id
+    FROM group_members
+    GROUP BY group_id
+    HAVING SUM(role = 'admin') = 0
+) without_admin ON without_admin.first_member_id = gm.id
+SET gm.role = 'admin';
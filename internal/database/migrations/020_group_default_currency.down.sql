@@ -0,0 +1,2 @@
+ALTER TABLE `groups`
+    DROP COLUMN default_currency;
@@ -0,0 +1,13 @@
+-- Currency used for expenses, settlements and balance views that don't name one.
+-- Existing groups default to the currency most of their expenses are in.
+ALTER TABLE `groups`
+    ADD COLUMN default_currency VARCHAR(3) NOT NULL DEFAULT 'USD' AFTER timezone;
+
+UPDATE `groups` g
+JOIN (
+    SELECT group_id, currency,
+           ROW_NUMBER() OVER (PARTITION BY group_id ORDER BY COUNT(*) DESC, currency) AS currency_rank
+    FROM expenses
+    GROUP BY group_id, currency
+) used ON used.group_id = g.id AND used.currency_rank = 1
+SET g.default_currency = used.currency;
@@ -0,0 +1,4 @@
+DELETE FROM idempotency_keys WHERE status = 'processing';
+
+ALTER TABLE idempotency_keys
+    DROP COLUMN status;
@@ -0,0 +1,5 @@
+-- Requests reserve their key as 'processing' before the handler runs, so a
+-- concurrent retry with the same key waits for the first one instead of
+-- executing again. Existing records all hold a finished response.
+ALTER TABLE idempotency_keys
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'completed' AFTER request_hash;
@@ -0,0 +1,8 @@
+-- Scoped records may share a key; they only cache responses, so drop them
+-- rather than fail to restore the global unique key.
+DELETE FROM idempotency_keys WHERE caller <> '';
+
+ALTER TABLE idempotency_keys
+    DROP INDEX uq_idempotency_caller_key,
+    DROP COLUMN caller,
+    ADD UNIQUE KEY key_value (key_value);
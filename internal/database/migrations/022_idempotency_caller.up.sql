@@ -0,0 +1,7 @@
+-- Idempotency keys are scoped to the caller that sent them ("user:<uuid>",
+-- "client:<X-Client-ID>", or '' for anonymous callers), so two callers that
+-- happen to pick the same key never see each other's responses.
+ALTER TABLE idempotency_keys
+    ADD COLUMN caller VARCHAR(100) NOT NULL DEFAULT '' AFTER id,
+    DROP INDEX key_value,
+    ADD UNIQUE KEY uq_idempotency_caller_key (caller, key_value);
@@ -0,0 +1,4 @@
+-- Replayed responses carry the original headers (Content-Type, Location, ...)
+-- instead of a hardcoded JSON content type. Older records have none stored.
+ALTER TABLE idempotency_keys
+    ADD COLUMN response_headers JSON NULL AFTER response_data;
@@ -0,0 +1,16 @@
+-- Domain events written in the same transaction as the change they describe.
+-- A background relay publishes rows with no dispatched_at in id order, which
+-- is commit order within a group. group_id has no foreign key so events
+-- outlive the rows they describe.
+CREATE TABLE outbox_events (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    group_id BIGINT NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSON NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(1000) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP NULL,
+    INDEX idx_pending (dispatched_at, id),
+    INDEX idx_group (group_id, id)
+);
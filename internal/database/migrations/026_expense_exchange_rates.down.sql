@@ -0,0 +1,4 @@
+ALTER TABLE expenses
+    DROP COLUMN exchange_rate,
+    DROP COLUMN base_currency,
+    DROP COLUMN base_amount;
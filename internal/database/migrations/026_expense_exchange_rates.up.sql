@@ -0,0 +1,14 @@
+-- Expenses entered in a currency other than their group's are converted when
+-- they are recorded. amount and currency keep what was entered; splits and
+-- balances use base_amount in base_currency, at the exchange_rate snapshot.
+ALTER TABLE expenses
+    ADD COLUMN base_amount DECIMAL(15,2) NULL AFTER currency,
+    ADD COLUMN base_currency VARCHAR(3) NULL AFTER base_amount,
+    ADD COLUMN exchange_rate DECIMAL(18,8) NOT NULL DEFAULT 1 AFTER base_currency;
+
+-- Existing expenses were booked in their own currency
+UPDATE expenses SET base_amount = amount, base_currency = currency;
+
+ALTER TABLE expenses
+    MODIFY COLUMN base_amount DECIMAL(15,2) NOT NULL,
+    MODIFY COLUMN base_currency VARCHAR(3) NOT NULL;
@@ -0,0 +1,22 @@
+-- Deleted records go for good; the old unique key cannot hold while replaced
+-- splits remain
+DELETE FROM expense_splits WHERE deleted_at IS NOT NULL;
+DELETE FROM expenses WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE settlements
+    DROP FOREIGN KEY fk_settlements_voided_by,
+    DROP COLUMN voided_by;
+
+ALTER TABLE expense_splits
+    DROP INDEX unique_expense_user,
+    ADD UNIQUE KEY unique_expense_user (expense_id, user_id),
+    DROP FOREIGN KEY fk_expense_splits_deleted_by,
+    DROP COLUMN live_marker,
+    DROP COLUMN deleted_by,
+    DROP COLUMN deleted_at;
+
+ALTER TABLE expenses
+    DROP INDEX idx_group_deleted,
+    DROP FOREIGN KEY fk_expenses_deleted_by,
+    DROP COLUMN deleted_by,
+    DROP COLUMN deleted_at;
@@ -0,0 +1,24 @@
+-- Deleted expenses and their splits are kept for history. Reads skip rows
+-- with a deleted_at unless they ask for deleted records; deleted_by is the
+-- acting user, NULL when the request had no identity. Splits replaced by an
+-- expense update are stamped the same way.
+ALTER TABLE expenses
+    ADD COLUMN deleted_at TIMESTAMP NULL AFTER updated_at,
+    ADD COLUMN deleted_by BIGINT NULL AFTER deleted_at,
+    ADD CONSTRAINT fk_expenses_deleted_by FOREIGN KEY (deleted_by) REFERENCES users(id) ON DELETE SET NULL,
+    ADD INDEX idx_group_deleted (group_id, deleted_at);
+
+-- Only one live split per user and expense. live_marker turns NULL once a
+-- split is deleted, and NULLs never collide in a unique key.
+ALTER TABLE expense_splits
+    ADD COLUMN deleted_at TIMESTAMP NULL AFTER created_at,
+    ADD COLUMN deleted_by BIGINT NULL AFTER deleted_at,
+    ADD COLUMN live_marker TINYINT AS (IF(deleted_at IS NULL, 1, NULL)) STORED,
+    ADD CONSTRAINT fk_expense_splits_deleted_by FOREIGN KEY (deleted_by) REFERENCES users(id) ON DELETE SET NULL,
+    DROP INDEX unique_expense_user,
+    ADD UNIQUE KEY unique_expense_user (expense_id, user_id, live_marker);
+
+-- Settlements were already soft deleted through voided_at
+ALTER TABLE settlements
+    ADD COLUMN voided_by BIGINT NULL AFTER voided_at,
+    ADD CONSTRAINT fk_settlements_voided_by FOREIGN KEY (voided_by) REFERENCES users(id) ON DELETE SET NULL;
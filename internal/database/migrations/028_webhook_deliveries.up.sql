@@ -0,0 +1,17 @@
+-- One row per event sent to a webhook. uuid is the X-Webhook-Delivery id and
+-- payload the posted body, kept so a dead-lettered delivery can be sent again
+-- unchanged. A delivery is dead_lettered once its final retry fails.
+CREATE TABLE webhook_deliveries (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    uuid VARCHAR(36) UNIQUE NOT NULL,
+    webhook_id BIGINT NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSON NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    INDEX idx_webhook_status (webhook_id, status)
+);
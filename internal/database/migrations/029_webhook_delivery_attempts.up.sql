@@ -0,0 +1,15 @@
+-- One row per POST of a webhook delivery. status_code is NULL when no
+-- response arrived (connection refused, timeout); outcome is delivered for a
+-- 2xx response and failed otherwise.
+CREATE TABLE webhook_delivery_attempts (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    delivery_id BIGINT NOT NULL,
+    attempt INT NOT NULL,
+    status_code INT NULL,
+    outcome VARCHAR(20) NOT NULL,
+    error TEXT NULL,
+    latency_ms BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    INDEX idx_delivery_attempt (delivery_id, attempt)
+);
@@ -0,0 +1,261 @@
+-- SQLite schema, equivalent to applying migrations 001 through 028 on MySQL.
+-- It is embedded in the server and applied on every start with
+-- DB_DRIVER=sqlite, so every statement only creates what is missing.
+--
+-- Ids are INTEGER PRIMARY KEY so they alias the rowid, timestamps are
+-- declared TIMESTAMP so the driver scans them as times and JSON is kept as
+-- TEXT. Keep this file in step with new migrations.
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid VARCHAR(36) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    is_pending BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS "groups" (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid VARCHAR(36) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    default_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    created_by BIGINT NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    archived_at TIMESTAMP NULL
+);
+CREATE INDEX IF NOT EXISTS idx_groups_created_by ON "groups" (created_by);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id BIGINT NOT NULL REFERENCES "groups"(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'member',
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_group_user UNIQUE (group_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members (user_id);
+
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid VARCHAR(36) NOT NULL UNIQUE,
+    group_id BIGINT NOT NULL REFERENCES "groups"(id) ON DELETE CASCADE,
+    paid_by BIGINT NOT NULL REFERENCES users(id),
+    amount DECIMAL(15,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    description VARCHAR(255) NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT 'uncategorized',
+    split_type VARCHAR(20) NOT NULL,
+    splits TEXT NOT NULL,
+    frequency VARCHAR(20) NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_group_id ON recurring_expenses (group_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_active_next_run ON recurring_expenses (active, next_run_at);
+
+CREATE TABLE IF NOT EXISTS expenses (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid VARCHAR(36) NOT NULL UNIQUE,
+    group_id BIGINT NOT NULL REFERENCES "groups"(id) ON DELETE CASCADE,
+    paid_by BIGINT NOT NULL REFERENCES users(id),
+    amount DECIMAL(15,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    base_amount DECIMAL(15,2) NOT NULL,
+    base_currency VARCHAR(3) NOT NULL,
+    exchange_rate DECIMAL(18,8) NOT NULL DEFAULT 1,
+    description TEXT NOT NULL,
+    category VARCHAR(50) NOT NULL DEFAULT 'uncategorized',
+    split_type VARCHAR(20) NOT NULL,
+    is_refund BOOLEAN NOT NULL DEFAULT FALSE,
+    receipt_url VARCHAR(2048) NOT NULL DEFAULT '',
+    recurring_expense_id BIGINT NULL REFERENCES recurring_expenses(id) ON DELETE SET NULL,
+    recurring_run_at TIMESTAMP NULL,
+    expense_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    deleted_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT unique_recurring_run UNIQUE (recurring_expense_id, recurring_run_at)
+);
+CREATE INDEX IF NOT EXISTS idx_expenses_group_id ON expenses (group_id);
+CREATE INDEX IF NOT EXISTS idx_expenses_paid_by ON expenses (paid_by);
+CREATE INDEX IF NOT EXISTS idx_expenses_created_at ON expenses (created_at);
+CREATE INDEX IF NOT EXISTS idx_expenses_group_category ON expenses (group_id, category);
+CREATE INDEX IF NOT EXISTS idx_expenses_group_expense_date ON expenses (group_id, expense_date);
+CREATE INDEX IF NOT EXISTS idx_expenses_group_deleted ON expenses (group_id, deleted_at);
+
+CREATE TABLE IF NOT EXISTS expense_splits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    expense_id BIGINT NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    amount DECIMAL(15,2) NOT NULL,
+    percentage DECIMAL(5,2) NULL,
+    shares INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    deleted_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL
+);
+-- Only live splits must be unique per user; soft-deleted ones are history
+CREATE UNIQUE INDEX IF NOT EXISTS unique_expense_user ON expense_splits (expense_id, user_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_expense_splits_user_id ON expense_splits (user_id);
+
+CREATE TABLE IF NOT EXISTS expense_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    expense_id BIGINT NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_expense_items_expense_position ON expense_items (expense_id, position);
+
+CREATE TABLE IF NOT EXISTS expense_item_users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id BIGINT NOT NULL REFERENCES expense_items(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id),
+    amount DECIMAL(15,2) NOT NULL,
+    CONSTRAINT unique_item_user UNIQUE (item_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS settlements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid VARCHAR(36) NOT NULL UNIQUE,
+    group_id BIGINT NOT NULL REFERENCES "groups"(id) ON DELETE CASCADE,
+    from_user_id BIGINT NOT NULL REFERENCES users(id),
+    to_user_id BIGINT NOT NULL REFERENCES users(id),
+    amount DECIMAL(15,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    description TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'confirmed',
+    method VARCHAR(20) NOT NULL DEFAULT 'other',
+    voided_at TIMESTAMP NULL,
+    voided_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_settlements_group_id ON settlements (group_id);
+CREATE INDEX IF NOT EXISTS idx_settlements_from_user ON settlements (from_user_id);
+CREATE INDEX IF NOT EXISTS idx_settlements_to_user ON settlements (to_user_id);
+CREATE INDEX IF NOT EXISTS idx_settlements_created_at ON settlements (created_at);
+
+-- created_at and expires_at hold the unix seconds the repository writes
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    caller VARCHAR(100) NOT NULL DEFAULT '',
+    key_value VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(80) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'completed',
+    response_data TEXT,
+    response_headers TEXT NULL,
+    status_code INT,
+    created_at BIGINT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    CONSTRAINT uq_idempotency_caller_key UNIQUE (caller, key_value)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_balances (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id BIGINT NOT NULL REFERENCES "groups"(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    balance DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_group_user_currency UNIQUE (group_id, user_id, currency)
+);
+CREATE INDEX IF NOT EXISTS idx_user_balances_user_id ON user_balances (user_id);
+
+CREATE TABLE IF NOT EXISTS user_debts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id BIGINT NOT NULL REFERENCES "groups"(id) ON DELETE CASCADE,
+    user_a_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_b_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    amount DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_group_pair_currency UNIQUE (group_id, user_a_id, user_b_id, currency)
+);
+CREATE INDEX IF NOT EXISTS idx_user_debts_group_currency ON user_debts (group_id, currency);
+
+CREATE TABLE IF NOT EXISTS balance_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id BIGINT NOT NULL REFERENCES "groups"(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    delta DECIMAL(15,2) NOT NULL,
+    balance DECIMAL(15,2) NOT NULL,
+    source_type VARCHAR(20) NOT NULL,
+    source_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_balance_history_group_user_created ON balance_history (group_id, user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_balance_history_source ON balance_history (source_type, source_id);
+
+CREATE TABLE IF NOT EXISTS group_locks (
+    group_id BIGINT PRIMARY KEY REFERENCES "groups"(id) ON DELETE CASCADE,
+    holder VARCHAR(36) NOT NULL,
+    purpose VARCHAR(50) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_group_locks_expires_at ON group_locks (expires_at);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid VARCHAR(36) NOT NULL UNIQUE,
+    group_id BIGINT NOT NULL REFERENCES "groups"(id) ON DELETE CASCADE,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    event_types TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_group_active ON webhooks (group_id, active);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid VARCHAR(36) NOT NULL UNIQUE,
+    webhook_id BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_status ON webhook_deliveries (webhook_id, status);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    status_code INT NULL,
+    outcome VARCHAR(20) NOT NULL,
+    error TEXT NULL,
+    latency_ms BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts (delivery_id, attempt);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id BIGINT NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(1000) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP NULL
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events (dispatched_at, id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_group ON outbox_events (group_id, id);
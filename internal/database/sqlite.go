@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteSchema creates every table that does not exist yet
+//
+//go:embed schema/sqlite.sql
+var sqliteSchema string
+
+func init() {
+	// Transactions serialize on the database write lock, and services read
+	// outside the transaction while one is open. Letting those reads see
+	// uncommitted rows keeps them from waiting on the table locks of a
+	// shared-cache in-memory database.
+	sql.Register(DriverSQLite, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec("PRAGMA read_uncommitted = 1", nil)
+			return err
+		},
+	})
+	sqlx.BindDriver(DriverSQLite, sqlx.QUESTION)
+}
+
+// openSQLite prepares a SQLite database on startup. One connection stays
+// open until Close, since an in-memory database disappears with its last
+// connection, and the schema is created; the script only creates what is
+// missing, so it is safe to run on every start.
+func (db *DB) openSQLite(ctx context.Context) error {
+	pinned, err := db.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open SQLite connection: %w", err)
+	}
+	db.pinned = pinned
+
+	if _, err := db.DB.ExecContext(ctx, sqliteSchema); err != nil {
+		return fmt.Errorf("failed to create SQLite schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"expense-split-tracker/internal/database"
+
+	"go.uber.org/zap"
+)
+
+// Emitter publishes domain events to interested consumers
+type Emitter interface {
+	Emit(ctx context.Context, event Event)
+}
+
+// NopEmitter discards every event
+type NopEmitter struct{}
+
+// Emit implements Emitter
+func (NopEmitter) Emit(ctx context.Context, event Event) {}
+
+// Recorder persists events inside the transaction that raised them, so they
+// are not lost if the process dies between commit and Emit. Services hand
+// every batch to their emitter's Recorder, when it has one, before the
+// transaction commits.
+type Recorder interface {
+	Record(ctx context.Context, tx *database.Tx, events []Event) error
+}
+
+// Record writes events through emitter's Recorder. Emitters without one
+// record nothing.
+func Record(ctx context.Context, emitter Emitter, tx *database.Tx, events ...Event) error {
+	recorder, ok := emitter.(Recorder)
+	if !ok || len(events) == 0 {
+		return nil
+	}
+	return recorder.Record(ctx, tx, events)
+}
+
+// Handler consumes events delivered by a Dispatcher
+type Handler func(ctx context.Context, event Event) error
+
+// Dispatcher delivers each event synchronously to every subscribed handler.
+// Downstream writers (activity feed, audit log, outbox) subscribe here rather
+// than being called from each service.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	handlers []Handler
+	recorder Recorder
+	logger   *zap.Logger
+}
+
+// NewDispatcher creates a dispatcher with no subscribers
+func NewDispatcher(logger *zap.Logger) *Dispatcher {
+	return &Dispatcher{logger: logger}
+}
+
+// Subscribe registers a handler for all events
+func (d *Dispatcher) Subscribe(handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers = append(d.handlers, handler)
+}
+
+// UseRecorder makes the dispatcher persist events through recorder inside
+// their transaction, e.g. into the outbox
+func (d *Dispatcher) UseRecorder(recorder Recorder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recorder = recorder
+}
+
+// Record implements Recorder by delegating to the recorder in use, if any
+func (d *Dispatcher) Record(ctx context.Context, tx *database.Tx, events []Event) error {
+	d.mu.RLock()
+	recorder := d.recorder
+	d.mu.RUnlock()
+
+	if recorder == nil {
+		return nil
+	}
+	return recorder.Record(ctx, tx, events)
+}
+
+// Emit implements Emitter. Handler errors are logged; the state change has
+// already been committed and is not undone.
+func (d *Dispatcher) Emit(ctx context.Context, event Event) {
+	d.mu.RLock()
+	handlers := d.handlers
+	d.mu.RUnlock()
+
+	d.logger.Debug("Domain event emitted", zap.String("event", event.EventName()))
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			d.logger.Error("Event handler failed", zap.Error(err), zap.String("event", event.EventName()))
+		}
+	}
+}
+
+// Batch collects events raised inside a transaction so they are emitted only
+// once it commits. A zero Batch is ready to use.
+type Batch struct {
+	events []Event
+}
+
+// Add queues an event
+func (b *Batch) Add(event Event) {
+	b.events = append(b.events, event)
+}
+
+// Record persists the queued events through emitter's Recorder inside tx.
+// Call it last in the transaction, once every event has been added.
+func (b *Batch) Record(ctx context.Context, tx *database.Tx, emitter Emitter) error {
+	return Record(ctx, emitter, tx, b.events...)
+}
+
+// Emit sends the queued events in order and empties the batch
+func (b *Batch) Emit(ctx context.Context, emitter Emitter) {
+	for _, event := range b.events {
+		emitter.Emit(ctx, event)
+	}
+	b.events = nil
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"expense-split-tracker/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Event is a domain event describing a state change that has been committed
+type Event interface {
+	EventName() string
+}
+
+// GroupCreated is emitted when a group is created
+type GroupCreated struct {
+	Group *models.Group
+}
+
+// GroupUpdated is emitted when a group's settings change
+type GroupUpdated struct {
+	Group *models.Group
+}
+
+// GroupArchived is emitted when a group is archived
+type GroupArchived struct {
+	Group *models.Group
+}
+
+// GroupUnarchived is emitted when an archived group is restored
+type GroupUnarchived struct {
+	Group *models.Group
+}
+
+// MemberAdded is emitted when a user joins a group
+type MemberAdded struct {
+	GroupID int64
+	UserID  int64
+}
+
+// MemberRemoved is emitted when a user leaves a group
+type MemberRemoved struct {
+	GroupID int64
+	UserID  int64
+}
+
+// MemberRoleChanged is emitted when a member is promoted or demoted
+type MemberRoleChanged struct {
+	GroupID int64
+	UserID  int64
+	Role    models.GroupRole
+}
+
+// GroupOwnershipTransferred is emitted when a group's owner hands it over to
+// another member
+type GroupOwnershipTransferred struct {
+	GroupID        int64
+	PreviousUserID int64
+	UserID         int64
+}
+
+// ExpenseCreated is emitted when an expense and its splits are recorded
+type ExpenseCreated struct {
+	Expense *models.Expense
+	Splits  []*models.ExpenseSplit
+}
+
+// ExpenseUpdated is emitted when an expense's amount or splits are replaced
+type ExpenseUpdated struct {
+	Expense *models.Expense
+	Splits  []*models.ExpenseSplit
+}
+
+// ExpenseDeleted is emitted when an expense and its splits are removed
+type ExpenseDeleted struct {
+	Expense *models.Expense
+}
+
+// SettlementCreated is emitted when a debt payment is recorded
+type SettlementCreated struct {
+	Settlement *models.Settlement
+}
+
+// SettlementVoided is emitted when a recorded payment is voided and its
+// effect on balances reversed
+type SettlementVoided struct {
+	Settlement *models.Settlement
+}
+
+// SettlementConfirmed is emitted when the receiver confirms a pending
+// settlement and it is applied to balances
+type SettlementConfirmed struct {
+	Settlement *models.Settlement
+}
+
+// SettlementRejected is emitted when the receiver rejects a pending settlement
+type SettlementRejected struct {
+	Settlement *models.Settlement
+}
+
+// BalanceAdjusted is emitted for every change to a user's cached balance.
+// A positive Delta means the user owes more.
+type BalanceAdjusted struct {
+	GroupID  int64
+	UserID   int64
+	Currency string
+	Delta    decimal.Decimal
+}
+
+func (GroupCreated) EventName() string              { return "group.created" }
+func (GroupUpdated) EventName() string              { return "group.updated" }
+func (GroupArchived) EventName() string             { return "group.archived" }
+func (GroupUnarchived) EventName() string           { return "group.unarchived" }
+func (MemberAdded) EventName() string               { return "group.member_added" }
+func (MemberRemoved) EventName() string             { return "group.member_removed" }
+func (MemberRoleChanged) EventName() string         { return "group.member_role_changed" }
+func (GroupOwnershipTransferred) EventName() string { return "group.ownership_transferred" }
+func (ExpenseCreated) EventName() string            { return "expense.created" }
+func (ExpenseUpdated) EventName() string            { return "expense.updated" }
+func (ExpenseDeleted) EventName() string            { return "expense.deleted" }
+func (SettlementCreated) EventName() string         { return "settlement.created" }
+func (SettlementVoided) EventName() string          { return "settlement.voided" }
+func (SettlementConfirmed) EventName() string       { return "settlement.confirmed" }
+func (SettlementRejected) EventName() string        { return "settlement.rejected" }
+func (BalanceAdjusted) EventName() string           { return "balance.adjusted" }
@@ -0,0 +1,87 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// HTTPProvider fetches the latest rates from a Frankfurter-compatible API:
+// GET {baseURL}/latest?from=EUR&to=USD answering
+// {"base":"EUR","date":"2024-03-01","rates":{"USD":1.08}}.
+// Rates are not cached; every lookup is a request.
+type HTTPProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// latestRates is the body of a /latest response
+type latestRates struct {
+	Base  string                     `json:"base"`
+	Date  string                     `json:"date"`
+	Rates map[string]decimal.Decimal `json:"rates"`
+}
+
+// NewHTTPProvider creates a provider for the API at baseURL
+func NewHTTPProvider(baseURL string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Rate implements ExchangeRateProvider
+func (p *HTTPProvider) Rate(ctx context.Context, from, to string) (*models.ExchangeRate, error) {
+	from = utils.NormalizeCurrency(from)
+	to = utils.NormalizeCurrency(to)
+	if from == to {
+		return &models.ExchangeRate{From: from, To: to, Rate: decimal.NewFromInt(1), AsOf: time.Now().UTC()}, nil
+	}
+
+	query := url.Values{"from": {from}, "to": {to}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/latest?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange rate %s/%s: %w", from, to, err)
+	}
+	defer resp.Body.Close()
+
+	// The API answers 404 or 422 for currencies it does not know
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity {
+		return nil, errors.NewExchangeRateUnavailableError(from, to)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch exchange rate %s/%s: unexpected status %d", from, to, resp.StatusCode)
+	}
+
+	var body latestRates
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode exchange rate %s/%s: %w", from, to, err)
+	}
+
+	rate, ok := body.Rates[to]
+	if !ok || !rate.IsPositive() {
+		return nil, errors.NewExchangeRateUnavailableError(from, to)
+	}
+
+	asOf := time.Now().UTC()
+	if date, err := time.Parse("2006-01-02", body.Date); err == nil {
+		asOf = date
+	}
+
+	return &models.ExchangeRate{From: from, To: to, Rate: rate.Round(RatePlaces), AsOf: asOf}, nil
+}
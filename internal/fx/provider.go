@@ -0,0 +1,77 @@
+package fx
+
+import (
+	"context"
+	"time"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// RatePlaces is the number of decimal places exchange rates are rounded to
+const RatePlaces = 8
+
+// ExchangeRateProvider looks up the rate that converts an amount in one
+// currency into another. Currencies it has no rate for fail with an
+// EXCHANGE_RATE_UNAVAILABLE error rather than a zero rate.
+type ExchangeRateProvider interface {
+	Rate(ctx context.Context, from, to string) (*models.ExchangeRate, error)
+}
+
+// Convert converts amount with rate, rounded to the precision of the target currency
+func Convert(amount decimal.Decimal, rate *models.ExchangeRate) decimal.Decimal {
+	return amount.Mul(rate.Rate).Round(utils.AmountDecimals(rate.To))
+}
+
+// StaticProvider serves fixed rates from configuration. Rates are given
+// against a base currency as the units of each currency one base unit buys,
+// so any pair of configured currencies can be converted through the base.
+type StaticProvider struct {
+	base  string
+	rates map[string]decimal.Decimal
+	asOf  time.Time
+}
+
+// NewStaticProvider creates a provider for the given rates against base.
+// The time it is created is reported as the rates' timestamp.
+func NewStaticProvider(base string, rates map[string]decimal.Decimal) *StaticProvider {
+	base = utils.NormalizeCurrency(base)
+	normalized := map[string]decimal.Decimal{base: decimal.NewFromInt(1)}
+	for currency, rate := range rates {
+		normalized[utils.NormalizeCurrency(currency)] = rate
+	}
+
+	return &StaticProvider{
+		base:  base,
+		rates: normalized,
+		asOf:  time.Now().UTC(),
+	}
+}
+
+// Rate implements ExchangeRateProvider
+func (p *StaticProvider) Rate(ctx context.Context, from, to string) (*models.ExchangeRate, error) {
+	from = utils.NormalizeCurrency(from)
+	to = utils.NormalizeCurrency(to)
+	if from == to {
+		return &models.ExchangeRate{From: from, To: to, Rate: decimal.NewFromInt(1), AsOf: p.asOf}, nil
+	}
+
+	fromRate, ok := p.rates[from]
+	if !ok || !fromRate.IsPositive() {
+		return nil, errors.NewExchangeRateUnavailableError(from, to)
+	}
+	toRate, ok := p.rates[to]
+	if !ok || !toRate.IsPositive() {
+		return nil, errors.NewExchangeRateUnavailableError(from, to)
+	}
+
+	return &models.ExchangeRate{
+		From: from,
+		To:   to,
+		Rate: toRate.DivRound(fromRate, RatePlaces),
+		AsOf: p.asOf,
+	}, nil
+}
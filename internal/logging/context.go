@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the request it serves
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID stored in ctx, or "" outside a request
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext returns logger tagged with the request ID carried by ctx, so
+// log lines from services and repositories can be tied to the request that
+// caused them
+func FromContext(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if requestID := RequestID(ctx); requestID != "" {
+		return logger.With(zap.String("request_id", requestID))
+	}
+	return logger
+}
@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewConfig returns the zap configuration for the given LOG_LEVEL and
+// environment: colored console output in development, JSON everywhere else.
+// Timestamps are ISO8601 either way.
+func NewConfig(level, env string) (zap.Config, error) {
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return zap.Config{}, fmt.Errorf("invalid LOG_LEVEL: %v", err)
+	}
+
+	var config zap.Config
+	if env == "development" {
+		config = zap.NewDevelopmentConfig()
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	} else {
+		config = zap.NewProductionConfig()
+	}
+	config.Level = zap.NewAtomicLevelAt(parsed)
+	config.EncoderConfig.TimeKey = "timestamp"
+	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	return config, nil
+}
+
+// New builds the application logger; see NewConfig
+func New(level, env string) (*zap.Logger, error) {
+	config, err := NewConfig(level, env)
+	if err != nil {
+		return nil, err
+	}
+	return config.Build()
+}
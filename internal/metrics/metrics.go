@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Recorder receives the counters services and middleware report. The
+// Registry exposes them at /metrics; tests use Nop.
+type Recorder interface {
+	ObserveRequest(method, route string, status int, duration time.Duration)
+	ExpensesCreated(n int)
+	SettlementsCreated(n int)
+	DebtSimplificationRun(mode string)
+}
+
+// Debt simplification modes reported to DebtSimplificationRun
+const (
+	SimplificationPreview = "preview"
+	SimplificationExecute = "execute"
+)
+
+// DBStatser is the part of database.DB the registry samples for pool gauges
+type DBStatser interface {
+	Stats() sql.DBStats
+}
+
+// Nop discards every observation
+type Nop struct{}
+
+// ObserveRequest implements Recorder
+func (Nop) ObserveRequest(method, route string, status int, duration time.Duration) {}
+
+// ExpensesCreated implements Recorder
+func (Nop) ExpensesCreated(n int) {}
+
+// SettlementsCreated implements Recorder
+func (Nop) SettlementsCreated(n int) {}
+
+// DebtSimplificationRun implements Recorder
+func (Nop) DebtSimplificationRun(mode string) {}
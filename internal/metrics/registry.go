@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds the application's Prometheus collectors. Each registry has
+// its own prometheus.Registry rather than the global default, so tests can
+// create as many as they need.
+type Registry struct {
+	registry           *prometheus.Registry
+	requests           *prometheus.CounterVec
+	durations          *prometheus.HistogramVec
+	expensesCreated    prometheus.Counter
+	settlementsCreated prometheus.Counter
+	simplificationRuns *prometheus.CounterVec
+}
+
+// NewRegistry creates a registry with the application metrics and the Go
+// runtime and process collectors. When db is not nil its connection pool
+// stats are sampled on every scrape.
+func NewRegistry(db DBStatser) *Registry {
+	r := &Registry{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route and status.",
+		}, []string{"method", "route", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		expensesCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "expenses_created_total",
+			Help: "Expenses created, including imported and recurring ones.",
+		}),
+		settlementsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "settlements_created_total",
+			Help: "Settlements recorded, including those from debt simplification and settle-all.",
+		}),
+		simplificationRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "debt_simplification_runs_total",
+			Help: "Debt simplification runs by mode.",
+		}, []string{"mode"}),
+	}
+
+	// Both modes are reported from the first scrape, even before any run
+	for _, mode := range []string{SimplificationPreview, SimplificationExecute} {
+		r.simplificationRuns.WithLabelValues(mode)
+	}
+
+	r.registry.MustRegister(
+		r.requests,
+		r.durations,
+		r.expensesCreated,
+		r.settlementsCreated,
+		r.simplificationRuns,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if db != nil {
+		r.registry.MustRegister(poolCollectors(db)...)
+	}
+	return r
+}
+
+// poolCollectors samples the connection pool of db when scraped
+func poolCollectors(db DBStatser) []prometheus.Collector {
+	gauge := func(name, help string, value func(stats sql.DBStats) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, func() float64 {
+			return value(db.Stats())
+		})
+	}
+	counter := func(name, help string, value func(stats sql.DBStats) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 {
+			return value(db.Stats())
+		})
+	}
+
+	return []prometheus.Collector{
+		gauge("db_max_open_connections", "Maximum number of open connections to the database.",
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }),
+		gauge("db_open_connections", "Established connections, in use and idle.",
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }),
+		gauge("db_in_use_connections", "Connections currently in use.",
+			func(s sql.DBStats) float64 { return float64(s.InUse) }),
+		gauge("db_idle_connections", "Idle connections.",
+			func(s sql.DBStats) float64 { return float64(s.Idle) }),
+		counter("db_wait_count_total", "Connections waited for.",
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }),
+		counter("db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }),
+	}
+}
+
+// ObserveRequest implements Recorder
+func (r *Registry) ObserveRequest(method, route string, status int, duration time.Duration) {
+	r.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	r.durations.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ExpensesCreated implements Recorder
+func (r *Registry) ExpensesCreated(n int) {
+	r.expensesCreated.Add(float64(n))
+}
+
+// SettlementsCreated implements Recorder
+func (r *Registry) SettlementsCreated(n int) {
+	r.settlementsCreated.Add(float64(n))
+}
+
+// DebtSimplificationRun implements Recorder
+func (r *Registry) DebtSimplificationRun(mode string) {
+	r.simplificationRuns.WithLabelValues(mode).Inc()
+}
+
+// Handler serves the registry for Prometheus to scrape. Like
+// promhttp.Handler, it also reports on the scrapes it serves.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.InstrumentMetricHandler(r.registry, promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{}))
+}
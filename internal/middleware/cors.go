@@ -1,29 +1,64 @@
 package middleware
 
 import (
+	"expense-split-tracker/internal/config"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// CORSMiddleware returns a CORS middleware with appropriate settings
-func CORSMiddleware() gin.HandlerFunc {
-	config := cors.DefaultConfig()
+// CORSPolicy is the cross-origin policy in effect for a configuration
+type CORSPolicy struct {
+	AllowAllOrigins  bool
+	AllowedOrigins   []string
+	AllowCredentials bool
+}
+
+// EffectiveCORSPolicy resolves the configured origins. Without an explicit
+// list every origin is allowed outside production, and none in production.
+// Credentials are only allowed for an explicit list, since browsers refuse
+// them for a wildcard origin.
+func EffectiveCORSPolicy(cfg *config.Config) CORSPolicy {
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		return CORSPolicy{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+		}
+	}
+	return CORSPolicy{AllowAllOrigins: cfg.Server.Env != "production"}
+}
+
+// CORSMiddleware returns a CORS middleware for the configured policy.
+// Requests from origins outside the policy get no CORS headers, so browsers
+// block them, but are otherwise handled normally.
+func CORSMiddleware(cfg *config.Config) gin.HandlerFunc {
+	policy := EffectiveCORSPolicy(cfg)
+	if !policy.AllowAllOrigins && len(policy.AllowedOrigins) == 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
 
-	// Allow all origins in development
-	config.AllowAllOrigins = true
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowAllOrigins = policy.AllowAllOrigins
+	corsConfig.AllowOrigins = policy.AllowedOrigins
+	corsConfig.AllowCredentials = policy.AllowCredentials
 
 	// Allow common headers
-	config.AllowHeaders = []string{
+	corsConfig.AllowHeaders = []string{
 		"Origin",
 		"Content-Length",
 		"Content-Type",
 		"Authorization",
 		"Idempotency-Key",
+		"X-User-UUID",
+		"X-Request-ID",
+		"X-Client-ID",
 		"X-Requested-With",
 	}
 
 	// Allow all common methods
-	config.AllowMethods = []string{
+	corsConfig.AllowMethods = []string{
 		"GET",
 		"POST",
 		"PUT",
@@ -34,10 +69,31 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 
 	// Expose custom headers
-	config.ExposeHeaders = []string{
+	corsConfig.ExposeHeaders = []string{
 		"X-Idempotent-Replayed",
 		"X-Request-ID",
+		"X-RateLimit-Limit",
+		"X-RateLimit-Burst",
+		"X-RateLimit-Remaining",
+		"Retry-After",
+	}
+
+	handler := cors.New(corsConfig)
+	if policy.AllowAllOrigins {
+		return handler
 	}
 
-	return cors.New(config)
+	allowed := make(map[string]bool, len(policy.AllowedOrigins))
+	for _, origin := range policy.AllowedOrigins {
+		allowed[origin] = true
+	}
+
+	return func(c *gin.Context) {
+		// The cors package answers unknown origins with 403; skip it instead
+		if origin := c.GetHeader("Origin"); origin != "" && !allowed[origin] {
+			c.Next()
+			return
+		}
+		handler(c)
+	}
 }
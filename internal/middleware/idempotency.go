@@ -3,11 +3,13 @@ package middleware
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	"expense-split-tracker/internal/auth"
 	"expense-split-tracker/internal/config"
 	"expense-split-tracker/internal/repository"
 	"expense-split-tracker/internal/utils"
@@ -18,7 +20,14 @@ import (
 	"go.uber.org/zap"
 )
 
-const IdempotencyKeyHeader = "Idempotency-Key"
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	ClientIDHeader       = "X-Client-ID"
+)
+
+// idempotencyPollInterval is how often a request waiting on a concurrent
+// request with the same key checks whether it has finished
+const idempotencyPollInterval = 50 * time.Millisecond
 
 type IdempotencyMiddleware struct {
 	repo   repository.IdempotencyRepository
@@ -52,6 +61,27 @@ func (w *responseWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// replayedHeaders are the response headers stored with an idempotency
+// record. Per-request headers such as X-Request-ID or the rate limit headers
+// describe the retry, not the original response, and are left out.
+var replayedHeaders = []string{
+	"Content-Type",
+	"Content-Disposition",
+	"Location",
+	"Link",
+}
+
+// replayHeaders returns the response headers worth replaying, encoded as JSON
+func (w *responseWriter) replayHeaders() ([]byte, error) {
+	headers := http.Header{}
+	for _, name := range replayedHeaders {
+		if values := w.Header().Values(name); len(values) > 0 {
+			headers[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return json.Marshal(headers)
+}
+
 // Handle processes idempotency for specific endpoints that need it
 func (m *IdempotencyMiddleware) Handle() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -88,15 +118,16 @@ func (m *IdempotencyMiddleware) Handle() gin.HandlerFunc {
 		// Restore request body for downstream handlers
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 
-		// Create request hash including method, path, and body
-		requestData := map[string]interface{}{
-			"method": c.Request.Method,
-			"path":   c.Request.URL.Path,
-			"query":  c.Request.URL.RawQuery,
-			"body":   string(body),
+		// Create request hash including method, path, query and canonicalized body
+		fingerprint := utils.RequestFingerprint{
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			Query:       c.Request.URL.RawQuery,
+			ContentType: c.ContentType(),
+			Body:        body,
 		}
 
-		requestHash, err := utils.HashRequest(requestData)
+		requestHash, err := utils.HashRequest(fingerprint)
 		if err != nil {
 			m.logger.Error("Failed to hash request", zap.Error(err))
 			response.Error(c, errors.NewInternalError("Failed to process request"))
@@ -104,30 +135,85 @@ func (m *IdempotencyMiddleware) Handle() gin.HandlerFunc {
 			return
 		}
 
-		// Check if we've seen this idempotency key before
-		existing, err := m.repo.GetByKey(c.Request.Context(), idempotencyKey)
-		if err != nil {
-			m.logger.Error("Failed to get idempotency record", zap.Error(err))
-			response.Error(c, errors.NewInternalError("Failed to process request"))
-			c.Abort()
-			return
-		}
+		// Keys are scoped to the caller so one caller can never replay a
+		// response another caller received
+		caller := idempotencyCaller(c)
 
-		if existing != nil {
-			// Check if the request hash matches
-			if existing.RequestHash != requestHash {
-				response.Error(c, errors.NewIdempotencyError("Idempotency key reused with different request"))
+		// Claim the key before running the handler. A concurrent request with
+		// the same key finds the claim and waits for this one's response
+		// instead of executing again.
+		expiresAt := time.Now().Add(m.config.Features.IdempotencyTTL).Unix()
+		deadline := time.Now().Add(m.config.Features.IdempotencyWait)
+		for {
+			reserved, err := m.repo.Reserve(c.Request.Context(), caller, idempotencyKey, requestHash, expiresAt)
+			if err != nil {
+				m.logger.Error("Failed to reserve idempotency key", zap.Error(err))
+				response.Error(c, errors.NewInternalError("Failed to process request"))
 				c.Abort()
 				return
 			}
+			if reserved {
+				break
+			}
 
-			// Return cached response
-			c.Header("X-Idempotent-Replayed", "true")
-			c.Data(existing.StatusCode, "application/json", existing.ResponseData)
-			c.Abort()
-			return
+			existing, err := m.repo.GetByKey(c.Request.Context(), caller, idempotencyKey)
+			if err != nil {
+				m.logger.Error("Failed to get idempotency record", zap.Error(err))
+				response.Error(c, errors.NewInternalError("Failed to process request"))
+				c.Abort()
+				return
+			}
+
+			// A missing record means the other request failed and released
+			// the key, so the next pass tries to claim it
+			if existing != nil {
+				// Check if the request hash matches, using the algorithm the record was stored with
+				matches, err := utils.RequestHashMatches(existing.RequestHash, fingerprint)
+				if err != nil {
+					m.logger.Error("Failed to compare request hash", zap.Error(err))
+					response.Error(c, errors.NewInternalError("Failed to process request"))
+					c.Abort()
+					return
+				}
+				if !matches {
+					response.Error(c, errors.NewIdempotencyError("Idempotency key reused with different request"))
+					c.Abort()
+					return
+				}
+
+				if existing.Status == repository.IdempotencyStatusCompleted {
+					m.replay(c, existing)
+					return
+				}
+			}
+
+			if time.Now().After(deadline) {
+				c.Header(RetryAfterHeader, "1")
+				response.Error(c, errors.NewProcessingError())
+				c.Abort()
+				return
+			}
+
+			select {
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			case <-time.After(idempotencyPollInterval):
+			}
 		}
 
+		// Store the outcome even if the client goes away mid-request, or the
+		// key would stay reserved until it expires
+		storeCtx := context.WithoutCancel(c.Request.Context())
+
+		// Free the key if the handler panics so the client can retry
+		defer func() {
+			if r := recover(); r != nil {
+				m.release(storeCtx, caller, idempotencyKey)
+				panic(r)
+			}
+		}()
+
 		// Capture response
 		writer := &responseWriter{
 			ResponseWriter: c.Writer,
@@ -139,30 +225,72 @@ func (m *IdempotencyMiddleware) Handle() gin.HandlerFunc {
 		// Process request
 		c.Next()
 
-		// Store idempotency record after successful processing
-		if !c.IsAborted() && writer.status < 500 {
-			expiresAt := time.Now().Add(m.config.Features.IdempotencyTTL).Unix()
+		// Store the response after successful processing; failed requests
+		// give the key back so a retry runs again
+		if c.IsAborted() || writer.status >= 500 {
+			m.release(storeCtx, caller, idempotencyKey)
+			return
+		}
 
-			err = m.repo.Create(
-				c.Request.Context(),
-				nil,
-				idempotencyKey,
-				requestHash,
-				writer.body.Bytes(),
-				writer.status,
-				expiresAt,
-			)
+		headers, err := writer.replayHeaders()
+		if err != nil {
+			m.logger.Error("Failed to encode response headers", zap.Error(err))
+		}
 
-			if err != nil {
-				m.logger.Error("Failed to store idempotency record",
-					zap.Error(err),
-					zap.String("key", idempotencyKey))
-				// Don't fail the request, just log the error
-			}
+		if err := m.repo.Complete(storeCtx, caller, idempotencyKey, writer.body.Bytes(), headers, writer.status); err != nil {
+			m.logger.Error("Failed to store idempotency record",
+				zap.Error(err),
+				zap.String("key", idempotencyKey))
+			// Don't fail the request, just log the error
 		}
 	}
 }
 
+// replay answers with a stored response: its status, body and headers, plus
+// X-Idempotent-Replayed
+func (m *IdempotencyMiddleware) replay(c *gin.Context, record *repository.IdempotencyRecord) {
+	var headers http.Header
+	if len(record.ResponseHeaders) > 0 {
+		if err := json.Unmarshal(record.ResponseHeaders, &headers); err != nil {
+			m.logger.Error("Failed to decode stored response headers", zap.Error(err), zap.String("key", record.KeyValue))
+		}
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+
+	// Records stored before headers were kept only ever held JSON
+	contentType := headers.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	c.Header("X-Idempotent-Replayed", "true")
+	c.Data(record.StatusCode, contentType, record.ResponseData)
+	c.Abort()
+}
+
+// release gives up a reserved key, logging rather than failing the request
+func (m *IdempotencyMiddleware) release(ctx context.Context, caller, key string) {
+	if err := m.repo.Release(ctx, caller, key); err != nil {
+		m.logger.Error("Failed to release idempotency key", zap.Error(err), zap.String("key", key))
+	}
+}
+
+// idempotencyCaller names the scope an Idempotency-Key belongs to: the
+// identified user, else the X-Client-ID header, else a shared anonymous scope
+func idempotencyCaller(c *gin.Context) string {
+	if identity, ok := auth.IdentityFromContext(c.Request.Context()); ok && !identity.IsAnonymous() {
+		return "user:" + identity.UserUUID.String()
+	}
+	if clientID := c.GetHeader(ClientIDHeader); validHeaderID.MatchString(clientID) {
+		return "client:" + clientID
+	}
+	return ""
+}
+
 // shouldApplyIdempotency determines if idempotency should be applied to the request
 // Only apply to financial operations that could cause duplicate charges/payments
 func (m *IdempotencyMiddleware) shouldApplyIdempotency(method, path string) bool {
@@ -175,7 +303,7 @@ func (m *IdempotencyMiddleware) shouldApplyIdempotency(method, path string) bool
 
 	// Define endpoints that need idempotency (financial operations)
 	idempotentEndpoints := []string{
-		"/api/v1/expenses",    // Creating expenses - critical for financial accuracy
+		"/api/v1/expenses",    // Creating and duplicating expenses - critical for financial accuracy
 		"/api/v1/settlements", // Recording payments - critical for financial accuracy
 		// "/api/v1/groups",      // Creating groups - temporarily disabled for testing
 	}
@@ -186,18 +314,41 @@ func (m *IdempotencyMiddleware) shouldApplyIdempotency(method, path string) bool
 		}
 	}
 
+	// CSV imports create expenses in bulk under the group path
+	if strings.HasPrefix(path, "/api/v1/groups/") && strings.HasSuffix(path, "/expenses/import") {
+		return true
+	}
+
+	// Executing debt simplification records settlements in bulk
+	if strings.HasPrefix(path, "/api/v1/groups/") && strings.HasSuffix(path, "/simplify-debts/execute") {
+		return true
+	}
+
+	// Settling all of a user's debts records settlements in bulk
+	if strings.HasPrefix(path, "/api/v1/groups/") && strings.HasSuffix(path, "/settle-all") {
+		return true
+	}
+
 	return false
 }
 
-// CleanupExpiredKeys periodically cleans up expired idempotency keys
-func (m *IdempotencyMiddleware) CleanupExpiredKeys() {
-	ticker := time.NewTicker(1 * time.Hour)
+// CleanupExpiredKeys deletes expired idempotency keys once on startup and
+// then on every cleanup interval until ctx is cancelled
+func (m *IdempotencyMiddleware) CleanupExpiredKeys(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Features.IdempotencyCleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		err := m.repo.DeleteExpired(context.TODO(), nil)
-		if err != nil {
+	for {
+		err := m.repo.DeleteExpired(ctx, nil)
+		if err != nil && ctx.Err() == nil {
 			m.logger.Error("Failed to cleanup expired idempotency keys", zap.Error(err))
 		}
+
+		select {
+		case <-ctx.Done():
+			m.logger.Info("Idempotency key cleanup stopped")
+			return
+		case <-ticker.C:
+		}
 	}
 }
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"expense-split-tracker/internal/auth"
+	"expense-split-tracker/internal/config"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const UserUUIDHeader = "X-User-UUID"
+
+// IdentityMiddleware resolves the caller of a request from the X-User-UUID
+// header so services can check group membership
+type IdentityMiddleware struct {
+	userRepo repository.UserRepository
+	config   *config.Config
+	logger   *zap.Logger
+}
+
+// NewIdentityMiddleware creates a new identity middleware
+func NewIdentityMiddleware(userRepo repository.UserRepository, config *config.Config, logger *zap.Logger) *IdentityMiddleware {
+	return &IdentityMiddleware{
+		userRepo: userRepo,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// Handle attaches the caller identity to the request context. When group
+// membership enforcement is disabled no identity is attached and services
+// skip their membership checks.
+func (m *IdentityMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.config.Security.EnforceGroupMembership {
+			c.Next()
+			return
+		}
+
+		identity := &auth.Identity{}
+
+		if userUUID := c.GetHeader(UserUUIDHeader); userUUID != "" {
+			if !utils.IsValidUUID(userUUID) {
+				response.Error(c, errors.NewUnauthorizedError("Invalid "+UserUUIDHeader+" header"))
+				c.Abort()
+				return
+			}
+
+			user, err := m.userRepo.GetByUUID(c.Request.Context(), userUUID)
+			if err != nil {
+				if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrCodeNotFound {
+					response.Error(c, errors.NewUnauthorizedError("Unknown user in "+UserUUIDHeader+" header"))
+				} else {
+					m.logger.Error("Failed to resolve caller identity", zap.Error(err))
+					response.Error(c, errors.NewInternalError("Failed to resolve caller identity"))
+				}
+				c.Abort()
+				return
+			}
+
+			identity.UserID = user.ID
+			identity.UserUUID = models.UserUUID(user.UUID)
+		}
+
+		c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), identity))
+		c.Next()
+	}
+}
@@ -3,6 +3,8 @@ package middleware
 import (
 	"time"
 
+	"expense-split-tracker/pkg/response"
+
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -52,7 +54,7 @@ func StructuredLoggingMiddleware(logger *zap.Logger) gin.HandlerFunc {
 		}
 
 		// Add request ID if present
-		if requestID := c.GetString("request_id"); requestID != "" {
+		if requestID := c.GetString(response.RequestIDKey); requestID != "" {
 			fields = append(fields, zap.String("request_id", requestID))
 		}
 
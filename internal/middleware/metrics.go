@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"time"
+
+	"expense-split-tracker/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that hit no registered route, so scanners
+// probing random paths cannot grow the metric's label set without bound
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware records the count, status and duration of every request
+// against its route template rather than the raw path
+func MetricsMiddleware(recorder metrics.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		recorder.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
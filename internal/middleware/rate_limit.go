@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitBurstHeader     = "X-RateLimit-Burst"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// unlimitedPaths are probed by infrastructure rather than clients
+var unlimitedPaths = map[string]bool{
+	"/health":      true,
+	"/health/live": true,
+	"/metrics":     true,
+}
+
+// RateLimit is the size of a client's token bucket: it refills at PerMinute
+// tokens a minute and holds at most Burst tokens
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// RateLimitResult is the outcome of taking a token from a client's bucket
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// RateLimiter hands out request tokens per client key. The in-memory
+// implementation limits each server instance on its own; a shared store can
+// implement the same interface to limit across instances.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (*RateLimitResult, error)
+	Limit() RateLimit
+}
+
+// RateLimitMiddleware rejects clients that exceed their request rate
+type RateLimitMiddleware struct {
+	limiter RateLimiter
+	logger  *zap.Logger
+}
+
+// NewRateLimitMiddleware creates a new rate limit middleware
+func NewRateLimitMiddleware(limiter RateLimiter, logger *zap.Logger) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limiter: limiter,
+		logger:  logger,
+	}
+}
+
+// Handle takes a token for the calling client and answers 429 once its bucket
+// is empty. Health checks and metrics scrapes are never limited.
+func (m *RateLimitMiddleware) Handle() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if unlimitedPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		limit := m.limiter.Limit()
+		c.Header(RateLimitLimitHeader, strconv.Itoa(limit.PerMinute))
+		c.Header(RateLimitBurstHeader, strconv.Itoa(limit.Burst))
+
+		result, err := m.limiter.Allow(c.Request.Context(), rateLimitKey(c))
+		if err != nil {
+			// A broken limiter should not take the API down with it
+			m.logger.Error("Rate limiter failed, allowing request", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header(RetryAfterHeader, strconv.Itoa(retryAfter))
+			response.Error(c, errors.NewRateLimitedError(retryAfter))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey buckets callers by IP. The X-User-UUID header is not
+// authenticated, so keying on it would let a client reset its bucket by
+// sending a fresh UUID with every request.
+func rateLimitKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
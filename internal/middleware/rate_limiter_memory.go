@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is how long an untouched bucket is kept. By then it has
+// refilled completely, so dropping it does not change any client's limit.
+const idleBucketTTL = 10 * time.Minute
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// MemoryRateLimiter is a token bucket limiter kept in process memory
+type MemoryRateLimiter struct {
+	limit   RateLimit
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// NewMemoryRateLimiter creates a new in-memory rate limiter
+func NewMemoryRateLimiter(limit RateLimit) *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		limit:   limit,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Limit returns the bucket size the limiter applies to every client
+func (l *MemoryRateLimiter) Limit() RateLimit {
+	return l.limit
+}
+
+// Allow refills the client's bucket for the time since its last request and
+// takes one token from it if there is one
+func (l *MemoryRateLimiter) Allow(ctx context.Context, key string) (*RateLimitResult, error) {
+	now := time.Now()
+	ratePerSecond := float64(l.limit.PerMinute) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.limit.Burst), lastSeen: now}
+		l.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.lastSeen).Seconds()
+	bucket.tokens = math.Min(float64(l.limit.Burst), bucket.tokens+elapsed*ratePerSecond)
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		wait := (1 - bucket.tokens) / ratePerSecond
+		return &RateLimitResult{
+			Allowed:    false,
+			Remaining:  0,
+			RetryAfter: time.Duration(wait * float64(time.Second)),
+		}, nil
+	}
+
+	bucket.tokens--
+	return &RateLimitResult{
+		Allowed:   true,
+		Remaining: int(bucket.tokens),
+	}, nil
+}
+
+// CleanupIdleBuckets periodically drops buckets of clients that went quiet
+// until ctx is cancelled
+func (l *MemoryRateLimiter) CleanupIdleBuckets(ctx context.Context) {
+	ticker := time.NewTicker(idleBucketTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.removeIdle(now)
+		}
+	}
+}
+
+func (l *MemoryRateLimiter) removeIdle(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.lastSeen) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"regexp"
+
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// validHeaderID limits client-supplied IDs (request and client IDs) to
+// something safe to log, echo and store
+var validHeaderID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestIDMiddleware tags every request with an ID, reusing the caller's
+// X-Request-ID when it is well formed. The ID is echoed in the response
+// header, stored for logging and error payloads, and carried in the request
+// context for services and repositories.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validHeaderID.MatchString(requestID) {
+			requestID = utils.GenerateUUID()
+		}
+
+		c.Set(response.RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+	}
+}
@@ -20,13 +20,56 @@ type Balance struct {
 	User  *User  `json:"user,omitempty"`
 }
 
-// BalanceSheet represents the complete balance sheet for a group
+// BalanceSheet represents the complete balance sheet for a group. A sheet for
+// one requested currency fills Balances, Summary and Currency; a sheet for all
+// of the group's currencies fills Sections instead.
 type BalanceSheet struct {
-	Group     *Group          `json:"group"`
-	Balances  []*UserBalance  `json:"balances"`
-	Summary   *BalanceSummary `json:"summary"`
-	Currency  string          `json:"currency"`
-	UpdatedAt time.Time       `json:"updated_at"`
+	Group     *Group                    `json:"group"`
+	Balances  []*UserBalance            `json:"balances,omitempty"`
+	Summary   *BalanceSummary           `json:"summary,omitempty"`
+	Currency  string                    `json:"currency,omitempty"`
+	Sections  []*CurrencyBalanceSection `json:"currencies,omitempty"`
+	UpdatedAt time.Time                 `json:"updated_at"`
+
+	// Conversion is set when the sheet is requested with convert_to
+	Conversion *BalanceConversion `json:"conversion,omitempty"`
+}
+
+// CurrencyBalanceSection holds the balances of a group in one currency
+type CurrencyBalanceSection struct {
+	Currency     string          `json:"currency"`
+	Balances     []*UserBalance  `json:"balances"`
+	Summary      *BalanceSummary `json:"summary"`
+	ExchangeRate *ExchangeRate   `json:"exchange_rate,omitempty"`
+}
+
+// ExchangeRate is the rate that converts one unit of From into To, as of a
+// point in time
+type ExchangeRate struct {
+	From string          `json:"from"`
+	To   string          `json:"to"`
+	Rate decimal.Decimal `json:"rate"`
+	AsOf time.Time       `json:"as_of"`
+}
+
+// BalanceConversion reports balances converted into a single currency with
+// the rates used for each original currency. On a balance sheet Balances
+// holds each member's converted total across currencies; on a user's
+// balances NetBalance is their converted net across groups and currencies.
+// Converted figures are approximations and are rounded to the currency.
+type BalanceConversion struct {
+	Currency   string                  `json:"currency"`
+	Rates      []*ExchangeRate         `json:"rates"`
+	Balances   []*ConvertedUserBalance `json:"balances,omitempty"`
+	NetBalance *decimal.Decimal        `json:"net_balance,omitempty"`
+}
+
+// ConvertedUserBalance is a member's balance across currencies in the
+// conversion currency
+type ConvertedUserBalance struct {
+	UserID  int64           `json:"user_id"`
+	User    *User           `json:"user,omitempty"`
+	Balance decimal.Decimal `json:"balance"`
 }
 
 // BalanceSummary represents summary statistics for a balance sheet
@@ -39,21 +82,186 @@ type BalanceSummary struct {
 
 // UserBalanceDetail represents detailed balance information for a user
 type UserBalanceDetail struct {
-	User         *User             `json:"user"`
-	Balance      decimal.Decimal   `json:"balance"`
-	Currency     string            `json:"currency"`
-	Breakdown    *BalanceBreakdown `json:"breakdown"`
-	Settlements  []*Settlement     `json:"recent_settlements,omitempty"`
-	LastActivity time.Time         `json:"last_activity"`
+	User         *User               `json:"user"`
+	Balance      decimal.Decimal     `json:"balance"`
+	Currency     string              `json:"currency"`
+	Breakdown    *BalanceBreakdown   `json:"breakdown"`
+	Settlements  []*Settlement       `json:"recent_settlements,omitempty"`
+	Debts        []*DebtRelationship `json:"debts,omitempty"`
+	LastActivity time.Time           `json:"last_activity"`
 }
 
-// BalanceBreakdown represents the breakdown of how a balance is calculated
+// UserBalanceOverview gathers a user's balances in all of their groups
+type UserBalanceOverview struct {
+	User       *User                   `json:"user"`
+	Currencies []*UserCurrencyPosition `json:"currencies"`
+
+	// Conversion is set when the balances are requested with convert_to
+	Conversion *BalanceConversion `json:"conversion,omitempty"`
+}
+
+// UserCurrencyPosition holds a user's group balances in one currency and their
+// net across those groups; a positive NetBalance means the user owes that much
+// overall, a negative one that they are owed it
+type UserCurrencyPosition struct {
+	Currency            string           `json:"currency"`
+	Balances            []*Balance       `json:"balances"`
+	NetBalance          decimal.Decimal  `json:"net_balance"`
+	ConvertedNetBalance *decimal.Decimal `json:"converted_net_balance,omitempty"`
+	ExchangeRate        *ExchangeRate    `json:"exchange_rate,omitempty"`
+}
+
+// BalanceBreakdown represents the breakdown of how a balance is calculated.
+// TotalSettled is what the user received through settlements minus what they
+// sent, so TotalPaid - TotalOwed - TotalSettled is what the user is owed, the
+// negated balance.
 type BalanceBreakdown struct {
-	TotalPaid    decimal.Decimal `json:"total_paid"`
-	TotalOwed    decimal.Decimal `json:"total_owed"`
-	TotalSettled decimal.Decimal `json:"total_settled"`
-	ExpenseCount int             `json:"expense_count"`
-	PaymentCount int             `json:"payment_count"`
+	TotalPaid       decimal.Decimal `json:"total_paid"`
+	TotalOwed       decimal.Decimal `json:"total_owed"`
+	TotalSettled    decimal.Decimal `json:"total_settled"`
+	SettledSent     decimal.Decimal `json:"settled_sent"`
+	SettledReceived decimal.Decimal `json:"settled_received"`
+	ExpenseCount    int             `json:"expense_count"`
+	PaymentCount    int             `json:"payment_count"`
+}
+
+// ExpenseTotals aggregates a user's expenses in a group and currency: what
+// they paid, the sum of their splits and how many expenses they took part in
+type ExpenseTotals struct {
+	Currency string          `db:"currency"`
+	Paid     decimal.Decimal `db:"paid"`
+	Owed     decimal.Decimal `db:"owed"`
+	Count    int             `db:"count"`
+}
+
+// SettlementTotals aggregates a user's confirmed settlements in a group and
+// currency
+type SettlementTotals struct {
+	Currency string          `db:"currency"`
+	Sent     decimal.Decimal `db:"sent"`
+	Received decimal.Decimal `db:"received"`
+	Count    int             `db:"count"`
+}
+
+// BalanceRebuild reports the outcome of recomputing a group's balances and
+// pairwise debts from its expenses and settlements
+type BalanceRebuild struct {
+	Group            *Group               `json:"group"`
+	Adjustments      []*BalanceAdjustment `json:"adjustments"`
+	DriftedCount     int                  `json:"drifted_count"`
+	DebtAdjustments  []*DebtAdjustment    `json:"debt_adjustments"`
+	DriftedDebtCount int                  `json:"drifted_debt_count"`
+	RebuiltAt        time.Time            `json:"rebuilt_at"`
+}
+
+// BalanceAdjustment compares a user's stored balance in one currency with the
+// recomputed one; a non-zero Delta means the stored balance had drifted
+type BalanceAdjustment struct {
+	User       *User           `json:"user"`
+	Currency   string          `json:"currency"`
+	Previous   decimal.Decimal `json:"previous_balance"`
+	Recomputed decimal.Decimal `json:"recomputed_balance"`
+	Delta      decimal.Decimal `json:"delta"`
+}
+
+// DebtAdjustment compares what Debtor owed Creditor in one currency with the
+// recomputed debt. The pair is oriented so the recomputed amount is not
+// negative; a negative Previous means the stored debt ran the other way.
+type DebtAdjustment struct {
+	Debtor     *User           `json:"debtor"`
+	Creditor   *User           `json:"creditor"`
+	Currency   string          `json:"currency"`
+	Previous   decimal.Decimal `json:"previous_amount"`
+	Recomputed decimal.Decimal `json:"recomputed_amount"`
+	Delta      decimal.Decimal `json:"delta"`
+}
+
+// BalanceVerification reports whether a group's stored balances agree with its
+// expense and settlement history in every currency
+type BalanceVerification struct {
+	Group      *Group                  `json:"group"`
+	Currencies []*CurrencyBalanceCheck `json:"currencies"`
+	Consistent bool                    `json:"consistent"`
+	VerifiedAt time.Time               `json:"verified_at"`
+}
+
+// CurrencyBalanceCheck compares the sum of a group's stored balances in one
+// currency with the sum recomputed from history. Both should be zero; a
+// currency is consistent when the stored sum is within a cent of zero and of
+// the recomputed sum.
+type CurrencyBalanceCheck struct {
+	Currency      string          `json:"currency"`
+	StoredSum     decimal.Decimal `json:"stored_sum"`
+	RecomputedSum decimal.Decimal `json:"recomputed_sum"`
+	Drift         decimal.Decimal `json:"drift"`
+	Consistent    bool            `json:"consistent"`
+}
+
+// UserCounterparties splits a user's pairwise debts in a group into the
+// members who owe them and the members they owe
+type UserCounterparties struct {
+	User      *User           `json:"user"`
+	Currency  string          `json:"currency"`
+	Debtors   []*Counterparty `json:"debtors"`
+	Creditors []*Counterparty `json:"creditors"`
+}
+
+// Counterparty is the other side of one of a user's pairwise debts
+type Counterparty struct {
+	User   *User           `json:"user"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// PairDebt is how much one user owes another in one group and currency;
+// negative when the other user is the one in debt
+type PairDebt struct {
+	Currency string          `db:"currency"`
+	Amount   decimal.Decimal `db:"amount"`
+}
+
+// NetDirection says which way a pairwise net points, seen from the user asked about
+type NetDirection string
+
+const (
+	NetDirectionOwes    NetDirection = "owes"
+	NetDirectionOwed    NetDirection = "owed"
+	NetDirectionSettled NetDirection = "settled"
+)
+
+// NetDirectionOf is the direction of a net amount that is positive when the
+// user owes the other user
+func NetDirectionOf(amount decimal.Decimal) NetDirection {
+	switch amount.Sign() {
+	case 1:
+		return NetDirectionOwes
+	case -1:
+		return NetDirectionOwed
+	}
+	return NetDirectionSettled
+}
+
+// PairwiseNet nets what a user and another user owe each other across the
+// groups they share, one section per currency
+type PairwiseNet struct {
+	User       *User                  `json:"user"`
+	OtherUser  *User                  `json:"other_user"`
+	Currencies []*PairwiseCurrencyNet `json:"currencies"`
+}
+
+// PairwiseCurrencyNet is the net between two users in one currency. Amount is
+// never negative; Direction says whether the user owes it or is owed it.
+type PairwiseCurrencyNet struct {
+	Currency  string               `json:"currency"`
+	Direction NetDirection         `json:"direction"`
+	Amount    decimal.Decimal      `json:"amount"`
+	Groups    []*PairwiseGroupDebt `json:"groups"`
+}
+
+// PairwiseGroupDebt is the pairwise debt between two users in one shared group
+type PairwiseGroupDebt struct {
+	Group     *Group          `json:"group"`
+	Direction NetDirection    `json:"direction"`
+	Amount    decimal.Decimal `json:"amount"`
 }
 
 // DebtRelationship represents a debt relationship between two users
@@ -64,7 +272,49 @@ type DebtRelationship struct {
 	Currency string          `json:"currency"`
 }
 
+// BalanceHistorySource identifies what kind of record changed a balance
+type BalanceHistorySource string
+
+const (
+	BalanceHistorySourceExpense    BalanceHistorySource = "expense"
+	BalanceHistorySourceSettlement BalanceHistorySource = "settlement"
+	// BalanceHistorySourceRebuild entries correct drift found by a balance
+	// rebuild; their source is the group
+	BalanceHistorySourceRebuild BalanceHistorySource = "rebuild"
+)
+
+// BalanceHistoryEntry is one change to a user's balance in a group. Balance is
+// the user's balance right after the change; SourceUUID is filled when read.
+type BalanceHistoryEntry struct {
+	ID         int64                `json:"id" db:"id"`
+	GroupID    int64                `json:"group_id" db:"group_id"`
+	UserID     int64                `json:"user_id" db:"user_id"`
+	Currency   string               `json:"currency" db:"currency"`
+	Delta      decimal.Decimal      `json:"delta" db:"delta"`
+	Balance    decimal.Decimal      `json:"balance" db:"balance"`
+	SourceType BalanceHistorySource `json:"source_type" db:"source_type" enums:"expense,settlement,rebuild"`
+	SourceID   int64                `json:"source_id" db:"source_id"`
+	SourceUUID string               `json:"source_uuid,omitempty" db:"source_uuid"`
+	CreatedAt  time.Time            `json:"created_at" db:"created_at"`
+}
+
+// BalanceHistoryFilter selects a user's balance history in a group
+type BalanceHistoryFilter struct {
+	GroupID  int64     `json:"-"`
+	UserID   int64     `json:"-"`
+	Currency string    `json:"currency,omitempty"`
+	FromDate time.Time `json:"from,omitempty"`
+	ToDate   time.Time `json:"to,omitempty"`
+	Page     int       `json:"page,omitempty"`
+	Limit    int       `json:"limit,omitempty"`
+}
+
 // TableName returns the table name for Balance model
 func (Balance) TableName() string {
 	return "user_balances"
 }
+
+// TableName returns the table name for BalanceHistoryEntry model
+func (BalanceHistoryEntry) TableName() string {
+	return "balance_history"
+}
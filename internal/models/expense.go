@@ -1,8 +1,12 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
+	"expense-split-tracker/pkg/errors"
+
 	"github.com/shopspring/decimal"
 )
 
@@ -13,8 +17,66 @@ const (
 	SplitTypeEqual      SplitType = "equal"
 	SplitTypeExact      SplitType = "exact"
 	SplitTypePercentage SplitType = "percentage"
+	SplitTypeShares     SplitType = "shares"
 )
 
+// AllSplitTypes returns every supported split type
+func AllSplitTypes() []SplitType {
+	return []SplitType{SplitTypeEqual, SplitTypeExact, SplitTypePercentage, SplitTypeShares}
+}
+
+// Validate checks that the split type is one of AllSplitTypes
+func (t SplitType) Validate() error {
+	allowed := make([]string, 0, len(AllSplitTypes()))
+	for _, known := range AllSplitTypes() {
+		if t == known {
+			return nil
+		}
+		allowed = append(allowed, string(known))
+	}
+
+	err := errors.NewInvalidValueError("split_type", string(t))
+	err.Details = map[string]string{
+		"field":   "split_type",
+		"allowed": strings.Join(allowed, ","),
+	}
+	return err
+}
+
+// UnmarshalJSON rejects unknown split types while decoding the request body.
+// An empty value is left for request validation to report.
+func (t *SplitType) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	splitType := SplitType(value)
+	if splitType != "" {
+		if err := splitType.Validate(); err != nil {
+			return err
+		}
+	}
+
+	*t = splitType
+	return nil
+}
+
+// SplitInclude controls how much split detail expense lists embed
+type SplitInclude string
+
+const (
+	// SplitIncludeFull embeds every split with its user. It remains the default
+	// for compatibility; the default will move to SplitIncludeSummary once
+	// clients have migrated.
+	SplitIncludeFull    SplitInclude = "splits"
+	SplitIncludeSummary SplitInclude = "splits_summary"
+	SplitIncludeNone    SplitInclude = "none"
+)
+
+// DefaultExpenseCategory is used when an expense is created without a category
+const DefaultExpenseCategory = "uncategorized"
+
 // Expense represents an expense in the system
 type Expense struct {
 	ID          int64           `json:"id" db:"id"`
@@ -24,38 +86,147 @@ type Expense struct {
 	Amount      decimal.Decimal `json:"amount" db:"amount"`
 	Currency    string          `json:"currency" db:"currency"`
 	Description string          `json:"description" db:"description"`
-	SplitType   SplitType       `json:"split_type" db:"split_type"`
-	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+	Category    string          `json:"category" db:"category"`
+
+	// BaseAmount is Amount converted at ExchangeRate into BaseCurrency, the
+	// group's currency when the expense was recorded. Splits and balances are
+	// in BaseCurrency; the rate is 1 when the two currencies match.
+	BaseAmount   decimal.Decimal `json:"base_amount" db:"base_amount"`
+	BaseCurrency string          `json:"base_currency" db:"base_currency"`
+	ExchangeRate decimal.Decimal `json:"exchange_rate" db:"exchange_rate"`
+
+	SplitType   SplitType `json:"split_type" db:"split_type"`
+	IsRefund    bool      `json:"is_refund" db:"is_refund"`
+	ReceiptURL  string    `json:"receipt_url,omitempty" db:"receipt_url"`
+	ExpenseDate time.Time `json:"expense_date" db:"expense_date"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+
+	// DeletedAt is set once the expense is deleted; deleted expenses are kept
+	// for history and only listed on request. DeletedBy is the acting user's
+	// ID, nil when the request carried no identity.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	DeletedBy *int64     `json:"deleted_by,omitempty" db:"deleted_by"`
+
+	// Recurrence is set on expenses created by a recurring expense run
+	Recurrence *ExpenseRecurrence `json:"-"`
 
 	// Relationships
-	Group  *Group          `json:"group,omitempty"`
-	Payer  *User           `json:"payer,omitempty"`
-	Splits []*ExpenseSplit `json:"splits,omitempty"`
+	Group         *Group                `json:"group,omitempty"`
+	Payer         *User                 `json:"payer,omitempty"`
+	Splits        []*ExpenseSplit       `json:"splits,omitempty"`
+	SplitsSummary *ExpenseSplitsSummary `json:"splits_summary,omitempty"`
+	Items         []*ExpenseItem        `json:"items,omitempty"`
+}
+
+// ExpenseSplitsSummary represents the trimmed split detail of a listed expense
+type ExpenseSplitsSummary struct {
+	ParticipantCount int              `json:"participant_count"`
+	ViewerShare      *decimal.Decimal `json:"viewer_share"`
 }
 
-// ExpenseSplit represents how an expense is split among users
+// ExpenseSplit represents how an expense is split among users. Amount is in
+// the expense's base currency.
 type ExpenseSplit struct {
 	ID         int64           `json:"id" db:"id"`
 	ExpenseID  int64           `json:"expense_id" db:"expense_id"`
 	UserID     int64           `json:"user_id" db:"user_id"`
 	Amount     decimal.Decimal `json:"amount" db:"amount"`
 	Percentage decimal.Decimal `json:"percentage" db:"percentage"`
+	Shares     int             `json:"shares,omitempty" db:"shares"`
 	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
 
 	// Relationships
 	User *User `json:"user,omitempty"`
 }
 
-// CreateExpenseRequest represents the request to create a new expense
+// ExpenseItem represents a line item of an itemized expense
+type ExpenseItem struct {
+	ID          int64           `json:"id" db:"id"`
+	ExpenseID   int64           `json:"expense_id" db:"expense_id"`
+	Position    int             `json:"position" db:"position"`
+	Description string          `json:"description" db:"description"`
+	Amount      decimal.Decimal `json:"amount" db:"amount"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+
+	// Relationships
+	Shares []*ExpenseItemShare `json:"shares"`
+}
+
+// ExpenseItemShare represents one user's share of a line item
+type ExpenseItemShare struct {
+	ID     int64           `json:"id" db:"id"`
+	ItemID int64           `json:"item_id" db:"item_id"`
+	UserID int64           `json:"user_id" db:"user_id"`
+	Amount decimal.Decimal `json:"amount" db:"amount"`
+
+	// Relationships
+	User *User `json:"user,omitempty"`
+}
+
+// CreateExpenseRequest represents the request to create a new expense.
+// Splits may be omitted for equal splits to include every group member. When
+// Items are given the splits are derived from them and split_type defaults to
+// exact. A refund is sent with positive amounts and IsRefund set; it is
+// stored negated so it credits the participants and debits the payer.
 type CreateExpenseRequest struct {
 	GroupUUID   string                      `json:"group_uuid" binding:"required"`
 	PaidByUUID  string                      `json:"paid_by_uuid" binding:"required"`
 	Amount      decimal.Decimal             `json:"amount" binding:"required"`
 	Currency    string                      `json:"currency,omitempty"`
 	Description string                      `json:"description" binding:"required"`
-	SplitType   SplitType                   `json:"split_type" binding:"required"`
-	Splits      []CreateExpenseSplitRequest `json:"splits" binding:"required"`
+	Category    string                      `json:"category,omitempty"`
+	ExpenseDate time.Time                   `json:"expense_date,omitempty"`
+	SplitType   SplitType                   `json:"split_type" enums:"equal,exact,percentage,shares"`
+	Splits      []CreateExpenseSplitRequest `json:"splits"`
+	Items       []ExpenseItemRequest        `json:"items,omitempty"`
+	IsRefund    bool                        `json:"is_refund,omitempty"`
+	ReceiptURL  string                      `json:"receipt_url,omitempty"`
+
+	// ExchangeRate converts a currency other than the group's into the
+	// group's currency; when omitted the rate provider's current rate is used
+	ExchangeRate *decimal.Decimal `json:"exchange_rate,omitempty"`
+
+	// Recurrence is set by the recurring expense scheduler, never by clients
+	Recurrence *ExpenseRecurrence `json:"-"`
+}
+
+// UpdateExpenseRequest represents the request to replace an expense's amount and splits.
+// The group and payer of an expense cannot be changed; an omitted expense_date
+// is left unchanged. Refunds stay refunds and are updated with positive amounts.
+// An omitted exchange_rate keeps the recorded rate while the currency is
+// unchanged.
+type UpdateExpenseRequest struct {
+	Amount       decimal.Decimal             `json:"amount" binding:"required"`
+	Currency     string                      `json:"currency,omitempty"`
+	ExchangeRate *decimal.Decimal            `json:"exchange_rate,omitempty"`
+	Description  string                      `json:"description" binding:"required"`
+	ExpenseDate  time.Time                   `json:"expense_date,omitempty"`
+	SplitType    SplitType                   `json:"split_type" binding:"required" enums:"equal,exact,percentage,shares"`
+	Splits       []CreateExpenseSplitRequest `json:"splits" binding:"required"`
+}
+
+// ExpenseItemRequest represents a line item in the expense creation request.
+// Its amount is divided equally among the listed users.
+type ExpenseItemRequest struct {
+	Description string          `json:"description" binding:"required"`
+	Amount      decimal.Decimal `json:"amount" binding:"required"`
+	UserUUIDs   []string        `json:"user_uuids" binding:"required"`
+}
+
+// SetReceiptRequest represents the request to attach a receipt to an expense.
+// An empty URL removes the receipt.
+type SetReceiptRequest struct {
+	ReceiptURL string `json:"receipt_url"`
+}
+
+// DuplicateExpenseRequest represents the optional overrides applied when an
+// expense is copied into a new one. Omitted fields keep the original's values,
+// except expense_date, which defaults to now.
+type DuplicateExpenseRequest struct {
+	Amount      *decimal.Decimal `json:"amount,omitempty"`
+	Description string           `json:"description,omitempty"`
+	ExpenseDate time.Time        `json:"expense_date,omitempty"`
 }
 
 // CreateExpenseSplitRequest represents a split in the expense creation request
@@ -63,6 +234,26 @@ type CreateExpenseSplitRequest struct {
 	UserUUID   string          `json:"user_uuid" binding:"required"`
 	Amount     decimal.Decimal `json:"amount,omitempty"`
 	Percentage decimal.Decimal `json:"percentage,omitempty"`
+	Shares     int             `json:"shares,omitempty"`
+}
+
+// ExpenseImportResult reports the outcome of a CSV expense import. Valid
+// counts rows that passed validation; Imported stays zero on a dry run.
+type ExpenseImportResult struct {
+	DryRun    bool                    `json:"dry_run"`
+	TotalRows int                     `json:"total_rows"`
+	Valid     int                     `json:"valid"`
+	Imported  int                     `json:"imported"`
+	Skipped   int                     `json:"skipped"`
+	Errors    []ExpenseImportRowError `json:"errors"`
+}
+
+// ExpenseImportRowError describes why a CSV row was skipped. Row is the line
+// number in the uploaded file, the header being line 1.
+type ExpenseImportRowError struct {
+	Row     int    `json:"row"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 // ExpenseListResponse represents the response for listing expenses
@@ -73,16 +264,51 @@ type ExpenseListResponse struct {
 	Limit      int        `json:"limit"`
 }
 
+// ExpenseListOptions represents the split detail options for expense lists.
+// ViewerUUID identifies whose share is reported by SplitIncludeSummary.
+// IncludeDeleted also returns deleted expenses, which are hidden by default.
+type ExpenseListOptions struct {
+	Include        SplitInclude `json:"include,omitempty"`
+	ViewerUUID     string       `json:"viewer_uuid,omitempty"`
+	IncludeDeleted bool         `json:"include_deleted,omitempty"`
+}
+
+// TopExpensesFilter selects a group's largest expenses. Dates match
+// expense_date and zero values leave that end of the range open.
+type TopExpensesFilter struct {
+	FromDate time.Time `json:"from_date,omitempty"`
+	ToDate   time.Time `json:"to_date,omitempty"`
+	Limit    int       `json:"limit,omitempty"`
+
+	ExpenseListOptions
+}
+
 // ExpenseFilter represents filters for expense queries
 type ExpenseFilter struct {
-	GroupUUID string    `json:"group_uuid,omitempty"`
-	UserUUID  string    `json:"user_uuid,omitempty"`
-	FromDate  time.Time `json:"from_date,omitempty"`
-	ToDate    time.Time `json:"to_date,omitempty"`
-	Currency  string    `json:"currency,omitempty"`
-	SplitType SplitType `json:"split_type,omitempty"`
-	Page      int       `json:"page,omitempty"`
-	Limit     int       `json:"limit,omitempty"`
+	GroupUUID string          `json:"group_uuid,omitempty"`
+	UserUUID  string          `json:"user_uuid,omitempty"`
+	FromDate  time.Time       `json:"from_date,omitempty"`
+	ToDate    time.Time       `json:"to_date,omitempty"`
+	Currency  string          `json:"currency,omitempty"`
+	Category  string          `json:"category,omitempty"`
+	Query     string          `json:"q,omitempty"`
+	SplitType SplitType       `json:"split_type,omitempty" enums:"equal,exact,percentage,shares"`
+	MinAmount decimal.Decimal `json:"min_amount,omitempty"`
+	MaxAmount decimal.Decimal `json:"max_amount,omitempty"`
+	Page      int             `json:"page,omitempty"`
+	Limit     int             `json:"limit,omitempty"`
+
+	ListSort
+	ExpenseListOptions
+}
+
+// ExpenseCurrencyTotal aggregates a group's expenses in one currency. Refunds
+// are stored as negative amounts, so TotalAmount is the net spend.
+type ExpenseCurrencyTotal struct {
+	Currency      string          `json:"currency" db:"currency"`
+	ExpenseCount  int             `json:"expense_count" db:"expense_count"`
+	TotalAmount   decimal.Decimal `json:"total_amount" db:"total_amount"`
+	LastExpenseAt time.Time       `json:"last_expense_at" db:"last_expense_at"`
 }
 
 // TableName returns the table name for Expense model
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Export formats accepted by the group export endpoint
+const (
+	ExportFormatCSV = "csv"
+)
+
+// ExportFilter restricts a group export to a date range. Expenses are matched
+// on expense_date and settlements on created_at; both ends are inclusive
+// calendar days and zero values leave that end open.
+type ExportFilter struct {
+	FromDate time.Time `json:"from_date,omitempty"`
+	ToDate   time.Time `json:"to_date,omitempty"`
+}
@@ -1,60 +1,211 @@
 package models
 
 import (
+	"strings"
 	"time"
+
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// GroupRole is a member's role in a group. Admins manage the group's members
+// and settings.
+type GroupRole string
+
+const (
+	GroupRoleAdmin  GroupRole = "admin"
+	GroupRoleMember GroupRole = "member"
 )
 
+// AllGroupRoles returns every group role
+func AllGroupRoles() []GroupRole {
+	return []GroupRole{GroupRoleAdmin, GroupRoleMember}
+}
+
+// Validate checks that the role is one of AllGroupRoles
+func (r GroupRole) Validate() error {
+	allowed := make([]string, 0, len(AllGroupRoles()))
+	for _, known := range AllGroupRoles() {
+		if r == known {
+			return nil
+		}
+		allowed = append(allowed, string(known))
+	}
+
+	err := errors.NewInvalidValueError("role", string(r))
+	err.Details = map[string]string{
+		"field":   "role",
+		"allowed": strings.Join(allowed, ","),
+	}
+	return err
+}
+
 // Group represents a group in the system
 type Group struct {
-	ID          int64     `json:"id" db:"id"`
-	UUID        string    `json:"uuid" db:"uuid"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	CreatedBy   int64     `json:"created_by" db:"created_by"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID              int64      `json:"id" db:"id"`
+	UUID            string     `json:"uuid" db:"uuid"`
+	Name            string     `json:"name" db:"name"`
+	Description     string     `json:"description" db:"description"`
+	Timezone        string     `json:"timezone" db:"timezone"`
+	DefaultCurrency string     `json:"default_currency" db:"default_currency"`
+	CreatedBy       int64      `json:"created_by" db:"created_by"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+	ArchivedAt      *time.Time `json:"archived_at,omitempty" db:"archived_at"`
 
 	// Relationships
 	Creator *User   `json:"creator,omitempty"`
 	Members []*User `json:"members,omitempty"`
 }
 
+// Location returns the group's timezone for bucketing by local day and month.
+// Groups with an unset or unknown timezone use UTC.
+func (g *Group) Location() *time.Location {
+	if g.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(g.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 // GroupMember represents a member of a group
 type GroupMember struct {
 	ID       int64     `json:"id" db:"id"`
 	GroupID  int64     `json:"group_id" db:"group_id"`
 	UserID   int64     `json:"user_id" db:"user_id"`
+	Role     GroupRole `json:"role" db:"role"`
 	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
 
+	// Balance is the member's current balance in Currency, the group's
+	// default currency. Positive means the member owes money.
+	Balance  decimal.Decimal `json:"balance" db:"-"`
+	Currency string          `json:"currency" db:"-"`
+
 	// Relationships
 	User *User `json:"user,omitempty"`
 }
 
 // CreateGroupRequest represents the request to create a new group
 type CreateGroupRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description,omitempty"`
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description,omitempty"`
+	Timezone        string `json:"timezone,omitempty" example:"Asia/Kolkata"`
+	DefaultCurrency string `json:"default_currency,omitempty" example:"EUR"`
+}
+
+// BootstrapGroupRequest represents the request to create a group together with its members.
+// The creator is identified by either CreatorUUID or CreatorEmail.
+type BootstrapGroupRequest struct {
+	Name            string                   `json:"name" binding:"required"`
+	Description     string                   `json:"description,omitempty"`
+	Timezone        string                   `json:"timezone,omitempty" example:"Asia/Kolkata"`
+	DefaultCurrency string                   `json:"default_currency,omitempty" example:"EUR"`
+	CreatorUUID     string                   `json:"creator_uuid,omitempty"`
+	CreatorEmail    string                   `json:"creator_email,omitempty"`
+	Members         []BootstrapMemberRequest `json:"members"`
 }
 
-// UpdateGroupRequest represents the request to update a group
+// BootstrapMemberRequest represents a member to add when bootstrapping a group
+type BootstrapMemberRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// BootstrapMemberStatus reports whether a bootstrapped member was created or reused
+type BootstrapMemberStatus string
+
+const (
+	BootstrapMemberCreated  BootstrapMemberStatus = "created"
+	BootstrapMemberExisting BootstrapMemberStatus = "existing"
+)
+
+// BootstrapMemberResult represents the outcome for one bootstrapped member
+type BootstrapMemberResult struct {
+	User   *User                 `json:"user"`
+	Status BootstrapMemberStatus `json:"status"`
+}
+
+// BootstrapGroupResponse represents the response for bootstrapping a group
+type BootstrapGroupResponse struct {
+	Group   *Group                   `json:"group"`
+	Members []*BootstrapMemberResult `json:"members"`
+}
+
+// UpdateGroupRequest represents the request to update a group. Empty fields
+// are left unchanged. ActingUserUUID identifies the user making the change,
+// who must be a group admin.
 type UpdateGroupRequest struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Description     string `json:"description,omitempty"`
+	Timezone        string `json:"timezone,omitempty" example:"Asia/Kolkata"`
+	DefaultCurrency string `json:"default_currency,omitempty" example:"EUR"`
+	ActingUserUUID  string `json:"acting_user_uuid" binding:"required"`
 }
 
 // AddMemberRequest represents the request to add a member to a group
 type AddMemberRequest struct {
+	UserUUID       string `json:"user_uuid" binding:"required"`
+	ActingUserUUID string `json:"acting_user_uuid" binding:"required"`
+}
+
+// AddMembersRequest represents the request to add several users to a group at once
+type AddMembersRequest struct {
+	UserUUIDs      []string `json:"user_uuids" binding:"required"`
+	ActingUserUUID string   `json:"acting_user_uuid" binding:"required"`
+}
+
+// LeaveGroupRequest represents the request for a user to leave a group
+type LeaveGroupRequest struct {
 	UserUUID string `json:"user_uuid" binding:"required"`
 }
 
+// UpdateMemberRoleRequest represents the request to change a member's role.
+// ActingUserUUID identifies the admin making the change.
+type UpdateMemberRoleRequest struct {
+	Role           GroupRole `json:"role" binding:"required" enums:"admin,member"`
+	ActingUserUUID string    `json:"acting_user_uuid" binding:"required"`
+}
+
+// TransferOwnershipRequest represents the request to hand a group over to
+// another member. ActingUserUUID must be the current owner.
+type TransferOwnershipRequest struct {
+	NewOwnerUUID   string `json:"new_owner_uuid" binding:"required"`
+	ActingUserUUID string `json:"acting_user_uuid" binding:"required"`
+}
+
+// AddMemberStatus reports whether a user in a batch was added or already a member
+type AddMemberStatus string
+
+const (
+	AddMemberAdded         AddMemberStatus = "added"
+	AddMemberAlreadyMember AddMemberStatus = "already_member"
+)
+
+// AddMemberResult represents the outcome for one user in a batch
+type AddMemberResult struct {
+	UserUUID string          `json:"user_uuid"`
+	User     *User           `json:"user"`
+	Status   AddMemberStatus `json:"status"`
+}
+
+// AddMembersResponse represents the response for adding several members at once
+type AddMembersResponse struct {
+	Members    []*AddMemberResult `json:"members"`
+	AddedCount int                `json:"added_count"`
+}
+
 // GroupSummary represents a summary of group's financial status
 type GroupSummary struct {
-	Group        *Group         `json:"group"`
-	MemberCount  int            `json:"member_count"`
-	ExpenseCount int            `json:"expense_count"`
-	TotalAmount  string         `json:"total_amount"`
-	Currency     string         `json:"currency"`
-	Balances     []*UserBalance `json:"balances,omitempty"`
+	Group        *Group                  `json:"group"`
+	MemberCount  int                     `json:"member_count"`
+	ExpenseCount int                     `json:"expense_count"`
+	Totals       []*ExpenseCurrencyTotal `json:"totals"`
+	Balances     []*UserBalance          `json:"balances"`
+	LastActivity *time.Time              `json:"last_activity,omitempty"`
 }
 
 // TableName returns the table name for Group model
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// GroupLockPurpose describes the operation holding a group lock
+type GroupLockPurpose string
+
+const (
+	GroupLockSettleUp  GroupLockPurpose = "settle_up"
+	GroupLockReconcile GroupLockPurpose = "reconcile"
+)
+
+// GroupLock represents a short-lived write lock on a group.
+// While active, new expenses and settlements for the group are rejected.
+type GroupLock struct {
+	GroupID   int64            `json:"group_id" db:"group_id"`
+	Holder    string           `json:"holder" db:"holder"`
+	Purpose   GroupLockPurpose `json:"purpose" db:"purpose"`
+	ExpiresAt time.Time        `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+// IsActive reports whether the lock is still held at the given time
+func (l *GroupLock) IsActive(now time.Time) bool {
+	return now.Before(l.ExpiresAt)
+}
+
+// TableName returns the table name for GroupLock model
+func (GroupLock) TableName() string {
+	return "group_locks"
+}
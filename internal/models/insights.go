@@ -0,0 +1,65 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// InsightAggregate represents a monthly total for a user in one group and currency
+type InsightAggregate struct {
+	GroupID   int64           `json:"group_id" db:"group_id"`
+	GroupUUID string          `json:"group_uuid" db:"group_uuid"`
+	GroupName string          `json:"group_name" db:"group_name"`
+	Month     string          `json:"month" db:"month"`
+	Currency  string          `json:"currency" db:"currency"`
+	Amount    decimal.Decimal `json:"amount" db:"amount"`
+	Count     int             `json:"count" db:"count"`
+}
+
+// UserInsights represents a user's personal spending dashboard across groups
+type UserInsights struct {
+	User       *User               `json:"user"`
+	Month      string              `json:"month"`
+	Months     []string            `json:"months"`
+	Currencies []*CurrencyInsights `json:"currencies"`
+}
+
+// CurrencyInsights represents the insights for a single currency.
+// ShareOfSpend is the user's share divided by the total spend of their groups
+// in the selected month. Outstanding is the net amount the user owes for the
+// window, and SettleUpLagMonths expresses it in months of their average share.
+type CurrencyInsights struct {
+	Currency          string            `json:"currency"`
+	TotalSpent        decimal.Decimal   `json:"total_spent"`
+	TotalPaid         decimal.Decimal   `json:"total_paid"`
+	GroupSpend        decimal.Decimal   `json:"group_spend"`
+	ShareOfSpend      *decimal.Decimal  `json:"share_of_spend"`
+	Outstanding       decimal.Decimal   `json:"outstanding"`
+	SettleUpLagMonths *decimal.Decimal  `json:"settle_up_lag_months"`
+	Trend             *InsightTrend     `json:"trend"`
+	TopGroups         []*GroupSpend     `json:"top_groups"`
+	Series            []*MonthlyInsight `json:"series"`
+}
+
+// InsightTrend represents the change in spend compared to the previous month
+type InsightTrend struct {
+	PreviousMonth string           `json:"previous_month"`
+	PreviousSpent decimal.Decimal  `json:"previous_spent"`
+	Change        decimal.Decimal  `json:"change"`
+	ChangePercent *decimal.Decimal `json:"change_percent"`
+}
+
+// GroupSpend represents a user's spend in one group for the selected month
+type GroupSpend struct {
+	Group *Group          `json:"group"`
+	Spent decimal.Decimal `json:"spent"`
+	Paid  decimal.Decimal `json:"paid"`
+}
+
+// MonthlyInsight represents one point of the monthly chart series
+type MonthlyInsight struct {
+	Month      string          `json:"month"`
+	Spent      decimal.Decimal `json:"spent"`
+	Paid       decimal.Decimal `json:"paid"`
+	SettledOut decimal.Decimal `json:"settled_out"`
+	SettledIn  decimal.Decimal `json:"settled_in"`
+}
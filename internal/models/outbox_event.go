@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event stored alongside the change that raised it,
+// waiting to be published by the outbox relay
+type OutboxEvent struct {
+	ID           int64           `json:"id" db:"id"`
+	GroupID      int64           `json:"group_id" db:"group_id"`
+	EventType    string          `json:"event_type" db:"event_type"`
+	Payload      json.RawMessage `json:"payload" db:"payload"`
+	Attempts     int             `json:"attempts" db:"attempts"`
+	LastError    *string         `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	DispatchedAt *time.Time      `json:"dispatched_at,omitempty" db:"dispatched_at"`
+}
+
+// MemberEventPayload is the outbox payload of membership events. Role is set
+// on role changes and PreviousUserID on ownership transfers.
+type MemberEventPayload struct {
+	GroupID        int64     `json:"group_id"`
+	UserID         int64     `json:"user_id"`
+	Role           GroupRole `json:"role,omitempty"`
+	PreviousUserID int64     `json:"previous_user_id,omitempty"`
+}
+
+// TableName returns the table name for OutboxEvent model
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// RecurrenceFrequency represents how often a recurring expense is created
+type RecurrenceFrequency string
+
+const (
+	FrequencyWeekly  RecurrenceFrequency = "weekly"
+	FrequencyMonthly RecurrenceFrequency = "monthly"
+)
+
+// AllFrequencies returns every supported recurrence frequency
+func AllFrequencies() []RecurrenceFrequency {
+	return []RecurrenceFrequency{FrequencyWeekly, FrequencyMonthly}
+}
+
+// Validate checks that the frequency is one of AllFrequencies
+func (f RecurrenceFrequency) Validate() error {
+	allowed := make([]string, 0, len(AllFrequencies()))
+	for _, known := range AllFrequencies() {
+		if f == known {
+			return nil
+		}
+		allowed = append(allowed, string(known))
+	}
+
+	err := errors.NewInvalidValueError("frequency", string(f))
+	err.Details = map[string]string{
+		"field":   "frequency",
+		"allowed": strings.Join(allowed, ","),
+	}
+	return err
+}
+
+// Next returns the run following after. Runs are computed in loc so they stay
+// at the same wall-clock time across DST changes. Monthly runs fall on the
+// anchor's day of month, or the last day of shorter months.
+func (f RecurrenceFrequency) Next(after, anchor time.Time, loc *time.Location) time.Time {
+	after = after.In(loc)
+	if f == FrequencyWeekly {
+		return after.AddDate(0, 0, 7)
+	}
+
+	anchor = anchor.In(loc)
+	year, month := after.Year(), after.Month()+1
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	day := anchor.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, anchor.Hour(), anchor.Minute(), anchor.Second(), 0, loc)
+}
+
+// RecurringExpense is an expense template created in its group every week or
+// month. Splits keep the request form so equal splits without entries are
+// shared by whoever is a member at each run.
+type RecurringExpense struct {
+	ID          int64                       `json:"id" db:"id"`
+	UUID        string                      `json:"uuid" db:"uuid"`
+	GroupID     int64                       `json:"group_id" db:"group_id"`
+	PaidBy      int64                       `json:"paid_by" db:"paid_by"`
+	Amount      decimal.Decimal             `json:"amount" db:"amount"`
+	Currency    string                      `json:"currency" db:"currency"`
+	Description string                      `json:"description" db:"description"`
+	Category    string                      `json:"category" db:"category"`
+	SplitType   SplitType                   `json:"split_type" db:"split_type"`
+	Splits      []CreateExpenseSplitRequest `json:"splits"`
+	Frequency   RecurrenceFrequency         `json:"frequency" db:"frequency"`
+	StartsAt    time.Time                   `json:"starts_at" db:"starts_at"`
+	NextRunAt   time.Time                   `json:"next_run_at" db:"next_run_at"`
+	LastRunAt   *time.Time                  `json:"last_run_at,omitempty" db:"last_run_at"`
+	Active      bool                        `json:"active" db:"active"`
+	CreatedAt   time.Time                   `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time                   `json:"updated_at" db:"updated_at"`
+
+	// Relationships
+	Group *Group `json:"group,omitempty"`
+	Payer *User  `json:"payer,omitempty"`
+}
+
+// ExpenseRequest builds the request that materializes the run at runAt
+func (r *RecurringExpense) ExpenseRequest(runAt time.Time) *CreateExpenseRequest {
+	req := &CreateExpenseRequest{
+		Amount:      r.Amount,
+		Currency:    r.Currency,
+		Description: r.Description,
+		Category:    r.Category,
+		ExpenseDate: runAt,
+		SplitType:   r.SplitType,
+		Splits:      append([]CreateExpenseSplitRequest(nil), r.Splits...),
+		Recurrence:  &ExpenseRecurrence{RecurringExpenseID: r.ID, RunAt: runAt},
+	}
+	if r.Group != nil {
+		req.GroupUUID = r.Group.UUID
+	}
+	if r.Payer != nil {
+		req.PaidByUUID = r.Payer.UUID
+	}
+	return req
+}
+
+// MarshalSplits encodes the split template for storage
+func (r *RecurringExpense) MarshalSplits() ([]byte, error) {
+	splits := r.Splits
+	if splits == nil {
+		splits = []CreateExpenseSplitRequest{}
+	}
+	return json.Marshal(splits)
+}
+
+// ExpenseRecurrence ties an expense to the recurring run that created it
+type ExpenseRecurrence struct {
+	RecurringExpenseID int64
+	RunAt              time.Time
+}
+
+// CreateRecurringExpenseRequest represents the request to create a recurring
+// expense. An omitted next_run_at schedules the first run immediately.
+type CreateRecurringExpenseRequest struct {
+	PaidByUUID  string                      `json:"paid_by_uuid" binding:"required"`
+	Amount      decimal.Decimal             `json:"amount" binding:"required"`
+	Currency    string                      `json:"currency,omitempty"`
+	Description string                      `json:"description" binding:"required"`
+	Category    string                      `json:"category,omitempty"`
+	SplitType   SplitType                   `json:"split_type" binding:"required" enums:"equal,exact,percentage,shares"`
+	Splits      []CreateExpenseSplitRequest `json:"splits"`
+	Frequency   RecurrenceFrequency         `json:"frequency" binding:"required" enums:"weekly,monthly"`
+	NextRunAt   time.Time                   `json:"next_run_at,omitempty"`
+}
+
+// UpdateRecurringExpenseRequest replaces a recurring expense's template and
+// schedule. An omitted next_run_at keeps the current schedule and an omitted
+// active flag leaves it unchanged.
+type UpdateRecurringExpenseRequest struct {
+	CreateRecurringExpenseRequest
+	Active *bool `json:"active,omitempty"`
+}
+
+// TableName returns the table name for RecurringExpense model
+func (RecurringExpense) TableName() string {
+	return "recurring_expenses"
+}
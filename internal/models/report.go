@@ -0,0 +1,125 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CategoryReportFilter restricts a category report to a date range and a
+// currency. Dates match expense_date, and date-only bounds are days in the
+// group's timezone; zero values and an empty currency leave that filter open.
+type CategoryReportFilter struct {
+	FromDate time.Time
+	ToDate   time.Time
+	Currency string
+}
+
+// CategoryAggregate represents the total spent in one category and currency
+type CategoryAggregate struct {
+	Category string          `json:"category" db:"category"`
+	Currency string          `json:"currency" db:"currency"`
+	Amount   decimal.Decimal `json:"amount" db:"amount"`
+	Count    int             `json:"count" db:"count"`
+}
+
+// CategoryReport represents a group's spending broken down by category, per currency
+type CategoryReport struct {
+	Group      *Group                `json:"group"`
+	Currencies []*CurrencyCategories `json:"currencies"`
+}
+
+// CurrencyCategories represents the category breakdown of a single currency.
+// Categories are sorted by total, largest first, and their percentages of
+// Total add up to 100 within rounding.
+type CurrencyCategories struct {
+	Currency   string           `json:"currency"`
+	Total      decimal.Decimal  `json:"total"`
+	Count      int              `json:"count"`
+	Categories []*CategorySpend `json:"categories"`
+}
+
+// CategorySpend represents the spend of one category
+type CategorySpend struct {
+	Category   string          `json:"category"`
+	Total      decimal.Decimal `json:"total"`
+	Count      int             `json:"count"`
+	Percentage decimal.Decimal `json:"percentage"`
+}
+
+// UserStatsFilter restricts user statistics to a date range. Expenses match
+// on expense_date and settlements on created_at; zero values leave that end
+// open. Date-only bounds are days in each group's timezone, so the service
+// resolves them into GroupRanges, which replace FromDate and ToDate when set.
+type UserStatsFilter struct {
+	FromDate    time.Time
+	ToDate      time.Time
+	GroupRanges []*GroupDateRange
+}
+
+// GroupDateRange is the date range of a stats query within one group
+type GroupDateRange struct {
+	GroupID  int64
+	FromDate time.Time
+	ToDate   time.Time
+}
+
+// StatsAggregate represents a user's total and count in one currency
+type StatsAggregate struct {
+	Currency string          `json:"currency" db:"currency"`
+	Amount   decimal.Decimal `json:"amount" db:"amount"`
+	Count    int             `json:"count" db:"count"`
+}
+
+// ExpenseActivity represents the expenses a user paid for or has a split
+// in, in one currency
+type ExpenseActivity struct {
+	Currency         string    `json:"currency" db:"currency"`
+	Count            int       `json:"count" db:"count"`
+	FirstExpenseDate time.Time `json:"first_expense_date" db:"first_expense_date"`
+}
+
+// SettlementStatsAggregate represents the confirmed settlements a user sent
+// and received in one currency
+type SettlementStatsAggregate struct {
+	Currency       string          `json:"currency" db:"currency"`
+	SentAmount     decimal.Decimal `json:"sent_amount" db:"sent_amount"`
+	SentCount      int             `json:"sent_count" db:"sent_count"`
+	ReceivedAmount decimal.Decimal `json:"received_amount" db:"received_amount"`
+	ReceivedCount  int             `json:"received_count" db:"received_count"`
+}
+
+// LargestExpense represents the largest single expense a user was involved in
+type LargestExpense struct {
+	UUID        string          `json:"uuid" db:"uuid"`
+	Description string          `json:"description" db:"description"`
+	Amount      decimal.Decimal `json:"amount" db:"amount"`
+	ExpenseDate time.Time       `json:"expense_date" db:"expense_date"`
+	GroupUUID   string          `json:"group_uuid" db:"group_uuid"`
+	GroupName   string          `json:"group_name" db:"group_name"`
+}
+
+// UserStats represents a user's spending statistics across all their groups
+type UserStats struct {
+	User       *User                `json:"user"`
+	Currencies []*CurrencyUserStats `json:"currencies"`
+}
+
+// CurrencyUserStats represents a user's statistics in a single currency.
+// TotalShare is the sum of the user's splits. Months is the number of
+// calendar months the averages are taken over: from from_date, or the
+// first expense in the currency, up to to_date or the current month.
+type CurrencyUserStats struct {
+	Currency                  string          `json:"currency"`
+	TotalPaid                 decimal.Decimal `json:"total_paid"`
+	TotalShare                decimal.Decimal `json:"total_share"`
+	ExpenseCount              int             `json:"expense_count"`
+	SettlementsSent           int             `json:"settlements_sent"`
+	SettlementsSentAmount     decimal.Decimal `json:"settlements_sent_amount"`
+	SettlementsReceived       int             `json:"settlements_received"`
+	SettlementsReceivedAmount decimal.Decimal `json:"settlements_received_amount"`
+	LargestExpense            *LargestExpense `json:"largest_expense"`
+	Months                    int             `json:"months"`
+	AveragePaidPerMonth       decimal.Decimal `json:"average_paid_per_month"`
+	AverageSharePerMonth      decimal.Decimal `json:"average_share_per_month"`
+}
@@ -1,22 +1,107 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"expense-split-tracker/pkg/errors"
+
 	"github.com/shopspring/decimal"
 )
 
+// SettlementStatus represents where a settlement is in its confirmation lifecycle
+type SettlementStatus string
+
+const (
+	// SettlementStatusPending settlements wait for the receiver and do not affect balances
+	SettlementStatusPending SettlementStatus = "pending"
+	// SettlementStatusConfirmed settlements have been applied to balances
+	SettlementStatusConfirmed SettlementStatus = "confirmed"
+	// SettlementStatusRejected settlements were refused by the receiver
+	SettlementStatusRejected SettlementStatus = "rejected"
+)
+
+// AllSettlementStatuses returns every settlement status
+func AllSettlementStatuses() []SettlementStatus {
+	return []SettlementStatus{SettlementStatusPending, SettlementStatusConfirmed, SettlementStatusRejected}
+}
+
+// Validate checks that the status is one of AllSettlementStatuses
+func (s SettlementStatus) Validate() error {
+	allowed := make([]string, 0, len(AllSettlementStatuses()))
+	for _, known := range AllSettlementStatuses() {
+		if s == known {
+			return nil
+		}
+		allowed = append(allowed, string(known))
+	}
+
+	err := errors.NewInvalidValueError("status", string(s))
+	err.Details = map[string]string{
+		"field":   "status",
+		"allowed": strings.Join(allowed, ","),
+	}
+	return err
+}
+
+// SettlementMethod records how a settlement was paid
+type SettlementMethod string
+
+const (
+	SettlementMethodCash         SettlementMethod = "cash"
+	SettlementMethodBankTransfer SettlementMethod = "bank_transfer"
+	SettlementMethodUPI          SettlementMethod = "upi"
+	SettlementMethodPayPal       SettlementMethod = "paypal"
+	SettlementMethodVenmo        SettlementMethod = "venmo"
+	// SettlementMethodOther is used when no method is given
+	SettlementMethodOther SettlementMethod = "other"
+)
+
+// AllSettlementMethods returns every settlement method
+func AllSettlementMethods() []SettlementMethod {
+	return []SettlementMethod{
+		SettlementMethodCash,
+		SettlementMethodBankTransfer,
+		SettlementMethodUPI,
+		SettlementMethodPayPal,
+		SettlementMethodVenmo,
+		SettlementMethodOther,
+	}
+}
+
+// Validate checks that the method is one of AllSettlementMethods
+func (m SettlementMethod) Validate() error {
+	allowed := make([]string, 0, len(AllSettlementMethods()))
+	for _, known := range AllSettlementMethods() {
+		if m == known {
+			return nil
+		}
+		allowed = append(allowed, string(known))
+	}
+
+	err := errors.NewInvalidValueError("method", string(m))
+	err.Details = map[string]string{
+		"field":   "method",
+		"allowed": strings.Join(allowed, ","),
+	}
+	return err
+}
+
 // Settlement represents a debt settlement between users
 type Settlement struct {
-	ID          int64           `json:"id" db:"id"`
-	UUID        string          `json:"uuid" db:"uuid"`
-	GroupID     int64           `json:"group_id" db:"group_id"`
-	FromUserID  int64           `json:"from_user_id" db:"from_user_id"`
-	ToUserID    int64           `json:"to_user_id" db:"to_user_id"`
-	Amount      decimal.Decimal `json:"amount" db:"amount"`
-	Currency    string          `json:"currency" db:"currency"`
-	Description string          `json:"description" db:"description"`
-	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	ID          int64            `json:"id" db:"id"`
+	UUID        string           `json:"uuid" db:"uuid"`
+	GroupID     int64            `json:"group_id" db:"group_id"`
+	FromUserID  int64            `json:"from_user_id" db:"from_user_id"`
+	ToUserID    int64            `json:"to_user_id" db:"to_user_id"`
+	Amount      decimal.Decimal  `json:"amount" db:"amount"`
+	Currency    string           `json:"currency" db:"currency"`
+	Description string           `json:"description" db:"description"`
+	Status      SettlementStatus `json:"status" db:"status"`
+	Method      SettlementMethod `json:"method" db:"method"`
+	VoidedAt    *time.Time       `json:"voided_at,omitempty" db:"voided_at"`
+	VoidedBy    *int64           `json:"voided_by,omitempty" db:"voided_by"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
 
 	// Relationships
 	Group    *Group `json:"group,omitempty"`
@@ -26,12 +111,31 @@ type Settlement struct {
 
 // CreateSettlementRequest represents the request to create a new settlement
 type CreateSettlementRequest struct {
-	GroupUUID    string          `json:"group_uuid" binding:"required"`
-	FromUserUUID string          `json:"from_user_uuid" binding:"required"`
-	ToUserUUID   string          `json:"to_user_uuid" binding:"required"`
+	GroupUUID    GroupUUID       `json:"group_uuid" binding:"required"`
+	FromUserUUID UserUUID        `json:"from_user_uuid" binding:"required"`
+	ToUserUUID   UserUUID        `json:"to_user_uuid" binding:"required"`
 	Amount       decimal.Decimal `json:"amount" binding:"required"`
 	Currency     string          `json:"currency,omitempty"`
 	Description  string          `json:"description,omitempty"`
+
+	// Method defaults to "other" when empty
+	Method SettlementMethod `json:"method,omitempty"`
+
+	// AllowOvershoot skips the check that stops a settlement from turning the
+	// recipient into a debtor
+	AllowOvershoot bool `json:"allow_overshoot,omitempty"`
+
+	// RequireConfirmation records the settlement as pending; balances only
+	// change once the receiver confirms it
+	RequireConfirmation bool `json:"require_confirmation,omitempty"`
+}
+
+// RespondSettlementRequest identifies the user confirming or rejecting a
+// pending settlement, who must be its receiver. AllowOvershoot lets a
+// confirmation leave the receiver owing the group, as on creation.
+type RespondSettlementRequest struct {
+	UserUUID       UserUUID `json:"user_uuid" binding:"required"`
+	AllowOvershoot bool     `json:"allow_overshoot,omitempty"`
 }
 
 // SettlementSuggestion represents a suggested settlement to simplify debts
@@ -42,12 +146,86 @@ type SettlementSuggestion struct {
 	Currency string          `json:"currency"`
 }
 
+// SimplificationMode selects the algorithm that simplifies a group's debts
+type SimplificationMode string
+
+const (
+	// SimplificationModeGreedy repeatedly pays the largest creditor from the
+	// largest debtor; it is the default
+	SimplificationModeGreedy SimplificationMode = "greedy"
+	// SimplificationModeOptimal finds the fewest transfers by splitting the
+	// balances into as many zero-sum groups as possible
+	SimplificationModeOptimal SimplificationMode = "optimal"
+)
+
+// AllSimplificationModes returns every simplification mode
+func AllSimplificationModes() []SimplificationMode {
+	return []SimplificationMode{SimplificationModeGreedy, SimplificationModeOptimal}
+}
+
+// Validate checks that the mode is one of AllSimplificationModes
+func (m SimplificationMode) Validate() error {
+	allowed := make([]string, 0, len(AllSimplificationModes()))
+	for _, known := range AllSimplificationModes() {
+		if m == known {
+			return nil
+		}
+		allowed = append(allowed, string(known))
+	}
+
+	err := errors.NewInvalidValueError("mode", string(m))
+	err.Details = map[string]string{
+		"field":   "mode",
+		"allowed": strings.Join(allowed, ","),
+	}
+	return err
+}
+
 // DebtSimplification represents the result of debt simplification
 type DebtSimplification struct {
-	OriginalTransactions   int                     `json:"original_transactions"`
-	SimplifiedTransactions int                     `json:"simplified_transactions"`
-	Savings                int                     `json:"savings"`
-	Suggestions            []*SettlementSuggestion `json:"suggestions"`
+	// OriginalTransactions is the number of outstanding pairwise debts, i.e.
+	// the payments needed if everyone paid back each person they owe
+	OriginalTransactions int `json:"original_transactions"`
+	// SimplifiedTransactions is the number of suggestions
+	SimplifiedTransactions int `json:"simplified_transactions"`
+	// Savings is OriginalTransactions minus SimplifiedTransactions, never
+	// below zero
+	Savings     int                     `json:"savings"`
+	Suggestions []*SettlementSuggestion `json:"suggestions"`
+
+	// Algorithm is the mode that produced the suggestions. An optimal request
+	// for a group too large to search falls back to greedy.
+	Algorithm SimplificationMode `json:"algorithm"`
+
+	// Residual is what suggestions dropped below the minimum amount, plus
+	// rounding drift, left unpaid. It is added to the largest suggestion;
+	// when every suggestion was dropped it stays on the balances.
+	Residual decimal.Decimal `json:"residual"`
+
+	// Hash identifies the suggestions; pass it to the execute endpoint to make
+	// sure balances did not change after they were previewed
+	Hash string `json:"hash"`
+}
+
+// SimplifyDebtsRequest holds the options of a debt simplification preview
+type SimplifyDebtsRequest struct {
+	Currency string
+	Mode     SimplificationMode
+
+	// MinAmount drops smaller suggestions; nil means the smallest unit of
+	// the currency
+	MinAmount *decimal.Decimal
+}
+
+// ExecuteSimplificationRequest represents the request to record every
+// debt-simplification suggestion of a group as a settlement
+type ExecuteSimplificationRequest struct {
+	Currency     string `json:"currency,omitempty"`
+	ExpectedHash string `json:"expected_hash,omitempty"`
+
+	// Mode and MinAmount should match the preview the expected hash came from
+	Mode      SimplificationMode `json:"mode,omitempty"`
+	MinAmount *decimal.Decimal   `json:"min_amount,omitempty"`
 }
 
 // SettlementListResponse represents the response for listing settlements
@@ -60,15 +238,22 @@ type SettlementListResponse struct {
 
 // SettlementFilter represents filters for settlement queries
 type SettlementFilter struct {
-	GroupUUID    string    `json:"group_uuid,omitempty"`
-	UserUUID     string    `json:"user_uuid,omitempty"`
-	FromUserUUID string    `json:"from_user_uuid,omitempty"`
-	ToUserUUID   string    `json:"to_user_uuid,omitempty"`
-	FromDate     time.Time `json:"from_date,omitempty"`
-	ToDate       time.Time `json:"to_date,omitempty"`
-	Currency     string    `json:"currency,omitempty"`
-	Page         int       `json:"page,omitempty"`
-	Limit        int       `json:"limit,omitempty"`
+	GroupUUID    string           `json:"group_uuid,omitempty"`
+	UserUUID     string           `json:"user_uuid,omitempty"`
+	FromUserUUID string           `json:"from_user_uuid,omitempty"`
+	ToUserUUID   string           `json:"to_user_uuid,omitempty"`
+	FromDate     time.Time        `json:"from_date,omitempty"`
+	ToDate       time.Time        `json:"to_date,omitempty"`
+	Currency     string           `json:"currency,omitempty"`
+	Status       SettlementStatus `json:"status,omitempty"`
+	Method       SettlementMethod `json:"method,omitempty"`
+	Page         int              `json:"page,omitempty"`
+	Limit        int              `json:"limit,omitempty"`
+
+	// IncludeVoided also returns voided settlements, which are hidden by default
+	IncludeVoided bool `json:"include_voided,omitempty"`
+
+	ListSort
 }
 
 // TableName returns the table name for Settlement model
@@ -0,0 +1,20 @@
+package models
+
+// Sort directions accepted by list endpoints
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// ExpenseSortFields lists the fields expense listings can be sorted by
+var ExpenseSortFields = []string{"created_at", "expense_date", "amount", "description"}
+
+// SettlementSortFields lists the fields settlement listings can be sorted by
+var SettlementSortFields = []string{"created_at", "amount", "description"}
+
+// ListSort represents the requested ordering of a list. An empty SortBy keeps
+// the endpoint's default order; an empty SortDir means descending.
+type ListSort struct {
+	SortBy  string `json:"sort_by,omitempty"`
+	SortDir string `json:"sort_dir,omitempty" enums:"asc,desc"`
+}
@@ -12,8 +12,12 @@ type User struct {
 	UUID      string    `json:"uuid" db:"uuid"`
 	Name      string    `json:"name" db:"name"`
 	Email     string    `json:"email" db:"email"`
+	IsPending bool      `json:"is_pending" db:"is_pending"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Role is only set when the user is listed as a group member
+	Role GroupRole `json:"role,omitempty" db:"role"`
 }
 
 // CreateUserRequest represents the request to create a new user
@@ -28,6 +32,11 @@ type UpdateUserRequest struct {
 	Email string `json:"email,omitempty"`
 }
 
+// LookupUsersRequest represents the request to fetch several users by UUID
+type LookupUsersRequest struct {
+	UUIDs []string `json:"uuids" binding:"required"`
+}
+
 // UserBalance represents a user's balance in a specific group
 type UserBalance struct {
 	UserID   int64           `json:"user_id" db:"user_id"`
@@ -35,16 +44,31 @@ type UserBalance struct {
 	User     *User           `json:"user,omitempty"`
 	Balance  decimal.Decimal `json:"balance" db:"balance"`
 	Currency string          `json:"currency" db:"currency"`
+
+	// ConvertedBalance is Balance in the currency of a requested conversion
+	ConvertedBalance *decimal.Decimal `json:"converted_balance,omitempty"`
 }
 
-// UserSummary represents a summary of user's financial status in a group
+// UserSummary gives a user's gross figures in a group for one currency: what
+// they paid for, their share of expenses (total_owed) and the settlements they
+// sent and received. NetBalance is derived from these and should match the
+// stored StoredBalance; positive means the user owes money.
 type UserSummary struct {
-	User         *User           `json:"user"`
-	TotalOwed    decimal.Decimal `json:"total_owed"`
-	TotalOwing   decimal.Decimal `json:"total_owing"`
-	NetBalance   decimal.Decimal `json:"net_balance"`
-	Currency     string          `json:"currency"`
-	ExpenseCount int             `json:"expense_count"`
+	Currency        string          `json:"currency"`
+	TotalPaid       decimal.Decimal `json:"total_paid"`
+	TotalOwed       decimal.Decimal `json:"total_owed"`
+	SettledSent     decimal.Decimal `json:"settled_sent"`
+	SettledReceived decimal.Decimal `json:"settled_received"`
+	NetBalance      decimal.Decimal `json:"net_balance"`
+	StoredBalance   decimal.Decimal `json:"stored_balance"`
+	ExpenseCount    int             `json:"expense_count"`
+	PaymentCount    int             `json:"payment_count"`
+}
+
+// UserGroupSummary gathers a user's summaries in a group, one per currency
+type UserGroupSummary struct {
+	User       *User          `json:"user"`
+	Currencies []*UserSummary `json:"currencies"`
 }
 
 // TableName returns the table name for User model
@@ -0,0 +1,47 @@
+package models
+
+// Typed UUIDs keep identifiers of different resources from being swapped by
+// accident: a GroupUUID cannot be passed where a UserUUID is expected without
+// an explicit conversion.
+
+// GroupUUID identifies a group
+type GroupUUID string
+
+// UserUUID identifies a user
+type UserUUID string
+
+// ExpenseUUID identifies an expense
+type ExpenseUUID string
+
+// SettlementUUID identifies a settlement
+type SettlementUUID string
+
+// RecurringExpenseUUID identifies a recurring expense template
+type RecurringExpenseUUID string
+
+// WebhookUUID identifies a webhook
+type WebhookUUID string
+
+// WebhookDeliveryUUID identifies one delivery of an event to a webhook
+type WebhookDeliveryUUID string
+
+// String returns the UUID as a plain string
+func (u GroupUUID) String() string { return string(u) }
+
+// String returns the UUID as a plain string
+func (u UserUUID) String() string { return string(u) }
+
+// String returns the UUID as a plain string
+func (u ExpenseUUID) String() string { return string(u) }
+
+// String returns the UUID as a plain string
+func (u SettlementUUID) String() string { return string(u) }
+
+// String returns the UUID as a plain string
+func (u RecurringExpenseUUID) String() string { return string(u) }
+
+// String returns the UUID as a plain string
+func (u WebhookUUID) String() string { return string(u) }
+
+// String returns the UUID as a plain string
+func (u WebhookDeliveryUUID) String() string { return string(u) }
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"expense-split-tracker/pkg/errors"
+)
+
+// WebhookEventType names an event a webhook can subscribe to. The values match
+// the domain event names.
+type WebhookEventType string
+
+const (
+	WebhookEventExpenseCreated    WebhookEventType = "expense.created"
+	WebhookEventExpenseDeleted    WebhookEventType = "expense.deleted"
+	WebhookEventSettlementCreated WebhookEventType = "settlement.created"
+	WebhookEventSettlementVoided  WebhookEventType = "settlement.voided"
+)
+
+// AllWebhookEventTypes returns every event type webhooks can subscribe to
+func AllWebhookEventTypes() []WebhookEventType {
+	return []WebhookEventType{
+		WebhookEventExpenseCreated,
+		WebhookEventExpenseDeleted,
+		WebhookEventSettlementCreated,
+		WebhookEventSettlementVoided,
+	}
+}
+
+// Validate checks that the event type is one of AllWebhookEventTypes
+func (t WebhookEventType) Validate() error {
+	allowed := make([]string, 0, len(AllWebhookEventTypes()))
+	for _, known := range AllWebhookEventTypes() {
+		if t == known {
+			return nil
+		}
+		allowed = append(allowed, string(known))
+	}
+
+	err := errors.NewInvalidValueError("event_types", string(t))
+	err.Details = map[string]string{
+		"field":   "event_types",
+		"allowed": strings.Join(allowed, ","),
+	}
+	return err
+}
+
+// Webhook is an HTTP endpoint notified of a group's expense and settlement
+// events. The secret is write-only and never returned by the API.
+type Webhook struct {
+	ID         int64              `json:"id" db:"id"`
+	UUID       string             `json:"uuid" db:"uuid"`
+	GroupID    int64              `json:"group_id" db:"group_id"`
+	URL        string             `json:"url" db:"url"`
+	Secret     string             `json:"-" db:"secret"`
+	EventTypes []WebhookEventType `json:"event_types"`
+	Active     bool               `json:"active" db:"active"`
+	CreatedAt  time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" db:"updated_at"`
+
+	// RecentAttempts are the latest delivery attempts, newest first. They are
+	// only loaded for the webhook detail endpoint.
+	RecentAttempts []*WebhookDeliveryAttempt `json:"recent_attempts,omitempty"`
+
+	// Relationships
+	Group *Group `json:"group,omitempty"`
+}
+
+// Subscribes reports whether the webhook is active and wants eventType
+func (w *Webhook) Subscribes(eventType WebhookEventType) bool {
+	if !w.Active {
+		return false
+	}
+	for _, subscribed := range w.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalEventTypes encodes the subscribed event types for storage
+func (w *Webhook) MarshalEventTypes() ([]byte, error) {
+	eventTypes := w.EventTypes
+	if eventTypes == nil {
+		eventTypes = []WebhookEventType{}
+	}
+	return json.Marshal(eventTypes)
+}
+
+// CreateWebhookRequest represents the request to register a webhook
+type CreateWebhookRequest struct {
+	URL        string             `json:"url" binding:"required"`
+	Secret     string             `json:"secret" binding:"required"`
+	EventTypes []WebhookEventType `json:"event_types" binding:"required" enums:"expense.created,expense.deleted,settlement.created,settlement.voided"`
+}
+
+// UpdateWebhookRequest replaces a webhook's target and subscriptions. An
+// omitted secret keeps the current one and an omitted active flag leaves it
+// unchanged.
+type UpdateWebhookRequest struct {
+	URL        string             `json:"url" binding:"required"`
+	Secret     string             `json:"secret,omitempty"`
+	EventTypes []WebhookEventType `json:"event_types" binding:"required" enums:"expense.created,expense.deleted,settlement.created,settlement.voided"`
+	Active     *bool              `json:"active,omitempty"`
+}
+
+// WebhookPayload is the JSON body posted to a webhook
+type WebhookPayload struct {
+	ID         string           `json:"id"`
+	Event      WebhookEventType `json:"event"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	Group      WebhookGroup     `json:"group"`
+	Data       json.RawMessage  `json:"data"`
+}
+
+// WebhookGroup identifies the group an event happened in
+type WebhookGroup struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// TableName returns the table name for Webhook model
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// WebhookDeliveryStatus is the state of a delivery
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending deliveries are queued or being retried
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliveryDelivered deliveries got a 2xx response
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryDeadLettered deliveries failed their final retry and are
+	// only sent again when redelivered by hand
+	WebhookDeliveryDeadLettered WebhookDeliveryStatus = "dead_lettered"
+)
+
+// WebhookDelivery is one event sent to a webhook. UUID is the
+// X-Webhook-Delivery header and Payload the posted body, so a redelivery
+// sends exactly what the first attempt did.
+type WebhookDelivery struct {
+	ID        int64                 `json:"-" db:"id"`
+	UUID      string                `json:"uuid" db:"uuid"`
+	WebhookID int64                 `json:"-" db:"webhook_id"`
+	EventType WebhookEventType      `json:"event_type" db:"event_type"`
+	Payload   json.RawMessage       `json:"payload" db:"payload" swaggertype:"object"`
+	Status    WebhookDeliveryStatus `json:"status" db:"status" enums:"pending,delivered,dead_lettered"`
+	Attempts  int                   `json:"attempts" db:"attempts"`
+	LastError *string               `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// TableName returns the table name for WebhookDelivery model
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookAttemptOutcome is the result of one POST of a delivery
+type WebhookAttemptOutcome string
+
+const (
+	// WebhookAttemptDelivered attempts got a 2xx response
+	WebhookAttemptDelivered WebhookAttemptOutcome = "delivered"
+	// WebhookAttemptFailed attempts got another status or no response
+	WebhookAttemptFailed WebhookAttemptOutcome = "failed"
+)
+
+// WebhookDeliveryAttempt is one POST of a delivery. StatusCode is nil when
+// no response arrived; LatencyMS is the time until the response or error.
+type WebhookDeliveryAttempt struct {
+	ID           int64                 `json:"-" db:"id"`
+	DeliveryID   int64                 `json:"-" db:"delivery_id"`
+	DeliveryUUID string                `json:"delivery_uuid" db:"delivery_uuid"`
+	EventType    WebhookEventType      `json:"event_type" db:"event_type"`
+	Attempt      int                   `json:"attempt" db:"attempt"`
+	StatusCode   *int                  `json:"status_code,omitempty" db:"status_code"`
+	Outcome      WebhookAttemptOutcome `json:"outcome" db:"outcome" enums:"delivered,failed"`
+	Error        *string               `json:"error,omitempty" db:"error"`
+	LatencyMS    int64                 `json:"latency_ms" db:"latency_ms"`
+	CreatedAt    time.Time             `json:"created_at" db:"created_at"`
+}
+
+// TableName returns the table name for WebhookDeliveryAttempt model
+func (WebhookDeliveryAttempt) TableName() string {
+	return "webhook_delivery_attempts"
+}
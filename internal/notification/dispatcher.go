@@ -0,0 +1,211 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// Users is the part of the user repository the dispatcher reads
+type Users interface {
+	GetByID(ctx context.Context, id int64) (*models.User, error)
+}
+
+// Groups is the part of the group repository the dispatcher reads
+type Groups interface {
+	GetByID(ctx context.Context, id int64) (*models.Group, error)
+}
+
+// Options tunes sending
+type Options struct {
+	// Workers is the number of messages sent concurrently
+	Workers int
+	// QueueSize is the number of messages that can wait for a worker
+	QueueSize int
+}
+
+// Dispatcher emails users when someone records that they owe money: the
+// participants of a new expense, other than the payer, and the receiver of
+// a new settlement. Messages are built by Consume, which the outbox relay
+// calls for committed events, and sent by Run.
+type Dispatcher struct {
+	notifier Notifier
+	users    Users
+	groups   Groups
+	options  Options
+	queue    chan Message
+	logger   *zap.Logger
+}
+
+// NewDispatcher creates a notification dispatcher
+func NewDispatcher(notifier Notifier, users Users, groups Groups, options Options, logger *zap.Logger) *Dispatcher {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = 100
+	}
+
+	return &Dispatcher{
+		notifier: notifier,
+		users:    users,
+		groups:   groups,
+		options:  options,
+		queue:    make(chan Message, options.QueueSize),
+		logger:   logger,
+	}
+}
+
+// Consume implements outbox.Consumer. Lookup failures are returned so the
+// relay tries again; once built, messages are queued without waiting and
+// dropped with a warning when the queue is full, as email is best effort.
+func (d *Dispatcher) Consume(ctx context.Context, event *models.OutboxEvent) error {
+	var messages []Message
+	var err error
+	switch event.EventType {
+	case events.ExpenseCreated{}.EventName():
+		var expense models.Expense
+		if err := json.Unmarshal(event.Payload, &expense); err != nil {
+			return fmt.Errorf("failed to decode expense: %w", err)
+		}
+		messages, err = d.expenseMessages(ctx, &expense)
+	case events.SettlementCreated{}.EventName():
+		var settlement models.Settlement
+		if err := json.Unmarshal(event.Payload, &settlement); err != nil {
+			return fmt.Errorf("failed to decode settlement: %w", err)
+		}
+		messages, err = d.settlementMessages(ctx, &settlement)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		select {
+		case d.queue <- message:
+		default:
+			d.logger.Warn("Notification queue full, dropping email",
+				zap.String("event", event.EventType), zap.Int64("group_id", event.GroupID))
+		}
+	}
+	return nil
+}
+
+// Run sends queued messages until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.logger.Info("Notification dispatcher started", zap.Int("workers", d.options.Workers))
+
+	var workers sync.WaitGroup
+	workers.Add(d.options.Workers)
+	for i := 0; i < d.options.Workers; i++ {
+		go func() {
+			defer workers.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case message := <-d.queue:
+					if err := d.notifier.Notify(ctx, message); err != nil {
+						d.logger.Error("Failed to send notification", zap.Error(err), zap.String("subject", message.Subject))
+					}
+				}
+			}
+		}()
+	}
+	workers.Wait()
+
+	d.logger.Info("Notification dispatcher stopped")
+}
+
+// expenseMessages tells every participant who owes part of expense their share
+func (d *Dispatcher) expenseMessages(ctx context.Context, expense *models.Expense) ([]Message, error) {
+	// Refunds reduce what participants owe
+	if expense.IsRefund {
+		return nil, nil
+	}
+
+	group, err := d.groups.GetByID(ctx, expense.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	payer, err := d.users.GetByID(ctx, expense.PaidBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shares are in the base currency the expense was booked in
+	decimals := utils.AmountDecimals(expense.Currency)
+	shareDecimals := utils.AmountDecimals(expense.BaseCurrency)
+	var messages []Message
+	for _, split := range expense.Splits {
+		if split.UserID == expense.PaidBy || !split.Amount.IsPositive() {
+			continue
+		}
+
+		participant, err := d.users.GetByID(ctx, split.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if participant.Email == "" {
+			continue
+		}
+
+		message, err := render(participant.Email, expenseSubject, expenseBody, expenseData{
+			Recipient:     participant.Name,
+			PaidBy:        payer.Name,
+			Group:         group.Name,
+			Description:   expense.Description,
+			Amount:        expense.Amount.StringFixed(decimals),
+			Share:         split.Amount.StringFixed(shareDecimals),
+			Currency:      expense.Currency,
+			ShareCurrency: expense.BaseCurrency,
+		})
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// settlementMessages tells the receiver of settlement about the payment
+func (d *Dispatcher) settlementMessages(ctx context.Context, settlement *models.Settlement) ([]Message, error) {
+	group, err := d.groups.GetByID(ctx, settlement.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := d.users.GetByID(ctx, settlement.FromUserID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := d.users.GetByID(ctx, settlement.ToUserID)
+	if err != nil {
+		return nil, err
+	}
+	if to.Email == "" {
+		return nil, nil
+	}
+
+	message, err := render(to.Email, settlementSubject, settlementBody, settlementData{
+		Recipient: to.Name,
+		From:      from.Name,
+		Group:     group.Name,
+		Amount:    settlement.Amount.StringFixed(utils.AmountDecimals(settlement.Currency)),
+		Currency:  settlement.Currency,
+		Pending:   settlement.Status == models.SettlementStatusPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []Message{message}, nil
+}
@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// Message is a plain-text email to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier sends messages to users
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+// NopNotifier discards every message. It is used when email is disabled.
+type NopNotifier struct{}
+
+// Notify implements Notifier
+func (NopNotifier) Notify(ctx context.Context, message Message) error { return nil }
+
+// SMTPConfig addresses the mail server. Username and Password are optional;
+// without them mail is sent unauthenticated.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	From     string
+	Username string
+	Password string
+}
+
+// SMTPNotifier sends messages through an SMTP server
+type SMTPNotifier struct {
+	config SMTPConfig
+	auth   smtp.Auth
+}
+
+// NewSMTPNotifier creates an SMTP notifier
+func NewSMTPNotifier(config SMTPConfig) *SMTPNotifier {
+	notifier := &SMTPNotifier{config: config}
+	if config.Username != "" {
+		notifier.auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+	return notifier
+}
+
+// Notify implements Notifier
+func (n *SMTPNotifier) Notify(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	addr := fmt.Sprintf("%s:%d", n.config.Host, n.config.Port)
+	if err := smtp.SendMail(addr, n.auth, n.config.From, []string{message.To}, n.compose(message)); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", message.To, err)
+	}
+	return nil
+}
+
+// compose renders message as an RFC 5322 email
+func (n *SMTPNotifier) compose(message Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + n.config.From + "\r\n")
+	b.WriteString("To: " + message.To + "\r\n")
+	b.WriteString("Subject: " + message.Subject + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
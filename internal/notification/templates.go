@@ -0,0 +1,60 @@
+package notification
+
+import (
+	"strings"
+	"text/template"
+)
+
+// expenseData fills the expense templates
+type expenseData struct {
+	Recipient     string
+	PaidBy        string
+	Group         string
+	Description   string
+	Amount        string
+	Share         string
+	Currency      string
+	ShareCurrency string
+}
+
+// settlementData fills the settlement templates
+type settlementData struct {
+	Recipient string
+	From      string
+	Group     string
+	Amount    string
+	Currency  string
+	Pending   bool
+}
+
+var (
+	expenseSubject = template.Must(template.New("expense_subject").Parse(
+		`You owe {{.Share}} {{.ShareCurrency}} for "{{.Description}}" in {{.Group}}`))
+
+	expenseBody = template.Must(template.New("expense_body").Parse(`Hi {{.Recipient}},
+
+{{.PaidBy}} paid {{.Amount}} {{.Currency}} for "{{.Description}}" in {{.Group}}.
+Your share is {{.Share}} {{.ShareCurrency}}.
+`))
+
+	settlementSubject = template.Must(template.New("settlement_subject").Parse(
+		`{{.From}} paid you {{.Amount}} {{.Currency}} in {{.Group}}`))
+
+	settlementBody = template.Must(template.New("settlement_body").Parse(`Hi {{.Recipient}},
+
+{{.From}} recorded a payment of {{.Amount}} {{.Currency}} to you in {{.Group}}.
+{{if .Pending}}Please confirm or reject it so the group's balances stay accurate.
+{{end}}`))
+)
+
+// render executes a subject and body template pair
+func render(to string, subject, body *template.Template, data interface{}) (Message, error) {
+	var s, b strings.Builder
+	if err := subject.Execute(&s, data); err != nil {
+		return Message{}, err
+	}
+	if err := body.Execute(&b, data); err != nil {
+		return Message{}, err
+	}
+	return Message{To: to, Subject: s.String(), Body: b.String()}, nil
+}
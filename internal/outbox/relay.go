@@ -0,0 +1,159 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"expense-split-tracker/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// Store is the part of the outbox repository the relay uses
+type Store interface {
+	GetPending(ctx context.Context, limit int) ([]*models.OutboxEvent, error)
+	MarkDispatched(ctx context.Context, id int64) error
+	RecordFailure(ctx context.Context, id int64, message string) error
+}
+
+// Consumer receives published outbox events. Returning an error makes the
+// relay try the event again later.
+type Consumer func(ctx context.Context, event *models.OutboxEvent) error
+
+// Options tunes the relay
+type Options struct {
+	// BatchSize is the number of pending events read per pass
+	BatchSize int
+	// MaxAttempts is the number of failed passes after which an event is
+	// given up on and marked dispatched with its last error, so it stops
+	// holding back its group. Zero retries forever.
+	MaxAttempts int
+}
+
+type subscription struct {
+	name     string
+	consumer Consumer
+}
+
+// Relay publishes pending outbox events to its consumers and marks them
+// dispatched. Delivery is at least once: an event is handed to every
+// consumer again when any of them fails. Within a group events are published
+// in commit order; a failed event holds back the rest of its group until it
+// succeeds.
+type Relay struct {
+	store         Store
+	options       Options
+	subscriptions []subscription
+	logger        *zap.Logger
+}
+
+// NewRelay creates a relay with no consumers
+func NewRelay(store Store, options Options, logger *zap.Logger) *Relay {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+
+	return &Relay{
+		store:   store,
+		options: options,
+		logger:  logger,
+	}
+}
+
+// Subscribe registers a consumer for every event. Register consumers before
+// Run starts.
+func (r *Relay) Subscribe(name string, consumer Consumer) {
+	r.subscriptions = append(r.subscriptions, subscription{name: name, consumer: consumer})
+}
+
+// Run publishes pending events every interval until ctx is cancelled
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	r.logger.Info("Outbox relay started", zap.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+			// Keep going while full batches come back so a backlog drains
+			// without waiting a tick per batch
+			for {
+				published, err := r.RelayPending(ctx)
+				if err != nil {
+					r.logger.Error("Outbox relay failed", zap.Error(err))
+					break
+				}
+				if published < r.options.BatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RelayPending publishes one batch of pending events and returns how many
+// were marked dispatched
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	pending, err := r.store.GetPending(ctx, r.options.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	blocked := make(map[int64]bool)
+	for _, event := range pending {
+		if blocked[event.GroupID] {
+			continue
+		}
+
+		if err := r.publish(ctx, event); err != nil {
+			attempts := event.Attempts + 1
+			r.logger.Warn("Outbox event not published", zap.Error(err),
+				zap.Int64("id", event.ID), zap.String("event", event.EventType),
+				zap.Int64("group_id", event.GroupID), zap.Int("attempts", attempts))
+
+			if recordErr := r.store.RecordFailure(ctx, event.ID, err.Error()); recordErr != nil {
+				return published, recordErr
+			}
+			if r.options.MaxAttempts <= 0 || attempts < r.options.MaxAttempts {
+				blocked[event.GroupID] = true
+				continue
+			}
+
+			r.logger.Error("Giving up on outbox event", zap.Int64("id", event.ID),
+				zap.String("event", event.EventType), zap.Int64("group_id", event.GroupID))
+		}
+
+		if err := r.store.MarkDispatched(ctx, event.ID); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// publish hands event to every consumer, stopping at the first failure
+func (r *Relay) publish(ctx context.Context, event *models.OutboxEvent) error {
+	for _, sub := range r.subscriptions {
+		if err := sub.consumer(ctx, event); err != nil {
+			return fmt.Errorf("%s: %w", sub.name, err)
+		}
+	}
+	return nil
+}
+
+// LoggingConsumer logs every published event
+func LoggingConsumer(logger *zap.Logger) Consumer {
+	return func(ctx context.Context, event *models.OutboxEvent) error {
+		logger.Info("Domain event published",
+			zap.Int64("id", event.ID), zap.String("event", event.EventType),
+			zap.Int64("group_id", event.GroupID), zap.Time("created_at", event.CreatedAt))
+		return nil
+	}
+}
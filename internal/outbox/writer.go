@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/models"
+)
+
+// Appender is the part of the outbox repository the writer uses
+type Appender interface {
+	Append(ctx context.Context, tx *database.Tx, events []*models.OutboxEvent) error
+}
+
+// Writer implements events.Recorder by appending events to the outbox in the
+// transaction that raised them
+type Writer struct {
+	repo Appender
+}
+
+// NewWriter creates an outbox writer
+func NewWriter(repo Appender) *Writer {
+	return &Writer{repo: repo}
+}
+
+// Record implements events.Recorder
+func (w *Writer) Record(ctx context.Context, tx *database.Tx, raised []events.Event) error {
+	var rows []*models.OutboxEvent
+	for _, event := range raised {
+		row, ok, err := Encode(event)
+		if err != nil {
+			return err
+		}
+		if ok {
+			rows = append(rows, row)
+		}
+	}
+	return w.repo.Append(ctx, tx, rows)
+}
+
+// Encode snapshots event as an outbox row. It reports false for events that
+// are not published: balance adjustments are derived from the expense and
+// settlement events that cause them.
+func Encode(event events.Event) (*models.OutboxEvent, bool, error) {
+	var groupID int64
+	var payload interface{}
+	switch e := event.(type) {
+	case events.GroupCreated:
+		groupID, payload = e.Group.ID, e.Group
+	case events.GroupUpdated:
+		groupID, payload = e.Group.ID, e.Group
+	case events.GroupArchived:
+		groupID, payload = e.Group.ID, e.Group
+	case events.GroupUnarchived:
+		groupID, payload = e.Group.ID, e.Group
+	case events.MemberAdded:
+		groupID, payload = e.GroupID, models.MemberEventPayload{GroupID: e.GroupID, UserID: e.UserID}
+	case events.MemberRemoved:
+		groupID, payload = e.GroupID, models.MemberEventPayload{GroupID: e.GroupID, UserID: e.UserID}
+	case events.MemberRoleChanged:
+		groupID, payload = e.GroupID, models.MemberEventPayload{GroupID: e.GroupID, UserID: e.UserID, Role: e.Role}
+	case events.GroupOwnershipTransferred:
+		groupID, payload = e.GroupID, models.MemberEventPayload{GroupID: e.GroupID, UserID: e.UserID, PreviousUserID: e.PreviousUserID}
+	case events.ExpenseCreated:
+		expense := *e.Expense
+		expense.Splits = e.Splits
+		groupID, payload = expense.GroupID, &expense
+	case events.ExpenseUpdated:
+		expense := *e.Expense
+		expense.Splits = e.Splits
+		groupID, payload = expense.GroupID, &expense
+	case events.ExpenseDeleted:
+		groupID, payload = e.Expense.GroupID, e.Expense
+	case events.SettlementCreated:
+		groupID, payload = e.Settlement.GroupID, e.Settlement
+	case events.SettlementVoided:
+		groupID, payload = e.Settlement.GroupID, e.Settlement
+	case events.SettlementConfirmed:
+		groupID, payload = e.Settlement.GroupID, e.Settlement
+	case events.SettlementRejected:
+		groupID, payload = e.Settlement.GroupID, e.Settlement
+	default:
+		return nil, false, nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode %s outbox payload: %w", event.EventName(), err)
+	}
+
+	return &models.OutboxEvent{
+		GroupID:   groupID,
+		EventType: event.EventName(),
+		Payload:   data,
+	}, true, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+type balanceHistoryRepository struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewBalanceHistoryRepository creates a new balance history repository
+func NewBalanceHistoryRepository(db *database.DB, logger *zap.Logger) BalanceHistoryRepository {
+	return &balanceHistoryRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Record appends a balance change to the history. It must run after the change
+// has been applied in the same transaction: the resulting balance is read from
+// user_balances as the row is written.
+func (r *balanceHistoryRepository) Record(ctx context.Context, tx *database.Tx, entry *models.BalanceHistoryEntry) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO balance_history (group_id, user_id, currency, delta, balance, source_type, source_id, created_at)
+		SELECT ub.group_id, ub.user_id, ub.currency, ?, ub.balance, ?, ?, CURRENT_TIMESTAMP
+		FROM user_balances ub
+		WHERE ub.group_id = ? AND ub.user_id = ? AND ub.currency = ?
+	`
+
+	args := []interface{}{entry.Delta, entry.SourceType, entry.SourceID, entry.GroupID, entry.UserID, entry.Currency}
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, args...)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, args...)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to record balance history", zap.Error(err),
+			zap.String("source_type", string(entry.SourceType)), zap.Int64("source_id", entry.SourceID))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// ListForUser retrieves a user's balance history in a group, oldest first,
+// with pagination
+func (r *balanceHistoryRepository) ListForUser(ctx context.Context, filter *models.BalanceHistoryFilter) ([]*models.BalanceHistoryEntry, int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	whereClause := []string{"bh.group_id = ?", "bh.user_id = ?"}
+	args := []interface{}{filter.GroupID, filter.UserID}
+
+	if filter.Currency != "" {
+		whereClause = append(whereClause, "bh.currency = ?")
+		args = append(args, filter.Currency)
+	}
+
+	if !filter.FromDate.IsZero() {
+		whereClause = append(whereClause, "bh.created_at >= ?")
+		args = append(args, filter.FromDate)
+	}
+
+	if !filter.ToDate.IsZero() {
+		whereClause = append(whereClause, "bh.created_at <= ?")
+		args = append(args, filter.ToDate)
+	}
+
+	whereSQL := strings.Join(whereClause, " AND ")
+
+	var total int
+	err := r.db.GetContext(ctx, &total, `SELECT COUNT(*) FROM balance_history bh WHERE `+whereSQL, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count balance history", zap.Error(err))
+		return nil, 0, errors.NewDatabaseError(err)
+	}
+
+	page := filter.Page
+	limit := filter.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	offset := (page - 1) * limit
+
+	// Entries written in one transaction share a timestamp; id keeps their order
+	query := `
+		SELECT bh.id, bh.group_id, bh.user_id, bh.currency, bh.delta, bh.balance, bh.source_type, bh.source_id,
+		       COALESCE(e.uuid, s.uuid, '') AS source_uuid, bh.created_at
+		FROM balance_history bh
+		LEFT JOIN expenses e ON bh.source_type = 'expense' AND e.id = bh.source_id
+		LEFT JOIN settlements s ON bh.source_type = 'settlement' AND s.id = bh.source_id
+		WHERE ` + whereSQL + `
+		ORDER BY bh.created_at ASC, bh.id ASC
+		LIMIT ? OFFSET ?
+	`
+
+	entries := []*models.BalanceHistoryEntry{}
+	err = r.db.SelectContext(ctx, &entries, query, append(args, limit, offset)...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to list balance history", zap.Error(err))
+		return nil, 0, errors.NewDatabaseError(err)
+	}
+
+	return entries, total, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/pkg/errors"
 
@@ -27,13 +28,14 @@ func NewBalanceRepository(db *database.DB, logger *zap.Logger) BalanceRepository
 
 // Upsert creates or updates a balance record
 func (r *balanceRepository) Upsert(ctx context.Context, tx *database.Tx, balance *models.Balance) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
 		INSERT INTO user_balances (group_id, user_id, balance, currency, last_updated)
-		VALUES (?, ?, ?, ?, NOW())
-		ON DUPLICATE KEY UPDATE
-		balance = VALUES(balance),
-		last_updated = NOW()
-	`
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		` + r.db.OnConflictUpdate([]string{"group_id", "user_id", "currency"},
+		"balance = "+r.db.Excluded("balance")+", last_updated = CURRENT_TIMESTAMP")
 
 	var err error
 	if tx != nil {
@@ -43,7 +45,7 @@ func (r *balanceRepository) Upsert(ctx context.Context, tx *database.Tx, balance
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to upsert balance", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to upsert balance", zap.Error(err))
 		return errors.NewDatabaseError(err)
 	}
 
@@ -52,12 +54,15 @@ func (r *balanceRepository) Upsert(ctx context.Context, tx *database.Tx, balance
 
 // GetByGroupAndUser retrieves a balance for a specific group and user
 func (r *balanceRepository) GetByGroupAndUser(ctx context.Context, groupID, userID int64, currency string) (*models.Balance, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
 		SELECT ub.id, ub.group_id, ub.user_id, ub.balance, ub.currency, ub.last_updated,
 		       g.uuid as group_uuid, g.name as group_name,
-		       u.uuid as user_uuid, u.name as user_name, u.email as user_email
+		       u.uuid as user_uuid, u.name as user_name, u.email as user_email, u.is_pending as user_is_pending
 		FROM user_balances ub
-		LEFT JOIN ` + "`groups`" + ` g ON ub.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON ub.group_id = g.id
 		LEFT JOIN users u ON ub.user_id = u.id
 		WHERE ub.group_id = ? AND ub.user_id = ? AND ub.currency = ?
 	`
@@ -66,11 +71,12 @@ func (r *balanceRepository) GetByGroupAndUser(ctx context.Context, groupID, user
 	group := &models.Group{}
 	user := &models.User{}
 	var groupUUID, groupName, userUUID, userName, userEmail sql.NullString
+	var userIsPending sql.NullBool
 
 	err := r.db.QueryRowContext(ctx, query, groupID, userID, currency).Scan(
 		&balance.ID, &balance.GroupID, &balance.UserID, &balance.Balance, &balance.Currency, &balance.LastUpdated,
 		&groupUUID, &groupName,
-		&userUUID, &userName, &userEmail,
+		&userUUID, &userName, &userEmail, &userIsPending,
 	)
 
 	if err != nil {
@@ -82,7 +88,7 @@ func (r *balanceRepository) GetByGroupAndUser(ctx context.Context, groupID, user
 			balance.Currency = currency
 			return balance, nil
 		}
-		r.logger.Error("Failed to get balance", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get balance", zap.Error(err))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -98,17 +104,62 @@ func (r *balanceRepository) GetByGroupAndUser(ctx context.Context, groupID, user
 		user.UUID = userUUID.String
 		user.Name = userName.String
 		user.Email = userEmail.String
+		user.IsPending = userIsPending.Bool
 		balance.User = user
 	}
 
 	return balance, nil
 }
 
+// GetByGroupAndUserForUpdate retrieves a balance and locks its row until the transaction ends
+func (r *balanceRepository) GetByGroupAndUserForUpdate(ctx context.Context, tx *database.Tx, groupID, userID int64, currency string) (*models.Balance, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, group_id, user_id, balance, currency, last_updated
+		FROM user_balances
+		WHERE group_id = ? AND user_id = ? AND currency = ?
+		` + r.db.ForUpdate() + `
+	`
+
+	balance := &models.Balance{}
+
+	var err error
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, groupID, userID, currency).Scan(
+			&balance.ID, &balance.GroupID, &balance.UserID, &balance.Balance, &balance.Currency, &balance.LastUpdated,
+		)
+	} else {
+		err = r.db.QueryRowContext(ctx, query, groupID, userID, currency).Scan(
+			&balance.ID, &balance.GroupID, &balance.UserID, &balance.Balance, &balance.Currency, &balance.LastUpdated,
+		)
+	}
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Return zero balance if no record exists
+			balance.GroupID = groupID
+			balance.UserID = userID
+			balance.Balance = decimal.Zero
+			balance.Currency = currency
+			return balance, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get balance for update", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return balance, nil
+}
+
 // GetGroupBalances retrieves all balances for a group
 func (r *balanceRepository) GetGroupBalances(ctx context.Context, groupID int64, currency string) ([]*models.Balance, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
 		SELECT ub.id, ub.group_id, ub.user_id, ub.balance, ub.currency, ub.last_updated,
-		       u.uuid as user_uuid, u.name as user_name, u.email as user_email
+		       u.uuid as user_uuid, u.name as user_name, u.email as user_email, u.is_pending as user_is_pending
 		FROM user_balances ub
 		LEFT JOIN users u ON ub.user_id = u.id
 		WHERE ub.group_id = ? AND ub.currency = ?
@@ -117,7 +168,59 @@ func (r *balanceRepository) GetGroupBalances(ctx context.Context, groupID int64,
 
 	rows, err := r.db.QueryContext(ctx, query, groupID, currency)
 	if err != nil {
-		r.logger.Error("Failed to get group balances", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get group balances", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var balances []*models.Balance
+	for rows.Next() {
+		balance := &models.Balance{}
+		user := &models.User{}
+		var userUUID, userName, userEmail sql.NullString
+		var userIsPending sql.NullBool
+
+		err := rows.Scan(
+			&balance.ID, &balance.GroupID, &balance.UserID, &balance.Balance, &balance.Currency, &balance.LastUpdated,
+			&userUUID, &userName, &userEmail, &userIsPending,
+		)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan balance row", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+
+		if userUUID.Valid {
+			user.ID = balance.UserID
+			user.UUID = userUUID.String
+			user.Name = userName.String
+			user.Email = userEmail.String
+			user.IsPending = userIsPending.Bool
+			balance.User = user
+		}
+
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
+
+// GetGroupBalancesAllCurrencies retrieves the balances of a group in every currency
+func (r *balanceRepository) GetGroupBalancesAllCurrencies(ctx context.Context, groupID int64) ([]*models.Balance, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ub.id, ub.group_id, ub.user_id, ub.balance, ub.currency, ub.last_updated,
+		       u.uuid as user_uuid, u.name as user_name, u.email as user_email, u.is_pending as user_is_pending
+		FROM user_balances ub
+		LEFT JOIN users u ON ub.user_id = u.id
+		WHERE ub.group_id = ?
+		ORDER BY ub.currency, ub.balance DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get group balances in all currencies", zap.Error(err))
 		return nil, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -127,13 +230,14 @@ func (r *balanceRepository) GetGroupBalances(ctx context.Context, groupID int64,
 		balance := &models.Balance{}
 		user := &models.User{}
 		var userUUID, userName, userEmail sql.NullString
+		var userIsPending sql.NullBool
 
 		err := rows.Scan(
 			&balance.ID, &balance.GroupID, &balance.UserID, &balance.Balance, &balance.Currency, &balance.LastUpdated,
-			&userUUID, &userName, &userEmail,
+			&userUUID, &userName, &userEmail, &userIsPending,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan balance row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan balance row", zap.Error(err))
 			return nil, errors.NewDatabaseError(err)
 		}
 
@@ -142,6 +246,67 @@ func (r *balanceRepository) GetGroupBalances(ctx context.Context, groupID int64,
 			user.UUID = userUUID.String
 			user.Name = userName.String
 			user.Email = userEmail.String
+			user.IsPending = userIsPending.Bool
+			balance.User = user
+		}
+
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
+
+// GetGroupBalancesForUpdate retrieves all balances for a group and locks them
+// until the transaction ends
+func (r *balanceRepository) GetGroupBalancesForUpdate(ctx context.Context, tx *database.Tx, groupID int64, currency string) ([]*models.Balance, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ub.id, ub.group_id, ub.user_id, ub.balance, ub.currency, ub.last_updated,
+		       u.uuid as user_uuid, u.name as user_name, u.email as user_email, u.is_pending as user_is_pending
+		FROM user_balances ub
+		LEFT JOIN users u ON ub.user_id = u.id
+		WHERE ub.group_id = ? AND ub.currency = ?
+		ORDER BY ub.balance DESC
+		` + r.db.ForUpdate("ub") + `
+	`
+
+	var rows *sql.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, query, groupID, currency)
+	} else {
+		rows, err = r.db.QueryContext(ctx, query, groupID, currency)
+	}
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get group balances for update", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var balances []*models.Balance
+	for rows.Next() {
+		balance := &models.Balance{}
+		user := &models.User{}
+		var userUUID, userName, userEmail sql.NullString
+		var userIsPending sql.NullBool
+
+		err := rows.Scan(
+			&balance.ID, &balance.GroupID, &balance.UserID, &balance.Balance, &balance.Currency, &balance.LastUpdated,
+			&userUUID, &userName, &userEmail, &userIsPending,
+		)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan balance row", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+
+		if userUUID.Valid {
+			user.ID = balance.UserID
+			user.UUID = userUUID.String
+			user.Name = userName.String
+			user.Email = userEmail.String
+			user.IsPending = userIsPending.Bool
 			balance.User = user
 		}
 
@@ -153,18 +318,21 @@ func (r *balanceRepository) GetGroupBalances(ctx context.Context, groupID int64,
 
 // GetUserBalances retrieves all balances for a user across all groups
 func (r *balanceRepository) GetUserBalances(ctx context.Context, userID int64) ([]*models.Balance, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
 		SELECT ub.id, ub.group_id, ub.user_id, ub.balance, ub.currency, ub.last_updated,
 		       g.uuid as group_uuid, g.name as group_name
 		FROM user_balances ub
-		LEFT JOIN ` + "`groups`" + ` g ON ub.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON ub.group_id = g.id
 		WHERE ub.user_id = ?
 		ORDER BY ub.last_updated DESC
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
-		r.logger.Error("Failed to get user balances", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get user balances", zap.Error(err))
 		return nil, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -180,7 +348,7 @@ func (r *balanceRepository) GetUserBalances(ctx context.Context, userID int64) (
 			&groupUUID, &groupName,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan user balance row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan user balance row", zap.Error(err))
 			return nil, errors.NewDatabaseError(err)
 		}
 
@@ -199,13 +367,14 @@ func (r *balanceRepository) GetUserBalances(ctx context.Context, userID int64) (
 
 // UpdateBalance updates a user's balance by adding/subtracting an amount
 func (r *balanceRepository) UpdateBalance(ctx context.Context, tx *database.Tx, groupID, userID int64, amount decimal.Decimal, currency string) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
 		INSERT INTO user_balances (group_id, user_id, balance, currency, last_updated)
-		VALUES (?, ?, ?, ?, NOW())
-		ON DUPLICATE KEY UPDATE
-		balance = balance + VALUES(balance),
-		last_updated = NOW()
-	`
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		` + r.db.OnConflictUpdate([]string{"group_id", "user_id", "currency"},
+		"balance = user_balances.balance + "+r.db.Excluded("balance")+", last_updated = CURRENT_TIMESTAMP")
 
 	var err error
 	if tx != nil {
@@ -215,7 +384,368 @@ func (r *balanceRepository) UpdateBalance(ctx context.Context, tx *database.Tx,
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to update balance", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to update balance", zap.Error(err))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// debtPair orders two users the way user_debts stores them and returns the
+// signed amount for the stored orientation (positive means user A owes user B)
+func debtPair(debtorID, creditorID int64, amount decimal.Decimal) (int64, int64, decimal.Decimal) {
+	if debtorID < creditorID {
+		return debtorID, creditorID, amount
+	}
+	return creditorID, debtorID, amount.Neg()
+}
+
+// UpdateDebt adjusts how much debtor owes creditor by amount. A negative amount
+// reduces the debt and may flip it the other way round.
+func (r *balanceRepository) UpdateDebt(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, amount decimal.Decimal, currency string) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_debts (group_id, user_a_id, user_b_id, currency, amount, last_updated)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		` + r.db.OnConflictUpdate([]string{"group_id", "user_a_id", "user_b_id", "currency"},
+		"amount = user_debts.amount + "+r.db.Excluded("amount")+", last_updated = CURRENT_TIMESTAMP")
+
+	userA, userB, signed := debtPair(debtorID, creditorID, amount)
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, groupID, userA, userB, currency, signed)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, groupID, userA, userB, currency, signed)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update debt", zap.Error(err))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// GetDebtForUpdate returns how much debtor owes creditor and locks the pair
+// until the transaction ends. The result is negative when creditor owes debtor.
+func (r *balanceRepository) GetDebtForUpdate(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, currency string) (decimal.Decimal, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT amount
+		FROM user_debts
+		WHERE group_id = ? AND user_a_id = ? AND user_b_id = ? AND currency = ?
+		` + r.db.ForUpdate() + `
+	`
+
+	userA, userB, _ := debtPair(debtorID, creditorID, decimal.Zero)
+
+	var amount decimal.Decimal
+	var err error
+	if tx != nil {
+		err = tx.QueryRowContext(ctx, query, groupID, userA, userB, currency).Scan(&amount)
+	} else {
+		err = r.db.QueryRowContext(ctx, query, groupID, userA, userB, currency).Scan(&amount)
+	}
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return decimal.Zero, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get debt for update", zap.Error(err))
+		return decimal.Zero, errors.NewDatabaseError(err)
+	}
+
+	if debtorID > creditorID {
+		amount = amount.Neg()
+	}
+
+	return amount, nil
+}
+
+// GetGroupDebts retrieves the outstanding pairwise debts of a group, each
+// oriented so the amount is positive
+func (r *balanceRepository) GetGroupDebts(ctx context.Context, groupID int64, currency string) ([]*models.DebtRelationship, error) {
+	return r.queryGroupDebts(ctx, "ud.group_id = ? AND ud.currency = ?", groupID, currency)
+}
+
+// GetGroupDebtsAllCurrencies retrieves the outstanding pairwise debts of a
+// group in every currency, each oriented so the amount is positive
+func (r *balanceRepository) GetGroupDebtsAllCurrencies(ctx context.Context, groupID int64) ([]*models.DebtRelationship, error) {
+	return r.queryGroupDebts(ctx, "ud.group_id = ?", groupID)
+}
+
+// queryGroupDebts loads the non-zero debts matching where, largest first
+func (r *balanceRepository) queryGroupDebts(ctx context.Context, where string, args ...interface{}) ([]*models.DebtRelationship, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ud.user_a_id, ud.user_b_id, ud.amount, ud.currency,
+		       ua.uuid, ua.name, ua.email, ua.is_pending,
+		       ub.uuid, ub.name, ub.email, ub.is_pending
+		FROM user_debts ud
+		LEFT JOIN users ua ON ud.user_a_id = ua.id
+		LEFT JOIN users ub ON ud.user_b_id = ub.id
+		WHERE ` + where + ` AND ud.amount <> 0
+		ORDER BY ABS(ud.amount) DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get group debts", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var debts []*models.DebtRelationship
+	for rows.Next() {
+		var userAID, userBID int64
+		var amount decimal.Decimal
+		var debtCurrency string
+		var aUUID, aName, aEmail, bUUID, bName, bEmail sql.NullString
+		var aIsPending, bIsPending sql.NullBool
+
+		err := rows.Scan(
+			&userAID, &userBID, &amount, &debtCurrency,
+			&aUUID, &aName, &aEmail, &aIsPending,
+			&bUUID, &bName, &bEmail, &bIsPending,
+		)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan debt row", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+
+		userA := &models.User{ID: userAID, UUID: aUUID.String, Name: aName.String, Email: aEmail.String, IsPending: aIsPending.Bool}
+		userB := &models.User{ID: userBID, UUID: bUUID.String, Name: bName.String, Email: bEmail.String, IsPending: bIsPending.Bool}
+
+		debt := &models.DebtRelationship{Debtor: userA, Creditor: userB, Amount: amount, Currency: debtCurrency}
+		if amount.IsNegative() {
+			debt.Debtor, debt.Creditor, debt.Amount = userB, userA, amount.Neg()
+		}
+
+		debts = append(debts, debt)
+	}
+
+	return debts, nil
+}
+
+// GetPairDebts retrieves what user owes otherUser in a group, one entry per
+// currency with an outstanding debt, negative where otherUser is in debt
+func (r *balanceRepository) GetPairDebts(ctx context.Context, groupID, userID, otherUserID int64) ([]*models.PairDebt, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT currency, amount
+		FROM user_debts
+		WHERE group_id = ? AND user_a_id = ? AND user_b_id = ? AND amount <> 0
+		ORDER BY currency
+	`
+
+	userA, userB, _ := debtPair(userID, otherUserID, decimal.Zero)
+
+	debts := []*models.PairDebt{}
+	err := r.db.SelectContext(ctx, &debts, query, groupID, userA, userB)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get pair debts", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	if userID > otherUserID {
+		for _, debt := range debts {
+			debt.Amount = debt.Amount.Neg()
+		}
+	}
+
+	return debts, nil
+}
+
+// ClearGroupDebts zeroes every pairwise debt of a group in one currency
+func (r *balanceRepository) ClearGroupDebts(ctx context.Context, tx *database.Tx, groupID int64, currency string) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE user_debts SET amount = 0, last_updated = CURRENT_TIMESTAMP WHERE group_id = ? AND currency = ? AND amount <> 0`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, groupID, currency)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, groupID, currency)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to clear group debts", zap.Error(err))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// GetUserExpenseTotals sums what a user paid for and owes across the expenses
+// of a group in one currency. Expenses count in the base currency their
+// balances were booked in. Refunds are stored negated and so net out.
+func (r *balanceRepository) GetUserExpenseTotals(ctx context.Context, groupID, userID int64, currency string) (*models.ExpenseTotals, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			(SELECT COALESCE(SUM(e.base_amount), 0)
+			 FROM expenses e
+			 WHERE e.group_id = ? AND e.base_currency = ? AND e.paid_by = ? AND e.deleted_at IS NULL) AS paid,
+			(SELECT COALESCE(SUM(es.amount), 0)
+			 FROM expense_splits es
+			 JOIN expenses e ON es.expense_id = e.id
+			 WHERE e.group_id = ? AND e.base_currency = ? AND es.user_id = ?
+			   AND e.deleted_at IS NULL AND es.deleted_at IS NULL) AS owed,
+			(SELECT COUNT(*)
+			 FROM expenses e
+			 WHERE e.group_id = ? AND e.base_currency = ? AND e.deleted_at IS NULL
+			   AND (e.paid_by = ? OR EXISTS (
+			       SELECT 1 FROM expense_splits es WHERE es.expense_id = e.id AND es.user_id = ? AND es.deleted_at IS NULL))) AS count
+	`
+
+	totals := &models.ExpenseTotals{}
+	err := r.db.QueryRowContext(ctx, query,
+		groupID, currency, userID,
+		groupID, currency, userID,
+		groupID, currency, userID, userID,
+	).Scan(&totals.Paid, &totals.Owed, &totals.Count)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get user expense totals", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return totals, nil
+}
+
+// GetUserExpenseTotalsByCurrency is GetUserExpenseTotals for every currency
+// the user has expenses in, ordered by currency
+func (r *balanceRepository) GetUserExpenseTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.ExpenseTotals, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	// A user has at most one split per expense, so the join keeps one row per expense
+	query := `
+		SELECT e.base_currency AS currency,
+		       COALESCE(SUM(CASE WHEN e.paid_by = ? THEN e.base_amount ELSE 0 END), 0) AS paid,
+		       COALESCE(SUM(es.amount), 0) AS owed,
+		       COUNT(*) AS count
+		FROM expenses e
+		LEFT JOIN expense_splits es ON es.expense_id = e.id AND es.user_id = ? AND es.deleted_at IS NULL
+		WHERE e.group_id = ? AND e.deleted_at IS NULL AND (e.paid_by = ? OR es.user_id IS NOT NULL)
+		GROUP BY e.base_currency
+		ORDER BY e.base_currency
+	`
+
+	totals := []*models.ExpenseTotals{}
+	err := r.db.SelectContext(ctx, &totals, query, userID, userID, groupID, userID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get user expense totals by currency", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return totals, nil
+}
+
+// GetUserSettlementTotalsByCurrency is GetUserSettlementTotals for every
+// currency the user has settlements in, ordered by currency
+func (r *balanceRepository) GetUserSettlementTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.SettlementTotals, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT s.currency,
+		       COALESCE(SUM(CASE WHEN s.from_user_id = ? THEN s.amount ELSE 0 END), 0) AS sent,
+		       COALESCE(SUM(CASE WHEN s.to_user_id = ? THEN s.amount ELSE 0 END), 0) AS received,
+		       COUNT(*) AS count
+		FROM settlements s
+		WHERE s.group_id = ? AND s.status = 'confirmed' AND s.voided_at IS NULL
+		  AND (s.from_user_id = ? OR s.to_user_id = ?)
+		GROUP BY s.currency
+		ORDER BY s.currency
+	`
+
+	totals := []*models.SettlementTotals{}
+	err := r.db.SelectContext(ctx, &totals, query, userID, userID, groupID, userID, userID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get user settlement totals by currency", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return totals, nil
+}
+
+// GetUserSettlementTotals sums the confirmed, non-voided settlements a user
+// sent and received in a group in one currency
+func (r *balanceRepository) GetUserSettlementTotals(ctx context.Context, groupID, userID int64, currency string) (*models.SettlementTotals, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COALESCE(SUM(CASE WHEN s.from_user_id = ? THEN s.amount ELSE 0 END), 0) AS sent,
+		       COALESCE(SUM(CASE WHEN s.to_user_id = ? THEN s.amount ELSE 0 END), 0) AS received,
+		       COUNT(*) AS count
+		FROM settlements s
+		WHERE s.group_id = ? AND s.currency = ? AND s.status = 'confirmed' AND s.voided_at IS NULL
+		  AND (s.from_user_id = ? OR s.to_user_id = ?)
+	`
+
+	totals := &models.SettlementTotals{}
+	err := r.db.QueryRowContext(ctx, query, userID, userID, groupID, currency, userID, userID).
+		Scan(&totals.Sent, &totals.Received, &totals.Count)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get user settlement totals", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return totals, nil
+}
+
+// ZeroGroupBalances sets every balance row of a group to zero, in all currencies
+func (r *balanceRepository) ZeroGroupBalances(ctx context.Context, tx *database.Tx, groupID int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE user_balances SET balance = 0, last_updated = CURRENT_TIMESTAMP WHERE group_id = ?`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, groupID)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, groupID)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to zero group balances", zap.Error(err), zap.Int64("groupID", groupID))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// ZeroGroupDebts sets every pairwise debt of a group to zero, in all currencies
+func (r *balanceRepository) ZeroGroupDebts(ctx context.Context, tx *database.Tx, groupID int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE user_debts SET amount = 0, last_updated = CURRENT_TIMESTAMP WHERE group_id = ? AND amount <> 0`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, groupID)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, groupID)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to zero group debts", zap.Error(err), zap.Int64("groupID", groupID))
 		return errors.NewDatabaseError(err)
 	}
 
@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"strings"
+	"time"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
 	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
 	"expense-split-tracker/pkg/errors"
 
 	"go.uber.org/zap"
@@ -27,48 +30,54 @@ func NewExpenseRepository(db *database.DB, logger *zap.Logger) ExpenseRepository
 
 // Create creates a new expense
 func (r *expenseRepository) Create(ctx context.Context, tx *database.Tx, expense *models.Expense) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		INSERT INTO expenses (uuid, group_id, paid_by, amount, currency, description, split_type, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+		INSERT INTO expenses (uuid, group_id, paid_by, amount, currency, base_amount, base_currency, exchange_rate, description, category, split_type, is_refund, receipt_url, recurring_expense_id, recurring_run_at, expense_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	`
 
-	var result sql.Result
-	var err error
-
-	if tx != nil {
-		result, err = tx.ExecContext(ctx, query, expense.UUID, expense.GroupID, expense.PaidBy,
-			expense.Amount, expense.Currency, expense.Description, expense.SplitType)
-	} else {
-		result, err = r.db.ExecContext(ctx, query, expense.UUID, expense.GroupID, expense.PaidBy,
-			expense.Amount, expense.Currency, expense.Description, expense.SplitType)
-	}
-
-	if err != nil {
-		r.logger.Error("Failed to create expense", zap.Error(err), zap.String("description", expense.Description))
-		return errors.NewDatabaseError(err)
+	var recurringID *int64
+	var recurringRunAt *time.Time
+	if expense.Recurrence != nil {
+		recurringID = &expense.Recurrence.RecurringExpenseID
+		recurringRunAt = &expense.Recurrence.RunAt
 	}
 
-	id, err := result.LastInsertId()
+	id, err := r.db.InsertReturningID(ctx, tx, query, expense.UUID, expense.GroupID, expense.PaidBy,
+		expense.Amount, expense.Currency, expense.BaseAmount, expense.BaseCurrency, expense.ExchangeRate, expense.Description, expense.Category, expense.SplitType, expense.IsRefund, expense.ReceiptURL,
+		recurringID, recurringRunAt, expense.ExpenseDate)
 	if err != nil {
-		r.logger.Error("Failed to get last insert ID", zap.Error(err))
+		// A recurring run may only create one expense
+		if isDuplicateKey(err) && expense.Recurrence != nil {
+			return errors.NewAlreadyExistsError("Expense for this recurring run")
+		}
+		if dupErr := duplicateKeyError(err, "Expense", map[string]string{"uuid": "uuid"}); dupErr != nil {
+			return dupErr
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to create expense", zap.Error(err), zap.String("description", expense.Description))
 		return errors.NewDatabaseError(err)
 	}
 
 	expense.ID = id
-	r.logger.Info("Expense created successfully", zap.Int64("id", expense.ID), zap.String("description", expense.Description))
+	logging.FromContext(ctx, r.logger).Info("Expense created successfully", zap.Int64("id", expense.ID), zap.String("description", expense.Description))
 	return nil
 }
 
 // GetByID retrieves an expense by ID
 func (r *expenseRepository) GetByID(ctx context.Context, id int64) (*models.Expense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.description, e.split_type, e.created_at, e.updated_at,
+		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.base_amount, e.base_currency, e.exchange_rate, e.description, e.category, e.split_type, e.is_refund, e.receipt_url, e.expense_date, e.created_at, e.updated_at, e.deleted_at, e.deleted_by,
 		       g.uuid as group_uuid, g.name as group_name,
-		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email
+		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email, u.is_pending as payer_is_pending
 		FROM expenses e
-		LEFT JOIN ` + "`groups`" + ` g ON e.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON e.group_id = g.id
 		LEFT JOIN users u ON e.paid_by = u.id
-		WHERE e.id = ?
+		WHERE e.id = ? AND e.deleted_at IS NULL
 	`
 
 	row := r.db.QueryRowContext(ctx, query, id)
@@ -77,19 +86,20 @@ func (r *expenseRepository) GetByID(ctx context.Context, id int64) (*models.Expe
 	group := &models.Group{}
 	payer := &models.User{}
 	var groupUUID, groupName, payerUUID, payerName, payerEmail sql.NullString
+	var payerIsPending sql.NullBool
 
 	err := row.Scan(
 		&expense.ID, &expense.UUID, &expense.GroupID, &expense.PaidBy, &expense.Amount,
-		&expense.Currency, &expense.Description, &expense.SplitType, &expense.CreatedAt, &expense.UpdatedAt,
+		&expense.Currency, &expense.BaseAmount, &expense.BaseCurrency, &expense.ExchangeRate, &expense.Description, &expense.Category, &expense.SplitType, &expense.IsRefund, &expense.ReceiptURL, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt, &expense.DeletedAt, &expense.DeletedBy,
 		&groupUUID, &groupName,
-		&payerUUID, &payerName, &payerEmail,
+		&payerUUID, &payerName, &payerEmail, &payerIsPending,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.NewNotFoundError("Expense")
 		}
-		r.logger.Error("Failed to get expense by ID", zap.Error(err), zap.Int64("id", id))
+		logging.FromContext(ctx, r.logger).Error("Failed to get expense by ID", zap.Error(err), zap.Int64("id", id))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -105,6 +115,7 @@ func (r *expenseRepository) GetByID(ctx context.Context, id int64) (*models.Expe
 		payer.UUID = payerUUID.String
 		payer.Name = payerName.String
 		payer.Email = payerEmail.String
+		payer.IsPending = payerIsPending.Bool
 		expense.Payer = payer
 	}
 
@@ -113,14 +124,37 @@ func (r *expenseRepository) GetByID(ctx context.Context, id int64) (*models.Expe
 
 // GetByUUID retrieves an expense by UUID
 func (r *expenseRepository) GetByUUID(ctx context.Context, uuid string) (*models.Expense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	return r.getByUUID(ctx, uuid, false)
+}
+
+// GetByUUIDIncludingDeleted retrieves an expense by UUID even if it was deleted
+func (r *expenseRepository) GetByUUIDIncludingDeleted(ctx context.Context, uuid string) (*models.Expense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	return r.getByUUID(ctx, uuid, true)
+}
+
+func (r *expenseRepository) getByUUID(ctx context.Context, uuid string, includeDeleted bool) (*models.Expense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	where := "e.uuid = ?"
+	if !includeDeleted {
+		where += " AND e.deleted_at IS NULL"
+	}
+
 	query := `
-		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.description, e.split_type, e.created_at, e.updated_at,
+		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.base_amount, e.base_currency, e.exchange_rate, e.description, e.category, e.split_type, e.is_refund, e.receipt_url, e.expense_date, e.created_at, e.updated_at, e.deleted_at, e.deleted_by,
 		       g.uuid as group_uuid, g.name as group_name,
-		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email
+		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email, u.is_pending as payer_is_pending
 		FROM expenses e
-		LEFT JOIN ` + "`groups`" + ` g ON e.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON e.group_id = g.id
 		LEFT JOIN users u ON e.paid_by = u.id
-		WHERE e.uuid = ?
+		WHERE ` + where + `
 	`
 
 	row := r.db.QueryRowContext(ctx, query, uuid)
@@ -129,19 +163,20 @@ func (r *expenseRepository) GetByUUID(ctx context.Context, uuid string) (*models
 	group := &models.Group{}
 	payer := &models.User{}
 	var groupUUID, groupName, payerUUID, payerName, payerEmail sql.NullString
+	var payerIsPending sql.NullBool
 
 	err := row.Scan(
 		&expense.ID, &expense.UUID, &expense.GroupID, &expense.PaidBy, &expense.Amount,
-		&expense.Currency, &expense.Description, &expense.SplitType, &expense.CreatedAt, &expense.UpdatedAt,
+		&expense.Currency, &expense.BaseAmount, &expense.BaseCurrency, &expense.ExchangeRate, &expense.Description, &expense.Category, &expense.SplitType, &expense.IsRefund, &expense.ReceiptURL, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt, &expense.DeletedAt, &expense.DeletedBy,
 		&groupUUID, &groupName,
-		&payerUUID, &payerName, &payerEmail,
+		&payerUUID, &payerName, &payerEmail, &payerIsPending,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.NewNotFoundError("Expense")
 		}
-		r.logger.Error("Failed to get expense by UUID", zap.Error(err), zap.String("uuid", uuid))
+		logging.FromContext(ctx, r.logger).Error("Failed to get expense by UUID", zap.Error(err), zap.String("uuid", uuid))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -157,70 +192,142 @@ func (r *expenseRepository) GetByUUID(ctx context.Context, uuid string) (*models
 		payer.UUID = payerUUID.String
 		payer.Name = payerName.String
 		payer.Email = payerEmail.String
+		payer.IsPending = payerIsPending.Bool
 		expense.Payer = payer
 	}
 
 	return expense, nil
 }
 
+// GetByIDForUpdate retrieves an expense, deleted or not, without its group
+// and payer, and locks its row until the transaction ends
+func (r *expenseRepository) GetByIDForUpdate(ctx context.Context, tx *database.Tx, id int64) (*models.Expense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, uuid, group_id, paid_by, amount, currency, base_amount, base_currency, exchange_rate, description, category, split_type, is_refund, receipt_url, expense_date, created_at, updated_at, deleted_at, deleted_by
+		FROM expenses
+		WHERE id = ?
+		` + r.db.ForUpdate() + `
+	`
+
+	var row *sql.Row
+	if tx != nil {
+		row = tx.QueryRowContext(ctx, query, id)
+	} else {
+		row = r.db.QueryRowContext(ctx, query, id)
+	}
+
+	expense := &models.Expense{}
+	err := row.Scan(
+		&expense.ID, &expense.UUID, &expense.GroupID, &expense.PaidBy, &expense.Amount,
+		&expense.Currency, &expense.BaseAmount, &expense.BaseCurrency, &expense.ExchangeRate, &expense.Description, &expense.Category, &expense.SplitType, &expense.IsRefund, &expense.ReceiptURL, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt, &expense.DeletedAt, &expense.DeletedBy,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Expense")
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get expense for update", zap.Error(err), zap.Int64("id", id))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return expense, nil
+}
+
 // Update updates an expense
 func (r *expenseRepository) Update(ctx context.Context, tx *database.Tx, expense *models.Expense) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE expenses
-		SET amount = ?, currency = ?, description = ?, updated_at = NOW()
-		WHERE id = ?
+		SET amount = ?, currency = ?, base_amount = ?, base_currency = ?, exchange_rate = ?, description = ?, split_type = ?, expense_date = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	var err error
 	if tx != nil {
-		_, err = tx.ExecContext(ctx, query, expense.Amount, expense.Currency, expense.Description, expense.ID)
+		_, err = tx.ExecContext(ctx, query, expense.Amount, expense.Currency, expense.BaseAmount, expense.BaseCurrency, expense.ExchangeRate,
+			expense.Description, expense.SplitType, expense.ExpenseDate, expense.ID)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, expense.Amount, expense.Currency, expense.BaseAmount, expense.BaseCurrency, expense.ExchangeRate,
+			expense.Description, expense.SplitType, expense.ExpenseDate, expense.ID)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update expense", zap.Error(err), zap.Int64("id", expense.ID))
+		return errors.NewDatabaseError(err)
+	}
+
+	logging.FromContext(ctx, r.logger).Info("Expense updated successfully", zap.Int64("id", expense.ID))
+	return nil
+}
+
+// UpdateReceiptURL sets or clears the receipt URL of an expense
+func (r *expenseRepository) UpdateReceiptURL(ctx context.Context, tx *database.Tx, id int64, receiptURL string) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE expenses SET receipt_url = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, receiptURL, id)
 	} else {
-		_, err = r.db.ExecContext(ctx, query, expense.Amount, expense.Currency, expense.Description, expense.ID)
+		_, err = r.db.ExecContext(ctx, query, receiptURL, id)
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to update expense", zap.Error(err), zap.Int64("id", expense.ID))
+		logging.FromContext(ctx, r.logger).Error("Failed to update expense receipt", zap.Error(err), zap.Int64("id", id))
 		return errors.NewDatabaseError(err)
 	}
 
-	r.logger.Info("Expense updated successfully", zap.Int64("id", expense.ID))
 	return nil
 }
 
-// Delete deletes an expense
-func (r *expenseRepository) Delete(ctx context.Context, tx *database.Tx, id int64) error {
-	query := `DELETE FROM expenses WHERE id = ?`
+// Delete marks an expense as deleted by deletedBy, which may be nil. The row
+// and its splits are kept for history. It reports false without changing
+// anything if the expense was already deleted.
+func (r *expenseRepository) Delete(ctx context.Context, tx *database.Tx, id int64, deletedBy *int64) (bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE expenses SET deleted_at = CURRENT_TIMESTAMP, deleted_by = ? WHERE id = ? AND deleted_at IS NULL`
 
 	var result sql.Result
 	var err error
 
 	if tx != nil {
-		result, err = tx.ExecContext(ctx, query, id)
+		result, err = tx.ExecContext(ctx, query, deletedBy, id)
 	} else {
-		result, err = r.db.ExecContext(ctx, query, id)
+		result, err = r.db.ExecContext(ctx, query, deletedBy, id)
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to delete expense", zap.Error(err), zap.Int64("id", id))
-		return errors.NewDatabaseError(err)
+		logging.FromContext(ctx, r.logger).Error("Failed to delete expense", zap.Error(err), zap.Int64("id", id))
+		return false, errors.NewDatabaseError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		r.logger.Error("Failed to get rows affected", zap.Error(err))
-		return errors.NewDatabaseError(err)
+		logging.FromContext(ctx, r.logger).Error("Failed to get rows affected", zap.Error(err))
+		return false, errors.NewDatabaseError(err)
 	}
 
 	if rowsAffected == 0 {
-		return errors.NewNotFoundError("Expense")
+		return false, nil
 	}
 
-	r.logger.Info("Expense deleted successfully", zap.Int64("id", id))
-	return nil
+	logging.FromContext(ctx, r.logger).Info("Expense deleted successfully", zap.Int64("id", id))
+	return true, nil
 }
 
 // List retrieves expenses with filtering
 func (r *expenseRepository) List(ctx context.Context, filter *models.ExpenseFilter) ([]*models.Expense, int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	whereClause := []string{"1=1"}
 	args := []interface{}{}
 	argIndex := 1
@@ -243,38 +350,66 @@ func (r *expenseRepository) List(ctx context.Context, filter *models.ExpenseFilt
 		argIndex++
 	}
 
+	if filter.Category != "" {
+		whereClause = append(whereClause, "e.category = ?")
+		args = append(args, filter.Category)
+		argIndex++
+	}
+
+	if filter.Query != "" {
+		whereClause = append(whereClause, "LOWER(e.description) LIKE ? ESCAPE '"+utils.LikeEscapeChar+"'")
+		args = append(args, strings.ToLower(utils.ContainsPattern(filter.Query)))
+		argIndex++
+	}
+
 	if filter.SplitType != "" {
 		whereClause = append(whereClause, "e.split_type = ?")
 		args = append(args, filter.SplitType)
 		argIndex++
 	}
 
+	if !filter.MinAmount.IsZero() {
+		whereClause = append(whereClause, "e.amount >= ?")
+		args = append(args, filter.MinAmount)
+		argIndex++
+	}
+
+	if !filter.MaxAmount.IsZero() {
+		whereClause = append(whereClause, "e.amount <= ?")
+		args = append(args, filter.MaxAmount)
+		argIndex++
+	}
+
 	if !filter.FromDate.IsZero() {
-		whereClause = append(whereClause, "e.created_at >= ?")
+		whereClause = append(whereClause, "e.expense_date >= ?")
 		args = append(args, filter.FromDate)
 		argIndex++
 	}
 
 	if !filter.ToDate.IsZero() {
-		whereClause = append(whereClause, "e.created_at <= ?")
+		whereClause = append(whereClause, "e.expense_date <= ?")
 		args = append(args, filter.ToDate)
 		argIndex++
 	}
 
+	if !filter.IncludeDeleted {
+		whereClause = append(whereClause, "e.deleted_at IS NULL")
+	}
+
 	whereSQL := strings.Join(whereClause, " AND ")
 
 	// Count total
 	countQuery := `
 		SELECT COUNT(*)
 		FROM expenses e
-		LEFT JOIN ` + "`groups`" + ` g ON e.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON e.group_id = g.id
 		LEFT JOIN users u ON e.paid_by = u.id
 		WHERE ` + whereSQL
 
 	var total int
 	err := r.db.GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
-		r.logger.Error("Failed to count expenses", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to count expenses", zap.Error(err))
 		return nil, 0, errors.NewDatabaseError(err)
 	}
 
@@ -289,22 +424,24 @@ func (r *expenseRepository) List(ctx context.Context, filter *models.ExpenseFilt
 	}
 	offset := (page - 1) * limit
 
+	orderBy := orderByClause(filter.ListSort, expenseSortColumns, "e.id", "e.expense_date DESC, e.id DESC")
+
 	query := `
-		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.description, e.split_type, e.created_at, e.updated_at,
+		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.base_amount, e.base_currency, e.exchange_rate, e.description, e.category, e.split_type, e.is_refund, e.receipt_url, e.expense_date, e.created_at, e.updated_at, e.deleted_at, e.deleted_by,
 		       g.uuid as group_uuid, g.name as group_name,
-		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email
+		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email, u.is_pending as payer_is_pending
 		FROM expenses e
-		LEFT JOIN ` + "`groups`" + ` g ON e.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON e.group_id = g.id
 		LEFT JOIN users u ON e.paid_by = u.id
 		WHERE ` + whereSQL + `
-		ORDER BY e.created_at DESC
+		ORDER BY ` + orderBy + `
 		LIMIT ? OFFSET ?
 	`
 
 	args = append(args, limit, offset)
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		r.logger.Error("Failed to list expenses", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to list expenses", zap.Error(err))
 		return nil, 0, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -315,15 +452,16 @@ func (r *expenseRepository) List(ctx context.Context, filter *models.ExpenseFilt
 		group := &models.Group{}
 		payer := &models.User{}
 		var groupUUID, groupName, payerUUID, payerName, payerEmail sql.NullString
+		var payerIsPending sql.NullBool
 
 		err := rows.Scan(
 			&expense.ID, &expense.UUID, &expense.GroupID, &expense.PaidBy, &expense.Amount,
-			&expense.Currency, &expense.Description, &expense.SplitType, &expense.CreatedAt, &expense.UpdatedAt,
+			&expense.Currency, &expense.BaseAmount, &expense.BaseCurrency, &expense.ExchangeRate, &expense.Description, &expense.Category, &expense.SplitType, &expense.IsRefund, &expense.ReceiptURL, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt, &expense.DeletedAt, &expense.DeletedBy,
 			&groupUUID, &groupName,
-			&payerUUID, &payerName, &payerEmail,
+			&payerUUID, &payerName, &payerEmail, &payerIsPending,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan expense row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan expense row", zap.Error(err))
 			return nil, 0, errors.NewDatabaseError(err)
 		}
 
@@ -339,6 +477,7 @@ func (r *expenseRepository) List(ctx context.Context, filter *models.ExpenseFilt
 			payer.UUID = payerUUID.String
 			payer.Name = payerName.String
 			payer.Email = payerEmail.String
+			payer.IsPending = payerIsPending.Bool
 			expense.Payer = payer
 		}
 
@@ -349,20 +488,28 @@ func (r *expenseRepository) List(ctx context.Context, filter *models.ExpenseFilt
 }
 
 // GetGroupExpenses retrieves expenses for a specific group
-func (r *expenseRepository) GetGroupExpenses(ctx context.Context, groupID int64, offset, limit int) ([]*models.Expense, error) {
+func (r *expenseRepository) GetGroupExpenses(ctx context.Context, groupID int64, offset, limit int, includeDeleted bool) ([]*models.Expense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	where := "e.group_id = ?"
+	if !includeDeleted {
+		where += " AND e.deleted_at IS NULL"
+	}
+
 	query := `
-		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.description, e.split_type, e.created_at, e.updated_at,
-		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email
+		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.base_amount, e.base_currency, e.exchange_rate, e.description, e.category, e.split_type, e.is_refund, e.receipt_url, e.expense_date, e.created_at, e.updated_at, e.deleted_at, e.deleted_by,
+		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email, u.is_pending as payer_is_pending
 		FROM expenses e
 		LEFT JOIN users u ON e.paid_by = u.id
-		WHERE e.group_id = ?
-		ORDER BY e.created_at DESC
+		WHERE ` + where + `
+		ORDER BY e.expense_date DESC, e.id DESC
 		LIMIT ? OFFSET ?
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, groupID, limit, offset)
 	if err != nil {
-		r.logger.Error("Failed to get group expenses", zap.Error(err), zap.Int64("groupID", groupID))
+		logging.FromContext(ctx, r.logger).Error("Failed to get group expenses", zap.Error(err), zap.Int64("groupID", groupID))
 		return nil, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -372,14 +519,15 @@ func (r *expenseRepository) GetGroupExpenses(ctx context.Context, groupID int64,
 		expense := &models.Expense{}
 		payer := &models.User{}
 		var payerUUID, payerName, payerEmail sql.NullString
+		var payerIsPending sql.NullBool
 
 		err := rows.Scan(
 			&expense.ID, &expense.UUID, &expense.GroupID, &expense.PaidBy, &expense.Amount,
-			&expense.Currency, &expense.Description, &expense.SplitType, &expense.CreatedAt, &expense.UpdatedAt,
-			&payerUUID, &payerName, &payerEmail,
+			&expense.Currency, &expense.BaseAmount, &expense.BaseCurrency, &expense.ExchangeRate, &expense.Description, &expense.Category, &expense.SplitType, &expense.IsRefund, &expense.ReceiptURL, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt, &expense.DeletedAt, &expense.DeletedBy,
+			&payerUUID, &payerName, &payerEmail, &payerIsPending,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan group expense row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan group expense row", zap.Error(err))
 			return nil, errors.NewDatabaseError(err)
 		}
 
@@ -388,6 +536,7 @@ func (r *expenseRepository) GetGroupExpenses(ctx context.Context, groupID int64,
 			payer.UUID = payerUUID.String
 			payer.Name = payerName.String
 			payer.Email = payerEmail.String
+			payer.IsPending = payerIsPending.Bool
 			expense.Payer = payer
 		}
 
@@ -398,20 +547,28 @@ func (r *expenseRepository) GetGroupExpenses(ctx context.Context, groupID int64,
 }
 
 // GetUserExpenses retrieves expenses paid by a specific user
-func (r *expenseRepository) GetUserExpenses(ctx context.Context, userID int64, offset, limit int) ([]*models.Expense, error) {
+func (r *expenseRepository) GetUserExpenses(ctx context.Context, userID int64, offset, limit int, includeDeleted bool) ([]*models.Expense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	where := "e.paid_by = ?"
+	if !includeDeleted {
+		where += " AND e.deleted_at IS NULL"
+	}
+
 	query := `
-		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.description, e.split_type, e.created_at, e.updated_at,
+		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.base_amount, e.base_currency, e.exchange_rate, e.description, e.category, e.split_type, e.is_refund, e.receipt_url, e.expense_date, e.created_at, e.updated_at, e.deleted_at, e.deleted_by,
 		       g.uuid as group_uuid, g.name as group_name
 		FROM expenses e
-		LEFT JOIN ` + "`groups`" + ` g ON e.group_id = g.id
-		WHERE e.paid_by = ?
-		ORDER BY e.created_at DESC
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON e.group_id = g.id
+		WHERE ` + where + `
+		ORDER BY e.expense_date DESC, e.id DESC
 		LIMIT ? OFFSET ?
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
 	if err != nil {
-		r.logger.Error("Failed to get user expenses", zap.Error(err), zap.Int64("userID", userID))
+		logging.FromContext(ctx, r.logger).Error("Failed to get user expenses", zap.Error(err), zap.Int64("userID", userID))
 		return nil, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -424,11 +581,11 @@ func (r *expenseRepository) GetUserExpenses(ctx context.Context, userID int64, o
 
 		err := rows.Scan(
 			&expense.ID, &expense.UUID, &expense.GroupID, &expense.PaidBy, &expense.Amount,
-			&expense.Currency, &expense.Description, &expense.SplitType, &expense.CreatedAt, &expense.UpdatedAt,
+			&expense.Currency, &expense.BaseAmount, &expense.BaseCurrency, &expense.ExchangeRate, &expense.Description, &expense.Category, &expense.SplitType, &expense.IsRefund, &expense.ReceiptURL, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt, &expense.DeletedAt, &expense.DeletedBy,
 			&groupUUID, &groupName,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan user expense row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan user expense row", zap.Error(err))
 			return nil, errors.NewDatabaseError(err)
 		}
 
@@ -445,30 +602,275 @@ func (r *expenseRepository) GetUserExpenses(ctx context.Context, userID int64, o
 	return expenses, nil
 }
 
-// CreateSplit creates an expense split
-func (r *expenseRepository) CreateSplit(ctx context.Context, tx *database.Tx, split *models.ExpenseSplit) error {
+// GetTopGroupExpenses retrieves a group's largest expenses by amount, with
+// their payer. Zero from/to times leave that end of the date range open.
+func (r *expenseRepository) GetTopGroupExpenses(ctx context.Context, groupID int64, from, to time.Time, limit int, includeDeleted bool) ([]*models.Expense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	whereClause := []string{"e.group_id = ?"}
+	args := []interface{}{groupID}
+
+	if !includeDeleted {
+		whereClause = append(whereClause, "e.deleted_at IS NULL")
+	}
+
+	if !from.IsZero() {
+		whereClause = append(whereClause, "e.expense_date >= ?")
+		args = append(args, from)
+	}
+
+	if !to.IsZero() {
+		whereClause = append(whereClause, "e.expense_date <= ?")
+		args = append(args, to)
+	}
+
 	query := `
-		INSERT INTO expense_splits (expense_id, user_id, amount, percentage, created_at)
-		VALUES (?, ?, ?, ?, NOW())
+		SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.base_amount, e.base_currency, e.exchange_rate, e.description, e.category, e.split_type, e.is_refund, e.receipt_url, e.expense_date, e.created_at, e.updated_at, e.deleted_at, e.deleted_by,
+		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email, u.is_pending as payer_is_pending
+		FROM expenses e
+		LEFT JOIN users u ON e.paid_by = u.id
+		WHERE ` + strings.Join(whereClause, " AND ") + `
+		ORDER BY e.amount DESC, e.id ASC
+		LIMIT ?
 	`
+	args = append(args, limit)
 
-	var result sql.Result
-	var err error
+	expenses, err := r.queryExpenseBatch(ctx, query, args)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get top group expenses", zap.Error(err), zap.Int64("groupID", groupID))
+		return nil, err
+	}
 
-	if tx != nil {
-		result, err = tx.ExecContext(ctx, query, split.ExpenseID, split.UserID, split.Amount, split.Percentage)
-	} else {
-		result, err = r.db.ExecContext(ctx, query, split.ExpenseID, split.UserID, split.Amount, split.Percentage)
+	return expenses, nil
+}
+
+// IterateGroupExpenses walks a group's expenses oldest first, calling fn with
+// batches of at most batchSize. Pages are fetched by keyset on
+// (expense_date, id) so a group of any size is never held in memory at once.
+// Zero from/to times leave that end of the date range open.
+func (r *expenseRepository) IterateGroupExpenses(ctx context.Context, groupID int64, from, to time.Time, batchSize int, fn func([]*models.Expense) error) error {
+	whereClause := []string{"e.group_id = ?", "e.deleted_at IS NULL"}
+	baseArgs := []interface{}{groupID}
+
+	if !from.IsZero() {
+		whereClause = append(whereClause, "e.expense_date >= ?")
+		baseArgs = append(baseArgs, from)
+	}
+
+	if !to.IsZero() {
+		whereClause = append(whereClause, "e.expense_date <= ?")
+		baseArgs = append(baseArgs, to)
+	}
+
+	var lastDate time.Time
+	var lastID int64
+	for {
+		where := whereClause
+		args := append([]interface{}{}, baseArgs...)
+		if lastID > 0 {
+			where = append(where, "(e.expense_date > ? OR (e.expense_date = ? AND e.id > ?))")
+			args = append(args, lastDate, lastDate, lastID)
+		}
+
+		query := `
+			SELECT e.id, e.uuid, e.group_id, e.paid_by, e.amount, e.currency, e.base_amount, e.base_currency, e.exchange_rate, e.description, e.category, e.split_type, e.is_refund, e.receipt_url, e.expense_date, e.created_at, e.updated_at, e.deleted_at, e.deleted_by,
+			       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email, u.is_pending as payer_is_pending
+			FROM expenses e
+			LEFT JOIN users u ON e.paid_by = u.id
+			WHERE ` + strings.Join(where, " AND ") + `
+			ORDER BY e.expense_date ASC, e.id ASC
+			LIMIT ?
+		`
+		args = append(args, batchSize)
+
+		expenses, err := r.queryExpenseBatch(ctx, query, args)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to iterate group expenses", zap.Error(err), zap.Int64("groupID", groupID))
+			return err
+		}
+		if len(expenses) == 0 {
+			return nil
+		}
+
+		if err := fn(expenses); err != nil {
+			return err
+		}
+		if len(expenses) < batchSize {
+			return nil
+		}
+
+		last := expenses[len(expenses)-1]
+		lastDate, lastID = last.ExpenseDate, last.ID
 	}
+}
+
+// queryExpenseBatch runs an expense query joined with the payer and scans
+// every row
+func (r *expenseRepository) queryExpenseBatch(ctx context.Context, query string, args []interface{}) ([]*models.Expense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
 
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		r.logger.Error("Failed to create expense split", zap.Error(err))
-		return errors.NewDatabaseError(err)
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var expenses []*models.Expense
+	for rows.Next() {
+		expense := &models.Expense{}
+		payer := &models.User{}
+		var payerUUID, payerName, payerEmail sql.NullString
+		var payerIsPending sql.NullBool
+
+		err := rows.Scan(
+			&expense.ID, &expense.UUID, &expense.GroupID, &expense.PaidBy, &expense.Amount,
+			&expense.Currency, &expense.BaseAmount, &expense.BaseCurrency, &expense.ExchangeRate, &expense.Description, &expense.Category, &expense.SplitType, &expense.IsRefund, &expense.ReceiptURL, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt, &expense.DeletedAt, &expense.DeletedBy,
+			&payerUUID, &payerName, &payerEmail, &payerIsPending,
+		)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+
+		if payerUUID.Valid {
+			payer.ID = expense.PaidBy
+			payer.UUID = payerUUID.String
+			payer.Name = payerName.String
+			payer.Email = payerEmail.String
+			payer.IsPending = payerIsPending.Bool
+			expense.Payer = payer
+		}
+
+		expenses = append(expenses, expense)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return expenses, nil
+}
+
+// GetGroupSplitUsers retrieves every user that has a split on one of the
+// group's expenses, including users who have since left the group
+func (r *expenseRepository) GetGroupSplitUsers(ctx context.Context, groupID int64) ([]*models.User, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT DISTINCT u.id, u.uuid, u.name, u.email, u.is_pending, u.created_at, u.updated_at
+		FROM users u
+		INNER JOIN expense_splits es ON u.id = es.user_id
+		INNER JOIN expenses e ON es.expense_id = e.id
+		WHERE e.group_id = ? AND e.deleted_at IS NULL AND es.deleted_at IS NULL
+		ORDER BY u.name ASC, u.id ASC
+	`
+
+	users := []*models.User{}
+	err := r.db.SelectContext(ctx, &users, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get group split users", zap.Error(err), zap.Int64("groupID", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return users, nil
+}
+
+// CountGroupExpenses counts the expenses of a group
+func (r *expenseRepository) CountGroupExpenses(ctx context.Context, groupID int64, includeDeleted bool) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM expenses WHERE group_id = ?`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count group expenses", zap.Error(err), zap.Int64("groupID", groupID))
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
+
+// GetGroupCurrencyTotals counts and sums a group's expenses per currency,
+// with the creation time of the latest expense in each
+func (r *expenseRepository) GetGroupCurrencyTotals(ctx context.Context, groupID int64) ([]*models.ExpenseCurrencyTotal, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT currency, COUNT(*) AS expense_count, SUM(amount) AS total_amount, MAX(created_at) AS last_expense_at
+		FROM expenses
+		WHERE group_id = ? AND deleted_at IS NULL
+		GROUP BY currency
+		ORDER BY currency
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to total group expenses", zap.Error(err), zap.Int64("groupID", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	totals := []*models.ExpenseCurrencyTotal{}
+	for rows.Next() {
+		total := &models.ExpenseCurrencyTotal{}
+		if err := rows.Scan(&total.Currency, &total.ExpenseCount, &total.TotalAmount, database.ScanTime(&total.LastExpenseAt)); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan group expense total", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+		totals = append(totals, total)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return totals, nil
+}
+
+// CountUserExpenses counts the expenses paid by a user
+func (r *expenseRepository) CountUserExpenses(ctx context.Context, userID int64, includeDeleted bool) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM expenses WHERE paid_by = ?`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, userID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count user expenses", zap.Error(err), zap.Int64("userID", userID))
+		return 0, errors.NewDatabaseError(err)
 	}
 
-	id, err := result.LastInsertId()
+	return count, nil
+}
+
+// CreateSplit creates an expense split
+func (r *expenseRepository) CreateSplit(ctx context.Context, tx *database.Tx, split *models.ExpenseSplit) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO expense_splits (expense_id, user_id, amount, percentage, shares, created_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`
+
+	id, err := r.db.InsertReturningID(ctx, tx, query, split.ExpenseID, split.UserID, split.Amount, split.Percentage, split.Shares)
 	if err != nil {
-		r.logger.Error("Failed to get last insert ID", zap.Error(err))
+		if dupErr := duplicateKeyError(err, "Expense split", map[string]string{"unique_expense_user": "user_uuid"}); dupErr != nil {
+			return dupErr
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to create expense split", zap.Error(err))
 		return errors.NewDatabaseError(err)
 	}
 
@@ -478,33 +880,73 @@ func (r *expenseRepository) CreateSplit(ctx context.Context, tx *database.Tx, sp
 
 // GetExpenseSplits retrieves all splits for an expense
 func (r *expenseRepository) GetExpenseSplits(ctx context.Context, expenseID int64) ([]*models.ExpenseSplit, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT es.id, es.expense_id, es.user_id, es.amount, es.percentage, es.created_at,
-		       u.uuid, u.name, u.email
+		SELECT es.id, es.expense_id, es.user_id, es.amount, es.percentage, es.shares, es.created_at,
+		       u.uuid, u.name, u.email, u.is_pending
 		FROM expense_splits es
 		LEFT JOIN users u ON es.user_id = u.id
-		WHERE es.expense_id = ?
+		WHERE es.expense_id = ? AND es.deleted_at IS NULL
 		ORDER BY es.created_at ASC
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, expenseID)
 	if err != nil {
-		r.logger.Error("Failed to get expense splits", zap.Error(err), zap.Int64("expenseID", expenseID))
+		logging.FromContext(ctx, r.logger).Error("Failed to get expense splits", zap.Error(err), zap.Int64("expenseID", expenseID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	return r.scanSplits(ctx, rows)
+}
+
+// GetExpenseSplitsForUpdate retrieves the splits of an expense and locks them
+// until the transaction ends
+func (r *expenseRepository) GetExpenseSplitsForUpdate(ctx context.Context, tx *database.Tx, expenseID int64) ([]*models.ExpenseSplit, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT es.id, es.expense_id, es.user_id, es.amount, es.percentage, es.shares, es.created_at,
+		       u.uuid, u.name, u.email, u.is_pending
+		FROM expense_splits es
+		LEFT JOIN users u ON es.user_id = u.id
+		WHERE es.expense_id = ? AND es.deleted_at IS NULL
+		ORDER BY es.created_at ASC
+		` + r.db.ForUpdate("es") + `
+	`
+
+	var rows *sql.Rows
+	var err error
+	if tx != nil {
+		rows, err = tx.QueryContext(ctx, query, expenseID)
+	} else {
+		rows, err = r.db.QueryContext(ctx, query, expenseID)
+	}
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get expense splits for update", zap.Error(err), zap.Int64("expenseID", expenseID))
 		return nil, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
 
+	return r.scanSplits(ctx, rows)
+}
+
+// scanSplits reads the rows of a split query joined with the split's user
+func (r *expenseRepository) scanSplits(ctx context.Context, rows *sql.Rows) ([]*models.ExpenseSplit, error) {
 	var splits []*models.ExpenseSplit
 	for rows.Next() {
 		split := &models.ExpenseSplit{}
 		user := &models.User{}
 
 		err := rows.Scan(
-			&split.ID, &split.ExpenseID, &split.UserID, &split.Amount, &split.Percentage, &split.CreatedAt,
-			&user.UUID, &user.Name, &user.Email,
+			&split.ID, &split.ExpenseID, &split.UserID, &split.Amount, &split.Percentage, &split.Shares, &split.CreatedAt,
+			&user.UUID, &user.Name, &user.Email, &user.IsPending,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan expense split row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan expense split row", zap.Error(err))
 			return nil, errors.NewDatabaseError(err)
 		}
 
@@ -516,32 +958,202 @@ func (r *expenseRepository) GetExpenseSplits(ctx context.Context, expenseID int6
 	return splits, nil
 }
 
+// GetSplitsForExpenses retrieves the splits of several expenses in one query,
+// keyed by expense ID
+func (r *expenseRepository) GetSplitsForExpenses(ctx context.Context, expenseIDs []int64) (map[int64][]*models.ExpenseSplit, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	splitsByExpense := make(map[int64][]*models.ExpenseSplit, len(expenseIDs))
+	if len(expenseIDs) == 0 {
+		return splitsByExpense, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(expenseIDs)), ", ")
+	args := make([]interface{}, len(expenseIDs))
+	for i, id := range expenseIDs {
+		args[i] = id
+	}
+
+	query := `
+		SELECT es.id, es.expense_id, es.user_id, es.amount, es.percentage, es.shares, es.created_at,
+		       u.uuid, u.name, u.email, u.is_pending
+		FROM expense_splits es
+		LEFT JOIN users u ON es.user_id = u.id
+		WHERE es.expense_id IN (` + placeholders + `) AND es.deleted_at IS NULL
+		ORDER BY es.expense_id, es.created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get splits for expenses", zap.Error(err), zap.Int("count", len(expenseIDs)))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		split := &models.ExpenseSplit{}
+		user := &models.User{}
+
+		err := rows.Scan(
+			&split.ID, &split.ExpenseID, &split.UserID, &split.Amount, &split.Percentage, &split.Shares, &split.CreatedAt,
+			&user.UUID, &user.Name, &user.Email, &user.IsPending,
+		)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan expense split row", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+
+		user.ID = split.UserID
+		split.User = user
+		splitsByExpense[split.ExpenseID] = append(splitsByExpense[split.ExpenseID], split)
+	}
+
+	return splitsByExpense, nil
+}
+
 // UpdateSplit updates an expense split
 func (r *expenseRepository) UpdateSplit(ctx context.Context, tx *database.Tx, split *models.ExpenseSplit) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE expense_splits
-		SET amount = ?, percentage = ?
-		WHERE id = ?
+		SET amount = ?, percentage = ?, shares = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	var err error
 	if tx != nil {
-		_, err = tx.ExecContext(ctx, query, split.Amount, split.Percentage, split.ID)
+		_, err = tx.ExecContext(ctx, query, split.Amount, split.Percentage, split.Shares, split.ID)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, split.Amount, split.Percentage, split.Shares, split.ID)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update expense split", zap.Error(err), zap.Int64("id", split.ID))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// DeleteExpenseSplits marks the live splits of an expense as deleted by
+// deletedBy, which may be nil. Replaced splits are kept for history.
+func (r *expenseRepository) DeleteExpenseSplits(ctx context.Context, tx *database.Tx, expenseID int64, deletedBy *int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE expense_splits SET deleted_at = CURRENT_TIMESTAMP, deleted_by = ? WHERE expense_id = ? AND deleted_at IS NULL`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, deletedBy, expenseID)
 	} else {
-		_, err = r.db.ExecContext(ctx, query, split.Amount, split.Percentage, split.ID)
+		_, err = r.db.ExecContext(ctx, query, deletedBy, expenseID)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete expense splits", zap.Error(err), zap.Int64("expenseID", expenseID))
+		return errors.NewDatabaseError(err)
 	}
 
+	return nil
+}
+
+// CreateItem creates a line item together with its user shares
+func (r *expenseRepository) CreateItem(ctx context.Context, tx *database.Tx, item *models.ExpenseItem) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO expense_items (expense_id, position, description, amount, created_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`
+
+	id, err := r.db.InsertReturningID(ctx, tx, query, item.ExpenseID, item.Position, item.Description, item.Amount)
 	if err != nil {
-		r.logger.Error("Failed to update expense split", zap.Error(err), zap.Int64("id", split.ID))
+		logging.FromContext(ctx, r.logger).Error("Failed to create expense item", zap.Error(err))
 		return errors.NewDatabaseError(err)
 	}
 
+	item.ID = id
+
+	shareQuery := `INSERT INTO expense_item_users (item_id, user_id, amount) VALUES (?, ?, ?)`
+	for _, share := range item.Shares {
+		share.ItemID = item.ID
+		share.ID, err = r.db.InsertReturningID(ctx, tx, shareQuery, share.ItemID, share.UserID, share.Amount)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to create expense item share", zap.Error(err), zap.Int64("itemID", item.ID))
+			return errors.NewDatabaseError(err)
+		}
+	}
+
 	return nil
 }
 
-// DeleteExpenseSplits deletes all splits for an expense
-func (r *expenseRepository) DeleteExpenseSplits(ctx context.Context, tx *database.Tx, expenseID int64) error {
-	query := `DELETE FROM expense_splits WHERE expense_id = ?`
+// GetExpenseItems retrieves the line items of an expense in receipt order,
+// each with its user shares
+func (r *expenseRepository) GetExpenseItems(ctx context.Context, expenseID int64) ([]*models.ExpenseItem, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT i.id, i.expense_id, i.position, i.description, i.amount, i.created_at,
+		       iu.id, iu.user_id, iu.amount,
+		       u.uuid, u.name, u.email, u.is_pending
+		FROM expense_items i
+		INNER JOIN expense_item_users iu ON iu.item_id = i.id
+		LEFT JOIN users u ON iu.user_id = u.id
+		WHERE i.expense_id = ?
+		ORDER BY i.position ASC, iu.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, expenseID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get expense items", zap.Error(err), zap.Int64("expenseID", expenseID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var items []*models.ExpenseItem
+	for rows.Next() {
+		item := &models.ExpenseItem{}
+		share := &models.ExpenseItemShare{}
+		user := &models.User{}
+
+		err := rows.Scan(
+			&item.ID, &item.ExpenseID, &item.Position, &item.Description, &item.Amount, &item.CreatedAt,
+			&share.ID, &share.UserID, &share.Amount,
+			&user.UUID, &user.Name, &user.Email, &user.IsPending,
+		)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan expense item row", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+
+		// Rows arrive grouped by item; start a new one when the item changes
+		if len(items) == 0 || items[len(items)-1].ID != item.ID {
+			items = append(items, item)
+		}
+		current := items[len(items)-1]
+
+		user.ID = share.UserID
+		share.ItemID = current.ID
+		share.User = user
+		current.Shares = append(current.Shares, share)
+	}
+
+	return items, nil
+}
+
+// DeleteExpenseItems deletes the line items of an expense; their shares are
+// removed by the foreign key cascade
+func (r *expenseRepository) DeleteExpenseItems(ctx context.Context, tx *database.Tx, expenseID int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `DELETE FROM expense_items WHERE expense_id = ?`
 
 	var err error
 	if tx != nil {
@@ -551,7 +1163,7 @@ func (r *expenseRepository) DeleteExpenseSplits(ctx context.Context, tx *databas
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to delete expense splits", zap.Error(err), zap.Int64("expenseID", expenseID))
+		logging.FromContext(ctx, r.logger).Error("Failed to delete expense items", zap.Error(err), zap.Int64("expenseID", expenseID))
 		return errors.NewDatabaseError(err)
 	}
 
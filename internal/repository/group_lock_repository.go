@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+type groupLockRepository struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewGroupLockRepository creates a new group lock repository
+func NewGroupLockRepository(db *database.DB, logger *zap.Logger) GroupLockRepository {
+	return &groupLockRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetForUpdate retrieves the lock row for a group, expired or not, and locks it
+// for the rest of the transaction. Returns nil if the group has never been locked.
+func (r *groupLockRepository) GetForUpdate(ctx context.Context, tx *database.Tx, groupID int64) (*models.GroupLock, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT group_id, holder, purpose, expires_at, created_at
+		FROM group_locks
+		WHERE group_id = ?
+		` + r.db.ForUpdate() + `
+	`
+
+	lock := &models.GroupLock{}
+
+	var err error
+	if tx != nil {
+		err = tx.GetContext(ctx, lock, query, groupID)
+	} else {
+		err = r.db.GetContext(ctx, lock, query, groupID)
+	}
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get group lock for update", zap.Error(err), zap.Int64("group_id", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return lock, nil
+}
+
+// GetActive retrieves the unexpired lock for a group, or nil if the group is not locked
+func (r *groupLockRepository) GetActive(ctx context.Context, tx *database.Tx, groupID int64) (*models.GroupLock, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT group_id, holder, purpose, expires_at, created_at
+		FROM group_locks
+		WHERE group_id = ? AND expires_at > ?
+	`
+
+	lock := &models.GroupLock{}
+	now := time.Now()
+
+	var err error
+	if tx != nil {
+		err = tx.GetContext(ctx, lock, query, groupID, now)
+	} else {
+		err = r.db.GetContext(ctx, lock, query, groupID, now)
+	}
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get active group lock", zap.Error(err), zap.Int64("group_id", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return lock, nil
+}
+
+// Upsert creates the lock row for a group or replaces an expired one
+func (r *groupLockRepository) Upsert(ctx context.Context, tx *database.Tx, lock *models.GroupLock) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO group_locks (group_id, holder, purpose, expires_at, created_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		` + r.db.OnConflictUpdate([]string{"group_id"},
+		"holder = "+r.db.Excluded("holder")+", purpose = "+r.db.Excluded("purpose")+
+			", expires_at = "+r.db.Excluded("expires_at")+", created_at = CURRENT_TIMESTAMP")
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, lock.GroupID, lock.Holder, lock.Purpose, lock.ExpiresAt)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, lock.GroupID, lock.Holder, lock.Purpose, lock.ExpiresAt)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to upsert group lock", zap.Error(err), zap.Int64("group_id", lock.GroupID))
+		return errors.NewDatabaseError(err)
+	}
+
+	logging.FromContext(ctx, r.logger).Debug("Group lock acquired", zap.Int64("group_id", lock.GroupID), zap.String("holder", lock.Holder))
+	return nil
+}
+
+// Delete removes a group lock, but only if it is still held by the given holder
+func (r *groupLockRepository) Delete(ctx context.Context, tx *database.Tx, groupID int64, holder string) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `DELETE FROM group_locks WHERE group_id = ? AND holder = ?`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, groupID, holder)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, groupID, holder)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete group lock", zap.Error(err), zap.Int64("group_id", groupID))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"strings"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/pkg/errors"
 
@@ -26,42 +28,37 @@ func NewGroupRepository(db *database.DB, logger *zap.Logger) GroupRepository {
 
 // Create creates a new group
 func (r *groupRepository) Create(ctx context.Context, tx *database.Tx, group *models.Group) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		INSERT INTO ` + "`groups`" + ` (uuid, name, description, created_by, created_at, updated_at)
-		VALUES (?, ?, ?, ?, NOW(), NOW())
+		INSERT INTO ` + r.db.Quote("groups") + ` (uuid, name, description, timezone, default_currency, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	`
 
-	var result sql.Result
-	var err error
-
-	if tx != nil {
-		result, err = tx.ExecContext(ctx, query, group.UUID, group.Name, group.Description, group.CreatedBy)
-	} else {
-		result, err = r.db.ExecContext(ctx, query, group.UUID, group.Name, group.Description, group.CreatedBy)
-	}
-
-	if err != nil {
-		r.logger.Error("Failed to create group", zap.Error(err), zap.String("name", group.Name))
-		return errors.NewDatabaseError(err)
-	}
-
-	id, err := result.LastInsertId()
+	id, err := r.db.InsertReturningID(ctx, tx, query, group.UUID, group.Name, group.Description, group.Timezone, group.DefaultCurrency, group.CreatedBy)
 	if err != nil {
-		r.logger.Error("Failed to get last insert ID", zap.Error(err))
+		if dupErr := duplicateKeyError(err, "Group", map[string]string{"uuid": "uuid"}); dupErr != nil {
+			return dupErr
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to create group", zap.Error(err), zap.String("name", group.Name))
 		return errors.NewDatabaseError(err)
 	}
 
 	group.ID = id
-	r.logger.Info("Group created successfully", zap.Int64("id", group.ID), zap.String("name", group.Name))
+	logging.FromContext(ctx, r.logger).Info("Group created successfully", zap.Int64("id", group.ID), zap.String("name", group.Name))
 	return nil
 }
 
 // GetByID retrieves a group by ID
 func (r *groupRepository) GetByID(ctx context.Context, id int64) (*models.Group, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT g.id, g.uuid, g.name, g.description, g.created_by, g.created_at, g.updated_at,
-		       u.uuid as creator_uuid, u.name as creator_name, u.email as creator_email
-		FROM ` + "`groups`" + ` g
+		SELECT g.id, g.uuid, g.name, g.description, g.timezone, g.default_currency, g.created_by, g.created_at, g.updated_at, g.archived_at,
+		       u.uuid as creator_uuid, u.name as creator_name, u.email as creator_email, u.is_pending as creator_is_pending
+		FROM ` + r.db.Quote("groups") + ` g
 		LEFT JOIN users u ON g.created_by = u.id
 		WHERE g.id = ?
 	`
@@ -71,18 +68,19 @@ func (r *groupRepository) GetByID(ctx context.Context, id int64) (*models.Group,
 	group := &models.Group{}
 	creator := &models.User{}
 	var creatorUUID, creatorName, creatorEmail sql.NullString
+	var creatorIsPending sql.NullBool
 
 	err := row.Scan(
-		&group.ID, &group.UUID, &group.Name, &group.Description, &group.CreatedBy,
-		&group.CreatedAt, &group.UpdatedAt,
-		&creatorUUID, &creatorName, &creatorEmail,
+		&group.ID, &group.UUID, &group.Name, &group.Description, &group.Timezone, &group.DefaultCurrency, &group.CreatedBy,
+		&group.CreatedAt, &group.UpdatedAt, &group.ArchivedAt,
+		&creatorUUID, &creatorName, &creatorEmail, &creatorIsPending,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.NewNotFoundError("Group")
 		}
-		r.logger.Error("Failed to get group by ID", zap.Error(err), zap.Int64("id", id))
+		logging.FromContext(ctx, r.logger).Error("Failed to get group by ID", zap.Error(err), zap.Int64("id", id))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -91,6 +89,7 @@ func (r *groupRepository) GetByID(ctx context.Context, id int64) (*models.Group,
 		creator.UUID = creatorUUID.String
 		creator.Name = creatorName.String
 		creator.Email = creatorEmail.String
+		creator.IsPending = creatorIsPending.Bool
 		group.Creator = creator
 	}
 
@@ -99,10 +98,13 @@ func (r *groupRepository) GetByID(ctx context.Context, id int64) (*models.Group,
 
 // GetByUUID retrieves a group by UUID
 func (r *groupRepository) GetByUUID(ctx context.Context, uuid string) (*models.Group, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT g.id, g.uuid, g.name, g.description, g.created_by, g.created_at, g.updated_at,
-		       u.uuid as creator_uuid, u.name as creator_name, u.email as creator_email
-		FROM ` + "`groups`" + ` g
+		SELECT g.id, g.uuid, g.name, g.description, g.timezone, g.default_currency, g.created_by, g.created_at, g.updated_at, g.archived_at,
+		       u.uuid as creator_uuid, u.name as creator_name, u.email as creator_email, u.is_pending as creator_is_pending
+		FROM ` + r.db.Quote("groups") + ` g
 		LEFT JOIN users u ON g.created_by = u.id
 		WHERE g.uuid = ?
 	`
@@ -112,18 +114,19 @@ func (r *groupRepository) GetByUUID(ctx context.Context, uuid string) (*models.G
 	group := &models.Group{}
 	creator := &models.User{}
 	var creatorUUID, creatorName, creatorEmail sql.NullString
+	var creatorIsPending sql.NullBool
 
 	err := row.Scan(
-		&group.ID, &group.UUID, &group.Name, &group.Description, &group.CreatedBy,
-		&group.CreatedAt, &group.UpdatedAt,
-		&creatorUUID, &creatorName, &creatorEmail,
+		&group.ID, &group.UUID, &group.Name, &group.Description, &group.Timezone, &group.DefaultCurrency, &group.CreatedBy,
+		&group.CreatedAt, &group.UpdatedAt, &group.ArchivedAt,
+		&creatorUUID, &creatorName, &creatorEmail, &creatorIsPending,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.NewNotFoundError("Group")
 		}
-		r.logger.Error("Failed to get group by UUID", zap.Error(err), zap.String("uuid", uuid))
+		logging.FromContext(ctx, r.logger).Error("Failed to get group by UUID", zap.Error(err), zap.String("uuid", uuid))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -132,6 +135,7 @@ func (r *groupRepository) GetByUUID(ctx context.Context, uuid string) (*models.G
 		creator.UUID = creatorUUID.String
 		creator.Name = creatorName.String
 		creator.Email = creatorEmail.String
+		creator.IsPending = creatorIsPending.Bool
 		group.Creator = creator
 	}
 
@@ -140,31 +144,106 @@ func (r *groupRepository) GetByUUID(ctx context.Context, uuid string) (*models.G
 
 // Update updates a group
 func (r *groupRepository) Update(ctx context.Context, tx *database.Tx, group *models.Group) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		UPDATE ` + "`groups`" + `
-		SET name = ?, description = ?, updated_at = NOW()
+		UPDATE ` + r.db.Quote("groups") + `
+		SET name = ?, description = ?, timezone = ?, default_currency = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 
 	var err error
 	if tx != nil {
-		_, err = tx.ExecContext(ctx, query, group.Name, group.Description, group.ID)
+		_, err = tx.ExecContext(ctx, query, group.Name, group.Description, group.Timezone, group.DefaultCurrency, group.ID)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, group.Name, group.Description, group.Timezone, group.DefaultCurrency, group.ID)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update group", zap.Error(err), zap.Int64("id", group.ID))
+		return errors.NewDatabaseError(err)
+	}
+
+	logging.FromContext(ctx, r.logger).Info("Group updated successfully", zap.Int64("id", group.ID))
+	return nil
+}
+
+// SetOwner records userID as the group's owner (created_by)
+func (r *groupRepository) SetOwner(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE ` + r.db.Quote("groups") + ` SET created_by = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, userID, groupID)
 	} else {
-		_, err = r.db.ExecContext(ctx, query, group.Name, group.Description, group.ID)
+		_, err = r.db.ExecContext(ctx, query, userID, groupID)
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to update group", zap.Error(err), zap.Int64("id", group.ID))
+		logging.FromContext(ctx, r.logger).Error("Failed to set group owner", zap.Error(err),
+			zap.Int64("groupID", groupID), zap.Int64("userID", userID))
 		return errors.NewDatabaseError(err)
 	}
 
-	r.logger.Info("Group updated successfully", zap.Int64("id", group.ID))
 	return nil
 }
 
+// Archive marks a group as archived. It reports false without changing
+// anything if the group is already archived.
+func (r *groupRepository) Archive(ctx context.Context, tx *database.Tx, id int64) (bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE ` + r.db.Quote("groups") + ` SET archived_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND archived_at IS NULL`
+	return r.execArchive(ctx, tx, query, id)
+}
+
+// Unarchive clears a group's archived mark. It reports false without changing
+// anything if the group is not archived.
+func (r *groupRepository) Unarchive(ctx context.Context, tx *database.Tx, id int64) (bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE ` + r.db.Quote("groups") + ` SET archived_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND archived_at IS NOT NULL`
+	return r.execArchive(ctx, tx, query, id)
+}
+
+func (r *groupRepository) execArchive(ctx context.Context, tx *database.Tx, query string, id int64) (bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, query, id)
+	} else {
+		result, err = r.db.ExecContext(ctx, query, id)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to change group archive state", zap.Error(err), zap.Int64("id", id))
+		return false, errors.NewDatabaseError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get affected rows", zap.Error(err))
+		return false, errors.NewDatabaseError(err)
+	}
+
+	return affected > 0, nil
+}
+
 // Delete deletes a group
 func (r *groupRepository) Delete(ctx context.Context, tx *database.Tx, id int64) error {
-	query := `DELETE FROM ` + "`groups`" + ` WHERE id = ?`
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `DELETE FROM ` + r.db.Quote("groups") + ` WHERE id = ?`
 
 	var result sql.Result
 	var err error
@@ -176,13 +255,13 @@ func (r *groupRepository) Delete(ctx context.Context, tx *database.Tx, id int64)
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to delete group", zap.Error(err), zap.Int64("id", id))
+		logging.FromContext(ctx, r.logger).Error("Failed to delete group", zap.Error(err), zap.Int64("id", id))
 		return errors.NewDatabaseError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get rows affected", zap.Error(err))
 		return errors.NewDatabaseError(err)
 	}
 
@@ -190,24 +269,29 @@ func (r *groupRepository) Delete(ctx context.Context, tx *database.Tx, id int64)
 		return errors.NewNotFoundError("Group")
 	}
 
-	r.logger.Info("Group deleted successfully", zap.Int64("id", id))
+	logging.FromContext(ctx, r.logger).Info("Group deleted successfully", zap.Int64("id", id))
 	return nil
 }
 
-// List retrieves a list of groups with pagination
-func (r *groupRepository) List(ctx context.Context, offset, limit int) ([]*models.Group, error) {
+// List retrieves a list of groups with pagination, leaving out archived groups
+// unless includeArchived is set
+func (r *groupRepository) List(ctx context.Context, offset, limit int, includeArchived bool) ([]*models.Group, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT g.id, g.uuid, g.name, g.description, g.created_by, g.created_at, g.updated_at,
-		       u.uuid as creator_uuid, u.name as creator_name, u.email as creator_email
-		FROM ` + "`groups`" + ` g
+		SELECT g.id, g.uuid, g.name, g.description, g.timezone, g.default_currency, g.created_by, g.created_at, g.updated_at, g.archived_at,
+		       u.uuid as creator_uuid, u.name as creator_name, u.email as creator_email, u.is_pending as creator_is_pending
+		FROM ` + r.db.Quote("groups") + ` g
 		LEFT JOIN users u ON g.created_by = u.id
+		WHERE ? OR g.archived_at IS NULL
 		ORDER BY g.created_at DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, includeArchived, limit, offset)
 	if err != nil {
-		r.logger.Error("Failed to list groups", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to list groups", zap.Error(err))
 		return nil, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -217,14 +301,15 @@ func (r *groupRepository) List(ctx context.Context, offset, limit int) ([]*model
 		group := &models.Group{}
 		creator := &models.User{}
 		var creatorUUID, creatorName, creatorEmail sql.NullString
+		var creatorIsPending sql.NullBool
 
 		err := rows.Scan(
-			&group.ID, &group.UUID, &group.Name, &group.Description, &group.CreatedBy,
-			&group.CreatedAt, &group.UpdatedAt,
-			&creatorUUID, &creatorName, &creatorEmail,
+			&group.ID, &group.UUID, &group.Name, &group.Description, &group.Timezone, &group.DefaultCurrency, &group.CreatedBy,
+			&group.CreatedAt, &group.UpdatedAt, &group.ArchivedAt,
+			&creatorUUID, &creatorName, &creatorEmail, &creatorIsPending,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan group row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan group row", zap.Error(err))
 			return nil, errors.NewDatabaseError(err)
 		}
 
@@ -233,6 +318,7 @@ func (r *groupRepository) List(ctx context.Context, offset, limit int) ([]*model
 			creator.UUID = creatorUUID.String
 			creator.Name = creatorName.String
 			creator.Email = creatorEmail.String
+			creator.IsPending = creatorIsPending.Bool
 			group.Creator = creator
 		}
 
@@ -242,22 +328,26 @@ func (r *groupRepository) List(ctx context.Context, offset, limit int) ([]*model
 	return groups, nil
 }
 
-// GetUserGroups retrieves groups that a user is a member of
-func (r *groupRepository) GetUserGroups(ctx context.Context, userID int64, offset, limit int) ([]*models.Group, error) {
+// GetUserGroups retrieves groups that a user is a member of, leaving out
+// archived groups unless includeArchived is set
+func (r *groupRepository) GetUserGroups(ctx context.Context, userID int64, offset, limit int, includeArchived bool) ([]*models.Group, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT g.id, g.uuid, g.name, g.description, g.created_by, g.created_at, g.updated_at,
-		       u.uuid as creator_uuid, u.name as creator_name, u.email as creator_email
-		FROM ` + "`groups`" + ` g
+		SELECT g.id, g.uuid, g.name, g.description, g.timezone, g.default_currency, g.created_by, g.created_at, g.updated_at, g.archived_at,
+		       u.uuid as creator_uuid, u.name as creator_name, u.email as creator_email, u.is_pending as creator_is_pending
+		FROM ` + r.db.Quote("groups") + ` g
 		LEFT JOIN users u ON g.created_by = u.id
 		INNER JOIN group_members gm ON g.id = gm.group_id
-		WHERE gm.user_id = ?
+		WHERE gm.user_id = ? AND (? OR g.archived_at IS NULL)
 		ORDER BY g.created_at DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, userID, includeArchived, limit, offset)
 	if err != nil {
-		r.logger.Error("Failed to get user groups", zap.Error(err), zap.Int64("userID", userID))
+		logging.FromContext(ctx, r.logger).Error("Failed to get user groups", zap.Error(err), zap.Int64("userID", userID))
 		return nil, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -267,14 +357,15 @@ func (r *groupRepository) GetUserGroups(ctx context.Context, userID int64, offse
 		group := &models.Group{}
 		creator := &models.User{}
 		var creatorUUID, creatorName, creatorEmail sql.NullString
+		var creatorIsPending sql.NullBool
 
 		err := rows.Scan(
-			&group.ID, &group.UUID, &group.Name, &group.Description, &group.CreatedBy,
-			&group.CreatedAt, &group.UpdatedAt,
-			&creatorUUID, &creatorName, &creatorEmail,
+			&group.ID, &group.UUID, &group.Name, &group.Description, &group.Timezone, &group.DefaultCurrency, &group.CreatedBy,
+			&group.CreatedAt, &group.UpdatedAt, &group.ArchivedAt,
+			&creatorUUID, &creatorName, &creatorEmail, &creatorIsPending,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan user group row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan user group row", zap.Error(err))
 			return nil, errors.NewDatabaseError(err)
 		}
 
@@ -283,6 +374,7 @@ func (r *groupRepository) GetUserGroups(ctx context.Context, userID int64, offse
 			creator.UUID = creatorUUID.String
 			creator.Name = creatorName.String
 			creator.Email = creatorEmail.String
+			creator.IsPending = creatorIsPending.Bool
 			group.Creator = creator
 		}
 
@@ -292,34 +384,118 @@ func (r *groupRepository) GetUserGroups(ctx context.Context, userID int64, offse
 	return groups, nil
 }
 
-// AddMember adds a user to a group
-func (r *groupRepository) AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
+// GetSharedGroups retrieves the groups both users are members of, archived
+// ones included, oldest first
+func (r *groupRepository) GetSharedGroups(ctx context.Context, userID, otherUserID int64) ([]*models.Group, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT g.id, g.uuid, g.name, g.description, g.timezone, g.default_currency, g.created_by, g.created_at, g.updated_at, g.archived_at
+		FROM ` + r.db.Quote("groups") + ` g
+		INNER JOIN group_members a ON a.group_id = g.id AND a.user_id = ?
+		INNER JOIN group_members b ON b.group_id = g.id AND b.user_id = ?
+		ORDER BY g.created_at, g.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, otherUserID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get shared groups", zap.Error(err),
+			zap.Int64("userID", userID), zap.Int64("otherUserID", otherUserID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var groups []*models.Group
+	for rows.Next() {
+		group := &models.Group{}
+		err := rows.Scan(
+			&group.ID, &group.UUID, &group.Name, &group.Description, &group.Timezone, &group.DefaultCurrency, &group.CreatedBy,
+			&group.CreatedAt, &group.UpdatedAt, &group.ArchivedAt,
+		)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan shared group row", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// Count counts all groups, leaving out archived groups unless includeArchived is set
+func (r *groupRepository) Count(ctx context.Context, includeArchived bool) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM ` + r.db.Quote("groups") + ` g WHERE ? OR g.archived_at IS NULL`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, includeArchived)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count groups", zap.Error(err))
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
+
+// CountUserGroups counts the groups a user is a member of, leaving out archived
+// groups unless includeArchived is set
+func (r *groupRepository) CountUserGroups(ctx context.Context, userID int64, includeArchived bool) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		INSERT INTO group_members (group_id, user_id, joined_at)
-		VALUES (?, ?, NOW())
-		ON DUPLICATE KEY UPDATE joined_at = joined_at
+		SELECT COUNT(*)
+		FROM group_members gm
+		INNER JOIN ` + r.db.Quote("groups") + ` g ON g.id = gm.group_id
+		WHERE gm.user_id = ? AND (? OR g.archived_at IS NULL)
 	`
 
+	var count int
+	err := r.db.GetContext(ctx, &count, query, userID, includeArchived)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count user groups", zap.Error(err), zap.Int64("userID", userID))
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
+
+// AddMember adds a user to a group
+func (r *groupRepository) AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO group_members (group_id, user_id, role, joined_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		` + r.db.OnConflictUpdate([]string{"group_id", "user_id"}, "joined_at = group_members.joined_at")
+
 	var err error
 	if tx != nil {
-		_, err = tx.ExecContext(ctx, query, groupID, userID)
+		_, err = tx.ExecContext(ctx, query, groupID, userID, role)
 	} else {
-		_, err = r.db.ExecContext(ctx, query, groupID, userID)
+		_, err = r.db.ExecContext(ctx, query, groupID, userID, role)
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to add member to group", zap.Error(err),
+		logging.FromContext(ctx, r.logger).Error("Failed to add member to group", zap.Error(err),
 			zap.Int64("groupID", groupID), zap.Int64("userID", userID))
 		return errors.NewDatabaseError(err)
 	}
 
-	r.logger.Info("Member added to group successfully",
+	logging.FromContext(ctx, r.logger).Info("Member added to group successfully",
 		zap.Int64("groupID", groupID), zap.Int64("userID", userID))
 	return nil
 }
 
 // RemoveMember removes a user from a group
 func (r *groupRepository) RemoveMember(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `DELETE FROM group_members WHERE group_id = ? AND user_id = ?`
 
 	var result sql.Result
@@ -332,14 +508,14 @@ func (r *groupRepository) RemoveMember(ctx context.Context, tx *database.Tx, gro
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to remove member from group", zap.Error(err),
+		logging.FromContext(ctx, r.logger).Error("Failed to remove member from group", zap.Error(err),
 			zap.Int64("groupID", groupID), zap.Int64("userID", userID))
 		return errors.NewDatabaseError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get rows affected", zap.Error(err))
 		return errors.NewDatabaseError(err)
 	}
 
@@ -347,15 +523,18 @@ func (r *groupRepository) RemoveMember(ctx context.Context, tx *database.Tx, gro
 		return errors.NewNotFoundError("Group membership")
 	}
 
-	r.logger.Info("Member removed from group successfully",
+	logging.FromContext(ctx, r.logger).Info("Member removed from group successfully",
 		zap.Int64("groupID", groupID), zap.Int64("userID", userID))
 	return nil
 }
 
 // GetMembers retrieves all members of a group
 func (r *groupRepository) GetMembers(ctx context.Context, groupID int64) ([]*models.User, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT u.id, u.uuid, u.name, u.email, u.created_at, u.updated_at
+		SELECT u.id, u.uuid, u.name, u.email, u.is_pending, u.created_at, u.updated_at, gm.role
 		FROM users u
 		INNER JOIN group_members gm ON u.id = gm.user_id
 		WHERE gm.group_id = ?
@@ -365,24 +544,199 @@ func (r *groupRepository) GetMembers(ctx context.Context, groupID int64) ([]*mod
 	users := []*models.User{}
 	err := r.db.SelectContext(ctx, &users, query, groupID)
 	if err != nil {
-		r.logger.Error("Failed to get group members", zap.Error(err), zap.Int64("groupID", groupID))
+		logging.FromContext(ctx, r.logger).Error("Failed to get group members", zap.Error(err), zap.Int64("groupID", groupID))
 		return nil, errors.NewDatabaseError(err)
 	}
 
 	return users, nil
 }
 
+// GetMemberships retrieves the memberships of a group with their users,
+// oldest first
+func (r *groupRepository) GetMemberships(ctx context.Context, groupID int64) ([]*models.GroupMember, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT gm.id, gm.group_id, gm.user_id, gm.role, gm.joined_at,
+		       u.uuid, u.name, u.email, u.is_pending, u.created_at, u.updated_at
+		FROM group_members gm
+		INNER JOIN users u ON u.id = gm.user_id
+		WHERE gm.group_id = ?
+		ORDER BY gm.joined_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get group memberships", zap.Error(err), zap.Int64("groupID", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	members := []*models.GroupMember{}
+	for rows.Next() {
+		member := &models.GroupMember{}
+		user := &models.User{}
+		err := rows.Scan(
+			&member.ID, &member.GroupID, &member.UserID, &member.Role, &member.JoinedAt,
+			&user.UUID, &user.Name, &user.Email, &user.IsPending, &user.CreatedAt, &user.UpdatedAt,
+		)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan group membership", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+		user.ID = member.UserID
+		member.User = user
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to iterate group memberships", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return members, nil
+}
+
 // IsMember checks if a user is a member of a group
 func (r *groupRepository) IsMember(ctx context.Context, groupID, userID int64) (bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `SELECT COUNT(*) FROM group_members WHERE group_id = ? AND user_id = ?`
 
 	var count int
 	err := r.db.GetContext(ctx, &count, query, groupID, userID)
 	if err != nil {
-		r.logger.Error("Failed to check group membership", zap.Error(err),
+		logging.FromContext(ctx, r.logger).Error("Failed to check group membership", zap.Error(err),
 			zap.Int64("groupID", groupID), zap.Int64("userID", userID))
 		return false, errors.NewDatabaseError(err)
 	}
 
 	return count > 0, nil
 }
+
+// AreMembers reports for each of userIDs whether the user is a member of the
+// group, using one query
+func (r *groupRepository) AreMembers(ctx context.Context, groupID int64, userIDs []int64) (map[int64]bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	members := make(map[int64]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return members, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(userIDs)), ", ")
+	args := make([]interface{}, 0, len(userIDs)+1)
+	args = append(args, groupID)
+	for _, id := range userIDs {
+		members[id] = false
+		args = append(args, id)
+	}
+
+	query := `SELECT user_id FROM group_members WHERE group_id = ? AND user_id IN (` + placeholders + `)`
+
+	var found []int64
+	err := r.db.SelectContext(ctx, &found, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to check group memberships", zap.Error(err),
+			zap.Int64("groupID", groupID), zap.Int("count", len(userIDs)))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	for _, id := range found {
+		members[id] = true
+	}
+
+	return members, nil
+}
+
+// GetMemberRole returns a member's role in a group
+func (r *groupRepository) GetMemberRole(ctx context.Context, groupID, userID int64) (models.GroupRole, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `SELECT role FROM group_members WHERE group_id = ? AND user_id = ?`
+
+	var role models.GroupRole
+	err := r.db.GetContext(ctx, &role, query, groupID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.NewNotFoundError("Group membership")
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get member role", zap.Error(err),
+			zap.Int64("groupID", groupID), zap.Int64("userID", userID))
+		return "", errors.NewDatabaseError(err)
+	}
+
+	return role, nil
+}
+
+// SetMemberRole changes a member's role in a group
+func (r *groupRepository) SetMemberRole(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE group_members SET role = ? WHERE group_id = ? AND user_id = ?`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, role, groupID, userID)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, role, groupID, userID)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to set member role", zap.Error(err),
+			zap.Int64("groupID", groupID), zap.Int64("userID", userID))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// CountAdminsForUpdate counts a group's admins, locking their membership rows
+// so two admins cannot step down at the same time and leave the group without one
+func (r *groupRepository) CountAdminsForUpdate(ctx context.Context, tx *database.Tx, groupID int64) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COUNT(*) FROM (
+			SELECT user_id FROM group_members WHERE group_id = ? AND role = 'admin' ` + r.db.ForUpdate() + `
+		) admins
+	`
+
+	var count int
+	var err error
+	if tx != nil {
+		err = tx.GetContext(ctx, &count, query, groupID)
+	} else {
+		err = r.db.GetContext(ctx, &count, query, groupID)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count group admins", zap.Error(err), zap.Int64("groupID", groupID))
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
+
+// CountMembers counts the members of a group
+func (r *groupRepository) CountMembers(ctx context.Context, groupID int64) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM group_members WHERE group_id = ?`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count group members", zap.Error(err), zap.Int64("groupID", groupID))
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
 	"expense-split-tracker/pkg/errors"
 
 	"go.uber.org/zap"
@@ -24,48 +25,97 @@ func NewIdempotencyRepository(db *database.DB, logger *zap.Logger) IdempotencyRe
 	}
 }
 
-// Create creates a new idempotency record
-func (r *idempotencyRepository) Create(ctx context.Context, tx *database.Tx, key, requestHash string, responseData []byte, statusCode int, expiresAt int64) error {
+// Reserve claims key for a request about to run by inserting a processing
+// record. It returns false when another live record already holds the key;
+// the unique constraint on key_value makes this safe under concurrency.
+func (r *idempotencyRepository) Reserve(ctx context.Context, caller, key, requestHash string, expiresAt int64) (bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	now := time.Now().Unix()
+
+	// An expired record still occupies the key until cleanup runs
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE caller = ? AND key_value = ? AND expires_at <= ?`, caller, key, now); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to clear expired idempotency record", zap.Error(err), zap.String("key", key))
+		return false, errors.NewDatabaseError(err)
+	}
+
 	query := `
-		INSERT INTO idempotency_keys (key_value, request_hash, response_data, status_code, created_at, expires_at)
+		INSERT INTO idempotency_keys (caller, key_value, request_hash, status, created_at, expires_at)
 		VALUES (?, ?, ?, ?, ?, ?)
 	`
 
-	createdAt := time.Now().Unix()
+	_, err := r.db.ExecContext(ctx, query, caller, key, requestHash, IdempotencyStatusProcessing, now, expiresAt)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return false, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to reserve idempotency key", zap.Error(err), zap.String("key", key))
+		return false, errors.NewDatabaseError(err)
+	}
 
-	var err error
-	if tx != nil {
-		_, err = tx.ExecContext(ctx, query, key, requestHash, responseData, statusCode, createdAt, expiresAt)
-	} else {
-		_, err = r.db.ExecContext(ctx, query, key, requestHash, responseData, statusCode, createdAt, expiresAt)
+	logging.FromContext(ctx, r.logger).Debug("Idempotency key reserved", zap.String("key", key))
+	return true, nil
+}
+
+// Complete stores the response body and headers for a reserved key so
+// retries replay it
+func (r *idempotencyRepository) Complete(ctx context.Context, caller, key string, responseData, responseHeaders []byte, statusCode int) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE idempotency_keys
+		SET status = ?, response_data = ?, response_headers = ?, status_code = ?
+		WHERE caller = ? AND key_value = ? AND status = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, IdempotencyStatusCompleted, responseData, responseHeaders, statusCode, caller, key, IdempotencyStatusProcessing)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to complete idempotency record", zap.Error(err), zap.String("key", key))
+		return errors.NewDatabaseError(err)
 	}
 
+	logging.FromContext(ctx, r.logger).Debug("Idempotency record completed", zap.String("key", key))
+	return nil
+}
+
+// Release drops a reservation whose request failed, so the key can be retried
+func (r *idempotencyRepository) Release(ctx context.Context, caller, key string) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `DELETE FROM idempotency_keys WHERE caller = ? AND key_value = ? AND status = ?`
+
+	_, err := r.db.ExecContext(ctx, query, caller, key, IdempotencyStatusProcessing)
 	if err != nil {
-		r.logger.Error("Failed to create idempotency record", zap.Error(err), zap.String("key", key))
+		logging.FromContext(ctx, r.logger).Error("Failed to release idempotency key", zap.Error(err), zap.String("key", key))
 		return errors.NewDatabaseError(err)
 	}
 
-	r.logger.Debug("Idempotency record created successfully", zap.String("key", key))
 	return nil
 }
 
-// GetByKey retrieves an idempotency record by key
-func (r *idempotencyRepository) GetByKey(ctx context.Context, key string) (*IdempotencyRecord, error) {
+// GetByKey retrieves a caller's idempotency record by key
+func (r *idempotencyRepository) GetByKey(ctx context.Context, caller, key string) (*IdempotencyRecord, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, key_value, request_hash, response_data, status_code, created_at, expires_at
+		SELECT id, caller, key_value, request_hash, status, response_data, response_headers, COALESCE(status_code, 0) AS status_code, created_at, expires_at
 		FROM idempotency_keys
-		WHERE key_value = ? AND expires_at > ?
+		WHERE caller = ? AND key_value = ? AND expires_at > ?
 	`
 
 	now := time.Now().Unix()
 	record := &IdempotencyRecord{}
 
-	err := r.db.GetContext(ctx, record, query, key, now)
+	err := r.db.GetContext(ctx, record, query, caller, key, now)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found, but not an error
 		}
-		r.logger.Error("Failed to get idempotency record", zap.Error(err), zap.String("key", key))
+		logging.FromContext(ctx, r.logger).Error("Failed to get idempotency record", zap.Error(err), zap.String("key", key))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -74,6 +124,9 @@ func (r *idempotencyRepository) GetByKey(ctx context.Context, key string) (*Idem
 
 // DeleteExpired deletes expired idempotency records
 func (r *idempotencyRepository) DeleteExpired(ctx context.Context, tx *database.Tx) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `DELETE FROM idempotency_keys WHERE expires_at <= ?`
 
 	now := time.Now().Unix()
@@ -88,18 +141,18 @@ func (r *idempotencyRepository) DeleteExpired(ctx context.Context, tx *database.
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to delete expired idempotency records", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to delete expired idempotency records", zap.Error(err))
 		return errors.NewDatabaseError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get rows affected", zap.Error(err))
 		return errors.NewDatabaseError(err)
 	}
 
 	if rowsAffected > 0 {
-		r.logger.Info("Deleted expired idempotency records", zap.Int64("count", rowsAffected))
+		logging.FromContext(ctx, r.logger).Info("Deleted expired idempotency records", zap.Int64("count", rowsAffected))
 	}
 
 	return nil
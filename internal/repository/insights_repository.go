@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type insightsRepository struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewInsightsRepository creates a new insights repository
+func NewInsightsRepository(db *database.DB, logger *zap.Logger) InsightsRepository {
+	return &insightsRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetPaidByMonth retrieves the amounts a user paid per group, month and
+// currency. Expenses are counted in their base currency, like their splits,
+// and in the month of their expense date, like the category report. Months are
+// calendar months in each group's timezone.
+func (r *insightsRepository) GetPaidByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	query := `
+		SELECT e.group_id, g.uuid, g.name, g.timezone, e.expense_date,
+		       e.base_currency, e.base_amount
+		FROM expenses e
+		JOIN ` + r.db.Quote("groups") + ` g ON e.group_id = g.id
+		WHERE e.paid_by = ? AND e.expense_date >= ? AND e.expense_date < ? AND e.deleted_at IS NULL
+	`
+
+	return r.queryAggregates(ctx, "paid", query, userID, from, to)
+}
+
+// GetShareByMonth retrieves a user's share of expenses per group, month and
+// currency. Splits are in their expense's base currency. Months are calendar
+// months in each group's timezone.
+func (r *insightsRepository) GetShareByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	query := `
+		SELECT e.group_id, g.uuid, g.name, g.timezone, e.expense_date,
+		       e.base_currency, es.amount
+		FROM expense_splits es
+		JOIN expenses e ON es.expense_id = e.id
+		JOIN ` + r.db.Quote("groups") + ` g ON e.group_id = g.id
+		WHERE es.user_id = ? AND e.expense_date >= ? AND e.expense_date < ?
+		  AND e.deleted_at IS NULL AND es.deleted_at IS NULL
+	`
+
+	return r.queryAggregates(ctx, "share", query, userID, from, to)
+}
+
+// GetGroupSpendByMonth retrieves the total spend of every group the user
+// belongs to per month, in the expenses' base currency and the group's timezone
+func (r *insightsRepository) GetGroupSpendByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	query := `
+		SELECT e.group_id, g.uuid, g.name, g.timezone, e.expense_date,
+		       e.base_currency, e.base_amount
+		FROM expenses e
+		JOIN ` + r.db.Quote("groups") + ` g ON e.group_id = g.id
+		JOIN group_members gm ON gm.group_id = e.group_id
+		WHERE gm.user_id = ? AND e.expense_date >= ? AND e.expense_date < ? AND e.deleted_at IS NULL
+	`
+
+	return r.queryAggregates(ctx, "group spend", query, userID, from, to)
+}
+
+// GetSettlementsSentByMonth retrieves the settlements a user paid per group,
+// month and currency, in the group's timezone
+func (r *insightsRepository) GetSettlementsSentByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	query := `
+		SELECT s.group_id, g.uuid, g.name, g.timezone, s.created_at,
+		       s.currency, s.amount
+		FROM settlements s
+		JOIN ` + r.db.Quote("groups") + ` g ON s.group_id = g.id
+		WHERE s.from_user_id = ? AND s.created_at >= ? AND s.created_at < ? AND s.voided_at IS NULL AND s.status = 'confirmed'
+	`
+
+	return r.queryAggregates(ctx, "settlements sent", query, userID, from, to)
+}
+
+// GetSettlementsReceivedByMonth retrieves the settlements a user received per
+// group, month and currency, in the group's timezone
+func (r *insightsRepository) GetSettlementsReceivedByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	query := `
+		SELECT s.group_id, g.uuid, g.name, g.timezone, s.created_at,
+		       s.currency, s.amount
+		FROM settlements s
+		JOIN ` + r.db.Quote("groups") + ` g ON s.group_id = g.id
+		WHERE s.to_user_id = ? AND s.created_at >= ? AND s.created_at < ? AND s.voided_at IS NULL AND s.status = 'confirmed'
+	`
+
+	return r.queryAggregates(ctx, "settlements received", query, userID, from, to)
+}
+
+// queryAggregates runs a query for the rows of one kind and sums them per
+// group, month and currency. Timezones differ between groups, so the months
+// are worked out here from each group's location rather than in SQL. Each query
+// selects the group, the time the row is counted at, currency and amount.
+func (r *insightsRepository) queryAggregates(ctx context.Context, kind, query string, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get insight aggregates", zap.String("kind", kind), zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	type aggregateKey struct {
+		groupID  int64
+		month    string
+		currency string
+	}
+	var aggregates []*models.InsightAggregate
+	byKey := make(map[aggregateKey]*models.InsightAggregate)
+	locations := make(map[int64]*time.Location)
+	for rows.Next() {
+		var group models.Group
+		var occurredAt time.Time
+		var currency string
+		var amount decimal.Decimal
+		if err := rows.Scan(&group.ID, &group.UUID, &group.Name, &group.Timezone, database.ScanTime(&occurredAt), &currency, &amount); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan insight row", zap.String("kind", kind), zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+
+		loc, ok := locations[group.ID]
+		if !ok {
+			loc = group.Location()
+			locations[group.ID] = loc
+		}
+		key := aggregateKey{groupID: group.ID, month: utils.LocalMonth(occurredAt, loc), currency: currency}
+		aggregate, ok := byKey[key]
+		if !ok {
+			aggregate = &models.InsightAggregate{
+				GroupID:   group.ID,
+				GroupUUID: group.UUID,
+				GroupName: group.Name,
+				Month:     key.month,
+				Currency:  currency,
+			}
+			byKey[key] = aggregate
+			aggregates = append(aggregates, aggregate)
+		}
+		aggregate.Amount = aggregate.Amount.Add(amount)
+		aggregate.Count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return aggregates, nil
+}
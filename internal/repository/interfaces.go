@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"time"
+
 	"expense-split-tracker/internal/database"
 	"expense-split-tracker/internal/models"
 
@@ -13,8 +15,12 @@ type UserRepository interface {
 	Create(ctx context.Context, tx *database.Tx, user *models.User) error
 	GetByID(ctx context.Context, id int64) (*models.User, error)
 	GetByUUID(ctx context.Context, uuid string) (*models.User, error)
+	GetByUUIDs(ctx context.Context, uuids []string) ([]*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	List(ctx context.Context, offset, limit int) ([]*models.User, error)
+	Search(ctx context.Context, query string, offset, limit int) ([]*models.User, error)
+	CountSearch(ctx context.Context, query string) (int, error)
+	Count(ctx context.Context) (int, error)
 }
 
 // GroupRepository defines the interface for group data operations
@@ -22,28 +28,61 @@ type GroupRepository interface {
 	Create(ctx context.Context, tx *database.Tx, group *models.Group) error
 	GetByID(ctx context.Context, id int64) (*models.Group, error)
 	GetByUUID(ctx context.Context, uuid string) (*models.Group, error)
-	List(ctx context.Context, offset, limit int) ([]*models.Group, error)
-	GetUserGroups(ctx context.Context, userID int64, offset, limit int) ([]*models.Group, error)
+	Update(ctx context.Context, tx *database.Tx, group *models.Group) error
+	Archive(ctx context.Context, tx *database.Tx, id int64) (bool, error)
+	Unarchive(ctx context.Context, tx *database.Tx, id int64) (bool, error)
+	List(ctx context.Context, offset, limit int, includeArchived bool) ([]*models.Group, error)
+	GetUserGroups(ctx context.Context, userID int64, offset, limit int, includeArchived bool) ([]*models.Group, error)
+	GetSharedGroups(ctx context.Context, userID, otherUserID int64) ([]*models.Group, error)
+	Count(ctx context.Context, includeArchived bool) (int, error)
+	CountUserGroups(ctx context.Context, userID int64, includeArchived bool) (int, error)
 
 	// Member operations
-	AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64) error
+	AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error
 	RemoveMember(ctx context.Context, tx *database.Tx, groupID, userID int64) error
 	GetMembers(ctx context.Context, groupID int64) ([]*models.User, error)
+	GetMemberships(ctx context.Context, groupID int64) ([]*models.GroupMember, error)
 	IsMember(ctx context.Context, groupID, userID int64) (bool, error)
+	AreMembers(ctx context.Context, groupID int64, userIDs []int64) (map[int64]bool, error)
+	CountMembers(ctx context.Context, groupID int64) (int, error)
+	GetMemberRole(ctx context.Context, groupID, userID int64) (models.GroupRole, error)
+	SetOwner(ctx context.Context, tx *database.Tx, groupID, userID int64) error
+	SetMemberRole(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error
+	CountAdminsForUpdate(ctx context.Context, tx *database.Tx, groupID int64) (int, error)
 }
 
 // ExpenseRepository defines the interface for expense data operations
 type ExpenseRepository interface {
 	Create(ctx context.Context, tx *database.Tx, expense *models.Expense) error
 	GetByID(ctx context.Context, id int64) (*models.Expense, error)
+	GetByUUID(ctx context.Context, uuid string) (*models.Expense, error)
+	GetByUUIDIncludingDeleted(ctx context.Context, uuid string) (*models.Expense, error)
+	GetByIDForUpdate(ctx context.Context, tx *database.Tx, id int64) (*models.Expense, error)
+	Update(ctx context.Context, tx *database.Tx, expense *models.Expense) error
+	UpdateReceiptURL(ctx context.Context, tx *database.Tx, id int64, receiptURL string) error
+	Delete(ctx context.Context, tx *database.Tx, id int64, deletedBy *int64) (bool, error)
 	List(ctx context.Context, filter *models.ExpenseFilter) ([]*models.Expense, int, error)
-	GetGroupExpenses(ctx context.Context, groupID int64, offset, limit int) ([]*models.Expense, error)
-	GetUserExpenses(ctx context.Context, userID int64, offset, limit int) ([]*models.Expense, error)
+	GetGroupExpenses(ctx context.Context, groupID int64, offset, limit int, includeDeleted bool) ([]*models.Expense, error)
+	GetUserExpenses(ctx context.Context, userID int64, offset, limit int, includeDeleted bool) ([]*models.Expense, error)
+	GetTopGroupExpenses(ctx context.Context, groupID int64, from, to time.Time, limit int, includeDeleted bool) ([]*models.Expense, error)
+	CountGroupExpenses(ctx context.Context, groupID int64, includeDeleted bool) (int, error)
+	GetGroupCurrencyTotals(ctx context.Context, groupID int64) ([]*models.ExpenseCurrencyTotal, error)
+	CountUserExpenses(ctx context.Context, userID int64, includeDeleted bool) (int, error)
+	IterateGroupExpenses(ctx context.Context, groupID int64, from, to time.Time, batchSize int, fn func([]*models.Expense) error) error
+	GetGroupSplitUsers(ctx context.Context, groupID int64) ([]*models.User, error)
 
 	// Split operations
 	CreateSplit(ctx context.Context, tx *database.Tx, split *models.ExpenseSplit) error
 	GetExpenseSplits(ctx context.Context, expenseID int64) ([]*models.ExpenseSplit, error)
+	GetExpenseSplitsForUpdate(ctx context.Context, tx *database.Tx, expenseID int64) ([]*models.ExpenseSplit, error)
+	GetSplitsForExpenses(ctx context.Context, expenseIDs []int64) (map[int64][]*models.ExpenseSplit, error)
 	UpdateSplit(ctx context.Context, tx *database.Tx, split *models.ExpenseSplit) error
+	DeleteExpenseSplits(ctx context.Context, tx *database.Tx, expenseID int64, deletedBy *int64) error
+
+	// Line item operations
+	CreateItem(ctx context.Context, tx *database.Tx, item *models.ExpenseItem) error
+	GetExpenseItems(ctx context.Context, expenseID int64) ([]*models.ExpenseItem, error)
+	DeleteExpenseItems(ctx context.Context, tx *database.Tx, expenseID int64) error
 }
 
 // SettlementRepository defines the interface for settlement data operations
@@ -51,44 +90,154 @@ type SettlementRepository interface {
 	Create(ctx context.Context, tx *database.Tx, settlement *models.Settlement) error
 	GetByID(ctx context.Context, id int64) (*models.Settlement, error)
 	GetByUUID(ctx context.Context, uuid string) (*models.Settlement, error)
+	Void(ctx context.Context, tx *database.Tx, id int64, voidedBy *int64) (bool, error)
+	UpdateStatus(ctx context.Context, tx *database.Tx, id int64, from, to models.SettlementStatus) (bool, error)
 	List(ctx context.Context, filter *models.SettlementFilter) ([]*models.Settlement, int, error)
 	GetGroupSettlements(ctx context.Context, groupID int64, offset, limit int) ([]*models.Settlement, error)
 	GetUserSettlements(ctx context.Context, userID int64, offset, limit int) ([]*models.Settlement, error)
+	CountGroupSettlements(ctx context.Context, groupID int64) (int, error)
+	CountUserSettlements(ctx context.Context, userID int64) (int, error)
+	IterateGroupSettlements(ctx context.Context, groupID int64, batchSize int, fn func([]*models.Settlement) error) error
 }
 
 // BalanceRepository defines the interface for balance data operations
 type BalanceRepository interface {
 	Upsert(ctx context.Context, tx *database.Tx, balance *models.Balance) error
 	GetByGroupAndUser(ctx context.Context, groupID, userID int64, currency string) (*models.Balance, error)
+	GetByGroupAndUserForUpdate(ctx context.Context, tx *database.Tx, groupID, userID int64, currency string) (*models.Balance, error)
 	GetGroupBalances(ctx context.Context, groupID int64, currency string) ([]*models.Balance, error)
+	GetGroupBalancesAllCurrencies(ctx context.Context, groupID int64) ([]*models.Balance, error)
+	GetGroupBalancesForUpdate(ctx context.Context, tx *database.Tx, groupID int64, currency string) ([]*models.Balance, error)
 	GetUserBalances(ctx context.Context, userID int64) ([]*models.Balance, error)
 	UpdateBalance(ctx context.Context, tx *database.Tx, groupID, userID int64, amount decimal.Decimal, currency string) error
+	UpdateDebt(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, amount decimal.Decimal, currency string) error
+	GetDebtForUpdate(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, currency string) (decimal.Decimal, error)
+	GetGroupDebts(ctx context.Context, groupID int64, currency string) ([]*models.DebtRelationship, error)
+	GetGroupDebtsAllCurrencies(ctx context.Context, groupID int64) ([]*models.DebtRelationship, error)
+	GetPairDebts(ctx context.Context, groupID, userID, otherUserID int64) ([]*models.PairDebt, error)
+	ClearGroupDebts(ctx context.Context, tx *database.Tx, groupID int64, currency string) error
+	GetUserExpenseTotals(ctx context.Context, groupID, userID int64, currency string) (*models.ExpenseTotals, error)
+	GetUserSettlementTotals(ctx context.Context, groupID, userID int64, currency string) (*models.SettlementTotals, error)
+	GetUserExpenseTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.ExpenseTotals, error)
+	GetUserSettlementTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.SettlementTotals, error)
+	ZeroGroupBalances(ctx context.Context, tx *database.Tx, groupID int64) error
+	ZeroGroupDebts(ctx context.Context, tx *database.Tx, groupID int64) error
+}
+
+// BalanceHistoryRepository defines the interface for balance history operations
+type BalanceHistoryRepository interface {
+	Record(ctx context.Context, tx *database.Tx, entry *models.BalanceHistoryEntry) error
+	ListForUser(ctx context.Context, filter *models.BalanceHistoryFilter) ([]*models.BalanceHistoryEntry, int, error)
+}
+
+// InsightsRepository defines the interface for spending insight aggregates
+type InsightsRepository interface {
+	GetPaidByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error)
+	GetShareByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error)
+	GetGroupSpendByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error)
+	GetSettlementsSentByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error)
+	GetSettlementsReceivedByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error)
+}
+
+// ReportRepository defines the interface for spending report aggregates
+type ReportRepository interface {
+	GetCategoryTotals(ctx context.Context, groupID int64, filter *models.CategoryReportFilter) ([]*models.CategoryAggregate, error)
+	GetUserPaidTotals(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.StatsAggregate, error)
+	GetUserShareTotals(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.StatsAggregate, error)
+	GetUserExpenseActivity(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.ExpenseActivity, error)
+	GetUserLargestExpense(ctx context.Context, userID int64, currency string, filter *models.UserStatsFilter) (*models.LargestExpense, error)
+	GetUserSettlementTotals(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.SettlementStatsAggregate, error)
+	GetUserActivityGroups(ctx context.Context, userID int64) ([]*models.Group, error)
+}
+
+// GroupLockRepository defines the interface for group write lock operations
+type GroupLockRepository interface {
+	GetForUpdate(ctx context.Context, tx *database.Tx, groupID int64) (*models.GroupLock, error)
+	GetActive(ctx context.Context, tx *database.Tx, groupID int64) (*models.GroupLock, error)
+	Upsert(ctx context.Context, tx *database.Tx, lock *models.GroupLock) error
+	Delete(ctx context.Context, tx *database.Tx, groupID int64, holder string) error
+}
+
+// RecurringExpenseRepository defines the interface for recurring expense operations
+type RecurringExpenseRepository interface {
+	Create(ctx context.Context, tx *database.Tx, recurring *models.RecurringExpense) error
+	GetByUUID(ctx context.Context, uuid string) (*models.RecurringExpense, error)
+	GetGroupRecurringExpenses(ctx context.Context, groupID int64) ([]*models.RecurringExpense, error)
+	GetDue(ctx context.Context, now time.Time, limit int) ([]*models.RecurringExpense, error)
+	Update(ctx context.Context, tx *database.Tx, recurring *models.RecurringExpense) error
+	Advance(ctx context.Context, tx *database.Tx, id int64, runAt, nextRunAt time.Time) (bool, error)
+	Delete(ctx context.Context, tx *database.Tx, id int64) error
+}
+
+// WebhookRepository defines the interface for webhook operations
+type WebhookRepository interface {
+	Create(ctx context.Context, tx *database.Tx, webhook *models.Webhook) error
+	GetByUUID(ctx context.Context, uuid string) (*models.Webhook, error)
+	GetGroupWebhooks(ctx context.Context, groupID int64) ([]*models.Webhook, error)
+	GetActiveGroupWebhooks(ctx context.Context, groupID int64) ([]*models.Webhook, error)
+	Update(ctx context.Context, tx *database.Tx, webhook *models.Webhook) error
+	Delete(ctx context.Context, tx *database.Tx, id int64) error
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDeliveryByUUID(ctx context.Context, uuid string) (*models.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, webhookID int64, status models.WebhookDeliveryStatus) ([]*models.WebhookDelivery, error)
+	CreateDeliveryAttempt(ctx context.Context, attempt *models.WebhookDeliveryAttempt) error
+	GetRecentDeliveryAttempts(ctx context.Context, webhookID int64, limit int) ([]*models.WebhookDeliveryAttempt, error)
 }
 
-// IdempotencyRepository defines the interface for idempotency key operations
+// OutboxRepository defines the interface for outbox event operations
+type OutboxRepository interface {
+	Append(ctx context.Context, tx *database.Tx, events []*models.OutboxEvent) error
+	GetPending(ctx context.Context, limit int) ([]*models.OutboxEvent, error)
+	MarkDispatched(ctx context.Context, id int64) error
+	RecordFailure(ctx context.Context, id int64, message string) error
+}
+
+// IdempotencyRepository defines the interface for idempotency key operations.
+// Keys are unique per caller, the scope the middleware derives from the
+// request's identity.
 type IdempotencyRepository interface {
-	Create(ctx context.Context, tx *database.Tx, key, requestHash string, responseData []byte, statusCode int, expiresAt int64) error
-	GetByKey(ctx context.Context, key string) (*IdempotencyRecord, error)
+	Reserve(ctx context.Context, caller, key, requestHash string, expiresAt int64) (bool, error)
+	Complete(ctx context.Context, caller, key string, responseData, responseHeaders []byte, statusCode int) error
+	Release(ctx context.Context, caller, key string) error
+	GetByKey(ctx context.Context, caller, key string) (*IdempotencyRecord, error)
 	DeleteExpired(ctx context.Context, tx *database.Tx) error
 }
 
+// Idempotency record states: a key is reserved as processing while its
+// request runs and completed once the response is stored
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusCompleted  = "completed"
+)
+
 // IdempotencyRecord represents an idempotency record
 type IdempotencyRecord struct {
-	ID           int64  `json:"id" db:"id"`
-	KeyValue     string `json:"key_value" db:"key_value"`
-	RequestHash  string `json:"request_hash" db:"request_hash"`
-	ResponseData []byte `json:"response_data" db:"response_data"`
-	StatusCode   int    `json:"status_code" db:"status_code"`
-	CreatedAt    int64  `json:"created_at" db:"created_at"`
-	ExpiresAt    int64  `json:"expires_at" db:"expires_at"`
+	ID              int64  `json:"id" db:"id"`
+	Caller          string `json:"caller" db:"caller"`
+	KeyValue        string `json:"key_value" db:"key_value"`
+	RequestHash     string `json:"request_hash" db:"request_hash"`
+	Status          string `json:"status" db:"status"`
+	ResponseData    []byte `json:"response_data" db:"response_data"`
+	ResponseHeaders []byte `json:"response_headers" db:"response_headers"`
+	StatusCode      int    `json:"status_code" db:"status_code"`
+	CreatedAt       int64  `json:"created_at" db:"created_at"`
+	ExpiresAt       int64  `json:"expires_at" db:"expires_at"`
 }
 
 // Repositories aggregates all repository interfaces
 type Repositories struct {
-	User        UserRepository
-	Group       GroupRepository
-	Expense     ExpenseRepository
-	Settlement  SettlementRepository
-	Balance     BalanceRepository
-	Idempotency IdempotencyRepository
+	User           UserRepository
+	Group          GroupRepository
+	Expense        ExpenseRepository
+	Settlement     SettlementRepository
+	Balance        BalanceRepository
+	BalanceHistory BalanceHistoryRepository
+	Insights       InsightsRepository
+	Report         ReportRepository
+	GroupLock      GroupLockRepository
+	Idempotency    IdempotencyRepository
+	Recurring      RecurringExpenseRepository
+	Webhook        WebhookRepository
+	Outbox         OutboxRepository
 }
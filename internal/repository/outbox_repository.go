@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// maxOutboxErrorLength matches the width of outbox_events.last_error
+const maxOutboxErrorLength = 1000
+
+type outboxRepository struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *database.DB, logger *zap.Logger) OutboxRepository {
+	return &outboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Append stores events inside tx. The groups they belong to are locked
+// first, so transactions writing events for the same group take ids in the
+// order they commit and the relay publishes them in that order.
+func (r *outboxRepository) Append(ctx context.Context, tx *database.Tx, events []*models.OutboxEvent) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	// Lock in id order so two transactions touching the same groups cannot deadlock
+	seen := make(map[int64]bool, len(events))
+	var groupIDs []int64
+	for _, event := range events {
+		if !seen[event.GroupID] {
+			seen[event.GroupID] = true
+			groupIDs = append(groupIDs, event.GroupID)
+		}
+	}
+	sort.Slice(groupIDs, func(i, j int) bool { return groupIDs[i] < groupIDs[j] })
+
+	for _, groupID := range groupIDs {
+		var id int64
+		if err := tx.GetContext(ctx, &id, "SELECT id FROM "+r.db.Quote("groups")+" WHERE id = ? "+r.db.ForUpdate(), groupID); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to lock group for outbox", zap.Error(err), zap.Int64("group_id", groupID))
+			return errors.NewDatabaseError(err)
+		}
+	}
+
+	query := `
+		INSERT INTO outbox_events (group_id, event_type, payload, created_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	`
+
+	for _, event := range events {
+		id, err := r.db.InsertReturningID(ctx, tx, query, event.GroupID, event.EventType, []byte(event.Payload))
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to append outbox event", zap.Error(err),
+				zap.String("event", event.EventType), zap.Int64("group_id", event.GroupID))
+			return errors.NewDatabaseError(err)
+		}
+		event.ID = id
+	}
+
+	return nil
+}
+
+// GetPending retrieves up to limit undispatched events, oldest first
+func (r *outboxRepository) GetPending(ctx context.Context, limit int) ([]*models.OutboxEvent, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, group_id, event_type, payload, attempts, last_error, created_at, dispatched_at
+		FROM outbox_events
+		WHERE dispatched_at IS NULL
+		ORDER BY id ASC
+		LIMIT ?
+	`
+
+	var events []*models.OutboxEvent
+	if err := r.db.SelectContext(ctx, &events, query, limit); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get pending outbox events", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return events, nil
+}
+
+// MarkDispatched records that an event has been published
+func (r *outboxRepository) MarkDispatched(ctx context.Context, id int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE outbox_events SET dispatched_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to mark outbox event dispatched", zap.Error(err), zap.Int64("id", id))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed publish attempt and keeps its error
+func (r *outboxRepository) RecordFailure(ctx context.Context, id int64, message string) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	if len(message) > maxOutboxErrorLength {
+		message = message[:maxOutboxErrorLength]
+	}
+
+	query := `UPDATE outbox_events SET attempts = attempts + 1, last_error = ? WHERE id = ?`
+
+	if _, err := r.db.ExecContext(ctx, query, message, id); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to record outbox failure", zap.Error(err), zap.Int64("id", id))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
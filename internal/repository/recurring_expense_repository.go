@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+type recurringExpenseRepository struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewRecurringExpenseRepository creates a new recurring expense repository
+func NewRecurringExpenseRepository(db *database.DB, logger *zap.Logger) RecurringExpenseRepository {
+	return &recurringExpenseRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// selectQuery loads a recurring expense with its group and payer
+func (r *recurringExpenseRepository) selectQuery() string {
+	return `
+		SELECT r.id, r.uuid, r.group_id, r.paid_by, r.amount, r.currency, r.description, r.category, r.split_type, r.splits,
+		       r.frequency, r.starts_at, r.next_run_at, r.last_run_at, r.active, r.created_at, r.updated_at,
+		       g.uuid as group_uuid, g.name as group_name, g.timezone as group_timezone, g.default_currency as group_default_currency,
+		       u.uuid as payer_uuid, u.name as payer_name, u.email as payer_email, u.is_pending as payer_is_pending
+		FROM recurring_expenses r
+		JOIN ` + r.db.Quote("groups") + ` g ON r.group_id = g.id
+		JOIN users u ON r.paid_by = u.id
+`
+}
+
+// Create creates a new recurring expense
+func (r *recurringExpenseRepository) Create(ctx context.Context, tx *database.Tx, recurring *models.RecurringExpense) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO recurring_expenses (uuid, group_id, paid_by, amount, currency, description, category, split_type, splits,
+		                                frequency, starts_at, next_run_at, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	`
+
+	splits, err := recurring.MarshalSplits()
+	if err != nil {
+		return errors.NewInternalError("Failed to encode recurring expense splits")
+	}
+
+	args := []interface{}{recurring.UUID, recurring.GroupID, recurring.PaidBy, recurring.Amount, recurring.Currency,
+		recurring.Description, recurring.Category, recurring.SplitType, splits,
+		recurring.Frequency, recurring.StartsAt, recurring.NextRunAt, recurring.Active}
+
+	id, err := r.db.InsertReturningID(ctx, tx, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create recurring expense", zap.Error(err), zap.String("description", recurring.Description))
+		return errors.NewDatabaseError(err)
+	}
+
+	recurring.ID = id
+	return nil
+}
+
+// GetByUUID retrieves a recurring expense by UUID
+func (r *recurringExpenseRepository) GetByUUID(ctx context.Context, uuid string) (*models.RecurringExpense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := r.selectQuery() + `
+		WHERE r.uuid = ?
+	`
+
+	recurring, err := scanRecurringExpense(r.db.QueryRowContext(ctx, query, uuid))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Recurring expense")
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get recurring expense by UUID", zap.Error(err), zap.String("uuid", uuid))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return recurring, nil
+}
+
+// GetGroupRecurringExpenses retrieves every recurring expense of a group, oldest first
+func (r *recurringExpenseRepository) GetGroupRecurringExpenses(ctx context.Context, groupID int64) ([]*models.RecurringExpense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := r.selectQuery() + `
+		WHERE r.group_id = ?
+		ORDER BY r.created_at ASC, r.id ASC
+	`
+
+	recurring, err := r.query(ctx, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get group recurring expenses", zap.Error(err), zap.Int64("group_id", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return recurring, nil
+}
+
+// GetDue retrieves up to limit active recurring expenses whose next run is at
+// or before now, earliest first
+func (r *recurringExpenseRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]*models.RecurringExpense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := r.selectQuery() + `
+		WHERE r.active = TRUE AND r.next_run_at <= ?
+		ORDER BY r.next_run_at ASC, r.id ASC
+		LIMIT ?
+	`
+
+	recurring, err := r.query(ctx, query, now, limit)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get due recurring expenses", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return recurring, nil
+}
+
+// Update replaces the template and schedule of a recurring expense
+func (r *recurringExpenseRepository) Update(ctx context.Context, tx *database.Tx, recurring *models.RecurringExpense) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE recurring_expenses
+		SET paid_by = ?, amount = ?, currency = ?, description = ?, category = ?, split_type = ?, splits = ?,
+		    frequency = ?, starts_at = ?, next_run_at = ?, active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	splits, err := recurring.MarshalSplits()
+	if err != nil {
+		return errors.NewInternalError("Failed to encode recurring expense splits")
+	}
+
+	args := []interface{}{recurring.PaidBy, recurring.Amount, recurring.Currency, recurring.Description, recurring.Category,
+		recurring.SplitType, splits, recurring.Frequency, recurring.StartsAt, recurring.NextRunAt, recurring.Active, recurring.ID}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, args...)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, args...)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update recurring expense", zap.Error(err), zap.Int64("id", recurring.ID))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// Advance moves a recurring expense from the run at runAt to nextRunAt. It
+// reports false without changing anything if the schedule no longer points at
+// runAt, which happens when another scheduler or an update got there first.
+func (r *recurringExpenseRepository) Advance(ctx context.Context, tx *database.Tx, id int64, runAt, nextRunAt time.Time) (bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE recurring_expenses
+		SET next_run_at = ?, last_run_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND next_run_at = ?
+	`
+
+	var result sql.Result
+	var err error
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, query, nextRunAt, runAt, id, runAt)
+	} else {
+		result, err = r.db.ExecContext(ctx, query, nextRunAt, runAt, id, runAt)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to advance recurring expense", zap.Error(err), zap.Int64("id", id))
+		return false, errors.NewDatabaseError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get affected rows", zap.Error(err))
+		return false, errors.NewDatabaseError(err)
+	}
+
+	return affected > 0, nil
+}
+
+// Delete deletes a recurring expense. Expenses it already created are kept.
+func (r *recurringExpenseRepository) Delete(ctx context.Context, tx *database.Tx, id int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `DELETE FROM recurring_expenses WHERE id = ?`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, id)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, id)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete recurring expense", zap.Error(err), zap.Int64("id", id))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// query runs a selectQuery query and scans every row
+func (r *recurringExpenseRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.RecurringExpense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recurring []*models.RecurringExpense
+	for rows.Next() {
+		item, err := scanRecurringExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		recurring = append(recurring, item)
+	}
+
+	return recurring, rows.Err()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRecurringExpense scans one row selected by selectQuery
+func scanRecurringExpense(row rowScanner) (*models.RecurringExpense, error) {
+	recurring := &models.RecurringExpense{}
+	group := &models.Group{}
+	payer := &models.User{}
+	var splits []byte
+	var lastRunAt sql.NullTime
+
+	err := row.Scan(
+		&recurring.ID, &recurring.UUID, &recurring.GroupID, &recurring.PaidBy, &recurring.Amount, &recurring.Currency,
+		&recurring.Description, &recurring.Category, &recurring.SplitType, &splits,
+		&recurring.Frequency, &recurring.StartsAt, &recurring.NextRunAt, &lastRunAt, &recurring.Active, &recurring.CreatedAt, &recurring.UpdatedAt,
+		&group.UUID, &group.Name, &group.Timezone, &group.DefaultCurrency,
+		&payer.UUID, &payer.Name, &payer.Email, &payer.IsPending,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(splits, &recurring.Splits); err != nil {
+		return nil, err
+	}
+	if lastRunAt.Valid {
+		recurring.LastRunAt = &lastRunAt.Time
+	}
+
+	group.ID = recurring.GroupID
+	recurring.Group = group
+	payer.ID = recurring.PaidBy
+	recurring.Payer = payer
+
+	return recurring, nil
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+type reportRepository struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewReportRepository creates a new report repository
+func NewReportRepository(db *database.DB, logger *zap.Logger) ReportRepository {
+	return &reportRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetCategoryTotals retrieves a group's expense totals per category and
+// currency. Expenses without a category are counted as uncategorized.
+func (r *reportRepository) GetCategoryTotals(ctx context.Context, groupID int64, filter *models.CategoryReportFilter) ([]*models.CategoryAggregate, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COALESCE(NULLIF(e.category, ''), ?) AS category, e.currency, SUM(e.amount), COUNT(*)
+		FROM expenses e
+		WHERE e.group_id = ? AND e.deleted_at IS NULL
+	`
+	args := []interface{}{models.DefaultExpenseCategory, groupID}
+
+	if filter.Currency != "" {
+		query += " AND e.currency = ?"
+		args = append(args, filter.Currency)
+	}
+	if !filter.FromDate.IsZero() {
+		query += " AND e.expense_date >= ?"
+		args = append(args, filter.FromDate)
+	}
+	if !filter.ToDate.IsZero() {
+		query += " AND e.expense_date <= ?"
+		args = append(args, filter.ToDate)
+	}
+	// By position, so empty and missing categories share a group; the alias
+	// category would otherwise resolve to the column of the same name
+	query += " GROUP BY 1, 2"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get category totals", zap.Error(err), zap.Int64("groupID", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var aggregates []*models.CategoryAggregate
+	for rows.Next() {
+		aggregate := &models.CategoryAggregate{}
+		if err := rows.Scan(&aggregate.Category, &aggregate.Currency, &aggregate.Amount, &aggregate.Count); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan category total", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+		aggregates = append(aggregates, aggregate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return aggregates, nil
+}
+
+// GetUserPaidTotals retrieves the amounts a user paid per currency
+func (r *reportRepository) GetUserPaidTotals(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.StatsAggregate, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT e.currency, SUM(e.amount), COUNT(*)
+		FROM expenses e
+		WHERE e.paid_by = ? AND e.deleted_at IS NULL
+	`
+	dateSQL, args := statsDateRange("e.expense_date", "e.group_id", filter, userID)
+	query += dateSQL + " GROUP BY e.currency"
+
+	return r.queryStats(ctx, "paid", query, args)
+}
+
+// GetUserShareTotals retrieves the sum of a user's splits per currency. Splits
+// are in their expense's base currency.
+func (r *reportRepository) GetUserShareTotals(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.StatsAggregate, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT e.base_currency, SUM(es.amount), COUNT(*)
+		FROM expense_splits es
+		JOIN expenses e ON es.expense_id = e.id
+		WHERE es.user_id = ? AND e.deleted_at IS NULL AND es.deleted_at IS NULL
+	`
+	dateSQL, args := statsDateRange("e.expense_date", "e.group_id", filter, userID)
+	query += dateSQL + " GROUP BY e.base_currency"
+
+	return r.queryStats(ctx, "share", query, args)
+}
+
+// GetUserExpenseActivity counts the expenses a user paid for or has a split
+// in per currency, with the date of the first one
+func (r *reportRepository) GetUserExpenseActivity(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.ExpenseActivity, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT e.currency, COUNT(*), MIN(e.expense_date)
+		FROM expenses e
+		WHERE (e.paid_by = ? OR EXISTS (SELECT 1 FROM expense_splits es WHERE es.expense_id = e.id AND es.user_id = ? AND es.deleted_at IS NULL))
+		  AND e.deleted_at IS NULL
+	`
+	dateSQL, args := statsDateRange("e.expense_date", "e.group_id", filter, userID, userID)
+	query += dateSQL + " GROUP BY e.currency"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get expense activity", zap.Error(err), zap.Int64("userID", userID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var activity []*models.ExpenseActivity
+	for rows.Next() {
+		entry := &models.ExpenseActivity{}
+		if err := rows.Scan(&entry.Currency, &entry.Count, database.ScanTime(&entry.FirstExpenseDate)); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan expense activity", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+		activity = append(activity, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return activity, nil
+}
+
+// GetUserLargestExpense retrieves the largest expense in a currency that a
+// user paid for or has a split in, or nil if there is none
+func (r *reportRepository) GetUserLargestExpense(ctx context.Context, userID int64, currency string, filter *models.UserStatsFilter) (*models.LargestExpense, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT e.uuid, e.description, e.amount, e.expense_date, g.uuid AS group_uuid, g.name AS group_name
+		FROM expenses e
+		JOIN ` + r.db.Quote("groups") + ` g ON e.group_id = g.id
+		WHERE (e.paid_by = ? OR EXISTS (SELECT 1 FROM expense_splits es WHERE es.expense_id = e.id AND es.user_id = ? AND es.deleted_at IS NULL))
+		  AND e.currency = ? AND e.deleted_at IS NULL
+	`
+	dateSQL, args := statsDateRange("e.expense_date", "e.group_id", filter, userID, userID, currency)
+	query += dateSQL + " ORDER BY e.amount DESC, e.id LIMIT 1"
+
+	largest := &models.LargestExpense{}
+	err := r.db.GetContext(ctx, largest, query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get largest expense", zap.Error(err), zap.Int64("userID", userID))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return largest, nil
+}
+
+// GetUserSettlementTotals retrieves the confirmed, non-voided settlements a
+// user sent and received per currency
+func (r *reportRepository) GetUserSettlementTotals(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.SettlementStatsAggregate, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT s.currency,
+		       COALESCE(SUM(CASE WHEN s.from_user_id = ? THEN s.amount END), 0),
+		       SUM(CASE WHEN s.from_user_id = ? THEN 1 ELSE 0 END),
+		       COALESCE(SUM(CASE WHEN s.to_user_id = ? THEN s.amount END), 0),
+		       SUM(CASE WHEN s.to_user_id = ? THEN 1 ELSE 0 END)
+		FROM settlements s
+		WHERE (s.from_user_id = ? OR s.to_user_id = ?) AND s.status = 'confirmed' AND s.voided_at IS NULL
+	`
+	dateSQL, args := statsDateRange("s.created_at", "s.group_id", filter, userID, userID, userID, userID, userID, userID)
+	query += dateSQL + " GROUP BY s.currency"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get settlement totals", zap.Error(err), zap.Int64("userID", userID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var aggregates []*models.SettlementStatsAggregate
+	for rows.Next() {
+		aggregate := &models.SettlementStatsAggregate{}
+		err := rows.Scan(&aggregate.Currency, &aggregate.SentAmount, &aggregate.SentCount, &aggregate.ReceivedAmount, &aggregate.ReceivedCount)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan settlement totals", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+		aggregates = append(aggregates, aggregate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return aggregates, nil
+}
+
+// GetUserActivityGroups retrieves the groups a user paid, shared or settled
+// in, with their timezones
+func (r *reportRepository) GetUserActivityGroups(ctx context.Context, userID int64) ([]*models.Group, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT g.id, g.timezone
+		FROM ` + r.db.Quote("groups") + ` g
+		WHERE g.id IN (SELECT e.group_id FROM expenses e WHERE e.paid_by = ?)
+		   OR g.id IN (SELECT e.group_id FROM expense_splits es JOIN expenses e ON es.expense_id = e.id WHERE es.user_id = ?)
+		   OR g.id IN (SELECT s.group_id FROM settlements s WHERE s.from_user_id = ? OR s.to_user_id = ?)
+	`
+
+	var groups []*models.Group
+	if err := r.db.SelectContext(ctx, &groups, query, userID, userID, userID, userID); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get user activity groups", zap.Error(err), zap.Int64("userID", userID))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return groups, nil
+}
+
+// queryStats runs a per-currency total query and scans the resulting rows
+func (r *reportRepository) queryStats(ctx context.Context, kind, query string, args []interface{}) ([]*models.StatsAggregate, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get user stats", zap.String("kind", kind), zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var aggregates []*models.StatsAggregate
+	for rows.Next() {
+		aggregate := &models.StatsAggregate{}
+		if err := rows.Scan(&aggregate.Currency, &aggregate.Amount, &aggregate.Count); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan user stats", zap.String("kind", kind), zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+		aggregates = append(aggregates, aggregate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return aggregates, nil
+}
+
+// statsDateRange builds the date conditions of a stats query on column,
+// appending their arguments to args. With group ranges each group is matched
+// against its own range on groupColumn.
+func statsDateRange(column, groupColumn string, filter *models.UserStatsFilter, args ...interface{}) (string, []interface{}) {
+	if len(filter.GroupRanges) == 0 {
+		conditions, args := dateRangeConditions(column, filter.FromDate, filter.ToDate, args)
+		return strings.Join(append([]string{""}, conditions...), " AND "), args
+	}
+
+	ranges := make([]string, 0, len(filter.GroupRanges))
+	for _, groupRange := range filter.GroupRanges {
+		args = append(args, groupRange.GroupID)
+		var conditions []string
+		conditions, args = dateRangeConditions(column, groupRange.FromDate, groupRange.ToDate, args)
+		ranges = append(ranges, "("+strings.Join(append([]string{groupColumn + " = ?"}, conditions...), " AND ")+")")
+	}
+	return " AND (" + strings.Join(ranges, " OR ") + ")", args
+}
+
+// dateRangeConditions returns the conditions bounding column by from and to,
+// leaving out zero bounds
+func dateRangeConditions(column string, from, to time.Time, args []interface{}) ([]string, []interface{}) {
+	var conditions []string
+	if !from.IsZero() {
+		conditions = append(conditions, column+" >= ?")
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		conditions = append(conditions, column+" <= ?")
+		args = append(args, to)
+	}
+	return conditions, args
+}
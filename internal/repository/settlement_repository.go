@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/pkg/errors"
 
@@ -27,47 +28,102 @@ func NewSettlementRepository(db *database.DB, logger *zap.Logger) SettlementRepo
 
 // Create creates a new settlement
 func (r *settlementRepository) Create(ctx context.Context, tx *database.Tx, settlement *models.Settlement) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		INSERT INTO settlements (uuid, group_id, from_user_id, to_user_id, amount, currency, description, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, NOW())
+		INSERT INTO settlements (uuid, group_id, from_user_id, to_user_id, amount, currency, description, status, method, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 	`
 
+	id, err := r.db.InsertReturningID(ctx, tx, query, settlement.UUID, settlement.GroupID, settlement.FromUserID,
+		settlement.ToUserID, settlement.Amount, settlement.Currency, settlement.Description, settlement.Status, settlement.Method)
+	if err != nil {
+		if dupErr := duplicateKeyError(err, "Settlement", map[string]string{"uuid": "uuid"}); dupErr != nil {
+			return dupErr
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to create settlement", zap.Error(err))
+		return errors.NewDatabaseError(err)
+	}
+
+	settlement.ID = id
+	logging.FromContext(ctx, r.logger).Info("Settlement created successfully", zap.Int64("id", settlement.ID))
+	return nil
+}
+
+// Void marks a settlement as voided by voidedBy, which may be nil. It reports
+// false without changing anything if the settlement was already voided.
+func (r *settlementRepository) Void(ctx context.Context, tx *database.Tx, id int64, voidedBy *int64) (bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `UPDATE settlements SET voided_at = CURRENT_TIMESTAMP, voided_by = ? WHERE id = ? AND voided_at IS NULL`
+
 	var result sql.Result
 	var err error
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, query, voidedBy, id)
+	} else {
+		result, err = r.db.ExecContext(ctx, query, voidedBy, id)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to void settlement", zap.Error(err), zap.Int64("id", id))
+		return false, errors.NewDatabaseError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get affected rows", zap.Error(err))
+		return false, errors.NewDatabaseError(err)
+	}
+
+	return affected > 0, nil
+}
+
+// UpdateStatus moves a settlement from one status to another. It reports false
+// without changing anything if the settlement is voided or no longer in the
+// from status.
+func (r *settlementRepository) UpdateStatus(ctx context.Context, tx *database.Tx, id int64, from, to models.SettlementStatus) (bool, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
 
+	query := `UPDATE settlements SET status = ? WHERE id = ? AND status = ? AND voided_at IS NULL`
+
+	var result sql.Result
+	var err error
 	if tx != nil {
-		result, err = tx.ExecContext(ctx, query, settlement.UUID, settlement.GroupID, settlement.FromUserID,
-			settlement.ToUserID, settlement.Amount, settlement.Currency, settlement.Description)
+		result, err = tx.ExecContext(ctx, query, to, id, from)
 	} else {
-		result, err = r.db.ExecContext(ctx, query, settlement.UUID, settlement.GroupID, settlement.FromUserID,
-			settlement.ToUserID, settlement.Amount, settlement.Currency, settlement.Description)
+		result, err = r.db.ExecContext(ctx, query, to, id, from)
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to create settlement", zap.Error(err))
-		return errors.NewDatabaseError(err)
+		logging.FromContext(ctx, r.logger).Error("Failed to update settlement status", zap.Error(err), zap.Int64("id", id))
+		return false, errors.NewDatabaseError(err)
 	}
 
-	id, err := result.LastInsertId()
+	affected, err := result.RowsAffected()
 	if err != nil {
-		r.logger.Error("Failed to get last insert ID", zap.Error(err))
-		return errors.NewDatabaseError(err)
+		logging.FromContext(ctx, r.logger).Error("Failed to get affected rows", zap.Error(err))
+		return false, errors.NewDatabaseError(err)
 	}
 
-	settlement.ID = id
-	r.logger.Info("Settlement created successfully", zap.Int64("id", settlement.ID))
-	return nil
+	return affected > 0, nil
 }
 
 // GetByID retrieves a settlement by ID
 func (r *settlementRepository) GetByID(ctx context.Context, id int64) (*models.Settlement, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.created_at,
+		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.status, s.method, s.voided_at, s.voided_by, s.created_at,
 		       g.uuid as group_uuid, g.name as group_name,
-		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email,
-		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email
+		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email, fu.is_pending as from_user_is_pending,
+		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email, tu.is_pending as to_user_is_pending
 		FROM settlements s
-		LEFT JOIN ` + "`groups`" + ` g ON s.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON s.group_id = g.id
 		LEFT JOIN users fu ON s.from_user_id = fu.id
 		LEFT JOIN users tu ON s.to_user_id = tu.id
 		WHERE s.id = ?
@@ -80,20 +136,21 @@ func (r *settlementRepository) GetByID(ctx context.Context, id int64) (*models.S
 	fromUser := &models.User{}
 	toUser := &models.User{}
 	var groupUUID, groupName, fromUserUUID, fromUserName, fromUserEmail, toUserUUID, toUserName, toUserEmail sql.NullString
+	var fromUserIsPending, toUserIsPending sql.NullBool
 
 	err := row.Scan(
 		&settlement.ID, &settlement.UUID, &settlement.GroupID, &settlement.FromUserID, &settlement.ToUserID,
-		&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.CreatedAt,
+		&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.Status, &settlement.Method, &settlement.VoidedAt, &settlement.VoidedBy, &settlement.CreatedAt,
 		&groupUUID, &groupName,
-		&fromUserUUID, &fromUserName, &fromUserEmail,
-		&toUserUUID, &toUserName, &toUserEmail,
+		&fromUserUUID, &fromUserName, &fromUserEmail, &fromUserIsPending,
+		&toUserUUID, &toUserName, &toUserEmail, &toUserIsPending,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.NewNotFoundError("Settlement")
 		}
-		r.logger.Error("Failed to get settlement by ID", zap.Error(err), zap.Int64("id", id))
+		logging.FromContext(ctx, r.logger).Error("Failed to get settlement by ID", zap.Error(err), zap.Int64("id", id))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -109,6 +166,7 @@ func (r *settlementRepository) GetByID(ctx context.Context, id int64) (*models.S
 		fromUser.UUID = fromUserUUID.String
 		fromUser.Name = fromUserName.String
 		fromUser.Email = fromUserEmail.String
+		fromUser.IsPending = fromUserIsPending.Bool
 		settlement.FromUser = fromUser
 	}
 
@@ -117,6 +175,7 @@ func (r *settlementRepository) GetByID(ctx context.Context, id int64) (*models.S
 		toUser.UUID = toUserUUID.String
 		toUser.Name = toUserName.String
 		toUser.Email = toUserEmail.String
+		toUser.IsPending = toUserIsPending.Bool
 		settlement.ToUser = toUser
 	}
 
@@ -125,13 +184,16 @@ func (r *settlementRepository) GetByID(ctx context.Context, id int64) (*models.S
 
 // GetByUUID retrieves a settlement by UUID
 func (r *settlementRepository) GetByUUID(ctx context.Context, uuid string) (*models.Settlement, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.created_at,
+		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.status, s.method, s.voided_at, s.voided_by, s.created_at,
 		       g.uuid as group_uuid, g.name as group_name,
-		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email,
-		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email
+		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email, fu.is_pending as from_user_is_pending,
+		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email, tu.is_pending as to_user_is_pending
 		FROM settlements s
-		LEFT JOIN ` + "`groups`" + ` g ON s.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON s.group_id = g.id
 		LEFT JOIN users fu ON s.from_user_id = fu.id
 		LEFT JOIN users tu ON s.to_user_id = tu.id
 		WHERE s.uuid = ?
@@ -144,20 +206,21 @@ func (r *settlementRepository) GetByUUID(ctx context.Context, uuid string) (*mod
 	fromUser := &models.User{}
 	toUser := &models.User{}
 	var groupUUID, groupName, fromUserUUID, fromUserName, fromUserEmail, toUserUUID, toUserName, toUserEmail sql.NullString
+	var fromUserIsPending, toUserIsPending sql.NullBool
 
 	err := row.Scan(
 		&settlement.ID, &settlement.UUID, &settlement.GroupID, &settlement.FromUserID, &settlement.ToUserID,
-		&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.CreatedAt,
+		&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.Status, &settlement.Method, &settlement.VoidedAt, &settlement.VoidedBy, &settlement.CreatedAt,
 		&groupUUID, &groupName,
-		&fromUserUUID, &fromUserName, &fromUserEmail,
-		&toUserUUID, &toUserName, &toUserEmail,
+		&fromUserUUID, &fromUserName, &fromUserEmail, &fromUserIsPending,
+		&toUserUUID, &toUserName, &toUserEmail, &toUserIsPending,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.NewNotFoundError("Settlement")
 		}
-		r.logger.Error("Failed to get settlement by UUID", zap.Error(err), zap.String("uuid", uuid))
+		logging.FromContext(ctx, r.logger).Error("Failed to get settlement by UUID", zap.Error(err), zap.String("uuid", uuid))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -173,6 +236,7 @@ func (r *settlementRepository) GetByUUID(ctx context.Context, uuid string) (*mod
 		fromUser.UUID = fromUserUUID.String
 		fromUser.Name = fromUserName.String
 		fromUser.Email = fromUserEmail.String
+		fromUser.IsPending = fromUserIsPending.Bool
 		settlement.FromUser = fromUser
 	}
 
@@ -181,6 +245,7 @@ func (r *settlementRepository) GetByUUID(ctx context.Context, uuid string) (*mod
 		toUser.UUID = toUserUUID.String
 		toUser.Name = toUserName.String
 		toUser.Email = toUserEmail.String
+		toUser.IsPending = toUserIsPending.Bool
 		settlement.ToUser = toUser
 	}
 
@@ -189,6 +254,9 @@ func (r *settlementRepository) GetByUUID(ctx context.Context, uuid string) (*mod
 
 // List retrieves settlements with filtering
 func (r *settlementRepository) List(ctx context.Context, filter *models.SettlementFilter) ([]*models.Settlement, int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	whereClause := []string{"1=1"}
 	args := []interface{}{}
 
@@ -227,13 +295,27 @@ func (r *settlementRepository) List(ctx context.Context, filter *models.Settleme
 		args = append(args, filter.ToDate)
 	}
 
+	if filter.Status != "" {
+		whereClause = append(whereClause, "s.status = ?")
+		args = append(args, filter.Status)
+	}
+
+	if filter.Method != "" {
+		whereClause = append(whereClause, "s.method = ?")
+		args = append(args, filter.Method)
+	}
+
+	if !filter.IncludeVoided {
+		whereClause = append(whereClause, "s.voided_at IS NULL")
+	}
+
 	whereSQL := strings.Join(whereClause, " AND ")
 
 	// Count total
 	countQuery := `
 		SELECT COUNT(*)
 		FROM settlements s
-		LEFT JOIN ` + "`groups`" + ` g ON s.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON s.group_id = g.id
 		LEFT JOIN users fu ON s.from_user_id = fu.id
 		LEFT JOIN users tu ON s.to_user_id = tu.id
 		WHERE ` + whereSQL
@@ -241,7 +323,7 @@ func (r *settlementRepository) List(ctx context.Context, filter *models.Settleme
 	var total int
 	err := r.db.GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
-		r.logger.Error("Failed to count settlements", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to count settlements", zap.Error(err))
 		return nil, 0, errors.NewDatabaseError(err)
 	}
 
@@ -256,24 +338,26 @@ func (r *settlementRepository) List(ctx context.Context, filter *models.Settleme
 	}
 	offset := (page - 1) * limit
 
+	orderBy := orderByClause(filter.ListSort, settlementSortColumns, "s.id", "s.created_at DESC")
+
 	query := `
-		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.created_at,
+		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.status, s.method, s.voided_at, s.voided_by, s.created_at,
 		       g.uuid as group_uuid, g.name as group_name,
-		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email,
-		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email
+		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email, fu.is_pending as from_user_is_pending,
+		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email, tu.is_pending as to_user_is_pending
 		FROM settlements s
-		LEFT JOIN ` + "`groups`" + ` g ON s.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON s.group_id = g.id
 		LEFT JOIN users fu ON s.from_user_id = fu.id
 		LEFT JOIN users tu ON s.to_user_id = tu.id
 		WHERE ` + whereSQL + `
-		ORDER BY s.created_at DESC
+		ORDER BY ` + orderBy + `
 		LIMIT ? OFFSET ?
 	`
 
 	args = append(args, limit, offset)
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		r.logger.Error("Failed to list settlements", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to list settlements", zap.Error(err))
 		return nil, 0, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -285,16 +369,17 @@ func (r *settlementRepository) List(ctx context.Context, filter *models.Settleme
 		fromUser := &models.User{}
 		toUser := &models.User{}
 		var groupUUID, groupName, fromUserUUID, fromUserName, fromUserEmail, toUserUUID, toUserName, toUserEmail sql.NullString
+		var fromUserIsPending, toUserIsPending sql.NullBool
 
 		err := rows.Scan(
 			&settlement.ID, &settlement.UUID, &settlement.GroupID, &settlement.FromUserID, &settlement.ToUserID,
-			&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.CreatedAt,
+			&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.Status, &settlement.Method, &settlement.VoidedAt, &settlement.VoidedBy, &settlement.CreatedAt,
 			&groupUUID, &groupName,
-			&fromUserUUID, &fromUserName, &fromUserEmail,
-			&toUserUUID, &toUserName, &toUserEmail,
+			&fromUserUUID, &fromUserName, &fromUserEmail, &fromUserIsPending,
+			&toUserUUID, &toUserName, &toUserEmail, &toUserIsPending,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan settlement row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan settlement row", zap.Error(err))
 			return nil, 0, errors.NewDatabaseError(err)
 		}
 
@@ -310,6 +395,7 @@ func (r *settlementRepository) List(ctx context.Context, filter *models.Settleme
 			fromUser.UUID = fromUserUUID.String
 			fromUser.Name = fromUserName.String
 			fromUser.Email = fromUserEmail.String
+			fromUser.IsPending = fromUserIsPending.Bool
 			settlement.FromUser = fromUser
 		}
 
@@ -318,6 +404,7 @@ func (r *settlementRepository) List(ctx context.Context, filter *models.Settleme
 			toUser.UUID = toUserUUID.String
 			toUser.Name = toUserName.String
 			toUser.Email = toUserEmail.String
+			toUser.IsPending = toUserIsPending.Bool
 			settlement.ToUser = toUser
 		}
 
@@ -329,21 +416,24 @@ func (r *settlementRepository) List(ctx context.Context, filter *models.Settleme
 
 // GetGroupSettlements retrieves settlements for a specific group
 func (r *settlementRepository) GetGroupSettlements(ctx context.Context, groupID int64, offset, limit int) ([]*models.Settlement, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.created_at,
-		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email,
-		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email
+		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.status, s.method, s.voided_at, s.voided_by, s.created_at,
+		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email, fu.is_pending as from_user_is_pending,
+		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email, tu.is_pending as to_user_is_pending
 		FROM settlements s
 		LEFT JOIN users fu ON s.from_user_id = fu.id
 		LEFT JOIN users tu ON s.to_user_id = tu.id
-		WHERE s.group_id = ?
+		WHERE s.group_id = ? AND s.voided_at IS NULL
 		ORDER BY s.created_at DESC
 		LIMIT ? OFFSET ?
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, groupID, limit, offset)
 	if err != nil {
-		r.logger.Error("Failed to get group settlements", zap.Error(err), zap.Int64("groupID", groupID))
+		logging.FromContext(ctx, r.logger).Error("Failed to get group settlements", zap.Error(err), zap.Int64("groupID", groupID))
 		return nil, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -354,15 +444,16 @@ func (r *settlementRepository) GetGroupSettlements(ctx context.Context, groupID
 		fromUser := &models.User{}
 		toUser := &models.User{}
 		var fromUserUUID, fromUserName, fromUserEmail, toUserUUID, toUserName, toUserEmail sql.NullString
+		var fromUserIsPending, toUserIsPending sql.NullBool
 
 		err := rows.Scan(
 			&settlement.ID, &settlement.UUID, &settlement.GroupID, &settlement.FromUserID, &settlement.ToUserID,
-			&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.CreatedAt,
-			&fromUserUUID, &fromUserName, &fromUserEmail,
-			&toUserUUID, &toUserName, &toUserEmail,
+			&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.Status, &settlement.Method, &settlement.VoidedAt, &settlement.VoidedBy, &settlement.CreatedAt,
+			&fromUserUUID, &fromUserName, &fromUserEmail, &fromUserIsPending,
+			&toUserUUID, &toUserName, &toUserEmail, &toUserIsPending,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan group settlement row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan group settlement row", zap.Error(err))
 			return nil, errors.NewDatabaseError(err)
 		}
 
@@ -371,6 +462,7 @@ func (r *settlementRepository) GetGroupSettlements(ctx context.Context, groupID
 			fromUser.UUID = fromUserUUID.String
 			fromUser.Name = fromUserName.String
 			fromUser.Email = fromUserEmail.String
+			fromUser.IsPending = fromUserIsPending.Bool
 			settlement.FromUser = fromUser
 		}
 
@@ -379,6 +471,7 @@ func (r *settlementRepository) GetGroupSettlements(ctx context.Context, groupID
 			toUser.UUID = toUserUUID.String
 			toUser.Name = toUserName.String
 			toUser.Email = toUserEmail.String
+			toUser.IsPending = toUserIsPending.Bool
 			settlement.ToUser = toUser
 		}
 
@@ -390,23 +483,26 @@ func (r *settlementRepository) GetGroupSettlements(ctx context.Context, groupID
 
 // GetUserSettlements retrieves settlements for a specific user (either as payer or receiver)
 func (r *settlementRepository) GetUserSettlements(ctx context.Context, userID int64, offset, limit int) ([]*models.Settlement, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.created_at,
+		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.status, s.method, s.voided_at, s.voided_by, s.created_at,
 		       g.uuid as group_uuid, g.name as group_name,
-		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email,
-		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email
+		       fu.uuid as from_user_uuid, fu.name as from_user_name, fu.email as from_user_email, fu.is_pending as from_user_is_pending,
+		       tu.uuid as to_user_uuid, tu.name as to_user_name, tu.email as to_user_email, tu.is_pending as to_user_is_pending
 		FROM settlements s
-		LEFT JOIN ` + "`groups`" + ` g ON s.group_id = g.id
+		LEFT JOIN ` + r.db.Quote("groups") + ` g ON s.group_id = g.id
 		LEFT JOIN users fu ON s.from_user_id = fu.id
 		LEFT JOIN users tu ON s.to_user_id = tu.id
-		WHERE s.from_user_id = ? OR s.to_user_id = ?
+		WHERE (s.from_user_id = ? OR s.to_user_id = ?) AND s.voided_at IS NULL
 		ORDER BY s.created_at DESC
 		LIMIT ? OFFSET ?
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, userID, userID, limit, offset)
 	if err != nil {
-		r.logger.Error("Failed to get user settlements", zap.Error(err), zap.Int64("userID", userID))
+		logging.FromContext(ctx, r.logger).Error("Failed to get user settlements", zap.Error(err), zap.Int64("userID", userID))
 		return nil, errors.NewDatabaseError(err)
 	}
 	defer rows.Close()
@@ -418,16 +514,17 @@ func (r *settlementRepository) GetUserSettlements(ctx context.Context, userID in
 		fromUser := &models.User{}
 		toUser := &models.User{}
 		var groupUUID, groupName, fromUserUUID, fromUserName, fromUserEmail, toUserUUID, toUserName, toUserEmail sql.NullString
+		var fromUserIsPending, toUserIsPending sql.NullBool
 
 		err := rows.Scan(
 			&settlement.ID, &settlement.UUID, &settlement.GroupID, &settlement.FromUserID, &settlement.ToUserID,
-			&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.CreatedAt,
+			&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.Status, &settlement.Method, &settlement.VoidedAt, &settlement.VoidedBy, &settlement.CreatedAt,
 			&groupUUID, &groupName,
-			&fromUserUUID, &fromUserName, &fromUserEmail,
-			&toUserUUID, &toUserName, &toUserEmail,
+			&fromUserUUID, &fromUserName, &fromUserEmail, &fromUserIsPending,
+			&toUserUUID, &toUserName, &toUserEmail, &toUserIsPending,
 		)
 		if err != nil {
-			r.logger.Error("Failed to scan user settlement row", zap.Error(err))
+			logging.FromContext(ctx, r.logger).Error("Failed to scan user settlement row", zap.Error(err))
 			return nil, errors.NewDatabaseError(err)
 		}
 
@@ -443,6 +540,7 @@ func (r *settlementRepository) GetUserSettlements(ctx context.Context, userID in
 			fromUser.UUID = fromUserUUID.String
 			fromUser.Name = fromUserName.String
 			fromUser.Email = fromUserEmail.String
+			fromUser.IsPending = fromUserIsPending.Bool
 			settlement.FromUser = fromUser
 		}
 
@@ -451,6 +549,7 @@ func (r *settlementRepository) GetUserSettlements(ctx context.Context, userID in
 			toUser.UUID = toUserUUID.String
 			toUser.Name = toUserName.String
 			toUser.Email = toUserEmail.String
+			toUser.IsPending = toUserIsPending.Bool
 			settlement.ToUser = toUser
 		}
 
@@ -459,3 +558,104 @@ func (r *settlementRepository) GetUserSettlements(ctx context.Context, userID in
 
 	return settlements, nil
 }
+
+// IterateGroupSettlements calls fn with batches of the group's settlements that
+// count towards balances (confirmed and not voided), oldest first. Batches use
+// keyset pagination on id, so every settlement is visited exactly once.
+func (r *settlementRepository) IterateGroupSettlements(ctx context.Context, groupID int64, batchSize int, fn func([]*models.Settlement) error) error {
+	var lastID int64
+	for {
+		settlements, err := r.queryGroupSettlementBatch(ctx, groupID, lastID, batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(settlements) == 0 {
+			return nil
+		}
+		if err := fn(settlements); err != nil {
+			return err
+		}
+		if len(settlements) < batchSize {
+			return nil
+		}
+
+		lastID = settlements[len(settlements)-1].ID
+	}
+}
+
+// queryGroupSettlementBatch fetches the batch of IterateGroupSettlements that
+// follows the settlement with id afterID
+func (r *settlementRepository) queryGroupSettlementBatch(ctx context.Context, groupID, afterID int64, batchSize int) ([]*models.Settlement, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT s.id, s.uuid, s.group_id, s.from_user_id, s.to_user_id, s.amount, s.currency, s.description, s.status, s.method, s.voided_at, s.voided_by, s.created_at
+		FROM settlements s
+		WHERE s.group_id = ? AND s.status = 'confirmed' AND s.voided_at IS NULL AND s.id > ?
+		ORDER BY s.id ASC
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, groupID, afterID, batchSize)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to iterate group settlements", zap.Error(err), zap.Int64("groupID", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var settlements []*models.Settlement
+	for rows.Next() {
+		settlement := &models.Settlement{}
+		err := rows.Scan(
+			&settlement.ID, &settlement.UUID, &settlement.GroupID, &settlement.FromUserID, &settlement.ToUserID,
+			&settlement.Amount, &settlement.Currency, &settlement.Description, &settlement.Status, &settlement.Method, &settlement.VoidedAt, &settlement.VoidedBy, &settlement.CreatedAt,
+		)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan group settlement row", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+		settlements = append(settlements, settlement)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return settlements, nil
+}
+
+// CountGroupSettlements counts the settlements of a group
+func (r *settlementRepository) CountGroupSettlements(ctx context.Context, groupID int64) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM settlements WHERE group_id = ? AND voided_at IS NULL`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count group settlements", zap.Error(err), zap.Int64("groupID", groupID))
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
+
+// CountUserSettlements counts the settlements a user sent or received
+func (r *settlementRepository) CountUserSettlements(ctx context.Context, userID int64) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM settlements WHERE (from_user_id = ? OR to_user_id = ?) AND voided_at IS NULL`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query, userID, userID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count user settlements", zap.Error(err), zap.Int64("userID", userID))
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"strings"
+
+	"expense-split-tracker/internal/models"
+)
+
+var expenseSortColumns = map[string]string{
+	"created_at":   "e.created_at",
+	"expense_date": "e.expense_date",
+	"amount":       "e.amount",
+	"description":  "e.description",
+}
+
+var settlementSortColumns = map[string]string{
+	"created_at":  "s.created_at",
+	"amount":      "s.amount",
+	"description": "s.description",
+}
+
+// orderByClause maps a requested sort onto a whitelisted column, falling back
+// to the default order for unknown fields. The id column breaks ties so
+// pagination stays stable.
+func orderByClause(sort models.ListSort, columns map[string]string, idColumn, fallback string) string {
+	column, ok := columns[sort.SortBy]
+	if !ok {
+		return fallback
+	}
+
+	dir := "DESC"
+	if strings.EqualFold(sort.SortDir, models.SortAsc) {
+		dir = "ASC"
+	}
+	return column + " " + dir + ", " + idColumn + " " + dir
+}
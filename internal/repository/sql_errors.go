@@ -0,0 +1,99 @@
+package repository
+
+import (
+	stderrors "errors"
+	"strings"
+
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// mysqlErrDuplicateEntry is the MySQL error number for a unique key violation
+const mysqlErrDuplicateEntry = 1062
+
+// pqErrUniqueViolation is the Postgres SQLSTATE for a unique key violation
+const pqErrUniqueViolation = "23505"
+
+// isDuplicateKey reports whether err is a unique key violation
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if stderrors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlErrDuplicateEntry
+	}
+	var sqliteErr sqlite3.Error
+	if stderrors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	var pqErr *pq.Error
+	return stderrors.As(err, &pqErr) && pqErr.Code == pqErrUniqueViolation
+}
+
+// duplicateKeyName returns the name of the unique index a duplicate entry
+// error refers to. MySQL 8 reports it as "table.index", older versions as
+// "index"; both come back as just the index name. Postgres names the
+// constraint of a single-column UNIQUE "table_column_key", which comes back
+// as the column name, matching MySQL's naming of the same index. SQLite only
+// reports the columns, so just single-column indexes get a name there.
+func duplicateKeyName(err error) string {
+	var sqliteErr sqlite3.Error
+	if stderrors.As(err, &sqliteErr) {
+		const marker = "constraint failed: "
+		start := strings.Index(sqliteErr.Error(), marker)
+		if start < 0 {
+			return ""
+		}
+		column := sqliteErr.Error()[start+len(marker):]
+		if strings.Contains(column, ",") {
+			return ""
+		}
+		if dot := strings.LastIndex(column, "."); dot >= 0 {
+			column = column[dot+1:]
+		}
+		return column
+	}
+
+	var pqErr *pq.Error
+	if stderrors.As(err, &pqErr) {
+		key := strings.TrimPrefix(pqErr.Constraint, pqErr.Table+"_")
+		if key != pqErr.Constraint {
+			key = strings.TrimSuffix(key, "_key")
+		}
+		return key
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if !stderrors.As(err, &mysqlErr) {
+		return ""
+	}
+
+	const marker = "for key '"
+	start := strings.LastIndex(mysqlErr.Message, marker)
+	if start < 0 {
+		return ""
+	}
+	key := strings.TrimSuffix(mysqlErr.Message[start+len(marker):], "'")
+	if dot := strings.LastIndex(key, "."); dot >= 0 {
+		key = key[dot+1:]
+	}
+	return key
+}
+
+// duplicateKeyError converts a unique key violation into a 409 naming the
+// conflicting field, so a write that loses a race against a concurrent one
+// reports a conflict instead of a database failure. fields maps the table's
+// unique index names to request field names. It returns nil if err is not a
+// duplicate entry error.
+func duplicateKeyError(err error, resource string, fields map[string]string) *errors.AppError {
+	if !isDuplicateKey(err) {
+		return nil
+	}
+
+	field, ok := fields[duplicateKeyName(err)]
+	if !ok {
+		return errors.NewAlreadyExistsError(resource)
+	}
+	return errors.NewDuplicateFieldError(resource, field)
+}
@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"strings"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
 	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
 	"expense-split-tracker/pkg/errors"
 
 	"go.uber.org/zap"
@@ -24,42 +27,41 @@ func NewUserRepository(db *database.DB, logger *zap.Logger) UserRepository {
 	}
 }
 
+// userUniqueFields maps the unique indexes of users to their fields
+var userUniqueFields = map[string]string{"email": "email", "uuid": "uuid"}
+
 // Create creates a new user
 func (r *userRepository) Create(ctx context.Context, tx *database.Tx, user *models.User) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		INSERT INTO users (uuid, name, email, created_at, updated_at)
-		VALUES (?, ?, ?, NOW(), NOW())
+		INSERT INTO users (uuid, name, email, is_pending, created_at, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	`
 
-	var result sql.Result
-	var err error
-
-	if tx != nil {
-		result, err = tx.ExecContext(ctx, query, user.UUID, user.Name, user.Email)
-	} else {
-		result, err = r.db.ExecContext(ctx, query, user.UUID, user.Name, user.Email)
-	}
-
+	id, err := r.db.InsertReturningID(ctx, tx, query, user.UUID, user.Name, user.Email, user.IsPending)
 	if err != nil {
-		r.logger.Error("Failed to create user", zap.Error(err), zap.String("email", user.Email))
-		return errors.NewDatabaseError(err)
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		r.logger.Error("Failed to get last insert ID", zap.Error(err))
+		// Two signups with the same email can both pass the service's check
+		if dupErr := duplicateKeyError(err, "User", userUniqueFields); dupErr != nil {
+			return dupErr
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to create user", zap.Error(err), zap.String("email", user.Email))
 		return errors.NewDatabaseError(err)
 	}
 
 	user.ID = id
-	r.logger.Info("User created successfully", zap.Int64("id", user.ID), zap.String("email", user.Email))
+	logging.FromContext(ctx, r.logger).Info("User created successfully", zap.Int64("id", user.ID), zap.String("email", user.Email))
 	return nil
 }
 
 // GetByID retrieves a user by ID
 func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, uuid, name, email, created_at, updated_at
+		SELECT id, uuid, name, email, is_pending, created_at, updated_at
 		FROM users
 		WHERE id = ?
 	`
@@ -70,7 +72,7 @@ func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, e
 		if err == sql.ErrNoRows {
 			return nil, errors.NewNotFoundError("User")
 		}
-		r.logger.Error("Failed to get user by ID", zap.Error(err), zap.Int64("id", id))
+		logging.FromContext(ctx, r.logger).Error("Failed to get user by ID", zap.Error(err), zap.Int64("id", id))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -79,8 +81,11 @@ func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, e
 
 // GetByUUID retrieves a user by UUID
 func (r *userRepository) GetByUUID(ctx context.Context, uuid string) (*models.User, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, uuid, name, email, created_at, updated_at
+		SELECT id, uuid, name, email, is_pending, created_at, updated_at
 		FROM users
 		WHERE uuid = ?
 	`
@@ -91,17 +96,52 @@ func (r *userRepository) GetByUUID(ctx context.Context, uuid string) (*models.Us
 		if err == sql.ErrNoRows {
 			return nil, errors.NewNotFoundError("User")
 		}
-		r.logger.Error("Failed to get user by UUID", zap.Error(err), zap.String("uuid", uuid))
+		logging.FromContext(ctx, r.logger).Error("Failed to get user by UUID", zap.Error(err), zap.String("uuid", uuid))
 		return nil, errors.NewDatabaseError(err)
 	}
 
 	return user, nil
 }
 
+// GetByUUIDs retrieves the users with the given UUIDs in one query. Unknown
+// UUIDs are skipped; the order of the result is unspecified.
+func (r *userRepository) GetByUUIDs(ctx context.Context, uuids []string) ([]*models.User, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	users := []*models.User{}
+	if len(uuids) == 0 {
+		return users, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(uuids)), ", ")
+	args := make([]interface{}, len(uuids))
+	for i, uuid := range uuids {
+		args[i] = uuid
+	}
+
+	query := `
+		SELECT id, uuid, name, email, is_pending, created_at, updated_at
+		FROM users
+		WHERE uuid IN (` + placeholders + `)
+	`
+
+	err := r.db.SelectContext(ctx, &users, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get users by UUID", zap.Error(err), zap.Int("count", len(uuids)))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return users, nil
+}
+
 // GetByEmail retrieves a user by email
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, uuid, name, email, created_at, updated_at
+		SELECT id, uuid, name, email, is_pending, created_at, updated_at
 		FROM users
 		WHERE email = ?
 	`
@@ -112,7 +152,7 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 		if err == sql.ErrNoRows {
 			return nil, errors.NewNotFoundError("User")
 		}
-		r.logger.Error("Failed to get user by email", zap.Error(err), zap.String("email", email))
+		logging.FromContext(ctx, r.logger).Error("Failed to get user by email", zap.Error(err), zap.String("email", email))
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -121,30 +161,39 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 
 // Update updates a user
 func (r *userRepository) Update(ctx context.Context, tx *database.Tx, user *models.User) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE users
-		SET name = ?, email = ?, updated_at = NOW()
+		SET name = ?, email = ?, is_pending = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`
 
 	var err error
 	if tx != nil {
-		_, err = tx.ExecContext(ctx, query, user.Name, user.Email, user.ID)
+		_, err = tx.ExecContext(ctx, query, user.Name, user.Email, user.IsPending, user.ID)
 	} else {
-		_, err = r.db.ExecContext(ctx, query, user.Name, user.Email, user.ID)
+		_, err = r.db.ExecContext(ctx, query, user.Name, user.Email, user.IsPending, user.ID)
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to update user", zap.Error(err), zap.Int64("id", user.ID))
+		if dupErr := duplicateKeyError(err, "User", userUniqueFields); dupErr != nil {
+			return dupErr
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to update user", zap.Error(err), zap.Int64("id", user.ID))
 		return errors.NewDatabaseError(err)
 	}
 
-	r.logger.Info("User updated successfully", zap.Int64("id", user.ID))
+	logging.FromContext(ctx, r.logger).Info("User updated successfully", zap.Int64("id", user.ID))
 	return nil
 }
 
 // Delete deletes a user
 func (r *userRepository) Delete(ctx context.Context, tx *database.Tx, id int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `DELETE FROM users WHERE id = ?`
 
 	var result sql.Result
@@ -157,13 +206,13 @@ func (r *userRepository) Delete(ctx context.Context, tx *database.Tx, id int64)
 	}
 
 	if err != nil {
-		r.logger.Error("Failed to delete user", zap.Error(err), zap.Int64("id", id))
+		logging.FromContext(ctx, r.logger).Error("Failed to delete user", zap.Error(err), zap.Int64("id", id))
 		return errors.NewDatabaseError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to get rows affected", zap.Error(err))
 		return errors.NewDatabaseError(err)
 	}
 
@@ -171,14 +220,17 @@ func (r *userRepository) Delete(ctx context.Context, tx *database.Tx, id int64)
 		return errors.NewNotFoundError("User")
 	}
 
-	r.logger.Info("User deleted successfully", zap.Int64("id", id))
+	logging.FromContext(ctx, r.logger).Info("User deleted successfully", zap.Int64("id", id))
 	return nil
 }
 
 // List retrieves a list of users with pagination
 func (r *userRepository) List(ctx context.Context, offset, limit int) ([]*models.User, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, uuid, name, email, created_at, updated_at
+		SELECT id, uuid, name, email, is_pending, created_at, updated_at
 		FROM users
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
@@ -187,9 +239,78 @@ func (r *userRepository) List(ctx context.Context, offset, limit int) ([]*models
 	users := []*models.User{}
 	err := r.db.SelectContext(ctx, &users, query, limit, offset)
 	if err != nil {
-		r.logger.Error("Failed to list users", zap.Error(err))
+		logging.FromContext(ctx, r.logger).Error("Failed to list users", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return users, nil
+}
+
+// userSearchCondition matches users whose name or email contains the
+// lowercased search pattern
+const userSearchCondition = "(LOWER(name) LIKE ? ESCAPE '" + utils.LikeEscapeChar + "' OR LOWER(email) LIKE ? ESCAPE '" + utils.LikeEscapeChar + "')"
+
+// Search retrieves users whose name or email contains query, ignoring case.
+// Prefix matches come first, then the newest users.
+func (r *userRepository) Search(ctx context.Context, query string, offset, limit int) ([]*models.User, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	sqlQuery := `
+		SELECT id, uuid, name, email, is_pending, created_at, updated_at
+		FROM users
+		WHERE ` + userSearchCondition + `
+		ORDER BY (LOWER(name) LIKE ? ESCAPE '` + utils.LikeEscapeChar + `' OR LOWER(email) LIKE ? ESCAPE '` + utils.LikeEscapeChar + `') DESC,
+		         created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	term := strings.ToLower(query)
+	contains := utils.ContainsPattern(term)
+	prefix := utils.PrefixPattern(term)
+
+	users := []*models.User{}
+	err := r.db.SelectContext(ctx, &users, sqlQuery, contains, contains, prefix, prefix, limit, offset)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to search users", zap.Error(err))
 		return nil, errors.NewDatabaseError(err)
 	}
 
 	return users, nil
 }
+
+// CountSearch counts the users matched by Search
+func (r *userRepository) CountSearch(ctx context.Context, query string) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	sqlQuery := `SELECT COUNT(*) FROM users WHERE ` + userSearchCondition
+
+	contains := utils.ContainsPattern(strings.ToLower(query))
+
+	var count int
+	err := r.db.GetContext(ctx, &count, sqlQuery, contains, contains)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count user search results", zap.Error(err))
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
+
+// Count counts all users
+func (r *userRepository) Count(ctx context.Context) (int, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM users`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, query)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to count users", zap.Error(err))
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+type webhookRepository struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *database.DB, logger *zap.Logger) WebhookRepository {
+	return &webhookRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// selectQuery loads a webhook with its group
+func (r *webhookRepository) selectQuery() string {
+	return `
+		SELECT w.id, w.uuid, w.group_id, w.url, w.secret, w.event_types, w.active, w.created_at, w.updated_at,
+		       g.uuid as group_uuid, g.name as group_name
+		FROM webhooks w
+		JOIN ` + r.db.Quote("groups") + ` g ON w.group_id = g.id
+`
+}
+
+// Create creates a new webhook
+func (r *webhookRepository) Create(ctx context.Context, tx *database.Tx, webhook *models.Webhook) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO webhooks (uuid, group_id, url, secret, event_types, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	`
+
+	eventTypes, err := webhook.MarshalEventTypes()
+	if err != nil {
+		return errors.NewInternalError("Failed to encode webhook event types")
+	}
+
+	args := []interface{}{webhook.UUID, webhook.GroupID, webhook.URL, webhook.Secret, eventTypes, webhook.Active}
+
+	id, err := r.db.InsertReturningID(ctx, tx, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create webhook", zap.Error(err), zap.Int64("group_id", webhook.GroupID))
+		return errors.NewDatabaseError(err)
+	}
+
+	webhook.ID = id
+	return nil
+}
+
+// GetByUUID retrieves a webhook by UUID
+func (r *webhookRepository) GetByUUID(ctx context.Context, uuid string) (*models.Webhook, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := r.selectQuery() + `
+		WHERE w.uuid = ?
+	`
+
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, uuid))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Webhook")
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get webhook by UUID", zap.Error(err), zap.String("uuid", uuid))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return webhook, nil
+}
+
+// GetGroupWebhooks retrieves every webhook of a group, oldest first
+func (r *webhookRepository) GetGroupWebhooks(ctx context.Context, groupID int64) ([]*models.Webhook, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := r.selectQuery() + `
+		WHERE w.group_id = ?
+		ORDER BY w.created_at ASC, w.id ASC
+	`
+
+	webhooks, err := r.query(ctx, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get group webhooks", zap.Error(err), zap.Int64("group_id", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return webhooks, nil
+}
+
+// GetActiveGroupWebhooks retrieves the active webhooks of a group
+func (r *webhookRepository) GetActiveGroupWebhooks(ctx context.Context, groupID int64) ([]*models.Webhook, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := r.selectQuery() + `
+		WHERE w.group_id = ? AND w.active = TRUE
+		ORDER BY w.id ASC
+	`
+
+	webhooks, err := r.query(ctx, query, groupID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get active group webhooks", zap.Error(err), zap.Int64("group_id", groupID))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return webhooks, nil
+}
+
+// Update replaces the target, secret, subscriptions and active flag of a webhook
+func (r *webhookRepository) Update(ctx context.Context, tx *database.Tx, webhook *models.Webhook) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE webhooks
+		SET url = ?, secret = ?, event_types = ?, active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	eventTypes, err := webhook.MarshalEventTypes()
+	if err != nil {
+		return errors.NewInternalError("Failed to encode webhook event types")
+	}
+
+	args := []interface{}{webhook.URL, webhook.Secret, eventTypes, webhook.Active, webhook.ID}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, args...)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, args...)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update webhook", zap.Error(err), zap.Int64("id", webhook.ID))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// Delete deletes a webhook. Deliveries already queued are still attempted.
+func (r *webhookRepository) Delete(ctx context.Context, tx *database.Tx, id int64) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `DELETE FROM webhooks WHERE id = ?`
+
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, id)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, id)
+	}
+
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to delete webhook", zap.Error(err), zap.Int64("id", id))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// deliverySelectQuery loads a webhook delivery
+const deliverySelectQuery = `
+		SELECT id, uuid, webhook_id, event_type, payload, status, attempts, last_error, created_at, updated_at
+		FROM webhook_deliveries
+`
+
+// CreateDelivery records a delivery before its first attempt
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO webhook_deliveries (uuid, webhook_id, event_type, payload, status, attempts, last_error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	`
+
+	args := []interface{}{
+		delivery.UUID, delivery.WebhookID, delivery.EventType, []byte(delivery.Payload),
+		delivery.Status, delivery.Attempts, delivery.LastError,
+	}
+
+	id, err := r.db.InsertReturningID(ctx, nil, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to create webhook delivery", zap.Error(err), zap.Int64("webhook_id", delivery.WebhookID))
+		return errors.NewDatabaseError(err)
+	}
+
+	delivery.ID = id
+	return nil
+}
+
+// UpdateDelivery records the status, attempt count and last error of a delivery
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, last_error = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, delivery.Status, delivery.Attempts, delivery.LastError, delivery.ID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to update webhook delivery", zap.Error(err), zap.Int64("id", delivery.ID))
+		return errors.NewDatabaseError(err)
+	}
+
+	return nil
+}
+
+// GetDeliveryByUUID retrieves a webhook delivery by UUID
+func (r *webhookRepository) GetDeliveryByUUID(ctx context.Context, uuid string) (*models.WebhookDelivery, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := deliverySelectQuery + `
+		WHERE uuid = ?
+	`
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, uuid))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Webhook delivery")
+		}
+		logging.FromContext(ctx, r.logger).Error("Failed to get webhook delivery by UUID", zap.Error(err), zap.String("uuid", uuid))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return delivery, nil
+}
+
+// GetWebhookDeliveries retrieves the deliveries of a webhook in a status,
+// newest first
+func (r *webhookRepository) GetWebhookDeliveries(ctx context.Context, webhookID int64, status models.WebhookDeliveryStatus) ([]*models.WebhookDelivery, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := deliverySelectQuery + `
+		WHERE webhook_id = ? AND status = ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	deliveries, err := r.queryDeliveries(ctx, query, webhookID, status)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get webhook deliveries", zap.Error(err), zap.Int64("webhook_id", webhookID))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return deliveries, nil
+}
+
+// CreateDeliveryAttempt records one POST of a delivery
+func (r *webhookRepository) CreateDeliveryAttempt(ctx context.Context, attempt *models.WebhookDeliveryAttempt) error {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, outcome, error, latency_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	`
+
+	args := []interface{}{
+		attempt.DeliveryID, attempt.Attempt, attempt.StatusCode, attempt.Outcome, attempt.Error, attempt.LatencyMS,
+	}
+
+	id, err := r.db.InsertReturningID(ctx, nil, query, args...)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to record webhook delivery attempt", zap.Error(err), zap.Int64("delivery_id", attempt.DeliveryID))
+		return errors.NewDatabaseError(err)
+	}
+
+	attempt.ID = id
+	return nil
+}
+
+// GetRecentDeliveryAttempts retrieves the latest delivery attempts of a
+// webhook, newest first
+func (r *webhookRepository) GetRecentDeliveryAttempts(ctx context.Context, webhookID int64, limit int) ([]*models.WebhookDeliveryAttempt, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	query := `
+		SELECT a.id, a.delivery_id, d.uuid, d.event_type, a.attempt, a.status_code, a.outcome, a.error, a.latency_ms, a.created_at
+		FROM webhook_delivery_attempts a
+		JOIN webhook_deliveries d ON a.delivery_id = d.id
+		WHERE d.webhook_id = ?
+		ORDER BY a.id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, webhookID, limit)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to get webhook delivery attempts", zap.Error(err), zap.Int64("webhook_id", webhookID))
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	attempts := []*models.WebhookDeliveryAttempt{}
+	for rows.Next() {
+		attempt := &models.WebhookDeliveryAttempt{}
+		err := rows.Scan(
+			&attempt.ID, &attempt.DeliveryID, &attempt.DeliveryUUID, &attempt.EventType, &attempt.Attempt,
+			&attempt.StatusCode, &attempt.Outcome, &attempt.Error, &attempt.LatencyMS, &attempt.CreatedAt,
+		)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to scan webhook delivery attempt", zap.Error(err))
+			return nil, errors.NewDatabaseError(err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to iterate webhook delivery attempts", zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return attempts, nil
+}
+
+// queryDeliveries runs a deliverySelectQuery query and scans every row
+func (r *webhookRepository) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// query runs a selectQuery query and scans every row
+func (r *webhookRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.Webhook, error) {
+	ctx, cancel := r.db.StatementContext(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// scanWebhook scans one row selected by selectQuery
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	group := &models.Group{}
+	var eventTypes []byte
+
+	err := row.Scan(
+		&webhook.ID, &webhook.UUID, &webhook.GroupID, &webhook.URL, &webhook.Secret, &eventTypes, &webhook.Active,
+		&webhook.CreatedAt, &webhook.UpdatedAt,
+		&group.UUID, &group.Name,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(eventTypes, &webhook.EventTypes); err != nil {
+		return nil, err
+	}
+
+	group.ID = webhook.GroupID
+	webhook.Group = group
+
+	return webhook, nil
+}
+
+// scanWebhookDelivery scans one row selected by deliverySelectQuery
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	var payload []byte
+
+	err := row.Scan(
+		&delivery.ID, &delivery.UUID, &delivery.WebhookID, &delivery.EventType, &payload,
+		&delivery.Status, &delivery.Attempts, &delivery.LastError, &delivery.CreatedAt, &delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = payload
+	return delivery, nil
+}
@@ -1,8 +1,8 @@
 package routes
 
 import (
-	"net/http"
-
+	// Registers the generated spec with swag for Swagger UI
+	_ "expense-split-tracker/docs/swagger"
 	"expense-split-tracker/internal/controller"
 	"expense-split-tracker/internal/service"
 
@@ -11,15 +11,12 @@ import (
 )
 
 // SetupRoutes configures all the routes for the application
-func SetupRoutes(router *gin.Engine, services *service.Services, logger *zap.Logger) {
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  "ok",
-			"service": "expense-split-tracker",
-			"version": "1.0.0",
-		})
-	})
+func SetupRoutes(router *gin.Engine, services *service.Services, db controller.HealthChecker, logger *zap.Logger) {
+	// Health checks: /health is readiness and pings the database, /health/live
+	// only reports that the process is up
+	healthController := controller.NewHealthController(db, logger)
+	router.GET("/health", healthController.Readiness)
+	router.GET("/health/live", healthController.Liveness)
 
 	// API version 1 routes
 	v1 := router.Group("/api/v1")
@@ -29,9 +26,21 @@ func SetupRoutes(router *gin.Engine, services *service.Services, logger *zap.Log
 		setupExpenseRoutes(v1, services, logger)
 		setupSettlementRoutes(v1, services, logger)
 		setupBalanceRoutes(v1, services, logger)
+		setupInsightsRoutes(v1, services, logger)
+		setupExportRoutes(v1, services, logger)
+		setupReportRoutes(v1, services, logger)
+		setupRecurringExpenseRoutes(v1, services, logger)
+		setupWebhookRoutes(v1, services, logger)
 	}
 }
 
+// SetupSwaggerRoutes serves the generated API spec at /swagger/doc.json and
+// Swagger UI at /swagger/index.html
+func SetupSwaggerRoutes(router *gin.Engine) {
+	docsController := controller.NewDocsController()
+	router.GET("/swagger/*any", docsController.Serve)
+}
+
 // setupUserRoutes configures user-related routes
 func setupUserRoutes(rg *gin.RouterGroup, services *service.Services, logger *zap.Logger) {
 	userController := controller.NewUserController(services.User, logger)
@@ -41,6 +50,7 @@ func setupUserRoutes(rg *gin.RouterGroup, services *service.Services, logger *za
 		users.POST("", userController.CreateUser)
 		users.GET("", userController.ListUsers)
 		users.GET("/by-email", userController.GetUserByEmail)
+		users.POST("/lookup", userController.LookupUsers)
 		users.GET("/:uuid", userController.GetUser)
 	}
 }
@@ -52,12 +62,22 @@ func setupGroupRoutes(rg *gin.RouterGroup, services *service.Services, logger *z
 	groups := rg.Group("/groups")
 	{
 		groups.POST("", groupController.CreateGroup)
+		groups.POST("/bootstrap", groupController.BootstrapGroup)
 		groups.GET("", groupController.ListGroups)
 		groups.GET("/:uuid", groupController.GetGroup)
+		groups.GET("/:uuid/summary", groupController.GetGroupSummary)
+		groups.PUT("/:uuid", groupController.UpdateGroup)
+		groups.PATCH("/:uuid", groupController.UpdateGroup)
+		groups.DELETE("/:uuid", groupController.ArchiveGroup)
+		groups.POST("/:uuid/unarchive", groupController.UnarchiveGroup)
+		groups.POST("/:uuid/transfer-ownership", groupController.TransferOwnership)
 
 		// Member management
 		groups.POST("/:uuid/members", groupController.AddMember)
+		groups.POST("/:uuid/members/batch", groupController.AddMembers)
 		groups.DELETE("/:uuid/members/:userUuid", groupController.RemoveMember)
+		groups.PUT("/:uuid/members/:userUuid/role", groupController.UpdateMemberRole)
+		groups.POST("/:uuid/leave", groupController.LeaveGroup)
 		groups.GET("/:uuid/members", groupController.GetMembers)
 	}
 
@@ -73,10 +93,17 @@ func setupExpenseRoutes(rg *gin.RouterGroup, services *service.Services, logger
 	{
 		expenses.POST("", expenseController.CreateExpense)
 		expenses.GET("", expenseController.ListExpenses)
+		expenses.GET("/:uuid", expenseController.GetExpense)
+		expenses.PUT("/:uuid", expenseController.UpdateExpense)
+		expenses.PUT("/:uuid/receipt", expenseController.SetReceipt)
+		expenses.POST("/:uuid/duplicate", expenseController.DuplicateExpense)
+		expenses.DELETE("/:uuid", expenseController.DeleteExpense)
 	}
 
 	// Group expenses
 	rg.GET("/groups/:uuid/expenses", expenseController.GetGroupExpenses)
+	rg.GET("/groups/:uuid/expenses/top", expenseController.GetTopGroupExpenses)
+	rg.POST("/groups/:uuid/expenses/import", expenseController.ImportExpenses)
 	// User expenses
 	rg.GET("/users/:uuid/expenses", expenseController.GetUserExpenses)
 }
@@ -90,14 +117,20 @@ func setupSettlementRoutes(rg *gin.RouterGroup, services *service.Services, logg
 		settlements.POST("", settlementController.CreateSettlement)
 		settlements.GET("", settlementController.ListSettlements)
 		settlements.GET("/:uuid", settlementController.GetSettlement)
+		settlements.DELETE("/:uuid", settlementController.VoidSettlement)
+		settlements.POST("/:uuid/confirm", settlementController.ConfirmSettlement)
+		settlements.POST("/:uuid/reject", settlementController.RejectSettlement)
 	}
 
 	// Group settlements
 	rg.GET("/groups/:uuid/settlements", settlementController.GetGroupSettlements)
 	// User settlements
 	rg.GET("/users/:uuid/settlements", settlementController.GetUserSettlements)
-	// Debt simplification (read-only)
+	// Debt simplification
 	rg.GET("/groups/:uuid/simplify-debts", settlementController.SimplifyDebts)
+	rg.POST("/groups/:uuid/simplify-debts/execute", settlementController.ExecuteDebtSimplification)
+	// Settle everything a user owes
+	rg.POST("/groups/:uuid/users/:userUuid/settle-all", settlementController.SettleAllForUser)
 }
 
 // setupBalanceRoutes configures balance-related routes
@@ -108,6 +141,78 @@ func setupBalanceRoutes(rg *gin.RouterGroup, services *service.Services, logger
 	rg.GET("/groups/:uuid/balance-sheet", balanceController.GetBalanceSheet)
 	// User balance in group (changed to avoid route conflict)
 	rg.GET("/groups/:uuid/users/:userUuid/balance", balanceController.GetUserBalance)
+	// Gross paid, owed and settled amounts of a user per currency
+	rg.GET("/groups/:uuid/users/:userUuid/summary", balanceController.GetUserSummary)
+	// Changes to a user's balance over time
+	rg.GET("/groups/:uuid/users/:userUuid/balance-history", balanceController.GetBalanceHistory)
+	// Who owes a user and whom they owe
+	rg.GET("/groups/:uuid/users/:userUuid/creditors-debtors", balanceController.GetUserCounterparties)
 	// Debt relationships
 	rg.GET("/groups/:uuid/debt-relationships", balanceController.GetDebtRelationships)
+	// User balances across all groups
+	rg.GET("/users/:uuid/balances", balanceController.GetUserBalances)
+	rg.GET("/users/:uuid/net-with/:otherUuid", balanceController.GetPairwiseNet)
+	// Recompute stored balances from expenses and settlements
+	rg.POST("/groups/:uuid/balances/rebuild", balanceController.RebuildGroupBalances)
+	// Check stored balances against expenses and settlements
+	rg.GET("/groups/:uuid/balances/verify", balanceController.VerifyGroupBalances)
+}
+
+// setupInsightsRoutes configures insights-related routes
+func setupInsightsRoutes(rg *gin.RouterGroup, services *service.Services, logger *zap.Logger) {
+	insightsController := controller.NewInsightsController(services.Insights, logger)
+
+	// User spending insights
+	rg.GET("/users/:uuid/insights", insightsController.GetUserInsights)
+}
+
+// setupExportRoutes configures export-related routes
+func setupExportRoutes(rg *gin.RouterGroup, services *service.Services, logger *zap.Logger) {
+	exportController := controller.NewExportController(services.Export, logger)
+
+	// Group CSV export
+	rg.GET("/groups/:uuid/export", exportController.ExportGroup)
+}
+
+// setupReportRoutes configures spending report routes
+func setupReportRoutes(rg *gin.RouterGroup, services *service.Services, logger *zap.Logger) {
+	reportController := controller.NewReportController(services.Report, logger)
+
+	reports := rg.Group("/groups/:uuid/reports")
+	{
+		reports.GET("/categories", reportController.GetCategoryReport)
+	}
+
+	// User spending statistics across groups
+	rg.GET("/users/:uuid/stats", reportController.GetUserStats)
+}
+
+// setupRecurringExpenseRoutes configures recurring expense routes
+func setupRecurringExpenseRoutes(rg *gin.RouterGroup, services *service.Services, logger *zap.Logger) {
+	recurringController := controller.NewRecurringExpenseController(services.Recurring, logger)
+
+	recurring := rg.Group("/groups/:uuid/recurring-expenses")
+	{
+		recurring.POST("", recurringController.CreateRecurringExpense)
+		recurring.GET("", recurringController.ListRecurringExpenses)
+		recurring.GET("/:recurringUuid", recurringController.GetRecurringExpense)
+		recurring.PUT("/:recurringUuid", recurringController.UpdateRecurringExpense)
+		recurring.DELETE("/:recurringUuid", recurringController.DeleteRecurringExpense)
+	}
+}
+
+// setupWebhookRoutes configures webhook routes
+func setupWebhookRoutes(rg *gin.RouterGroup, services *service.Services, logger *zap.Logger) {
+	webhookController := controller.NewWebhookController(services.Webhook, logger)
+
+	webhooks := rg.Group("/groups/:uuid/webhooks")
+	{
+		webhooks.POST("", webhookController.CreateWebhook)
+		webhooks.GET("", webhookController.ListWebhooks)
+		webhooks.GET("/:webhookUuid", webhookController.GetWebhook)
+		webhooks.PUT("/:webhookUuid", webhookController.UpdateWebhook)
+		webhooks.DELETE("/:webhookUuid", webhookController.DeleteWebhook)
+		webhooks.GET("/:webhookUuid/dead-letters", webhookController.ListDeadLetters)
+		webhooks.POST("/:webhookUuid/dead-letters/:deliveryUuid/redeliver", webhookController.RedeliverDeadLetter)
+	}
 }
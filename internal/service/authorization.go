@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+
+	"expense-split-tracker/internal/auth"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+)
+
+// requireGroupMember checks that the caller attached to ctx belongs to the
+// group. Contexts without an identity (enforcement disabled, or internal
+// callers) are allowed through.
+func requireGroupMember(ctx context.Context, groupRepo repository.GroupRepository, groupID int64) error {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if identity.IsAnonymous() {
+		return errors.NewUnauthorizedError("X-User-UUID header is required to access group data")
+	}
+
+	isMember, err := groupRepo.IsMember(ctx, groupID, identity.UserID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return errors.NewForbiddenError("Only group members can access this group")
+	}
+
+	return nil
+}
+
+// actingUserID returns the ID of the caller attached to ctx for audit
+// columns such as deleted_by, or nil when the request carries no user
+func actingUserID(ctx context.Context) *int64 {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok || identity.IsAnonymous() {
+		return nil
+	}
+
+	userID := identity.UserID
+	return &userID
+}
+
+// requireListedGroupMember guards list endpoints that take an optional
+// group_uuid filter. With membership enforced the filter becomes mandatory,
+// since an unfiltered list would span groups the caller is not part of.
+func requireListedGroupMember(ctx context.Context, groupRepo repository.GroupRepository, groupUUID string) error {
+	if _, ok := auth.IdentityFromContext(ctx); !ok {
+		return nil
+	}
+	if groupUUID == "" {
+		return errors.NewForbiddenError("group_uuid is required to list group data")
+	}
+	if !utils.IsValidUUID(groupUUID) {
+		return errors.NewInvalidValueError("group_uuid", groupUUID)
+	}
+
+	group, err := groupRepo.GetByUUID(ctx, groupUUID)
+	if err != nil {
+		return err
+	}
+
+	return requireGroupMember(ctx, groupRepo, group.ID)
+}
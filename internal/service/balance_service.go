@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"sort"
 	"time"
 
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/fx"
+	"expense-split-tracker/internal/logging"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/repository"
 	"expense-split-tracker/internal/utils"
@@ -13,12 +17,28 @@ import (
 	"go.uber.org/zap"
 )
 
+// rebuildBatchSize is the number of expenses or settlements replayed per
+// repository batch while recomputing balances
+const rebuildBatchSize = 500
+
+// consistencyTolerance is how far a currency's stored balances may sum away
+// from the recomputed total before a verification reports it as inconsistent
+var consistencyTolerance = decimal.New(1, -2)
+
+// minCounterpartyAmount is the smallest pairwise debt listed as a counterparty;
+// anything below it is rounding left over from splits
+var minCounterpartyAmount = decimal.New(1, -2)
+
 type balanceService struct {
 	balanceRepo    repository.BalanceRepository
 	groupRepo      repository.GroupRepository
 	userRepo       repository.UserRepository
 	settlementRepo repository.SettlementRepository
+	expenseRepo    repository.ExpenseRepository
+	historyRepo    repository.BalanceHistoryRepository
+	groupLocks     GroupLockService
 	db             DBTransactor
+	rates          fx.ExchangeRateProvider
 	logger         *zap.Logger
 }
 
@@ -28,7 +48,11 @@ func NewBalanceService(
 	groupRepo repository.GroupRepository,
 	userRepo repository.UserRepository,
 	settlementRepo repository.SettlementRepository,
+	expenseRepo repository.ExpenseRepository,
+	historyRepo repository.BalanceHistoryRepository,
+	groupLocks GroupLockService,
 	db DBTransactor,
+	rates fx.ExchangeRateProvider,
 	logger *zap.Logger,
 ) BalanceService {
 	return &balanceService{
@@ -36,30 +60,164 @@ func NewBalanceService(
 		groupRepo:      groupRepo,
 		userRepo:       userRepo,
 		settlementRepo: settlementRepo,
+		expenseRepo:    expenseRepo,
+		historyRepo:    historyRepo,
+		groupLocks:     groupLocks,
 		db:             db,
+		rates:          rates,
 		logger:         logger,
 	}
 }
 
-// GetGroupBalanceSheet retrieves the complete balance sheet for a group
-func (s *balanceService) GetGroupBalanceSheet(ctx context.Context, groupUUID string) (*models.BalanceSheet, error) {
-	if !utils.IsValidUUID(groupUUID) {
-		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
+// GetGroupBalanceSheet retrieves the complete balance sheet for a group. With a
+// currency it covers only that currency; without one it has a section for
+// every currency the group has balances in. With convertTo every balance is
+// also converted into that currency and totalled per member.
+func (s *balanceService) GetGroupBalanceSheet(ctx context.Context, groupUUID models.GroupUUID, currency, convertTo string) (*models.BalanceSheet, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	convertTo, err := normalizeConvertTo(convertTo)
+	if err != nil {
+		return nil, err
 	}
 
-	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
 	if err != nil {
 		return nil, err
 	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	var balanceSheet *models.BalanceSheet
+	if currency == "" {
+		balanceSheet, err = s.getGroupBalanceSheetAllCurrencies(ctx, group)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		currency, err = resolveGroupCurrency(group, currency)
+		if err != nil {
+			return nil, err
+		}
+
+		balances, err := s.balanceRepo.GetGroupBalances(ctx, group.ID, currency)
+		if err != nil {
+			return nil, err
+		}
+
+		userBalances, summary := summarizeBalances(balances)
+
+		balanceSheet = &models.BalanceSheet{
+			Group:     group,
+			Balances:  userBalances,
+			Summary:   summary,
+			Currency:  currency,
+			UpdatedAt: time.Now(),
+		}
+	}
+
+	if convertTo != "" {
+		if err := s.convertBalanceSheet(ctx, balanceSheet, convertTo); err != nil {
+			return nil, err
+		}
+	}
+
+	return balanceSheet, nil
+}
+
+// convertBalanceSheet converts every balance of a sheet into convertTo and
+// totals each member's converted balances
+func (s *balanceService) convertBalanceSheet(ctx context.Context, sheet *models.BalanceSheet, convertTo string) error {
+	sections := sheet.Sections
+	if sheet.Currency != "" {
+		sections = []*models.CurrencyBalanceSection{{Currency: sheet.Currency, Balances: sheet.Balances}}
+	}
+
+	conversion := &models.BalanceConversion{
+		Currency: convertTo,
+		Rates:    []*models.ExchangeRate{},
+		Balances: []*models.ConvertedUserBalance{},
+	}
+	totals := make(map[int64]*models.ConvertedUserBalance)
+	for _, section := range sections {
+		rate, err := s.exchangeRate(ctx, section.Currency, convertTo)
+		if err != nil {
+			return err
+		}
+		section.ExchangeRate = rate
+		conversion.Rates = append(conversion.Rates, rate)
+
+		for _, balance := range section.Balances {
+			converted := fx.Convert(balance.Balance, rate)
+			balance.ConvertedBalance = &converted
+
+			total, ok := totals[balance.UserID]
+			if !ok {
+				total = &models.ConvertedUserBalance{UserID: balance.UserID, User: balance.User}
+				totals[balance.UserID] = total
+				conversion.Balances = append(conversion.Balances, total)
+			}
+			total.Balance = total.Balance.Add(converted)
+		}
+	}
+	sort.Slice(conversion.Balances, func(i, j int) bool { return conversion.Balances[i].UserID < conversion.Balances[j].UserID })
 
-	// Get all balances for the group (assuming USD for now)
-	currency := "USD"
-	balances, err := s.balanceRepo.GetGroupBalances(ctx, group.ID, currency)
+	sheet.Conversion = conversion
+	return nil
+}
+
+// exchangeRate looks up the rate from one currency into another
+func (s *balanceService) exchangeRate(ctx context.Context, from, to string) (*models.ExchangeRate, error) {
+	return lookupExchangeRate(ctx, s.rates, s.logger, from, to)
+}
+
+// normalizeConvertTo validates an optional convert_to currency
+func normalizeConvertTo(convertTo string) (string, error) {
+	if convertTo == "" {
+		return "", nil
+	}
+	if err := utils.ValidateCurrency(convertTo); err != nil {
+		return "", err
+	}
+	return utils.NormalizeCurrency(convertTo), nil
+}
+
+// getGroupBalanceSheetAllCurrencies builds a balance sheet with one section per currency
+func (s *balanceService) getGroupBalanceSheetAllCurrencies(ctx context.Context, group *models.Group) (*models.BalanceSheet, error) {
+	balances, err := s.balanceRepo.GetGroupBalancesAllCurrencies(ctx, group.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to UserBalance format
+	// Rows come ordered by currency
+	var sections []*models.CurrencyBalanceSection
+	for start := 0; start < len(balances); {
+		end := start
+		for end < len(balances) && balances[end].Currency == balances[start].Currency {
+			end++
+		}
+
+		userBalances, summary := summarizeBalances(balances[start:end])
+		sections = append(sections, &models.CurrencyBalanceSection{
+			Currency: balances[start].Currency,
+			Balances: userBalances,
+			Summary:  summary,
+		})
+		start = end
+	}
+
+	return &models.BalanceSheet{
+		Group:     group,
+		Sections:  sections,
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// summarizeBalances converts balances of a single currency to user balances and totals them
+func summarizeBalances(balances []*models.Balance) ([]*models.UserBalance, *models.BalanceSummary) {
 	var userBalances []*models.UserBalance
 	totalPositive := decimal.Zero
 	totalNegative := decimal.Zero
@@ -81,7 +239,6 @@ func (s *balanceService) GetGroupBalanceSheet(ctx context.Context, groupUUID str
 		}
 	}
 
-	// Create summary
 	summary := &models.BalanceSummary{
 		TotalPositive: totalPositive,
 		TotalNegative: totalNegative,
@@ -89,33 +246,28 @@ func (s *balanceService) GetGroupBalanceSheet(ctx context.Context, groupUUID str
 		UserCount:     len(userBalances),
 	}
 
-	balanceSheet := &models.BalanceSheet{
-		Group:     group,
-		Balances:  userBalances,
-		Summary:   summary,
-		Currency:  currency,
-		UpdatedAt: time.Now(),
-	}
-
-	return balanceSheet, nil
+	return userBalances, summary
 }
 
 // GetUserBalance retrieves detailed balance information for a user in a group
-func (s *balanceService) GetUserBalance(ctx context.Context, groupUUID, userUUID string) (*models.UserBalanceDetail, error) {
-	if !utils.IsValidUUID(groupUUID) {
-		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
+func (s *balanceService) GetUserBalance(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, currency string) (*models.UserBalanceDetail, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
 	}
 
-	if !utils.IsValidUUID(userUUID) {
-		return nil, errors.NewInvalidValueError("user_uuid", userUUID)
+	if !utils.IsValidUUID(userUUID.String()) {
+		return nil, errors.NewInvalidValueError("user_uuid", userUUID.String())
 	}
 
-	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
 	if err != nil {
 		return nil, err
 	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
 
-	user, err := s.userRepo.GetByUUID(ctx, userUUID)
+	user, err := s.userRepo.GetByUUID(ctx, userUUID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -129,17 +281,24 @@ func (s *balanceService) GetUserBalance(ctx context.Context, groupUUID, userUUID
 		return nil, errors.NewValidationError("User is not a member of this group")
 	}
 
+	currency, err = resolveGroupCurrency(group, currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get current balance
-	currency := "USD"
 	balance, err := s.balanceRepo.GetByGroupAndUser(ctx, group.ID, user.ID, currency)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get recent settlements for this user
+	// Pending and rejected settlements have not changed the balance
 	settlementFilter := &models.SettlementFilter{
-		GroupUUID: groupUUID,
-		UserUUID:  userUUID,
+		GroupUUID: groupUUID.String(),
+		UserUUID:  userUUID.String(),
+		Currency:  currency,
+		Status:    models.SettlementStatusConfirmed,
 		Page:      1,
 		Limit:     5, // Last 5 settlements
 	}
@@ -149,13 +308,36 @@ func (s *balanceService) GetUserBalance(ctx context.Context, groupUUID, userUUID
 		return nil, err
 	}
 
-	// Calculate breakdown (this is simplified - in a real system you'd query expenses and settlements)
+	// Pairwise debts the user is part of, owed in either direction
+	groupDebts, err := s.balanceRepo.GetGroupDebts(ctx, group.ID, currency)
+	if err != nil {
+		return nil, err
+	}
+	var debts []*models.DebtRelationship
+	for _, debt := range groupDebts {
+		if debt.Debtor.ID == user.ID || debt.Creditor.ID == user.ID {
+			debts = append(debts, debt)
+		}
+	}
+
+	expenseTotals, err := s.balanceRepo.GetUserExpenseTotals(ctx, group.ID, user.ID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	settlementTotals, err := s.balanceRepo.GetUserSettlementTotals(ctx, group.ID, user.ID, currency)
+	if err != nil {
+		return nil, err
+	}
+
 	breakdown := &models.BalanceBreakdown{
-		TotalPaid:    decimal.Zero, // TODO: Calculate from expenses where user is payer
-		TotalOwed:    balance.Balance.Abs(),
-		TotalSettled: decimal.Zero, // TODO: Calculate from settlements
-		ExpenseCount: 0,            // TODO: Count expenses involving this user
-		PaymentCount: len(settlements),
+		TotalPaid:       expenseTotals.Paid,
+		TotalOwed:       expenseTotals.Owed,
+		TotalSettled:    settlementTotals.Received.Sub(settlementTotals.Sent),
+		SettledSent:     settlementTotals.Sent,
+		SettledReceived: settlementTotals.Received,
+		ExpenseCount:    expenseTotals.Count,
+		PaymentCount:    settlementTotals.Count,
 	}
 
 	userBalanceDetail := &models.UserBalanceDetail{
@@ -164,67 +346,766 @@ func (s *balanceService) GetUserBalance(ctx context.Context, groupUUID, userUUID
 		Currency:     currency,
 		Breakdown:    breakdown,
 		Settlements:  settlements,
+		Debts:        debts,
 		LastActivity: balance.LastUpdated,
 	}
 
 	return userBalanceDetail, nil
 }
 
+// GetUserSummary sums a user's expenses and settlements in a group per
+// currency. Unlike GetUserBalance it works from the gross amounts; the net
+// they add up to is reported next to the stored balance, and a drift of more
+// than a cent between the two is logged.
+func (s *balanceService) GetUserSummary(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID) (*models.UserGroupSummary, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	if !utils.IsValidUUID(userUUID.String()) {
+		return nil, errors.NewInvalidValueError("user_uuid", userUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByUUID(ctx, userUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, group.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, errors.NewValidationError("User is not a member of this group")
+	}
+
+	expenseTotals, err := s.balanceRepo.GetUserExpenseTotalsByCurrency(ctx, group.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	settlementTotals, err := s.balanceRepo.GetUserSettlementTotalsByCurrency(ctx, group.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := s.balanceRepo.GetGroupBalancesAllCurrencies(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	byCurrency := make(map[string]*models.UserSummary)
+	summaryFor := func(currency string) *models.UserSummary {
+		summary, ok := byCurrency[currency]
+		if !ok {
+			summary = &models.UserSummary{Currency: currency}
+			byCurrency[currency] = summary
+		}
+		return summary
+	}
+	for _, totals := range expenseTotals {
+		summary := summaryFor(totals.Currency)
+		summary.TotalPaid = totals.Paid
+		summary.TotalOwed = totals.Owed
+		summary.ExpenseCount = totals.Count
+	}
+	for _, totals := range settlementTotals {
+		summary := summaryFor(totals.Currency)
+		summary.SettledSent = totals.Sent
+		summary.SettledReceived = totals.Received
+		summary.PaymentCount = totals.Count
+	}
+	for _, balance := range balances {
+		if balance.UserID == user.ID {
+			summaryFor(balance.Currency).StoredBalance = balance.Balance
+		}
+	}
+
+	result := &models.UserGroupSummary{User: user, Currencies: make([]*models.UserSummary, 0, len(byCurrency))}
+	for _, summary := range byCurrency {
+		// Shares raise what the user owes, paying and settling lower it
+		summary.NetBalance = summary.TotalOwed.Sub(summary.TotalPaid).
+			Sub(summary.SettledSent).Add(summary.SettledReceived)
+
+		if summary.NetBalance.Sub(summary.StoredBalance).Abs().GreaterThan(consistencyTolerance) {
+			logging.FromContext(ctx, s.logger).Warn("User summary disagrees with stored balance",
+				zap.String("groupUUID", group.UUID), zap.String("userUUID", user.UUID),
+				zap.String("currency", summary.Currency),
+				zap.String("net", summary.NetBalance.String()), zap.String("stored", summary.StoredBalance.String()))
+		}
+
+		result.Currencies = append(result.Currencies, summary)
+	}
+	sort.Slice(result.Currencies, func(i, j int) bool {
+		return result.Currencies[i].Currency < result.Currencies[j].Currency
+	})
+
+	return result, nil
+}
+
 // GetDebtRelationships retrieves debt relationships between users in a group
-func (s *balanceService) GetDebtRelationships(ctx context.Context, groupUUID string) ([]*models.DebtRelationship, error) {
-	if !utils.IsValidUUID(groupUUID) {
-		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
+func (s *balanceService) GetDebtRelationships(ctx context.Context, groupUUID models.GroupUUID, currency string) ([]*models.DebtRelationship, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	currency, err = resolveGroupCurrency(group, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pairwise debts record who owes whom directly: every expense adds each
+	// participant's split to what they owe the payer, settlements pay the pair
+	// down and opposing directions net out. Only people who actually shared an
+	// expense or a settlement can appear together.
+	return s.balanceRepo.GetGroupDebts(ctx, group.ID, currency)
+}
+
+// GetUserCounterparties lists who owes a user in a group and whom the user
+// owes, from the same pairwise debts as GetDebtRelationships. Both lists are
+// ordered by amount, largest first.
+func (s *balanceService) GetUserCounterparties(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, currency string) (*models.UserCounterparties, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	if !utils.IsValidUUID(userUUID.String()) {
+		return nil, errors.NewInvalidValueError("user_uuid", userUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByUUID(ctx, userUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, group.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, errors.NewValidationError("User is not a member of this group")
+	}
+
+	currency, err = resolveGroupCurrency(group, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	debts, err := s.balanceRepo.GetGroupDebts(ctx, group.ID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	counterparties := &models.UserCounterparties{
+		User:      user,
+		Currency:  currency,
+		Debtors:   []*models.Counterparty{},
+		Creditors: []*models.Counterparty{},
+	}
+	for _, debt := range debts {
+		if debt.Amount.LessThan(minCounterpartyAmount) {
+			continue
+		}
+		switch user.ID {
+		case debt.Creditor.ID:
+			counterparties.Debtors = append(counterparties.Debtors, &models.Counterparty{User: debt.Debtor, Amount: debt.Amount})
+		case debt.Debtor.ID:
+			counterparties.Creditors = append(counterparties.Creditors, &models.Counterparty{User: debt.Creditor, Amount: debt.Amount})
+		}
+	}
+	for _, list := range [][]*models.Counterparty{counterparties.Debtors, counterparties.Creditors} {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].Amount.GreaterThan(list[j].Amount)
+		})
+	}
+
+	return counterparties, nil
+}
+
+// GetBalanceHistory retrieves the changes to a user's balance in a group in the
+// order they happened, each with the balance it left behind. to covers the
+// whole day.
+func (s *balanceService) GetBalanceHistory(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, filter *models.BalanceHistoryFilter) ([]*models.BalanceHistoryEntry, int, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, 0, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	if !utils.IsValidUUID(userUUID.String()) {
+		return nil, 0, errors.NewInvalidValueError("user_uuid", userUUID.String())
+	}
+
+	if !filter.FromDate.IsZero() && !filter.ToDate.IsZero() && filter.ToDate.Before(filter.FromDate) {
+		return nil, 0, errors.NewValidationError("to must not be before from")
+	}
+
+	if filter.Currency != "" {
+		if err := utils.ValidateCurrency(filter.Currency); err != nil {
+			return nil, 0, err
+		}
+		filter.Currency = utils.NormalizeCurrency(filter.Currency)
 	}
 
-	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, 0, err
+	}
+
+	user, err := s.userRepo.GetByUUID(ctx, userUUID.String())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, group.ID, user.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !isMember {
+		return nil, 0, errors.NewValidationError("User is not a member of this group")
+	}
+
+	query := *filter
+	query.GroupID = group.ID
+	query.UserID = user.ID
+	if !query.ToDate.IsZero() {
+		query.ToDate = query.ToDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	return s.historyRepo.ListForUser(ctx, &query)
+}
+
+// GetUserBalances retrieves a user's balance in every group they belong to,
+// grouped by currency with the net across groups. A currency narrows it down
+// to that currency only.
+func (s *balanceService) GetUserBalances(ctx context.Context, userUUID models.UserUUID, currency, convertTo string) (*models.UserBalanceOverview, error) {
+	if !utils.IsValidUUID(userUUID.String()) {
+		return nil, errors.NewInvalidValueError("user_uuid", userUUID.String())
+	}
+
+	convertTo, err := normalizeConvertTo(convertTo)
+	if err != nil {
+		return nil, err
+	}
+
+	if currency != "" {
+		if err := utils.ValidateCurrency(currency); err != nil {
+			return nil, err
+		}
+		currency = utils.NormalizeCurrency(currency)
+	}
+
+	user, err := s.userRepo.GetByUUID(ctx, userUUID.String())
 	if err != nil {
 		return nil, err
 	}
 
-	// Get all balances for the group
-	currency := "USD"
-	balances, err := s.balanceRepo.GetGroupBalances(ctx, group.ID, currency)
+	balances, err := s.balanceRepo.GetUserBalances(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Separate creditors and debtors
-	var creditors, debtors []*models.Balance
+	positions := make(map[string]*models.UserCurrencyPosition)
+	overview := &models.UserBalanceOverview{User: user, Currencies: []*models.UserCurrencyPosition{}}
 	for _, balance := range balances {
-		if balance.Balance.GreaterThan(decimal.Zero) {
-			debtors = append(debtors, balance)
-		} else if balance.Balance.LessThan(decimal.Zero) {
-			creditors = append(creditors, balance)
+		if currency != "" && balance.Currency != currency {
+			continue
+		}
+		position := positions[balance.Currency]
+		if position == nil {
+			position = &models.UserCurrencyPosition{Currency: balance.Currency, NetBalance: decimal.Zero}
+			positions[balance.Currency] = position
+			overview.Currencies = append(overview.Currencies, position)
+		}
+		position.Balances = append(position.Balances, balance)
+		position.NetBalance = position.NetBalance.Add(balance.Balance)
+	}
+	sort.Slice(overview.Currencies, func(i, j int) bool {
+		return overview.Currencies[i].Currency < overview.Currencies[j].Currency
+	})
+
+	if convertTo != "" {
+		net := decimal.Zero
+		conversion := &models.BalanceConversion{Currency: convertTo, Rates: []*models.ExchangeRate{}}
+		for _, position := range overview.Currencies {
+			rate, err := s.exchangeRate(ctx, position.Currency, convertTo)
+			if err != nil {
+				return nil, err
+			}
+			converted := fx.Convert(position.NetBalance, rate)
+			position.ConvertedNetBalance = &converted
+			position.ExchangeRate = rate
+			conversion.Rates = append(conversion.Rates, rate)
+			net = net.Add(converted)
+		}
+		conversion.NetBalance = &net
+		overview.Conversion = conversion
+	}
+
+	return overview, nil
+}
+
+// GetPairwiseNet nets the pairwise debts between two users across every group
+// they share, per currency, so that debts running both ways in different
+// groups come down to one amount. Groups where the two are square are left
+// out of the detail. A currency narrows it down to that currency only.
+func (s *balanceService) GetPairwiseNet(ctx context.Context, userUUID, otherUserUUID models.UserUUID, currency string) (*models.PairwiseNet, error) {
+	if !utils.IsValidUUID(userUUID.String()) {
+		return nil, errors.NewInvalidValueError("user_uuid", userUUID.String())
+	}
+	if !utils.IsValidUUID(otherUserUUID.String()) {
+		return nil, errors.NewInvalidValueError("other_user_uuid", otherUserUUID.String())
+	}
+	if userUUID == otherUserUUID {
+		return nil, errors.NewValidationError("Cannot net a user's debts with themselves")
+	}
+
+	if currency != "" {
+		if err := utils.ValidateCurrency(currency); err != nil {
+			return nil, err
+		}
+		currency = utils.NormalizeCurrency(currency)
+	}
+
+	user, err := s.userRepo.GetByUUID(ctx, userUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	otherUser, err := s.userRepo.GetByUUID(ctx, otherUserUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.groupRepo.GetSharedGroups(ctx, user.ID, otherUser.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make(map[string]*models.PairwiseCurrencyNet)
+	net := &models.PairwiseNet{User: user, OtherUser: otherUser, Currencies: []*models.PairwiseCurrencyNet{}}
+	for _, group := range groups {
+		debts, err := s.balanceRepo.GetPairDebts(ctx, group.ID, user.ID, otherUser.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, debt := range debts {
+			if debt.Amount.IsZero() || (currency != "" && debt.Currency != currency) {
+				continue
+			}
+			section := sections[debt.Currency]
+			if section == nil {
+				section = &models.PairwiseCurrencyNet{Currency: debt.Currency, Amount: decimal.Zero}
+				sections[debt.Currency] = section
+				net.Currencies = append(net.Currencies, section)
+			}
+			// Amount holds the signed net until every group is added
+			section.Amount = section.Amount.Add(debt.Amount)
+			section.Groups = append(section.Groups, &models.PairwiseGroupDebt{
+				Group:     group,
+				Direction: models.NetDirectionOf(debt.Amount),
+				Amount:    debt.Amount.Abs(),
+			})
+		}
+	}
+
+	for _, section := range net.Currencies {
+		section.Direction = models.NetDirectionOf(section.Amount)
+		section.Amount = section.Amount.Abs()
+	}
+	sort.Slice(net.Currencies, func(i, j int) bool {
+		return net.Currencies[i].Currency < net.Currencies[j].Currency
+	})
+
+	return net, nil
+}
+
+// balanceKey identifies one balance row of a group
+type balanceKey struct {
+	userID   int64
+	currency string
+}
+
+// debtKey identifies one pairwise debt row of a group, lower user ID first as
+// user_debts stores it
+type debtKey struct {
+	userA    int64
+	userB    int64
+	currency string
+}
+
+// newDebtKey returns the row for a debt from debtor to creditor along with the
+// amount as stored on it: what userA owes userB
+func newDebtKey(debtorID, creditorID int64, currency string, amount decimal.Decimal) (debtKey, decimal.Decimal) {
+	if debtorID < creditorID {
+		return debtKey{userA: debtorID, userB: creditorID, currency: currency}, amount
+	}
+	return debtKey{userA: creditorID, userB: debtorID, currency: currency}, amount.Neg()
+}
+
+// groupReplay is a group's balances and pairwise debts recomputed from its
+// history, along with the users it came across
+type groupReplay struct {
+	balances map[balanceKey]decimal.Decimal
+	debts    map[debtKey]decimal.Decimal
+	users    map[int64]*models.User
+}
+
+// RebuildGroupBalances recomputes a group's balances and pairwise debts by
+// replaying every expense split and confirmed settlement, replacing the stored
+// rows in one transaction. Each balance that moves gets a rebuild entry in the
+// balance history. The group is locked for reconciliation meanwhile so no new
+// expenses or settlements land halfway through.
+func (s *balanceService) RebuildGroupBalances(ctx context.Context, groupUUID models.GroupUUID) (*models.BalanceRebuild, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	lock, err := s.groupLocks.Acquire(ctx, group.ID, models.GroupLockReconcile)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := s.groupLocks.Release(ctx, lock); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to release group lock", zap.Error(err), zap.Int64("groupID", group.ID))
+		}
+	}()
+
+	previousBalances, err := s.balanceRepo.GetGroupBalancesAllCurrencies(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	previousDebts, err := s.balanceRepo.GetGroupDebtsAllCurrencies(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	replay, err := s.replayGroupBalances(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	users := replay.users
+
+	// Rows whose stored value has nothing left to back it end up at zero
+	storedBalances := make(map[balanceKey]decimal.Decimal, len(previousBalances))
+	for _, balance := range previousBalances {
+		key := balanceKey{userID: balance.UserID, currency: balance.Currency}
+		storedBalances[key] = balance.Balance
+		if _, ok := replay.balances[key]; !ok {
+			replay.balances[key] = decimal.Zero
+		}
+		if balance.User != nil && users[balance.UserID] == nil {
+			users[balance.UserID] = balance.User
+		}
+	}
+	storedDebts := make(map[debtKey]decimal.Decimal, len(previousDebts))
+	for _, debt := range previousDebts {
+		key, amount := newDebtKey(debt.Debtor.ID, debt.Creditor.ID, debt.Currency, debt.Amount)
+		storedDebts[key] = amount
+		if _, ok := replay.debts[key]; !ok {
+			replay.debts[key] = decimal.Zero
+		}
+		for _, user := range []*models.User{debt.Debtor, debt.Creditor} {
+			if user.UUID != "" && users[user.ID] == nil {
+				users[user.ID] = user
+			}
+		}
+	}
+
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		if err := s.balanceRepo.ZeroGroupBalances(ctx, tx, group.ID); err != nil {
+			return err
+		}
+		for key, amount := range replay.balances {
+			if err := s.balanceRepo.Upsert(ctx, tx, &models.Balance{GroupID: group.ID, UserID: key.userID, Balance: amount, Currency: key.currency}); err != nil {
+				return err
+			}
+			// Recorded after the upsert so the entry carries the rebuilt balance
+			delta := amount.Sub(storedBalances[key])
+			if delta.IsZero() {
+				continue
+			}
+			err := s.historyRepo.Record(ctx, tx, &models.BalanceHistoryEntry{
+				GroupID: group.ID, UserID: key.userID, Currency: key.currency, Delta: delta,
+				SourceType: models.BalanceHistorySourceRebuild, SourceID: group.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := s.balanceRepo.ZeroGroupDebts(ctx, tx, group.ID); err != nil {
+			return err
+		}
+		for key, amount := range replay.debts {
+			if amount.IsZero() {
+				continue
+			}
+			if err := s.balanceRepo.UpdateDebt(ctx, tx, group.ID, key.userA, key.userB, amount, key.currency); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to rebuild group balances", zap.Error(err), zap.Int64("groupID", group.ID))
+		return nil, err
+	}
+
+	userByID := func(id int64) (*models.User, error) {
+		if user := users[id]; user != nil {
+			return user, nil
+		}
+		user, err := s.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		users[id] = user
+		return user, nil
+	}
+
+	rebuild := &models.BalanceRebuild{
+		Group:           group,
+		Adjustments:     make([]*models.BalanceAdjustment, 0, len(replay.balances)),
+		DebtAdjustments: make([]*models.DebtAdjustment, 0, len(replay.debts)),
+		RebuiltAt:       time.Now(),
+	}
+	for key, amount := range replay.balances {
+		user, err := userByID(key.userID)
+		if err != nil {
+			return nil, err
+		}
+
+		adjustment := &models.BalanceAdjustment{
+			User:       user,
+			Currency:   key.currency,
+			Previous:   storedBalances[key],
+			Recomputed: amount,
+			Delta:      amount.Sub(storedBalances[key]),
+		}
+		if !adjustment.Delta.IsZero() {
+			rebuild.DriftedCount++
+		}
+		rebuild.Adjustments = append(rebuild.Adjustments, adjustment)
+	}
+	sort.Slice(rebuild.Adjustments, func(i, j int) bool {
+		a, b := rebuild.Adjustments[i], rebuild.Adjustments[j]
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.User.ID < b.User.ID
+	})
+
+	for key, amount := range replay.debts {
+		previous := storedDebts[key]
+		if amount.IsZero() && previous.IsZero() {
+			continue
+		}
+
+		userA, err := userByID(key.userA)
+		if err != nil {
+			return nil, err
+		}
+		userB, err := userByID(key.userB)
+		if err != nil {
+			return nil, err
+		}
+
+		adjustment := &models.DebtAdjustment{Debtor: userA, Creditor: userB, Currency: key.currency, Previous: previous, Recomputed: amount}
+		if amount.IsNegative() || (amount.IsZero() && previous.IsNegative()) {
+			adjustment.Debtor, adjustment.Creditor = userB, userA
+			adjustment.Previous, adjustment.Recomputed = previous.Neg(), amount.Neg()
 		}
+		adjustment.Delta = adjustment.Recomputed.Sub(adjustment.Previous)
+		if !adjustment.Delta.IsZero() {
+			rebuild.DriftedDebtCount++
+		}
+		rebuild.DebtAdjustments = append(rebuild.DebtAdjustments, adjustment)
+	}
+	sort.Slice(rebuild.DebtAdjustments, func(i, j int) bool {
+		a, b := rebuild.DebtAdjustments[i], rebuild.DebtAdjustments[j]
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		if a.Debtor.ID != b.Debtor.ID {
+			return a.Debtor.ID < b.Debtor.ID
+		}
+		return a.Creditor.ID < b.Creditor.ID
+	})
+
+	logging.FromContext(ctx, s.logger).Info("Group balances rebuilt", zap.String("groupUUID", groupUUID.String()),
+		zap.Int("drifted", rebuild.DriftedCount), zap.Int("driftedDebts", rebuild.DriftedDebtCount))
+	return rebuild, nil
+}
+
+// VerifyGroupBalances checks per currency that a group's stored balances add
+// up to the same total as balances recomputed from its history. Stored
+// balances are only read, so the report reflects the group as it is.
+func (s *balanceService) VerifyGroupBalances(ctx context.Context, groupUUID models.GroupUUID) (*models.BalanceVerification, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.balanceRepo.GetGroupBalancesAllCurrencies(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	replay, err := s.replayGroupBalances(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make(map[string]*models.CurrencyBalanceCheck)
+	check := func(currency string) *models.CurrencyBalanceCheck {
+		if checks[currency] == nil {
+			checks[currency] = &models.CurrencyBalanceCheck{Currency: currency}
+		}
+		return checks[currency]
+	}
+	for _, balance := range stored {
+		c := check(balance.Currency)
+		c.StoredSum = c.StoredSum.Add(balance.Balance)
+	}
+	for key, amount := range replay.balances {
+		c := check(key.currency)
+		c.RecomputedSum = c.RecomputedSum.Add(amount)
+	}
+
+	verification := &models.BalanceVerification{
+		Group:      group,
+		Currencies: make([]*models.CurrencyBalanceCheck, 0, len(checks)),
+		Consistent: true,
+		VerifiedAt: time.Now(),
+	}
+	for _, c := range checks {
+		c.Drift = c.StoredSum.Sub(c.RecomputedSum)
+		c.Consistent = c.Drift.Abs().LessThanOrEqual(consistencyTolerance) && c.StoredSum.Abs().LessThanOrEqual(consistencyTolerance)
+		if !c.Consistent {
+			verification.Consistent = false
+			logging.FromContext(ctx, s.logger).Error("Group balances are inconsistent",
+				zap.String("groupUUID", groupUUID.String()),
+				zap.String("currency", c.Currency),
+				zap.String("storedSum", c.StoredSum.String()),
+				zap.String("recomputedSum", c.RecomputedSum.String()))
+		}
+		verification.Currencies = append(verification.Currencies, c)
 	}
+	sort.Slice(verification.Currencies, func(i, j int) bool {
+		return verification.Currencies[i].Currency < verification.Currencies[j].Currency
+	})
 
-	// Create debt relationships
-	var relationships []*models.DebtRelationship
+	return verification, nil
+}
 
-	// Simple approach: each debtor owes proportionally to each creditor
-	for _, debtor := range debtors {
-		totalCredit := decimal.Zero
-		for _, creditor := range creditors {
-			totalCredit = totalCredit.Add(creditor.Balance.Abs())
+// replayGroupBalances recomputes every balance and pairwise debt of a group
+// from its expense splits and confirmed settlements
+func (s *balanceService) replayGroupBalances(ctx context.Context, groupID int64) (*groupReplay, error) {
+	replay := &groupReplay{
+		balances: make(map[balanceKey]decimal.Decimal),
+		debts:    make(map[debtKey]decimal.Decimal),
+		users:    make(map[int64]*models.User),
+	}
+	add := func(userID int64, user *models.User, currency string, amount decimal.Decimal) {
+		key := balanceKey{userID: userID, currency: currency}
+		replay.balances[key] = replay.balances[key].Add(amount)
+		if user != nil && replay.users[userID] == nil {
+			replay.users[userID] = user
 		}
+	}
+	owe := func(debtorID, creditorID int64, currency string, amount decimal.Decimal) {
+		key, signed := newDebtKey(debtorID, creditorID, currency, amount)
+		replay.debts[key] = replay.debts[key].Add(signed)
+	}
 
-		if totalCredit.GreaterThan(decimal.Zero) {
-			for _, creditor := range creditors {
-				// Calculate proportional debt
-				proportion := creditor.Balance.Abs().Div(totalCredit)
-				debtAmount := debtor.Balance.Mul(proportion).Round(2)
+	// Splits raise what each participant owes and the payer is credited the
+	// total, both in the base currency the expense was booked in. Everyone but
+	// the payer owes the payer their share.
+	err := s.expenseRepo.IterateGroupExpenses(ctx, groupID, time.Time{}, time.Time{}, rebuildBatchSize, func(expenses []*models.Expense) error {
+		ids := make([]int64, len(expenses))
+		for i, expense := range expenses {
+			ids[i] = expense.ID
+		}
+		splitsByExpense, err := s.expenseRepo.GetSplitsForExpenses(ctx, ids)
+		if err != nil {
+			return err
+		}
 
-				if debtAmount.GreaterThan(decimal.NewFromFloat(0.01)) { // Only include debts > 1 cent
-					relationships = append(relationships, &models.DebtRelationship{
-						Creditor: creditor.User,
-						Debtor:   debtor.User,
-						Amount:   debtAmount,
-						Currency: currency,
-					})
+		for _, expense := range expenses {
+			for _, split := range splitsByExpense[expense.ID] {
+				add(split.UserID, split.User, expense.BaseCurrency, split.Amount)
+				if split.UserID != expense.PaidBy {
+					owe(split.UserID, expense.PaidBy, expense.BaseCurrency, split.Amount)
 				}
 			}
+			add(expense.PaidBy, expense.Payer, expense.BaseCurrency, expense.BaseAmount.Neg())
 		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to replay group expenses", zap.Error(err), zap.Int64("groupID", groupID))
+		return nil, err
 	}
 
-	return relationships, nil
+	err = s.settlementRepo.IterateGroupSettlements(ctx, groupID, rebuildBatchSize, func(settlements []*models.Settlement) error {
+		for _, settlement := range settlements {
+			add(settlement.FromUserID, settlement.FromUser, settlement.Currency, settlement.Amount.Neg())
+			add(settlement.ToUserID, settlement.ToUser, settlement.Currency, settlement.Amount)
+			owe(settlement.FromUserID, settlement.ToUserID, settlement.Currency, settlement.Amount.Neg())
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to replay group settlements", zap.Error(err), zap.Int64("groupID", groupID))
+		return nil, err
+	}
+
+	return replay, nil
+}
+
+// adjustBalance adds entry.Delta to a user's balance and records the change in
+// the balance history, both within tx
+func adjustBalance(ctx context.Context, tx *database.Tx, balanceRepo repository.BalanceRepository, historyRepo repository.BalanceHistoryRepository, entry *models.BalanceHistoryEntry) error {
+	if err := balanceRepo.UpdateBalance(ctx, tx, entry.GroupID, entry.UserID, entry.Delta, entry.Currency); err != nil {
+		return err
+	}
+	return historyRepo.Record(ctx, tx, entry)
 }
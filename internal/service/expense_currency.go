@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"expense-split-tracker/internal/fx"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// lookupExchangeRate looks up the rate from one currency into another; without
+// a configured provider only same-currency conversions succeed
+func lookupExchangeRate(ctx context.Context, rates fx.ExchangeRateProvider, logger *zap.Logger, from, to string) (*models.ExchangeRate, error) {
+	if rates == nil {
+		if from == to {
+			return &models.ExchangeRate{From: from, To: to, Rate: decimal.NewFromInt(1), AsOf: time.Now().UTC()}, nil
+		}
+		return nil, errors.NewExchangeRateUnavailableError(from, to)
+	}
+
+	rate, err := rates.Rate(ctx, from, to)
+	if err != nil {
+		logging.FromContext(ctx, logger).Warn("Exchange rate lookup failed", zap.Error(err), zap.String("from", from), zap.String("to", to))
+		return nil, err
+	}
+	return rate, nil
+}
+
+// resolveExchangeRate returns the rate converting an expense in currency into
+// baseCurrency: 1 when they match, otherwise the explicit rate of the request
+// or the provider's current rate
+func (s *expenseService) resolveExchangeRate(ctx context.Context, currency, baseCurrency string, explicit *decimal.Decimal) (decimal.Decimal, error) {
+	one := decimal.NewFromInt(1)
+
+	if explicit != nil {
+		if err := utils.ValidateExchangeRate(*explicit); err != nil {
+			return decimal.Zero, err
+		}
+		if currency == baseCurrency && !explicit.Equal(one) {
+			rateErr := errors.NewValidationError("exchange_rate only applies to expenses in a currency other than the group's")
+			rateErr.Details = map[string]string{"field": "exchange_rate", "currency": currency}
+			return decimal.Zero, rateErr
+		}
+		return *explicit, nil
+	}
+
+	if currency == baseCurrency {
+		return one, nil
+	}
+
+	rate, err := lookupExchangeRate(ctx, s.rates, s.logger, currency, baseCurrency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return rate.Rate.Round(fx.RatePlaces), nil
+}
+
+// convertToBase sets the expense's base amount from its amount and exchange
+// rate and converts the splits, calculated in the expense's own currency, into
+// the base currency. Rounding each split separately can drift from the base
+// amount; the largest split absorbs the difference so balances reconcile.
+func convertToBase(expense *models.Expense, splits []*models.ExpenseSplit) {
+	rate := &models.ExchangeRate{From: expense.Currency, To: expense.BaseCurrency, Rate: expense.ExchangeRate}
+	expense.BaseAmount = fx.Convert(expense.Amount, rate)
+	if expense.Currency == expense.BaseCurrency || len(splits) == 0 {
+		return
+	}
+
+	total := decimal.Zero
+	largest := splits[0]
+	for _, split := range splits {
+		split.Amount = fx.Convert(split.Amount, rate)
+		total = total.Add(split.Amount)
+		if split.Amount.Abs().GreaterThan(largest.Amount.Abs()) {
+			largest = split
+		}
+	}
+	largest.Amount = largest.Amount.Add(expense.BaseAmount.Sub(total))
+}
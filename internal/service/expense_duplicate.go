@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// DuplicateExpense creates a new expense with the payer, participants and
+// split type of an existing one. The copy goes through CreateExpense, so it is
+// validated against the group as it is now, at today's exchange rate. Exact
+// and itemized splits have fixed amounts and cannot take a new total.
+func (s *expenseService) DuplicateExpense(ctx context.Context, uuid string, req *models.DuplicateExpenseRequest) (*models.Expense, error) {
+	original, err := s.GetExpenseByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if original.Group == nil || original.Payer == nil {
+		return nil, errors.NewInternalError("Expense group or payer could not be loaded")
+	}
+
+	// Refunds are stored negated but created from positive amounts
+	createReq := &models.CreateExpenseRequest{
+		GroupUUID:   original.Group.UUID,
+		PaidByUUID:  original.Payer.UUID,
+		Amount:      original.Amount.Abs(),
+		Currency:    original.Currency,
+		Description: original.Description,
+		Category:    original.Category,
+		ExpenseDate: req.ExpenseDate,
+		SplitType:   original.SplitType,
+		IsRefund:    original.IsRefund,
+	}
+
+	// Split amounts are stored converted, so exact splits of a converted
+	// expense are copied in the base currency they were converted into
+	if original.SplitType == models.SplitTypeExact && len(original.Items) == 0 && original.BaseCurrency != original.Currency {
+		createReq.Amount = original.BaseAmount.Abs()
+		createReq.Currency = original.BaseCurrency
+	}
+
+	if req.Amount != nil && !req.Amount.Equal(createReq.Amount) {
+		if original.SplitType == models.SplitTypeExact {
+			return nil, errors.NewInvalidSplitError("The amount of an expense with exact or itemized splits cannot be changed when duplicating it")
+		}
+		createReq.Amount = *req.Amount
+	}
+	if description := strings.TrimSpace(req.Description); description != "" {
+		createReq.Description = description
+	}
+
+	if len(original.Items) > 0 {
+		createReq.Items = duplicateItems(original.Items)
+	} else {
+		createReq.Splits = duplicateSplits(original)
+	}
+
+	expense, err := s.CreateExpense(ctx, createReq)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Expense duplicated", zap.String("from", uuid), zap.String("uuid", expense.UUID))
+	return expense, nil
+}
+
+// duplicateSplits rebuilds the split requests of an expense for its split type
+func duplicateSplits(expense *models.Expense) []models.CreateExpenseSplitRequest {
+	splits := make([]models.CreateExpenseSplitRequest, 0, len(expense.Splits))
+	for _, split := range expense.Splits {
+		if split.User == nil {
+			continue
+		}
+
+		splitReq := models.CreateExpenseSplitRequest{UserUUID: split.User.UUID}
+		switch expense.SplitType {
+		case models.SplitTypeExact:
+			splitReq.Amount = split.Amount.Abs()
+		case models.SplitTypePercentage:
+			splitReq.Percentage = split.Percentage.Abs()
+		case models.SplitTypeShares:
+			splitReq.Shares = split.Shares
+		}
+		splits = append(splits, splitReq)
+	}
+	return splits
+}
+
+// duplicateItems rebuilds the line item requests of an itemized expense
+func duplicateItems(items []*models.ExpenseItem) []models.ExpenseItemRequest {
+	requests := make([]models.ExpenseItemRequest, 0, len(items))
+	for _, item := range items {
+		itemReq := models.ExpenseItemRequest{Description: item.Description, Amount: item.Amount.Abs()}
+		for _, share := range item.Shares {
+			if share.User != nil {
+				itemReq.UserUUIDs = append(itemReq.UserUUIDs, share.User.UUID)
+			}
+		}
+		requests = append(requests, itemReq)
+	}
+	return requests
+}
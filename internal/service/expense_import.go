@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// maxImportRows caps the number of data rows accepted in one CSV import
+const maxImportRows = 1000
+
+// expenseImportColumns lists the columns every import file must have; a
+// "category" column is optional
+var expenseImportColumns = []string{"date", "description", "amount", "currency", "paid_by_email", "split_type", "participants"}
+
+// preparedImportRow is a validated CSV row waiting to be written
+type preparedImportRow struct {
+	expense *models.Expense
+	splits  []*models.ExpenseSplit
+}
+
+// ImportExpenses creates expenses in a group from a CSV file. Invalid rows are
+// reported and skipped; the valid rows are written in a single transaction.
+// With dryRun set nothing is written.
+func (s *expenseService) ImportExpenses(ctx context.Context, groupUUID string, file io.Reader, dryRun bool) (*models.ExpenseImportResult, error) {
+	if !utils.IsValidUUID(groupUUID) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.NewValidationError("CSV file is empty or not valid CSV")
+	}
+	columns, err := importColumnIndex(header)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ExpenseImportResult{DryRun: dryRun, Errors: []models.ExpenseImportRowError{}}
+	users := make(map[string]*models.User)
+	var prepared []preparedImportRow
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row, _ := reader.FieldPos(0)
+		if err != nil {
+			result.Errors = append(result.Errors, models.ExpenseImportRowError{Row: row, Code: errors.ErrCodeInvalid, Message: "Malformed CSV row"})
+			result.TotalRows++
+			continue
+		}
+
+		result.TotalRows++
+		if result.TotalRows > maxImportRows {
+			return nil, errors.NewValidationError(fmt.Sprintf("CSV import is limited to %d rows", maxImportRows))
+		}
+
+		expense, splits, err := s.prepareImportRow(ctx, group, record, columns, users)
+		if err != nil {
+			appErr, ok := err.(*errors.AppError)
+			if !ok || appErr.Code == errors.ErrCodeDatabase || appErr.Code == errors.ErrCodeInternal {
+				return nil, err
+			}
+			result.Errors = append(result.Errors, models.ExpenseImportRowError{Row: row, Code: appErr.Code, Message: appErr.Message})
+			continue
+		}
+		prepared = append(prepared, preparedImportRow{expense: expense, splits: splits})
+	}
+
+	result.Valid = len(prepared)
+	result.Skipped = len(result.Errors)
+	if dryRun || len(prepared) == 0 {
+		return result, nil
+	}
+
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+
+		if err := ensureGroupUnlocked(ctx, s.lockRepo, tx, group.ID); err != nil {
+			return err
+		}
+
+		for _, row := range prepared {
+			if err := s.insertExpense(ctx, tx, row.expense, row.splits, &batch); err != nil {
+				return err
+			}
+		}
+		return batch.Record(ctx, tx, s.emitter)
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to import expenses", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return nil, err
+	}
+
+	batch.Emit(ctx, s.emitter)
+	s.metrics.ExpensesCreated(len(prepared))
+
+	result.Imported = len(prepared)
+	logging.FromContext(ctx, s.logger).Info("Expenses imported", zap.String("groupUUID", groupUUID),
+		zap.Int("imported", result.Imported), zap.Int("skipped", result.Skipped))
+	return result, nil
+}
+
+// importColumnIndex maps the header row onto column positions
+func importColumnIndex(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	var missing []string
+	for _, name := range expenseImportColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		appErr := errors.NewValidationError("CSV header is missing required columns")
+		appErr.Details = map[string]string{"missing": strings.Join(missing, ",")}
+		return nil, appErr
+	}
+
+	return columns, nil
+}
+
+// prepareImportRow turns a CSV row into a validated expense and its splits
+func (s *expenseService) prepareImportRow(ctx context.Context, group *models.Group, record []string, columns map[string]int, users map[string]*models.User) (*models.Expense, []*models.ExpenseSplit, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	req := &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		Description: field("description"),
+		Currency:    strings.ToUpper(field("currency")),
+		Category:    field("category"),
+		SplitType:   models.SplitType(strings.ToLower(field("split_type"))),
+	}
+	if req.SplitType == "" {
+		req.SplitType = models.SplitTypeEqual
+	}
+
+	// Dates are calendar days in the group's timezone
+	if date := field("date"); date != "" {
+		expenseDate, err := time.ParseInLocation("2006-01-02", date, group.Location())
+		if err != nil {
+			return nil, nil, errors.NewInvalidValueError("date", date)
+		}
+		req.ExpenseDate = expenseDate
+	}
+
+	amount, err := decimal.NewFromString(field("amount"))
+	if err != nil {
+		return nil, nil, errors.NewInvalidValueError("amount", field("amount"))
+	}
+	req.Amount = amount
+
+	payer, err := s.importUser(ctx, field("paid_by_email"), users)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.PaidByUUID = payer.UUID
+
+	splits, err := s.importSplits(ctx, field("participants"), req.SplitType, users)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Splits = splits
+
+	return s.prepareExpense(ctx, req)
+}
+
+// importSplits parses the participants column: a ";"-separated list of
+// emails, each followed by ":value" for exact amounts, percentages or shares.
+// An empty list splits an equal expense across every member.
+func (s *expenseService) importSplits(ctx context.Context, participants string, splitType models.SplitType, users map[string]*models.User) ([]models.CreateExpenseSplitRequest, error) {
+	var splits []models.CreateExpenseSplitRequest
+	for _, entry := range strings.Split(participants, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		email, value, hasValue := strings.Cut(entry, ":")
+		user, err := s.importUser(ctx, email, users)
+		if err != nil {
+			return nil, err
+		}
+		split := models.CreateExpenseSplitRequest{UserUUID: user.UUID}
+
+		if splitType == models.SplitTypeEqual {
+			splits = append(splits, split)
+			continue
+		}
+		if !hasValue {
+			return nil, errors.NewInvalidSplitError(fmt.Sprintf("Participant '%s' needs a value for %s splits", strings.TrimSpace(email), splitType))
+		}
+
+		value = strings.TrimSpace(value)
+		switch splitType {
+		case models.SplitTypeShares:
+			shares, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.NewInvalidValueError("participants", entry)
+			}
+			split.Shares = shares
+		default:
+			number, err := decimal.NewFromString(value)
+			if err != nil {
+				return nil, errors.NewInvalidValueError("participants", entry)
+			}
+			if splitType == models.SplitTypePercentage {
+				split.Percentage = number
+			} else {
+				split.Amount = number
+			}
+		}
+		splits = append(splits, split)
+	}
+
+	return splits, nil
+}
+
+// importUser resolves an email to a user, caching lookups for the file
+func (s *expenseService) importUser(ctx context.Context, email string, users map[string]*models.User) (*models.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, errors.NewRequiredFieldError("email")
+	}
+	if user, ok := users[email]; ok {
+		return user, nil
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrCodeNotFound {
+			return nil, &errors.AppError{
+				Code:    errors.ErrCodeNotFound,
+				Message: fmt.Sprintf("Unknown email '%s'", email),
+				Status:  appErr.Status,
+			}
+		}
+		return nil, err
+	}
+
+	users[email] = user
+	return user, nil
+}
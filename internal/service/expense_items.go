@@ -0,0 +1,108 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// itemizeSplits turns the line items of a request into exact splits. Each
+// item's amount is divided evenly among its users in the minor units of
+// currency, with leftover units going to the first users listed, and every
+// user's split is the sum of their item shares, so the splits always add up to
+// the item total. Shares reference users by UUID only until resolveItemUsers
+// fills in the IDs.
+func itemizeSplits(req *models.CreateExpenseRequest, currency string) ([]*models.ExpenseItem, []models.CreateExpenseSplitRequest, error) {
+	places := utils.AmountDecimals(currency)
+	items := make([]*models.ExpenseItem, 0, len(req.Items))
+	totals := make(map[string]decimal.Decimal)
+	var order []string
+	itemsTotal := decimal.Zero
+
+	for i, itemReq := range req.Items {
+		description := strings.TrimSpace(itemReq.Description)
+		if description == "" {
+			return nil, nil, errors.NewRequiredFieldError(fmt.Sprintf("items[%d].description", i))
+		}
+		if len(description) > 255 {
+			return nil, nil, errors.NewValidationError("Item description must be less than 255 characters")
+		}
+
+		if itemReq.Amount.LessThanOrEqual(decimal.Zero) {
+			return nil, nil, errors.NewValidationError("Item amounts must be greater than zero")
+		}
+		if err := utils.ValidateAmountPrecision(fmt.Sprintf("items[%d].amount", i), itemReq.Amount, currency); err != nil {
+			return nil, nil, err
+		}
+		if len(itemReq.UserUUIDs) == 0 {
+			return nil, nil, errors.NewInvalidSplitError(fmt.Sprintf("Item '%s' must list at least one user", description))
+		}
+		// Every user must get at least one minor unit of the item
+		if itemReq.Amount.LessThan(decimal.New(int64(len(itemReq.UserUUIDs)), -places)) {
+			return nil, nil, errors.NewInvalidSplitError(fmt.Sprintf("Item '%s' is too small to split among its users", description))
+		}
+
+		item := &models.ExpenseItem{Position: i, Description: description, Amount: itemReq.Amount}
+		amounts := utils.SplitEvenly(itemReq.Amount, len(itemReq.UserUUIDs), places)
+		seen := make(map[string]bool, len(itemReq.UserUUIDs))
+		for j, userUUID := range itemReq.UserUUIDs {
+			if !utils.IsValidUUID(userUUID) {
+				return nil, nil, errors.NewInvalidValueError("user_uuid", userUUID)
+			}
+			key := strings.ToLower(userUUID)
+			if seen[key] {
+				splitErr := errors.NewInvalidSplitError(fmt.Sprintf("Duplicate user in item '%s': %s", description, userUUID))
+				splitErr.Details = map[string]string{"user_uuid": userUUID}
+				return nil, nil, splitErr
+			}
+			seen[key] = true
+
+			if _, ok := totals[key]; !ok {
+				order = append(order, key)
+			}
+			totals[key] = totals[key].Add(amounts[j])
+			item.Shares = append(item.Shares, &models.ExpenseItemShare{
+				Amount: amounts[j],
+				User:   &models.User{UUID: key},
+			})
+		}
+
+		itemsTotal = itemsTotal.Add(itemReq.Amount)
+		items = append(items, item)
+	}
+
+	if !itemsTotal.Equal(req.Amount) {
+		return nil, nil, errors.NewInvalidSplitError("Sum of item amounts must equal total expense amount")
+	}
+
+	splits := make([]models.CreateExpenseSplitRequest, 0, len(order))
+	for _, userUUID := range order {
+		splits = append(splits, models.CreateExpenseSplitRequest{UserUUID: userUUID, Amount: totals[userUUID]})
+	}
+
+	return items, splits, nil
+}
+
+// resolveItemUsers points item shares at the users loaded for the splits
+func resolveItemUsers(items []*models.ExpenseItem, splits []*models.ExpenseSplit) {
+	users := make(map[string]*models.User, len(splits))
+	for _, split := range splits {
+		if split.User != nil {
+			users[strings.ToLower(split.User.UUID)] = split.User
+		}
+	}
+
+	for _, item := range items {
+		for _, share := range item.Shares {
+			if user, ok := users[share.User.UUID]; ok {
+				share.UserID = user.ID
+				share.User = user
+			}
+		}
+	}
+}
@@ -2,8 +2,14 @@ package service
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/fx"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/metrics"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/repository"
 	"expense-split-tracker/internal/utils"
@@ -13,12 +19,25 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// defaultTopExpenses is the number of expenses returned by the top
+	// expenses endpoint when no limit is given
+	defaultTopExpenses = 10
+	// maxTopExpenses caps the limit of the top expenses endpoint
+	maxTopExpenses = 100
+)
+
 type expenseService struct {
 	expenseRepo repository.ExpenseRepository
 	groupRepo   repository.GroupRepository
 	userRepo    repository.UserRepository
 	balanceRepo repository.BalanceRepository
+	historyRepo repository.BalanceHistoryRepository
+	lockRepo    repository.GroupLockRepository
 	db          DBTransactor
+	rates       fx.ExchangeRateProvider
+	emitter     events.Emitter
+	metrics     metrics.Recorder
 	logger      *zap.Logger
 }
 
@@ -28,7 +47,12 @@ func NewExpenseService(
 	groupRepo repository.GroupRepository,
 	userRepo repository.UserRepository,
 	balanceRepo repository.BalanceRepository,
+	historyRepo repository.BalanceHistoryRepository,
+	lockRepo repository.GroupLockRepository,
 	db DBTransactor,
+	rates fx.ExchangeRateProvider,
+	emitter events.Emitter,
+	recorder metrics.Recorder,
 	logger *zap.Logger,
 ) ExpenseService {
 	return &expenseService{
@@ -36,213 +60,604 @@ func NewExpenseService(
 		groupRepo:   groupRepo,
 		userRepo:    userRepo,
 		balanceRepo: balanceRepo,
+		historyRepo: historyRepo,
+		lockRepo:    lockRepo,
 		db:          db,
+		rates:       rates,
+		emitter:     emitter,
+		metrics:     recorder,
 		logger:      logger,
 	}
 }
 
 // CreateExpense creates a new expense with splits
 func (s *expenseService) CreateExpense(ctx context.Context, req *models.CreateExpenseRequest) (*models.Expense, error) {
-	// Validate input
-	if err := utils.ValidateAmount(req.Amount); err != nil {
+	expense, splits, err := s.prepareExpense(ctx, req)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := utils.ValidateDescription(req.Description); err != nil {
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+
+		if err := ensureGroupUnlocked(ctx, s.lockRepo, tx, expense.GroupID); err != nil {
+			return err
+		}
+
+		if err := s.insertExpense(ctx, tx, expense, splits, &batch); err != nil {
+			return err
+		}
+		return batch.Record(ctx, tx, s.emitter)
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to create expense", zap.Error(err), zap.String("description", req.Description))
 		return nil, err
 	}
 
-	currency := req.Currency
-	if currency == "" {
-		currency = "USD"
-	}
-	if err := utils.ValidateCurrency(currency); err != nil {
+	batch.Emit(ctx, s.emitter)
+	s.metrics.ExpensesCreated(1)
+
+	// Get splits for response
+	expense.Splits, err = s.expenseRepo.GetExpenseSplits(ctx, expense.ID)
+	if err != nil {
 		return nil, err
 	}
 
+	logging.FromContext(ctx, s.logger).Info("Expense created successfully", zap.String("uuid", expense.UUID), zap.String("description", expense.Description))
+	return expense, nil
+}
+
+// ValidateExpense checks a create request exactly as CreateExpense would,
+// without writing anything or modifying req
+func (s *expenseService) ValidateExpense(ctx context.Context, req *models.CreateExpenseRequest) error {
+	check := *req
+	check.Splits = append([]models.CreateExpenseSplitRequest(nil), req.Splits...)
+	_, _, err := s.prepareExpense(ctx, &check)
+	return err
+}
+
+// prepareExpense validates a create request and calculates its splits
+// without writing anything
+func (s *expenseService) prepareExpense(ctx context.Context, req *models.CreateExpenseRequest) (*models.Expense, []*models.ExpenseSplit, error) {
+	// Itemized expenses derive exact splits from their line items
+	if req.SplitType == "" && len(req.Items) > 0 {
+		req.SplitType = models.SplitTypeExact
+	}
+
+	// Validate input
+	if req.SplitType == "" {
+		return nil, nil, errors.NewRequiredFieldError("split_type")
+	}
+	if err := req.SplitType.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := utils.ValidateAmount(req.Amount, req.Currency); err != nil {
+		return nil, nil, err
+	}
+
+	if err := utils.ValidateDescription(req.Description); err != nil {
+		return nil, nil, err
+	}
+
+	if req.Currency != "" {
+		if err := utils.ValidateCurrency(req.Currency); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	category := utils.NormalizeCategory(req.Category)
+	if category == "" {
+		category = models.DefaultExpenseCategory
+	}
+	if err := utils.ValidateCategory(category); err != nil {
+		return nil, nil, err
+	}
+
+	receiptURL := strings.TrimSpace(req.ReceiptURL)
+	if receiptURL != "" {
+		if err := utils.ValidateReceiptURL(receiptURL); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	now := time.Now()
+	expenseDate := req.ExpenseDate
+	if expenseDate.IsZero() {
+		expenseDate = now
+	}
+	if err := utils.ValidateExpenseDate(expenseDate, now); err != nil {
+		return nil, nil, err
+	}
+
 	if !utils.IsValidUUID(req.GroupUUID) {
-		return nil, errors.NewInvalidValueError("group_uuid", req.GroupUUID)
+		return nil, nil, errors.NewInvalidValueError("group_uuid", req.GroupUUID)
 	}
 
 	if !utils.IsValidUUID(req.PaidByUUID) {
-		return nil, errors.NewInvalidValueError("paid_by_uuid", req.PaidByUUID)
+		return nil, nil, errors.NewInvalidValueError("paid_by_uuid", req.PaidByUUID)
 	}
 
 	// Get group and validate
 	group, err := s.groupRepo.GetByUUID(ctx, req.GroupUUID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, nil, err
+	}
+	if err := ensureGroupActive(group); err != nil {
+		return nil, nil, err
+	}
+
+	// An expense without an explicit currency is booked in the group's default
+	currency, err := resolveGroupCurrency(group, req.Currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := utils.ValidateAmountPrecision("amount", req.Amount, currency); err != nil {
+		return nil, nil, err
+	}
+
+	// Foreign receipts are converted so the group's balances stay in its own currency
+	baseCurrency := group.DefaultCurrency
+	if baseCurrency == "" {
+		baseCurrency = currency
+	}
+	rate, err := s.resolveExchangeRate(ctx, currency, baseCurrency, req.ExchangeRate)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Get payer and validate
 	payer, err := s.userRepo.GetByUUID(ctx, req.PaidByUUID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// Pending users may owe a share but cannot be recorded as paying
+	if payer.IsPending {
+		return nil, nil, errors.NewPendingUserError(payer.Email)
 	}
 
 	// Check if payer is a member of the group
 	isMember, err := s.groupRepo.IsMember(ctx, group.ID, payer.ID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if !isMember {
-		return nil, errors.NewValidationError("Payer must be a member of the group")
+		return nil, nil, errors.NewValidationError("Payer must be a member of the group")
+	}
+
+	var items []*models.ExpenseItem
+	if len(req.Items) > 0 {
+		if req.SplitType != models.SplitTypeExact || len(req.Splits) > 0 {
+			return nil, nil, errors.NewInvalidSplitError("Itemized expenses derive their splits from items; omit splits and use split_type exact")
+		}
+		items, req.Splits, err = itemizeSplits(req, currency)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	// Validate splits based on split type
-	splits, err := s.validateAndCalculateSplits(ctx, req, group.ID)
+	// An equal split without explicit entries is shared by every current member
+	if req.SplitType == models.SplitTypeEqual && len(req.Splits) == 0 {
+		members, err := s.groupRepo.GetMembers(ctx, group.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, member := range members {
+			req.Splits = append(req.Splits, models.CreateExpenseSplitRequest{UserUUID: member.UUID})
+		}
+	}
+
+	// Validate splits based on split type, in the resolved currency
+	splitReq := *req
+	splitReq.Currency = currency
+	splits, err := s.validateAndCalculateSplits(ctx, &splitReq, group.ID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Create expense with transaction
+	resolveItemUsers(items, splits)
+
 	expense := &models.Expense{
-		UUID:        utils.GenerateUUID(),
-		GroupID:     group.ID,
-		PaidBy:      payer.ID,
-		Amount:      req.Amount,
-		Currency:    currency,
-		Description: req.Description,
-		SplitType:   req.SplitType,
-	}
-
-	err = s.db.WithTransaction(func(tx *database.Tx) error {
-		// Create expense
-		if err := s.expenseRepo.Create(ctx, tx, expense); err != nil {
+		UUID:         utils.GenerateUUID(),
+		GroupID:      group.ID,
+		PaidBy:       payer.ID,
+		Amount:       req.Amount,
+		Currency:     currency,
+		BaseCurrency: baseCurrency,
+		ExchangeRate: rate,
+		Description:  req.Description,
+		Category:     category,
+		SplitType:    req.SplitType,
+		ReceiptURL:   receiptURL,
+		ExpenseDate:  expenseDate,
+		Items:        items,
+		Recurrence:   req.Recurrence,
+	}
+	convertToBase(expense, splits)
+	if req.IsRefund {
+		expense.IsRefund = true
+		applyRefundSign(expense, splits)
+	}
+
+	return expense, splits, nil
+}
+
+// insertExpense writes a prepared expense and its splits and applies them to
+// balances inside the caller's transaction
+func (s *expenseService) insertExpense(ctx context.Context, tx *database.Tx, expense *models.Expense, splits []*models.ExpenseSplit, batch *events.Batch) error {
+	if err := s.expenseRepo.Create(ctx, tx, expense); err != nil {
+		return err
+	}
+
+	for _, split := range splits {
+		split.ExpenseID = expense.ID
+		if err := s.expenseRepo.CreateSplit(ctx, tx, split); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range expense.Items {
+		item.ExpenseID = expense.ID
+		if err := s.expenseRepo.CreateItem(ctx, tx, item); err != nil {
+			return err
+		}
+	}
+	batch.Add(events.ExpenseCreated{Expense: expense, Splits: splits})
+
+	return s.updateBalancesAfterExpense(ctx, tx, expense, splits, batch)
+}
+
+// UpdateExpense replaces an expense's amount, description, currency and splits.
+// The old splits' effect on balances is reversed and the new one applied in a
+// single transaction that holds the expense's row lock, so concurrent updates
+// apply one after the other; invalid new splits leave the expense untouched.
+func (s *expenseService) UpdateExpense(ctx context.Context, uuid string, req *models.UpdateExpenseRequest) (*models.Expense, error) {
+	if !utils.IsValidUUID(uuid) {
+		return nil, errors.NewInvalidValueError("uuid", uuid)
+	}
+
+	if err := req.SplitType.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateAmount(req.Amount, req.Currency); err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateDescription(req.Description); err != nil {
+		return nil, err
+	}
+
+	if req.Currency != "" {
+		if err := utils.ValidateCurrency(req.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	if !req.ExpenseDate.IsZero() {
+		if err := utils.ValidateExpenseDate(req.ExpenseDate, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	expense, err := s.expenseRepo.GetByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, expense.GroupID); err != nil {
+		return nil, err
+	}
+
+	// Omitting the currency on update keeps the expense's existing currency
+	currency := expense.Currency
+	if req.Currency != "" {
+		currency = utils.NormalizeCurrency(req.Currency)
+	}
+	if err := utils.ValidateAmountPrecision("amount", req.Amount, currency); err != nil {
+		return nil, err
+	}
+
+	// The recorded rate is kept while the currency stays the same
+	rate := expense.ExchangeRate
+	if req.ExchangeRate != nil || currency != expense.Currency {
+		rate, err = s.resolveExchangeRate(ctx, currency, expense.BaseCurrency, req.ExchangeRate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate the new splits before touching any state
+	splits, err := s.validateAndCalculateSplits(ctx, &models.CreateExpenseRequest{
+		Amount:    req.Amount,
+		Currency:  currency,
+		SplitType: req.SplitType,
+		Splits:    req.Splits,
+	}, expense.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	expense.Amount = req.Amount
+	expense.Currency = currency
+	expense.ExchangeRate = rate
+	expense.Description = req.Description
+	expense.SplitType = req.SplitType
+	if !req.ExpenseDate.IsZero() {
+		expense.ExpenseDate = req.ExpenseDate
+	}
+	convertToBase(expense, splits)
+	if expense.IsRefund {
+		applyRefundSign(expense, splits)
+	}
+
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+
+		if err := ensureGroupUnlocked(ctx, s.lockRepo, tx, expense.GroupID); err != nil {
+			return err
+		}
+
+		// Reverse the expense as it stands now, not as it was read above: a
+		// concurrent update or delete may have committed since, and the lock
+		// keeps the next one waiting until this one commits
+		original, err := s.expenseRepo.GetByIDForUpdate(ctx, tx, expense.ID)
+		if err != nil {
+			return err
+		}
+		if original.DeletedAt != nil {
+			return errors.NewAlreadyDeletedError("Expense")
+		}
+		oldSplits, err := s.expenseRepo.GetExpenseSplitsForUpdate(ctx, tx, expense.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.reverseBalancesForExpense(ctx, tx, original, oldSplits, &batch); err != nil {
+			return err
+		}
+
+		if err := s.expenseRepo.DeleteExpenseSplits(ctx, tx, expense.ID, actingUserID(ctx)); err != nil {
+			return err
+		}
+
+		// Replaced splits no longer follow any line items
+		if err := s.expenseRepo.DeleteExpenseItems(ctx, tx, expense.ID); err != nil {
+			return err
+		}
+
+		if err := s.expenseRepo.Update(ctx, tx, expense); err != nil {
 			return err
 		}
 
-		// Create splits
 		for _, split := range splits {
 			split.ExpenseID = expense.ID
 			if err := s.expenseRepo.CreateSplit(ctx, tx, split); err != nil {
 				return err
 			}
 		}
+		batch.Add(events.ExpenseUpdated{Expense: expense, Splits: splits})
 
-		// Update balances
-		return s.updateBalancesAfterExpense(ctx, tx, expense, splits)
+		if err := s.updateBalancesAfterExpense(ctx, tx, expense, splits, &batch); err != nil {
+			return err
+		}
+		return batch.Record(ctx, tx, s.emitter)
 	})
 
 	if err != nil {
-		s.logger.Error("Failed to create expense", zap.Error(err), zap.String("description", req.Description))
+		logging.FromContext(ctx, s.logger).Error("Failed to update expense", zap.Error(err), zap.String("uuid", uuid))
 		return nil, err
 	}
 
-	// Get splits for response
+	batch.Emit(ctx, s.emitter)
+
 	expense.Splits, err = s.expenseRepo.GetExpenseSplits(ctx, expense.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	s.logger.Info("Expense created successfully", zap.String("uuid", expense.UUID), zap.String("description", expense.Description))
+	logging.FromContext(ctx, s.logger).Info("Expense updated successfully", zap.String("uuid", expense.UUID), zap.String("amount", expense.Amount.String()))
 	return expense, nil
 }
 
+// DeleteExpense soft deletes an expense on behalf of the caller, reversing its
+// splits' effect on balances. The expense and its splits are kept for history.
+func (s *expenseService) DeleteExpense(ctx context.Context, uuid string) error {
+	if !utils.IsValidUUID(uuid) {
+		return errors.NewInvalidValueError("uuid", uuid)
+	}
+
+	expense, err := s.expenseRepo.GetByUUIDIncludingDeleted(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, expense.GroupID); err != nil {
+		return err
+	}
+	if expense.DeletedAt != nil {
+		return errors.NewAlreadyDeletedError("Expense")
+	}
+
+	// Balances are kept per group; without the group there is nothing to reverse against
+	if _, err := s.groupRepo.GetByID(ctx, expense.GroupID); err != nil {
+		return err
+	}
+
+	splits, err := s.expenseRepo.GetExpenseSplits(ctx, expense.ID)
+	if err != nil {
+		return err
+	}
+
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+
+		if err := ensureGroupUnlocked(ctx, s.lockRepo, tx, expense.GroupID); err != nil {
+			return err
+		}
+
+		// Only one of two concurrent deletes gets to reverse the balances
+		deleted, err := s.expenseRepo.Delete(ctx, tx, expense.ID, actingUserID(ctx))
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return errors.NewAlreadyDeletedError("Expense")
+		}
+
+		if err := s.reverseBalancesForExpense(ctx, tx, expense, splits, &batch); err != nil {
+			return err
+		}
+		batch.Add(events.ExpenseDeleted{Expense: expense})
+
+		return batch.Record(ctx, tx, s.emitter)
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to delete expense", zap.Error(err), zap.String("uuid", uuid))
+		return err
+	}
+
+	batch.Emit(ctx, s.emitter)
+
+	logging.FromContext(ctx, s.logger).Info("Expense deleted successfully", zap.String("uuid", uuid))
+	return nil
+}
+
 // validateAndCalculateSplits validates and calculates splits based on split type
 func (s *expenseService) validateAndCalculateSplits(ctx context.Context, req *models.CreateExpenseRequest, groupID int64) ([]*models.ExpenseSplit, error) {
 	if len(req.Splits) == 0 {
 		return nil, errors.NewValidationError("At least one split is required")
 	}
 
-	var splits []*models.ExpenseSplit
+	// A user listed twice would be charged twice
+	seen := make(map[string]bool, len(req.Splits))
+	for _, splitReq := range req.Splits {
+		key := strings.ToLower(splitReq.UserUUID)
+		if seen[key] {
+			splitErr := errors.NewInvalidSplitError("Duplicate user in splits: " + splitReq.UserUUID)
+			splitErr.Details = map[string]string{"user_uuid": splitReq.UserUUID}
+			return nil, splitErr
+		}
+		seen[key] = true
+	}
+
+	switch req.SplitType {
+	case models.SplitTypeEqual, models.SplitTypeExact, models.SplitTypePercentage, models.SplitTypeShares:
+	default:
+		return nil, errors.NewInvalidValueError("split_type", string(req.SplitType))
+	}
+
+	users, err := s.resolveSplitUsers(ctx, req.Splits, groupID)
+	if err != nil {
+		return nil, err
+	}
 
 	switch req.SplitType {
 	case models.SplitTypeEqual:
-		return s.calculateEqualSplits(ctx, req, groupID)
+		return calculateEqualSplits(req, users)
 
 	case models.SplitTypeExact:
-		return s.calculateExactSplits(ctx, req, groupID)
+		return calculateExactSplits(req, users)
 
 	case models.SplitTypePercentage:
-		return s.calculatePercentageSplits(ctx, req, groupID)
+		return calculatePercentageSplits(req, users)
 
 	default:
-		return nil, errors.NewInvalidValueError("split_type", string(req.SplitType))
+		return calculateShareSplits(req, users)
 	}
-
-	return splits, nil
 }
 
-// calculateEqualSplits calculates equal splits among users
-func (s *expenseService) calculateEqualSplits(ctx context.Context, req *models.CreateExpenseRequest, groupID int64) ([]*models.ExpenseSplit, error) {
-	var splits []*models.ExpenseSplit
-	splitCount := decimal.NewFromInt(int64(len(req.Splits)))
-	amountPerUser := req.Amount.Div(splitCount).Round(2)
-
-	// Handle rounding by giving remainder to first user
-	totalAssigned := decimal.Zero
-
-	for i, splitReq := range req.Splits {
+// resolveSplitUsers loads the users of splits, in the same order, and checks
+// that they all belong to the group. Users and memberships are each fetched in
+// one query however many splits there are.
+func (s *expenseService) resolveSplitUsers(ctx context.Context, splits []models.CreateExpenseSplitRequest, groupID int64) ([]*models.User, error) {
+	uuids := make([]string, len(splits))
+	for i, splitReq := range splits {
 		if !utils.IsValidUUID(splitReq.UserUUID) {
 			return nil, errors.NewInvalidValueError("user_uuid", splitReq.UserUUID)
 		}
+		uuids[i] = splitReq.UserUUID
+	}
 
-		user, err := s.userRepo.GetByUUID(ctx, splitReq.UserUUID)
-		if err != nil {
-			return nil, err
-		}
+	found, err := s.userRepo.GetByUUIDs(ctx, uuids)
+	if err != nil {
+		return nil, err
+	}
+	byUUID := make(map[string]*models.User, len(found))
+	for _, user := range found {
+		byUUID[strings.ToLower(user.UUID)] = user
+	}
 
-		// Check if user is a member of the group
-		isMember, err := s.groupRepo.IsMember(ctx, groupID, user.ID)
-		if err != nil {
-			return nil, err
+	users := make([]*models.User, len(uuids))
+	userIDs := make([]int64, len(uuids))
+	for i, uuid := range uuids {
+		user, ok := byUUID[strings.ToLower(uuid)]
+		if !ok {
+			return nil, errors.NewNotFoundError("User")
 		}
-		if !isMember {
+		users[i] = user
+		userIDs[i] = user.ID
+	}
+
+	members, err := s.groupRepo.AreMembers(ctx, groupID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if !members[user.ID] {
 			return nil, errors.NewValidationError("All users in split must be members of the group")
 		}
+	}
+
+	return users, nil
+}
 
-		amount := amountPerUser
+// calculateEqualSplits calculates equal splits among users in the currency's
+// minor units. Leftover units are spread one each over the first users so no
+// two shares differ by more than one unit.
+func calculateEqualSplits(req *models.CreateExpenseRequest, users []*models.User) ([]*models.ExpenseSplit, error) {
+	var splits []*models.ExpenseSplit
+	amounts := utils.SplitEvenly(req.Amount, len(req.Splits), utils.AmountDecimals(req.Currency))
 
-		// For the last user, assign remaining amount to handle rounding
-		if i == len(req.Splits)-1 {
-			amount = req.Amount.Sub(totalAssigned)
+	for i := range req.Splits {
+		if amounts[i].IsNegative() {
+			return nil, errors.NewInvalidSplitError("Split amounts cannot be negative")
 		}
 
 		splits = append(splits, &models.ExpenseSplit{
-			UserID: user.ID,
-			Amount: amount,
-			User:   user,
+			UserID: users[i].ID,
+			Amount: amounts[i],
+			User:   users[i],
 		})
-
-		totalAssigned = totalAssigned.Add(amount)
 	}
 
 	return splits, nil
 }
 
 // calculateExactSplits calculates exact amount splits
-func (s *expenseService) calculateExactSplits(ctx context.Context, req *models.CreateExpenseRequest, groupID int64) ([]*models.ExpenseSplit, error) {
+func calculateExactSplits(req *models.CreateExpenseRequest, users []*models.User) ([]*models.ExpenseSplit, error) {
 	var splits []*models.ExpenseSplit
 	totalSplitAmount := decimal.Zero
 
-	for _, splitReq := range req.Splits {
-		if !utils.IsValidUUID(splitReq.UserUUID) {
-			return nil, errors.NewInvalidValueError("user_uuid", splitReq.UserUUID)
-		}
-
+	for i, splitReq := range req.Splits {
 		if splitReq.Amount.LessThanOrEqual(decimal.Zero) {
 			return nil, errors.NewValidationError("Split amounts must be greater than zero")
 		}
-
-		user, err := s.userRepo.GetByUUID(ctx, splitReq.UserUUID)
-		if err != nil {
+		if err := utils.ValidateAmountPrecision("splits.amount", splitReq.Amount, req.Currency); err != nil {
 			return nil, err
 		}
 
-		// Check if user is a member of the group
-		isMember, err := s.groupRepo.IsMember(ctx, groupID, user.ID)
-		if err != nil {
-			return nil, err
-		}
-		if !isMember {
-			return nil, errors.NewValidationError("All users in split must be members of the group")
-		}
-
 		splits = append(splits, &models.ExpenseSplit{
-			UserID: user.ID,
+			UserID: users[i].ID,
 			Amount: splitReq.Amount,
-			User:   user,
+			User:   users[i],
 		})
 
 		totalSplitAmount = totalSplitAmount.Add(splitReq.Amount)
@@ -256,90 +671,259 @@ func (s *expenseService) calculateExactSplits(ctx context.Context, req *models.C
 	return splits, nil
 }
 
-// calculatePercentageSplits calculates percentage-based splits
-func (s *expenseService) calculatePercentageSplits(ctx context.Context, req *models.CreateExpenseRequest, groupID int64) ([]*models.ExpenseSplit, error) {
-	var splits []*models.ExpenseSplit
+// calculatePercentageSplits calculates percentage-based splits. Amounts are
+// whole minor units of the expense currency, rounded the same way as shares
+// splits so they always add up to the expense total.
+func calculatePercentageSplits(req *models.CreateExpenseRequest, users []*models.User) ([]*models.ExpenseSplit, error) {
 	totalPercentage := decimal.Zero
-
 	for _, splitReq := range req.Splits {
-		if !utils.IsValidUUID(splitReq.UserUUID) {
-			return nil, errors.NewInvalidValueError("user_uuid", splitReq.UserUUID)
-		}
-
 		if err := utils.ValidatePercentage(splitReq.Percentage); err != nil {
 			return nil, err
 		}
+		totalPercentage = totalPercentage.Add(splitReq.Percentage)
+	}
 
-		user, err := s.userRepo.GetByUUID(ctx, splitReq.UserUUID)
-		if err != nil {
-			return nil, err
-		}
+	// Validate that percentages sum to 100
+	if !totalPercentage.Equal(decimal.NewFromInt(100)) {
+		return nil, errors.NewInvalidSplitError("Percentages must sum to 100")
+	}
 
-		// Check if user is a member of the group
-		isMember, err := s.groupRepo.IsMember(ctx, groupID, user.ID)
-		if err != nil {
-			return nil, err
-		}
-		if !isMember {
-			return nil, errors.NewValidationError("All users in split must be members of the group")
-		}
+	weights := make([]decimal.Decimal, len(req.Splits))
+	for i, splitReq := range req.Splits {
+		weights[i] = splitReq.Percentage
+	}
 
-		// Calculate amount from percentage
-		amount := req.Amount.Mul(splitReq.Percentage).Div(decimal.NewFromInt(100)).Round(2)
+	var splits []*models.ExpenseSplit
+	amounts := utils.SplitByWeights(req.Amount, weights, utils.AmountDecimals(req.Currency))
 
+	for i, splitReq := range req.Splits {
 		splits = append(splits, &models.ExpenseSplit{
-			UserID:     user.ID,
-			Amount:     amount,
+			UserID:     users[i].ID,
+			Amount:     amounts[i],
 			Percentage: splitReq.Percentage,
-			User:       user,
+			User:       users[i],
 		})
+	}
 
-		totalPercentage = totalPercentage.Add(splitReq.Percentage)
+	return splits, nil
+}
+
+// calculateShareSplits calculates splits weighted by each user's share count.
+// Amounts are whole minor units of the expense currency; the units lost to
+// rounding go one each to the users with the largest remainders so the
+// splits always add up to the expense total.
+func calculateShareSplits(req *models.CreateExpenseRequest, users []*models.User) ([]*models.ExpenseSplit, error) {
+	weights := make([]decimal.Decimal, len(req.Splits))
+	for i, splitReq := range req.Splits {
+		if splitReq.Shares <= 0 {
+			return nil, errors.NewInvalidSplitError("Shares must be greater than zero")
+		}
+		weights[i] = decimal.NewFromInt(int64(splitReq.Shares))
 	}
 
-	// Validate that percentages sum to 100
-	if !totalPercentage.Equal(decimal.NewFromInt(100)) {
-		return nil, errors.NewInvalidSplitError("Percentages must sum to 100")
+	var splits []*models.ExpenseSplit
+	amounts := utils.SplitByWeights(req.Amount, weights, utils.AmountDecimals(req.Currency))
+
+	for i, splitReq := range req.Splits {
+		splits = append(splits, &models.ExpenseSplit{
+			UserID: users[i].ID,
+			Amount: amounts[i],
+			Shares: splitReq.Shares,
+			User:   users[i],
+		})
 	}
 
 	return splits, nil
 }
 
-// updateBalancesAfterExpense updates user balances after creating an expense
-func (s *expenseService) updateBalancesAfterExpense(ctx context.Context, tx *database.Tx, expense *models.Expense, splits []*models.ExpenseSplit) error {
+// applyRefundSign negates a refund's amounts, splits and line items. Splits
+// are always calculated on the positive amount first, so a refund with the
+// same splits as an expense reverses it cent for cent, remainders included.
+func applyRefundSign(expense *models.Expense, splits []*models.ExpenseSplit) {
+	expense.Amount = expense.Amount.Neg()
+	expense.BaseAmount = expense.BaseAmount.Neg()
+	for _, split := range splits {
+		split.Amount = split.Amount.Neg()
+	}
+	for _, item := range expense.Items {
+		item.Amount = item.Amount.Neg()
+		for _, share := range item.Shares {
+			share.Amount = share.Amount.Neg()
+		}
+	}
+}
+
+// updateBalancesAfterExpense updates user balances after creating an expense.
+// Balances are kept in the expense's base currency.
+func (s *expenseService) updateBalancesAfterExpense(ctx context.Context, tx *database.Tx, expense *models.Expense, splits []*models.ExpenseSplit, batch *events.Batch) error {
 	// For each split, increase the user's debt (positive balance means they owe money)
 	for _, split := range splits {
-		err := s.balanceRepo.UpdateBalance(ctx, tx, expense.GroupID, split.UserID, split.Amount, expense.Currency)
+		err := adjustBalance(ctx, tx, s.balanceRepo, s.historyRepo, &models.BalanceHistoryEntry{
+			GroupID: expense.GroupID, UserID: split.UserID, Currency: expense.BaseCurrency, Delta: split.Amount,
+			SourceType: models.BalanceHistorySourceExpense, SourceID: expense.ID,
+		})
 		if err != nil {
 			return err
 		}
+		batch.Add(events.BalanceAdjusted{GroupID: expense.GroupID, UserID: split.UserID, Currency: expense.BaseCurrency, Delta: split.Amount})
+
+		// Everyone but the payer now owes the payer their share
+		if split.UserID != expense.PaidBy {
+			if err := s.balanceRepo.UpdateDebt(ctx, tx, expense.GroupID, split.UserID, expense.PaidBy, split.Amount, expense.BaseCurrency); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Decrease the payer's debt (they paid for others)
-	err := s.balanceRepo.UpdateBalance(ctx, tx, expense.GroupID, expense.PaidBy, expense.Amount.Neg(), expense.Currency)
+	err := adjustBalance(ctx, tx, s.balanceRepo, s.historyRepo, &models.BalanceHistoryEntry{
+		GroupID: expense.GroupID, UserID: expense.PaidBy, Currency: expense.BaseCurrency, Delta: expense.BaseAmount.Neg(),
+		SourceType: models.BalanceHistorySourceExpense, SourceID: expense.ID,
+	})
 	if err != nil {
 		return err
 	}
+	batch.Add(events.BalanceAdjusted{GroupID: expense.GroupID, UserID: expense.PaidBy, Currency: expense.BaseCurrency, Delta: expense.BaseAmount.Neg()})
 
 	return nil
 }
 
-// ListExpenses retrieves expenses with filtering
-func (s *expenseService) ListExpenses(ctx context.Context, filter *models.ExpenseFilter) (*models.ExpenseListResponse, error) {
-	expenses, total, err := s.expenseRepo.List(ctx, filter)
+// SetReceipt attaches a receipt URL to an existing expense, replacing any
+// earlier one; an empty URL removes it. Balances are not touched.
+func (s *expenseService) SetReceipt(ctx context.Context, uuid string, req *models.SetReceiptRequest) (*models.Expense, error) {
+	if !utils.IsValidUUID(uuid) {
+		return nil, errors.NewInvalidValueError("uuid", uuid)
+	}
+
+	receiptURL := strings.TrimSpace(req.ReceiptURL)
+	if receiptURL != "" {
+		if err := utils.ValidateReceiptURL(receiptURL); err != nil {
+			return nil, err
+		}
+	}
+
+	expense, err := s.expenseRepo.GetByUUID(ctx, uuid)
 	if err != nil {
-		s.logger.Error("Failed to list expenses", zap.Error(err))
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, expense.GroupID); err != nil {
 		return nil, err
 	}
 
-	// Get splits for each expense
-	for _, expense := range expenses {
-		expense.Splits, err = s.expenseRepo.GetExpenseSplits(ctx, expense.ID)
+	if err := s.expenseRepo.UpdateReceiptURL(ctx, nil, expense.ID, receiptURL); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to set expense receipt", zap.Error(err), zap.String("uuid", uuid))
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Expense receipt updated", zap.String("uuid", uuid))
+	return s.GetExpenseByUUID(ctx, uuid)
+}
+
+// GetExpenseByUUID retrieves an expense by UUID together with its splits and
+// line items
+func (s *expenseService) GetExpenseByUUID(ctx context.Context, uuid string) (*models.Expense, error) {
+	if !utils.IsValidUUID(uuid) {
+		return nil, errors.NewInvalidValueError("uuid", uuid)
+	}
+
+	expense, err := s.expenseRepo.GetByUUID(ctx, uuid)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get expense by UUID", zap.Error(err), zap.String("uuid", uuid))
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, expense.GroupID); err != nil {
+		return nil, err
+	}
+
+	expense.Splits, err = s.expenseRepo.GetExpenseSplits(ctx, expense.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get expense splits", zap.Error(err), zap.Int64("expenseID", expense.ID))
+		return nil, err
+	}
+
+	expense.Items, err = s.expenseRepo.GetExpenseItems(ctx, expense.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get expense items", zap.Error(err), zap.Int64("expenseID", expense.ID))
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+// reverseBalancesForExpense undoes the balance changes made by updateBalancesAfterExpense
+func (s *expenseService) reverseBalancesForExpense(ctx context.Context, tx *database.Tx, expense *models.Expense, splits []*models.ExpenseSplit, batch *events.Batch) error {
+	for _, split := range splits {
+		err := adjustBalance(ctx, tx, s.balanceRepo, s.historyRepo, &models.BalanceHistoryEntry{
+			GroupID: expense.GroupID, UserID: split.UserID, Currency: expense.BaseCurrency, Delta: split.Amount.Neg(),
+			SourceType: models.BalanceHistorySourceExpense, SourceID: expense.ID,
+		})
 		if err != nil {
+			return err
+		}
+		batch.Add(events.BalanceAdjusted{GroupID: expense.GroupID, UserID: split.UserID, Currency: expense.BaseCurrency, Delta: split.Amount.Neg()})
+
+		if split.UserID != expense.PaidBy {
+			if err := s.balanceRepo.UpdateDebt(ctx, tx, expense.GroupID, split.UserID, expense.PaidBy, split.Amount.Neg(), expense.BaseCurrency); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := adjustBalance(ctx, tx, s.balanceRepo, s.historyRepo, &models.BalanceHistoryEntry{
+		GroupID: expense.GroupID, UserID: expense.PaidBy, Currency: expense.BaseCurrency, Delta: expense.BaseAmount,
+		SourceType: models.BalanceHistorySourceExpense, SourceID: expense.ID,
+	})
+	if err != nil {
+		return err
+	}
+	batch.Add(events.BalanceAdjusted{GroupID: expense.GroupID, UserID: expense.PaidBy, Currency: expense.BaseCurrency, Delta: expense.BaseAmount})
+
+	return nil
+}
+
+// ListExpenses retrieves expenses with filtering
+func (s *expenseService) ListExpenses(ctx context.Context, filter *models.ExpenseFilter) (*models.ExpenseListResponse, error) {
+	if filter.SplitType != "" {
+		if err := filter.SplitType.Validate(); err != nil {
 			return nil, err
 		}
 	}
 
+	filter.Category = utils.NormalizeCategory(filter.Category)
+
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Query != "" {
+		if err := utils.ValidateSearchQuery(filter.Query); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.MinAmount.IsNegative() || filter.MaxAmount.IsNegative() {
+		return nil, errors.NewValidationError("Amount filters cannot be negative")
+	}
+	if !filter.MinAmount.IsZero() && !filter.MaxAmount.IsZero() && filter.MinAmount.GreaterThan(filter.MaxAmount) {
+		return nil, errors.NewValidationError("min_amount cannot be greater than max_amount")
+	}
+
+	if err := requireListedGroupMember(ctx, s.groupRepo, filter.GroupUUID); err != nil {
+		return nil, err
+	}
+
+	include, viewerID, err := s.resolveListOptions(ctx, &filter.ExpenseListOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	expenses, total, err := s.expenseRepo.List(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to list expenses", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.attachSplits(ctx, expenses, include, viewerID); err != nil {
+		return nil, err
+	}
+
 	page := filter.Page
 	limit := filter.Limit
 	if page < 1 {
@@ -358,14 +942,22 @@ func (s *expenseService) ListExpenses(ctx context.Context, filter *models.Expens
 }
 
 // GetGroupExpenses retrieves expenses for a specific group
-func (s *expenseService) GetGroupExpenses(ctx context.Context, groupUUID string, page, limit int) ([]*models.Expense, error) {
+func (s *expenseService) GetGroupExpenses(ctx context.Context, groupUUID string, page, limit int, opts *models.ExpenseListOptions) ([]*models.Expense, int, error) {
 	if !utils.IsValidUUID(groupUUID) {
-		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
+		return nil, 0, errors.NewInvalidValueError("group_uuid", groupUUID)
+	}
+
+	include, viewerID, err := s.resolveListOptions(ctx, opts)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, 0, err
 	}
 
 	if page < 1 {
@@ -376,32 +968,88 @@ func (s *expenseService) GetGroupExpenses(ctx context.Context, groupUUID string,
 	}
 	offset := (page - 1) * limit
 
-	expenses, err := s.expenseRepo.GetGroupExpenses(ctx, group.ID, offset, limit)
+	expenses, err := s.expenseRepo.GetGroupExpenses(ctx, group.ID, offset, limit, includeDeleted(opts))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get group expenses", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return nil, 0, err
+	}
+
+	total, err := s.expenseRepo.CountGroupExpenses(ctx, group.ID, includeDeleted(opts))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to count group expenses", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return nil, 0, err
+	}
+
+	if err := s.attachSplits(ctx, expenses, include, viewerID); err != nil {
+		return nil, 0, err
+	}
+
+	return expenses, total, nil
+}
+
+// GetTopGroupExpenses retrieves a group's largest expenses in a date range,
+// largest first, with their payer and split detail
+func (s *expenseService) GetTopGroupExpenses(ctx context.Context, groupUUID string, filter *models.TopExpensesFilter) ([]*models.Expense, error) {
+	if !utils.IsValidUUID(groupUUID) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
+	}
+	if !filter.FromDate.IsZero() && !filter.ToDate.IsZero() && filter.ToDate.Before(filter.FromDate) {
+		return nil, errors.NewValidationError("to_date must not be before from_date")
+	}
+
+	include, viewerID, err := s.resolveListOptions(ctx, &filter.ExpenseListOptions)
 	if err != nil {
-		s.logger.Error("Failed to get group expenses", zap.Error(err), zap.String("groupUUID", groupUUID))
 		return nil, err
 	}
 
-	// Get splits for each expense
-	for _, expense := range expenses {
-		expense.Splits, err = s.expenseRepo.GetExpenseSplits(ctx, expense.ID)
-		if err != nil {
-			return nil, err
-		}
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	limit := filter.Limit
+	if limit < 1 {
+		limit = defaultTopExpenses
+	}
+	if limit > maxTopExpenses {
+		limit = maxTopExpenses
+	}
+
+	loc := group.Location()
+	from, to := startOfDateFilter(filter.FromDate, loc), endOfDateFilter(filter.ToDate, loc)
+	expenses, err := s.expenseRepo.GetTopGroupExpenses(ctx, group.ID, from, to, limit, filter.IncludeDeleted)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get top group expenses", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return nil, err
+	}
+	if expenses == nil {
+		expenses = []*models.Expense{}
+	}
+
+	if err := s.attachSplits(ctx, expenses, include, viewerID); err != nil {
+		return nil, err
 	}
 
 	return expenses, nil
 }
 
 // GetUserExpenses retrieves expenses paid by a specific user
-func (s *expenseService) GetUserExpenses(ctx context.Context, userUUID string, page, limit int) ([]*models.Expense, error) {
+func (s *expenseService) GetUserExpenses(ctx context.Context, userUUID string, page, limit int, opts *models.ExpenseListOptions) ([]*models.Expense, int, error) {
 	if !utils.IsValidUUID(userUUID) {
-		return nil, errors.NewInvalidValueError("user_uuid", userUUID)
+		return nil, 0, errors.NewInvalidValueError("user_uuid", userUUID)
+	}
+
+	include, viewerID, err := s.resolveListOptions(ctx, opts)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	user, err := s.userRepo.GetByUUID(ctx, userUUID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if page < 1 {
@@ -412,19 +1060,100 @@ func (s *expenseService) GetUserExpenses(ctx context.Context, userUUID string, p
 	}
 	offset := (page - 1) * limit
 
-	expenses, err := s.expenseRepo.GetUserExpenses(ctx, user.ID, offset, limit)
+	expenses, err := s.expenseRepo.GetUserExpenses(ctx, user.ID, offset, limit, includeDeleted(opts))
 	if err != nil {
-		s.logger.Error("Failed to get user expenses", zap.Error(err), zap.String("userUUID", userUUID))
-		return nil, err
+		logging.FromContext(ctx, s.logger).Error("Failed to get user expenses", zap.Error(err), zap.String("userUUID", userUUID))
+		return nil, 0, err
 	}
 
-	// Get splits for each expense
-	for _, expense := range expenses {
-		expense.Splits, err = s.expenseRepo.GetExpenseSplits(ctx, expense.ID)
+	total, err := s.expenseRepo.CountUserExpenses(ctx, user.ID, includeDeleted(opts))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to count user expenses", zap.Error(err), zap.String("userUUID", userUUID))
+		return nil, 0, err
+	}
+
+	if err := s.attachSplits(ctx, expenses, include, viewerID); err != nil {
+		return nil, 0, err
+	}
+
+	return expenses, total, nil
+}
+
+// resolveListOptions validates the split detail options of an expense list and
+// resolves the viewer required by the summary mode
+func (s *expenseService) resolveListOptions(ctx context.Context, opts *models.ExpenseListOptions) (models.SplitInclude, int64, error) {
+	if opts == nil || opts.Include == "" {
+		return models.SplitIncludeFull, 0, nil
+	}
+
+	switch opts.Include {
+	case models.SplitIncludeFull, models.SplitIncludeNone:
+		return opts.Include, 0, nil
+
+	case models.SplitIncludeSummary:
+		// Until requests are authenticated the caller identifies itself explicitly
+		if opts.ViewerUUID == "" {
+			return "", 0, errors.NewRequiredFieldError("viewer_uuid")
+		}
+		if !utils.IsValidUUID(opts.ViewerUUID) {
+			return "", 0, errors.NewInvalidValueError("viewer_uuid", opts.ViewerUUID)
+		}
+
+		viewer, err := s.userRepo.GetByUUID(ctx, opts.ViewerUUID)
 		if err != nil {
-			return nil, err
+			return "", 0, err
 		}
+		return opts.Include, viewer.ID, nil
+
+	default:
+		return "", 0, errors.NewInvalidValueError("include", string(opts.Include))
 	}
+}
 
-	return expenses, nil
+// includeDeleted reports whether an expense list asked for deleted expenses too
+func includeDeleted(opts *models.ExpenseListOptions) bool {
+	return opts != nil && opts.IncludeDeleted
+}
+
+// attachSplits loads split detail for listed expenses according to the include mode
+func (s *expenseService) attachSplits(ctx context.Context, expenses []*models.Expense, include models.SplitInclude, viewerID int64) error {
+	if include == models.SplitIncludeNone {
+		return nil
+	}
+
+	if len(expenses) == 0 {
+		return nil
+	}
+
+	expenseIDs := make([]int64, len(expenses))
+	for i, expense := range expenses {
+		expenseIDs[i] = expense.ID
+	}
+
+	// One query for the whole page rather than one per expense
+	splitsByExpense, err := s.expenseRepo.GetSplitsForExpenses(ctx, expenseIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, expense := range expenses {
+		splits := splitsByExpense[expense.ID]
+
+		if include == models.SplitIncludeFull {
+			expense.Splits = splits
+			continue
+		}
+
+		summary := &models.ExpenseSplitsSummary{ParticipantCount: len(splits)}
+		for _, split := range splits {
+			if split.UserID == viewerID {
+				share := split.Amount
+				summary.ViewerShare = &share
+				break
+			}
+		}
+		expense.SplitsSummary = summary
+	}
+
+	return nil
 }
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"time"
+
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// exportBatchSize is the number of rows fetched per repository page while
+// streaming an export
+const exportBatchSize = 100
+
+// exportColumns are the fixed leading columns of a group CSV export; one
+// column per participant follows, headed by their email
+var exportColumns = []string{"type", "date", "uuid", "description", "category", "split_type", "amount", "currency", "paid_by", "paid_to"}
+
+type exportService struct {
+	expenseRepo    repository.ExpenseRepository
+	settlementRepo repository.SettlementRepository
+	groupRepo      repository.GroupRepository
+	logger         *zap.Logger
+}
+
+// NewExportService creates a new export service
+func NewExportService(
+	expenseRepo repository.ExpenseRepository,
+	settlementRepo repository.SettlementRepository,
+	groupRepo repository.GroupRepository,
+	logger *zap.Logger,
+) ExportService {
+	return &exportService{
+		expenseRepo:    expenseRepo,
+		settlementRepo: settlementRepo,
+		groupRepo:      groupRepo,
+		logger:         logger,
+	}
+}
+
+// ExportGroupCSV writes every expense and settlement of a group to w as CSV,
+// expenses first, each oldest first. Expenses carry one column per user with
+// that user's share. Nothing is written to w if the group cannot be loaded,
+// so callers can still report those errors normally.
+func (s *exportService) ExportGroupCSV(ctx context.Context, groupUUID string, filter *models.ExportFilter, w io.Writer) error {
+	if !utils.IsValidUUID(groupUUID) {
+		return errors.NewInvalidValueError("group_uuid", groupUUID)
+	}
+
+	if !filter.FromDate.IsZero() && !filter.ToDate.IsZero() && filter.ToDate.Before(filter.FromDate) {
+		return errors.NewValidationError("to_date must not be before from_date")
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	if err != nil {
+		return err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return err
+	}
+
+	participants, err := s.expenseRepo.GetGroupSplitUsers(ctx, group.ID)
+	if err != nil {
+		return err
+	}
+
+	// to_date covers the whole day
+	from := filter.FromDate
+	to := filter.ToDate
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	columnIndex := make(map[int64]int, len(participants))
+	header := append([]string{}, exportColumns...)
+	for i, user := range participants {
+		columnIndex[user.ID] = len(exportColumns) + i
+		header = append(header, user.Email)
+	}
+
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	loc := group.Location()
+	expenseCount := 0
+	err = s.expenseRepo.IterateGroupExpenses(ctx, group.ID, from, to, exportBatchSize, func(expenses []*models.Expense) error {
+		ids := make([]int64, len(expenses))
+		for i, expense := range expenses {
+			ids[i] = expense.ID
+		}
+		splitsByExpense, err := s.expenseRepo.GetSplitsForExpenses(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		for _, expense := range expenses {
+			record := make([]string, len(header))
+			copy(record, []string{
+				"expense",
+				expense.ExpenseDate.In(loc).Format("2006-01-02"),
+				expense.UUID,
+				expense.Description,
+				expense.Category,
+				string(expense.SplitType),
+				expense.Amount.StringFixed(2),
+				expense.Currency,
+				userEmail(expense.Payer),
+				"",
+			})
+			for _, split := range splitsByExpense[expense.ID] {
+				if i, ok := columnIndex[split.UserID]; ok {
+					record[i] = split.Amount.StringFixed(2)
+				}
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+		}
+		expenseCount += len(expenses)
+
+		csvWriter.Flush()
+		return csvWriter.Error()
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to export group expenses", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return err
+	}
+
+	// Only confirmed settlements, so the export adds up to the balances
+	settlementFilter := &models.SettlementFilter{
+		GroupUUID: group.UUID,
+		Status:    models.SettlementStatusConfirmed,
+		FromDate:  from,
+		ToDate:    to,
+		Limit:     exportBatchSize,
+		ListSort:  models.ListSort{SortBy: "created_at", SortDir: models.SortAsc},
+	}
+	settlementCount := 0
+	for page := 1; ; page++ {
+		settlementFilter.Page = page
+		settlements, total, err := s.settlementRepo.List(ctx, settlementFilter)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to export group settlements", zap.Error(err), zap.String("groupUUID", groupUUID))
+			return err
+		}
+
+		for _, settlement := range settlements {
+			record := make([]string, len(header))
+			copy(record, []string{
+				"settlement",
+				settlement.CreatedAt.In(loc).Format("2006-01-02"),
+				settlement.UUID,
+				settlement.Description,
+				"",
+				"",
+				settlement.Amount.StringFixed(2),
+				settlement.Currency,
+				userEmail(settlement.FromUser),
+				userEmail(settlement.ToUser),
+			})
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+		}
+		settlementCount += len(settlements)
+
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+
+		if len(settlements) < exportBatchSize || page*exportBatchSize >= total {
+			break
+		}
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Group exported", zap.String("groupUUID", groupUUID),
+		zap.Int("expenses", expenseCount), zap.Int("settlements", settlementCount))
+	return nil
+}
+
+// userEmail returns the email of a joined user, or "" if it was not loaded
+func userEmail(user *models.User) string {
+	if user == nil {
+		return ""
+	}
+	return user.Email
+}
@@ -2,30 +2,44 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/logging"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/repository"
 	"expense-split-tracker/internal/utils"
 	"expense-split-tracker/pkg/errors"
 
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
 type groupService struct {
-	groupRepo repository.GroupRepository
-	userRepo  repository.UserRepository
-	db        DBTransactor
-	logger    *zap.Logger
+	groupRepo    repository.GroupRepository
+	userRepo     repository.UserRepository
+	expenseRepo  repository.ExpenseRepository
+	balanceRepo  repository.BalanceRepository
+	db           DBTransactor
+	maxGroupSize int
+	emitter      events.Emitter
+	logger       *zap.Logger
 }
 
 // NewGroupService creates a new group service
-func NewGroupService(groupRepo repository.GroupRepository, userRepo repository.UserRepository, db DBTransactor, logger *zap.Logger) GroupService {
+func NewGroupService(groupRepo repository.GroupRepository, userRepo repository.UserRepository, expenseRepo repository.ExpenseRepository, balanceRepo repository.BalanceRepository, db DBTransactor, maxGroupSize int, emitter events.Emitter, logger *zap.Logger) GroupService {
 	return &groupService{
-		groupRepo: groupRepo,
-		userRepo:  userRepo,
-		db:        db,
-		logger:    logger,
+		groupRepo:    groupRepo,
+		userRepo:     userRepo,
+		expenseRepo:  expenseRepo,
+		balanceRepo:  balanceRepo,
+		db:           db,
+		maxGroupSize: maxGroupSize,
+		emitter:      emitter,
+		logger:       logger,
 	}
 }
 
@@ -40,44 +54,493 @@ func (s *groupService) CreateGroup(ctx context.Context, req *models.CreateGroupR
 		return nil, errors.NewInvalidValueError("creator_uuid", creatorUUID)
 	}
 
+	timezone, err := resolveGroupTimezone(req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCurrency, err := resolveDefaultCurrency(req.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get creator user
 	creator, err := s.userRepo.GetByUUID(ctx, creatorUUID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Pending users have never registered and cannot act on their own behalf
+	if creator.IsPending {
+		return nil, errors.NewPendingUserError(creator.Email)
+	}
+
 	// Create group with transaction
 	group := &models.Group{
-		UUID:        utils.GenerateUUID(),
-		Name:        req.Name,
-		Description: req.Description,
-		CreatedBy:   creator.ID,
+		UUID:            utils.GenerateUUID(),
+		Name:            req.Name,
+		Description:     req.Description,
+		Timezone:        timezone,
+		DefaultCurrency: defaultCurrency,
+		CreatedBy:       creator.ID,
 	}
 
-	err = s.db.WithTransaction(func(tx *database.Tx) error {
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+
 		// Create group
 		if err := s.groupRepo.Create(ctx, tx, group); err != nil {
 			return err
 		}
+		batch.Add(events.GroupCreated{Group: group})
 
-		// Add creator as first member
-		if err := s.groupRepo.AddMember(ctx, tx, group.ID, creator.ID); err != nil {
+		// Add creator as first member and admin
+		if err := s.groupRepo.AddMember(ctx, tx, group.ID, creator.ID, models.GroupRoleAdmin); err != nil {
 			return err
 		}
+		batch.Add(events.MemberAdded{GroupID: group.ID, UserID: creator.ID})
 
-		return nil
+		return batch.Record(ctx, tx, s.emitter)
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to create group", zap.Error(err), zap.String("name", req.Name))
+		return nil, err
+	}
+
+	batch.Emit(ctx, s.emitter)
+
+	group.Creator = creator
+	logging.FromContext(ctx, s.logger).Info("Group created successfully", zap.String("uuid", group.UUID), zap.String("name", group.Name))
+	return group, nil
+}
+
+// BootstrapGroup creates a group, any missing member users and all memberships
+// in a single transaction. Members are matched to existing users by email;
+// unknown emails become pending users until they register.
+func (s *groupService) BootstrapGroup(ctx context.Context, req *models.BootstrapGroupRequest) (*models.BootstrapGroupResponse, error) {
+	if err := utils.ValidateName(req.Name); err != nil {
+		return nil, err
+	}
+
+	timezone, err := resolveGroupTimezone(req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCurrency, err := resolveDefaultCurrency(req.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	creator, err := s.resolveBootstrapCreator(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate every member before touching the database so a single bad entry
+	// rejects the whole request with all problems reported at once
+	details := make(map[string]string)
+	seen := map[string]int{strings.ToLower(creator.Email): -1}
+	var members []models.BootstrapMemberRequest
+	for i, member := range req.Members {
+		field := fmt.Sprintf("members[%d]", i)
+
+		if err := utils.ValidateName(member.Name); err != nil {
+			details[field+".name"] = err.(*errors.AppError).Message
+		}
+		if err := utils.ValidateEmail(member.Email); err != nil {
+			details[field+".email"] = err.(*errors.AppError).Message
+			continue
+		}
+
+		key := strings.ToLower(member.Email)
+		if first, exists := seen[key]; exists {
+			if first >= 0 {
+				details[field+".email"] = fmt.Sprintf("Duplicate of members[%d].email", first)
+			}
+			// The creator is always a member; listing them again is harmless
+			continue
+		}
+		seen[key] = i
+		members = append(members, member)
+	}
+
+	if len(details) > 0 {
+		validationErr := errors.NewValidationError("Invalid group members")
+		validationErr.Details = details
+		return nil, validationErr
+	}
+
+	if len(members)+1 > s.maxGroupSize {
+		return nil, errors.NewValidationError(fmt.Sprintf("Groups cannot have more than %d members", s.maxGroupSize))
+	}
+
+	results := []*models.BootstrapMemberResult{{User: creator, Status: models.BootstrapMemberExisting}}
+	for _, member := range members {
+		existing, err := s.userRepo.GetByEmail(ctx, member.Email)
+		if err != nil {
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrCodeNotFound {
+				return nil, err
+			}
+			results = append(results, &models.BootstrapMemberResult{
+				User: &models.User{
+					UUID:      utils.GenerateUUID(),
+					Name:      member.Name,
+					Email:     member.Email,
+					IsPending: true,
+				},
+				Status: models.BootstrapMemberCreated,
+			})
+			continue
+		}
+		results = append(results, &models.BootstrapMemberResult{User: existing, Status: models.BootstrapMemberExisting})
+	}
+
+	group := &models.Group{
+		UUID:            utils.GenerateUUID(),
+		Name:            req.Name,
+		Description:     req.Description,
+		Timezone:        timezone,
+		DefaultCurrency: defaultCurrency,
+		CreatedBy:       creator.ID,
+	}
+
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+
+		for _, result := range results {
+			if result.Status == models.BootstrapMemberCreated {
+				if err := s.userRepo.Create(ctx, tx, result.User); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := s.groupRepo.Create(ctx, tx, group); err != nil {
+			return err
+		}
+		batch.Add(events.GroupCreated{Group: group})
+
+		for _, result := range results {
+			role := models.GroupRoleMember
+			if result.User.ID == creator.ID {
+				role = models.GroupRoleAdmin
+			}
+			if err := s.groupRepo.AddMember(ctx, tx, group.ID, result.User.ID, role); err != nil {
+				return err
+			}
+			batch.Add(events.MemberAdded{GroupID: group.ID, UserID: result.User.ID})
+		}
+
+		return batch.Record(ctx, tx, s.emitter)
 	})
 
 	if err != nil {
-		s.logger.Error("Failed to create group", zap.Error(err), zap.String("name", req.Name))
+		logging.FromContext(ctx, s.logger).Error("Failed to bootstrap group", zap.Error(err), zap.String("name", req.Name))
 		return nil, err
 	}
 
+	batch.Emit(ctx, s.emitter)
+
 	group.Creator = creator
-	s.logger.Info("Group created successfully", zap.String("uuid", group.UUID), zap.String("name", group.Name))
+	for _, result := range results {
+		group.Members = append(group.Members, result.User)
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Group bootstrapped successfully",
+		zap.String("uuid", group.UUID), zap.Int("members", len(results)))
+
+	return &models.BootstrapGroupResponse{
+		Group:   group,
+		Members: results,
+	}, nil
+}
+
+// resolveBootstrapCreator looks up the creator of a bootstrapped group by UUID or email
+func (s *groupService) resolveBootstrapCreator(ctx context.Context, req *models.BootstrapGroupRequest) (*models.User, error) {
+	var creator *models.User
+	var err error
+
+	switch {
+	case req.CreatorUUID != "":
+		if !utils.IsValidUUID(req.CreatorUUID) {
+			return nil, errors.NewInvalidValueError("creator_uuid", req.CreatorUUID)
+		}
+		creator, err = s.userRepo.GetByUUID(ctx, req.CreatorUUID)
+	case req.CreatorEmail != "":
+		if err := utils.ValidateEmail(req.CreatorEmail); err != nil {
+			return nil, err
+		}
+		creator, err = s.userRepo.GetByEmail(ctx, req.CreatorEmail)
+	default:
+		return nil, errors.NewRequiredFieldError("creator_uuid")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if creator.IsPending {
+		return nil, errors.NewPendingUserError(creator.Email)
+	}
+
+	return creator, nil
+}
+
+// UpdateGroup updates a group's settings. Empty fields are left unchanged.
+func (s *groupService) UpdateGroup(ctx context.Context, groupUUID string, req *models.UpdateGroupRequest) (*models.Group, error) {
+	if !utils.IsValidUUID(groupUUID) {
+		return nil, errors.NewInvalidValueError("uuid", groupUUID)
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireGroupAdmin(ctx, group.ID, req.ActingUserUUID, "update the group"); err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		if err := utils.ValidateName(req.Name); err != nil {
+			return nil, err
+		}
+		group.Name = req.Name
+	}
+
+	if req.Description != "" {
+		if err := utils.ValidateDescription(req.Description); err != nil {
+			return nil, err
+		}
+		group.Description = req.Description
+	}
+
+	if req.Timezone != "" {
+		if err := utils.ValidateTimezone(req.Timezone); err != nil {
+			return nil, err
+		}
+		group.Timezone = req.Timezone
+	}
+
+	if req.DefaultCurrency != "" {
+		if err := utils.ValidateCurrency(req.DefaultCurrency); err != nil {
+			return nil, err
+		}
+		group.DefaultCurrency = utils.NormalizeCurrency(req.DefaultCurrency)
+	}
+
+	if err := s.groupRepo.Update(ctx, nil, group); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to update group", zap.Error(err), zap.String("uuid", groupUUID))
+		return nil, err
+	}
+
+	s.emitter.Emit(ctx, events.GroupUpdated{Group: group})
+
+	group.Members, err = s.groupRepo.GetMembers(ctx, group.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get group members", zap.Error(err), zap.Int64("groupID", group.ID))
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Group updated successfully", zap.String("uuid", group.UUID), zap.String("timezone", group.Timezone))
 	return group, nil
 }
 
+// ArchiveGroup archives a group once every member's balance is settled in
+// every currency. Archived groups keep their expenses, settlements and
+// balances but take no new ones.
+func (s *groupService) ArchiveGroup(ctx context.Context, groupUUID models.GroupUUID, actingUserUUID models.UserUUID) (*models.Group, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("uuid", groupUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireGroupAdmin(ctx, group.ID, actingUserUUID.String(), "archive the group"); err != nil {
+		return nil, err
+	}
+	if group.ArchivedAt != nil {
+		return nil, errors.NewGroupArchivedError()
+	}
+
+	balances, err := s.balanceRepo.GetGroupBalancesAllCurrencies(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := make(map[string]string)
+	var names []string
+	for _, balance := range balances {
+		if balance.Balance.IsZero() {
+			continue
+		}
+		key, name := strconv.FormatInt(balance.UserID, 10), strconv.FormatInt(balance.UserID, 10)
+		if balance.User != nil {
+			key, name = balance.User.UUID, balance.User.Name
+		}
+		if _, seen := details[key]; seen {
+			details[key] += ", "
+		} else {
+			names = append(names, name)
+		}
+		details[key] += balance.Balance.StringFixed(2) + " " + balance.Currency
+	}
+	if len(details) > 0 {
+		appErr := errors.NewValidationError("Group cannot be archived while members have unsettled balances: " + strings.Join(names, ", "))
+		appErr.Details = details
+		return nil, appErr
+	}
+
+	archived, err := s.groupRepo.Archive(ctx, nil, group.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to archive group", zap.Error(err), zap.String("uuid", groupUUID.String()))
+		return nil, err
+	}
+	if !archived {
+		return nil, errors.NewGroupArchivedError()
+	}
+
+	group, err = s.GetGroupByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	s.emitter.Emit(ctx, events.GroupArchived{Group: group})
+
+	logging.FromContext(ctx, s.logger).Info("Group archived", zap.String("uuid", groupUUID.String()))
+	return group, nil
+}
+
+// UnarchiveGroup restores an archived group so it takes expenses and
+// settlements again
+func (s *groupService) UnarchiveGroup(ctx context.Context, groupUUID models.GroupUUID, actingUserUUID models.UserUUID) (*models.Group, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("uuid", groupUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireGroupAdmin(ctx, group.ID, actingUserUUID.String(), "unarchive the group"); err != nil {
+		return nil, err
+	}
+
+	unarchived, err := s.groupRepo.Unarchive(ctx, nil, group.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to unarchive group", zap.Error(err), zap.String("uuid", groupUUID.String()))
+		return nil, err
+	}
+	if !unarchived {
+		return nil, errors.NewValidationError("Group is not archived")
+	}
+
+	group, err = s.GetGroupByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	s.emitter.Emit(ctx, events.GroupUnarchived{Group: group})
+
+	logging.FromContext(ctx, s.logger).Info("Group unarchived", zap.String("uuid", groupUUID.String()))
+	return group, nil
+}
+
+// ensureGroupActive rejects new expenses and settlements in an archived group
+func ensureGroupActive(group *models.Group) error {
+	if group.ArchivedAt != nil {
+		return errors.NewGroupArchivedError()
+	}
+	return nil
+}
+
+// resolveGroupTimezone validates a requested group timezone, defaulting to UTC
+func resolveGroupTimezone(timezone string) (string, error) {
+	if timezone == "" {
+		return utils.DefaultTimezone, nil
+	}
+	if err := utils.ValidateTimezone(timezone); err != nil {
+		return "", err
+	}
+	return timezone, nil
+}
+
+// resolveDefaultCurrency validates a requested group default currency,
+// defaulting to USD
+func resolveDefaultCurrency(currency string) (string, error) {
+	if currency == "" {
+		return utils.DefaultCurrency, nil
+	}
+	if err := utils.ValidateCurrency(currency); err != nil {
+		return "", err
+	}
+	return utils.NormalizeCurrency(currency), nil
+}
+
+// resolveGroupCurrency validates the currency requested for an expense,
+// settlement or balance view of a group, falling back to the group's default
+// currency when none is given
+func resolveGroupCurrency(group *models.Group, currency string) (string, error) {
+	if currency == "" {
+		if group.DefaultCurrency == "" {
+			return utils.DefaultCurrency, nil
+		}
+		return group.DefaultCurrency, nil
+	}
+	if err := utils.ValidateCurrency(currency); err != nil {
+		return "", err
+	}
+	return utils.NormalizeCurrency(currency), nil
+}
+
+// ensureCurrencyInUse returns CURRENCY_MISMATCH, listing the group's
+// currencies, when the given user (any member when userID is 0) has balances
+// in other currencies but none in currency. A group with no balances yet
+// accepts any currency.
+func ensureCurrencyInUse(ctx context.Context, balanceRepo repository.BalanceRepository, groupID, userID int64, currency string) error {
+	balances, err := balanceRepo.GetGroupBalancesAllCurrencies(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	var inUse []string
+	usesOther := false
+	for _, balance := range balances {
+		if len(inUse) == 0 || inUse[len(inUse)-1] != balance.Currency {
+			inUse = append(inUse, balance.Currency)
+		}
+		if userID != 0 && balance.UserID != userID {
+			continue
+		}
+		if balance.Currency == currency {
+			return nil
+		}
+		usesOther = true
+	}
+
+	if !usesOther {
+		return nil
+	}
+	return errors.NewCurrencyMismatchError(currency, inUse)
+}
+
 // GetGroupByUUID retrieves a group by UUID
 func (s *groupService) GetGroupByUUID(ctx context.Context, uuid string) (*models.Group, error) {
 	if !utils.IsValidUUID(uuid) {
@@ -86,14 +549,17 @@ func (s *groupService) GetGroupByUUID(ctx context.Context, uuid string) (*models
 
 	group, err := s.groupRepo.GetByUUID(ctx, uuid)
 	if err != nil {
-		s.logger.Error("Failed to get group by UUID", zap.Error(err), zap.String("uuid", uuid))
+		logging.FromContext(ctx, s.logger).Error("Failed to get group by UUID", zap.Error(err), zap.String("uuid", uuid))
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
 		return nil, err
 	}
 
 	// Get members
 	members, err := s.groupRepo.GetMembers(ctx, group.ID)
 	if err != nil {
-		s.logger.Error("Failed to get group members", zap.Error(err), zap.Int64("groupID", group.ID))
+		logging.FromContext(ctx, s.logger).Error("Failed to get group members", zap.Error(err), zap.Int64("groupID", group.ID))
 		return nil, err
 	}
 
@@ -101,8 +567,66 @@ func (s *groupService) GetGroupByUUID(ctx context.Context, uuid string) (*models
 	return group, nil
 }
 
-// ListGroups retrieves a paginated list of groups
-func (s *groupService) ListGroups(ctx context.Context, page, limit int) ([]*models.Group, error) {
+// GetGroupSummary gathers the figures a group dashboard shows: member and
+// expense counts, expense totals per currency, current balances and the time
+// of the latest expense
+func (s *groupService) GetGroupSummary(ctx context.Context, groupUUID string) (*models.GroupSummary, error) {
+	if !utils.IsValidUUID(groupUUID) {
+		return nil, errors.NewInvalidValueError("uuid", groupUUID)
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	memberCount, err := s.groupRepo.CountMembers(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.expenseRepo.GetGroupCurrencyTotals(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := s.balanceRepo.GetGroupBalancesAllCurrencies(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.GroupSummary{
+		Group:       group,
+		MemberCount: memberCount,
+		Totals:      totals,
+		Balances:    []*models.UserBalance{},
+	}
+	for _, total := range totals {
+		summary.ExpenseCount += total.ExpenseCount
+		if summary.LastActivity == nil || total.LastExpenseAt.After(*summary.LastActivity) {
+			lastExpenseAt := total.LastExpenseAt
+			summary.LastActivity = &lastExpenseAt
+		}
+	}
+	for _, balance := range balances {
+		summary.Balances = append(summary.Balances, &models.UserBalance{
+			UserID:   balance.UserID,
+			GroupID:  balance.GroupID,
+			User:     balance.User,
+			Balance:  balance.Balance,
+			Currency: balance.Currency,
+		})
+	}
+
+	return summary, nil
+}
+
+// ListGroups retrieves a paginated list of groups, leaving out archived groups
+// unless includeArchived is set
+func (s *groupService) ListGroups(ctx context.Context, page, limit int, includeArchived bool) ([]*models.Group, int, error) {
 	// Validate pagination parameters
 	if page < 1 {
 		page = 1
@@ -113,25 +637,32 @@ func (s *groupService) ListGroups(ctx context.Context, page, limit int) ([]*mode
 
 	offset := (page - 1) * limit
 
-	groups, err := s.groupRepo.List(ctx, offset, limit)
+	groups, err := s.groupRepo.List(ctx, offset, limit, includeArchived)
 	if err != nil {
-		s.logger.Error("Failed to list groups", zap.Error(err))
-		return nil, err
+		logging.FromContext(ctx, s.logger).Error("Failed to list groups", zap.Error(err))
+		return nil, 0, err
 	}
 
-	return groups, nil
+	total, err := s.groupRepo.Count(ctx, includeArchived)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to count groups", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return groups, total, nil
 }
 
-// GetUserGroups retrieves groups that a user is a member of
-func (s *groupService) GetUserGroups(ctx context.Context, userUUID string, page, limit int) ([]*models.Group, error) {
+// GetUserGroups retrieves groups that a user is a member of, leaving out
+// archived groups unless includeArchived is set
+func (s *groupService) GetUserGroups(ctx context.Context, userUUID string, page, limit int, includeArchived bool) ([]*models.Group, int, error) {
 	if !utils.IsValidUUID(userUUID) {
-		return nil, errors.NewInvalidValueError("user_uuid", userUUID)
+		return nil, 0, errors.NewInvalidValueError("user_uuid", userUUID)
 	}
 
 	// Get user
 	user, err := s.userRepo.GetByUUID(ctx, userUUID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Validate pagination parameters
@@ -144,13 +675,19 @@ func (s *groupService) GetUserGroups(ctx context.Context, userUUID string, page,
 
 	offset := (page - 1) * limit
 
-	groups, err := s.groupRepo.GetUserGroups(ctx, user.ID, offset, limit)
+	groups, err := s.groupRepo.GetUserGroups(ctx, user.ID, offset, limit, includeArchived)
 	if err != nil {
-		s.logger.Error("Failed to get user groups", zap.Error(err), zap.String("userUUID", userUUID))
-		return nil, err
+		logging.FromContext(ctx, s.logger).Error("Failed to get user groups", zap.Error(err), zap.String("userUUID", userUUID))
+		return nil, 0, err
 	}
 
-	return groups, nil
+	total, err := s.groupRepo.CountUserGroups(ctx, user.ID, includeArchived)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to count user groups", zap.Error(err), zap.String("userUUID", userUUID))
+		return nil, 0, err
+	}
+
+	return groups, total, nil
 }
 
 // AddMember adds a user to a group
@@ -168,6 +705,13 @@ func (s *groupService) AddMember(ctx context.Context, groupUUID string, req *mod
 	if err != nil {
 		return err
 	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return err
+	}
+
+	if _, err := s.requireGroupAdmin(ctx, group.ID, req.ActingUserUUID, "add members"); err != nil {
+		return err
+	}
 
 	// Get user
 	user, err := s.userRepo.GetByUUID(ctx, req.UserUUID)
@@ -185,62 +729,504 @@ func (s *groupService) AddMember(ctx context.Context, groupUUID string, req *mod
 		return errors.NewAlreadyExistsError("User is already a member of this group")
 	}
 
+	members, err := s.groupRepo.GetMembers(ctx, group.ID)
+	if err != nil {
+		return err
+	}
+	if len(members) >= s.maxGroupSize {
+		return errors.NewValidationError(fmt.Sprintf("Groups cannot have more than %d members", s.maxGroupSize))
+	}
+
 	// Add member with transaction
-	err = s.db.WithTransaction(func(tx *database.Tx) error {
-		return s.groupRepo.AddMember(ctx, tx, group.ID, user.ID)
+	added := events.MemberAdded{GroupID: group.ID, UserID: user.ID}
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		if err := s.groupRepo.AddMember(ctx, tx, group.ID, user.ID, models.GroupRoleMember); err != nil {
+			return err
+		}
+		return events.Record(ctx, s.emitter, tx, added)
 	})
 
 	if err != nil {
-		s.logger.Error("Failed to add member to group", zap.Error(err),
+		logging.FromContext(ctx, s.logger).Error("Failed to add member to group", zap.Error(err),
 			zap.String("groupUUID", groupUUID), zap.String("userUUID", req.UserUUID))
 		return err
 	}
 
-	s.logger.Info("Member added to group successfully",
+	s.emitter.Emit(ctx, added)
+
+	logging.FromContext(ctx, s.logger).Info("Member added to group successfully",
 		zap.String("groupUUID", groupUUID), zap.String("userUUID", req.UserUUID))
 	return nil
 }
 
-// RemoveMember removes a user from a group
-func (s *groupService) RemoveMember(ctx context.Context, groupUUID, userUUID string) error {
+// maxMemberBatchSize caps how many users one AddMembers call can take, which
+// bounds the size of its transaction
+const maxMemberBatchSize = 50
+
+// AddMembers adds several users to a group in one transaction. Every UUID is
+// checked before anything is written; users who already belong to the group
+// are reported as already_member instead of failing the batch.
+func (s *groupService) AddMembers(ctx context.Context, groupUUID string, req *models.AddMembersRequest) (*models.AddMembersResponse, error) {
 	if !utils.IsValidUUID(groupUUID) {
-		return errors.NewInvalidValueError("group_uuid", groupUUID)
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
 	}
 
-	if !utils.IsValidUUID(userUUID) {
-		return errors.NewInvalidValueError("user_uuid", userUUID)
+	if len(req.UserUUIDs) == 0 {
+		return nil, errors.NewValidationError("user_uuids must list at least one user")
+	}
+	if len(req.UserUUIDs) > maxMemberBatchSize {
+		return nil, errors.NewValidationError(fmt.Sprintf("Cannot add more than %d members at once", maxMemberBatchSize))
 	}
 
-	// Get group
 	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
 	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireGroupAdmin(ctx, group.ID, req.ActingUserUUID, "add members"); err != nil {
+		return nil, err
+	}
+
+	// Resolve every user before touching the database so a single bad entry
+	// rejects the whole request with all problems reported at once
+	details := make(map[string]string)
+	seen := make(map[string]int)
+	var users []*models.User
+	for i, userUUID := range req.UserUUIDs {
+		field := fmt.Sprintf("user_uuids[%d]", i)
+
+		if !utils.IsValidUUID(userUUID) {
+			details[field] = "Invalid UUID"
+			continue
+		}
+
+		key := strings.ToLower(userUUID)
+		if first, exists := seen[key]; exists {
+			details[field] = fmt.Sprintf("Duplicate of user_uuids[%d]", first)
+			continue
+		}
+		seen[key] = i
+
+		user, err := s.userRepo.GetByUUID(ctx, userUUID)
+		if err != nil {
+			if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrCodeNotFound {
+				details[field] = "User not found"
+				continue
+			}
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if len(details) > 0 {
+		validationErr := errors.NewValidationError("Invalid group members")
+		validationErr.Details = details
+		return nil, validationErr
+	}
+
+	members, err := s.groupRepo.GetMembers(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	memberIDs := make(map[int64]bool, len(members))
+	for _, member := range members {
+		memberIDs[member.ID] = true
+	}
+
+	response := &models.AddMembersResponse{}
+	var toAdd []*models.User
+	for _, user := range users {
+		result := &models.AddMemberResult{UserUUID: user.UUID, User: user, Status: models.AddMemberAdded}
+		if memberIDs[user.ID] {
+			result.Status = models.AddMemberAlreadyMember
+		} else {
+			toAdd = append(toAdd, user)
+		}
+		response.Members = append(response.Members, result)
+	}
+	response.AddedCount = len(toAdd)
+
+	if len(members)+len(toAdd) > s.maxGroupSize {
+		return nil, errors.NewValidationError(fmt.Sprintf("Groups cannot have more than %d members", s.maxGroupSize))
+	}
+
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+		for _, user := range toAdd {
+			if err := s.groupRepo.AddMember(ctx, tx, group.ID, user.ID, models.GroupRoleMember); err != nil {
+				return err
+			}
+			batch.Add(events.MemberAdded{GroupID: group.ID, UserID: user.ID})
+		}
+		return batch.Record(ctx, tx, s.emitter)
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to add members to group", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return nil, err
+	}
+
+	batch.Emit(ctx, s.emitter)
+
+	logging.FromContext(ctx, s.logger).Info("Members added to group successfully",
+		zap.String("groupUUID", groupUUID), zap.Int("added", len(toAdd)), zap.Int("requested", len(users)))
+	return response, nil
+}
+
+// RemoveMember removes a user from a group. Only admins can remove members,
+// and the group's last admin cannot be removed.
+func (s *groupService) RemoveMember(ctx context.Context, groupUUID models.GroupUUID, userUUID, actingUserUUID models.UserUUID) error {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	if !utils.IsValidUUID(userUUID.String()) {
+		return errors.NewInvalidValueError("user_uuid", userUUID.String())
+	}
+
+	// Get group
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return err
+	}
+
+	if _, err := s.requireGroupAdmin(ctx, group.ID, actingUserUUID.String(), "remove members"); err != nil {
 		return err
 	}
 
 	// Get user
-	user, err := s.userRepo.GetByUUID(ctx, userUUID)
+	user, err := s.userRepo.GetByUUID(ctx, userUUID.String())
 	if err != nil {
 		return err
 	}
 
-	// Remove member with transaction
-	err = s.db.WithTransaction(func(tx *database.Tx) error {
-		return s.groupRepo.RemoveMember(ctx, tx, group.ID, user.ID)
+	role, err := s.groupRepo.GetMemberRole(ctx, group.ID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	details, outstanding, err := s.outstandingBalances(ctx, group.ID, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(outstanding) > 0 {
+		appErr := errors.NewValidationError("Member has outstanding balance of " + strings.Join(outstanding, ", ") + "; settle before removing")
+		appErr.Details = details
+		return appErr
+	}
+
+	if err := s.removeMembership(ctx, group, user, role); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Member removed from group successfully",
+		zap.String("groupUUID", groupUUID.String()), zap.String("userUUID", userUUID.String()))
+	return nil
+}
+
+// LeaveGroup removes the calling user from a group. Like RemoveMember it needs
+// the user to be settled up in every currency, and the last admin has to
+// promote someone else before leaving.
+func (s *groupService) LeaveGroup(ctx context.Context, groupUUID models.GroupUUID, req *models.LeaveGroupRequest) error {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	if !utils.IsValidUUID(req.UserUUID) {
+		return errors.NewInvalidValueError("user_uuid", req.UserUUID)
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByUUID(ctx, req.UserUUID)
+	if err != nil {
+		return err
+	}
+
+	role, err := s.groupRepo.GetMemberRole(ctx, group.ID, user.ID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrCodeNotFound {
+			return errors.NewValidationError("User is not a member of this group")
+		}
+		return err
+	}
+
+	details, outstanding, err := s.outstandingBalances(ctx, group.ID, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(outstanding) > 0 {
+		simplifyPath := "/api/v1/groups/" + group.UUID + "/simplify-debts"
+		appErr := errors.NewValidationError("You have an outstanding balance of " + strings.Join(outstanding, ", ") +
+			"; settle up before leaving. GET " + simplifyPath + " suggests the payments that clear it")
+		details["simplify_debts"] = simplifyPath
+		appErr.Details = details
+		return appErr
+	}
+
+	if err := s.removeMembership(ctx, group, user, role); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Member left group",
+		zap.String("groupUUID", groupUUID.String()), zap.String("userUUID", req.UserUUID))
+	return nil
+}
+
+// outstandingBalances returns a member's non-zero balances in a group, keyed by
+// currency in details and formatted as "30.00 USD" in outstanding. A departing
+// member's balances would be stranded in user_balances, so only members who
+// are settled up in every currency can leave.
+func (s *groupService) outstandingBalances(ctx context.Context, groupID, userID int64) (map[string]string, []string, error) {
+	balances, err := s.balanceRepo.GetGroupBalancesAllCurrencies(ctx, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	details := make(map[string]string)
+	var outstanding []string
+	for _, balance := range balances {
+		if balance.UserID != userID || balance.Balance.IsZero() {
+			continue
+		}
+		details[balance.Currency] = balance.Balance.StringFixed(2)
+		outstanding = append(outstanding, balance.Balance.StringFixed(2)+" "+balance.Currency)
+	}
+
+	return details, outstanding, nil
+}
+
+// removeMembership deletes a membership, refusing to remove the group's last admin
+func (s *groupService) removeMembership(ctx context.Context, group *models.Group, user *models.User, role models.GroupRole) error {
+	removed := events.MemberRemoved{GroupID: group.ID, UserID: user.ID}
+	err := s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		if role == models.GroupRoleAdmin {
+			if err := s.ensureAnotherAdmin(ctx, tx, group.ID); err != nil {
+				return err
+			}
+		}
+		if err := s.groupRepo.RemoveMember(ctx, tx, group.ID, user.ID); err != nil {
+			return err
+		}
+		return events.Record(ctx, s.emitter, tx, removed)
 	})
 
 	if err != nil {
-		s.logger.Error("Failed to remove member from group", zap.Error(err),
-			zap.String("groupUUID", groupUUID), zap.String("userUUID", userUUID))
+		logging.FromContext(ctx, s.logger).Error("Failed to remove member from group", zap.Error(err),
+			zap.String("groupUUID", group.UUID), zap.String("userUUID", user.UUID))
 		return err
 	}
 
-	s.logger.Info("Member removed from group successfully",
-		zap.String("groupUUID", groupUUID), zap.String("userUUID", userUUID))
+	s.emitter.Emit(ctx, removed)
 	return nil
 }
 
-// GetGroupMembers retrieves all members of a group
-func (s *groupService) GetGroupMembers(ctx context.Context, groupUUID string) ([]*models.User, error) {
+// UpdateMemberRole changes a member's role. Only admins can change roles, and
+// the group's last admin cannot be demoted.
+func (s *groupService) UpdateMemberRole(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, req *models.UpdateMemberRoleRequest) (*models.User, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	if !utils.IsValidUUID(userUUID.String()) {
+		return nil, errors.NewInvalidValueError("user_uuid", userUUID.String())
+	}
+
+	if err := req.Role.Validate(); err != nil {
+		return nil, err
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.requireGroupAdmin(ctx, group.ID, req.ActingUserUUID, "change member roles"); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByUUID(ctx, userUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.groupRepo.GetMemberRole(ctx, group.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if role != req.Role {
+		changed := events.MemberRoleChanged{GroupID: group.ID, UserID: user.ID, Role: req.Role}
+		err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+			if role == models.GroupRoleAdmin {
+				if err := s.ensureAnotherAdmin(ctx, tx, group.ID); err != nil {
+					return err
+				}
+			}
+			if err := s.groupRepo.SetMemberRole(ctx, tx, group.ID, user.ID, req.Role); err != nil {
+				return err
+			}
+			return events.Record(ctx, s.emitter, tx, changed)
+		})
+
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to update member role", zap.Error(err),
+				zap.String("groupUUID", groupUUID.String()), zap.String("userUUID", userUUID.String()))
+			return nil, err
+		}
+
+		s.emitter.Emit(ctx, changed)
+
+		logging.FromContext(ctx, s.logger).Info("Member role updated",
+			zap.String("groupUUID", groupUUID.String()), zap.String("userUUID", userUUID.String()),
+			zap.String("role", string(req.Role)))
+	}
+
+	user.Role = req.Role
+	return user, nil
+}
+
+// TransferOwnership makes another member the owner (created_by) of a group.
+// Only the current owner may do this. The new owner is made an admin in the
+// same transaction; the previous owner keeps their role.
+func (s *groupService) TransferOwnership(ctx context.Context, groupUUID models.GroupUUID, req *models.TransferOwnershipRequest) (*models.Group, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	if !utils.IsValidUUID(req.NewOwnerUUID) {
+		return nil, errors.NewInvalidValueError("new_owner_uuid", req.NewOwnerUUID)
+	}
+
+	if !utils.IsValidUUID(req.ActingUserUUID) {
+		return nil, errors.NewInvalidValueError("acting_user_uuid", req.ActingUserUUID)
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	actingUser, err := s.userRepo.GetByUUID(ctx, req.ActingUserUUID)
+	if err != nil {
+		return nil, err
+	}
+	if actingUser.ID != group.CreatedBy {
+		return nil, errors.NewForbiddenError("Only the group owner can transfer ownership")
+	}
+
+	newOwner, err := s.userRepo.GetByUUID(ctx, req.NewOwnerUUID)
+	if err != nil {
+		return nil, err
+	}
+	if newOwner.ID == group.CreatedBy {
+		return nil, errors.NewValidationError("User already owns this group")
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, group.ID, newOwner.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		name := newOwner.Name
+		if name == "" {
+			name = newOwner.Email
+		}
+		appErr := errors.NewValidationError(fmt.Sprintf("%s is not a member of this group", name))
+		appErr.Details = map[string]string{"new_owner_uuid": newOwner.UUID}
+		return nil, appErr
+	}
+
+	transferred := events.GroupOwnershipTransferred{GroupID: group.ID, PreviousUserID: group.CreatedBy, UserID: newOwner.ID}
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		if err := s.groupRepo.SetOwner(ctx, tx, group.ID, newOwner.ID); err != nil {
+			return err
+		}
+		if err := s.groupRepo.SetMemberRole(ctx, tx, group.ID, newOwner.ID, models.GroupRoleAdmin); err != nil {
+			return err
+		}
+		return events.Record(ctx, s.emitter, tx, transferred)
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to transfer group ownership", zap.Error(err),
+			zap.String("groupUUID", groupUUID.String()), zap.String("newOwnerUUID", newOwner.UUID))
+		return nil, err
+	}
+
+	group.CreatedBy = newOwner.ID
+	s.emitter.Emit(ctx, transferred)
+
+	group.Members, err = s.groupRepo.GetMembers(ctx, group.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get group members", zap.Error(err), zap.Int64("groupID", group.ID))
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Group ownership transferred",
+		zap.String("groupUUID", groupUUID.String()), zap.String("newOwnerUUID", newOwner.UUID))
+	return group, nil
+}
+
+// requireGroupAdmin resolves the acting user and checks that they are an
+// admin of the group. action completes the "Only group admins can ..." error.
+func (s *groupService) requireGroupAdmin(ctx context.Context, groupID int64, actingUserUUID, action string) (*models.User, error) {
+	if !utils.IsValidUUID(actingUserUUID) {
+		return nil, errors.NewInvalidValueError("acting_user_uuid", actingUserUUID)
+	}
+
+	actingUser, err := s.userRepo.GetByUUID(ctx, actingUserUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.groupRepo.GetMemberRole(ctx, groupID, actingUser.ID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrCodeNotFound {
+			return nil, err
+		}
+	}
+	if role != models.GroupRoleAdmin {
+		return nil, errors.NewForbiddenError("Only group admins can " + action)
+	}
+
+	return actingUser, nil
+}
+
+// ensureAnotherAdmin rejects a change that would take away the group's last
+// admin. It must run in the transaction that makes the change.
+func (s *groupService) ensureAnotherAdmin(ctx context.Context, tx *database.Tx, groupID int64) error {
+	admins, err := s.groupRepo.CountAdminsForUpdate(ctx, tx, groupID)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return errors.NewValidationError("A group must keep at least one admin; promote another member first")
+	}
+	return nil
+}
+
+// GetGroupMembers retrieves all members of a group with their role, join
+// time and current balance in the group's default currency
+func (s *groupService) GetGroupMembers(ctx context.Context, groupUUID string) ([]*models.GroupMember, error) {
 	if !utils.IsValidUUID(groupUUID) {
 		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
 	}
@@ -250,12 +1236,34 @@ func (s *groupService) GetGroupMembers(ctx context.Context, groupUUID string) ([
 	if err != nil {
 		return nil, err
 	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
 
-	members, err := s.groupRepo.GetMembers(ctx, group.ID)
+	members, err := s.groupRepo.GetMemberships(ctx, group.ID)
 	if err != nil {
-		s.logger.Error("Failed to get group members", zap.Error(err), zap.String("groupUUID", groupUUID))
+		logging.FromContext(ctx, s.logger).Error("Failed to get group members", zap.Error(err), zap.String("groupUUID", groupUUID))
 		return nil, err
 	}
 
+	currency, err := resolveGroupCurrency(group, "")
+	if err != nil {
+		return nil, err
+	}
+	balances, err := s.balanceRepo.GetGroupBalances(ctx, group.ID, currency)
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[int64]decimal.Decimal, len(balances))
+	for _, balance := range balances {
+		byUser[balance.UserID] = balance.Balance
+	}
+
+	// Members without a balance row yet are settled up
+	for _, member := range members {
+		member.Balance = byUser[member.UserID]
+		member.Currency = currency
+	}
+
 	return members, nil
 }
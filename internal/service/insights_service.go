@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	// insightsMonthLayout is the layout of the month query parameter
+	insightsMonthLayout = "2006-01"
+	// insightsWindowMonths is the number of trailing months covered by insights
+	insightsWindowMonths = 6
+	// insightsTopGroups is the maximum number of top groups returned per currency
+	insightsTopGroups = 5
+)
+
+type insightsService struct {
+	insightsRepo repository.InsightsRepository
+	userRepo     repository.UserRepository
+	logger       *zap.Logger
+}
+
+// NewInsightsService creates a new insights service
+func NewInsightsService(
+	insightsRepo repository.InsightsRepository,
+	userRepo repository.UserRepository,
+	logger *zap.Logger,
+) InsightsService {
+	return &insightsService{
+		insightsRepo: insightsRepo,
+		userRepo:     userRepo,
+		logger:       logger,
+	}
+}
+
+// currencyAccumulator collects the aggregates of a single currency
+type currencyAccumulator struct {
+	series     map[string]*models.MonthlyInsight
+	groups     map[int64]*models.GroupSpend
+	groupSpend decimal.Decimal
+}
+
+// GetUserInsights builds the spending dashboard of a user for the given month
+func (s *insightsService) GetUserInsights(ctx context.Context, userUUID, month string) (*models.UserInsights, error) {
+	if !utils.IsValidUUID(userUUID) {
+		return nil, errors.NewInvalidValueError("user_uuid", userUUID)
+	}
+
+	selected := time.Now().UTC()
+	if month != "" {
+		parsed, err := time.Parse(insightsMonthLayout, month)
+		if err != nil {
+			return nil, errors.NewInvalidValueError("month", month)
+		}
+		selected = parsed
+	}
+	selected = time.Date(selected.Year(), selected.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	user, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	first := selected.AddDate(0, -(insightsWindowMonths - 1), 0)
+	months := make([]string, 0, insightsWindowMonths)
+	inWindow := make(map[string]bool, insightsWindowMonths)
+	for m := first; !m.After(selected); m = m.AddDate(0, 1, 0) {
+		months = append(months, m.Format(insightsMonthLayout))
+		inWindow[m.Format(insightsMonthLayout)] = true
+	}
+
+	// Rows are bucketed into the months of their group's timezone, so the
+	// query covers the window in every timezone and rows that fall outside
+	// it locally are skipped below
+	from, _ := utils.MonthRange(first.Year(), first.Month(), utils.EarliestZone)
+	_, to := utils.MonthRange(selected.Year(), selected.Month(), utils.LatestZone)
+
+	paid, err := s.insightsRepo.GetPaidByMonth(ctx, user.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	share, err := s.insightsRepo.GetShareByMonth(ctx, user.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	groupSpend, err := s.insightsRepo.GetGroupSpendByMonth(ctx, user.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	sent, err := s.insightsRepo.GetSettlementsSentByMonth(ctx, user.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	received, err := s.insightsRepo.GetSettlementsReceivedByMonth(ctx, user.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	currentMonth := selected.Format(insightsMonthLayout)
+	accumulators := make(map[string]*currencyAccumulator)
+	accumulatorFor := func(currency string) *currencyAccumulator {
+		acc, exists := accumulators[currency]
+		if !exists {
+			acc = &currencyAccumulator{
+				series: make(map[string]*models.MonthlyInsight),
+				groups: make(map[int64]*models.GroupSpend),
+			}
+			for _, m := range months {
+				acc.series[m] = &models.MonthlyInsight{Month: m}
+			}
+			accumulators[currency] = acc
+		}
+		return acc
+	}
+	groupFor := func(acc *currencyAccumulator, aggregate *models.InsightAggregate) *models.GroupSpend {
+		group, exists := acc.groups[aggregate.GroupID]
+		if !exists {
+			group = &models.GroupSpend{
+				Group: &models.Group{
+					ID:   aggregate.GroupID,
+					UUID: aggregate.GroupUUID,
+					Name: aggregate.GroupName,
+				},
+			}
+			acc.groups[aggregate.GroupID] = group
+		}
+		return group
+	}
+
+	for _, aggregate := range share {
+		if !inWindow[aggregate.Month] {
+			continue
+		}
+		acc := accumulatorFor(aggregate.Currency)
+		if point, exists := acc.series[aggregate.Month]; exists {
+			point.Spent = point.Spent.Add(aggregate.Amount)
+		}
+		if aggregate.Month == currentMonth {
+			group := groupFor(acc, aggregate)
+			group.Spent = group.Spent.Add(aggregate.Amount)
+		}
+	}
+	for _, aggregate := range paid {
+		if !inWindow[aggregate.Month] {
+			continue
+		}
+		acc := accumulatorFor(aggregate.Currency)
+		if point, exists := acc.series[aggregate.Month]; exists {
+			point.Paid = point.Paid.Add(aggregate.Amount)
+		}
+		if aggregate.Month == currentMonth {
+			group := groupFor(acc, aggregate)
+			group.Paid = group.Paid.Add(aggregate.Amount)
+		}
+	}
+	for _, aggregate := range sent {
+		if !inWindow[aggregate.Month] {
+			continue
+		}
+		acc := accumulatorFor(aggregate.Currency)
+		if point, exists := acc.series[aggregate.Month]; exists {
+			point.SettledOut = point.SettledOut.Add(aggregate.Amount)
+		}
+	}
+	for _, aggregate := range received {
+		if !inWindow[aggregate.Month] {
+			continue
+		}
+		acc := accumulatorFor(aggregate.Currency)
+		if point, exists := acc.series[aggregate.Month]; exists {
+			point.SettledIn = point.SettledIn.Add(aggregate.Amount)
+		}
+	}
+	for _, aggregate := range groupSpend {
+		if aggregate.Month != currentMonth {
+			continue
+		}
+		acc := accumulatorFor(aggregate.Currency)
+		acc.groupSpend = acc.groupSpend.Add(aggregate.Amount)
+	}
+
+	currencies := make([]string, 0, len(accumulators))
+	for currency := range accumulators {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	insights := &models.UserInsights{
+		User:       user,
+		Month:      currentMonth,
+		Months:     months,
+		Currencies: make([]*models.CurrencyInsights, 0, len(currencies)),
+	}
+	for _, currency := range currencies {
+		insights.Currencies = append(insights.Currencies, s.buildCurrencyInsights(currency, months, accumulators[currency]))
+	}
+
+	logging.FromContext(ctx, s.logger).Info("User insights generated",
+		zap.String("user_uuid", userUUID),
+		zap.String("month", currentMonth),
+		zap.Int("currencies", len(insights.Currencies)))
+
+	return insights, nil
+}
+
+// buildCurrencyInsights derives the ratios and rankings for a single currency
+func (s *insightsService) buildCurrencyInsights(currency string, months []string, acc *currencyAccumulator) *models.CurrencyInsights {
+	hundred := decimal.NewFromInt(100)
+	currentMonth := months[len(months)-1]
+	previousMonth := months[len(months)-2]
+
+	result := &models.CurrencyInsights{
+		Currency:   currency,
+		TotalSpent: acc.series[currentMonth].Spent,
+		TotalPaid:  acc.series[currentMonth].Paid,
+		GroupSpend: acc.groupSpend,
+		Series:     make([]*models.MonthlyInsight, 0, len(months)),
+		TopGroups:  make([]*models.GroupSpend, 0, len(acc.groups)),
+	}
+
+	if acc.groupSpend.IsPositive() {
+		ratio := result.TotalSpent.Div(acc.groupSpend).Round(4)
+		result.ShareOfSpend = &ratio
+	}
+
+	// Outstanding is positive when the user still owes money for the window
+	windowSpent := decimal.Zero
+	for _, m := range months {
+		point := acc.series[m]
+		result.Series = append(result.Series, point)
+		windowSpent = windowSpent.Add(point.Spent)
+		result.Outstanding = result.Outstanding.
+			Add(point.Spent).
+			Sub(point.Paid).
+			Sub(point.SettledOut).
+			Add(point.SettledIn)
+	}
+
+	averageSpent := windowSpent.Div(decimal.NewFromInt(int64(len(months))))
+	if averageSpent.IsPositive() {
+		lag := decimal.Zero
+		if result.Outstanding.IsPositive() {
+			lag = result.Outstanding.Div(averageSpent).Round(2)
+		}
+		result.SettleUpLagMonths = &lag
+	}
+
+	previous := acc.series[previousMonth].Spent
+	result.Trend = &models.InsightTrend{
+		PreviousMonth: previousMonth,
+		PreviousSpent: previous,
+		Change:        result.TotalSpent.Sub(previous),
+	}
+	if !previous.IsZero() {
+		percent := result.Trend.Change.Div(previous).Mul(hundred).Round(2)
+		result.Trend.ChangePercent = &percent
+	}
+
+	for _, group := range acc.groups {
+		result.TopGroups = append(result.TopGroups, group)
+	}
+	sort.Slice(result.TopGroups, func(i, j int) bool {
+		if !result.TopGroups[i].Spent.Equal(result.TopGroups[j].Spent) {
+			return result.TopGroups[i].Spent.GreaterThan(result.TopGroups[j].Spent)
+		}
+		return result.TopGroups[i].Group.Name < result.TopGroups[j].Group.Name
+	})
+	if len(result.TopGroups) > insightsTopGroups {
+		result.TopGroups = result.TopGroups[:insightsTopGroups]
+	}
+
+	return result
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"expense-split-tracker/internal/models"
+	"io"
+	"time"
 )
 
 // UserService defines the interface for user business logic
@@ -10,45 +12,121 @@ type UserService interface {
 	CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error)
 	GetUserByUUID(ctx context.Context, uuid string) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
-	ListUsers(ctx context.Context, page, limit int) ([]*models.User, error)
+	ListUsers(ctx context.Context, page, limit int) ([]*models.User, int, error)
+	SearchUsers(ctx context.Context, query string, page, limit int) ([]*models.User, int, error)
+	LookupUsers(ctx context.Context, uuids []string) ([]*models.User, error)
 }
 
 // GroupService defines the interface for group business logic
 type GroupService interface {
 	CreateGroup(ctx context.Context, req *models.CreateGroupRequest, creatorUUID string) (*models.Group, error)
+	BootstrapGroup(ctx context.Context, req *models.BootstrapGroupRequest) (*models.BootstrapGroupResponse, error)
 	GetGroupByUUID(ctx context.Context, uuid string) (*models.Group, error)
-	ListGroups(ctx context.Context, page, limit int) ([]*models.Group, error)
-	GetUserGroups(ctx context.Context, userUUID string, page, limit int) ([]*models.Group, error)
+	UpdateGroup(ctx context.Context, groupUUID string, req *models.UpdateGroupRequest) (*models.Group, error)
+	GetGroupSummary(ctx context.Context, groupUUID string) (*models.GroupSummary, error)
+	ArchiveGroup(ctx context.Context, groupUUID models.GroupUUID, actingUserUUID models.UserUUID) (*models.Group, error)
+	UnarchiveGroup(ctx context.Context, groupUUID models.GroupUUID, actingUserUUID models.UserUUID) (*models.Group, error)
+	ListGroups(ctx context.Context, page, limit int, includeArchived bool) ([]*models.Group, int, error)
+	GetUserGroups(ctx context.Context, userUUID string, page, limit int, includeArchived bool) ([]*models.Group, int, error)
 
 	// Member operations
 	AddMember(ctx context.Context, groupUUID string, req *models.AddMemberRequest) error
-	RemoveMember(ctx context.Context, groupUUID, userUUID string) error
-	GetGroupMembers(ctx context.Context, groupUUID string) ([]*models.User, error)
+	AddMembers(ctx context.Context, groupUUID string, req *models.AddMembersRequest) (*models.AddMembersResponse, error)
+	RemoveMember(ctx context.Context, groupUUID models.GroupUUID, userUUID, actingUserUUID models.UserUUID) error
+	LeaveGroup(ctx context.Context, groupUUID models.GroupUUID, req *models.LeaveGroupRequest) error
+	UpdateMemberRole(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, req *models.UpdateMemberRoleRequest) (*models.User, error)
+	TransferOwnership(ctx context.Context, groupUUID models.GroupUUID, req *models.TransferOwnershipRequest) (*models.Group, error)
+	GetGroupMembers(ctx context.Context, groupUUID string) ([]*models.GroupMember, error)
 }
 
 // ExpenseService defines the interface for expense business logic
 type ExpenseService interface {
 	CreateExpense(ctx context.Context, req *models.CreateExpenseRequest) (*models.Expense, error)
+	ValidateExpense(ctx context.Context, req *models.CreateExpenseRequest) error
+	DuplicateExpense(ctx context.Context, uuid string, req *models.DuplicateExpenseRequest) (*models.Expense, error)
+	GetExpenseByUUID(ctx context.Context, uuid string) (*models.Expense, error)
+	UpdateExpense(ctx context.Context, uuid string, req *models.UpdateExpenseRequest) (*models.Expense, error)
+	DeleteExpense(ctx context.Context, uuid string) error
+	SetReceipt(ctx context.Context, uuid string, req *models.SetReceiptRequest) (*models.Expense, error)
 	ListExpenses(ctx context.Context, filter *models.ExpenseFilter) (*models.ExpenseListResponse, error)
-	GetGroupExpenses(ctx context.Context, groupUUID string, page, limit int) ([]*models.Expense, error)
-	GetUserExpenses(ctx context.Context, userUUID string, page, limit int) ([]*models.Expense, error)
+	GetGroupExpenses(ctx context.Context, groupUUID string, page, limit int, opts *models.ExpenseListOptions) ([]*models.Expense, int, error)
+	GetTopGroupExpenses(ctx context.Context, groupUUID string, filter *models.TopExpensesFilter) ([]*models.Expense, error)
+	GetUserExpenses(ctx context.Context, userUUID string, page, limit int, opts *models.ExpenseListOptions) ([]*models.Expense, int, error)
+	ImportExpenses(ctx context.Context, groupUUID string, file io.Reader, dryRun bool) (*models.ExpenseImportResult, error)
 }
 
 // SettlementService defines the interface for settlement business logic
 type SettlementService interface {
 	CreateSettlement(ctx context.Context, req *models.CreateSettlementRequest) (*models.Settlement, error)
 	GetSettlementByUUID(ctx context.Context, uuid string) (*models.Settlement, error)
+	VoidSettlement(ctx context.Context, uuid string) (*models.Settlement, error)
+	ConfirmSettlement(ctx context.Context, uuid string, req *models.RespondSettlementRequest) (*models.Settlement, error)
+	RejectSettlement(ctx context.Context, uuid string, req *models.RespondSettlementRequest) (*models.Settlement, error)
 	ListSettlements(ctx context.Context, filter *models.SettlementFilter) (*models.SettlementListResponse, error)
-	GetGroupSettlements(ctx context.Context, groupUUID string, page, limit int) ([]*models.Settlement, error)
-	GetUserSettlements(ctx context.Context, userUUID string, page, limit int) ([]*models.Settlement, error)
-	SimplifyDebts(ctx context.Context, groupUUID string) (*models.DebtSimplification, error)
+	GetGroupSettlements(ctx context.Context, groupUUID string, page, limit int) ([]*models.Settlement, int, error)
+	GetUserSettlements(ctx context.Context, userUUID string, page, limit int) ([]*models.Settlement, int, error)
+	SimplifyDebts(ctx context.Context, groupUUID string, req *models.SimplifyDebtsRequest) (*models.DebtSimplification, error)
+	ExecuteDebtSimplification(ctx context.Context, groupUUID string, req *models.ExecuteSimplificationRequest) ([]*models.Settlement, error)
+	SettleAllForUser(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, currency string) ([]*models.Settlement, error)
 }
 
 // BalanceService defines the interface for balance business logic
 type BalanceService interface {
-	GetGroupBalanceSheet(ctx context.Context, groupUUID string) (*models.BalanceSheet, error)
-	GetUserBalance(ctx context.Context, groupUUID, userUUID string) (*models.UserBalanceDetail, error)
-	GetDebtRelationships(ctx context.Context, groupUUID string) ([]*models.DebtRelationship, error)
+	GetGroupBalanceSheet(ctx context.Context, groupUUID models.GroupUUID, currency, convertTo string) (*models.BalanceSheet, error)
+	GetUserBalance(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, currency string) (*models.UserBalanceDetail, error)
+	GetUserSummary(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID) (*models.UserGroupSummary, error)
+	GetDebtRelationships(ctx context.Context, groupUUID models.GroupUUID, currency string) ([]*models.DebtRelationship, error)
+	GetUserCounterparties(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, currency string) (*models.UserCounterparties, error)
+	GetBalanceHistory(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, filter *models.BalanceHistoryFilter) ([]*models.BalanceHistoryEntry, int, error)
+	GetUserBalances(ctx context.Context, userUUID models.UserUUID, currency, convertTo string) (*models.UserBalanceOverview, error)
+	GetPairwiseNet(ctx context.Context, userUUID, otherUserUUID models.UserUUID, currency string) (*models.PairwiseNet, error)
+	RebuildGroupBalances(ctx context.Context, groupUUID models.GroupUUID) (*models.BalanceRebuild, error)
+	VerifyGroupBalances(ctx context.Context, groupUUID models.GroupUUID) (*models.BalanceVerification, error)
+}
+
+// InsightsService defines the interface for personal spending insights
+type InsightsService interface {
+	GetUserInsights(ctx context.Context, userUUID, month string) (*models.UserInsights, error)
+}
+
+// ReportService defines the interface for spending reports
+type ReportService interface {
+	GetCategoryReport(ctx context.Context, groupUUID string, filter *models.CategoryReportFilter) (*models.CategoryReport, error)
+	GetUserStats(ctx context.Context, userUUID string, filter *models.UserStatsFilter) (*models.UserStats, error)
+}
+
+// GroupLockService defines the interface for short-lived group write locks
+type GroupLockService interface {
+	Acquire(ctx context.Context, groupID int64, purpose models.GroupLockPurpose) (*models.GroupLock, error)
+	Release(ctx context.Context, lock *models.GroupLock) error
+}
+
+// ExportService defines the interface for exporting group data
+type ExportService interface {
+	ExportGroupCSV(ctx context.Context, groupUUID string, filter *models.ExportFilter, w io.Writer) error
+}
+
+// RecurringExpenseService defines the interface for recurring expense
+// templates and the scheduler that turns them into expenses
+type RecurringExpenseService interface {
+	CreateRecurringExpense(ctx context.Context, groupUUID models.GroupUUID, req *models.CreateRecurringExpenseRequest) (*models.RecurringExpense, error)
+	GetRecurringExpense(ctx context.Context, groupUUID models.GroupUUID, uuid models.RecurringExpenseUUID) (*models.RecurringExpense, error)
+	ListRecurringExpenses(ctx context.Context, groupUUID models.GroupUUID) ([]*models.RecurringExpense, error)
+	UpdateRecurringExpense(ctx context.Context, groupUUID models.GroupUUID, uuid models.RecurringExpenseUUID, req *models.UpdateRecurringExpenseRequest) (*models.RecurringExpense, error)
+	DeleteRecurringExpense(ctx context.Context, groupUUID models.GroupUUID, uuid models.RecurringExpenseUUID) error
+	RunDue(ctx context.Context, now time.Time) (int, error)
+	RunScheduler(ctx context.Context, interval time.Duration)
+}
+
+// WebhookService defines the interface for managing a group's webhooks
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, groupUUID models.GroupUUID, req *models.CreateWebhookRequest) (*models.Webhook, error)
+	GetWebhook(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context, groupUUID models.GroupUUID) ([]*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID, req *models.UpdateWebhookRequest) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID) error
+	ListDeadLetters(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID) ([]*models.WebhookDelivery, error)
+	RedeliverDeadLetter(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID, deliveryUUID models.WebhookDeliveryUUID) (*models.WebhookDelivery, error)
 }
 
 // Services aggregates all service interfaces
@@ -58,4 +136,10 @@ type Services struct {
 	Expense    ExpenseService
 	Settlement SettlementService
 	Balance    BalanceService
+	Insights   InsightsService
+	Report     ReportService
+	GroupLock  GroupLockService
+	Export     ExportService
+	Recurring  RecurringExpenseService
+	Webhook    WebhookService
 }
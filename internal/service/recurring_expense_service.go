@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// recurringBatchSize caps the number of due templates handled per scheduler tick
+const recurringBatchSize = 100
+
+type recurringExpenseService struct {
+	recurringRepo  repository.RecurringExpenseRepository
+	groupRepo      repository.GroupRepository
+	userRepo       repository.UserRepository
+	expenseService ExpenseService
+	logger         *zap.Logger
+}
+
+// NewRecurringExpenseService creates a new recurring expense service
+func NewRecurringExpenseService(
+	recurringRepo repository.RecurringExpenseRepository,
+	groupRepo repository.GroupRepository,
+	userRepo repository.UserRepository,
+	expenseService ExpenseService,
+	logger *zap.Logger,
+) RecurringExpenseService {
+	return &recurringExpenseService{
+		recurringRepo:  recurringRepo,
+		groupRepo:      groupRepo,
+		userRepo:       userRepo,
+		expenseService: expenseService,
+		logger:         logger,
+	}
+}
+
+// CreateRecurringExpense stores a new expense template for a group. The
+// template is validated like a regular expense so that bad splits are
+// reported now rather than at the first run.
+func (s *recurringExpenseService) CreateRecurringExpense(ctx context.Context, groupUUID models.GroupUUID, req *models.CreateRecurringExpenseRequest) (*models.RecurringExpense, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	recurring := &models.RecurringExpense{
+		UUID:    utils.GenerateUUID(),
+		GroupID: group.ID,
+		Group:   group,
+		Active:  true,
+	}
+	if err := s.applyTemplate(ctx, recurring, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.recurringRepo.Create(ctx, nil, recurring); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to create recurring expense", zap.Error(err), zap.String("groupUUID", groupUUID.String()))
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Recurring expense created", zap.String("uuid", recurring.UUID),
+		zap.String("frequency", string(recurring.Frequency)), zap.Time("nextRunAt", recurring.NextRunAt))
+	return s.recurringRepo.GetByUUID(ctx, recurring.UUID)
+}
+
+// GetRecurringExpense retrieves a recurring expense of a group
+func (s *recurringExpenseService) GetRecurringExpense(ctx context.Context, groupUUID models.GroupUUID, uuid models.RecurringExpenseUUID) (*models.RecurringExpense, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+	if !utils.IsValidUUID(uuid.String()) {
+		return nil, errors.NewInvalidValueError("uuid", uuid.String())
+	}
+
+	recurring, err := s.recurringRepo.GetByUUID(ctx, uuid.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Templates are only reachable through their own group
+	if !strings.EqualFold(recurring.Group.UUID, groupUUID.String()) {
+		return nil, errors.NewNotFoundError("Recurring expense")
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, recurring.GroupID); err != nil {
+		return nil, err
+	}
+
+	return recurring, nil
+}
+
+// ListRecurringExpenses retrieves every recurring expense of a group
+func (s *recurringExpenseService) ListRecurringExpenses(ctx context.Context, groupUUID models.GroupUUID) ([]*models.RecurringExpense, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	recurring, err := s.recurringRepo.GetGroupRecurringExpenses(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	if recurring == nil {
+		recurring = []*models.RecurringExpense{}
+	}
+
+	return recurring, nil
+}
+
+// UpdateRecurringExpense replaces the template of a recurring expense. The
+// schedule only moves when next_run_at is given; expenses already created
+// are not touched.
+func (s *recurringExpenseService) UpdateRecurringExpense(ctx context.Context, groupUUID models.GroupUUID, uuid models.RecurringExpenseUUID, req *models.UpdateRecurringExpenseRequest) (*models.RecurringExpense, error) {
+	recurring, err := s.GetRecurringExpense(ctx, groupUUID, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyTemplate(ctx, recurring, &req.CreateRecurringExpenseRequest); err != nil {
+		return nil, err
+	}
+	if req.Active != nil {
+		recurring.Active = *req.Active
+	}
+
+	if err := s.recurringRepo.Update(ctx, nil, recurring); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to update recurring expense", zap.Error(err), zap.String("uuid", uuid.String()))
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Recurring expense updated", zap.String("uuid", uuid.String()))
+	return s.recurringRepo.GetByUUID(ctx, recurring.UUID)
+}
+
+// DeleteRecurringExpense stops and removes a recurring expense. Expenses it
+// already created are kept.
+func (s *recurringExpenseService) DeleteRecurringExpense(ctx context.Context, groupUUID models.GroupUUID, uuid models.RecurringExpenseUUID) error {
+	recurring, err := s.GetRecurringExpense(ctx, groupUUID, uuid)
+	if err != nil {
+		return err
+	}
+
+	if err := s.recurringRepo.Delete(ctx, nil, recurring.ID); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to delete recurring expense", zap.Error(err), zap.String("uuid", uuid.String()))
+		return err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Recurring expense deleted", zap.String("uuid", uuid.String()))
+	return nil
+}
+
+// applyTemplate validates a create or update request against the group of
+// recurring and copies it over. A zero next_run_at schedules a new template
+// immediately and leaves an existing schedule alone.
+func (s *recurringExpenseService) applyTemplate(ctx context.Context, recurring *models.RecurringExpense, req *models.CreateRecurringExpenseRequest) error {
+	if err := req.Frequency.Validate(); err != nil {
+		return err
+	}
+	if req.SplitType == "" {
+		return errors.NewRequiredFieldError("split_type")
+	}
+
+	currency, err := resolveGroupCurrency(recurring.Group, req.Currency)
+	if err != nil {
+		return err
+	}
+	category := utils.NormalizeCategory(req.Category)
+	if category == "" {
+		category = models.DefaultExpenseCategory
+	}
+
+	if err := s.expenseService.ValidateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   recurring.Group.UUID,
+		PaidByUUID:  req.PaidByUUID,
+		Amount:      req.Amount,
+		Currency:    currency,
+		Description: req.Description,
+		Category:    category,
+		SplitType:   req.SplitType,
+		Splits:      req.Splits,
+	}); err != nil {
+		return err
+	}
+
+	payer, err := s.userRepo.GetByUUID(ctx, req.PaidByUUID)
+	if err != nil {
+		return err
+	}
+
+	// TIMESTAMP columns hold whole seconds; the scheduler matches on them exactly
+	now := time.Now().Truncate(time.Second)
+	nextRunAt := req.NextRunAt.Truncate(time.Second)
+	switch {
+	case !nextRunAt.IsZero():
+		if nextRunAt.Before(now.Add(-24 * time.Hour)) {
+			return errors.NewValidationError("next_run_at cannot be more than 1 day in the past")
+		}
+		recurring.StartsAt = nextRunAt
+		recurring.NextRunAt = nextRunAt
+	case recurring.NextRunAt.IsZero():
+		recurring.StartsAt = now
+		recurring.NextRunAt = now
+	}
+
+	recurring.PaidBy = payer.ID
+	recurring.Payer = payer
+	recurring.Amount = req.Amount
+	recurring.Currency = currency
+	recurring.Description = req.Description
+	recurring.Category = category
+	recurring.SplitType = req.SplitType
+	recurring.Splits = req.Splits
+	recurring.Frequency = req.Frequency
+	return nil
+}
+
+// RunScheduler materializes due recurring expenses every interval until ctx
+// is cancelled. It runs once immediately so runs missed while the server was
+// down are caught up on start. A run in progress is allowed to finish before
+// it returns.
+func (s *recurringExpenseService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logging.FromContext(ctx, s.logger).Info("Recurring expense scheduler started", zap.Duration("interval", interval))
+	for {
+		created, err := s.RunDue(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to run recurring expenses", zap.Error(err))
+		}
+		if created > 0 {
+			logging.FromContext(ctx, s.logger).Info("Recurring expenses created", zap.Int("count", created))
+		}
+
+		select {
+		case <-ctx.Done():
+			logging.FromContext(ctx, s.logger).Info("Recurring expense scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue creates the expenses of every run due at now and returns how many
+// were created. Each run goes through ExpenseService.CreateExpense, whose
+// transaction records the run on the expense; a run replayed after a restart
+// is rejected as a duplicate there and only moves the schedule forward.
+func (s *recurringExpenseService) RunDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.recurringRepo.GetDue(ctx, now, recurringBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, recurring := range due {
+		if ctx.Err() != nil {
+			return created, ctx.Err()
+		}
+
+		count, err := s.runRecurringExpense(ctx, recurring, now)
+		created += count
+		if err != nil {
+			// Leave the run due so the next tick retries it
+			logging.FromContext(ctx, s.logger).Error("Failed to run recurring expense", zap.Error(err), zap.String("uuid", recurring.UUID))
+		}
+	}
+
+	return created, nil
+}
+
+// runRecurringExpense creates every run of recurring due at now, oldest first
+func (s *recurringExpenseService) runRecurringExpense(ctx context.Context, recurring *models.RecurringExpense, now time.Time) (int, error) {
+	// Let the current run finish during shutdown; cancellation is checked between runs
+	runCtx := context.WithoutCancel(ctx)
+	loc := recurring.Group.Location()
+
+	created := 0
+	for !recurring.NextRunAt.After(now) {
+		if ctx.Err() != nil {
+			return created, ctx.Err()
+		}
+
+		runAt := recurring.NextRunAt
+		_, err := s.expenseService.CreateExpense(runCtx, recurring.ExpenseRequest(runAt))
+		if err != nil {
+			appErr, ok := err.(*errors.AppError)
+			switch {
+			case ok && appErr.Code == errors.ErrCodeAlreadyExists:
+				// Created before a restart; only the schedule is behind
+			case !ok || appErr.Code == errors.ErrCodeDatabase || appErr.Code == errors.ErrCodeInternal || appErr.Code == errors.ErrCodeGroupLocked:
+				return created, err
+			default:
+				// The template no longer fits the group, e.g. the payer left;
+				// skip this run rather than retrying it forever
+				logging.FromContext(ctx, s.logger).Warn("Skipping recurring expense run", zap.Error(err),
+					zap.String("uuid", recurring.UUID), zap.Time("runAt", runAt))
+			}
+		} else {
+			created++
+		}
+
+		nextRunAt := recurring.Frequency.Next(runAt, recurring.StartsAt, loc)
+		advanced, err := s.recurringRepo.Advance(runCtx, nil, recurring.ID, runAt, nextRunAt)
+		if err != nil {
+			return created, err
+		}
+		if !advanced {
+			// Rescheduled by an update or another server in the meantime
+			return created, nil
+		}
+		recurring.NextRunAt = nextRunAt
+	}
+
+	return created, nil
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// reportPercentagePlaces is the number of decimal places report percentages are rounded to
+const reportPercentagePlaces = 2
+
+type reportService struct {
+	reportRepo repository.ReportRepository
+	groupRepo  repository.GroupRepository
+	userRepo   repository.UserRepository
+	logger     *zap.Logger
+}
+
+// NewReportService creates a new report service
+func NewReportService(
+	reportRepo repository.ReportRepository,
+	groupRepo repository.GroupRepository,
+	userRepo repository.UserRepository,
+	logger *zap.Logger,
+) ReportService {
+	return &reportService{
+		reportRepo: reportRepo,
+		groupRepo:  groupRepo,
+		userRepo:   userRepo,
+		logger:     logger,
+	}
+}
+
+// GetCategoryReport breaks a group's expenses down by category for each
+// currency, with each category's share of that currency's total spend
+func (s *reportService) GetCategoryReport(ctx context.Context, groupUUID string, filter *models.CategoryReportFilter) (*models.CategoryReport, error) {
+	if !utils.IsValidUUID(groupUUID) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
+	}
+
+	query := *filter
+	if query.Currency != "" {
+		if err := utils.ValidateCurrency(query.Currency); err != nil {
+			return nil, err
+		}
+		query.Currency = utils.NormalizeCurrency(query.Currency)
+	}
+	if !query.FromDate.IsZero() && !query.ToDate.IsZero() && query.ToDate.Before(query.FromDate) {
+		return nil, errors.NewValidationError("to_date must not be before from_date")
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	// Date-only bounds are calendar days in the group's timezone
+	query.FromDate = startOfDateFilter(query.FromDate, group.Location())
+	query.ToDate = endOfDateFilter(query.ToDate, group.Location())
+
+	aggregates, err := s.reportRepo.GetCategoryTotals(ctx, group.ID, &query)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to get category totals", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return nil, err
+	}
+
+	return &models.CategoryReport{
+		Group:      group,
+		Currencies: buildCategoryBreakdown(aggregates),
+	}, nil
+}
+
+// buildCategoryBreakdown groups category totals by currency, largest
+// category first, and works out each category's percentage of its currency
+func buildCategoryBreakdown(aggregates []*models.CategoryAggregate) []*models.CurrencyCategories {
+	byCurrency := make(map[string]*models.CurrencyCategories)
+	for _, aggregate := range aggregates {
+		breakdown, ok := byCurrency[aggregate.Currency]
+		if !ok {
+			breakdown = &models.CurrencyCategories{Currency: aggregate.Currency, Categories: []*models.CategorySpend{}}
+			byCurrency[aggregate.Currency] = breakdown
+		}
+		breakdown.Total = breakdown.Total.Add(aggregate.Amount)
+		breakdown.Count += aggregate.Count
+		breakdown.Categories = append(breakdown.Categories, &models.CategorySpend{
+			Category: aggregate.Category,
+			Total:    aggregate.Amount,
+			Count:    aggregate.Count,
+		})
+	}
+
+	currencies := make([]*models.CurrencyCategories, 0, len(byCurrency))
+	for _, breakdown := range byCurrency {
+		for _, category := range breakdown.Categories {
+			category.Percentage = decimal.Zero
+			if breakdown.Total.IsPositive() {
+				category.Percentage = category.Total.Mul(decimal.NewFromInt(100)).Div(breakdown.Total).Round(reportPercentagePlaces)
+			}
+		}
+		sort.Slice(breakdown.Categories, func(i, j int) bool {
+			a, b := breakdown.Categories[i], breakdown.Categories[j]
+			if !a.Total.Equal(b.Total) {
+				return a.Total.GreaterThan(b.Total)
+			}
+			return a.Category < b.Category
+		})
+		currencies = append(currencies, breakdown)
+	}
+
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Currency < currencies[j].Currency })
+	return currencies
+}
+
+// GetUserStats summarizes a user's spending across all their groups, per
+// currency: what they paid, their share, the expenses and settlements they
+// were part of and their largest expense, with monthly averages
+func (s *reportService) GetUserStats(ctx context.Context, userUUID string, filter *models.UserStatsFilter) (*models.UserStats, error) {
+	if !utils.IsValidUUID(userUUID) {
+		return nil, errors.NewInvalidValueError("user_uuid", userUUID)
+	}
+	if !filter.FromDate.IsZero() && !filter.ToDate.IsZero() && filter.ToDate.Before(filter.FromDate) {
+		return nil, errors.NewValidationError("to_date must not be before from_date")
+	}
+
+	user, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := s.userStatsQuery(ctx, user.ID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	paid, err := s.reportRepo.GetUserPaidTotals(ctx, user.ID, query)
+	if err != nil {
+		return nil, err
+	}
+	share, err := s.reportRepo.GetUserShareTotals(ctx, user.ID, query)
+	if err != nil {
+		return nil, err
+	}
+	activity, err := s.reportRepo.GetUserExpenseActivity(ctx, user.ID, query)
+	if err != nil {
+		return nil, err
+	}
+	settlements, err := s.reportRepo.GetUserSettlementTotals(ctx, user.ID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	byCurrency := make(map[string]*models.CurrencyUserStats)
+	statsFor := func(currency string) *models.CurrencyUserStats {
+		stats, ok := byCurrency[currency]
+		if !ok {
+			stats = &models.CurrencyUserStats{Currency: currency}
+			byCurrency[currency] = stats
+		}
+		return stats
+	}
+	for _, aggregate := range paid {
+		statsFor(aggregate.Currency).TotalPaid = aggregate.Amount
+	}
+	for _, aggregate := range share {
+		statsFor(aggregate.Currency).TotalShare = aggregate.Amount
+	}
+	for _, aggregate := range settlements {
+		stats := statsFor(aggregate.Currency)
+		stats.SettlementsSent = aggregate.SentCount
+		stats.SettlementsSentAmount = aggregate.SentAmount
+		stats.SettlementsReceived = aggregate.ReceivedCount
+		stats.SettlementsReceivedAmount = aggregate.ReceivedAmount
+	}
+
+	// Averages run up to to_date, or the current month when it is open
+	end := filter.ToDate
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	firstExpense := make(map[string]time.Time, len(activity))
+	for _, entry := range activity {
+		statsFor(entry.Currency).ExpenseCount = entry.Count
+		firstExpense[entry.Currency] = entry.FirstExpenseDate
+	}
+
+	currencies := make([]*models.CurrencyUserStats, 0, len(byCurrency))
+	for currency, stats := range byCurrency {
+		if stats.ExpenseCount > 0 {
+			largest, err := s.reportRepo.GetUserLargestExpense(ctx, user.ID, currency, query)
+			if err != nil {
+				return nil, err
+			}
+			stats.LargestExpense = largest
+		}
+
+		start := filter.FromDate
+		if start.IsZero() {
+			start = firstExpense[currency]
+		}
+		stats.Months = monthsBetween(start, end)
+
+		months := decimal.NewFromInt(int64(stats.Months))
+		places := utils.AmountDecimals(currency)
+		stats.AveragePaidPerMonth = stats.TotalPaid.Div(months).Round(places)
+		stats.AverageSharePerMonth = stats.TotalShare.Div(months).Round(places)
+
+		currencies = append(currencies, stats)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Currency < currencies[j].Currency })
+
+	return &models.UserStats{User: user, Currencies: currencies}, nil
+}
+
+// userStatsQuery resolves the date filter of a user's stats. The stats span
+// groups, so date-only bounds become a range per group the user was active
+// in, covering those days in the group's timezone.
+func (s *reportService) userStatsQuery(ctx context.Context, userID int64, filter *models.UserStatsFilter) (*models.UserStatsFilter, error) {
+	query := *filter
+	if !isDateOnly(filter.FromDate) && !isDateOnly(filter.ToDate) {
+		return &query, nil
+	}
+
+	groups, err := s.reportRepo.GetUserActivityGroups(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		loc := group.Location()
+		query.GroupRanges = append(query.GroupRanges, &models.GroupDateRange{
+			GroupID:  group.ID,
+			FromDate: startOfDateFilter(filter.FromDate, loc),
+			ToDate:   endOfDateFilter(filter.ToDate, loc),
+		})
+	}
+	// FromDate and ToDate only apply when there are no group ranges
+	query.FromDate = startOfDateFilter(filter.FromDate, time.UTC)
+	query.ToDate = endOfDateFilter(filter.ToDate, time.UTC)
+	return &query, nil
+}
+
+// isDateOnly reports whether a date filter was given as a date rather than
+// a timestamp; dates are parsed as midnight UTC
+func isDateOnly(t time.Time) bool {
+	return !t.IsZero() && t.Equal(t.Truncate(24*time.Hour))
+}
+
+// startOfDateFilter moves a date-only from_date to the start of that day in
+// loc; timestamps are used as given
+func startOfDateFilter(from time.Time, loc *time.Location) time.Time {
+	if !isDateOnly(from) {
+		return from
+	}
+	start, _ := utils.DayRange(from.Year(), from.Month(), from.Day(), loc)
+	return start
+}
+
+// endOfDateFilter makes a date-only to_date cover the whole of that day in
+// loc; timestamps are used as given
+func endOfDateFilter(to time.Time, loc *time.Location) time.Time {
+	if !isDateOnly(to) {
+		return to
+	}
+	_, end := utils.DayRange(to.Year(), to.Month(), to.Day(), loc)
+	return end.Add(-time.Nanosecond)
+}
+
+// monthsBetween counts the calendar months from start to end, both included,
+// and is at least one
+func monthsBetween(start, end time.Time) int {
+	if start.IsZero() || end.Before(start) {
+		return 1
+	}
+	return (end.Year()-start.Year())*12 + int(end.Month()-start.Month()) + 1
+}
@@ -2,8 +2,14 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/bits"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/metrics"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/repository"
 	"expense-split-tracker/internal/utils"
@@ -13,12 +19,20 @@ import (
 	"go.uber.org/zap"
 )
 
+// overshootTolerance is the largest positive balance a settlement may leave the
+// recipient with before it is treated as a data-entry error
+var overshootTolerance = decimal.NewFromFloat(0.01)
+
 type settlementService struct {
 	settlementRepo repository.SettlementRepository
 	groupRepo      repository.GroupRepository
 	userRepo       repository.UserRepository
 	balanceRepo    repository.BalanceRepository
+	historyRepo    repository.BalanceHistoryRepository
+	lockRepo       repository.GroupLockRepository
 	db             DBTransactor
+	emitter        events.Emitter
+	metrics        metrics.Recorder
 	logger         *zap.Logger
 }
 
@@ -28,7 +42,11 @@ func NewSettlementService(
 	groupRepo repository.GroupRepository,
 	userRepo repository.UserRepository,
 	balanceRepo repository.BalanceRepository,
+	historyRepo repository.BalanceHistoryRepository,
+	lockRepo repository.GroupLockRepository,
 	db DBTransactor,
+	emitter events.Emitter,
+	recorder metrics.Recorder,
 	logger *zap.Logger,
 ) SettlementService {
 	return &settlementService{
@@ -36,7 +54,11 @@ func NewSettlementService(
 		groupRepo:      groupRepo,
 		userRepo:       userRepo,
 		balanceRepo:    balanceRepo,
+		historyRepo:    historyRepo,
+		lockRepo:       lockRepo,
 		db:             db,
+		emitter:        emitter,
+		metrics:        recorder,
 		logger:         logger,
 	}
 }
@@ -44,28 +66,35 @@ func NewSettlementService(
 // CreateSettlement creates a new settlement (debt payment)
 func (s *settlementService) CreateSettlement(ctx context.Context, req *models.CreateSettlementRequest) (*models.Settlement, error) {
 	// Validate input
-	if err := utils.ValidateAmount(req.Amount); err != nil {
+	if err := utils.ValidateAmount(req.Amount, req.Currency); err != nil {
 		return nil, err
 	}
 
-	currency := req.Currency
-	if currency == "" {
-		currency = "USD"
+	if req.Currency != "" {
+		if err := utils.ValidateCurrency(req.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	// Settlements recorded before methods existed count as "other"
+	method := req.Method
+	if method == "" {
+		method = models.SettlementMethodOther
 	}
-	if err := utils.ValidateCurrency(currency); err != nil {
+	if err := method.Validate(); err != nil {
 		return nil, err
 	}
 
-	if !utils.IsValidUUID(req.GroupUUID) {
-		return nil, errors.NewInvalidValueError("group_uuid", req.GroupUUID)
+	if !utils.IsValidUUID(req.GroupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", req.GroupUUID.String())
 	}
 
-	if !utils.IsValidUUID(req.FromUserUUID) {
-		return nil, errors.NewInvalidValueError("from_user_uuid", req.FromUserUUID)
+	if !utils.IsValidUUID(req.FromUserUUID.String()) {
+		return nil, errors.NewInvalidValueError("from_user_uuid", req.FromUserUUID.String())
 	}
 
-	if !utils.IsValidUUID(req.ToUserUUID) {
-		return nil, errors.NewInvalidValueError("to_user_uuid", req.ToUserUUID)
+	if !utils.IsValidUUID(req.ToUserUUID.String()) {
+		return nil, errors.NewInvalidValueError("to_user_uuid", req.ToUserUUID.String())
 	}
 
 	if req.FromUserUUID == req.ToUserUUID {
@@ -73,18 +102,32 @@ func (s *settlementService) CreateSettlement(ctx context.Context, req *models.Cr
 	}
 
 	// Get group and validate
-	group, err := s.groupRepo.GetByUUID(ctx, req.GroupUUID)
+	group, err := s.groupRepo.GetByUUID(ctx, req.GroupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+	if err := ensureGroupActive(group); err != nil {
+		return nil, err
+	}
+
+	currency, err := resolveGroupCurrency(group, req.Currency)
 	if err != nil {
 		return nil, err
 	}
+	if err := utils.ValidateAmountPrecision("amount", req.Amount, currency); err != nil {
+		return nil, err
+	}
 
 	// Get users and validate
-	fromUser, err := s.userRepo.GetByUUID(ctx, req.FromUserUUID)
+	fromUser, err := s.userRepo.GetByUUID(ctx, req.FromUserUUID.String())
 	if err != nil {
 		return nil, err
 	}
 
-	toUser, err := s.userRepo.GetByUUID(ctx, req.ToUserUUID)
+	toUser, err := s.userRepo.GetByUUID(ctx, req.ToUserUUID.String())
 	if err != nil {
 		return nil, err
 	}
@@ -106,20 +149,6 @@ func (s *settlementService) CreateSettlement(ctx context.Context, req *models.Cr
 		return nil, errors.NewValidationError("To user must be a member of the group")
 	}
 
-	// Get current balances to validate settlement amount
-	fromBalance, err := s.balanceRepo.GetByGroupAndUser(ctx, group.ID, fromUser.ID, currency)
-	if err != nil {
-		return nil, err
-	}
-
-	// Validate settlement amount (user cannot pay more than they owe)
-	if req.Amount.GreaterThan(fromBalance.Balance) {
-		return nil, errors.NewInsufficientFundError(
-			fromBalance.Balance.String(),
-			req.Amount.String(),
-		)
-	}
-
 	// Create settlement with transaction
 	settlement := &models.Settlement{
 		UUID:        utils.GenerateUUID(),
@@ -129,48 +158,318 @@ func (s *settlementService) CreateSettlement(ctx context.Context, req *models.Cr
 		Amount:      req.Amount,
 		Currency:    currency,
 		Description: req.Description,
+		Status:      models.SettlementStatusConfirmed,
+		Method:      method,
+	}
+	if req.RequireConfirmation {
+		settlement.Status = models.SettlementStatusPending
 	}
 
-	err = s.db.WithTransaction(func(tx *database.Tx) error {
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+
+		if err := ensureGroupUnlocked(ctx, s.lockRepo, tx, group.ID); err != nil {
+			return err
+		}
+
+		// Validating, inserting and updating balances under one transaction with
+		// the pair locked stops two concurrent settlements from both passing
+		if err := s.validateSettlementAmounts(ctx, tx, settlement, req.AllowOvershoot); err != nil {
+			return err
+		}
+
 		// Create settlement
 		if err := s.settlementRepo.Create(ctx, tx, settlement); err != nil {
 			return err
 		}
+		batch.Add(events.SettlementCreated{Settlement: settlement})
 
-		// Update balances
-		return s.updateBalancesAfterSettlement(ctx, tx, settlement)
+		// Pending settlements change balances once the receiver confirms them
+		if settlement.Status != models.SettlementStatusPending {
+			if err := s.updateBalancesAfterSettlement(ctx, tx, settlement, &batch); err != nil {
+				return err
+			}
+		}
+
+		return batch.Record(ctx, tx, s.emitter)
 	})
 
 	if err != nil {
-		s.logger.Error("Failed to create settlement", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to create settlement", zap.Error(err))
 		return nil, err
 	}
 
+	batch.Emit(ctx, s.emitter)
+	s.metrics.SettlementsCreated(1)
+
 	// Get complete settlement with relationships
 	settlement, err = s.settlementRepo.GetByUUID(ctx, settlement.UUID)
 	if err != nil {
 		return nil, err
 	}
 
-	s.logger.Info("Settlement created successfully", zap.String("uuid", settlement.UUID))
+	logging.FromContext(ctx, s.logger).Info("Settlement created successfully", zap.String("uuid", settlement.UUID))
 	return settlement, nil
 }
 
+// validateSettlementAmounts checks that the payer does not pay the receiver
+// more than they owe the receiver specifically and, unless allowOvershoot is
+// set, that the receiver is not left owing more than overshootTolerance
+// overall. Pairwise debts are not netted, so a payer can owe the receiver while
+// the receiver owes the group more; paying them then usually means the wrong
+// person was paid. The pair and the receiver's balance stay locked until the
+// transaction ends so neither can change in between.
+func (s *settlementService) validateSettlementAmounts(ctx context.Context, tx *database.Tx, settlement *models.Settlement, allowOvershoot bool) error {
+	owed, err := s.balanceRepo.GetDebtForUpdate(ctx, tx, settlement.GroupID, settlement.FromUserID, settlement.ToUserID, settlement.Currency)
+	if err != nil {
+		return err
+	}
+
+	if settlement.Amount.GreaterThan(owed) {
+		// Nothing owed at all may mean the payer picked a currency they have
+		// no balance in, which deserves a clearer error
+		if owed.IsZero() {
+			if err := ensureCurrencyInUse(ctx, s.balanceRepo, settlement.GroupID, settlement.FromUserID, settlement.Currency); err != nil {
+				return err
+			}
+		}
+		return errors.NewInsufficientFundError(owed.String(), settlement.Amount.String())
+	}
+
+	if allowOvershoot {
+		return nil
+	}
+
+	toBalance, err := s.balanceRepo.GetByGroupAndUserForUpdate(ctx, tx, settlement.GroupID, settlement.ToUserID, settlement.Currency)
+	if err != nil {
+		return err
+	}
+
+	toAfter := toBalance.Balance.Add(settlement.Amount)
+	if toAfter.GreaterThan(overshootTolerance) {
+		return errors.NewOvershootError(toBalance.Balance.StringFixed(2), toAfter.StringFixed(2))
+	}
+
+	return nil
+}
+
 // updateBalancesAfterSettlement updates user balances after creating a settlement
-func (s *settlementService) updateBalancesAfterSettlement(ctx context.Context, tx *database.Tx, settlement *models.Settlement) error {
+func (s *settlementService) updateBalancesAfterSettlement(ctx context.Context, tx *database.Tx, settlement *models.Settlement, batch *events.Batch) error {
 	// Reduce debt for the payer (fromUser owes less)
-	err := s.balanceRepo.UpdateBalance(ctx, tx, settlement.GroupID, settlement.FromUserID, settlement.Amount.Neg(), settlement.Currency)
+	err := adjustBalance(ctx, tx, s.balanceRepo, s.historyRepo, &models.BalanceHistoryEntry{
+		GroupID: settlement.GroupID, UserID: settlement.FromUserID, Currency: settlement.Currency, Delta: settlement.Amount.Neg(),
+		SourceType: models.BalanceHistorySourceSettlement, SourceID: settlement.ID,
+	})
 	if err != nil {
 		return err
 	}
+	batch.Add(events.BalanceAdjusted{GroupID: settlement.GroupID, UserID: settlement.FromUserID, Currency: settlement.Currency, Delta: settlement.Amount.Neg()})
 
 	// Reduce credit for the receiver (toUser is owed less)
-	err = s.balanceRepo.UpdateBalance(ctx, tx, settlement.GroupID, settlement.ToUserID, settlement.Amount, settlement.Currency)
+	err = adjustBalance(ctx, tx, s.balanceRepo, s.historyRepo, &models.BalanceHistoryEntry{
+		GroupID: settlement.GroupID, UserID: settlement.ToUserID, Currency: settlement.Currency, Delta: settlement.Amount,
+		SourceType: models.BalanceHistorySourceSettlement, SourceID: settlement.ID,
+	})
 	if err != nil {
 		return err
 	}
+	batch.Add(events.BalanceAdjusted{GroupID: settlement.GroupID, UserID: settlement.ToUserID, Currency: settlement.Currency, Delta: settlement.Amount})
 
-	return nil
+	// The payer owes the receiver less
+	return s.balanceRepo.UpdateDebt(ctx, tx, settlement.GroupID, settlement.FromUserID, settlement.ToUserID, settlement.Amount.Neg(), settlement.Currency)
+}
+
+// VoidSettlement marks a settlement as voided and reverses its effect on
+// both balances in one transaction. The row is kept for history.
+func (s *settlementService) VoidSettlement(ctx context.Context, uuid string) (*models.Settlement, error) {
+	settlement, err := s.GetSettlementByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if settlement.VoidedAt != nil {
+		return nil, errors.NewAlreadyVoidedError("Settlement")
+	}
+	// Only confirmed settlements touched balances; a confirmed settlement
+	// cannot change status again, so this check cannot go stale
+	if settlement.Status != models.SettlementStatusConfirmed {
+		return nil, errors.NewValidationError("Only confirmed settlements can be voided; pending settlements are rejected by their receiver")
+	}
+
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+
+		if err := ensureGroupUnlocked(ctx, s.lockRepo, tx, settlement.GroupID); err != nil {
+			return err
+		}
+
+		// Only one of two concurrent voids gets to reverse the balances
+		voided, err := s.settlementRepo.Void(ctx, tx, settlement.ID, actingUserID(ctx))
+		if err != nil {
+			return err
+		}
+		if !voided {
+			return errors.NewAlreadyVoidedError("Settlement")
+		}
+		batch.Add(events.SettlementVoided{Settlement: settlement})
+
+		if err := s.reverseBalancesForSettlement(ctx, tx, settlement, &batch); err != nil {
+			return err
+		}
+		return batch.Record(ctx, tx, s.emitter)
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to void settlement", zap.Error(err), zap.String("uuid", uuid))
+		return nil, err
+	}
+
+	batch.Emit(ctx, s.emitter)
+
+	settlement, err = s.settlementRepo.GetByUUID(ctx, settlement.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Settlement voided", zap.String("uuid", uuid))
+	return settlement, nil
+}
+
+// ConfirmSettlement applies a pending settlement to both balances on behalf of
+// its receiver. The payer's debt to the receiver and the receiver's overall
+// balance are checked again since either may have changed while the settlement
+// was pending.
+func (s *settlementService) ConfirmSettlement(ctx context.Context, uuid string, req *models.RespondSettlementRequest) (*models.Settlement, error) {
+	settlement, err := s.getPendingSettlementForReceiver(ctx, uuid, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		batch = events.Batch{}
+
+		if err := ensureGroupUnlocked(ctx, s.lockRepo, tx, settlement.GroupID); err != nil {
+			return err
+		}
+
+		if err := s.validateSettlementAmounts(ctx, tx, settlement, req.AllowOvershoot); err != nil {
+			return err
+		}
+
+		// Only one of two concurrent responses gets to apply the settlement
+		updated, err := s.settlementRepo.UpdateStatus(ctx, tx, settlement.ID, models.SettlementStatusPending, models.SettlementStatusConfirmed)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return errors.NewSettlementNotPendingError("no longer pending")
+		}
+		settlement.Status = models.SettlementStatusConfirmed
+		batch.Add(events.SettlementConfirmed{Settlement: settlement})
+
+		if err := s.updateBalancesAfterSettlement(ctx, tx, settlement, &batch); err != nil {
+			return err
+		}
+		return batch.Record(ctx, tx, s.emitter)
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to confirm settlement", zap.Error(err), zap.String("uuid", uuid))
+		return nil, err
+	}
+
+	batch.Emit(ctx, s.emitter)
+
+	settlement, err = s.settlementRepo.GetByUUID(ctx, settlement.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Settlement confirmed", zap.String("uuid", uuid))
+	return settlement, nil
+}
+
+// RejectSettlement marks a pending settlement as rejected on behalf of its
+// receiver. Balances are left alone.
+func (s *settlementService) RejectSettlement(ctx context.Context, uuid string, req *models.RespondSettlementRequest) (*models.Settlement, error) {
+	settlement, err := s.getPendingSettlementForReceiver(ctx, uuid, req)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.settlementRepo.UpdateStatus(ctx, nil, settlement.ID, models.SettlementStatusPending, models.SettlementStatusRejected)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to reject settlement", zap.Error(err), zap.String("uuid", uuid))
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.NewSettlementNotPendingError("no longer pending")
+	}
+	settlement.Status = models.SettlementStatusRejected
+	s.emitter.Emit(ctx, events.SettlementRejected{Settlement: settlement})
+
+	settlement, err = s.settlementRepo.GetByUUID(ctx, settlement.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Settlement rejected", zap.String("uuid", uuid))
+	return settlement, nil
+}
+
+// getPendingSettlementForReceiver loads a settlement that the requesting user
+// may respond to: both the user named in the request and the caller attached
+// to ctx, if any, must be its receiver, and it must still be pending
+func (s *settlementService) getPendingSettlementForReceiver(ctx context.Context, uuid string, req *models.RespondSettlementRequest) (*models.Settlement, error) {
+	if !utils.IsValidUUID(req.UserUUID.String()) {
+		return nil, errors.NewInvalidValueError("user_uuid", req.UserUUID.String())
+	}
+
+	settlement, err := s.GetSettlementByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if settlement.ToUser == nil || settlement.ToUser.UUID != req.UserUUID.String() {
+		return nil, errors.NewForbiddenError("Only the receiver of a settlement can confirm or reject it")
+	}
+	// The body can name anyone; a caller with an identity must be the receiver
+	if acting := actingUserID(ctx); acting != nil && *acting != settlement.ToUserID {
+		return nil, errors.NewForbiddenError("Only the receiver of a settlement can confirm or reject it")
+	}
+	if settlement.Status != models.SettlementStatusPending || settlement.VoidedAt != nil {
+		return nil, errors.NewSettlementNotPendingError(string(settlement.Status))
+	}
+
+	return settlement, nil
+}
+
+// reverseBalancesForSettlement undoes the balance changes made by updateBalancesAfterSettlement
+func (s *settlementService) reverseBalancesForSettlement(ctx context.Context, tx *database.Tx, settlement *models.Settlement, batch *events.Batch) error {
+	// The payer owes the amount again
+	err := adjustBalance(ctx, tx, s.balanceRepo, s.historyRepo, &models.BalanceHistoryEntry{
+		GroupID: settlement.GroupID, UserID: settlement.FromUserID, Currency: settlement.Currency, Delta: settlement.Amount,
+		SourceType: models.BalanceHistorySourceSettlement, SourceID: settlement.ID,
+	})
+	if err != nil {
+		return err
+	}
+	batch.Add(events.BalanceAdjusted{GroupID: settlement.GroupID, UserID: settlement.FromUserID, Currency: settlement.Currency, Delta: settlement.Amount})
+
+	// The receiver is owed the amount again
+	err = adjustBalance(ctx, tx, s.balanceRepo, s.historyRepo, &models.BalanceHistoryEntry{
+		GroupID: settlement.GroupID, UserID: settlement.ToUserID, Currency: settlement.Currency, Delta: settlement.Amount.Neg(),
+		SourceType: models.BalanceHistorySourceSettlement, SourceID: settlement.ID,
+	})
+	if err != nil {
+		return err
+	}
+	batch.Add(events.BalanceAdjusted{GroupID: settlement.GroupID, UserID: settlement.ToUserID, Currency: settlement.Currency, Delta: settlement.Amount.Neg()})
+
+	// The payer owes the receiver the amount again
+	return s.balanceRepo.UpdateDebt(ctx, tx, settlement.GroupID, settlement.FromUserID, settlement.ToUserID, settlement.Amount, settlement.Currency)
 }
 
 // GetSettlementByUUID retrieves a settlement by UUID
@@ -181,7 +480,10 @@ func (s *settlementService) GetSettlementByUUID(ctx context.Context, uuid string
 
 	settlement, err := s.settlementRepo.GetByUUID(ctx, uuid)
 	if err != nil {
-		s.logger.Error("Failed to get settlement by UUID", zap.Error(err), zap.String("uuid", uuid))
+		logging.FromContext(ctx, s.logger).Error("Failed to get settlement by UUID", zap.Error(err), zap.String("uuid", uuid))
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, settlement.GroupID); err != nil {
 		return nil, err
 	}
 
@@ -190,9 +492,13 @@ func (s *settlementService) GetSettlementByUUID(ctx context.Context, uuid string
 
 // ListSettlements retrieves settlements with filtering
 func (s *settlementService) ListSettlements(ctx context.Context, filter *models.SettlementFilter) (*models.SettlementListResponse, error) {
+	if err := requireListedGroupMember(ctx, s.groupRepo, filter.GroupUUID); err != nil {
+		return nil, err
+	}
+
 	settlements, total, err := s.settlementRepo.List(ctx, filter)
 	if err != nil {
-		s.logger.Error("Failed to list settlements", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to list settlements", zap.Error(err))
 		return nil, err
 	}
 
@@ -214,14 +520,17 @@ func (s *settlementService) ListSettlements(ctx context.Context, filter *models.
 }
 
 // GetGroupSettlements retrieves settlements for a specific group
-func (s *settlementService) GetGroupSettlements(ctx context.Context, groupUUID string, page, limit int) ([]*models.Settlement, error) {
+func (s *settlementService) GetGroupSettlements(ctx context.Context, groupUUID string, page, limit int) ([]*models.Settlement, int, error) {
 	if !utils.IsValidUUID(groupUUID) {
-		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
+		return nil, 0, errors.NewInvalidValueError("group_uuid", groupUUID)
 	}
 
 	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, 0, err
 	}
 
 	if page < 1 {
@@ -234,22 +543,28 @@ func (s *settlementService) GetGroupSettlements(ctx context.Context, groupUUID s
 
 	settlements, err := s.settlementRepo.GetGroupSettlements(ctx, group.ID, offset, limit)
 	if err != nil {
-		s.logger.Error("Failed to get group settlements", zap.Error(err), zap.String("groupUUID", groupUUID))
-		return nil, err
+		logging.FromContext(ctx, s.logger).Error("Failed to get group settlements", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return nil, 0, err
 	}
 
-	return settlements, nil
+	total, err := s.settlementRepo.CountGroupSettlements(ctx, group.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to count group settlements", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return nil, 0, err
+	}
+
+	return settlements, total, nil
 }
 
 // GetUserSettlements retrieves settlements for a specific user
-func (s *settlementService) GetUserSettlements(ctx context.Context, userUUID string, page, limit int) ([]*models.Settlement, error) {
+func (s *settlementService) GetUserSettlements(ctx context.Context, userUUID string, page, limit int) ([]*models.Settlement, int, error) {
 	if !utils.IsValidUUID(userUUID) {
-		return nil, errors.NewInvalidValueError("user_uuid", userUUID)
+		return nil, 0, errors.NewInvalidValueError("user_uuid", userUUID)
 	}
 
 	user, err := s.userRepo.GetByUUID(ctx, userUUID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if page < 1 {
@@ -262,31 +577,109 @@ func (s *settlementService) GetUserSettlements(ctx context.Context, userUUID str
 
 	settlements, err := s.settlementRepo.GetUserSettlements(ctx, user.ID, offset, limit)
 	if err != nil {
-		s.logger.Error("Failed to get user settlements", zap.Error(err), zap.String("userUUID", userUUID))
-		return nil, err
+		logging.FromContext(ctx, s.logger).Error("Failed to get user settlements", zap.Error(err), zap.String("userUUID", userUUID))
+		return nil, 0, err
 	}
 
-	return settlements, nil
+	total, err := s.settlementRepo.CountUserSettlements(ctx, user.ID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to count user settlements", zap.Error(err), zap.String("userUUID", userUUID))
+		return nil, 0, err
+	}
+
+	return settlements, total, nil
 }
 
 // SimplifyDebts calculates debt simplification suggestions for a group
-func (s *settlementService) SimplifyDebts(ctx context.Context, groupUUID string) (*models.DebtSimplification, error) {
+func (s *settlementService) SimplifyDebts(ctx context.Context, groupUUID string, req *models.SimplifyDebtsRequest) (*models.DebtSimplification, error) {
 	if !utils.IsValidUUID(groupUUID) {
 		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
 	}
+	mode, err := resolveSimplificationMode(req.Mode)
+	if err != nil {
+		return nil, err
+	}
 
 	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
 	if err != nil {
 		return nil, err
 	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	currency, err := resolveGroupCurrency(group, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	minAmount, err := resolveMinSuggestion(req.MinAmount, currency)
+	if err != nil {
+		return nil, err
+	}
 
-	// Get all balances for the group (assuming USD for now)
-	currency := "USD"
 	balances, err := s.balanceRepo.GetGroupBalances(ctx, group.ID, currency)
 	if err != nil {
 		return nil, err
 	}
 
+	debts, err := s.balanceRepo.GetGroupDebts(ctx, group.ID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	simplification := s.simplifyBalances(balances, currency, mode, minAmount)
+	simplification.OriginalTransactions = originalTransactionCount(balances, debts)
+	simplification.Savings = max(simplification.OriginalTransactions-simplification.SimplifiedTransactions, 0)
+
+	s.metrics.DebtSimplificationRun(metrics.SimplificationPreview)
+	return simplification, nil
+}
+
+// originalTransactionCount is how many payments would settle the group
+// without simplification: one per outstanding pairwise debt. Groups whose
+// balances predate pairwise debt tracking have no debts to count, so they get
+// the non-zero balances minus one, the most payments a simplification needs.
+func originalTransactionCount(balances []*models.Balance, debts []*models.DebtRelationship) int {
+	if len(debts) > 0 {
+		return len(debts)
+	}
+
+	nonZero := 0
+	for _, balance := range balances {
+		if !balance.Balance.IsZero() {
+			nonZero++
+		}
+	}
+	return max(nonZero-1, 0)
+}
+
+// resolveSimplificationMode defaults an empty mode to greedy
+func resolveSimplificationMode(mode models.SimplificationMode) (models.SimplificationMode, error) {
+	if mode == "" {
+		return models.SimplificationModeGreedy, nil
+	}
+	return mode, mode.Validate()
+}
+
+// resolveMinSuggestion returns the smallest suggestion worth making, which
+// defaults to the smallest unit of the currency
+func resolveMinSuggestion(minAmount *decimal.Decimal, currency string) (decimal.Decimal, error) {
+	if minAmount == nil {
+		return decimal.New(1, -utils.AmountDecimals(currency)), nil
+	}
+	if minAmount.IsNegative() {
+		return decimal.Zero, errors.NewInvalidValueError("min_amount", minAmount.String())
+	}
+	return *minAmount, nil
+}
+
+// maxOptimalBalances is the most non-zero balances the optimal mode searches;
+// its work doubles with each one, so larger groups fall back to greedy
+const maxOptimalBalances = 15
+
+// simplifyBalances turns a group's balances into the smallest set of payments
+// found by the algorithm mode selects, leaving out payments below minAmount
+func (s *settlementService) simplifyBalances(balances []*models.Balance, currency string, mode models.SimplificationMode, minAmount decimal.Decimal) *models.DebtSimplification {
 	// Separate creditors (negative balance - they are owed money) and debtors (positive balance - they owe money)
 	var creditors, debtors []*models.Balance
 	for _, balance := range balances {
@@ -294,32 +687,324 @@ func (s *settlementService) SimplifyDebts(ctx context.Context, groupUUID string)
 			debtors = append(debtors, balance)
 		} else if balance.Balance.LessThan(decimal.Zero) {
 			// Convert to positive for easier calculation
-			balance.Balance = balance.Balance.Abs()
-			creditors = append(creditors, balance)
+			creditors = append(creditors, &models.Balance{User: balance.User, Balance: balance.Balance.Abs()})
 		}
 	}
 
-	// Calculate minimum number of transactions needed
-	originalTransactions := len(debtors) * len(creditors) // Worst case: everyone owes everyone
-	if originalTransactions == 0 {
-		originalTransactions = 1 // At least 1 to avoid division by zero
+	algorithm := models.SimplificationModeGreedy
+	if mode == models.SimplificationModeOptimal && len(creditors)+len(debtors) <= maxOptimalBalances {
+		algorithm = models.SimplificationModeOptimal
 	}
 
-	// Generate settlement suggestions using greedy algorithm
-	suggestions := s.generateSettlementSuggestions(creditors, debtors, currency)
-
-	simplifiedTransactions := len(suggestions)
-	savings := originalTransactions - simplifiedTransactions
-	if savings < 0 {
-		savings = 0
+	var suggestions []*models.SettlementSuggestion
+	if algorithm == models.SimplificationModeOptimal {
+		suggestions = s.generateOptimalSettlementSuggestions(creditors, debtors, currency)
+	} else {
+		suggestions = s.generateSettlementSuggestions(creditors, debtors, currency)
 	}
+	suggestions, residual := roundSuggestions(suggestions, currency, minAmount)
 
 	return &models.DebtSimplification{
-		OriginalTransactions:   originalTransactions,
-		SimplifiedTransactions: simplifiedTransactions,
-		Savings:                savings,
+		SimplifiedTransactions: len(suggestions),
 		Suggestions:            suggestions,
-	}, nil
+		Algorithm:              algorithm,
+		Residual:               residual,
+		Hash:                   suggestionsHash(suggestions),
+	}
+}
+
+// roundSuggestions rounds each suggestion to the currency's precision and
+// drops those below minAmount. What that leaves unpaid, to the nearest unit,
+// is returned as the residual and added to the largest suggestion, so the
+// suggestions still add up to the total debt within one unit.
+func roundSuggestions(suggestions []*models.SettlementSuggestion, currency string, minAmount decimal.Decimal) ([]*models.SettlementSuggestion, decimal.Decimal) {
+	places := utils.AmountDecimals(currency)
+
+	total := decimal.Zero
+	paid := decimal.Zero
+	var kept []*models.SettlementSuggestion
+	var largest *models.SettlementSuggestion
+	for _, suggestion := range suggestions {
+		total = total.Add(suggestion.Amount)
+
+		amount := suggestion.Amount.Round(places)
+		if amount.IsZero() || amount.LessThan(minAmount) {
+			continue
+		}
+		if !amount.Equal(suggestion.Amount) {
+			suggestion.Amount = amount
+		}
+		paid = paid.Add(amount)
+		kept = append(kept, suggestion)
+		if largest == nil || amount.GreaterThan(largest.Amount) {
+			largest = suggestion
+		}
+	}
+
+	residual := total.Sub(paid).Round(places)
+	if residual.IsZero() {
+		return kept, decimal.Zero
+	}
+	if largest != nil && largest.Amount.Add(residual).IsPositive() {
+		largest.Amount = largest.Amount.Add(residual)
+	}
+	return kept, residual
+}
+
+// suggestionsHash fingerprints a list of suggestions so that a preview can be
+// compared with the suggestions computed when they are executed
+func suggestionsHash(suggestions []*models.SettlementSuggestion) string {
+	h := sha256.New()
+	for _, suggestion := range suggestions {
+		var from, to string
+		if suggestion.FromUser != nil {
+			from = suggestion.FromUser.UUID
+		}
+		if suggestion.ToUser != nil {
+			to = suggestion.ToUser.UUID
+		}
+		fmt.Fprintf(h, "%s|%s|%s|%s\n", from, to, suggestion.Amount.StringFixed(2), suggestion.Currency)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// ExecuteDebtSimplification records every current debt-simplification
+// suggestion of a group as a settlement in one transaction. The suggestions
+// are recomputed from locked balances; if expectedHash is set and they no
+// longer match what the client previewed, nothing is recorded.
+func (s *settlementService) ExecuteDebtSimplification(ctx context.Context, groupUUID string, req *models.ExecuteSimplificationRequest) ([]*models.Settlement, error) {
+	if !utils.IsValidUUID(groupUUID) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID)
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+	if err := ensureGroupActive(group); err != nil {
+		return nil, err
+	}
+
+	currency, err := resolveGroupCurrency(group, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := resolveSimplificationMode(req.Mode)
+	if err != nil {
+		return nil, err
+	}
+	minAmount, err := resolveMinSuggestion(req.MinAmount, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []*models.Settlement
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		created = nil
+		batch = events.Batch{}
+
+		if err := ensureGroupUnlocked(ctx, s.lockRepo, tx, group.ID); err != nil {
+			return err
+		}
+
+		balances, err := s.balanceRepo.GetGroupBalancesForUpdate(ctx, tx, group.ID, currency)
+		if err != nil {
+			return err
+		}
+
+		simplification := s.simplifyBalances(balances, currency, mode, minAmount)
+		if req.ExpectedHash != "" && req.ExpectedHash != simplification.Hash {
+			return errors.NewBalancesChangedError(req.ExpectedHash, simplification.Hash)
+		}
+
+		for _, suggestion := range simplification.Suggestions {
+			if suggestion.FromUser == nil || suggestion.ToUser == nil {
+				return errors.NewInternalError("Balance user could not be loaded")
+			}
+			settlement := &models.Settlement{
+				UUID:        utils.GenerateUUID(),
+				GroupID:     group.ID,
+				FromUserID:  suggestion.FromUser.ID,
+				ToUserID:    suggestion.ToUser.ID,
+				Amount:      suggestion.Amount,
+				Currency:    suggestion.Currency,
+				Description: "Debt simplification",
+				Status:      models.SettlementStatusConfirmed,
+				Method:      models.SettlementMethodOther,
+			}
+			if err := s.settlementRepo.Create(ctx, tx, settlement); err != nil {
+				return err
+			}
+			batch.Add(events.SettlementCreated{Settlement: settlement})
+
+			if err := s.updateBalancesAfterSettlement(ctx, tx, settlement, &batch); err != nil {
+				return err
+			}
+			created = append(created, settlement)
+		}
+
+		// The suggestions settle every balance, so they replace the pairwise
+		// debts they were computed from rather than add to them
+		if err := s.balanceRepo.ClearGroupDebts(ctx, tx, group.ID, currency); err != nil {
+			return err
+		}
+		return batch.Record(ctx, tx, s.emitter)
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to execute debt simplification", zap.Error(err), zap.String("groupUUID", groupUUID))
+		return nil, err
+	}
+
+	batch.Emit(ctx, s.emitter)
+	s.metrics.SettlementsCreated(len(created))
+	s.metrics.DebtSimplificationRun(metrics.SimplificationExecute)
+
+	settlements := make([]*models.Settlement, 0, len(created))
+	for _, settlement := range created {
+		settlement, err := s.settlementRepo.GetByUUID(ctx, settlement.UUID)
+		if err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, settlement)
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Debt simplification executed", zap.String("groupUUID", groupUUID), zap.Int("settlements", len(settlements)))
+	return settlements, nil
+}
+
+// SettleAllForUser pays off everything a user owes in a group: one confirmed
+// settlement per creditor for the full pairwise debt, recorded together with
+// the balance updates in one transaction. A user who is owed money overall has
+// nothing to pay and gets a validation error.
+func (s *settlementService) SettleAllForUser(ctx context.Context, groupUUID models.GroupUUID, userUUID models.UserUUID, currency string) ([]*models.Settlement, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+	if !utils.IsValidUUID(userUUID.String()) {
+		return nil, errors.NewInvalidValueError("user_uuid", userUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+	if err := ensureGroupActive(group); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByUUID(ctx, userUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, group.ID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, errors.NewValidationError("User is not a member of this group")
+	}
+
+	currency, err = resolveGroupCurrency(group, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []*models.Settlement
+	var batch events.Batch
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		created = nil
+		batch = events.Batch{}
+
+		if err := ensureGroupUnlocked(ctx, s.lockRepo, tx, group.ID); err != nil {
+			return err
+		}
+
+		// Locking the user's balance holds back expenses and settlements that
+		// would change what they owe while the debts are read
+		balance, err := s.balanceRepo.GetByGroupAndUserForUpdate(ctx, tx, group.ID, user.ID, currency)
+		if err != nil {
+			return err
+		}
+		if balance.Balance.IsNegative() {
+			return errors.NewValidationError(fmt.Sprintf("User is owed %s %s in this group and has nothing to settle", balance.Balance.Neg().StringFixed(2), currency))
+		}
+
+		debts, err := s.balanceRepo.GetGroupDebts(ctx, group.ID, currency)
+		if err != nil {
+			return err
+		}
+
+		for _, debt := range debts {
+			if debt.Debtor == nil || debt.Debtor.ID != user.ID {
+				continue
+			}
+
+			owed, err := s.balanceRepo.GetDebtForUpdate(ctx, tx, group.ID, user.ID, debt.Creditor.ID, currency)
+			if err != nil {
+				return err
+			}
+			if !owed.IsPositive() {
+				continue
+			}
+
+			settlement := &models.Settlement{
+				UUID:        utils.GenerateUUID(),
+				GroupID:     group.ID,
+				FromUserID:  user.ID,
+				ToUserID:    debt.Creditor.ID,
+				Amount:      owed,
+				Currency:    currency,
+				Description: "Settle all",
+				Status:      models.SettlementStatusConfirmed,
+				Method:      models.SettlementMethodOther,
+			}
+			if err := s.settlementRepo.Create(ctx, tx, settlement); err != nil {
+				return err
+			}
+			batch.Add(events.SettlementCreated{Settlement: settlement})
+
+			if err := s.updateBalancesAfterSettlement(ctx, tx, settlement, &batch); err != nil {
+				return err
+			}
+			created = append(created, settlement)
+		}
+
+		if len(created) == 0 {
+			return errors.NewValidationError("User does not owe anyone in this group")
+		}
+
+		return batch.Record(ctx, tx, s.emitter)
+	})
+
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to settle all debts", zap.Error(err), zap.String("groupUUID", groupUUID.String()), zap.String("userUUID", userUUID.String()))
+		return nil, err
+	}
+
+	batch.Emit(ctx, s.emitter)
+	s.metrics.SettlementsCreated(len(created))
+
+	settlements := make([]*models.Settlement, 0, len(created))
+	for _, settlement := range created {
+		settlement, err := s.settlementRepo.GetByUUID(ctx, settlement.UUID)
+		if err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, settlement)
+	}
+
+	logging.FromContext(ctx, s.logger).Info("User debts settled", zap.String("groupUUID", groupUUID.String()), zap.String("userUUID", userUUID.String()), zap.Int("settlements", len(settlements)))
+	return settlements, nil
 }
 
 // generateSettlementSuggestions generates optimal settlement suggestions
@@ -395,3 +1080,88 @@ func (s *settlementService) generateSettlementSuggestions(creditors, debtors []*
 
 	return suggestions
 }
+
+// generateOptimalSettlementSuggestions finds the fewest payments that settle
+// every balance. Each group of balances summing to zero can be settled on its
+// own with one payment fewer than it has members, so the fewest payments come
+// from splitting the balances into as many zero-sum groups as possible. The
+// search is exponential in the number of balances; see maxOptimalBalances.
+func (s *settlementService) generateOptimalSettlementSuggestions(creditors, debtors []*models.Balance, currency string) []*models.SettlementSuggestion {
+	// Debtors are positive and creditors negative, as stored
+	balances := make([]*models.Balance, 0, len(creditors)+len(debtors))
+	balances = append(balances, debtors...)
+	for _, c := range creditors {
+		balances = append(balances, &models.Balance{User: c.User, Balance: c.Balance.Neg()})
+	}
+
+	n := len(balances)
+	if n == 0 {
+		return nil
+	}
+	full := 1<<n - 1
+
+	// sums[mask] is the total balance of the members in mask
+	sums := make([]decimal.Decimal, full+1)
+	for mask := 1; mask <= full; mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		sums[mask] = sums[mask&(mask-1)].Add(balances[low].Balance)
+	}
+
+	// groups[mask] is the most zero-sum groups mask can be split into, counting
+	// an unbalanced remainder as nothing. Removing members one at a time, the
+	// prefixes that sum to zero mark where one group ends and the next begins.
+	groups := make([]int, full+1)
+	for mask := 1; mask <= full; mask++ {
+		best := 0
+		for rest := mask; rest != 0; rest &= rest - 1 {
+			if g := groups[mask&^(rest&-rest)]; g > best {
+				best = g
+			}
+		}
+		if sums[mask].IsZero() {
+			best++
+		}
+		groups[mask] = best
+	}
+
+	// Walk back from the full set, cutting a group off at every zero-sum prefix
+	var suggestions []*models.SettlementSuggestion
+	groupStart := full
+	mask := full
+	for mask != 0 {
+		want := groups[mask]
+		if sums[mask].IsZero() {
+			want--
+		}
+		for rest := mask; rest != 0; rest &= rest - 1 {
+			next := mask &^ (rest & -rest)
+			if groups[next] == want {
+				mask = next
+				break
+			}
+		}
+		if mask == 0 || sums[mask].IsZero() {
+			suggestions = append(suggestions, s.settleZeroSumGroup(balances, groupStart&^mask, currency)...)
+			groupStart = mask
+		}
+	}
+
+	return suggestions
+}
+
+// settleZeroSumGroup settles the balances in members, which sum to zero, with
+// the greedy matcher; it needs at most one payment fewer than there are members
+func (s *settlementService) settleZeroSumGroup(balances []*models.Balance, members int, currency string) []*models.SettlementSuggestion {
+	var creditors, debtors []*models.Balance
+	for i, balance := range balances {
+		if members&(1<<i) == 0 {
+			continue
+		}
+		if balance.Balance.GreaterThan(decimal.Zero) {
+			debtors = append(debtors, balance)
+		} else {
+			creditors = append(creditors, &models.Balance{User: balance.User, Balance: balance.Balance.Abs()})
+		}
+	}
+	return s.generateSettlementSuggestions(creditors, debtors, currency)
+}
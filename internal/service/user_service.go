@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/logging"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/repository"
 	"expense-split-tracker/internal/utils"
@@ -12,9 +16,10 @@ import (
 	"go.uber.org/zap"
 )
 
-// DBTransactor defines the interface for database transaction operations
+// DBTransactor defines the interface for database transaction operations.
+// The transaction is rolled back if ctx is cancelled before it commits.
 type DBTransactor interface {
-	WithTransaction(fn func(*database.Tx) error) error
+	WithTransaction(ctx context.Context, fn func(*database.Tx) error) error
 }
 
 type userService struct {
@@ -63,16 +68,16 @@ func (s *userService) CreateUser(ctx context.Context, req *models.CreateUserRequ
 		Email: req.Email,
 	}
 
-	err = s.db.WithTransaction(func(tx *database.Tx) error {
+	err = s.db.WithTransaction(ctx, func(tx *database.Tx) error {
 		return s.repo.Create(ctx, tx, user)
 	})
 
 	if err != nil {
-		s.logger.Error("Failed to create user", zap.Error(err), zap.String("email", req.Email))
+		logging.FromContext(ctx, s.logger).Error("Failed to create user", zap.Error(err), zap.String("email", req.Email))
 		return nil, err
 	}
 
-	s.logger.Info("User created successfully", zap.String("uuid", user.UUID), zap.String("email", user.Email))
+	logging.FromContext(ctx, s.logger).Info("User created successfully", zap.String("uuid", user.UUID), zap.String("email", user.Email))
 	return user, nil
 }
 
@@ -84,7 +89,7 @@ func (s *userService) GetUserByUUID(ctx context.Context, uuid string) (*models.U
 
 	user, err := s.repo.GetByUUID(ctx, uuid)
 	if err != nil {
-		s.logger.Error("Failed to get user by UUID", zap.Error(err), zap.String("uuid", uuid))
+		logging.FromContext(ctx, s.logger).Error("Failed to get user by UUID", zap.Error(err), zap.String("uuid", uuid))
 		return nil, err
 	}
 
@@ -99,7 +104,7 @@ func (s *userService) GetUserByEmail(ctx context.Context, email string) (*models
 
 	user, err := s.repo.GetByEmail(ctx, email)
 	if err != nil {
-		s.logger.Error("Failed to get user by email", zap.Error(err), zap.String("email", email))
+		logging.FromContext(ctx, s.logger).Error("Failed to get user by email", zap.Error(err), zap.String("email", email))
 		return nil, err
 	}
 
@@ -107,7 +112,7 @@ func (s *userService) GetUserByEmail(ctx context.Context, email string) (*models
 }
 
 // ListUsers retrieves a paginated list of users
-func (s *userService) ListUsers(ctx context.Context, page, limit int) ([]*models.User, error) {
+func (s *userService) ListUsers(ctx context.Context, page, limit int) ([]*models.User, int, error) {
 	// Validate pagination parameters
 	if page < 1 {
 		page = 1
@@ -120,9 +125,99 @@ func (s *userService) ListUsers(ctx context.Context, page, limit int) ([]*models
 
 	users, err := s.repo.List(ctx, offset, limit)
 	if err != nil {
-		s.logger.Error("Failed to list users", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to list users", zap.Error(err))
+		return nil, 0, err
+	}
+
+	total, err := s.repo.Count(ctx)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to count users", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// SearchUsers retrieves a paginated list of users whose name or email
+// contains query, ignoring case. Queries shorter than 2 characters list all
+// users instead.
+func (s *userService) SearchUsers(ctx context.Context, query string, page, limit int) ([]*models.User, int, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) < 2 {
+		return s.ListUsers(ctx, page, limit)
+	}
+	if err := utils.ValidateSearchQuery(query); err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	users, err := s.repo.Search(ctx, query, offset, limit)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to search users", zap.Error(err), zap.String("query", query))
+		return nil, 0, err
+	}
+
+	total, err := s.repo.CountSearch(ctx, query)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to count user search results", zap.Error(err), zap.String("query", query))
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// maxUserLookupSize caps how many UUIDs one LookupUsers call can take
+const maxUserLookupSize = 100
+
+// LookupUsers retrieves several users by UUID in one query, in the order they
+// were asked for. Repeated UUIDs are returned once and unknown ones are left
+// out, so callers can tell which users no longer exist.
+func (s *userService) LookupUsers(ctx context.Context, uuids []string) ([]*models.User, error) {
+	if len(uuids) == 0 {
+		return nil, errors.NewValidationError("uuids must list at least one user")
+	}
+	if len(uuids) > maxUserLookupSize {
+		return nil, errors.NewValidationError(fmt.Sprintf("Cannot look up more than %d users at once", maxUserLookupSize))
+	}
+
+	details := make(map[string]string)
+	for i, uuid := range uuids {
+		if !utils.IsValidUUID(uuid) {
+			details[fmt.Sprintf("uuids[%d]", i)] = "Invalid UUID"
+		}
+	}
+	if len(details) > 0 {
+		validationErr := errors.NewValidationError("Some UUIDs are invalid")
+		validationErr.Details = details
+		return nil, validationErr
+	}
+
+	found, err := s.repo.GetByUUIDs(ctx, uuids)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to look up users", zap.Error(err), zap.Int("count", len(uuids)))
 		return nil, err
 	}
+	byUUID := make(map[string]*models.User, len(found))
+	for _, user := range found {
+		byUUID[strings.ToLower(user.UUID)] = user
+	}
+
+	users := make([]*models.User, 0, len(found))
+	for _, uuid := range uuids {
+		key := strings.ToLower(uuid)
+		if user, ok := byUUID[key]; ok {
+			users = append(users, user)
+			delete(byUUID, key)
+		}
+	}
 
 	return users, nil
 }
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"go.uber.org/zap"
+)
+
+// WebhookRedeliverer queues a dead-lettered delivery to be sent again; the
+// webhook dispatcher implements it
+type WebhookRedeliverer interface {
+	Redeliver(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) error
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	groupRepo   repository.GroupRepository
+	redeliverer WebhookRedeliverer
+	logger      *zap.Logger
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(webhookRepo repository.WebhookRepository, groupRepo repository.GroupRepository, redeliverer WebhookRedeliverer, logger *zap.Logger) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		groupRepo:   groupRepo,
+		redeliverer: redeliverer,
+		logger:      logger,
+	}
+}
+
+// CreateWebhook registers a webhook for a group's events
+func (s *webhookService) CreateWebhook(ctx context.Context, groupUUID models.GroupUUID, req *models.CreateWebhookRequest) (*models.Webhook, error) {
+	group, err := s.getGroup(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateWebhookSecret(req.Secret); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookTarget(req.URL, req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		UUID:       utils.GenerateUUID(),
+		GroupID:    group.ID,
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		Active:     true,
+	}
+
+	if err := s.webhookRepo.Create(ctx, nil, webhook); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to create webhook", zap.Error(err), zap.String("groupUUID", groupUUID.String()))
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Webhook created", zap.String("uuid", webhook.UUID), zap.Int64("groupID", group.ID))
+	return s.webhookRepo.GetByUUID(ctx, webhook.UUID)
+}
+
+// recentAttemptsLimit caps the delivery attempts shown with a webhook
+const recentAttemptsLimit = 50
+
+// GetWebhook retrieves a webhook of a group with its latest delivery attempts
+func (s *webhookService) GetWebhook(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID) (*models.Webhook, error) {
+	webhook, err := s.findWebhook(ctx, groupUUID, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.RecentAttempts, err = s.webhookRepo.GetRecentDeliveryAttempts(ctx, webhook.ID, recentAttemptsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// findWebhook resolves a webhook of a group the caller is a member of
+func (s *webhookService) findWebhook(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID) (*models.Webhook, error) {
+	group, err := s.getGroup(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.IsValidUUID(uuid.String()) {
+		return nil, errors.NewInvalidValueError("uuid", uuid.String())
+	}
+
+	webhook, err := s.webhookRepo.GetByUUID(ctx, uuid.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Webhooks are only reachable through their own group
+	if webhook.GroupID != group.ID {
+		return nil, errors.NewNotFoundError("Webhook")
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks retrieves every webhook of a group
+func (s *webhookService) ListWebhooks(ctx context.Context, groupUUID models.GroupUUID) ([]*models.Webhook, error) {
+	group, err := s.getGroup(ctx, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhookRepo.GetGroupWebhooks(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []*models.Webhook{}
+	}
+
+	return webhooks, nil
+}
+
+// UpdateWebhook replaces a webhook's target and subscriptions
+func (s *webhookService) UpdateWebhook(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID, req *models.UpdateWebhookRequest) (*models.Webhook, error) {
+	webhook, err := s.findWebhook(ctx, groupUUID, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateWebhookTarget(req.URL, req.EventTypes); err != nil {
+		return nil, err
+	}
+	if req.Secret != "" {
+		if err := utils.ValidateWebhookSecret(req.Secret); err != nil {
+			return nil, err
+		}
+		webhook.Secret = req.Secret
+	}
+
+	webhook.URL = req.URL
+	webhook.EventTypes = req.EventTypes
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	if err := s.webhookRepo.Update(ctx, nil, webhook); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to update webhook", zap.Error(err), zap.String("uuid", uuid.String()))
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Webhook updated", zap.String("uuid", uuid.String()))
+	return s.webhookRepo.GetByUUID(ctx, webhook.UUID)
+}
+
+// DeleteWebhook removes a webhook
+func (s *webhookService) DeleteWebhook(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID) error {
+	webhook, err := s.findWebhook(ctx, groupUUID, uuid)
+	if err != nil {
+		return err
+	}
+
+	if err := s.webhookRepo.Delete(ctx, nil, webhook.ID); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to delete webhook", zap.Error(err), zap.String("uuid", uuid.String()))
+		return err
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Webhook deleted", zap.String("uuid", uuid.String()))
+	return nil
+}
+
+// ListDeadLetters retrieves the dead-lettered deliveries of a webhook, newest first
+func (s *webhookService) ListDeadLetters(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID) ([]*models.WebhookDelivery, error) {
+	webhook, err := s.findWebhook(ctx, groupUUID, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.webhookRepo.GetWebhookDeliveries(ctx, webhook.ID, models.WebhookDeliveryDeadLettered)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverDeadLetter queues a dead-lettered delivery of a webhook to be sent
+// again. The delivery is returned as pending; it is dead-lettered again if
+// every retry fails.
+func (s *webhookService) RedeliverDeadLetter(ctx context.Context, groupUUID models.GroupUUID, uuid models.WebhookUUID, deliveryUUID models.WebhookDeliveryUUID) (*models.WebhookDelivery, error) {
+	webhook, err := s.findWebhook(ctx, groupUUID, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.IsValidUUID(deliveryUUID.String()) {
+		return nil, errors.NewInvalidValueError("delivery_uuid", deliveryUUID.String())
+	}
+
+	delivery, err := s.webhookRepo.GetDeliveryByUUID(ctx, deliveryUUID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Deliveries are only reachable through their own webhook
+	if delivery.WebhookID != webhook.ID {
+		return nil, errors.NewNotFoundError("Webhook delivery")
+	}
+	if delivery.Status != models.WebhookDeliveryDeadLettered {
+		return nil, errors.NewDeliveryNotDeadLetteredError(string(delivery.Status))
+	}
+
+	if err := s.redeliverer.Redeliver(ctx, webhook, delivery); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to queue webhook redelivery", zap.Error(err), zap.String("delivery", deliveryUUID.String()))
+		if _, ok := err.(*errors.AppError); ok {
+			return nil, err
+		}
+		return nil, errors.NewUnavailableError("Webhook deliveries are backed up, retry shortly")
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Webhook delivery queued for redelivery", zap.String("uuid", uuid.String()), zap.String("delivery", deliveryUUID.String()))
+	return delivery, nil
+}
+
+// getGroup resolves a group the caller is a member of
+func (s *webhookService) getGroup(ctx context.Context, groupUUID models.GroupUUID) (*models.Group, error) {
+	if !utils.IsValidUUID(groupUUID.String()) {
+		return nil, errors.NewInvalidValueError("group_uuid", groupUUID.String())
+	}
+
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := requireGroupMember(ctx, s.groupRepo, group.ID); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// validateWebhookTarget checks the URL and that at least one known event
+// type is subscribed, without duplicates
+func validateWebhookTarget(targetURL string, eventTypes []models.WebhookEventType) error {
+	if err := utils.ValidateWebhookURL(strings.TrimSpace(targetURL)); err != nil {
+		return err
+	}
+	if len(eventTypes) == 0 {
+		return errors.NewRequiredFieldError("event_types")
+	}
+
+	seen := make(map[models.WebhookEventType]bool, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if err := eventType.Validate(); err != nil {
+			return err
+		}
+		if seen[eventType] {
+			return errors.NewValidationError("event_types cannot contain duplicates")
+		}
+		seen[eventType] = true
+	}
+	return nil
+}
@@ -6,6 +6,9 @@ import (
 	"expense-split-tracker/pkg/errors"
 )
 
+// DefaultCurrency is used for groups that have not chosen a default currency
+const DefaultCurrency = "USD"
+
 // SupportedCurrencies defines the list of supported currencies
 var SupportedCurrencies = map[string]bool{
 	"USD": true,
@@ -19,6 +22,31 @@ var SupportedCurrencies = map[string]bool{
 	"INR": true,
 }
 
+// CurrencyDecimals is the number of decimal places amounts may have in each
+// supported currency (its ISO 4217 minor unit)
+var CurrencyDecimals = map[string]int32{
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"CAD": 2,
+	"AUD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"INR": 2,
+}
+
+// defaultCurrencyDecimals applies when the currency is unknown or not yet resolved
+const defaultCurrencyDecimals = 2
+
+// AmountDecimals returns how many decimal places amounts in currency may have
+func AmountDecimals(currency string) int32 {
+	if decimals, ok := CurrencyDecimals[NormalizeCurrency(currency)]; ok {
+		return decimals
+	}
+	return defaultCurrencyDecimals
+}
+
 // ValidateCurrency checks if the currency is supported
 func ValidateCurrency(currency string) error {
 	currency = strings.ToUpper(currency)
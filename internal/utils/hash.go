@@ -1,14 +1,97 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
+	"strings"
 )
 
-// HashRequest creates a hash of the request data for idempotency
-func HashRequest(data interface{}) (string, error) {
-	jsonData, err := json.Marshal(data)
+// requestHashV2Prefix marks hashes computed over a canonicalized request body.
+// Hashes without a prefix were produced by the original raw-body algorithm.
+const requestHashV2Prefix = "v2:"
+
+// RequestFingerprint holds the parts of a request that identify it for idempotency
+type RequestFingerprint struct {
+	Method      string
+	Path        string
+	Query       string
+	ContentType string
+	Body        []byte
+}
+
+// HashRequest creates a versioned hash of the request for idempotency.
+// JSON bodies are canonicalized first so that key order and whitespace do not
+// affect the result; other content types are hashed byte for byte.
+func HashRequest(req RequestFingerprint) (string, error) {
+	body := req.Body
+	if isJSONContentType(req.ContentType) {
+		if canonical, err := CanonicalizeJSON(body); err == nil {
+			body = canonical
+		}
+	}
+
+	h := sha256.New()
+	for _, part := range []string{req.Method, req.Path, req.Query} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+
+	return fmt.Sprintf("%s%x", requestHashV2Prefix, h.Sum(nil)), nil
+}
+
+// RequestHashMatches reports whether a stored hash was produced by the same request,
+// using the algorithm version the stored hash was created with
+func RequestHashMatches(stored string, req RequestFingerprint) (bool, error) {
+	var current string
+	var err error
+	if strings.HasPrefix(stored, requestHashV2Prefix) {
+		current, err = HashRequest(req)
+	} else {
+		current, err = hashRequestV1(req)
+	}
+	if err != nil {
+		return false, err
+	}
+	return stored == current, nil
+}
+
+// CanonicalizeJSON re-encodes a JSON document with sorted object keys and no
+// insignificant whitespace. Numbers keep their exact textual form.
+func CanonicalizeJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// hashRequestV1 reproduces the original hash over the raw request body
+func hashRequestV1(req RequestFingerprint) (string, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"method": req.Method,
+		"path":   req.Path,
+		"query":  req.Query,
+		"body":   string(req.Body),
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal request data: %w", err)
 	}
@@ -16,3 +99,12 @@ func HashRequest(data interface{}) (string, error) {
 	hash := sha256.Sum256(jsonData)
 	return fmt.Sprintf("%x", hash), nil
 }
+
+// isJSONContentType reports whether the content type carries a JSON body
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
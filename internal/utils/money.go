@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// SplitEvenly divides amount into n parts of whole minor units of a currency
+// with the given number of decimal places, using the largest-remainder method:
+// every part gets amount/n truncated to the minor unit and the leftover units
+// go one each to the first parts. The parts always sum to amount and differ
+// from each other by at most one minor unit.
+func SplitEvenly(amount decimal.Decimal, n int, places int32) []decimal.Decimal {
+	if n <= 0 {
+		return nil
+	}
+
+	unit := decimal.New(1, -places)
+	count := decimal.NewFromInt(int64(n))
+	base := amount.Div(count).Truncate(places)
+	residual := amount.Sub(base.Mul(count))
+	extraUnits := residual.Div(unit).IntPart()
+
+	parts := make([]decimal.Decimal, n)
+	for i := range parts {
+		parts[i] = base
+		if int64(i) < extraUnits {
+			parts[i] = parts[i].Add(unit)
+		}
+	}
+
+	// Amounts finer than the minor unit cannot be spread evenly; keep the total exact
+	parts[0] = parts[0].Add(residual.Sub(unit.Mul(decimal.NewFromInt(extraUnits))))
+
+	return parts
+}
+
+// SplitByWeights divides amount into parts proportional to weights, in whole
+// minor units of a currency with the given number of decimal places. It uses
+// the largest-remainder method like SplitEvenly: every part gets its exact
+// share truncated to the minor unit and the leftover units go one each to the
+// parts that lost the most to truncation, earlier parts first on ties. The
+// parts always sum to amount.
+func SplitByWeights(amount decimal.Decimal, weights []decimal.Decimal, places int32) []decimal.Decimal {
+	total := decimal.Zero
+	for _, weight := range weights {
+		total = total.Add(weight)
+	}
+	if len(weights) == 0 || !total.IsPositive() {
+		return nil
+	}
+
+	unit := decimal.New(1, -places)
+	parts := make([]decimal.Decimal, len(weights))
+	remainders := make([]decimal.Decimal, len(weights))
+	assigned := decimal.Zero
+	for i, weight := range weights {
+		exact := amount.Mul(weight).Div(total)
+		parts[i] = exact.Truncate(places)
+		remainders[i] = exact.Sub(parts[i])
+		assigned = assigned.Add(parts[i])
+	}
+
+	residual := amount.Sub(assigned)
+	extraUnits := residual.Div(unit).IntPart()
+	if extraUnits > int64(len(parts)) {
+		extraUnits = int64(len(parts))
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].GreaterThan(remainders[order[b]])
+	})
+	for i := int64(0); i < extraUnits; i++ {
+		parts[order[i]] = parts[order[i]].Add(unit)
+	}
+
+	// Amounts finer than the minor unit cannot be spread; keep the total exact
+	parts[order[0]] = parts[order[0]].Add(residual.Sub(unit.Mul(decimal.NewFromInt(extraUnits))))
+
+	return parts
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"expense-split-tracker/pkg/errors"
+)
+
+// LikeEscapeChar is the escape character used with ContainsPattern; queries
+// must declare it with ESCAPE '!'. It avoids backslash, whose meaning inside
+// MySQL string literals depends on the sql_mode.
+const LikeEscapeChar = "!"
+
+var likeEscaper = strings.NewReplacer(
+	LikeEscapeChar, LikeEscapeChar+LikeEscapeChar,
+	"%", LikeEscapeChar+"%",
+	"_", LikeEscapeChar+"_",
+)
+
+// ContainsPattern builds a LIKE pattern matching text that contains term
+// literally: LIKE wildcards in term are escaped so "50%" only matches "50%"
+func ContainsPattern(term string) string {
+	return "%" + likeEscaper.Replace(term) + "%"
+}
+
+// PrefixPattern builds a LIKE pattern matching text that starts with term
+// literally, escaped like ContainsPattern
+func PrefixPattern(term string) string {
+	return likeEscaper.Replace(term) + "%"
+}
+
+// ValidateSearchQuery validates a free-text search term
+func ValidateSearchQuery(query string) error {
+	length := utf8.RuneCountInString(strings.TrimSpace(query))
+	if length < 2 {
+		return errors.NewValidationError("Search query must be at least 2 characters long")
+	}
+	if length > 255 {
+		return errors.NewValidationError("Search query must be less than 255 characters")
+	}
+	return nil
+}
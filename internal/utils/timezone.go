@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"time"
+	// Embed the IANA database so timezone validation does not depend on the host
+	_ "time/tzdata"
+
+	"expense-split-tracker/pkg/errors"
+)
+
+// DefaultTimezone is used for groups that have not chosen a timezone
+const DefaultTimezone = "UTC"
+
+// EarliestZone and LatestZone have the furthest offsets from UTC that any
+// timezone uses. A local calendar period starts no earlier than it does in
+// EarliestZone and ends no later than it does in LatestZone.
+var (
+	EarliestZone = time.FixedZone("UTC+14", 14*60*60)
+	LatestZone   = time.FixedZone("UTC-12", -12*60*60)
+)
+
+// ValidateTimezone validates an IANA timezone name such as "Asia/Kolkata"
+func ValidateTimezone(timezone string) error {
+	if timezone == "" {
+		return errors.NewRequiredFieldError("timezone")
+	}
+	// "Local" resolves to the server's zone, which is not a stable group setting
+	if timezone == "Local" {
+		return errors.NewInvalidValueError("timezone", timezone)
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return errors.NewInvalidValueError("timezone", timezone)
+	}
+	return nil
+}
+
+// LocalDate returns the calendar day of t in loc, formatted as YYYY-MM-DD
+func LocalDate(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
+}
+
+// LocalMonth returns the calendar month of t in loc, formatted as YYYY-MM
+func LocalMonth(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01")
+}
+
+// DayRange returns the UTC instants bounding a local calendar day as [start, end)
+func DayRange(year int, month time.Month, day int, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return start.UTC(), start.AddDate(0, 0, 1).UTC()
+}
+
+// MonthRange returns the UTC instants bounding a local calendar month as [start, end)
+func MonthRange(year int, month time.Month, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return start.UTC(), start.AddDate(0, 1, 0).UTC()
+}
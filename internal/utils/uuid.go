@@ -4,13 +4,22 @@ import (
 	"github.com/google/uuid"
 )
 
-// GenerateUUID generates a new UUID string
+// GenerateUUID generates a new version 4 UUID string
 func GenerateUUID() string {
 	return uuid.New().String()
 }
 
-// IsValidUUID checks if the provided string is a valid UUID
+// IsValidUUID checks if the provided string is a canonical version 4 UUID
+// with the RFC 4122 variant
 func IsValidUUID(uuidStr string) bool {
-	_, err := uuid.Parse(uuidStr)
-	return err == nil
+	if len(uuidStr) != 36 {
+		return false
+	}
+
+	parsed, err := uuid.Parse(uuidStr)
+	if err != nil {
+		return false
+	}
+
+	return parsed.Version() == 4 && parsed.Variant() == uuid.RFC4122
 }
@@ -1,8 +1,12 @@
 package utils
 
 import (
+	"fmt"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"expense-split-tracker/pkg/errors"
 
@@ -37,15 +41,49 @@ func ValidateName(name string) error {
 	return nil
 }
 
-// ValidateAmount validates monetary amount
-func ValidateAmount(amount decimal.Decimal) error {
+// ValidateAmount validates monetary amount, including that it has no more
+// decimal places than currency allows. An empty currency allows two.
+func ValidateAmount(amount decimal.Decimal, currency string) error {
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return errors.NewValidationError("Amount must be greater than zero")
 	}
 	if amount.GreaterThan(decimal.NewFromFloat(999999999.99)) {
 		return errors.NewValidationError("Amount is too large")
 	}
-	return nil
+	return ValidateAmountPrecision("amount", amount, currency)
+}
+
+// ValidateExchangeRate validates an exchange rate given with a request: it
+// must be positive and have at most 8 decimal places
+func ValidateExchangeRate(rate decimal.Decimal) error {
+	var appErr *errors.AppError
+	switch {
+	case !rate.IsPositive():
+		appErr = errors.NewValidationError("Exchange rate must be greater than zero")
+	case !rate.Equal(rate.Truncate(8)):
+		appErr = errors.NewValidationError("Exchange rate can have at most 8 decimal places")
+	default:
+		return nil
+	}
+	appErr.Details = map[string]string{"field": "exchange_rate"}
+	return appErr
+}
+
+// ValidateAmountPrecision rejects amounts with more decimal places than
+// currency allows rather than letting them be rounded somewhere downstream
+func ValidateAmountPrecision(field string, amount decimal.Decimal, currency string) error {
+	decimals := AmountDecimals(currency)
+	if amount.Equal(amount.Truncate(decimals)) {
+		return nil
+	}
+
+	message := fmt.Sprintf("Amount can have at most %d decimal places", decimals)
+	if currency != "" {
+		message = fmt.Sprintf("%s amounts can have at most %d decimal places", NormalizeCurrency(currency), decimals)
+	}
+	appErr := errors.NewValidationError(message)
+	appErr.Details = map[string]string{"field": field, "decimals": strconv.Itoa(int(decimals))}
+	return appErr
 }
 
 // ValidateDescription validates description field
@@ -60,6 +98,64 @@ func ValidateDescription(description string) error {
 	return nil
 }
 
+// NormalizeCategory trims and lowercases an expense category so filters match
+// regardless of how clients capitalize it
+func NormalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// ValidateCategory validates a normalized expense category
+func ValidateCategory(category string) error {
+	if category == "" {
+		return errors.NewRequiredFieldError("category")
+	}
+	if len(category) > 50 {
+		return errors.NewValidationError("Category must be at most 50 characters")
+	}
+	return nil
+}
+
+// ValidateExpenseDate rejects expense dates more than a day after now; the
+// slack covers clients whose local date is already ahead of the server's
+func ValidateExpenseDate(date, now time.Time) error {
+	if date.After(now.Add(24 * time.Hour)) {
+		return errors.NewValidationError("Expense date cannot be more than 1 day in the future")
+	}
+	return nil
+}
+
+// ValidateReceiptURL validates that a receipt link is an absolute http(s) URL
+func ValidateReceiptURL(receiptURL string) error {
+	if len(receiptURL) > 2048 {
+		return errors.NewValidationError("Receipt URL must be at most 2048 characters")
+	}
+	parsed, err := url.Parse(receiptURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.NewInvalidValueError("receipt_url", receiptURL)
+	}
+	return nil
+}
+
+// ValidateWebhookURL validates that a webhook target is an absolute http(s) URL
+func ValidateWebhookURL(targetURL string) error {
+	if len(targetURL) > 2048 {
+		return errors.NewValidationError("Webhook URL must be at most 2048 characters")
+	}
+	parsed, err := url.Parse(targetURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.NewInvalidValueError("url", targetURL)
+	}
+	return nil
+}
+
+// ValidateWebhookSecret requires a signing secret long enough to resist guessing
+func ValidateWebhookSecret(secret string) error {
+	if len(secret) < 16 || len(secret) > 255 {
+		return errors.NewValidationError("Webhook secret must be between 16 and 255 characters")
+	}
+	return nil
+}
+
 // ValidatePercentage validates percentage value
 func ValidatePercentage(percentage decimal.Decimal) error {
 	if percentage.LessThan(decimal.Zero) {
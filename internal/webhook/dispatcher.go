@@ -0,0 +1,472 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// Delivery headers. The signature is "sha256=" followed by the hex HMAC-SHA256
+// of the raw request body keyed with the webhook's secret.
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// ErrQueueFull is returned when an event or redelivery cannot be queued
+var ErrQueueFull = errors.New("webhook queue full")
+
+// Repository is the part of the webhook repository the dispatcher uses. Every
+// delivery and each of its attempts is recorded so failed deliveries can be
+// listed and redelivered.
+type Repository interface {
+	GetActiveGroupWebhooks(ctx context.Context, groupID int64) ([]*models.Webhook, error)
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	CreateDeliveryAttempt(ctx context.Context, attempt *models.WebhookDeliveryAttempt) error
+}
+
+// Options tunes delivery
+type Options struct {
+	// MaxAttempts is the number of tries per delivery, including the first.
+	// A delivery whose last try fails is dead-lettered.
+	MaxAttempts int
+	// Backoff is the wait before the first retry; it doubles after each attempt
+	Backoff time.Duration
+	// Timeout bounds each HTTP request
+	Timeout time.Duration
+	// Workers is the number of deliveries made concurrently
+	Workers int
+	// QueueSize is the number of events that can wait to be fanned out to
+	// their webhooks
+	QueueSize int
+}
+
+// job is a committed event waiting to be fanned out to its group's webhooks,
+// or a dead-lettered delivery to send again to its webhook
+type job struct {
+	eventType  models.WebhookEventType
+	groupID    int64
+	occurredAt time.Time
+	data       json.RawMessage
+
+	webhook  *models.Webhook
+	delivery *models.WebhookDelivery
+}
+
+// pending is a delivery waiting in its webhook's lane. tries counts the
+// attempts since it was queued, so a redelivery gets MaxAttempts fresh tries.
+type pending struct {
+	webhook  *models.Webhook
+	delivery *models.WebhookDelivery
+	tries    int
+	backoff  time.Duration
+}
+
+// lane holds the deliveries of one webhook in the order they are to be sent.
+// Only its head is attempted, and nothing else is sent to the webhook while
+// the head waits for a retry, so every webhook receives events in order.
+type lane struct {
+	webhookID int64
+	queue     []*pending
+	busy      bool
+}
+
+// task is handed to a worker: fan an event out to its webhooks, or attempt
+// the head of a lane once
+type task struct {
+	event *job
+	lane  *lane
+	head  *pending
+}
+
+// result reports a finished task back to Run. For a fan-out, queued lists the
+// new deliveries; for an attempt, finished says whether the head was
+// delivered or dead-lettered rather than left for another try.
+type result struct {
+	queued   []*pending
+	lane     *lane
+	finished bool
+}
+
+// Dispatcher posts expense and settlement events to the webhooks of their
+// group. Events are queued by Consume, which the outbox relay calls for
+// committed events, and delivered by Run.
+//
+// Each webhook has its own lane of deliveries, sent one at a time in order;
+// different webhooks are served concurrently by a fixed pool of workers. A
+// failed attempt is retried after a backoff timer rather than by a sleeping
+// worker, so a failing endpoint only holds up its own lane.
+type Dispatcher struct {
+	repo    Repository
+	client  *http.Client
+	options Options
+	queue   chan job
+	logger  *zap.Logger
+}
+
+// NewDispatcher creates a webhook dispatcher
+func NewDispatcher(repo Repository, options Options, logger *zap.Logger) *Dispatcher {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 1
+	}
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = 100
+	}
+
+	return &Dispatcher{
+		repo:    repo,
+		client:  &http.Client{Timeout: options.Timeout},
+		options: options,
+		queue:   make(chan job, options.QueueSize),
+		logger:  logger,
+	}
+}
+
+// Consume implements outbox.Consumer. It queues the events webhooks can
+// subscribe to without waiting for delivery; when the queue is full it
+// returns an error so the relay offers the event again later.
+func (d *Dispatcher) Consume(ctx context.Context, event *models.OutboxEvent) error {
+	eventType := models.WebhookEventType(event.EventType)
+	if eventType.Validate() != nil {
+		return nil
+	}
+
+	queued := job{
+		eventType:  eventType,
+		groupID:    event.GroupID,
+		occurredAt: event.CreatedAt.UTC(),
+		data:       event.Payload,
+	}
+	select {
+	case d.queue <- queued:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Redeliver queues a dead-lettered delivery to be sent again with its
+// original body and delivery id, and marks it pending. It does not wait for
+// the delivery; when the queue is full it returns an error.
+func (d *Dispatcher) Redeliver(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) error {
+	delivery.Status = models.WebhookDeliveryPending
+	if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		return err
+	}
+
+	select {
+	case d.queue <- job{webhook: webhook, delivery: delivery}:
+		return nil
+	default:
+		delivery.Status = models.WebhookDeliveryDeadLettered
+		if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+			d.logger.Error("Failed to restore dead-lettered webhook delivery", zap.Error(err), zap.String("delivery", delivery.UUID))
+		}
+		return ErrQueueFull
+	}
+}
+
+// Run delivers queued events until ctx is cancelled. It owns the lanes and
+// hands tasks to the workers. Events are fanned out one at a time so lanes
+// receive them in the order they were queued.
+//
+// On shutdown the attempts in progress finish, and every delivery still
+// waiting in a lane is dead-lettered so it can be redelivered later.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.logger.Info("Webhook dispatcher started", zap.Int("workers", d.options.Workers))
+
+	tasks := make(chan task)
+	results := make(chan result)
+	retries := make(chan *lane)
+	stopped := make(chan struct{})
+
+	var workers sync.WaitGroup
+	workers.Add(d.options.Workers)
+	for i := 0; i < d.options.Workers; i++ {
+		go func() {
+			defer workers.Done()
+			for t := range tasks {
+				if t.event != nil {
+					results <- result{queued: d.fanOut(ctx, *t.event)}
+				} else {
+					results <- result{lane: t.lane, finished: d.attempt(ctx, t.head)}
+				}
+			}
+		}()
+	}
+
+	lanes := make(map[int64]*lane)
+	var work []task
+	fanningOut := false
+	inFlight := 0
+
+	// enqueue appends a delivery to its webhook's lane, starting the lane
+	// when it was idle
+	enqueue := func(p *pending) {
+		l, ok := lanes[p.webhook.ID]
+		if !ok {
+			l = &lane{webhookID: p.webhook.ID}
+			lanes[l.webhookID] = l
+		}
+		l.queue = append(l.queue, p)
+		if !l.busy && len(l.queue) == 1 {
+			l.busy = true
+			work = append(work, task{lane: l, head: p})
+		}
+	}
+	// advance moves a lane on to its next delivery, or drops it when empty
+	advance := func(l *lane) {
+		l.queue = l.queue[1:]
+		if len(l.queue) == 0 {
+			l.busy = false
+			delete(lanes, l.webhookID)
+			return
+		}
+		work = append(work, task{lane: l, head: l.queue[0]})
+	}
+
+	for ctx.Err() == nil {
+		var next task
+		var out chan task
+		if len(work) > 0 {
+			next, out = work[0], tasks
+		}
+		// Take the next event only once the previous one is fanned out
+		var intake chan job
+		if !fanningOut {
+			intake = d.queue
+		}
+
+		select {
+		case <-ctx.Done():
+		case out <- next:
+			work = work[1:]
+			inFlight++
+		case queued := <-intake:
+			if queued.delivery != nil {
+				enqueue(&pending{webhook: queued.webhook, delivery: queued.delivery, backoff: d.options.Backoff})
+				continue
+			}
+			fanningOut = true
+			work = append(work, task{event: &queued})
+		case done := <-results:
+			inFlight--
+			switch {
+			case done.lane == nil:
+				fanningOut = false
+				for _, p := range done.queued {
+					enqueue(p)
+				}
+			case done.finished:
+				advance(done.lane)
+			default:
+				// The head stays in place, so later deliveries keep waiting
+				head := done.lane.queue[0]
+				l := done.lane
+				time.AfterFunc(head.backoff, func() {
+					select {
+					case retries <- l:
+					case <-stopped:
+					}
+				})
+				head.backoff *= 2
+			}
+		case l := <-retries:
+			work = append(work, task{lane: l, head: l.queue[0]})
+		}
+	}
+
+	close(stopped)
+	for ; inFlight > 0; inFlight-- {
+		done := <-results
+		switch {
+		case done.lane == nil:
+			for _, p := range done.queued {
+				enqueue(p)
+			}
+		case done.finished:
+			advance(done.lane)
+		}
+	}
+	close(tasks)
+	workers.Wait()
+
+	for _, l := range lanes {
+		for _, p := range l.queue {
+			d.deadLetter(p, "dispatcher stopped before delivery")
+		}
+	}
+
+	d.logger.Info("Webhook dispatcher stopped")
+}
+
+// fanOut records a delivery of one event for every active webhook of its
+// group that subscribes to it
+func (d *Dispatcher) fanOut(ctx context.Context, queued job) []*pending {
+	webhooks, err := d.repo.GetActiveGroupWebhooks(ctx, queued.groupID)
+	if err != nil {
+		d.logger.Error("Failed to load webhooks", zap.Error(err), zap.Int64("group_id", queued.groupID))
+		return nil
+	}
+
+	var deliveries []*pending
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(queued.eventType) {
+			continue
+		}
+
+		payload := models.WebhookPayload{
+			ID:         utils.GenerateUUID(),
+			Event:      queued.eventType,
+			OccurredAt: queued.occurredAt,
+			Data:       queued.data,
+		}
+		if webhook.Group != nil {
+			payload.Group = models.WebhookGroup{UUID: webhook.Group.UUID, Name: webhook.Group.Name}
+		}
+
+		body, err := json.Marshal(payload)
+		if err != nil {
+			d.logger.Error("Failed to encode webhook payload", zap.Error(err), zap.Int64("webhook_id", webhook.ID))
+			continue
+		}
+
+		delivery := &models.WebhookDelivery{
+			UUID:      payload.ID,
+			WebhookID: webhook.ID,
+			EventType: queued.eventType,
+			Payload:   body,
+			Status:    models.WebhookDeliveryPending,
+		}
+		// A delivery that could not be recorded is still sent, it just
+		// cannot be dead-lettered
+		if err := d.repo.CreateDelivery(ctx, delivery); err != nil {
+			d.logger.Error("Failed to record webhook delivery", zap.Error(err),
+				zap.Int64("webhook_id", webhook.ID), zap.String("delivery", delivery.UUID))
+		}
+
+		deliveries = append(deliveries, &pending{webhook: webhook, delivery: delivery, backoff: d.options.Backoff})
+	}
+	return deliveries
+}
+
+// attempt posts a delivery once and records the attempt. It reports whether
+// the delivery is finished: delivered, or dead-lettered with the last error
+// because it has used up its tries. Outcomes are recorded even when ctx was
+// cancelled during shutdown.
+func (d *Dispatcher) attempt(ctx context.Context, p *pending) bool {
+	delivery := p.delivery
+	p.tries++
+	delivery.Attempts++
+
+	started := time.Now()
+	statusCode, err := d.post(ctx, p.webhook, delivery.UUID, delivery.EventType, delivery.Payload)
+	record := &models.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		Outcome:    models.WebhookAttemptDelivered,
+		LatencyMS:  time.Since(started).Milliseconds(),
+	}
+	if statusCode != 0 {
+		record.StatusCode = &statusCode
+	}
+	if err != nil {
+		lastError := err.Error()
+		record.Outcome = models.WebhookAttemptFailed
+		record.Error = &lastError
+		delivery.LastError = &lastError
+	}
+
+	recordCtx := context.WithoutCancel(ctx)
+	if delivery.ID != 0 {
+		if err := d.repo.CreateDeliveryAttempt(recordCtx, record); err != nil {
+			d.logger.Error("Failed to record webhook delivery attempt", zap.Error(err), zap.String("delivery", delivery.UUID))
+		}
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.LastError = nil
+	case p.tries >= d.options.MaxAttempts:
+		d.deadLetter(p, fmt.Sprintf("giving up after %d attempts: %v", p.tries, err))
+		return true
+	default:
+		d.logger.Warn("Webhook delivery attempt failed", zap.Error(err),
+			zap.Int64("webhook_id", p.webhook.ID), zap.Int("attempt", p.tries), zap.Duration("retry_in", p.backoff))
+	}
+
+	d.updateDelivery(recordCtx, delivery)
+	return err == nil
+}
+
+// deadLetter marks a delivery as failed for good with reason as its last error
+func (d *Dispatcher) deadLetter(p *pending, reason string) {
+	delivery := p.delivery
+	delivery.Status = models.WebhookDeliveryDeadLettered
+	delivery.LastError = &reason
+	d.logger.Error("Webhook delivery dead-lettered", zap.String("reason", reason),
+		zap.Int64("webhook_id", p.webhook.ID), zap.String("webhook_uuid", p.webhook.UUID),
+		zap.String("event", string(delivery.EventType)), zap.String("delivery", delivery.UUID))
+	d.updateDelivery(context.Background(), delivery)
+}
+
+// updateDelivery saves the status, attempts and last error of a recorded delivery
+func (d *Dispatcher) updateDelivery(ctx context.Context, delivery *models.WebhookDelivery) {
+	if delivery.ID == 0 {
+		return
+	}
+	if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		d.logger.Error("Failed to record webhook delivery outcome", zap.Error(err), zap.String("delivery", delivery.UUID))
+	}
+}
+
+// post makes a single signed delivery attempt and returns the response
+// status, or zero when no response arrived. A 2xx response counts as
+// delivered.
+func (d *Dispatcher) post(ctx context.Context, webhook *models.Webhook, deliveryID string, event models.WebhookEventType, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	req.Header.Set(EventHeader, string(event))
+	req.Header.Set(DeliveryHeader, deliveryID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -3,13 +3,22 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // AppError represents application-specific errors
 type AppError struct {
-	Code    string `json:"code"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+	Fields  []FieldError      `json:"fields,omitempty"`
+	Status  int               `json:"-"`
+}
+
+// FieldError names one invalid field of a request body and what is wrong with it
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
-	Status  int    `json:"-"`
 }
 
 func (e *AppError) Error() string {
@@ -29,11 +38,26 @@ const (
 	ErrCodeInsufficientFund = "INSUFFICIENT_FUND"
 	ErrCodeInvalidSplit     = "INVALID_SPLIT"
 	ErrCodeCurrencyMismatch = "CURRENCY_MISMATCH"
+	ErrCodePendingUser      = "PENDING_USER"
+	ErrCodeOvershoot        = "SETTLEMENT_OVERSHOOT"
+	ErrCodeGroupLocked      = "GROUP_LOCKED"
+	ErrCodeAlreadyVoided    = "ALREADY_VOIDED"
+	ErrCodeAlreadyDeleted   = "ALREADY_DELETED"
+	ErrCodeNotPending       = "SETTLEMENT_NOT_PENDING"
+	ErrCodeNotDeadLettered  = "DELIVERY_NOT_DEAD_LETTERED"
+	ErrCodeForbidden        = "FORBIDDEN"
+	ErrCodeUnauthorized     = "UNAUTHORIZED"
+	ErrCodeBalancesChanged  = "BALANCES_CHANGED"
+	ErrCodeGroupArchived    = "GROUP_ARCHIVED"
+	ErrCodeRateUnavailable  = "EXCHANGE_RATE_UNAVAILABLE"
 
 	// System errors
 	ErrCodeDatabase    = "DATABASE_ERROR"
 	ErrCodeInternal    = "INTERNAL_ERROR"
 	ErrCodeIdempotency = "IDEMPOTENCY_ERROR"
+	ErrCodeProcessing  = "PROCESSING"
+	ErrCodeRateLimited = "RATE_LIMITED"
+	ErrCodeUnavailable = "SERVICE_UNAVAILABLE"
 )
 
 // Validation errors
@@ -45,6 +69,17 @@ func NewValidationError(message string) *AppError {
 	}
 }
 
+// NewRequestBodyError reports a request body that could not be bound, listing
+// each invalid field when they are known
+func NewRequestBodyError(message string, fields []FieldError) *AppError {
+	return &AppError{
+		Code:    ErrCodeValidation,
+		Message: message,
+		Fields:  fields,
+		Status:  http.StatusBadRequest,
+	}
+}
+
 func NewRequiredFieldError(field string) *AppError {
 	return &AppError{
 		Code:    ErrCodeRequired,
@@ -78,11 +113,26 @@ func NewAlreadyExistsError(resource string) *AppError {
 	}
 }
 
+// NewDuplicateFieldError reports that another resource already uses the
+// value given for field
+func NewDuplicateFieldError(resource, field string) *AppError {
+	return &AppError{
+		Code:    ErrCodeAlreadyExists,
+		Message: fmt.Sprintf("%s with this %s already exists", resource, field),
+		Details: map[string]string{"field": field},
+		Status:  http.StatusConflict,
+	}
+}
+
 func NewInsufficientFundError(available, required string) *AppError {
 	return &AppError{
 		Code:    ErrCodeInsufficientFund,
 		Message: fmt.Sprintf("Insufficient funds: available %s, required %s", available, required),
-		Status:  http.StatusBadRequest,
+		Details: map[string]string{
+			"available": available,
+			"required":  required,
+		},
+		Status: http.StatusBadRequest,
 	}
 }
 
@@ -94,14 +144,144 @@ func NewInvalidSplitError(message string) *AppError {
 	}
 }
 
-func NewCurrencyMismatchError() *AppError {
+func NewCurrencyMismatchError(currency string, inUse []string) *AppError {
 	return &AppError{
 		Code:    ErrCodeCurrencyMismatch,
-		Message: "Currency mismatch in transaction",
+		Message: fmt.Sprintf("No balances in %s; currencies in use in this group: %s", currency, strings.Join(inUse, ", ")),
+		Details: map[string]string{"currency": currency, "currencies_in_use": strings.Join(inUse, ",")},
 		Status:  http.StatusBadRequest,
 	}
 }
 
+func NewExchangeRateUnavailableError(from, to string) *AppError {
+	return &AppError{
+		Code:    ErrCodeRateUnavailable,
+		Message: fmt.Sprintf("No exchange rate from %s to %s", from, to),
+		Details: map[string]string{"from": from, "to": to},
+		Status:  http.StatusBadRequest,
+	}
+}
+
+func NewOvershootError(before, after string) *AppError {
+	return &AppError{
+		Code:    ErrCodeOvershoot,
+		Message: fmt.Sprintf("Settlement would turn the recipient's balance from %s into %s", before, after),
+		Details: map[string]string{
+			"recipient_balance_before": before,
+			"recipient_balance_after":  after,
+		},
+		Status: http.StatusBadRequest,
+	}
+}
+
+func NewPendingUserError(email string) *AppError {
+	return &AppError{
+		Code:    ErrCodePendingUser,
+		Message: fmt.Sprintf("User %s has not registered yet", email),
+		Details: map[string]string{"email": email},
+		Status:  http.StatusConflict,
+	}
+}
+
+func NewGroupLockedError(purpose, expiresAt string) *AppError {
+	return &AppError{
+		Code:    ErrCodeGroupLocked,
+		Message: fmt.Sprintf("Group is locked for %s until %s, please retry shortly", purpose, expiresAt),
+		Details: map[string]string{
+			"purpose":    purpose,
+			"expires_at": expiresAt,
+		},
+		Status: http.StatusLocked,
+	}
+}
+
+func NewProcessingError() *AppError {
+	return &AppError{
+		Code:    ErrCodeProcessing,
+		Message: "A request with this Idempotency-Key is still being processed, retry shortly",
+		Status:  http.StatusConflict,
+	}
+}
+
+func NewRateLimitedError(retryAfterSeconds int) *AppError {
+	return &AppError{
+		Code:    ErrCodeRateLimited,
+		Message: fmt.Sprintf("Too many requests, retry in %d seconds", retryAfterSeconds),
+		Details: map[string]string{"retry_after": fmt.Sprintf("%d", retryAfterSeconds)},
+		Status:  http.StatusTooManyRequests,
+	}
+}
+
+func NewGroupArchivedError() *AppError {
+	return &AppError{
+		Code:    ErrCodeGroupArchived,
+		Message: "Group is archived; unarchive it before adding expenses or settlements",
+		Status:  http.StatusConflict,
+	}
+}
+
+func NewAlreadyVoidedError(resource string) *AppError {
+	return &AppError{
+		Code:    ErrCodeAlreadyVoided,
+		Message: fmt.Sprintf("%s has already been voided", resource),
+		Status:  http.StatusConflict,
+	}
+}
+
+func NewAlreadyDeletedError(resource string) *AppError {
+	return &AppError{
+		Code:    ErrCodeAlreadyDeleted,
+		Message: fmt.Sprintf("%s has already been deleted", resource),
+		Status:  http.StatusConflict,
+	}
+}
+
+func NewSettlementNotPendingError(status string) *AppError {
+	return &AppError{
+		Code:    ErrCodeNotPending,
+		Message: fmt.Sprintf("Settlement is %s; only pending settlements can be confirmed or rejected", status),
+		Details: map[string]string{"status": status},
+		Status:  http.StatusConflict,
+	}
+}
+
+func NewDeliveryNotDeadLetteredError(status string) *AppError {
+	return &AppError{
+		Code:    ErrCodeNotDeadLettered,
+		Message: fmt.Sprintf("Delivery is %s; only dead-lettered deliveries can be redelivered", status),
+		Details: map[string]string{"status": status},
+		Status:  http.StatusConflict,
+	}
+}
+
+func NewForbiddenError(message string) *AppError {
+	return &AppError{
+		Code:    ErrCodeForbidden,
+		Message: message,
+		Status:  http.StatusForbidden,
+	}
+}
+
+func NewUnauthorizedError(message string) *AppError {
+	return &AppError{
+		Code:    ErrCodeUnauthorized,
+		Message: message,
+		Status:  http.StatusUnauthorized,
+	}
+}
+
+func NewBalancesChangedError(expected, actual string) *AppError {
+	return &AppError{
+		Code:    ErrCodeBalancesChanged,
+		Message: "Balances changed since the debt simplification was previewed",
+		Details: map[string]string{
+			"expected_hash": expected,
+			"current_hash":  actual,
+		},
+		Status: http.StatusConflict,
+	}
+}
+
 // System errors
 func NewDatabaseError(err error) *AppError {
 	return &AppError{
@@ -119,6 +299,14 @@ func NewInternalError(message string) *AppError {
 	}
 }
 
+func NewUnavailableError(message string) *AppError {
+	return &AppError{
+		Code:    ErrCodeUnavailable,
+		Message: message,
+		Status:  http.StatusServiceUnavailable,
+	}
+}
+
 func NewIdempotencyError(message string) *AppError {
 	return &AppError{
 		Code:    ErrCodeIdempotency,
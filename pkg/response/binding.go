@@ -0,0 +1,259 @@
+package response
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+)
+
+const invalidBodyMessage = "Invalid request body"
+
+var registerJSONNames sync.Once
+
+// BindJSON decodes and validates the request body into obj. Failures come back
+// as a VALIDATION_ERROR whose fields name each offending JSON field.
+func BindJSON(c *gin.Context, obj interface{}) error {
+	if err := bindJSON(c, obj); err != nil {
+		return bindingError(c, obj, err)
+	}
+	return nil
+}
+
+// BindOptionalJSON is BindJSON for endpoints whose body may be left out
+func BindOptionalJSON(c *gin.Context, obj interface{}) error {
+	if err := bindJSON(c, obj); err != nil && err != io.EOF {
+		return bindingError(c, obj, err)
+	}
+	return nil
+}
+
+func bindJSON(c *gin.Context, obj interface{}) error {
+	registerJSONNames.Do(func() {
+		// Report validation failures under their JSON names
+		if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+			v.RegisterTagNameFunc(jsonFieldName)
+		}
+	})
+
+	// The body is kept on the context so a decoding error can be traced back
+	// to its field
+	return c.ShouldBindBodyWith(obj, binding.JSON)
+}
+
+// bindingError converts what gin's JSON binding returned into an AppError
+func bindingError(c *gin.Context, obj interface{}, err error) error {
+	// Field-level decoding errors such as an unknown split_type already name the field
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		return appErr
+	}
+
+	if err == io.EOF {
+		return errors.NewRequestBodyError("Request body is required", nil)
+	}
+
+	var validationErrs validator.ValidationErrors
+	if stderrors.As(err, &validationErrs) {
+		fields := make([]errors.FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, errors.FieldError{
+				Field:   validationFieldPath(fieldErr),
+				Message: validationMessage(fieldErr),
+			})
+		}
+		return errors.NewRequestBodyError(invalidBodyMessage, fields)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if stderrors.As(err, &typeErr) {
+		return errors.NewRequestBodyError(invalidBodyMessage, []errors.FieldError{
+			{Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type)},
+		})
+	}
+
+	var syntaxErr *json.SyntaxError
+	if stderrors.As(err, &syntaxErr) || err == io.ErrUnexpectedEOF {
+		return errors.NewRequestBodyError("Request body is not valid JSON", nil)
+	}
+
+	// Rejected when unknown fields are disallowed
+	if field, ok := strings.CutPrefix(err.Error(), `json: unknown field "`); ok {
+		return errors.NewRequestBodyError(invalidBodyMessage, []errors.FieldError{
+			{Field: strings.TrimSuffix(field, `"`), Message: "is not a known field"},
+		})
+	}
+
+	// Errors from a type's own UnmarshalJSON (decimal amounts, for one) do
+	// not say which field they came from; find it by decoding field by field
+	if body, ok := c.Get(gin.BodyBytesKey); ok {
+		if data, ok := body.([]byte); ok {
+			if field, fieldType, found := locateDecodeError(reflect.TypeOf(obj), data, ""); found {
+				return errors.NewRequestBodyError(invalidBodyMessage, []errors.FieldError{
+					{Field: field, Message: "must be " + jsonKind(fieldType)},
+				})
+			}
+		}
+	}
+
+	return errors.NewRequestBodyError(invalidBodyMessage, nil)
+}
+
+// locateDecodeError returns the JSON path of the first value in data that
+// does not decode into its field of t, descending into objects and arrays
+func locateDecodeError(t reflect.Type, data json.RawMessage, path string) (string, reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if json.Unmarshal(data, reflect.New(t).Interface()) == nil {
+		return "", nil, false
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && !isScalarStruct(t):
+		var object map[string]json.RawMessage
+		if json.Unmarshal(data, &object) != nil {
+			return path, t, true
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field, ok := structFieldByJSONName(t, key)
+			if !ok {
+				continue
+			}
+			if p, ft, found := locateDecodeError(field.Type, object[key], joinPath(path, key)); found {
+				return p, ft, true
+			}
+		}
+
+	case t.Kind() == reflect.Slice:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return path, t, true
+		}
+		for i, item := range items {
+			if p, ft, found := locateDecodeError(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i)); found {
+				return p, ft, true
+			}
+		}
+	}
+
+	return path, t, path != ""
+}
+
+// isScalarStruct reports struct types that are written as a single JSON value
+func isScalarStruct(t reflect.Type) bool {
+	return t == reflect.TypeOf(decimal.Decimal{}) || reflect.PointerTo(t).Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem())
+}
+
+// structFieldByJSONName finds the field of t that decodes the JSON key name
+func structFieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if embedded, ok := structFieldByJSONName(field.Type, name); ok {
+				return embedded, true
+			}
+			continue
+		}
+		if strings.EqualFold(jsonFieldName(field), name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonFieldName is the name a struct field has in JSON
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// validationFieldPath drops the request type from the validator's namespace,
+// leaving e.g. "splits[0].user_uuid"
+func validationFieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// validationMessage describes a failed validation rule
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "min":
+		return "must be at least " + fieldErr.Param()
+	case "max":
+		return "must be at most " + fieldErr.Param()
+	case "gt":
+		return "must be greater than " + fieldErr.Param()
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	}
+	return fmt.Sprintf("failed the '%s' check", fieldErr.Tag())
+}
+
+// jsonKind describes the JSON value expected for a Go type
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(decimal.Decimal{}) {
+		return "a decimal number"
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "an RFC3339 timestamp"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
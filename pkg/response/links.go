@@ -0,0 +1,99 @@
+package response
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Links represents machine-readable pagination links
+type Links struct {
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+}
+
+// Pagination describes the position of a page within a list. Offset mode uses
+// Page and HasNext; cursor mode is selected by setting CursorParam, in which
+// case NextCursor and PrevCursor are the cursors of the neighbouring pages.
+type Pagination struct {
+	Page    int
+	HasNext bool
+
+	CursorParam string
+	NextCursor  string
+	PrevCursor  string
+}
+
+// BuildLinks builds fully-qualified pagination links from the incoming request
+// URL, swapping only the page or cursor parameter so every filter is kept verbatim
+func BuildLinks(c *gin.Context, p Pagination) *Links {
+	links := &Links{}
+
+	if p.CursorParam != "" {
+		links.First = pageURL(c, p.CursorParam, "")
+		if p.PrevCursor != "" {
+			links.Prev = pageURL(c, p.CursorParam, p.PrevCursor)
+		}
+		if p.NextCursor != "" {
+			links.Next = pageURL(c, p.CursorParam, p.NextCursor)
+		}
+		return links
+	}
+
+	page := p.Page
+	if page < 1 {
+		page = 1
+	}
+
+	links.First = pageURL(c, "page", "1")
+	if page > 1 {
+		links.Prev = pageURL(c, "page", strconv.Itoa(page-1))
+	}
+	if p.HasNext {
+		links.Next = pageURL(c, "page", strconv.Itoa(page+1))
+	}
+	return links
+}
+
+// pageURL returns the request URL with param set to value, or removed when value is empty
+func pageURL(c *gin.Context, param, value string) string {
+	req := c.Request
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := req.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+
+	// Rewrite the raw query by hand so the order and encoding of the other
+	// parameters are preserved exactly as the client sent them
+	var parts []string
+	if req.URL.RawQuery != "" {
+		for _, part := range strings.Split(req.URL.RawQuery, "&") {
+			key := part
+			if i := strings.Index(part, "="); i >= 0 {
+				key = part[:i]
+			}
+			if unescaped, err := url.QueryUnescape(key); err == nil && unescaped == param {
+				continue
+			}
+			parts = append(parts, part)
+		}
+	}
+	if value != "" {
+		parts = append(parts, url.QueryEscape(param)+"="+url.QueryEscape(value))
+	}
+
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     req.Host,
+		Path:     req.URL.Path,
+		RawQuery: strings.Join(parts, "&"),
+	}
+	return u.String()
+}
@@ -16,18 +16,25 @@ type APIResponse struct {
 	Meta    *Meta       `json:"meta,omitempty"`
 }
 
+// RequestIDKey is the gin context key holding the current request's ID
+const RequestIDKey = "request_id"
+
 // ErrorInfo represents error information in API responses
 type ErrorInfo struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code      string              `json:"code"`
+	Message   string              `json:"message"`
+	Details   map[string]string   `json:"details,omitempty"`
+	Fields    []errors.FieldError `json:"fields,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
 }
 
 // Meta represents metadata for paginated responses
 type Meta struct {
-	Page       int `json:"page,omitempty"`
-	Limit      int `json:"limit,omitempty"`
-	Total      int `json:"total,omitempty"`
-	TotalPages int `json:"total_pages,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	Total      int    `json:"total,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	Links      *Links `json:"links,omitempty"`
 }
 
 // Success sends a successful response
@@ -58,55 +65,52 @@ func Created(c *gin.Context, data interface{}) {
 // Error sends an error response
 func Error(c *gin.Context, err error) {
 	if appErr, ok := err.(*errors.AppError); ok {
-		c.JSON(appErr.Status, APIResponse{
-			Success: false,
-			Error: &ErrorInfo{
-				Code:    appErr.Code,
-				Message: appErr.Message,
-			},
+		errorJSON(c, appErr.Status, &ErrorInfo{
+			Code:    appErr.Code,
+			Message: appErr.Message,
+			Details: appErr.Details,
+			Fields:  appErr.Fields,
 		})
 		return
 	}
 
 	// Handle unknown errors
-	c.JSON(http.StatusInternalServerError, APIResponse{
-		Success: false,
-		Error: &ErrorInfo{
-			Code:    errors.ErrCodeInternal,
-			Message: "Internal server error",
-		},
+	errorJSON(c, http.StatusInternalServerError, &ErrorInfo{
+		Code:    errors.ErrCodeInternal,
+		Message: "Internal server error",
 	})
 }
 
 // BadRequest sends a 400 Bad Request response
 func BadRequest(c *gin.Context, message string) {
-	c.JSON(http.StatusBadRequest, APIResponse{
-		Success: false,
-		Error: &ErrorInfo{
-			Code:    errors.ErrCodeValidation,
-			Message: message,
-		},
+	errorJSON(c, http.StatusBadRequest, &ErrorInfo{
+		Code:    errors.ErrCodeValidation,
+		Message: message,
 	})
 }
 
 // NotFound sends a 404 Not Found response
 func NotFound(c *gin.Context, message string) {
-	c.JSON(http.StatusNotFound, APIResponse{
-		Success: false,
-		Error: &ErrorInfo{
-			Code:    errors.ErrCodeNotFound,
-			Message: message,
-		},
+	errorJSON(c, http.StatusNotFound, &ErrorInfo{
+		Code:    errors.ErrCodeNotFound,
+		Message: message,
 	})
 }
 
 // InternalError sends a 500 Internal Server Error response
 func InternalError(c *gin.Context, message string) {
-	c.JSON(http.StatusInternalServerError, APIResponse{
+	errorJSON(c, http.StatusInternalServerError, &ErrorInfo{
+		Code:    errors.ErrCodeInternal,
+		Message: message,
+	})
+}
+
+// errorJSON sends an error response tagged with the request's ID so users
+// can quote it when reporting a problem
+func errorJSON(c *gin.Context, status int, info *ErrorInfo) {
+	info.RequestID = c.GetString(RequestIDKey)
+	c.JSON(status, APIResponse{
 		Success: false,
-		Error: &ErrorInfo{
-			Code:    errors.ErrCodeInternal,
-			Message: message,
-		},
+		Error:   info,
 	})
 }
@@ -0,0 +1,434 @@
+// Package integration runs the services on their real repositories against
+// an in-memory SQLite database, so it needs no external services. Every test
+// gets a fresh database created from the embedded schema.
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/config"
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/fx"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/outbox"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// app wires the repositories and services the way the server does
+type app struct {
+	db       *database.DB
+	repos    *repository.Repositories
+	services *service.Services
+	events   *events.Dispatcher
+}
+
+// openDB creates an empty in-memory database with the schema loaded
+func openDB(t *testing.T, statementTimeout time.Duration) *database.DB {
+	t.Helper()
+
+	cfg := &config.Config{Database: config.DatabaseConfig{
+		Driver:           database.DriverSQLite,
+		DSN:              config.SQLiteDSN(":memory:", utils.GenerateUUID()),
+		StatementTimeout: statementTimeout,
+	}}
+	db, err := database.NewConnection(cfg, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newApp(t *testing.T) *app {
+	t.Helper()
+	logger := zaptest.NewLogger(t)
+	db := openDB(t, 5*time.Second)
+
+	repos := &repository.Repositories{
+		User:           repository.NewUserRepository(db, logger),
+		Group:          repository.NewGroupRepository(db, logger),
+		Expense:        repository.NewExpenseRepository(db, logger),
+		Settlement:     repository.NewSettlementRepository(db, logger),
+		Balance:        repository.NewBalanceRepository(db, logger),
+		BalanceHistory: repository.NewBalanceHistoryRepository(db, logger),
+		GroupLock:      repository.NewGroupLockRepository(db, logger),
+		Outbox:         repository.NewOutboxRepository(db, logger),
+		Insights:       repository.NewInsightsRepository(db, logger),
+		Report:         repository.NewReportRepository(db, logger),
+	}
+
+	emitter := events.NewDispatcher(logger)
+	emitter.UseRecorder(outbox.NewWriter(repos.Outbox))
+	rates := fx.NewStaticProvider("USD", nil)
+
+	groupLocks := service.NewGroupLockService(repos.GroupLock, db, 30*time.Second, logger)
+	services := &service.Services{
+		User:       service.NewUserService(repos.User, db, logger),
+		Group:      service.NewGroupService(repos.Group, repos.User, repos.Expense, repos.Balance, db, 50, emitter, logger),
+		Expense:    service.NewExpenseService(repos.Expense, repos.Group, repos.User, repos.Balance, repos.BalanceHistory, repos.GroupLock, db, rates, emitter, metrics.Nop{}, logger),
+		Settlement: service.NewSettlementService(repos.Settlement, repos.Group, repos.User, repos.Balance, repos.BalanceHistory, repos.GroupLock, db, emitter, metrics.Nop{}, logger),
+		Balance:    service.NewBalanceService(repos.Balance, repos.Group, repos.User, repos.Settlement, repos.Expense, repos.BalanceHistory, groupLocks, db, rates, logger),
+		Insights:   service.NewInsightsService(repos.Insights, repos.User, logger),
+		Report:     service.NewReportService(repos.Report, repos.Group, repos.User, logger),
+		GroupLock:  groupLocks,
+	}
+
+	return &app{db: db, repos: repos, services: services, events: emitter}
+}
+
+// trip creates a USD group of three members with alice as its admin
+func (a *app) trip(t *testing.T) (*models.Group, *models.User, *models.User, *models.User) {
+	t.Helper()
+	ctx := context.Background()
+
+	newUser := func(name string) *models.User {
+		user, err := a.services.User.CreateUser(ctx, &models.CreateUserRequest{Name: name, Email: utils.GenerateUUID() + "@example.com"})
+		require.NoError(t, err)
+		return user
+	}
+	alice, bob, carol := newUser("Alice"), newUser("Bob"), newUser("Carol")
+
+	group, err := a.services.Group.CreateGroup(ctx, &models.CreateGroupRequest{Name: "Trip", DefaultCurrency: "USD"}, alice.UUID)
+	require.NoError(t, err)
+	for _, member := range []*models.User{bob, carol} {
+		require.NoError(t, a.services.Group.AddMember(ctx, group.UUID, &models.AddMemberRequest{UserUUID: member.UUID, ActingUserUUID: alice.UUID}))
+	}
+
+	return group, alice, bob, carol
+}
+
+// balance returns what user owes in group; it is negative when user is owed
+func (a *app) balance(t *testing.T, group *models.Group, user *models.User) decimal.Decimal {
+	t.Helper()
+	detail, err := a.services.Balance.GetUserBalance(context.Background(), models.GroupUUID(group.UUID), models.UserUUID(user.UUID), "USD")
+	require.NoError(t, err)
+	return detail.Balance
+}
+
+func assertAmount(t *testing.T, want string, got decimal.Decimal) {
+	t.Helper()
+	assert.True(t, decimal.RequireFromString(want).Equal(got), "want %s, got %s", want, got)
+}
+
+func TestExpenseBalanceSettlementFlow(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+	group, alice, bob, carol := a.trip(t)
+
+	// Alice pays 90 split equally three ways
+	expense, err := a.services.Expense.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  alice.UUID,
+		Amount:      decimal.NewFromInt(90),
+		Currency:    "USD",
+		Description: "Dinner",
+		SplitType:   models.SplitTypeEqual,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: alice.UUID}, {UserUUID: bob.UUID}, {UserUUID: carol.UUID},
+		},
+	})
+	require.NoError(t, err)
+
+	loaded, err := a.services.Expense.GetExpenseByUUID(ctx, expense.UUID)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Splits, 3)
+
+	assertAmount(t, "-60", a.balance(t, group, alice))
+	assertAmount(t, "30", a.balance(t, group, bob))
+	assertAmount(t, "30", a.balance(t, group, carol))
+
+	debts, err := a.services.Balance.GetDebtRelationships(ctx, models.GroupUUID(group.UUID), "USD")
+	require.NoError(t, err)
+	require.Len(t, debts, 2)
+	for _, debt := range debts {
+		assert.Equal(t, alice.UUID, debt.Creditor.UUID)
+		assertAmount(t, "30", debt.Amount)
+	}
+
+	// Bob pays Alice back in full, Carol pays half
+	_, err = a.services.Settlement.CreateSettlement(ctx, &models.CreateSettlementRequest{
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(bob.UUID),
+		ToUserUUID:   models.UserUUID(alice.UUID),
+		Amount:       decimal.NewFromInt(30),
+		Currency:     "USD",
+	})
+	require.NoError(t, err)
+	_, err = a.services.Settlement.CreateSettlement(ctx, &models.CreateSettlementRequest{
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(carol.UUID),
+		ToUserUUID:   models.UserUUID(alice.UUID),
+		Amount:       decimal.NewFromInt(15),
+		Currency:     "USD",
+	})
+	require.NoError(t, err)
+
+	assertAmount(t, "-15", a.balance(t, group, alice))
+	assertAmount(t, "0", a.balance(t, group, bob))
+	assertAmount(t, "15", a.balance(t, group, carol))
+
+	debts, err = a.services.Balance.GetDebtRelationships(ctx, models.GroupUUID(group.UUID), "USD")
+	require.NoError(t, err)
+	require.Len(t, debts, 1)
+	assert.Equal(t, carol.UUID, debts[0].Debtor.UUID)
+	assertAmount(t, "15", debts[0].Amount)
+
+	verification, err := a.services.Balance.VerifyGroupBalances(ctx, models.GroupUUID(group.UUID))
+	require.NoError(t, err)
+	assert.True(t, verification.Consistent)
+
+	// Every change was written to the outbox in its transaction
+	pending, err := a.repos.Outbox.GetPending(ctx, 100)
+	require.NoError(t, err)
+	assert.NotEmpty(t, pending)
+}
+
+func TestDeletingExpenseReversesBalances(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+	group, alice, bob, carol := a.trip(t)
+
+	expense, err := a.services.Expense.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  bob.UUID,
+		Amount:      decimal.NewFromInt(40),
+		Currency:    "USD",
+		Description: "Taxi",
+		SplitType:   models.SplitTypeExact,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: alice.UUID, Amount: decimal.NewFromInt(25)},
+			{UserUUID: carol.UUID, Amount: decimal.NewFromInt(15)},
+		},
+	})
+	require.NoError(t, err)
+	assertAmount(t, "-40", a.balance(t, group, bob))
+
+	require.NoError(t, a.services.Expense.DeleteExpense(ctx, expense.UUID))
+
+	for _, user := range []*models.User{alice, bob, carol} {
+		assertAmount(t, "0", a.balance(t, group, user))
+	}
+
+	_, err = a.services.Expense.GetExpenseByUUID(ctx, expense.UUID)
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, errors.ErrCodeNotFound, appErr.Code)
+}
+
+func TestUpdatingExpenseReplacesItsSplits(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+	group, alice, bob, carol := a.trip(t)
+
+	everyone := []models.CreateExpenseSplitRequest{{UserUUID: alice.UUID}, {UserUUID: bob.UUID}, {UserUUID: carol.UUID}}
+	expense, err := a.services.Expense.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  alice.UUID,
+		Amount:      decimal.NewFromInt(90),
+		Currency:    "USD",
+		Description: "Dinner",
+		SplitType:   models.SplitTypeEqual,
+		Splits:      everyone,
+	})
+	require.NoError(t, err)
+
+	// Each update reverses the splits the previous one stored
+	_, err = a.services.Expense.UpdateExpense(ctx, expense.UUID, &models.UpdateExpenseRequest{
+		Amount:      decimal.NewFromInt(120),
+		Description: "Dinner and drinks",
+		SplitType:   models.SplitTypeExact,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: alice.UUID, Amount: decimal.NewFromInt(50)},
+			{UserUUID: bob.UUID, Amount: decimal.NewFromInt(40)},
+			{UserUUID: carol.UUID, Amount: decimal.NewFromInt(30)},
+		},
+	})
+	require.NoError(t, err)
+	_, err = a.services.Expense.UpdateExpense(ctx, expense.UUID, &models.UpdateExpenseRequest{
+		Amount:      decimal.NewFromInt(60),
+		Description: "Dinner",
+		SplitType:   models.SplitTypeEqual,
+		Splits:      everyone,
+	})
+	require.NoError(t, err)
+
+	assertAmount(t, "-40", a.balance(t, group, alice))
+	assertAmount(t, "20", a.balance(t, group, bob))
+	assertAmount(t, "20", a.balance(t, group, carol))
+
+	// A deleted expense can no longer be updated
+	require.NoError(t, a.services.Expense.DeleteExpense(ctx, expense.UUID))
+	_, err = a.services.Expense.UpdateExpense(ctx, expense.UUID, &models.UpdateExpenseRequest{
+		Amount:      decimal.NewFromInt(30),
+		Description: "Dinner",
+		SplitType:   models.SplitTypeEqual,
+		Splits:      everyone,
+	})
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, errors.ErrCodeNotFound, appErr.Code)
+	for _, user := range []*models.User{alice, bob, carol} {
+		assertAmount(t, "0", a.balance(t, group, user))
+	}
+}
+
+func TestSplitsUseTheCurrencyMinorUnit(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+	_, alice, bob, carol := a.trip(t)
+
+	group, err := a.services.Group.CreateGroup(ctx, &models.CreateGroupRequest{Name: "Tokyo", DefaultCurrency: "JPY"}, alice.UUID)
+	require.NoError(t, err)
+	for _, member := range []*models.User{bob, carol} {
+		require.NoError(t, a.services.Group.AddMember(ctx, group.UUID, &models.AddMemberRequest{UserUUID: member.UUID, ActingUserUUID: alice.UUID}))
+	}
+
+	tests := []struct {
+		name      string
+		amount    int64
+		splitType models.SplitType
+		splits    []models.CreateExpenseSplitRequest
+		items     []models.ExpenseItemRequest
+		want      []string
+	}{
+		{
+			name:      "equal",
+			amount:    1000,
+			splitType: models.SplitTypeEqual,
+			splits:    []models.CreateExpenseSplitRequest{{UserUUID: alice.UUID}, {UserUUID: bob.UUID}, {UserUUID: carol.UUID}},
+			want:      []string{"334", "333", "333"},
+		},
+		{
+			name:      "items",
+			amount:    1500,
+			splitType: models.SplitTypeExact,
+			items: []models.ExpenseItemRequest{
+				{Description: "Ramen", Amount: decimal.NewFromInt(1000), UserUUIDs: []string{alice.UUID, bob.UUID, carol.UUID}},
+				{Description: "Gyoza", Amount: decimal.NewFromInt(500), UserUUIDs: []string{bob.UUID, carol.UUID}},
+			},
+			want: []string{"334", "583", "583"},
+		},
+		{
+			name:      "shares",
+			amount:    1000,
+			splitType: models.SplitTypeShares,
+			splits: []models.CreateExpenseSplitRequest{
+				{UserUUID: alice.UUID, Shares: 1}, {UserUUID: bob.UUID, Shares: 1}, {UserUUID: carol.UUID, Shares: 1},
+			},
+			want: []string{"334", "333", "333"},
+		},
+		{
+			name:      "percentage",
+			amount:    100,
+			splitType: models.SplitTypePercentage,
+			splits: []models.CreateExpenseSplitRequest{
+				{UserUUID: alice.UUID, Percentage: decimal.RequireFromString("12.5")},
+				{UserUUID: bob.UUID, Percentage: decimal.RequireFromString("12.5")},
+				{UserUUID: carol.UUID, Percentage: decimal.NewFromInt(75)},
+			},
+			want: []string{"13", "12", "75"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The currency is left out so the group's yen default applies
+			expense, err := a.services.Expense.CreateExpense(ctx, &models.CreateExpenseRequest{
+				GroupUUID:   group.UUID,
+				PaidByUUID:  alice.UUID,
+				Amount:      decimal.NewFromInt(tt.amount),
+				Description: "Ramen",
+				SplitType:   tt.splitType,
+				Splits:      tt.splits,
+				Items:       tt.items,
+			})
+			require.NoError(t, err)
+
+			loaded, err := a.services.Expense.GetExpenseByUUID(ctx, expense.UUID)
+			require.NoError(t, err)
+			byUser := make(map[int64]decimal.Decimal)
+			for _, split := range loaded.Splits {
+				byUser[split.UserID] = split.Amount
+			}
+			assert.Equal(t, "JPY", loaded.Currency)
+			for i, user := range []*models.User{alice, bob, carol} {
+				assertAmount(t, tt.want[i], byUser[user.ID])
+			}
+		})
+	}
+}
+
+func TestRebuildRestoresBalancesAndDebts(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+	group, alice, bob, carol := a.trip(t)
+
+	_, err := a.services.Expense.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  alice.UUID,
+		Amount:      decimal.NewFromInt(90),
+		Currency:    "USD",
+		Description: "Dinner",
+		SplitType:   models.SplitTypeEqual,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: alice.UUID}, {UserUUID: bob.UUID}, {UserUUID: carol.UUID},
+		},
+	})
+	require.NoError(t, err)
+
+	// Bob's balance and his debt to Alice drift, and Carol picks up a debt to
+	// Bob that no expense backs
+	require.NoError(t, a.repos.Balance.UpdateBalance(ctx, nil, group.ID, bob.ID, decimal.NewFromInt(5), "USD"))
+	require.NoError(t, a.repos.Balance.UpdateDebt(ctx, nil, group.ID, bob.ID, alice.ID, decimal.NewFromInt(5), "USD"))
+	require.NoError(t, a.repos.Balance.UpdateDebt(ctx, nil, group.ID, carol.ID, bob.ID, decimal.NewFromInt(7), "USD"))
+
+	rebuild, err := a.services.Balance.RebuildGroupBalances(ctx, models.GroupUUID(group.UUID))
+	require.NoError(t, err)
+	assert.Equal(t, 1, rebuild.DriftedCount)
+	assert.Equal(t, 2, rebuild.DriftedDebtCount)
+	require.Len(t, rebuild.DebtAdjustments, 3)
+
+	assertAmount(t, "30", a.balance(t, group, bob))
+	debts, err := a.services.Balance.GetDebtRelationships(ctx, models.GroupUUID(group.UUID), "USD")
+	require.NoError(t, err)
+	require.Len(t, debts, 2)
+	for _, debt := range debts {
+		assert.Equal(t, alice.UUID, debt.Creditor.UUID)
+		assertAmount(t, "30", debt.Amount)
+	}
+
+	// The correction shows up in Bob's history and leaves it ending on his
+	// rebuilt balance
+	history, _, err := a.services.Balance.GetBalanceHistory(ctx, models.GroupUUID(group.UUID), models.UserUUID(bob.UUID), &models.BalanceHistoryFilter{})
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	last := history[len(history)-1]
+	assert.Equal(t, models.BalanceHistorySourceRebuild, last.SourceType)
+	assertAmount(t, "-5", last.Delta)
+	assertAmount(t, "30", last.Balance)
+}
+
+func TestDuplicateEmailIsConflict(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+
+	user, err := a.services.User.CreateUser(ctx, &models.CreateUserRequest{Name: "Alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	// Bypass the service's lookup so the unique index reports the clash
+	err = a.repos.User.Create(ctx, nil, &models.User{UUID: utils.GenerateUUID(), Name: "Alice again", Email: user.Email})
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, errors.ErrCodeAlreadyExists, appErr.Code)
+	assert.Equal(t, "email", appErr.Details["field"])
+}
@@ -0,0 +1,48 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsightsCountForeignCurrencyExpensesInBaseCurrency(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+	group, alice, bob, _ := a.trip(t)
+
+	// Alice pays 100 EUR at 1.10 in the USD group; the splits are 55 USD each
+	rate := decimal.RequireFromString("1.10")
+	_, err := a.services.Expense.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:    group.UUID,
+		PaidByUUID:   alice.UUID,
+		Amount:       decimal.NewFromInt(100),
+		Currency:     "EUR",
+		ExchangeRate: &rate,
+		Description:  "Museum",
+		SplitType:    models.SplitTypeEqual,
+		Splits:       []models.CreateExpenseSplitRequest{{UserUUID: alice.UUID}, {UserUUID: bob.UUID}},
+	})
+	require.NoError(t, err)
+
+	month := time.Now().UTC().Format("2006-01")
+	insights, err := a.services.Insights.GetUserInsights(ctx, alice.UUID, month)
+	require.NoError(t, err)
+
+	// Paid, spent and group spend all land in USD, so outstanding nets to
+	// what Bob owes Alice rather than mixing 100 EUR with 55 USD
+	require.Len(t, insights.Currencies, 1)
+	usd := insights.Currencies[0]
+	assert.Equal(t, "USD", usd.Currency)
+	assertAmount(t, "55", usd.TotalSpent)
+	assertAmount(t, "110", usd.TotalPaid)
+	assertAmount(t, "110", usd.GroupSpend)
+	assertAmount(t, "-55", usd.Outstanding)
+	assertAmount(t, "-55", a.balance(t, group, alice))
+}
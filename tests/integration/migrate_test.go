@@ -0,0 +1,60 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"expense-split-tracker/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate_SQLiteRecordsConsolidatedSchema(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+
+	migrations, err := database.Migrations()
+	require.NoError(t, err)
+
+	count, err := a.db.Migrate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), count)
+
+	// Nothing is pending the second time
+	count, err = a.db.Migrate(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	statuses, err := a.db.MigrationStatus(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(migrations))
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.Name)
+		assert.NotNil(t, status.AppliedAt, status.Name)
+	}
+
+	// The migrations are MySQL's, so they cannot be reverted here
+	_, err = a.db.MigrateDown(ctx, 1)
+	assert.Error(t, err)
+}
+
+func TestMigrate_BaselineRecordsWithoutRunning(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+
+	count, err := a.db.Baseline(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	statuses, err := a.db.MigrationStatus(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.Equal(t, status.Version <= 5, status.Applied, status.Name)
+	}
+
+	// Later migrations cannot run on SQLite, so Migrate names the first one
+	_, err = a.db.Migrate(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), statuses[5].Name)
+}
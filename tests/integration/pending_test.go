@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"expense-split-tracker/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingUsersAreFlaggedWhereverTheyAreJoined(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+
+	alice, err := a.services.User.CreateUser(ctx, &models.CreateUserRequest{Name: "Alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+
+	// Dan has never registered, so bootstrapping the group adds him as a placeholder
+	bootstrapped, err := a.services.Group.BootstrapGroup(ctx, &models.BootstrapGroupRequest{
+		Name:            "Flat",
+		DefaultCurrency: "USD",
+		CreatorUUID:     alice.UUID,
+		Members:         []models.BootstrapMemberRequest{{Name: "Dan", Email: "dan@example.com"}},
+	})
+	require.NoError(t, err)
+	group := bootstrapped.Group
+	require.Len(t, bootstrapped.Members, 2)
+	dan := bootstrapped.Members[1].User
+	require.True(t, dan.IsPending)
+
+	expense, err := a.services.Expense.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  alice.UUID,
+		Amount:      decimal.NewFromInt(40),
+		Currency:    "USD",
+		Description: "Groceries",
+		SplitType:   models.SplitTypeEqual,
+		Splits:      []models.CreateExpenseSplitRequest{{UserUUID: alice.UUID}, {UserUUID: dan.UUID}},
+	})
+	require.NoError(t, err)
+
+	pendingByUUID := func(users ...*models.User) map[string]bool {
+		flags := make(map[string]bool)
+		for _, user := range users {
+			require.NotNil(t, user)
+			flags[user.UUID] = user.IsPending
+		}
+		return flags
+	}
+	want := map[string]bool{alice.UUID: false, dan.UUID: true}
+
+	members, err := a.services.Group.GetGroupMembers(ctx, group.UUID)
+	require.NoError(t, err)
+	var memberUsers []*models.User
+	for _, member := range members {
+		memberUsers = append(memberUsers, member.User)
+	}
+	assert.Equal(t, want, pendingByUUID(memberUsers...), "members")
+
+	loaded, err := a.services.Expense.GetExpenseByUUID(ctx, expense.UUID)
+	require.NoError(t, err)
+	assert.False(t, loaded.Payer.IsPending)
+	var splitUsers []*models.User
+	for _, split := range loaded.Splits {
+		splitUsers = append(splitUsers, split.User)
+	}
+	assert.Equal(t, want, pendingByUUID(splitUsers...), "splits")
+
+	sheet, err := a.services.Balance.GetGroupBalanceSheet(ctx, models.GroupUUID(group.UUID), "USD", "")
+	require.NoError(t, err)
+	var balanceUsers []*models.User
+	for _, balance := range sheet.Balances {
+		balanceUsers = append(balanceUsers, balance.User)
+	}
+	assert.Equal(t, want, pendingByUUID(balanceUsers...), "balances")
+
+	debts, err := a.services.Balance.GetDebtRelationships(ctx, models.GroupUUID(group.UUID), "USD")
+	require.NoError(t, err)
+	require.Len(t, debts, 1)
+	assert.Equal(t, want, pendingByUUID(debts[0].Debtor, debts[0].Creditor), "debts")
+
+	settlement, err := a.services.Settlement.CreateSettlement(ctx, &models.CreateSettlementRequest{
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(dan.UUID),
+		ToUserUUID:   models.UserUUID(alice.UUID),
+		Amount:       decimal.NewFromInt(20),
+		Currency:     "USD",
+	})
+	require.NoError(t, err)
+
+	loadedSettlement, err := a.services.Settlement.GetSettlementByUUID(ctx, settlement.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, want, pendingByUUID(loadedSettlement.FromUser, loadedSettlement.ToUser), "settlement")
+
+	settlements, _, err := a.services.Settlement.GetGroupSettlements(ctx, group.UUID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, settlements, 1)
+	assert.Equal(t, want, pendingByUUID(settlements[0].FromUser, settlements[0].ToUser), "group settlements")
+}
@@ -0,0 +1,70 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportsUseTheGroupTimezone(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+	_, alice, bob, _ := a.trip(t)
+
+	group, err := a.services.Group.CreateGroup(ctx, &models.CreateGroupRequest{Name: "New York", Timezone: "America/New_York", DefaultCurrency: "USD"}, alice.UUID)
+	require.NoError(t, err)
+	require.NoError(t, a.services.Group.AddMember(ctx, group.UUID, &models.AddMemberRequest{UserUUID: bob.UUID, ActingUserUUID: alice.UUID}))
+
+	// 03:00 UTC on 1 March is still 29 February in New York
+	lateNight := time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC)
+	_, err = a.services.Expense.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  alice.UUID,
+		Amount:      decimal.NewFromInt(30),
+		Currency:    "USD",
+		Description: "Late dinner",
+		Category:    "food",
+		ExpenseDate: lateNight,
+		SplitType:   models.SplitTypeEqual,
+		Splits:      []models.CreateExpenseSplitRequest{{UserUUID: alice.UUID}, {UserUUID: bob.UUID}},
+	})
+	require.NoError(t, err)
+
+	categoriesOn := func(day time.Time) []*models.CurrencyCategories {
+		report, err := a.services.Report.GetCategoryReport(ctx, group.UUID, &models.CategoryReportFilter{FromDate: day, ToDate: day})
+		require.NoError(t, err)
+		return report.Currencies
+	}
+	leapDay := categoriesOn(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	require.Len(t, leapDay, 1)
+	assertAmount(t, "30", leapDay[0].Total)
+	assert.Empty(t, categoriesOn(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+
+	// The insights count it in February as well, going by its expense date
+	// rather than when it was recorded
+	insights, err := a.services.Insights.GetUserInsights(ctx, alice.UUID, "2024-03")
+	require.NoError(t, err)
+	require.Len(t, insights.Currencies, 1)
+	series := insights.Currencies[0].Series
+	require.Len(t, series, 6)
+	assert.Equal(t, "2024-02", series[4].Month)
+	assertAmount(t, "30", series[4].Paid)
+	assertAmount(t, "0", series[5].Paid)
+
+	// So do Bob's stats, which span his groups
+	statsOn := func(day time.Time) []*models.CurrencyUserStats {
+		stats, err := a.services.Report.GetUserStats(ctx, bob.UUID, &models.UserStatsFilter{FromDate: day, ToDate: day})
+		require.NoError(t, err)
+		return stats.Currencies
+	}
+	leapDayStats := statsOn(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	require.Len(t, leapDayStats, 1)
+	assertAmount(t, "15", leapDayStats[0].TotalShare)
+	assert.Empty(t, statsOn(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTransaction_CancelledContextRollsBack(t *testing.T) {
+	a := newApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	user := &models.User{UUID: utils.GenerateUUID(), Name: "Alice", Email: "alice@example.com"}
+	err := a.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		if err := a.repos.User.Create(ctx, tx, user); err != nil {
+			return err
+		}
+		// The client goes away before the transaction commits
+		cancel()
+		return nil
+	})
+	require.Error(t, err)
+
+	// database/sql rolls a cancelled transaction back in the background
+	assert.Eventually(t, func() bool {
+		_, err := a.repos.User.GetByUUID(context.Background(), user.UUID)
+		var appErr *errors.AppError
+		return stderrors.As(err, &appErr) && appErr.Code == errors.ErrCodeNotFound
+	}, time.Second, 10*time.Millisecond)
+
+	// A context cancelled up front never starts one
+	err = a.db.WithTransaction(ctx, func(tx *database.Tx) error {
+		t.Fatal("transaction started with a cancelled context")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWithTransaction_CommitFailureReachesCaller(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+	group, alice, bob, carol := a.trip(t)
+
+	_, err := a.services.Expense.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  alice.UUID,
+		Amount:      decimal.NewFromInt(90),
+		Currency:    "USD",
+		Description: "Dinner",
+		SplitType:   models.SplitTypeEqual,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: alice.UUID}, {UserUUID: bob.UUID}, {UserUUID: carol.UUID},
+		},
+	})
+	require.NoError(t, err)
+	outboxBefore, err := a.repos.Outbox.GetPending(ctx, 100)
+	require.NoError(t, err)
+
+	var emitted []events.Event
+	a.events.Subscribe(func(ctx context.Context, event events.Event) error {
+		emitted = append(emitted, event)
+		return nil
+	})
+
+	// Every settlement insert leaves a dangling reference that SQLite only
+	// checks when the transaction commits, so fn succeeds and COMMIT fails
+	_, err = a.db.ExecContext(ctx, `
+		CREATE TABLE commit_trap (user_id INTEGER REFERENCES users(id) DEFERRABLE INITIALLY DEFERRED);
+		CREATE TRIGGER settlements_commit_trap AFTER INSERT ON settlements
+		BEGIN
+			INSERT INTO commit_trap (user_id) VALUES (-1);
+		END;
+	`)
+	require.NoError(t, err)
+
+	settlement, err := a.services.Settlement.CreateSettlement(ctx, &models.CreateSettlementRequest{
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(bob.UUID),
+		ToUserUUID:   models.UserUUID(alice.UUID),
+		Amount:       decimal.NewFromInt(30),
+		Currency:     "USD",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "FOREIGN KEY constraint failed")
+	assert.Nil(t, settlement)
+	assert.Empty(t, emitted)
+
+	// Nothing the transaction wrote survived
+	assertAmount(t, "30", a.balance(t, group, bob))
+	assertAmount(t, "-60", a.balance(t, group, alice))
+	settlements, total, err := a.services.Settlement.GetGroupSettlements(ctx, group.UUID, 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, settlements)
+	assert.Zero(t, total)
+	outboxAfter, err := a.repos.Outbox.GetPending(ctx, 100)
+	require.NoError(t, err)
+	assert.Len(t, outboxAfter, len(outboxBefore))
+}
+
+func TestStatementContext_CancelsSlowStatements(t *testing.T) {
+	db := openDB(t, 50*time.Millisecond)
+
+	ctx, cancel := db.StatementContext(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), deadline, 50*time.Millisecond)
+
+	// Counts far enough to outlast the timeout unless it is interrupted
+	var count int64
+	start := time.Now()
+	err := db.QueryRowContext(ctx, `
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000000000)
+		SELECT COUNT(*) FROM n
+	`).Scan(&count)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestStatementContext_ZeroTimeoutHasNoDeadline(t *testing.T) {
+	db := openDB(t, 0)
+
+	ctx, cancel := db.StatementContext(context.Background())
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+}
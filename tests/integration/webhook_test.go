@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/routes"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/internal/webhook"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// deliveriesResponse is the envelope of the dead-letter endpoints
+type deliveriesResponse[T any] struct {
+	Success bool `json:"success"`
+	Data    T    `json:"data"`
+	Error   *struct {
+		Code string `json:"code"`
+	} `json:"error"`
+}
+
+func serve[T any](t *testing.T, router *gin.Engine, method, path string) (int, deliveriesResponse[T]) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+
+	var body deliveriesResponse[T]
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body), recorder.Body.String())
+	return recorder.Code, body
+}
+
+func TestDeadLetteredWebhookDeliveryCanBeRedelivered(t *testing.T) {
+	a := newApp(t)
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+	group, _, _, _ := a.trip(t)
+
+	// The receiver is down until up is set
+	var up atomic.Bool
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		if !up.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	webhookRepo := repository.NewWebhookRepository(a.db, logger)
+	dispatcher := webhook.NewDispatcher(webhookRepo, webhook.Options{MaxAttempts: 2, Backoff: time.Millisecond}, logger)
+	a.services.Webhook = service.NewWebhookService(webhookRepo, a.repos.Group, dispatcher, logger)
+
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(runCtx)
+		close(done)
+	}()
+	defer func() {
+		stop()
+		<-done
+	}()
+
+	hook, err := a.services.Webhook.CreateWebhook(ctx, models.GroupUUID(group.UUID), &models.CreateWebhookRequest{
+		URL:        receiver.URL,
+		Secret:     "0123456789abcdef-secret",
+		EventTypes: []models.WebhookEventType{models.WebhookEventExpenseCreated},
+	})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	routes.SetupRoutes(router, a.services, a.db, logger)
+	deadLetters := "/api/v1/groups/" + group.UUID + "/webhooks/" + hook.UUID + "/dead-letters"
+
+	event := &models.OutboxEvent{ID: 1, GroupID: group.ID, EventType: string(models.WebhookEventExpenseCreated), Payload: json.RawMessage(`{"amount":"90"}`)}
+	require.NoError(t, dispatcher.Consume(ctx, event))
+
+	// Both attempts fail, so the delivery is dead-lettered
+	var listed []*models.WebhookDelivery
+	require.Eventually(t, func() bool {
+		code, body := serve[[]*models.WebhookDelivery](t, router, http.MethodGet, deadLetters)
+		require.Equal(t, http.StatusOK, code)
+		listed = body.Data
+		return len(listed) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	deadLettered := listed[0]
+	assert.Equal(t, models.WebhookDeliveryDeadLettered, deadLettered.Status)
+	assert.Equal(t, models.WebhookEventExpenseCreated, deadLettered.EventType)
+	assert.Equal(t, 2, deadLettered.Attempts)
+	require.NotNil(t, deadLettered.LastError)
+	assert.Contains(t, *deadLettered.LastError, "502")
+	assert.EqualValues(t, 2, received.Load())
+
+	// Once the receiver is back the delivery is sent again on request
+	up.Store(true)
+	redeliver := deadLetters + "/" + deadLettered.UUID + "/redeliver"
+	code, redelivered := serve[*models.WebhookDelivery](t, router, http.MethodPost, redeliver)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.WebhookDeliveryPending, redelivered.Data.Status)
+
+	require.Eventually(t, func() bool {
+		delivery, err := webhookRepo.GetDeliveryByUUID(ctx, deadLettered.UUID)
+		require.NoError(t, err)
+		return delivery.Status == models.WebhookDeliveryDelivered
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 3, received.Load())
+
+	code, body := serve[[]*models.WebhookDelivery](t, router, http.MethodGet, deadLetters)
+	require.Equal(t, http.StatusOK, code)
+	assert.Empty(t, body.Data)
+
+	// The webhook detail lists every attempt, newest first
+	code, detail := serve[*models.Webhook](t, router, http.MethodGet, "/api/v1/groups/"+group.UUID+"/webhooks/"+hook.UUID)
+	require.Equal(t, http.StatusOK, code)
+	attempts := detail.Data.RecentAttempts
+	require.Len(t, attempts, 3)
+	assert.Equal(t, models.WebhookAttemptDelivered, attempts[0].Outcome)
+	assert.Equal(t, 3, attempts[0].Attempt)
+	require.NotNil(t, attempts[0].StatusCode)
+	assert.Equal(t, http.StatusOK, *attempts[0].StatusCode)
+	for _, attempt := range attempts[1:] {
+		assert.Equal(t, models.WebhookAttemptFailed, attempt.Outcome)
+		require.NotNil(t, attempt.StatusCode)
+		assert.Equal(t, http.StatusBadGateway, *attempt.StatusCode)
+		require.NotNil(t, attempt.Error)
+		assert.Contains(t, *attempt.Error, "502")
+	}
+	for _, attempt := range attempts {
+		assert.Equal(t, deadLettered.UUID, attempt.DeliveryUUID)
+		assert.Equal(t, models.WebhookEventExpenseCreated, attempt.EventType)
+		assert.GreaterOrEqual(t, attempt.LatencyMS, int64(0))
+	}
+
+	// Only dead-lettered deliveries can be redelivered
+	code, rejected := serve[*models.WebhookDelivery](t, router, http.MethodPost, redeliver)
+	assert.Equal(t, http.StatusConflict, code)
+	require.NotNil(t, rejected.Error)
+	assert.Equal(t, errors.ErrCodeNotDeadLettered, rejected.Error.Code)
+}
@@ -0,0 +1,281 @@
+// Package repository runs the repositories against a real database. The
+// suite is skipped unless TEST_DB_DRIVER (mysql or postgres) and TEST_DB_DSN
+// point at a database with the current schema loaded: the migrations for
+// MySQL, internal/database/schema/postgres.sql for Postgres. Every test
+// creates its own users and groups, so the database can be reused.
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/config"
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// openDB connects to the database selected by the environment
+func openDB(t *testing.T) *database.DB {
+	t.Helper()
+
+	driver, dsn := os.Getenv("TEST_DB_DRIVER"), os.Getenv("TEST_DB_DSN")
+	if driver == "" || dsn == "" {
+		t.Skip("TEST_DB_DRIVER and TEST_DB_DSN are not set")
+	}
+
+	cfg := &config.Config{Database: config.DatabaseConfig{Driver: driver, DSN: dsn}}
+	db, err := database.NewConnection(cfg, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fixture creates a group whose creator and second member are returned with it
+func fixture(t *testing.T, db *database.DB) (*models.Group, *models.User, *models.User) {
+	t.Helper()
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+	userRepo := repository.NewUserRepository(db, logger)
+	groupRepo := repository.NewGroupRepository(db, logger)
+
+	newUser := func(name string) *models.User {
+		user := &models.User{UUID: utils.GenerateUUID(), Name: name}
+		user.Email = user.UUID + "@example.com"
+		require.NoError(t, userRepo.Create(ctx, nil, user))
+		require.NotZero(t, user.ID)
+		return user
+	}
+	alice, bob := newUser("Alice"), newUser("Bob")
+
+	group := &models.Group{UUID: utils.GenerateUUID(), Name: "Trip", Timezone: "UTC", DefaultCurrency: "USD", CreatedBy: alice.ID}
+	require.NoError(t, groupRepo.Create(ctx, nil, group))
+	require.NotZero(t, group.ID)
+	require.NoError(t, groupRepo.AddMember(ctx, nil, group.ID, alice.ID, models.GroupRoleAdmin))
+	require.NoError(t, groupRepo.AddMember(ctx, nil, group.ID, bob.ID, models.GroupRoleMember))
+
+	return group, alice, bob
+}
+
+func newExpense(group *models.Group, payer *models.User, amount int64, category string) *models.Expense {
+	return &models.Expense{
+		UUID:         utils.GenerateUUID(),
+		GroupID:      group.ID,
+		PaidBy:       payer.ID,
+		Amount:       decimal.NewFromInt(amount),
+		Currency:     "USD",
+		BaseAmount:   decimal.NewFromInt(amount),
+		BaseCurrency: "USD",
+		ExchangeRate: decimal.NewFromInt(1),
+		Description:  "Dinner",
+		Category:     category,
+		SplitType:    models.SplitTypeEqual,
+		ExpenseDate:  time.Now(),
+	}
+}
+
+func TestUserRepository_DuplicateEmailIsConflict(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	repo := repository.NewUserRepository(db, zaptest.NewLogger(t))
+
+	first := &models.User{UUID: utils.GenerateUUID(), Name: "Alice"}
+	first.Email = first.UUID + "@example.com"
+	require.NoError(t, repo.Create(ctx, nil, first))
+
+	second := &models.User{UUID: utils.GenerateUUID(), Name: "Alice again", Email: first.Email}
+	err := repo.Create(ctx, nil, second)
+
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, errors.ErrCodeAlreadyExists, appErr.Code)
+	assert.Equal(t, "email", appErr.Details["field"])
+}
+
+func TestGroupRepository_AddMemberTwiceKeepsOneMembership(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	repo := repository.NewGroupRepository(db, zaptest.NewLogger(t))
+	group, alice, bob := fixture(t, db)
+
+	require.NoError(t, repo.AddMember(ctx, nil, group.ID, bob.ID, models.GroupRoleMember))
+
+	members, err := repo.GetMembers(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	loaded, err := repo.GetByUUID(ctx, group.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, loaded.CreatedBy)
+}
+
+func TestBalanceRepository_UpsertsAccumulateAndReplace(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	repo := repository.NewBalanceRepository(db, zaptest.NewLogger(t))
+	group, alice, bob := fixture(t, db)
+
+	require.NoError(t, repo.UpdateBalance(ctx, nil, group.ID, alice.ID, decimal.NewFromInt(30), "USD"))
+	require.NoError(t, repo.UpdateBalance(ctx, nil, group.ID, alice.ID, decimal.NewFromInt(-12), "USD"))
+
+	balance, err := repo.GetByGroupAndUser(ctx, group.ID, alice.ID, "USD")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(18).Equal(balance.Balance), balance.Balance.String())
+
+	require.NoError(t, repo.Upsert(ctx, nil, &models.Balance{GroupID: group.ID, UserID: alice.ID, Balance: decimal.NewFromInt(5), Currency: "USD"}))
+	balance, err = repo.GetByGroupAndUser(ctx, group.ID, alice.ID, "USD")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(5).Equal(balance.Balance), balance.Balance.String())
+
+	require.NoError(t, repo.UpdateDebt(ctx, nil, group.ID, bob.ID, alice.ID, decimal.NewFromInt(20), "USD"))
+	require.NoError(t, repo.UpdateDebt(ctx, nil, group.ID, alice.ID, bob.ID, decimal.NewFromInt(5), "USD"))
+
+	err = db.WithTransaction(ctx, func(tx *database.Tx) error {
+		owed, err := repo.GetDebtForUpdate(ctx, tx, group.ID, bob.ID, alice.ID, "USD")
+		if err != nil {
+			return err
+		}
+		assert.True(t, decimal.NewFromInt(15).Equal(owed), owed.String())
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestGroupLockRepository_UpsertReplacesLock(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	repo := repository.NewGroupLockRepository(db, zaptest.NewLogger(t))
+	group, _, _ := fixture(t, db)
+
+	expired := &models.GroupLock{GroupID: group.ID, Holder: utils.GenerateUUID(), Purpose: models.GroupLockSettleUp, ExpiresAt: time.Now().Add(-time.Minute)}
+	require.NoError(t, repo.Upsert(ctx, nil, expired))
+
+	active, err := repo.GetActive(ctx, nil, group.ID)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	current := &models.GroupLock{GroupID: group.ID, Holder: utils.GenerateUUID(), Purpose: models.GroupLockReconcile, ExpiresAt: time.Now().Add(time.Minute)}
+	require.NoError(t, repo.Upsert(ctx, nil, current))
+
+	active, err = repo.GetActive(ctx, nil, group.ID)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, current.Holder, active.Holder)
+	assert.Equal(t, models.GroupLockReconcile, active.Purpose)
+}
+
+func TestExpenseRepository_CreateAndDuplicateSplit(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	repo := repository.NewExpenseRepository(db, zaptest.NewLogger(t))
+	group, alice, bob := fixture(t, db)
+
+	expense := newExpense(group, alice, 90, "food")
+	require.NoError(t, repo.Create(ctx, nil, expense))
+	require.NotZero(t, expense.ID)
+
+	split := &models.ExpenseSplit{ExpenseID: expense.ID, UserID: bob.ID, Amount: decimal.NewFromInt(45)}
+	require.NoError(t, repo.CreateSplit(ctx, nil, split))
+	require.NotZero(t, split.ID)
+
+	err := repo.CreateSplit(ctx, nil, &models.ExpenseSplit{ExpenseID: expense.ID, UserID: bob.ID, Amount: decimal.NewFromInt(45)})
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, "user_uuid", appErr.Details["field"])
+
+	// A soft-deleted split no longer blocks a new one for the same user
+	require.NoError(t, repo.DeleteExpenseSplits(ctx, nil, expense.ID, &alice.ID))
+	require.NoError(t, repo.CreateSplit(ctx, nil, &models.ExpenseSplit{ExpenseID: expense.ID, UserID: bob.ID, Amount: decimal.NewFromInt(30)}))
+
+	deleted, err := repo.Delete(ctx, nil, expense.ID, &alice.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	loaded, err := repo.GetByUUIDIncludingDeleted(ctx, expense.UUID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.DeletedBy)
+	assert.Equal(t, alice.ID, *loaded.DeletedBy)
+}
+
+func TestInsightsRepository_GroupsByMonth(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	expenseRepo := repository.NewExpenseRepository(db, zaptest.NewLogger(t))
+	repo := repository.NewInsightsRepository(db, zaptest.NewLogger(t))
+	group, alice, _ := fixture(t, db)
+
+	require.NoError(t, expenseRepo.Create(ctx, nil, newExpense(group, alice, 40, "food")))
+	require.NoError(t, expenseRepo.Create(ctx, nil, newExpense(group, alice, 60, "travel")))
+
+	now := time.Now()
+	aggregates, err := repo.GetPaidByMonth(ctx, alice.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.NotEmpty(t, aggregates)
+
+	var total decimal.Decimal
+	var count int
+	for _, aggregate := range aggregates {
+		assert.Regexp(t, `^\d{4}-\d{2}$`, aggregate.Month)
+		total = total.Add(aggregate.Amount)
+		count += aggregate.Count
+	}
+	assert.True(t, decimal.NewFromInt(100).Equal(total), total.String())
+	assert.Equal(t, 2, count)
+}
+
+func TestInsightsRepository_CountsExpensesOnTheirExpenseDate(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	expenseRepo := repository.NewExpenseRepository(db, zaptest.NewLogger(t))
+	repo := repository.NewInsightsRepository(db, zaptest.NewLogger(t))
+	group, alice, _ := fixture(t, db)
+
+	// Recorded today for a dinner in January
+	backdated := newExpense(group, alice, 25, "food")
+	backdated.ExpenseDate = time.Date(2024, 1, 15, 19, 0, 0, 0, time.UTC)
+	require.NoError(t, expenseRepo.Create(ctx, nil, backdated))
+
+	now := time.Now()
+	aggregates, err := repo.GetPaidByMonth(ctx, alice.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, aggregates)
+
+	aggregates, err = repo.GetPaidByMonth(ctx, alice.ID, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, aggregates, 1)
+	assert.Equal(t, "2024-01", aggregates[0].Month)
+	assert.True(t, decimal.NewFromInt(25).Equal(aggregates[0].Amount), aggregates[0].Amount.String())
+}
+
+func TestReportRepository_CategoryTotalsMergeUncategorized(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+	expenseRepo := repository.NewExpenseRepository(db, zaptest.NewLogger(t))
+	repo := repository.NewReportRepository(db, zaptest.NewLogger(t))
+	group, alice, _ := fixture(t, db)
+
+	require.NoError(t, expenseRepo.Create(ctx, nil, newExpense(group, alice, 10, "")))
+	require.NoError(t, expenseRepo.Create(ctx, nil, newExpense(group, alice, 15, models.DefaultExpenseCategory)))
+	require.NoError(t, expenseRepo.Create(ctx, nil, newExpense(group, alice, 20, "food")))
+
+	totals, err := repo.GetCategoryTotals(ctx, group.ID, &models.CategoryReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, totals, 2)
+
+	byCategory := make(map[string]*models.CategoryAggregate, len(totals))
+	for _, total := range totals {
+		byCategory[total.Category] = total
+	}
+	require.Contains(t, byCategory, models.DefaultExpenseCategory)
+	assert.Equal(t, 2, byCategory[models.DefaultExpenseCategory].Count)
+	assert.True(t, decimal.NewFromInt(25).Equal(byCategory[models.DefaultExpenseCategory].Amount))
+}
@@ -0,0 +1,225 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"expense-split-tracker/internal/auth"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestGroupService_GetGroupMembers_Authorization(t *testing.T) {
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", DefaultCurrency: "USD"}
+	member := &auth.Identity{UserID: 1, UserUUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	outsider := &auth.Identity{UserID: 3, UserUUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	newService := func() (service.GroupService, *MockGroupRepositoryES) {
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, member.UserID).Return(true, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, outsider.UserID).Return(false, nil)
+		groupRepo.On("GetMemberships", mock.Anything, group.ID).Return([]*models.GroupMember{}, nil)
+		balanceRepo := new(MockBalanceRepositoryES)
+		balanceRepo.On("GetGroupBalances", mock.Anything, group.ID, "USD").Return([]*models.Balance{}, nil)
+
+		gs := service.NewGroupService(groupRepo, new(MockUserRepositoryES), nil, balanceRepo, new(MockDBES), 50, events.NopEmitter{}, zaptest.NewLogger(t))
+		return gs, groupRepo
+	}
+
+	t.Run("member is allowed", func(t *testing.T) {
+		gs, groupRepo := newService()
+
+		_, err := gs.GetGroupMembers(auth.WithIdentity(context.Background(), member), group.UUID)
+		assert.NoError(t, err)
+		groupRepo.AssertCalled(t, "IsMember", mock.Anything, group.ID, member.UserID)
+	})
+
+	t.Run("non-member is forbidden", func(t *testing.T) {
+		gs, groupRepo := newService()
+
+		_, err := gs.GetGroupMembers(auth.WithIdentity(context.Background(), outsider), group.UUID)
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeForbidden, appErr.Code)
+			assert.Equal(t, http.StatusForbidden, appErr.Status)
+		}
+		groupRepo.AssertNotCalled(t, "GetMemberships", mock.Anything, mock.Anything)
+	})
+
+	t.Run("anonymous caller is unauthorized", func(t *testing.T) {
+		gs, groupRepo := newService()
+
+		_, err := gs.GetGroupMembers(auth.WithIdentity(context.Background(), &auth.Identity{}), group.UUID)
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeUnauthorized, appErr.Code)
+			assert.Equal(t, http.StatusUnauthorized, appErr.Status)
+		}
+		groupRepo.AssertNotCalled(t, "IsMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("no identity skips the check", func(t *testing.T) {
+		gs, groupRepo := newService()
+
+		_, err := gs.GetGroupMembers(context.Background(), group.UUID)
+		assert.NoError(t, err)
+		groupRepo.AssertNotCalled(t, "IsMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestSettlementService_GetSettlementByUUID_NonMember(t *testing.T) {
+	settlementUUID := "dddddddd-dddd-4ddd-8ddd-dddddddddddd"
+	outsider := &auth.Identity{UserID: 3, UserUUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	settlementRepo := new(MockSettlementRepository)
+	settlementRepo.On("GetByUUID", mock.Anything, settlementUUID).Return(&models.Settlement{ID: 1, UUID: settlementUUID, GroupID: 10}, nil)
+	groupRepo := new(MockGroupRepository2)
+	groupRepo.On("IsMember", mock.Anything, int64(10), outsider.UserID).Return(false, nil)
+
+	s := service.NewSettlementService(settlementRepo, groupRepo, new(MockUserRepository2), new(MockBalanceRepository2), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB2), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	settlement, err := s.GetSettlementByUUID(auth.WithIdentity(context.Background(), outsider), settlementUUID)
+	assert.Nil(t, settlement)
+	appErr, ok := err.(*errors.AppError)
+	if assert.True(t, ok) {
+		assert.Equal(t, errors.ErrCodeForbidden, appErr.Code)
+	}
+}
+
+func TestExpenseService_ListExpenses_RequiresGroupWhenEnforced(t *testing.T) {
+	member := &auth.Identity{UserID: 1, UserUUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	es := service.NewExpenseService(expenseRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES),
+		newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	result, err := es.ListExpenses(auth.WithIdentity(context.Background(), member), &models.ExpenseFilter{Page: 1, Limit: 10})
+	assert.Nil(t, result)
+	appErr, ok := err.(*errors.AppError)
+	if assert.True(t, ok) {
+		assert.Equal(t, errors.ErrCodeForbidden, appErr.Code)
+	}
+	expenseRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestExportService_ExportGroupCSV_Authorization(t *testing.T) {
+	member := &auth.Identity{UserID: 1, UserUUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	outsider := &auth.Identity{UserID: 3, UserUUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	newService := func() (*MockExpenseRepositoryES, service.ExportService, *models.Group) {
+		expenseRepo, settlementRepo, groupRepo, es, group := setupGroupExport(t)
+		groupRepo.On("IsMember", mock.Anything, group.ID, member.UserID).Return(true, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, outsider.UserID).Return(false, nil)
+		expenseRepo.On("GetGroupSplitUsers", mock.Anything, group.ID).Return([]*models.User{}, nil)
+		expenseRepo.On("IterateGroupExpenses", mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything).Return([][]*models.Expense{}, nil)
+		settlementRepo.On("List", mock.Anything, mock.Anything).Return([]*models.Settlement{}, 0, nil)
+		return expenseRepo, es, group
+	}
+
+	t.Run("member is allowed", func(t *testing.T) {
+		_, es, group := newService()
+
+		var buf bytes.Buffer
+		assert.NoError(t, es.ExportGroupCSV(auth.WithIdentity(context.Background(), member), group.UUID, &models.ExportFilter{}, &buf))
+		assert.NotZero(t, buf.Len())
+	})
+
+	t.Run("non-member is forbidden", func(t *testing.T) {
+		expenseRepo, es, group := newService()
+
+		var buf bytes.Buffer
+		err := es.ExportGroupCSV(auth.WithIdentity(context.Background(), outsider), group.UUID, &models.ExportFilter{}, &buf)
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeForbidden, appErr.Code)
+		}
+		assert.Zero(t, buf.Len())
+		expenseRepo.AssertNotCalled(t, "GetGroupSplitUsers", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRecurringExpenseService_Authorization(t *testing.T) {
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", DefaultCurrency: "USD"}
+	recurring := &models.RecurringExpense{ID: 5, UUID: "ffffffff-ffff-4fff-8fff-ffffffffffff", GroupID: group.ID, Group: group}
+	member := &auth.Identity{UserID: 1, UserUUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	outsider := &auth.Identity{UserID: 3, UserUUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	newService := func() (service.RecurringExpenseService, *MockRecurringExpenseRepository) {
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, member.UserID).Return(true, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, outsider.UserID).Return(false, nil)
+		recurringRepo := new(MockRecurringExpenseRepository)
+		recurringRepo.On("GetByUUID", mock.Anything, recurring.UUID).Return(recurring, nil)
+		recurringRepo.On("GetGroupRecurringExpenses", mock.Anything, group.ID).Return([]*models.RecurringExpense{recurring}, nil)
+		recurringRepo.On("Delete", mock.Anything, mock.Anything, recurring.ID).Return(nil)
+
+		rs := service.NewRecurringExpenseService(recurringRepo, groupRepo, new(MockUserRepositoryES), new(MockExpenseServiceRE), zaptest.NewLogger(t))
+		return rs, recurringRepo
+	}
+
+	template := models.CreateRecurringExpenseRequest{
+		PaidByUUID:  string(member.UserUUID),
+		Amount:      decimal.NewFromInt(100),
+		Description: "Internet",
+		SplitType:   models.SplitTypeEqual,
+		Frequency:   models.FrequencyMonthly,
+	}
+	operations := map[string]func(ctx context.Context, rs service.RecurringExpenseService) error{
+		"create": func(ctx context.Context, rs service.RecurringExpenseService) error {
+			_, err := rs.CreateRecurringExpense(ctx, models.GroupUUID(group.UUID), &template)
+			return err
+		},
+		"get": func(ctx context.Context, rs service.RecurringExpenseService) error {
+			_, err := rs.GetRecurringExpense(ctx, models.GroupUUID(group.UUID), models.RecurringExpenseUUID(recurring.UUID))
+			return err
+		},
+		"list": func(ctx context.Context, rs service.RecurringExpenseService) error {
+			_, err := rs.ListRecurringExpenses(ctx, models.GroupUUID(group.UUID))
+			return err
+		},
+		"update": func(ctx context.Context, rs service.RecurringExpenseService) error {
+			_, err := rs.UpdateRecurringExpense(ctx, models.GroupUUID(group.UUID), models.RecurringExpenseUUID(recurring.UUID), &models.UpdateRecurringExpenseRequest{CreateRecurringExpenseRequest: template})
+			return err
+		},
+		"delete": func(ctx context.Context, rs service.RecurringExpenseService) error {
+			return rs.DeleteRecurringExpense(ctx, models.GroupUUID(group.UUID), models.RecurringExpenseUUID(recurring.UUID))
+		},
+	}
+
+	for name, operation := range operations {
+		t.Run(name+" by a non-member is forbidden", func(t *testing.T) {
+			rs, recurringRepo := newService()
+
+			err := operation(auth.WithIdentity(context.Background(), outsider), rs)
+			appErr, ok := err.(*errors.AppError)
+			if assert.True(t, ok) {
+				assert.Equal(t, errors.ErrCodeForbidden, appErr.Code)
+			}
+			recurringRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+			recurringRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+			recurringRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	// Create and update also validate the template; reads and deletes only
+	// need the membership
+	for _, name := range []string{"get", "list", "delete"} {
+		t.Run(name+" by a member is allowed", func(t *testing.T) {
+			rs, _ := newService()
+
+			assert.NoError(t, operations[name](auth.WithIdentity(context.Background(), member), rs))
+		})
+	}
+}
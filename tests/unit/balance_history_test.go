@@ -0,0 +1,189 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// MockBalanceHistoryRepository is a mock implementation of BalanceHistoryRepository
+type MockBalanceHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockBalanceHistoryRepository) Record(ctx context.Context, tx *database.Tx, entry *models.BalanceHistoryEntry) error {
+	args := m.Called(ctx, tx, entry)
+	return args.Error(0)
+}
+
+func (m *MockBalanceHistoryRepository) ListForUser(ctx context.Context, filter *models.BalanceHistoryFilter) ([]*models.BalanceHistoryEntry, int, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]*models.BalanceHistoryEntry), args.Int(1), args.Error(2)
+}
+
+// newBalanceHistoryRepo returns a history repository that accepts every entry
+func newBalanceHistoryRepo() *MockBalanceHistoryRepository {
+	historyRepo := new(MockBalanceHistoryRepository)
+	historyRepo.On("Record", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return historyRepo
+}
+
+// recordedHistory returns the entries passed to Record, in order
+func recordedHistory(historyRepo *MockBalanceHistoryRepository) []*models.BalanceHistoryEntry {
+	var entries []*models.BalanceHistoryEntry
+	for _, call := range historyRepo.Calls {
+		if call.Method == "Record" {
+			entries = append(entries, call.Arguments.Get(2).(*models.BalanceHistoryEntry))
+		}
+	}
+	return entries
+}
+
+func TestBalanceHistory_RecordedForExpense(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	other := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	groupRepo := new(MockGroupRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+	userRepo := new(MockUserRepositoryES)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	userRepo.On("GetByUUID", mock.Anything, other.UUID).Return(other, nil)
+	expenseRepo := new(MockExpenseRepositoryES)
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Expense).ID = 42
+	}).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(42)).Return([]*models.ExpenseSplit{}, nil)
+	balanceRepo := new(MockBalanceRepositoryES)
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+	db := new(MockDBES)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+	historyRepo := newBalanceHistoryRepo()
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, historyRepo, newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(60),
+		Currency:    "USD",
+		Description: "Taxi",
+		SplitType:   models.SplitTypeEqual,
+		Splits:      []models.CreateExpenseSplitRequest{{UserUUID: payer.UUID}, {UserUUID: other.UUID}},
+	})
+	assert.NoError(t, err)
+
+	// One entry per balance change, all pointing back at the expense
+	entries := recordedHistory(historyRepo)
+	assert.Len(t, entries, 3)
+	deltas := map[int64]decimal.Decimal{}
+	for _, entry := range entries {
+		assert.Equal(t, models.BalanceHistorySourceExpense, entry.SourceType)
+		assert.Equal(t, int64(42), entry.SourceID)
+		assert.Equal(t, group.ID, entry.GroupID)
+		assert.Equal(t, "USD", entry.Currency)
+		deltas[entry.UserID] = deltas[entry.UserID].Add(entry.Delta)
+	}
+	assert.True(t, deltas[payer.ID].Equal(decimal.NewFromInt(-30)))
+	assert.True(t, deltas[other.ID].Equal(decimal.NewFromInt(30)))
+	balanceRepo.AssertNumberOfCalls(t, "UpdateBalance", 3)
+}
+
+func TestBalanceHistory_RecordedForVoidedSettlement(t *testing.T) {
+	ctx := context.Background()
+
+	settlement := &models.Settlement{
+		ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", GroupID: 10,
+		FromUserID: 1, ToUserID: 2, Amount: decimal.NewFromInt(50), Currency: "USD", Status: models.SettlementStatusConfirmed,
+	}
+	now := time.Now()
+	voided := *settlement
+	voided.VoidedAt = &now
+
+	sr := new(MockSettlementRepository)
+	sr.On("GetByUUID", mock.Anything, settlement.UUID).Return(settlement, nil).Once()
+	sr.On("GetByUUID", mock.Anything, settlement.UUID).Return(&voided, nil)
+	sr.On("Void", mock.Anything, mock.Anything, settlement.ID, mock.Anything).Return(true, nil)
+	br := new(MockBalanceRepository2)
+	br.On("UpdateBalance", mock.Anything, mock.Anything, int64(10), mock.Anything, mock.Anything, "USD").Return(nil)
+	br.On("UpdateDebt", mock.Anything, mock.Anything, int64(10), int64(1), int64(2), decimal.NewFromInt(50), "USD").Return(nil)
+	db := new(MockDB2)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+	historyRepo := newBalanceHistoryRepo()
+
+	s := service.NewSettlementService(sr, new(MockGroupRepository2), new(MockUserRepository2), br, historyRepo, newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	_, err := s.VoidSettlement(ctx, settlement.UUID)
+	assert.NoError(t, err)
+
+	entries := recordedHistory(historyRepo)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, int64(1), entries[0].UserID)
+	assert.True(t, entries[0].Delta.Equal(decimal.NewFromInt(50)))
+	assert.Equal(t, int64(2), entries[1].UserID)
+	assert.True(t, entries[1].Delta.Equal(decimal.NewFromInt(-50)))
+	for _, entry := range entries {
+		assert.Equal(t, models.BalanceHistorySourceSettlement, entry.SourceType)
+		assert.Equal(t, settlement.ID, entry.SourceID)
+	}
+}
+
+func TestBalanceService_GetBalanceHistory(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	user := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+
+	gr := new(MockGroupRepository2)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	gr.On("IsMember", mock.Anything, group.ID, user.ID).Return(true, nil)
+	ur := new(MockUserRepository2)
+	ur.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+
+	from := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 7, 14, 0, 0, 0, 0, time.UTC)
+
+	t.Run("queries the whole last day for the user in the group", func(t *testing.T) {
+		historyRepo := new(MockBalanceHistoryRepository)
+		entries := []*models.BalanceHistoryEntry{
+			{ID: 1, GroupID: group.ID, UserID: user.ID, Currency: "USD", Delta: decimal.NewFromInt(30), Balance: decimal.NewFromInt(30), SourceType: models.BalanceHistorySourceExpense, SourceID: 5},
+			{ID: 2, GroupID: group.ID, UserID: user.ID, Currency: "USD", Delta: decimal.NewFromInt(-30), Balance: decimal.Zero, SourceType: models.BalanceHistorySourceSettlement, SourceID: 7},
+		}
+		historyRepo.On("ListForUser", mock.Anything, mock.MatchedBy(func(filter *models.BalanceHistoryFilter) bool {
+			return filter.GroupID == group.ID && filter.UserID == user.ID && filter.Currency == "USD" &&
+				filter.FromDate.Equal(from) && filter.ToDate.Equal(to.AddDate(0, 0, 1).Add(-time.Nanosecond))
+		})).Return(entries, 2, nil)
+
+		s := service.NewBalanceService(new(MockBalanceRepository2), gr, ur, nil, nil, historyRepo, nil, new(MockDB2), nil, zaptest.NewLogger(t))
+
+		filter := &models.BalanceHistoryFilter{Currency: "usd", FromDate: from, ToDate: to, Page: 1, Limit: 10}
+		result, total, err := s.GetBalanceHistory(ctx, models.GroupUUID(group.UUID), models.UserUUID(user.UUID), filter)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		assert.Equal(t, entries, result)
+	})
+
+	t.Run("rejects a range ending before it starts", func(t *testing.T) {
+		s := service.NewBalanceService(new(MockBalanceRepository2), gr, ur, nil, nil, new(MockBalanceHistoryRepository), nil, new(MockDB2), nil, zaptest.NewLogger(t))
+
+		filter := &models.BalanceHistoryFilter{FromDate: to, ToDate: from}
+		_, _, err := s.GetBalanceHistory(ctx, models.GroupUUID(group.UUID), models.UserUUID(user.UUID), filter)
+		assert.Error(t, err)
+	})
+}
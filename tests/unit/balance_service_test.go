@@ -0,0 +1,638 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/fx"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestBalanceService_GetUserBalance_Breakdown(t *testing.T) {
+	cases := []struct {
+		name       string
+		balance    string
+		expenses   models.ExpenseTotals
+		settlement models.SettlementTotals
+	}{
+		{
+			// Paid 240 for dinner, owes 60 of it plus 90 of Bob's groceries; Bob sent 50 back
+			name:       "creditor partly paid back",
+			balance:    "-40",
+			expenses:   models.ExpenseTotals{Paid: decimal.NewFromInt(240), Owed: decimal.NewFromInt(150), Count: 2},
+			settlement: models.SettlementTotals{Received: decimal.NewFromInt(50), Count: 1},
+		},
+		{
+			// Owes 240 of a 300 hotel booked by someone else and has paid 100 of it back
+			name:       "debtor after a settlement",
+			balance:    "140",
+			expenses:   models.ExpenseTotals{Owed: decimal.NewFromInt(240), Count: 1},
+			settlement: models.SettlementTotals{Sent: decimal.NewFromInt(100), Count: 1},
+		},
+		{
+			// A refund is stored negated and cancels the expense it reverses
+			name:       "refund nets out",
+			balance:    "0",
+			expenses:   models.ExpenseTotals{Paid: decimal.Zero, Owed: decimal.Zero, Count: 2},
+			settlement: models.SettlementTotals{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+			user := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+			balance := decimal.RequireFromString(tc.balance)
+
+			gr := new(MockGroupRepository2)
+			gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			gr.On("IsMember", mock.Anything, group.ID, user.ID).Return(true, nil)
+			ur := new(MockUserRepository2)
+			ur.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+			sr := new(MockSettlementRepository)
+			sr.On("List", mock.Anything, mock.Anything).Return([]*models.Settlement{}, 0, nil)
+			br := new(MockBalanceRepository2)
+			br.On("GetByGroupAndUser", mock.Anything, group.ID, user.ID, "USD").Return(&models.Balance{Balance: balance, Currency: "USD"}, nil)
+			br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return([]*models.DebtRelationship{}, nil)
+			expenses, settlement := tc.expenses, tc.settlement
+			br.On("GetUserExpenseTotals", mock.Anything, group.ID, user.ID, "USD").Return(&expenses, nil)
+			br.On("GetUserSettlementTotals", mock.Anything, group.ID, user.ID, "USD").Return(&settlement, nil)
+
+			s := service.NewBalanceService(br, gr, ur, sr, nil, nil, nil, new(MockDB2), nil, zaptest.NewLogger(t))
+
+			detail, err := s.GetUserBalance(ctx, models.GroupUUID(group.UUID), models.UserUUID(user.UUID), "USD")
+			assert.NoError(t, err)
+
+			b := detail.Breakdown
+			assert.True(t, b.TotalPaid.Equal(tc.expenses.Paid))
+			assert.True(t, b.TotalOwed.Equal(tc.expenses.Owed))
+			assert.True(t, b.TotalSettled.Equal(tc.settlement.Received.Sub(tc.settlement.Sent)))
+			assert.Equal(t, tc.expenses.Count, b.ExpenseCount)
+			assert.Equal(t, tc.settlement.Count, b.PaymentCount)
+
+			// What the user is owed is the negated stored balance
+			owed := b.TotalPaid.Sub(b.TotalOwed).Sub(b.TotalSettled)
+			assert.True(t, owed.Equal(detail.Balance.Neg()), "paid - owed - settled = %s, balance = %s", owed, detail.Balance)
+		})
+	}
+}
+
+func TestBalanceService_GetUserSummary(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	user := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	gr := new(MockGroupRepository2)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	gr.On("IsMember", mock.Anything, group.ID, user.ID).Return(true, nil)
+	ur := new(MockUserRepository2)
+	ur.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+	br := new(MockBalanceRepository2)
+	// In USD the user paid 240, owes 150 of it and got 50 back; in EUR they
+	// owe 30 of someone else's expense and have only settled in that currency
+	br.On("GetUserExpenseTotalsByCurrency", mock.Anything, group.ID, user.ID).Return([]*models.ExpenseTotals{
+		{Currency: "USD", Paid: decimal.NewFromInt(240), Owed: decimal.NewFromInt(150), Count: 2},
+		{Currency: "EUR", Owed: decimal.NewFromInt(30), Count: 1},
+	}, nil)
+	br.On("GetUserSettlementTotalsByCurrency", mock.Anything, group.ID, user.ID).Return([]*models.SettlementTotals{
+		{Currency: "USD", Received: decimal.NewFromInt(50), Count: 1},
+		{Currency: "GBP", Sent: decimal.NewFromInt(5), Count: 1},
+	}, nil)
+	br.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{
+		{UserID: user.ID, Currency: "USD", Balance: decimal.NewFromInt(-40)},
+		{UserID: bob.ID, Currency: "USD", Balance: decimal.NewFromInt(40)},
+		{UserID: user.ID, Currency: "EUR", Balance: decimal.NewFromInt(30)},
+		{UserID: user.ID, Currency: "GBP", Balance: decimal.NewFromInt(-5)},
+	}, nil)
+
+	s := service.NewBalanceService(br, gr, ur, nil, nil, nil, nil, new(MockDB2), nil, zaptest.NewLogger(t))
+
+	summary, err := s.GetUserSummary(ctx, models.GroupUUID(group.UUID), models.UserUUID(user.UUID))
+	assert.NoError(t, err)
+	assert.Equal(t, user, summary.User)
+	if assert.Len(t, summary.Currencies, 3) {
+		eur, gbp, usd := summary.Currencies[0], summary.Currencies[1], summary.Currencies[2]
+		assert.Equal(t, "EUR", eur.Currency)
+		assert.True(t, eur.NetBalance.Equal(decimal.NewFromInt(30)))
+		assert.Equal(t, 1, eur.ExpenseCount)
+
+		assert.Equal(t, "GBP", gbp.Currency)
+		assert.True(t, gbp.NetBalance.Equal(decimal.NewFromInt(-5)))
+		assert.Equal(t, 1, gbp.PaymentCount)
+
+		assert.Equal(t, "USD", usd.Currency)
+		assert.True(t, usd.TotalPaid.Equal(decimal.NewFromInt(240)))
+		assert.True(t, usd.TotalOwed.Equal(decimal.NewFromInt(150)))
+		assert.True(t, usd.SettledReceived.Equal(decimal.NewFromInt(50)))
+		assert.True(t, usd.NetBalance.Equal(decimal.NewFromInt(-40)))
+		assert.True(t, usd.NetBalance.Equal(usd.StoredBalance))
+	}
+}
+
+func TestBalanceService_RebuildGroupBalances(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"}
+
+	gr := new(MockGroupRepository2)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+
+	// Alice paid 90 split three ways and Bob paid her back 30. Alice's stored
+	// balance is right, Bob's misses the settlement and Carol's was edited by
+	// hand; so was a debt from Carol to Bob that nothing backs.
+	er := new(MockExpenseRepositoryES)
+	er.On("IterateGroupExpenses", mock.Anything, group.ID, time.Time{}, time.Time{}, mock.Anything).Return([][]*models.Expense{
+		{{ID: 1, GroupID: group.ID, PaidBy: alice.ID, Payer: alice, Amount: decimal.NewFromInt(90), Currency: "USD", BaseAmount: decimal.NewFromInt(90), BaseCurrency: "USD"}},
+	}, nil)
+	er.On("GetSplitsForExpenses", mock.Anything, []int64{1}).Return(map[int64][]*models.ExpenseSplit{
+		1: {
+			{ExpenseID: 1, UserID: alice.ID, User: alice, Amount: decimal.NewFromInt(30)},
+			{ExpenseID: 1, UserID: bob.ID, User: bob, Amount: decimal.NewFromInt(30)},
+			{ExpenseID: 1, UserID: carol.ID, User: carol, Amount: decimal.NewFromInt(30)},
+		},
+	}, nil)
+	sr := new(MockSettlementRepository)
+	sr.On("IterateGroupSettlements", mock.Anything, group.ID, mock.Anything).Return([][]*models.Settlement{
+		{{ID: 1, GroupID: group.ID, FromUserID: bob.ID, ToUserID: alice.ID, Amount: decimal.NewFromInt(30), Currency: "USD"}},
+	}, nil)
+
+	br := new(MockBalanceRepository2)
+	br.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{
+		{GroupID: group.ID, UserID: alice.ID, User: alice, Balance: decimal.NewFromInt(-30), Currency: "USD"},
+		{GroupID: group.ID, UserID: bob.ID, User: bob, Balance: decimal.NewFromInt(30), Currency: "USD"},
+		{GroupID: group.ID, UserID: carol.ID, User: carol, Balance: decimal.NewFromInt(25), Currency: "USD"},
+		{GroupID: group.ID, UserID: carol.ID, User: carol, Balance: decimal.NewFromInt(5), Currency: "EUR"},
+	}, nil)
+	br.On("GetGroupDebtsAllCurrencies", mock.Anything, group.ID).Return([]*models.DebtRelationship{
+		{Debtor: bob, Creditor: alice, Amount: decimal.NewFromInt(30), Currency: "USD"},
+		{Debtor: carol, Creditor: alice, Amount: decimal.NewFromInt(30), Currency: "USD"},
+		{Debtor: carol, Creditor: bob, Amount: decimal.NewFromInt(5), Currency: "EUR"},
+	}, nil)
+	br.On("ZeroGroupBalances", mock.Anything, mock.Anything, group.ID).Return(nil)
+	br.On("ZeroGroupDebts", mock.Anything, mock.Anything, group.ID).Return(nil)
+	br.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	upserted := map[string]decimal.Decimal{}
+	br.On("Upsert", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Balance")).Run(func(args mock.Arguments) {
+		balance := args.Get(2).(*models.Balance)
+		upserted[fmt.Sprintf("%d/%s", balance.UserID, balance.Currency)] = balance.Balance
+	}).Return(nil)
+
+	lockRepo := new(MockGroupLockRepository)
+	lockRepo.On("GetForUpdate", mock.Anything, mock.Anything, group.ID).Return(nil, nil)
+	lockRepo.On("Upsert", mock.Anything, mock.Anything, mock.AnythingOfType("*models.GroupLock")).Return(nil)
+	lockRepo.On("Delete", mock.Anything, mock.Anything, group.ID, mock.Anything).Return(nil)
+	db := new(MockDB2)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+	locks := service.NewGroupLockService(lockRepo, db, 30*time.Second, zaptest.NewLogger(t))
+
+	historyRepo := newBalanceHistoryRepo()
+
+	s := service.NewBalanceService(br, gr, new(MockUserRepository2), sr, er, historyRepo, locks, db, nil, zaptest.NewLogger(t))
+
+	rebuild, err := s.RebuildGroupBalances(ctx, models.GroupUUID(group.UUID))
+	assert.NoError(t, err)
+
+	// Alice -30, Bob 0, Carol 30 in USD; Carol's EUR balance has nothing behind it
+	assert.True(t, upserted["1/USD"].Equal(decimal.NewFromInt(-30)))
+	assert.True(t, upserted["2/USD"].Equal(decimal.Zero))
+	assert.True(t, upserted["3/USD"].Equal(decimal.NewFromInt(30)))
+	assert.True(t, upserted["3/EUR"].Equal(decimal.Zero))
+
+	assert.Len(t, rebuild.Adjustments, 4)
+	assert.Equal(t, 3, rebuild.DriftedCount)
+	assert.True(t, rebuild.Adjustments[1].Delta.IsZero())
+	eur := rebuild.Adjustments[0]
+	assert.Equal(t, "EUR", eur.Currency)
+	assert.True(t, eur.Delta.Equal(decimal.NewFromInt(-5)))
+	carolUSD := rebuild.Adjustments[3]
+	assert.Equal(t, carol, carolUSD.User)
+	assert.True(t, carolUSD.Previous.Equal(decimal.NewFromInt(25)))
+	assert.True(t, carolUSD.Recomputed.Equal(decimal.NewFromInt(30)))
+	assert.True(t, carolUSD.Delta.Equal(decimal.NewFromInt(5)))
+
+	// Each drifted balance gets a rebuild entry in its history
+	history := recordedHistory(historyRepo)
+	assert.Len(t, history, 3)
+	for _, entry := range history {
+		assert.Equal(t, models.BalanceHistorySourceRebuild, entry.SourceType)
+		assert.Equal(t, group.ID, entry.SourceID)
+	}
+
+	// Only Carol's debt to Alice is backed by the history, so it is the only
+	// one written back after the debts are zeroed
+	br.AssertNumberOfCalls(t, "ZeroGroupDebts", 1)
+	br.AssertNumberOfCalls(t, "UpdateDebt", 1)
+	br.AssertCalled(t, "UpdateDebt", mock.Anything, mock.Anything, group.ID, alice.ID, carol.ID, decimal.NewFromInt(-30), "USD")
+
+	assert.Len(t, rebuild.DebtAdjustments, 3)
+	assert.Equal(t, 2, rebuild.DriftedDebtCount)
+	want := []struct {
+		debtor, creditor     *models.User
+		currency             string
+		previous, recomputed int64
+	}{
+		{carol, bob, "EUR", 5, 0},
+		{bob, alice, "USD", 30, 0},
+		{carol, alice, "USD", 30, 30},
+	}
+	for i, w := range want {
+		debt := rebuild.DebtAdjustments[i]
+		assert.Equal(t, w.debtor, debt.Debtor)
+		assert.Equal(t, w.creditor, debt.Creditor)
+		assert.Equal(t, w.currency, debt.Currency)
+		assert.True(t, debt.Previous.Equal(decimal.NewFromInt(w.previous)), debt.Previous.String())
+		assert.True(t, debt.Recomputed.Equal(decimal.NewFromInt(w.recomputed)), debt.Recomputed.String())
+		assert.True(t, debt.Delta.Equal(decimal.NewFromInt(w.recomputed-w.previous)), debt.Delta.String())
+	}
+
+	// The reconcile lock is taken and given back
+	lockRepo.AssertCalled(t, "Upsert", mock.Anything, mock.Anything, mock.MatchedBy(func(lock *models.GroupLock) bool {
+		return lock.Purpose == models.GroupLockReconcile
+	}))
+	lockRepo.AssertNumberOfCalls(t, "Delete", 1)
+}
+
+func TestBalanceService_VerifyGroupBalances(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+
+	gr := new(MockGroupRepository2)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+
+	// One USD expense of 60 paid by user 1 and split with user 2, and one EUR
+	// expense of 20 paid by user 2 and split with user 1
+	er := new(MockExpenseRepositoryES)
+	er.On("IterateGroupExpenses", mock.Anything, group.ID, time.Time{}, time.Time{}, mock.Anything).Return([][]*models.Expense{
+		{
+			{ID: 1, GroupID: group.ID, PaidBy: 1, Amount: decimal.NewFromInt(60), Currency: "USD", BaseAmount: decimal.NewFromInt(60), BaseCurrency: "USD"},
+			{ID: 2, GroupID: group.ID, PaidBy: 2, Amount: decimal.NewFromInt(20), Currency: "EUR", BaseAmount: decimal.NewFromInt(20), BaseCurrency: "EUR"},
+		},
+	}, nil)
+	er.On("GetSplitsForExpenses", mock.Anything, []int64{1, 2}).Return(map[int64][]*models.ExpenseSplit{
+		1: {{ExpenseID: 1, UserID: 1, Amount: decimal.NewFromInt(30)}, {ExpenseID: 1, UserID: 2, Amount: decimal.NewFromInt(30)}},
+		2: {{ExpenseID: 2, UserID: 1, Amount: decimal.NewFromInt(10)}, {ExpenseID: 2, UserID: 2, Amount: decimal.NewFromInt(10)}},
+	}, nil)
+	sr := new(MockSettlementRepository)
+	sr.On("IterateGroupSettlements", mock.Anything, group.ID, mock.Anything).Return([][]*models.Settlement{}, nil)
+
+	// USD is stored correctly; EUR has drifted by 2.50
+	br := new(MockBalanceRepository2)
+	br.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{
+		{GroupID: group.ID, UserID: 1, Balance: decimal.NewFromInt(-30), Currency: "USD"},
+		{GroupID: group.ID, UserID: 2, Balance: decimal.NewFromInt(30), Currency: "USD"},
+		{GroupID: group.ID, UserID: 1, Balance: decimal.NewFromFloat(12.5), Currency: "EUR"},
+		{GroupID: group.ID, UserID: 2, Balance: decimal.NewFromInt(-10), Currency: "EUR"},
+	}, nil)
+
+	s := service.NewBalanceService(br, gr, new(MockUserRepository2), sr, er, nil, nil, new(MockDB2), nil, zaptest.NewLogger(t))
+
+	verification, err := s.VerifyGroupBalances(ctx, models.GroupUUID(group.UUID))
+	assert.NoError(t, err)
+	assert.False(t, verification.Consistent)
+	assert.Len(t, verification.Currencies, 2)
+
+	eur := verification.Currencies[0]
+	assert.Equal(t, "EUR", eur.Currency)
+	assert.True(t, eur.StoredSum.Equal(decimal.NewFromFloat(2.5)))
+	assert.True(t, eur.RecomputedSum.IsZero())
+	assert.True(t, eur.Drift.Equal(decimal.NewFromFloat(2.5)))
+	assert.False(t, eur.Consistent)
+
+	usd := verification.Currencies[1]
+	assert.Equal(t, "USD", usd.Currency)
+	assert.True(t, usd.StoredSum.IsZero())
+	assert.True(t, usd.RecomputedSum.IsZero())
+	assert.True(t, usd.Consistent)
+
+	// Verification never writes balances
+	br.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything, mock.Anything)
+	br.AssertNotCalled(t, "ZeroGroupBalances", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// pairwiseDebtLedger keeps pairwise debts in memory the way user_debts does:
+// one signed amount per ordered pair, positive when the lower ID owes the higher
+type pairwiseDebtLedger struct {
+	*MockBalanceRepository2
+	users map[int64]*models.User
+	debts map[[2]int64]decimal.Decimal
+}
+
+func (l *pairwiseDebtLedger) UpdateDebt(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, amount decimal.Decimal, currency string) error {
+	if debtorID > creditorID {
+		debtorID, creditorID, amount = creditorID, debtorID, amount.Neg()
+	}
+	l.debts[[2]int64{debtorID, creditorID}] = l.debts[[2]int64{debtorID, creditorID}].Add(amount)
+	return nil
+}
+
+func (l *pairwiseDebtLedger) GetDebtForUpdate(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, currency string) (decimal.Decimal, error) {
+	if debtorID < creditorID {
+		return l.debts[[2]int64{debtorID, creditorID}], nil
+	}
+	return l.debts[[2]int64{creditorID, debtorID}].Neg(), nil
+}
+
+func (l *pairwiseDebtLedger) GetGroupDebts(ctx context.Context, groupID int64, currency string) ([]*models.DebtRelationship, error) {
+	var relationships []*models.DebtRelationship
+	for pair, amount := range l.debts {
+		switch {
+		case amount.IsPositive():
+			relationships = append(relationships, &models.DebtRelationship{Debtor: l.users[pair[0]], Creditor: l.users[pair[1]], Amount: amount, Currency: currency})
+		case amount.IsNegative():
+			relationships = append(relationships, &models.DebtRelationship{Debtor: l.users[pair[1]], Creditor: l.users[pair[0]], Amount: amount.Neg(), Currency: currency})
+		}
+	}
+	return relationships, nil
+}
+
+func TestBalanceService_GetDebtRelationships_FollowsTransactions(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"}
+	dave := &models.User{ID: 4, UUID: "dddddddd-dddd-4ddd-8ddd-dddddddddddd", Name: "Dave"}
+	members := []*models.User{alice, bob, carol, dave}
+
+	ledger := &pairwiseDebtLedger{
+		MockBalanceRepository2: new(MockBalanceRepository2),
+		users:                  map[int64]*models.User{},
+		debts:                  map[[2]int64]decimal.Decimal{},
+	}
+	ledger.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	ledger.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, group.ID, alice.ID, "USD").Return(&models.Balance{Balance: decimal.NewFromInt(-30)}, nil)
+
+	groupRepoES := new(MockGroupRepositoryES)
+	groupRepoES.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepoES := new(MockUserRepositoryES)
+	groupRepo2 := new(MockGroupRepository2)
+	groupRepo2.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo2 := new(MockUserRepository2)
+	for _, user := range members {
+		ledger.users[user.ID] = user
+		userRepoES.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+		groupRepoES.On("IsMember", mock.Anything, group.ID, user.ID).Return(true, nil)
+		userRepo2.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+		groupRepo2.On("IsMember", mock.Anything, group.ID, user.ID).Return(true, nil)
+	}
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, mock.Anything).Return([]*models.ExpenseSplit{}, nil)
+	expenseDB := new(MockDBES)
+	expenseDB.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+	expenses := service.NewExpenseService(expenseRepo, groupRepoES, userRepoES, ledger, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), expenseDB, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	settlementRepo := new(MockSettlementRepository)
+	settlementRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Settlement")).Return(nil)
+	settlementRepo.On("GetByUUID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Settlement{}, nil)
+	settlementDB := new(MockDB2)
+	settlementDB.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+	settlements := service.NewSettlementService(settlementRepo, groupRepo2, userRepo2, ledger, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), settlementDB, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	split := func(paidBy *models.User, amount int64, between ...*models.User) {
+		req := &models.CreateExpenseRequest{
+			GroupUUID:   group.UUID,
+			PaidByUUID:  paidBy.UUID,
+			Amount:      decimal.NewFromInt(amount),
+			Currency:    "USD",
+			Description: "Shared",
+			SplitType:   models.SplitTypeEqual,
+		}
+		for _, user := range between {
+			req.Splits = append(req.Splits, models.CreateExpenseSplitRequest{UserUUID: user.UUID})
+		}
+		_, err := expenses.CreateExpense(ctx, req)
+		assert.NoError(t, err)
+	}
+
+	// Alice only ever shares costs with Bob, and Carol only with Dave. Bob's
+	// own expense and his payment net against what he owes Alice.
+	split(alice, 100, alice, bob)
+	split(bob, 40, alice, bob)
+	split(carol, 60, carol, dave)
+	_, err := settlements.CreateSettlement(ctx, &models.CreateSettlementRequest{
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(bob.UUID),
+		ToUserUUID:   models.UserUUID(alice.UUID),
+		Amount:       decimal.NewFromInt(10),
+		Currency:     "USD",
+	})
+	assert.NoError(t, err)
+
+	s := service.NewBalanceService(ledger, groupRepo2, userRepo2, settlementRepo, expenseRepo, nil, nil, settlementDB, nil, logger)
+	relationships, err := s.GetDebtRelationships(ctx, models.GroupUUID(group.UUID), "USD")
+	assert.NoError(t, err)
+
+	owes := map[string]decimal.Decimal{}
+	for _, relationship := range relationships {
+		owes[relationship.Debtor.Name+"->"+relationship.Creditor.Name] = relationship.Amount
+	}
+	assert.Len(t, owes, 2)
+	assert.True(t, owes["Bob->Alice"].Equal(decimal.NewFromInt(20)))
+	assert.True(t, owes["Dave->Carol"].Equal(decimal.NewFromInt(30)))
+	for _, relationship := range relationships {
+		if relationship.Debtor == carol || relationship.Creditor == carol {
+			assert.Equal(t, dave, relationship.Debtor, "Carol only ever shared an expense with Dave")
+		}
+	}
+}
+
+func TestBalanceService_GetUserBalances(t *testing.T) {
+	ctx := context.Background()
+
+	user := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	trip := &models.Group{ID: 10, Name: "Trip"}
+	flat := &models.Group{ID: 11, Name: "Flat"}
+	office := &models.Group{ID: 12, Name: "Office"}
+
+	ur := new(MockUserRepository2)
+	ur.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+	br := new(MockBalanceRepository2)
+	br.On("GetUserBalances", mock.Anything, user.ID).Return([]*models.Balance{
+		{GroupID: trip.ID, Group: trip, UserID: user.ID, Balance: decimal.NewFromInt(150), Currency: "USD"},
+		{GroupID: flat.ID, Group: flat, UserID: user.ID, Balance: decimal.NewFromInt(-40), Currency: "EUR"},
+		{GroupID: office.ID, Group: office, UserID: user.ID, Balance: decimal.NewFromInt(-18), Currency: "USD"},
+	}, nil)
+
+	s := service.NewBalanceService(br, new(MockGroupRepository2), ur, nil, nil, nil, nil, new(MockDB2), nil, zaptest.NewLogger(t))
+
+	t.Run("all currencies", func(t *testing.T) {
+		overview, err := s.GetUserBalances(ctx, models.UserUUID(user.UUID), "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, user, overview.User)
+		assert.Len(t, overview.Currencies, 2)
+
+		eur, usd := overview.Currencies[0], overview.Currencies[1]
+		assert.Equal(t, "EUR", eur.Currency)
+		assert.Len(t, eur.Balances, 1)
+		assert.True(t, eur.NetBalance.Equal(decimal.NewFromInt(-40)))
+
+		// Owes 150 on the trip and is owed 18 at the office
+		assert.Equal(t, "USD", usd.Currency)
+		assert.Len(t, usd.Balances, 2)
+		assert.Equal(t, trip, usd.Balances[0].Group)
+		assert.True(t, usd.NetBalance.Equal(decimal.NewFromInt(132)))
+	})
+
+	t.Run("currency filter", func(t *testing.T) {
+		overview, err := s.GetUserBalances(ctx, models.UserUUID(user.UUID), "eur", "")
+		assert.NoError(t, err)
+		assert.Len(t, overview.Currencies, 1)
+		assert.Equal(t, "EUR", overview.Currencies[0].Currency)
+	})
+
+	t.Run("converted", func(t *testing.T) {
+		rates := fx.NewStaticProvider("USD", map[string]decimal.Decimal{"EUR": decimal.RequireFromString("0.8")})
+		s := service.NewBalanceService(br, new(MockGroupRepository2), ur, nil, nil, nil, nil, new(MockDB2), rates, zaptest.NewLogger(t))
+
+		overview, err := s.GetUserBalances(ctx, models.UserUUID(user.UUID), "", "usd")
+		assert.NoError(t, err)
+		eur, usd := overview.Currencies[0], overview.Currencies[1]
+		assert.True(t, eur.NetBalance.Equal(decimal.NewFromInt(-40)))
+		assert.True(t, eur.ConvertedNetBalance.Equal(decimal.NewFromInt(-50)))
+		assert.Equal(t, "1.25", eur.ExchangeRate.Rate.String())
+		assert.True(t, usd.ConvertedNetBalance.Equal(decimal.NewFromInt(132)))
+
+		// Owes 132 USD and is owed 50 USD worth of euros
+		assert.Equal(t, "USD", overview.Conversion.Currency)
+		assert.Len(t, overview.Conversion.Rates, 2)
+		assert.True(t, overview.Conversion.NetBalance.Equal(decimal.NewFromInt(82)))
+
+		// Without a provider only same-currency conversions work
+		_, err = service.NewBalanceService(br, new(MockGroupRepository2), ur, nil, nil, nil, nil, new(MockDB2), nil, zaptest.NewLogger(t)).
+			GetUserBalances(ctx, models.UserUUID(user.UUID), "", "USD")
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid currency", func(t *testing.T) {
+		_, err := s.GetUserBalances(ctx, models.UserUUID(user.UUID), "DOLLARS", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid uuid", func(t *testing.T) {
+		_, err := s.GetUserBalances(ctx, "not-a-uuid", "", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestBalanceService_GetUserCounterparties(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, Name: "Bob"}
+	carol := &models.User{ID: 3, Name: "Carol"}
+	dave := &models.User{ID: 4, Name: "Dave"}
+	erin := &models.User{ID: 5, Name: "Erin"}
+
+	gr := new(MockGroupRepository2)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	gr.On("IsMember", mock.Anything, group.ID, alice.ID).Return(true, nil)
+	ur := new(MockUserRepository2)
+	ur.On("GetByUUID", mock.Anything, alice.UUID).Return(alice, nil)
+	br := new(MockBalanceRepository2)
+	br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return([]*models.DebtRelationship{
+		{Debtor: bob, Creditor: alice, Amount: decimal.RequireFromString("12.00"), Currency: "USD"},
+		{Debtor: dave, Creditor: alice, Amount: decimal.RequireFromString("32.50"), Currency: "USD"},
+		{Debtor: alice, Creditor: carol, Amount: decimal.RequireFromString("8.25"), Currency: "USD"},
+		{Debtor: erin, Creditor: alice, Amount: decimal.RequireFromString("0.004"), Currency: "USD"},
+		{Debtor: bob, Creditor: carol, Amount: decimal.RequireFromString("5.00"), Currency: "USD"},
+	}, nil)
+
+	s := service.NewBalanceService(br, gr, ur, nil, nil, nil, nil, new(MockDB2), nil, zaptest.NewLogger(t))
+
+	counterparties, err := s.GetUserCounterparties(ctx, models.GroupUUID(group.UUID), models.UserUUID(alice.UUID), "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", counterparties.Currency)
+
+	// Erin's sub-cent remainder and Bob's debt to Carol are not Alice's business
+	assert.Len(t, counterparties.Debtors, 2)
+	assert.Equal(t, dave, counterparties.Debtors[0].User)
+	assert.True(t, counterparties.Debtors[0].Amount.Equal(decimal.RequireFromString("32.50")))
+	assert.Equal(t, bob, counterparties.Debtors[1].User)
+
+	assert.Len(t, counterparties.Creditors, 1)
+	assert.Equal(t, carol, counterparties.Creditors[0].User)
+	assert.True(t, counterparties.Creditors[0].Amount.Equal(decimal.RequireFromString("8.25")))
+}
+
+func TestBalanceService_GetPairwiseNet(t *testing.T) {
+	ctx := context.Background()
+
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	flat := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Flat"}
+	ski := &models.Group{ID: 11, UUID: "22222222-2222-4222-8222-222222222222", Name: "Ski trip"}
+	book := &models.Group{ID: 12, UUID: "33333333-3333-4333-8333-333333333333", Name: "Book club"}
+
+	ur := new(MockUserRepository2)
+	ur.On("GetByUUID", mock.Anything, alice.UUID).Return(alice, nil)
+	ur.On("GetByUUID", mock.Anything, bob.UUID).Return(bob, nil)
+	gr := new(MockGroupRepository2)
+	gr.On("GetSharedGroups", mock.Anything, alice.ID, bob.ID).Return([]*models.Group{flat, ski, book}, nil)
+	br := new(MockBalanceRepository2)
+	// Alice owes Bob 40 in Flat, Bob owes Alice 25 in Ski trip; they are square in Book club
+	br.On("GetPairDebts", mock.Anything, flat.ID, alice.ID, bob.ID).Return([]*models.PairDebt{
+		{Currency: "EUR", Amount: decimal.NewFromInt(-8)},
+		{Currency: "USD", Amount: decimal.NewFromInt(40)},
+	}, nil)
+	br.On("GetPairDebts", mock.Anything, ski.ID, alice.ID, bob.ID).Return([]*models.PairDebt{
+		{Currency: "USD", Amount: decimal.NewFromInt(-25)},
+	}, nil)
+	br.On("GetPairDebts", mock.Anything, book.ID, alice.ID, bob.ID).Return([]*models.PairDebt{}, nil)
+
+	s := service.NewBalanceService(br, gr, ur, new(MockSettlementRepository), nil, nil, nil, new(MockDB2), nil, zaptest.NewLogger(t))
+
+	net, err := s.GetPairwiseNet(ctx, models.UserUUID(alice.UUID), models.UserUUID(bob.UUID), "")
+	assert.NoError(t, err)
+	assert.Equal(t, bob, net.OtherUser)
+	if assert.Len(t, net.Currencies, 2) {
+		eur, usd := net.Currencies[0], net.Currencies[1]
+		assert.Equal(t, "EUR", eur.Currency)
+		assert.Equal(t, models.NetDirectionOwed, eur.Direction)
+		assert.True(t, eur.Amount.Equal(decimal.NewFromInt(8)))
+
+		assert.Equal(t, "USD", usd.Currency)
+		assert.Equal(t, models.NetDirectionOwes, usd.Direction)
+		assert.True(t, usd.Amount.Equal(decimal.NewFromInt(15)))
+		if assert.Len(t, usd.Groups, 2) {
+			assert.Equal(t, flat, usd.Groups[0].Group)
+			assert.Equal(t, models.NetDirectionOwes, usd.Groups[0].Direction)
+			assert.True(t, usd.Groups[0].Amount.Equal(decimal.NewFromInt(40)))
+			assert.Equal(t, ski, usd.Groups[1].Group)
+			assert.Equal(t, models.NetDirectionOwed, usd.Groups[1].Direction)
+			assert.True(t, usd.Groups[1].Amount.Equal(decimal.NewFromInt(25)))
+		}
+	}
+
+	net, err = s.GetPairwiseNet(ctx, models.UserUUID(alice.UUID), models.UserUUID(bob.UUID), "usd")
+	assert.NoError(t, err)
+	assert.Len(t, net.Currencies, 1)
+	assert.Equal(t, "USD", net.Currencies[0].Currency)
+
+	_, err = s.GetPairwiseNet(ctx, models.UserUUID(alice.UUID), models.UserUUID(alice.UUID), "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"expense-split-tracker/internal/controller"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func postCreateExpense(t *testing.T, svc *MockExpenseServiceHandler, body string) response.APIResponse {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/expenses", controller.NewExpenseController(svc, zaptest.NewLogger(t)).CreateExpense)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/expenses", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp response.APIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, errors.ErrCodeValidation, resp.Error.Code)
+	assert.Equal(t, "Invalid request body", resp.Error.Message)
+	return resp
+}
+
+func TestBindJSON_MissingGroupUUID(t *testing.T) {
+	svc := new(MockExpenseServiceHandler)
+	resp := postCreateExpense(t, svc, `{"paid_by_uuid":"aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa","amount":"30",`+
+		`"description":"Taxi","split_type":"equal","splits":[{"user_uuid":"aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}]}`)
+
+	assert.Equal(t, []errors.FieldError{{Field: "group_uuid", Message: "is required"}}, resp.Error.Fields)
+	svc.AssertNotCalled(t, "CreateExpense", mock.Anything, mock.Anything)
+}
+
+func TestBindJSON_StringForDecimal(t *testing.T) {
+	svc := new(MockExpenseServiceHandler)
+	resp := postCreateExpense(t, svc, `{"group_uuid":"11111111-1111-4111-8111-111111111111","paid_by_uuid":"aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa",`+
+		`"amount":"ten","description":"Taxi","split_type":"equal","splits":[{"user_uuid":"aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}]}`)
+
+	assert.Equal(t, []errors.FieldError{{Field: "amount", Message: "must be a decimal number"}}, resp.Error.Fields)
+	svc.AssertNotCalled(t, "CreateExpense", mock.Anything, mock.Anything)
+}
+
+func TestBindJSON_UnknownFieldsWhenDisallowed(t *testing.T) {
+	binding.EnableDecoderDisallowUnknownFields = true
+	defer func() { binding.EnableDecoderDisallowUnknownFields = false }()
+
+	svc := new(MockExpenseServiceHandler)
+	resp := postCreateExpense(t, svc, `{"group_uuid":"11111111-1111-4111-8111-111111111111","paid_by_uuid":"aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa",`+
+		`"amount":"30","description":"Taxi","split_type":"equal","splits":[{"user_uuid":"aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}],"tip":"5"}`)
+
+	assert.Equal(t, []errors.FieldError{{Field: "tip", Message: "is not a known field"}}, resp.Error.Fields)
+	svc.AssertNotCalled(t, "CreateExpense", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"expense-split-tracker/internal/config"
+	"expense-split-tracker/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func corsRequest(cfg *config.Config, origin string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.CORSMiddleware(cfg))
+	router.GET("/api/v1/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	req.Header.Set("Origin", origin)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSMiddleware_AllowedOrigins(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Env: "production"},
+		CORS: config.CORSConfig{
+			AllowedOrigins:   []string{"https://app.example.com"},
+			AllowCredentials: true,
+		},
+	}
+
+	rec := corsRequest(cfg, "https://app.example.com")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	// Other origins are served without CORS headers rather than rejected
+	rec = corsRequest(cfg, "https://evil.example.com")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSMiddleware_DefaultsByEnvironment(t *testing.T) {
+	rec := corsRequest(&config.Config{Server: config.ServerConfig{Env: "development"}}, "http://localhost:3000")
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = corsRequest(&config.Config{Server: config.ServerConfig{Env: "production"}}, "http://localhost:3000")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestEffectiveCORSPolicy_NoCredentialsForWildcard(t *testing.T) {
+	policy := middleware.EffectiveCORSPolicy(&config.Config{
+		Server: config.ServerConfig{Env: "development"},
+		CORS:   config.CORSConfig{AllowCredentials: true},
+	})
+	assert.True(t, policy.AllowAllOrigins)
+	assert.False(t, policy.AllowCredentials)
+}
@@ -0,0 +1,96 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/controller"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// listSettlementsStub implements only ListSettlements; any other call panics
+type listSettlementsStub struct {
+	service.SettlementService
+	filter *models.SettlementFilter
+}
+
+func (s *listSettlementsStub) ListSettlements(ctx context.Context, filter *models.SettlementFilter) (*models.SettlementListResponse, error) {
+	s.filter = filter
+	return &models.SettlementListResponse{Settlements: []*models.Settlement{}, Page: 1, Limit: 10}, nil
+}
+
+func newDateFilterRouter(t *testing.T, expenses *MockExpenseServiceHandler, settlements *listSettlementsStub) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := zaptest.NewLogger(t)
+
+	router := gin.New()
+	router.GET("/api/v1/expenses", controller.NewExpenseController(expenses, logger).ListExpenses)
+	router.GET("/api/v1/settlements", controller.NewSettlementController(settlements, logger).ListSettlements)
+	return router
+}
+
+func TestListControllers_RejectMalformedDates(t *testing.T) {
+	expenses := new(MockExpenseServiceHandler)
+	settlements := &listSettlementsStub{}
+	router := newDateFilterRouter(t, expenses, settlements)
+
+	tests := []struct {
+		url     string
+		message string
+	}{
+		{"/api/v1/expenses?from_date=2024/01/01", "from_date must be a date (YYYY-MM-DD) or an RFC3339 timestamp, got '2024/01/01'"},
+		{"/api/v1/expenses?to_date=yesterday", "to_date must be a date (YYYY-MM-DD) or an RFC3339 timestamp, got 'yesterday'"},
+		{"/api/v1/expenses?from_date=2024-03-01&to_date=2024-02-01", "from_date must not be after to_date"},
+		{"/api/v1/settlements?from_date=01-02-2024", "from_date must be a date (YYYY-MM-DD) or an RFC3339 timestamp, got '01-02-2024'"},
+		{"/api/v1/settlements?from_date=2024-01-01T18:00:00Z&to_date=2024-01-01T09:00:00Z", "from_date must not be after to_date"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, tt.url)
+
+		var resp response.APIResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, errors.ErrCodeValidation, resp.Error.Code, tt.url)
+		assert.Equal(t, tt.message, resp.Error.Message, tt.url)
+	}
+	expenses.AssertNotCalled(t, "ListExpenses", mock.Anything, mock.Anything)
+	assert.Nil(t, settlements.filter)
+}
+
+func TestListControllers_AcceptDatesAndTimestamps(t *testing.T) {
+	expenses := new(MockExpenseServiceHandler)
+	expenses.On("ListExpenses", mock.Anything, mock.MatchedBy(func(filter *models.ExpenseFilter) bool {
+		return filter.FromDate.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) &&
+			filter.ToDate.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	})).Return(&models.ExpenseListResponse{Expenses: []*models.Expense{}, Page: 1, Limit: 10}, nil)
+	settlements := &listSettlementsStub{}
+	router := newDateFilterRouter(t, expenses, settlements)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/expenses?from_date=2024-01-01&to_date=2024-01-31", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	expenses.AssertExpectations(t)
+
+	// Intraday range with an offset; equal bounds are allowed
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/settlements?from_date=2024-01-01T09:00:00%2B05:30&to_date=2024-01-01T03:30:00Z", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotNil(t, settlements.filter) {
+		assert.True(t, settlements.filter.FromDate.Equal(time.Date(2024, 1, 1, 3, 30, 0, 0, time.UTC)))
+		assert.True(t, settlements.filter.ToDate.Equal(settlements.filter.FromDate))
+	}
+}
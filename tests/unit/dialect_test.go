@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/database"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dialectDB returns a DB for driver that is never connected; the dialect
+// helpers only look at the driver name
+func dialectDB(driver string) *database.DB {
+	return &database.DB{DB: sqlx.NewDb(nil, driver)}
+}
+
+func TestDialect_Quote(t *testing.T) {
+	assert.Equal(t, "`groups`", dialectDB(database.DriverMySQL).Quote("groups"))
+	assert.Equal(t, `"groups"`, dialectDB(database.DriverPostgres).Quote("groups"))
+	assert.Equal(t, `"groups"`, dialectDB(database.DriverSQLite).Quote("groups"))
+}
+
+func TestDialect_OnConflictUpdate(t *testing.T) {
+	for _, tc := range []struct {
+		driver string
+		want   string
+	}{
+		{database.DriverMySQL, "ON DUPLICATE KEY UPDATE balance = user_balances.balance + VALUES(balance)"},
+		{database.DriverPostgres, "ON CONFLICT (group_id, user_id, currency) DO UPDATE SET balance = user_balances.balance + EXCLUDED.balance"},
+		{database.DriverSQLite, "ON CONFLICT (group_id, user_id, currency) DO UPDATE SET balance = user_balances.balance + EXCLUDED.balance"},
+	} {
+		t.Run(tc.driver, func(t *testing.T) {
+			db := dialectDB(tc.driver)
+			got := db.OnConflictUpdate([]string{"group_id", "user_id", "currency"}, "balance = user_balances.balance + "+db.Excluded("balance"))
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDialect_ForUpdate(t *testing.T) {
+	assert.Equal(t, "FOR UPDATE", dialectDB(database.DriverMySQL).ForUpdate())
+	assert.Equal(t, "FOR UPDATE OF ub", dialectDB(database.DriverPostgres).ForUpdate("ub"))
+	// SQLite locks the database when the transaction begins instead
+	assert.Empty(t, dialectDB(database.DriverSQLite).ForUpdate("ub"))
+}
+
+func TestScanTime(t *testing.T) {
+	want := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+
+	for _, value := range []interface{}{want, "2024-03-05 14:30:00", []byte("2024-03-05T14:30:00Z")} {
+		var got time.Time
+		require.NoError(t, database.ScanTime(&got).Scan(value))
+		assert.True(t, want.Equal(got), "%v scanned as %v", value, got)
+	}
+
+	var got time.Time
+	assert.Error(t, database.ScanTime(&got).Scan("yesterday"))
+}
+
+func TestDialect_RebindsPlaceholders(t *testing.T) {
+	query := "SELECT id FROM users WHERE uuid = ? AND email = ?"
+
+	assert.Equal(t, query, dialectDB(database.DriverMySQL).Rebind(query))
+	assert.Equal(t, "SELECT id FROM users WHERE uuid = $1 AND email = $2", dialectDB(database.DriverPostgres).Rebind(query))
+}
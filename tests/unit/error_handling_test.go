@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"expense-split-tracker/internal/metrics"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/service"
 
@@ -16,9 +17,9 @@ func TestErrorHandling_InvalidUUIDs(t *testing.T) {
 	ctx := context.Background()
 	logger := zaptest.NewLogger(t)
 
-	es := service.NewExpenseService(nil, nil, nil, nil, nil, logger)
-	s := service.NewSettlementService(nil, nil, nil, nil, nil, logger)
-	bs := service.NewBalanceService(nil, nil, nil, nil, nil, logger)
+	es := service.NewExpenseService(nil, nil, nil, nil, newBalanceHistoryRepo(), nil, nil, nil, nil, metrics.Nop{}, logger)
+	s := service.NewSettlementService(nil, nil, nil, nil, newBalanceHistoryRepo(), nil, nil, nil, metrics.Nop{}, logger)
+	bs := service.NewBalanceService(nil, nil, nil, nil, nil, nil, nil, nil, nil, logger)
 
 	_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{GroupUUID: "bad", PaidByUUID: "bad", Amount: decimal.NewFromInt(1), Description: "d", SplitType: models.SplitTypeEqual, Splits: []models.CreateExpenseSplitRequest{{UserUUID: "bad"}}})
 	assert.Error(t, err)
@@ -26,6 +27,6 @@ func TestErrorHandling_InvalidUUIDs(t *testing.T) {
 	_, err = s.CreateSettlement(ctx, &models.CreateSettlementRequest{GroupUUID: "bad", FromUserUUID: "bad", ToUserUUID: "bad", Amount: decimal.NewFromInt(1)})
 	assert.Error(t, err)
 
-	_, err = bs.GetGroupBalanceSheet(ctx, "bad")
+	_, err = bs.GetGroupBalanceSheet(ctx, "bad", "", "")
 	assert.Error(t, err)
 }
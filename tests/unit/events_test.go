@@ -0,0 +1,121 @@
+package unit
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// RecordingEmitter captures emitted events so tests can assert side effects
+type RecordingEmitter struct {
+	Events []events.Event
+}
+
+func (r *RecordingEmitter) Emit(ctx context.Context, event events.Event) {
+	r.Events = append(r.Events, event)
+}
+
+func (r *RecordingEmitter) Names() []string {
+	names := make([]string, 0, len(r.Events))
+	for _, event := range r.Events {
+		names = append(names, event.EventName())
+	}
+	return names
+}
+
+func setupThreeWayDinner(t *testing.T, commitErr error) (service.ExpenseService, *RecordingEmitter, *models.CreateExpenseRequest) {
+	recorder := &RecordingEmitter{}
+	es, req := setupThreeWayDinnerWith(t, commitErr, recorder)
+	return es, recorder, req
+}
+
+// setupThreeWayDinnerWith prepares a 90 USD dinner split equally between
+// three members, reporting events to emitter
+func setupThreeWayDinnerWith(t *testing.T, commitErr error, emitter events.Emitter) (service.ExpenseService, *models.CreateExpenseRequest) {
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	users := []*models.User{
+		{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"},
+		{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"},
+		{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"},
+	}
+
+	req := &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  users[0].UUID,
+		Amount:      decimal.NewFromInt(90),
+		Currency:    "USD",
+		Description: "Dinner",
+		SplitType:   models.SplitTypeEqual,
+	}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	for _, user := range users {
+		req.Splits = append(req.Splits, models.CreateExpenseSplitRequest{UserUUID: user.UUID})
+		userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, user.ID).Return(true, nil)
+	}
+
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+	db.On("WithTransaction", mock.Anything, mock.Anything).Return(commitErr)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, emitter, metrics.Nop{}, zaptest.NewLogger(t))
+	return es, req
+}
+
+func TestEvents_MultiSplitExpenseEmitsExactSequence(t *testing.T) {
+	es, recorder, req := setupThreeWayDinner(t, nil)
+
+	_, err := es.CreateExpense(context.Background(), req)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"expense.created",
+		"balance.adjusted",
+		"balance.adjusted",
+		"balance.adjusted",
+		"balance.adjusted",
+	}, recorder.Names())
+
+	created := recorder.Events[0].(events.ExpenseCreated)
+	assert.Equal(t, "Dinner", created.Expense.Description)
+	assert.Len(t, created.Splits, 3)
+
+	expected := []struct {
+		userID int64
+		delta  int64
+	}{{1, 30}, {2, 30}, {3, 30}, {1, -90}}
+	for i, want := range expected {
+		adjusted := recorder.Events[i+1].(events.BalanceAdjusted)
+		assert.Equal(t, int64(10), adjusted.GroupID)
+		assert.Equal(t, want.userID, adjusted.UserID)
+		assert.True(t, adjusted.Delta.Equal(decimal.NewFromInt(want.delta)), "event %d delta %s", i+1, adjusted.Delta)
+	}
+}
+
+func TestEvents_NoEventsWhenTransactionFails(t *testing.T) {
+	es, recorder, req := setupThreeWayDinner(t, stderrors.New("commit failed"))
+
+	_, err := es.CreateExpense(context.Background(), req)
+	assert.Error(t, err)
+	assert.Empty(t, recorder.Events)
+}
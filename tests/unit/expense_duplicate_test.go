@@ -0,0 +1,121 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestExpenseService_DuplicateExpense(t *testing.T) {
+	ctx := context.Background()
+	originalUUID := "dddddddd-dddd-4ddd-8ddd-dddddddddddd"
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	// A refund of 90 shared 2:1, stored negated
+	expenseRepo := new(MockExpenseRepositoryES)
+	expenseRepo.On("GetByUUID", mock.Anything, originalUUID).Return(&models.Expense{
+		ID: 7, UUID: originalUUID, GroupID: group.ID, PaidBy: payer.ID, Amount: decimal.NewFromInt(-90), Currency: "EUR",
+		Description: "Groceries", Category: "food", SplitType: models.SplitTypeShares, IsRefund: true,
+		ReceiptURL: "https://receipts.example.com/old.jpg", Group: group, Payer: payer,
+	}, nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(7)).Return([]*models.ExpenseSplit{
+		{UserID: payer.ID, Amount: decimal.NewFromInt(-60), Shares: 2, User: payer},
+		{UserID: user2.ID, Amount: decimal.NewFromInt(-30), Shares: 1, User: user2},
+	}, nil)
+	expenseRepo.On("GetExpenseItems", mock.Anything, int64(7)).Return([]*models.ExpenseItem{}, nil)
+
+	var expense *models.Expense
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).
+		Run(func(args mock.Arguments) { expense = args.Get(2).(*models.Expense) }).Return(nil)
+	var created []*models.ExpenseSplit
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).
+		Run(func(args mock.Arguments) { created = append(created, args.Get(2).(*models.ExpenseSplit)) }).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	for _, u := range []*models.User{payer, user2} {
+		userRepo.On("GetByUUID", mock.Anything, u.UUID).Return(u, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, u.ID).Return(true, nil)
+	}
+	balanceRepo := new(MockBalanceRepositoryES)
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "EUR").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "EUR").Return(nil)
+	db := new(MockDBES)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	amount := decimal.NewFromInt(120)
+	_, err := es.DuplicateExpense(ctx, originalUUID, &models.DuplicateExpenseRequest{Amount: &amount, Description: "Groceries week 2"})
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, originalUUID, expense.UUID)
+	assert.Equal(t, "Groceries week 2", expense.Description)
+	assert.Equal(t, "food", expense.Category)
+	assert.Equal(t, "", expense.ReceiptURL)
+	assert.True(t, expense.IsRefund)
+	assert.True(t, expense.Amount.Equal(decimal.NewFromInt(-120)))
+	assert.Len(t, created, 2)
+	assert.Equal(t, 2, created[0].Shares)
+	assert.True(t, created[0].Amount.Equal(decimal.NewFromInt(-80)))
+	assert.True(t, created[1].Amount.Equal(decimal.NewFromInt(-40)))
+}
+
+func TestExpenseService_DuplicateExpense_Revalidates(t *testing.T) {
+	ctx := context.Background()
+	originalUUID := "dddddddd-dddd-4ddd-8ddd-dddddddddddd"
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	leaver := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	expenseRepo.On("GetByUUID", mock.Anything, originalUUID).Return(&models.Expense{
+		ID: 7, UUID: originalUUID, GroupID: group.ID, PaidBy: payer.ID, Amount: decimal.NewFromInt(50), Currency: "USD",
+		Description: "Taxi", SplitType: models.SplitTypeExact, Group: group, Payer: payer,
+	}, nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(7)).Return([]*models.ExpenseSplit{
+		{UserID: payer.ID, Amount: decimal.NewFromInt(20), User: payer},
+		{UserID: leaver.ID, Amount: decimal.NewFromInt(30), User: leaver},
+	}, nil)
+	expenseRepo.On("GetExpenseItems", mock.Anything, int64(7)).Return([]*models.ExpenseItem{}, nil)
+
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	userRepo.On("GetByUUID", mock.Anything, leaver.UUID).Return(leaver, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, leaver.ID).Return(false, nil)
+	db := new(MockDBES)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	// Exact amounts cannot be stretched to a new total
+	amount := decimal.NewFromInt(60)
+	_, err := es.DuplicateExpense(ctx, originalUUID, &models.DuplicateExpenseRequest{Amount: &amount})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidSplit, appErr.Code)
+
+	// A participant who left the group fails the normal membership check
+	_, err = es.DuplicateExpense(ctx, originalUUID, &models.DuplicateExpenseRequest{})
+	assert.Error(t, err)
+	expenseRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+}
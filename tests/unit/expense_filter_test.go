@@ -0,0 +1,182 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"expense-split-tracker/internal/controller"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestExpenseController_ListExpenses_AmountRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := new(MockExpenseServiceHandler)
+	svc.On("ListExpenses", mock.Anything, mock.MatchedBy(func(filter *models.ExpenseFilter) bool {
+		return filter.MinAmount.Equal(decimal.NewFromInt(100)) && filter.MaxAmount.Equal(decimal.RequireFromString("250.50"))
+	})).Return(&models.ExpenseListResponse{Expenses: []*models.Expense{}, Page: 1, Limit: 10}, nil)
+
+	ec := controller.NewExpenseController(svc, zaptest.NewLogger(t))
+	router := gin.New()
+	router.GET("/api/v1/expenses", ec.ListExpenses)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/expenses?min_amount=100&max_amount=250.50", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	svc.AssertExpectations(t)
+}
+
+func TestExpenseController_ListExpenses_RejectsNonNumericAmount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := new(MockExpenseServiceHandler)
+	ec := controller.NewExpenseController(svc, zaptest.NewLogger(t))
+	router := gin.New()
+	router.GET("/api/v1/expenses", ec.ListExpenses)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/expenses?min_amount=lots", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp response.APIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, errors.ErrCodeInvalid, resp.Error.Code)
+	svc.AssertNotCalled(t, "ListExpenses", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_ListExpenses_MinAboveMax(t *testing.T) {
+	expenseRepo := new(MockExpenseRepositoryES)
+	es := service.NewExpenseService(expenseRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES),
+		newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	result, err := es.ListExpenses(context.Background(), &models.ExpenseFilter{
+		MinAmount: decimal.NewFromInt(200),
+		MaxAmount: decimal.NewFromInt(100),
+		Page:      1,
+		Limit:     10,
+	})
+	assert.Nil(t, result)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+	expenseRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestExpenseController_ListExpenses_Sort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := new(MockExpenseServiceHandler)
+	svc.On("ListExpenses", mock.Anything, mock.MatchedBy(func(filter *models.ExpenseFilter) bool {
+		return filter.SortBy == "amount" && filter.SortDir == models.SortAsc
+	})).Return(&models.ExpenseListResponse{Expenses: []*models.Expense{}, Page: 1, Limit: 10}, nil)
+
+	ec := controller.NewExpenseController(svc, zaptest.NewLogger(t))
+	router := gin.New()
+	router.GET("/api/v1/expenses", ec.ListExpenses)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/expenses?sort_by=amount&sort_dir=ASC", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	svc.AssertExpectations(t)
+}
+
+func TestListControllers_RejectInvalidSort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := zaptest.NewLogger(t)
+	svc := new(MockExpenseServiceHandler)
+
+	router := gin.New()
+	router.GET("/api/v1/expenses", controller.NewExpenseController(svc, logger).ListExpenses)
+	router.GET("/api/v1/settlements", controller.NewSettlementController(nil, logger).ListSettlements)
+
+	tests := []struct {
+		url   string
+		field string
+	}{
+		{url: "/api/v1/expenses?sort_by=amount DESC, (SELECT 1)", field: "sort_by"},
+		{url: "/api/v1/expenses?sort_by=amount&sort_dir=sideways", field: "sort_dir"},
+		{url: "/api/v1/settlements?sort_by=expense_date", field: "sort_by"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, strings.ReplaceAll(tt.url, " ", "%20"), nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, tt.url)
+
+		var resp response.APIResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, errors.ErrCodeInvalid, resp.Error.Code)
+		assert.Equal(t, tt.field, resp.Error.Details["field"])
+	}
+	svc.AssertNotCalled(t, "ListExpenses", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_ListExpenses_SearchQuery(t *testing.T) {
+	newService := func(expenseRepo *MockExpenseRepositoryES) service.ExpenseService {
+		return service.NewExpenseService(expenseRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES),
+			newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+	}
+
+	t.Run("too short", func(t *testing.T) {
+		expenseRepo := new(MockExpenseRepositoryES)
+		_, err := newService(expenseRepo).ListExpenses(context.Background(), &models.ExpenseFilter{Query: " l ", Page: 1, Limit: 10})
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+		expenseRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+
+	t.Run("composes with other filters", func(t *testing.T) {
+		expenseRepo := new(MockExpenseRepositoryES)
+		expenseRepo.On("List", mock.Anything, mock.MatchedBy(func(filter *models.ExpenseFilter) bool {
+			return filter.Query == "luigi's" && filter.Currency == "EUR" && filter.GroupUUID != ""
+		})).Return([]*models.Expense{}, 0, nil)
+
+		_, err := newService(expenseRepo).ListExpenses(context.Background(), &models.ExpenseFilter{
+			Query:              "  luigi's ",
+			Currency:           "EUR",
+			GroupUUID:          "11111111-1111-4111-8111-111111111111",
+			Page:               1,
+			Limit:              10,
+			ExpenseListOptions: models.ExpenseListOptions{Include: models.SplitIncludeNone},
+		})
+		assert.NoError(t, err)
+		expenseRepo.AssertExpectations(t)
+	})
+}
+
+func TestContainsPattern_EscapesLikeWildcards(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"dinner", "%dinner%"},
+		{"50%", "%50!%%"},
+		{"a_b", "%a!_b%"},
+		{"wow!", "%wow!!%"},
+		{`back\slash`, `%back\slash%`},
+		{"100%_off!", "%100!%!_off!!%"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, utils.ContainsPattern(tt.term), tt.term)
+	}
+}
+
+func TestPrefixPattern_EscapesLikeWildcards(t *testing.T) {
+	assert.Equal(t, "pri%", utils.PrefixPattern("pri"))
+	assert.Equal(t, "a!_b!%%", utils.PrefixPattern("a_b%"))
+}
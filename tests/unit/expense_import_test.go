@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+const importCSV = `date,description,amount,currency,paid_by_email,split_type,participants
+2024-03-01,Dinner,90,USD,alice@example.com,equal,
+2024-03-02,Taxi,30,USD,mallory@example.com,equal,
+2024-03-03,Museum,abc,USD,alice@example.com,equal,
+2024-03-04,Hotel,100,USD,alice@example.com,exact,alice@example.com:60;bob@example.com:40
+`
+
+func setupExpenseImport(t *testing.T) (*MockExpenseRepositoryES, *MockDBES, service.ExpenseService, *models.Group) {
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Email: "alice@example.com"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Email: "bob@example.com"}
+	members := []*models.User{alice, bob}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("GetMembers", mock.Anything, group.ID).Return(members, nil)
+	for _, u := range members {
+		userRepo.On("GetByEmail", mock.Anything, u.Email).Return(u, nil)
+		userRepo.On("GetByUUID", mock.Anything, u.UUID).Return(u, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, u.ID).Return(true, nil)
+	}
+	userRepo.On("GetByEmail", mock.Anything, "mallory@example.com").Return(nil, errors.NewNotFoundError("User"))
+
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil)
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+	return expenseRepo, db, es, group
+}
+
+func TestExpenseService_ImportExpenses_SkipsInvalidRows(t *testing.T) {
+	expenseRepo, db, es, group := setupExpenseImport(t)
+
+	result, err := es.ImportExpenses(context.Background(), group.UUID, strings.NewReader(importCSV), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, result.TotalRows)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 2, result.Skipped)
+	if assert.Len(t, result.Errors, 2) {
+		assert.Equal(t, 3, result.Errors[0].Row)
+		assert.Equal(t, errors.ErrCodeNotFound, result.Errors[0].Code)
+		assert.Equal(t, 4, result.Errors[1].Row)
+		assert.Equal(t, errors.ErrCodeInvalid, result.Errors[1].Code)
+	}
+
+	db.AssertNumberOfCalls(t, "WithTransaction", 1)
+	expenseRepo.AssertNumberOfCalls(t, "Create", 2)
+	expenseRepo.AssertNumberOfCalls(t, "CreateSplit", 4)
+	expenseRepo.AssertCalled(t, "Create", mock.Anything, mock.Anything, mock.MatchedBy(func(e *models.Expense) bool {
+		return e.Description == "Hotel" && e.Amount.Equal(decimal.NewFromInt(100))
+	}))
+}
+
+func TestExpenseService_ImportExpenses_DryRunWritesNothing(t *testing.T) {
+	expenseRepo, db, es, group := setupExpenseImport(t)
+
+	result, err := es.ImportExpenses(context.Background(), group.UUID, strings.NewReader(importCSV), true)
+	assert.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 2, result.Valid)
+	assert.Equal(t, 0, result.Imported)
+	assert.Len(t, result.Errors, 2)
+
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+	expenseRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_ImportExpenses_MissingColumns(t *testing.T) {
+	_, _, es, group := setupExpenseImport(t)
+
+	_, err := es.ImportExpenses(context.Background(), group.UUID, strings.NewReader("date,description,amount\n"), false)
+	if assert.Error(t, err) {
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+		assert.Contains(t, appErr.Details["missing"], "paid_by_email")
+	}
+}
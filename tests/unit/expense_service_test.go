@@ -2,11 +2,19 @@ package unit
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"expense-split-tracker/internal/auth"
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -43,21 +51,111 @@ func (m *MockExpenseRepositoryES) GetByID(ctx context.Context, id int64) (*model
 	return args.Get(0).(*models.Expense), args.Error(1)
 }
 
+func (m *MockExpenseRepositoryES) GetByUUID(ctx context.Context, uuid string) (*models.Expense, error) {
+	args := m.Called(ctx, uuid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Expense), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) GetByUUIDIncludingDeleted(ctx context.Context, uuid string) (*models.Expense, error) {
+	args := m.Called(ctx, uuid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Expense), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) GetByIDForUpdate(ctx context.Context, tx *database.Tx, id int64) (*models.Expense, error) {
+	args := m.Called(ctx, tx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Expense), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) Update(ctx context.Context, tx *database.Tx, expense *models.Expense) error {
+	args := m.Called(ctx, tx, expense)
+	return args.Error(0)
+}
+
+func (m *MockExpenseRepositoryES) UpdateReceiptURL(ctx context.Context, tx *database.Tx, id int64, receiptURL string) error {
+	args := m.Called(ctx, tx, id, receiptURL)
+	return args.Error(0)
+}
+
+func (m *MockExpenseRepositoryES) Delete(ctx context.Context, tx *database.Tx, id int64, deletedBy *int64) (bool, error) {
+	args := m.Called(ctx, tx, id, deletedBy)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockExpenseRepositoryES) List(ctx context.Context, filter *models.ExpenseFilter) ([]*models.Expense, int, error) {
 	args := m.Called(ctx, filter)
 	return args.Get(0).([]*models.Expense), args.Int(1), args.Error(2)
 }
 
-func (m *MockExpenseRepositoryES) GetGroupExpenses(ctx context.Context, groupID int64, offset, limit int) ([]*models.Expense, error) {
-	args := m.Called(ctx, groupID, offset, limit)
+func (m *MockExpenseRepositoryES) GetGroupExpenses(ctx context.Context, groupID int64, offset, limit int, includeDeleted bool) ([]*models.Expense, error) {
+	args := m.Called(ctx, groupID, offset, limit, includeDeleted)
+	return args.Get(0).([]*models.Expense), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) GetUserExpenses(ctx context.Context, userID int64, offset, limit int, includeDeleted bool) ([]*models.Expense, error) {
+	args := m.Called(ctx, userID, offset, limit, includeDeleted)
 	return args.Get(0).([]*models.Expense), args.Error(1)
 }
 
-func (m *MockExpenseRepositoryES) GetUserExpenses(ctx context.Context, userID int64, offset, limit int) ([]*models.Expense, error) {
-	args := m.Called(ctx, userID, offset, limit)
+func (m *MockExpenseRepositoryES) GetGroupCurrencyTotals(ctx context.Context, groupID int64) ([]*models.ExpenseCurrencyTotal, error) {
+	args := m.Called(ctx, groupID)
+	return args.Get(0).([]*models.ExpenseCurrencyTotal), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) CountGroupExpenses(ctx context.Context, groupID int64, includeDeleted bool) (int, error) {
+	args := m.Called(ctx, groupID, includeDeleted)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) GetTopGroupExpenses(ctx context.Context, groupID int64, from, to time.Time, limit int, includeDeleted bool) ([]*models.Expense, error) {
+	args := m.Called(ctx, groupID, from, to, limit, includeDeleted)
 	return args.Get(0).([]*models.Expense), args.Error(1)
 }
 
+func (m *MockExpenseRepositoryES) CountUserExpenses(ctx context.Context, userID int64, includeDeleted bool) (int, error) {
+	args := m.Called(ctx, userID, includeDeleted)
+	return args.Int(0), args.Error(1)
+}
+
+// IterateGroupExpenses hands each configured batch to fn in order
+func (m *MockExpenseRepositoryES) IterateGroupExpenses(ctx context.Context, groupID int64, from, to time.Time, batchSize int, fn func([]*models.Expense) error) error {
+	args := m.Called(ctx, groupID, from, to, batchSize)
+	for _, batch := range args.Get(0).([][]*models.Expense) {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) CreateItem(ctx context.Context, tx *database.Tx, item *models.ExpenseItem) error {
+	args := m.Called(ctx, tx, item)
+	return args.Error(0)
+}
+
+func (m *MockExpenseRepositoryES) GetExpenseItems(ctx context.Context, expenseID int64) ([]*models.ExpenseItem, error) {
+	args := m.Called(ctx, expenseID)
+	return args.Get(0).([]*models.ExpenseItem), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) DeleteExpenseItems(ctx context.Context, tx *database.Tx, expenseID int64) error {
+	args := m.Called(ctx, tx, expenseID)
+	return args.Error(0)
+}
+
+func (m *MockExpenseRepositoryES) GetGroupSplitUsers(ctx context.Context, groupID int64) ([]*models.User, error) {
+	args := m.Called(ctx, groupID)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
 func (m *MockExpenseRepositoryES) CreateSplit(ctx context.Context, tx *database.Tx, split *models.ExpenseSplit) error {
 	args := m.Called(ctx, tx, split)
 	return args.Error(0)
@@ -68,6 +166,21 @@ func (m *MockExpenseRepositoryES) GetExpenseSplits(ctx context.Context, expenseI
 	return args.Get(0).([]*models.ExpenseSplit), args.Error(1)
 }
 
+func (m *MockExpenseRepositoryES) GetExpenseSplitsForUpdate(ctx context.Context, tx *database.Tx, expenseID int64) ([]*models.ExpenseSplit, error) {
+	args := m.Called(ctx, tx, expenseID)
+	return args.Get(0).([]*models.ExpenseSplit), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) GetSplitsForExpenses(ctx context.Context, expenseIDs []int64) (map[int64][]*models.ExpenseSplit, error) {
+	args := m.Called(ctx, expenseIDs)
+	return args.Get(0).(map[int64][]*models.ExpenseSplit), args.Error(1)
+}
+
+func (m *MockExpenseRepositoryES) DeleteExpenseSplits(ctx context.Context, tx *database.Tx, expenseID int64, deletedBy *int64) error {
+	args := m.Called(ctx, tx, expenseID, deletedBy)
+	return args.Error(0)
+}
+
 func (m *MockExpenseRepositoryES) UpdateSplit(ctx context.Context, tx *database.Tx, split *models.ExpenseSplit) error {
 	args := m.Called(ctx, tx, split)
 	return args.Error(0)
@@ -94,18 +207,43 @@ func (m *MockGroupRepositoryES) GetByUUID(ctx context.Context, uuid string) (*mo
 	return args.Get(0).(*models.Group), args.Error(1)
 }
 
-func (m *MockGroupRepositoryES) List(ctx context.Context, offset, limit int) ([]*models.Group, error) {
-	args := m.Called(ctx, offset, limit)
+func (m *MockGroupRepositoryES) Update(ctx context.Context, tx *database.Tx, group *models.Group) error {
+	args := m.Called(ctx, tx, group)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepositoryES) List(ctx context.Context, offset, limit int, includeArchived bool) ([]*models.Group, error) {
+	args := m.Called(ctx, offset, limit, includeArchived)
 	return args.Get(0).([]*models.Group), args.Error(1)
 }
 
-func (m *MockGroupRepositoryES) GetUserGroups(ctx context.Context, userID int64, offset, limit int) ([]*models.Group, error) {
-	args := m.Called(ctx, userID, offset, limit)
+func (m *MockGroupRepositoryES) GetUserGroups(ctx context.Context, userID int64, offset, limit int, includeArchived bool) ([]*models.Group, error) {
+	args := m.Called(ctx, userID, offset, limit, includeArchived)
 	return args.Get(0).([]*models.Group), args.Error(1)
 }
 
-func (m *MockGroupRepositoryES) AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
-	args := m.Called(ctx, tx, groupID, userID)
+func (m *MockGroupRepositoryES) Count(ctx context.Context, includeArchived bool) (int, error) {
+	args := m.Called(ctx, includeArchived)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockGroupRepositoryES) CountUserGroups(ctx context.Context, userID int64, includeArchived bool) (int, error) {
+	args := m.Called(ctx, userID, includeArchived)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockGroupRepositoryES) Archive(ctx context.Context, tx *database.Tx, id int64) (bool, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockGroupRepositoryES) Unarchive(ctx context.Context, tx *database.Tx, id int64) (bool, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockGroupRepositoryES) AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error {
+	args := m.Called(ctx, tx, groupID, userID, role)
 	return args.Error(0)
 }
 
@@ -119,6 +257,55 @@ func (m *MockGroupRepositoryES) GetMembers(ctx context.Context, groupID int64) (
 	return args.Get(0).([]*models.User), args.Error(1)
 }
 
+func (m *MockGroupRepositoryES) GetMemberships(ctx context.Context, groupID int64) ([]*models.GroupMember, error) {
+	args := m.Called(ctx, groupID)
+	return args.Get(0).([]*models.GroupMember), args.Error(1)
+}
+
+// AreMembers resolves each user through IsMember so tests can keep stubbing
+// single membership checks
+func (m *MockGroupRepositoryES) AreMembers(ctx context.Context, groupID int64, userIDs []int64) (map[int64]bool, error) {
+	members := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		isMember, err := m.IsMember(ctx, groupID, id)
+		if err != nil {
+			return nil, err
+		}
+		members[id] = isMember
+	}
+	return members, nil
+}
+
+func (m *MockGroupRepositoryES) GetMemberRole(ctx context.Context, groupID, userID int64) (models.GroupRole, error) {
+	args := m.Called(ctx, groupID, userID)
+	return args.Get(0).(models.GroupRole), args.Error(1)
+}
+
+func (m *MockGroupRepositoryES) SetOwner(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
+	args := m.Called(ctx, tx, groupID, userID)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepositoryES) SetMemberRole(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error {
+	args := m.Called(ctx, tx, groupID, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockGroupRepositoryES) CountAdminsForUpdate(ctx context.Context, tx *database.Tx, groupID int64) (int, error) {
+	args := m.Called(ctx, tx, groupID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockGroupRepositoryES) GetSharedGroups(ctx context.Context, userID, otherUserID int64) ([]*models.Group, error) {
+	args := m.Called(ctx, userID, otherUserID)
+	return args.Get(0).([]*models.Group), args.Error(1)
+}
+
+func (m *MockGroupRepositoryES) CountMembers(ctx context.Context, groupID int64) (int, error) {
+	args := m.Called(ctx, groupID)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockGroupRepositoryES) IsMember(ctx context.Context, groupID, userID int64) (bool, error) {
 	args := m.Called(ctx, groupID, userID)
 	return args.Bool(0), args.Error(1)
@@ -145,6 +332,23 @@ func (m *MockUserRepositoryES) GetByUUID(ctx context.Context, uuid string) (*mod
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+// GetByUUIDs resolves each UUID through GetByUUID so tests can keep stubbing
+// single lookups; unknown UUIDs are skipped like the real repository does
+func (m *MockUserRepositoryES) GetByUUIDs(ctx context.Context, uuids []string) ([]*models.User, error) {
+	users := []*models.User{}
+	for _, uuid := range uuids {
+		user, err := m.GetByUUID(ctx, uuid)
+		if err != nil {
+			if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrCodeNotFound {
+				continue
+			}
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
 func (m *MockUserRepositoryES) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
@@ -158,6 +362,21 @@ func (m *MockUserRepositoryES) List(ctx context.Context, offset, limit int) ([]*
 	return args.Get(0).([]*models.User), args.Error(1)
 }
 
+func (m *MockUserRepositoryES) Search(ctx context.Context, query string, offset, limit int) ([]*models.User, error) {
+	args := m.Called(ctx, query, offset, limit)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepositoryES) CountSearch(ctx context.Context, query string) (int, error) {
+	args := m.Called(ctx, query)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepositoryES) Count(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockBalanceRepositoryES) Upsert(ctx context.Context, tx *database.Tx, balance *models.Balance) error {
 	args := m.Called(ctx, tx, balance)
 	return args.Error(0)
@@ -171,11 +390,29 @@ func (m *MockBalanceRepositoryES) GetByGroupAndUser(ctx context.Context, groupID
 	return args.Get(0).(*models.Balance), args.Error(1)
 }
 
+func (m *MockBalanceRepositoryES) GetByGroupAndUserForUpdate(ctx context.Context, tx *database.Tx, groupID, userID int64, currency string) (*models.Balance, error) {
+	args := m.Called(ctx, tx, groupID, userID, currency)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Balance), args.Error(1)
+}
+
 func (m *MockBalanceRepositoryES) GetGroupBalances(ctx context.Context, groupID int64, currency string) ([]*models.Balance, error) {
 	args := m.Called(ctx, groupID, currency)
 	return args.Get(0).([]*models.Balance), args.Error(1)
 }
 
+func (m *MockBalanceRepositoryES) GetGroupBalancesAllCurrencies(ctx context.Context, groupID int64) ([]*models.Balance, error) {
+	args := m.Called(ctx, groupID)
+	return args.Get(0).([]*models.Balance), args.Error(1)
+}
+
+func (m *MockBalanceRepositoryES) GetGroupBalancesForUpdate(ctx context.Context, tx *database.Tx, groupID int64, currency string) ([]*models.Balance, error) {
+	args := m.Called(ctx, tx, groupID, currency)
+	return args.Get(0).([]*models.Balance), args.Error(1)
+}
+
 func (m *MockBalanceRepositoryES) GetUserBalances(ctx context.Context, userID int64) ([]*models.Balance, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).([]*models.Balance), args.Error(1)
@@ -186,8 +423,57 @@ func (m *MockBalanceRepositoryES) UpdateBalance(ctx context.Context, tx *databas
 	return args.Error(0)
 }
 
-func (m *MockDBES) WithTransaction(fn func(tx *database.Tx) error) error {
-	args := m.Called(fn)
+func (m *MockBalanceRepositoryES) UpdateDebt(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, amount decimal.Decimal, currency string) error {
+	args := m.Called(ctx, tx, groupID, debtorID, creditorID, amount, currency)
+	return args.Error(0)
+}
+
+func (m *MockBalanceRepositoryES) GetDebtForUpdate(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, currency string) (decimal.Decimal, error) {
+	return decimal.Zero, nil
+}
+
+func (m *MockBalanceRepositoryES) GetGroupDebts(ctx context.Context, groupID int64, currency string) ([]*models.DebtRelationship, error) {
+	return nil, nil
+}
+
+func (m *MockBalanceRepositoryES) GetGroupDebtsAllCurrencies(ctx context.Context, groupID int64) ([]*models.DebtRelationship, error) {
+	return nil, nil
+}
+
+func (m *MockBalanceRepositoryES) GetPairDebts(ctx context.Context, groupID, userID, otherUserID int64) ([]*models.PairDebt, error) {
+	return nil, nil
+}
+
+func (m *MockBalanceRepositoryES) ClearGroupDebts(ctx context.Context, tx *database.Tx, groupID int64, currency string) error {
+	return nil
+}
+
+func (m *MockBalanceRepositoryES) ZeroGroupBalances(ctx context.Context, tx *database.Tx, groupID int64) error {
+	return nil
+}
+
+func (m *MockBalanceRepositoryES) ZeroGroupDebts(ctx context.Context, tx *database.Tx, groupID int64) error {
+	return nil
+}
+
+func (m *MockBalanceRepositoryES) GetUserExpenseTotals(ctx context.Context, groupID, userID int64, currency string) (*models.ExpenseTotals, error) {
+	return &models.ExpenseTotals{}, nil
+}
+
+func (m *MockBalanceRepositoryES) GetUserSettlementTotals(ctx context.Context, groupID, userID int64, currency string) (*models.SettlementTotals, error) {
+	return &models.SettlementTotals{}, nil
+}
+
+func (m *MockBalanceRepositoryES) GetUserExpenseTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.ExpenseTotals, error) {
+	return nil, nil
+}
+
+func (m *MockBalanceRepositoryES) GetUserSettlementTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.SettlementTotals, error) {
+	return nil, nil
+}
+
+func (m *MockDBES) WithTransaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	args := m.Called(ctx, fn)
 	if err := fn(nil); err != nil {
 		return err
 	}
@@ -204,10 +490,10 @@ func TestExpenseService_CreateExpense_EqualSplit(t *testing.T) {
 	balanceRepo := new(MockBalanceRepositoryES)
 	db := new(MockDBES)
 
-	group := &models.Group{ID: 10, UUID: "11111111-1111-1111-1111-111111111111", Name: "Trip"}
-	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa", Name: "Alice"}
-	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb", Name: "Bob"}
-	user3 := &models.User{ID: 3, UUID: "cccccccc-cccc-cccc-cccc-cccccccccccc", Name: "Carol"}
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	user3 := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"}
 
 	req := &models.CreateExpenseRequest{
 		GroupUUID:   group.UUID,
@@ -243,10 +529,11 @@ func TestExpenseService_CreateExpense_EqualSplit(t *testing.T) {
 	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, payer.ID, mock.Anything, "USD").Return(nil)
 	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, user2.ID, mock.Anything, "USD").Return(nil)
 	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, user3.ID, mock.Anything, "USD").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
 
-	db.On("WithTransaction", mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
 
-	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, db, logger)
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
 
 	expense, err := es.CreateExpense(ctx, req)
 	assert.NoError(t, err)
@@ -254,6 +541,11 @@ func TestExpenseService_CreateExpense_EqualSplit(t *testing.T) {
 	assert.Equal(t, models.SplitTypeEqual, expense.SplitType)
 	assert.Equal(t, "USD", expense.Currency)
 	assert.Equal(t, 3, len(expense.Splits))
+
+	// Each participant owes the payer their own share; the payer owes nobody
+	balanceRepo.AssertCalled(t, "UpdateDebt", mock.Anything, mock.Anything, group.ID, user2.ID, payer.ID, mock.Anything, "USD")
+	balanceRepo.AssertCalled(t, "UpdateDebt", mock.Anything, mock.Anything, group.ID, user3.ID, payer.ID, mock.Anything, "USD")
+	balanceRepo.AssertNumberOfCalls(t, "UpdateDebt", 2)
 }
 
 func TestExpenseService_CreateExpense_ExactSplit_SumMismatch(t *testing.T) {
@@ -266,9 +558,9 @@ func TestExpenseService_CreateExpense_ExactSplit_SumMismatch(t *testing.T) {
 	balanceRepo := new(MockBalanceRepositoryES)
 	db := new(MockDBES)
 
-	group := &models.Group{ID: 10, UUID: "11111111-1111-1111-1111-111111111111", Name: "Trip"}
-	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa", Name: "Alice"}
-	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb", Name: "Bob"}
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
 
 	req := &models.CreateExpenseRequest{
 		GroupUUID:   group.UUID,
@@ -289,7 +581,7 @@ func TestExpenseService_CreateExpense_ExactSplit_SumMismatch(t *testing.T) {
 	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
 	groupRepo.On("IsMember", mock.Anything, group.ID, user2.ID).Return(true, nil)
 
-	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, db, logger)
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
 
 	expense, err := es.CreateExpense(ctx, req)
 	assert.Error(t, err)
@@ -297,6 +589,84 @@ func TestExpenseService_CreateExpense_ExactSplit_SumMismatch(t *testing.T) {
 	assert.Contains(t, err.Error(), "Sum of split amounts must equal")
 }
 
+func TestExpenseService_CreateExpense_PrecisionFollowsGroupCurrency(t *testing.T) {
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Tokyo", DefaultCurrency: "JPY"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+
+	newService := func() service.ExpenseService {
+		groupRepo := new(MockGroupRepositoryES)
+		userRepo := new(MockUserRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+		userRepo.On("GetByUUID", mock.Anything, user2.UUID).Return(user2, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+		return service.NewExpenseService(new(MockExpenseRepositoryES), groupRepo, userRepo, new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+	}
+
+	tests := []struct {
+		name   string
+		amount string
+		splits []string
+		field  string
+	}{
+		// 1500.50 is fine in USD but not in the group's default currency
+		{name: "expense amount", amount: "1500.50", splits: []string{"1000.25", "500.25"}, field: "amount"},
+		{name: "exact split amount", amount: "1500", splits: []string{"1000.5", "499.5"}, field: "splits.amount"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expense, err := newService().CreateExpense(context.Background(), &models.CreateExpenseRequest{
+				GroupUUID:   group.UUID,
+				PaidByUUID:  payer.UUID,
+				Amount:      decimal.RequireFromString(tt.amount),
+				Description: "Ramen",
+				SplitType:   models.SplitTypeExact,
+				Splits: []models.CreateExpenseSplitRequest{
+					{UserUUID: payer.UUID, Amount: decimal.RequireFromString(tt.splits[0])},
+					{UserUUID: user2.UUID, Amount: decimal.RequireFromString(tt.splits[1])},
+				},
+			})
+			assert.Nil(t, expense)
+			appErr, ok := err.(*errors.AppError)
+			if assert.True(t, ok) {
+				assert.Equal(t, "JPY amounts can have at most 0 decimal places", appErr.Message)
+				assert.Equal(t, tt.field, appErr.Details["field"])
+			}
+		})
+	}
+}
+
+func TestExpenseService_CreateExpense_ForeignCurrencyWithoutRate(t *testing.T) {
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip", DefaultCurrency: "USD"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+
+	// No rate provider is configured and the request names no rate
+	es := service.NewExpenseService(new(MockExpenseRepositoryES), groupRepo, userRepo, new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	expense, err := es.CreateExpense(context.Background(), &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(100),
+		Currency:    "EUR",
+		Description: "Museum",
+		SplitType:   models.SplitTypeEqual,
+	})
+	assert.Nil(t, expense)
+	appErr, ok := err.(*errors.AppError)
+	if assert.True(t, ok) {
+		assert.Equal(t, errors.ErrCodeRateUnavailable, appErr.Code)
+		assert.Equal(t, "EUR", appErr.Details["from"])
+		assert.Equal(t, "USD", appErr.Details["to"])
+	}
+	userRepo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+}
+
 func TestExpenseService_CreateExpense_Percentage_SumTo100(t *testing.T) {
 	ctx := context.Background()
 	logger := zaptest.NewLogger(t)
@@ -307,9 +677,9 @@ func TestExpenseService_CreateExpense_Percentage_SumTo100(t *testing.T) {
 	balanceRepo := new(MockBalanceRepositoryES)
 	db := new(MockDBES)
 
-	group := &models.Group{ID: 10, UUID: "11111111-1111-1111-1111-111111111111"}
-	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}
-	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"}
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
 
 	req := &models.CreateExpenseRequest{
 		GroupUUID:   group.UUID,
@@ -340,10 +710,11 @@ func TestExpenseService_CreateExpense_Percentage_SumTo100(t *testing.T) {
 	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, payer.ID, decimal.NewFromInt(200).Neg(), "USD").Return(nil)
 	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, payer.ID, mock.Anything, "USD").Return(nil)
 	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, user2.ID, mock.Anything, "USD").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
 
-	db.On("WithTransaction", mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
 
-	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, db, logger)
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
 
 	expense, err := es.CreateExpense(ctx, req)
 	assert.NoError(t, err)
@@ -352,23 +723,1488 @@ func TestExpenseService_CreateExpense_Percentage_SumTo100(t *testing.T) {
 	assert.Equal(t, 2, len(expense.Splits))
 }
 
-func TestExpenseService_CreateExpense_InvalidUUID(t *testing.T) {
+func TestExpenseService_CreateExpense_Percentage_RoundingDriftReconciles(t *testing.T) {
 	ctx := context.Background()
 	logger := zaptest.NewLogger(t)
 
-	es := service.NewExpenseService(new(MockExpenseRepositoryES), new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES), new(MockDBES), logger)
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	users := []*models.User{
+		{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"},
+		{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"},
+		{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"},
+	}
 
-	req := &models.CreateExpenseRequest{
-		GroupUUID:   "invalid",
-		PaidByUUID:  "also-invalid",
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	for _, u := range users {
+		userRepo.On("GetByUUID", mock.Anything, u.UUID).Return(u, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, u.ID).Return(true, nil)
+	}
+
+	var created []*models.ExpenseSplit
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).
+		Run(func(args mock.Arguments) { created = append(created, args.Get(2).(*models.ExpenseSplit)) }).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+	ledger := balanceLedger{}
+	ledger.track(balanceRepo)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  users[0].UUID,
 		Amount:      decimal.NewFromInt(10),
-		Description: "x",
-		SplitType:   models.SplitTypeEqual,
-		Splits:      []models.CreateExpenseSplitRequest{{UserUUID: "invalid"}},
+		Currency:    "USD",
+		Description: "Snacks",
+		SplitType:   models.SplitTypePercentage,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: users[0].UUID, Percentage: decimal.RequireFromString("33.33")},
+			{UserUUID: users[1].UUID, Percentage: decimal.RequireFromString("33.33")},
+			{UserUUID: users[2].UUID, Percentage: decimal.RequireFromString("33.34")},
+		},
+	})
+	assert.NoError(t, err)
+
+	total := decimal.Zero
+	for _, split := range created {
+		total = total.Add(split.Amount)
+	}
+	assert.True(t, total.Equal(decimal.NewFromInt(10)), "splits sum to %s", total)
+	assert.True(t, created[0].Amount.Equal(decimal.RequireFromString("3.33")))
+	assert.True(t, created[1].Amount.Equal(decimal.RequireFromString("3.33")))
+	assert.True(t, created[2].Amount.Equal(decimal.RequireFromString("3.34")))
+
+	// The balance sheet nets to zero
+	net := decimal.Zero
+	for _, balance := range ledger {
+		net = net.Add(balance)
 	}
+	assert.True(t, net.IsZero(), "balances net to %s", net)
+}
 
-	res, err := es.CreateExpense(ctx, req)
-	assert.Error(t, err)
-	assert.Nil(t, res)
-	assert.Contains(t, err.Error(), "Invalid value")
+func TestExpenseService_CreateExpense_SharesSplit(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	user3 := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	req := &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(1000),
+		Currency:    "USD",
+		Description: "Rent",
+		SplitType:   models.SplitTypeShares,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: payer.UUID, Shares: 2},
+			{UserUUID: user2.UUID, Shares: 1},
+			{UserUUID: user3.UUID, Shares: 1},
+		},
+	}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	for _, u := range []*models.User{payer, user2, user3} {
+		userRepo.On("GetByUUID", mock.Anything, u.UUID).Return(u, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, u.ID).Return(true, nil)
+	}
+
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	var created []*models.ExpenseSplit
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).
+		Run(func(args mock.Arguments) { created = append(created, args.Get(2).(*models.ExpenseSplit)) }).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	_, err := es.CreateExpense(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(created))
+	assert.True(t, created[0].Amount.Equal(decimal.NewFromInt(500)))
+	assert.True(t, created[1].Amount.Equal(decimal.NewFromInt(250)))
+	assert.True(t, created[2].Amount.Equal(decimal.NewFromInt(250)))
+	assert.Equal(t, 2, created[0].Shares)
+
+	// Uneven weights still sum to the total
+	req.Amount = decimal.NewFromInt(100)
+	req.Splits[0].Shares = 1
+	created = nil
+	_, err = es.CreateExpense(ctx, req)
+	assert.NoError(t, err)
+	total := decimal.Zero
+	for _, split := range created {
+		total = total.Add(split.Amount)
+	}
+	assert.True(t, total.Equal(req.Amount))
+	assert.True(t, created[0].Amount.Equal(decimal.RequireFromString("33.34")))
+	assert.True(t, created[1].Amount.Equal(decimal.RequireFromString("33.33")))
+	assert.True(t, created[2].Amount.Equal(decimal.RequireFromString("33.33")))
+}
+
+func TestExpenseService_CreateExpense_SharesSplit_RejectsNonPositiveShares(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	for _, shares := range []int{0, -1} {
+		_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+			GroupUUID:   group.UUID,
+			PaidByUUID:  payer.UUID,
+			Amount:      decimal.NewFromInt(100),
+			Currency:    "USD",
+			Description: "Rent",
+			SplitType:   models.SplitTypeShares,
+			Splits: []models.CreateExpenseSplitRequest{
+				{UserUUID: payer.UUID, Shares: shares},
+			},
+		})
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeInvalidSplit, appErr.Code)
+	}
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_CreateExpense_EqualSplitDefaultsToAllMembers(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	user3 := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+	members := []*models.User{payer, user2, user3}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("GetMembers", mock.Anything, group.ID).Return(members, nil)
+	for _, u := range members {
+		userRepo.On("GetByUUID", mock.Anything, u.UUID).Return(u, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, u.ID).Return(true, nil)
+	}
+
+	var created []*models.ExpenseSplit
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).
+		Run(func(args mock.Arguments) { created = append(created, args.Get(2).(*models.ExpenseSplit)) }).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{{}, {}, {}}, nil)
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	expense, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(90),
+		Currency:    "USD",
+		Description: "Groceries",
+		SplitType:   models.SplitTypeEqual,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(expense.Splits))
+	assert.Equal(t, 3, len(created))
+	for i, split := range created {
+		assert.Equal(t, members[i].ID, split.UserID)
+		assert.True(t, split.Amount.Equal(decimal.NewFromInt(30)))
+	}
+}
+
+func TestExpenseService_CreateExpense_ExactSplitRequiresSplits(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	for _, splitType := range []models.SplitType{models.SplitTypeExact, models.SplitTypePercentage} {
+		_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+			GroupUUID:   group.UUID,
+			PaidByUUID:  payer.UUID,
+			Amount:      decimal.NewFromInt(90),
+			Currency:    "USD",
+			Description: "Groceries",
+			SplitType:   splitType,
+		})
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+	}
+	groupRepo.AssertNotCalled(t, "GetMembers", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_CreateExpense_DuplicateSplitUser(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(90),
+		Currency:    "USD",
+		Description: "Dinner",
+		SplitType:   models.SplitTypeExact,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: payer.UUID, Amount: decimal.NewFromInt(30)},
+			{UserUUID: user2.UUID, Amount: decimal.NewFromInt(30)},
+			{UserUUID: payer.UUID, Amount: decimal.NewFromInt(30)},
+		},
+	})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidSplit, appErr.Code)
+	assert.Equal(t, payer.UUID, appErr.Details["user_uuid"])
+
+	// Only the payer lookup happened; no split users were resolved
+	userRepo.AssertNumberOfCalls(t, "GetByUUID", 1)
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_CreateExpense_Itemized(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	for _, u := range []*models.User{alice, bob, carol} {
+		userRepo.On("GetByUUID", mock.Anything, u.UUID).Return(u, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, u.ID).Return(true, nil)
+	}
+
+	var created []*models.ExpenseSplit
+	var items []*models.ExpenseItem
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).
+		Run(func(args mock.Arguments) { created = append(created, args.Get(2).(*models.ExpenseSplit)) }).Return(nil)
+	expenseRepo.On("CreateItem", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseItem")).
+		Run(func(args mock.Arguments) { items = append(items, args.Get(2).(*models.ExpenseItem)) }).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{{}, {}, {}}, nil)
+	ledger := balanceLedger{}
+	ledger.track(balanceRepo)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	// Milk is shared three ways, wine is Alice's, bread is split by Bob and Carol
+	expense, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  alice.UUID,
+		Amount:      decimal.NewFromInt(100),
+		Currency:    "USD",
+		Description: "Groceries",
+		Items: []models.ExpenseItemRequest{
+			{Description: "Milk", Amount: decimal.NewFromInt(10), UserUUIDs: []string{alice.UUID, bob.UUID, carol.UUID}},
+			{Description: "Wine", Amount: decimal.NewFromInt(30), UserUUIDs: []string{alice.UUID}},
+			{Description: "Bread", Amount: decimal.NewFromInt(60), UserUUIDs: []string{bob.UUID, carol.UUID}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.SplitTypeExact, expense.SplitType)
+
+	want := map[int64]string{alice.ID: "33.34", bob.ID: "33.33", carol.ID: "33.33"}
+	if assert.Len(t, created, 3) {
+		for _, split := range created {
+			assert.Equal(t, want[split.UserID], split.Amount.StringFixed(2))
+		}
+	}
+
+	if assert.Len(t, items, 3) {
+		assert.Equal(t, "Milk", items[0].Description)
+		assert.Equal(t, int64(1), items[0].ExpenseID)
+		assert.Len(t, items[0].Shares, 3)
+		assert.Equal(t, alice.ID, items[0].Shares[0].UserID)
+		assert.Equal(t, "3.34", items[0].Shares[0].Amount.StringFixed(2))
+		assert.Equal(t, carol.ID, items[2].Shares[1].UserID)
+	}
+	assert.Len(t, expense.Items, 3)
+
+	// Per-user totals still net out against what the payer paid
+	total := decimal.Zero
+	for _, delta := range ledger {
+		total = total.Add(delta)
+	}
+	assert.True(t, total.IsZero())
+}
+
+func TestExpenseService_CreateExpense_ItemizedValidation(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	tests := []struct {
+		name      string
+		splitType models.SplitType
+		splits    []models.CreateExpenseSplitRequest
+		items     []models.ExpenseItemRequest
+	}{
+		{
+			name:  "items do not add up",
+			items: []models.ExpenseItemRequest{{Description: "Milk", Amount: decimal.NewFromInt(40), UserUUIDs: []string{alice.UUID}}},
+		},
+		{
+			name:  "item without users",
+			items: []models.ExpenseItemRequest{{Description: "Milk", Amount: decimal.NewFromInt(50)}},
+		},
+		{
+			name:  "duplicate user in item",
+			items: []models.ExpenseItemRequest{{Description: "Milk", Amount: decimal.NewFromInt(50), UserUUIDs: []string{alice.UUID, alice.UUID}}},
+		},
+		{
+			name:      "items with another split type",
+			splitType: models.SplitTypeEqual,
+			items:     []models.ExpenseItemRequest{{Description: "Milk", Amount: decimal.NewFromInt(50), UserUUIDs: []string{alice.UUID}}},
+		},
+		{
+			name:   "items with explicit splits",
+			splits: []models.CreateExpenseSplitRequest{{UserUUID: bob.UUID, Amount: decimal.NewFromInt(50)}},
+			items:  []models.ExpenseItemRequest{{Description: "Milk", Amount: decimal.NewFromInt(50), UserUUIDs: []string{alice.UUID}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groupRepo := new(MockGroupRepositoryES)
+			userRepo := new(MockUserRepositoryES)
+			db := new(MockDBES)
+			groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			userRepo.On("GetByUUID", mock.Anything, alice.UUID).Return(alice, nil)
+			groupRepo.On("IsMember", mock.Anything, group.ID, alice.ID).Return(true, nil)
+
+			es := service.NewExpenseService(new(MockExpenseRepositoryES), groupRepo, userRepo, new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+			_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+				GroupUUID:   group.UUID,
+				PaidByUUID:  alice.UUID,
+				Amount:      decimal.NewFromInt(50),
+				Currency:    "USD",
+				Description: "Groceries",
+				SplitType:   tt.splitType,
+				Splits:      tt.splits,
+				Items:       tt.items,
+			})
+			appErr, ok := err.(*errors.AppError)
+			assert.True(t, ok)
+			assert.Equal(t, errors.ErrCodeInvalidSplit, appErr.Code)
+			db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestExpenseService_CreateExpense_RefundReversesExpense(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+	users := []*models.User{alice, bob, carol}
+
+	tests := []struct {
+		name      string
+		amount    int64
+		splitType models.SplitType
+		splits    []models.CreateExpenseSplitRequest
+	}{
+		{
+			name:      "equal",
+			amount:    100,
+			splitType: models.SplitTypeEqual,
+			splits:    []models.CreateExpenseSplitRequest{{UserUUID: alice.UUID}, {UserUUID: bob.UUID}, {UserUUID: carol.UUID}},
+		},
+		{
+			name:      "exact",
+			amount:    90,
+			splitType: models.SplitTypeExact,
+			splits: []models.CreateExpenseSplitRequest{
+				{UserUUID: alice.UUID, Amount: decimal.NewFromInt(20)},
+				{UserUUID: bob.UUID, Amount: decimal.NewFromInt(30)},
+				{UserUUID: carol.UUID, Amount: decimal.NewFromInt(40)},
+			},
+		},
+		{
+			name:      "percentage",
+			amount:    90,
+			splitType: models.SplitTypePercentage,
+			splits: []models.CreateExpenseSplitRequest{
+				{UserUUID: alice.UUID, Percentage: decimal.RequireFromString("33.33")},
+				{UserUUID: bob.UUID, Percentage: decimal.RequireFromString("33.33")},
+				{UserUUID: carol.UUID, Percentage: decimal.RequireFromString("33.34")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenseRepo := new(MockExpenseRepositoryES)
+			groupRepo := new(MockGroupRepositoryES)
+			userRepo := new(MockUserRepositoryES)
+			balanceRepo := new(MockBalanceRepositoryES)
+			db := new(MockDBES)
+
+			groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			for _, u := range users {
+				userRepo.On("GetByUUID", mock.Anything, u.UUID).Return(u, nil)
+				groupRepo.On("IsMember", mock.Anything, group.ID, u.ID).Return(true, nil)
+			}
+			var created []*models.Expense
+			expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).
+				Run(func(args mock.Arguments) { created = append(created, args.Get(2).(*models.Expense)) }).Return(nil)
+			expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil)
+			expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+			ledger := balanceLedger{}
+			ledger.track(balanceRepo)
+			db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+			es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+			for _, isRefund := range []bool{false, true} {
+				_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+					GroupUUID:   group.UUID,
+					PaidByUUID:  alice.UUID,
+					Amount:      decimal.NewFromInt(tt.amount),
+					Currency:    "USD",
+					Description: "Store purchase",
+					SplitType:   tt.splitType,
+					Splits:      append([]models.CreateExpenseSplitRequest{}, tt.splits...),
+					IsRefund:    isRefund,
+				})
+				assert.NoError(t, err)
+			}
+
+			if assert.Len(t, created, 2) {
+				assert.False(t, created[0].IsRefund)
+				assert.True(t, created[1].IsRefund)
+				assert.True(t, created[1].Amount.Equal(decimal.NewFromInt(-tt.amount)))
+			}
+			for _, u := range users {
+				assert.True(t, ledger[u.ID].IsZero(), "user %d balance %s", u.ID, ledger[u.ID])
+			}
+		})
+	}
+}
+
+func TestExpenseService_CreateExpense_Category(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+
+	tests := []struct {
+		name     string
+		category string
+		want     string
+		wantErr  bool
+	}{
+		{name: "omitted", category: "", want: models.DefaultExpenseCategory},
+		{name: "normalized", category: "  Food ", want: "food"},
+		{name: "too long", category: strings.Repeat("x", 51), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenseRepo := new(MockExpenseRepositoryES)
+			groupRepo := new(MockGroupRepositoryES)
+			userRepo := new(MockUserRepositoryES)
+			balanceRepo := new(MockBalanceRepositoryES)
+			db := new(MockDBES)
+
+			groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+			groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+			expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+			expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil)
+			expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+			balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, payer.ID, mock.Anything, "USD").Return(nil)
+			balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+			db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+			es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+			expense, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+				GroupUUID:   group.UUID,
+				PaidByUUID:  payer.UUID,
+				Amount:      decimal.NewFromInt(12),
+				Currency:    "USD",
+				Description: "Lunch",
+				Category:    tt.category,
+				SplitType:   models.SplitTypeEqual,
+				Splits:      []models.CreateExpenseSplitRequest{{UserUUID: payer.UUID}},
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, expense.Category)
+		})
+	}
+}
+
+func TestExpenseService_CreateExpense_DefaultsToGroupCurrency(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", DefaultCurrency: "EUR"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, payer.ID, mock.Anything, "EUR").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "EUR").Return(nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	expense, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(12),
+		Description: "Lunch",
+		SplitType:   models.SplitTypeEqual,
+		Splits:      []models.CreateExpenseSplitRequest{{UserUUID: payer.UUID}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", expense.Currency)
+}
+
+func TestExpenseService_CreateExpense_ExpenseDate(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	lastWeek := time.Now().AddDate(0, 0, -7).Truncate(time.Second)
+
+	tests := []struct {
+		name        string
+		expenseDate time.Time
+		wantErr     bool
+	}{
+		{name: "omitted defaults to now"},
+		{name: "backdated", expenseDate: lastWeek},
+		{name: "tomorrow", expenseDate: time.Now().Add(12 * time.Hour)},
+		{name: "too far ahead", expenseDate: time.Now().AddDate(0, 0, 3), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenseRepo := new(MockExpenseRepositoryES)
+			groupRepo := new(MockGroupRepositoryES)
+			userRepo := new(MockUserRepositoryES)
+			balanceRepo := new(MockBalanceRepositoryES)
+			db := new(MockDBES)
+
+			groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+			groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+			expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+			expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil)
+			expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+			balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, payer.ID, mock.Anything, "USD").Return(nil)
+			balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+			db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+			es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+			before := time.Now()
+			expense, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+				GroupUUID:   group.UUID,
+				PaidByUUID:  payer.UUID,
+				Amount:      decimal.NewFromInt(12),
+				Currency:    "USD",
+				Description: "Lunch",
+				ExpenseDate: tt.expenseDate,
+				SplitType:   models.SplitTypeEqual,
+				Splits:      []models.CreateExpenseSplitRequest{{UserUUID: payer.UUID}},
+			})
+			if tt.wantErr {
+				appErr, ok := err.(*errors.AppError)
+				assert.True(t, ok)
+				assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+				db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.expenseDate.IsZero() {
+				assert.False(t, expense.ExpenseDate.Before(before))
+			} else {
+				assert.True(t, tt.expenseDate.Equal(expense.ExpenseDate))
+			}
+		})
+	}
+}
+
+func TestExpenseService_ListExpenses_NormalizesCategoryFilter(t *testing.T) {
+	expenseRepo := new(MockExpenseRepositoryES)
+	expenseRepo.On("List", mock.Anything, mock.MatchedBy(func(filter *models.ExpenseFilter) bool {
+		return filter.Category == "transport"
+	})).Return([]*models.Expense{}, 0, nil)
+
+	es := service.NewExpenseService(expenseRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES),
+		newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	result, err := es.ListExpenses(context.Background(), &models.ExpenseFilter{Category: " Transport", Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.TotalCount)
+	expenseRepo.AssertExpectations(t)
+}
+
+func TestExpenseService_CreateExpense_InvalidUUID(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	es := service.NewExpenseService(new(MockExpenseRepositoryES), new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	req := &models.CreateExpenseRequest{
+		GroupUUID:   "invalid",
+		PaidByUUID:  "also-invalid",
+		Amount:      decimal.NewFromInt(10),
+		Description: "x",
+		SplitType:   models.SplitTypeEqual,
+		Splits:      []models.CreateExpenseSplitRequest{{UserUUID: "invalid"}},
+	}
+
+	res, err := es.CreateExpense(ctx, req)
+	assert.Error(t, err)
+	assert.Nil(t, res)
+	assert.Contains(t, err.Error(), "Invalid value")
+}
+
+func TestExpenseService_CreateExpense_UnknownSplitUser(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	unknown := "cccccccc-cccc-4ccc-8ccc-cccccccccccc"
+
+	groupRepo := new(MockGroupRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+	userRepo := new(MockUserRepositoryES)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	userRepo.On("GetByUUID", mock.Anything, unknown).Return(nil, errors.NewNotFoundError("User"))
+	db := new(MockDBES)
+
+	es := service.NewExpenseService(new(MockExpenseRepositoryES), groupRepo, userRepo, new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(10),
+		Description: "Taxi",
+		SplitType:   models.SplitTypeEqual,
+		Splits:      []models.CreateExpenseSplitRequest{{UserUUID: payer.UUID}, {UserUUID: unknown}},
+	})
+	appErr, ok := err.(*errors.AppError)
+	if assert.True(t, ok) {
+		assert.Equal(t, errors.ErrCodeNotFound, appErr.Code)
+		assert.Equal(t, "User not found", appErr.Message)
+	}
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_CreateExpense_CommitFailure(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	req := &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(50),
+		Currency:    "USD",
+		Description: "Groceries",
+		SplitType:   models.SplitTypeEqual,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: payer.UUID},
+			{UserUUID: user2.UUID},
+		},
+	}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	userRepo.On("GetByUUID", mock.Anything, user2.UUID).Return(user2, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, user2.ID).Return(true, nil)
+
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil)
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+
+	// fn succeeds but the commit itself fails
+	commitErr := errors.NewDatabaseError(nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(commitErr)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	expense, err := es.CreateExpense(ctx, req)
+	assert.Equal(t, commitErr, err)
+	assert.Nil(t, expense)
+	expenseRepo.AssertNotCalled(t, "GetExpenseSplits", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_CreateExpense_PendingPayer(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Email: "alice@example.com", IsPending: true}
+	user2 := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+
+	req := &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(50),
+		Currency:    "USD",
+		Description: "Lunch",
+		SplitType:   models.SplitTypeEqual,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: payer.UUID},
+			{UserUUID: user2.UUID},
+		},
+	}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	expense, err := es.CreateExpense(ctx, req)
+	assert.Nil(t, expense)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodePendingUser, appErr.Code)
+	assert.Equal(t, http.StatusConflict, appErr.Status)
+	assert.Equal(t, "alice@example.com", appErr.Details["email"])
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_CreateExpense_PendingParticipant(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	pending := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Email: "bob@example.com", IsPending: true}
+
+	req := &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(50),
+		Currency:    "USD",
+		Description: "Lunch",
+		SplitType:   models.SplitTypeEqual,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: payer.UUID},
+			{UserUUID: pending.UUID},
+		},
+	}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	userRepo.On("GetByUUID", mock.Anything, pending.UUID).Return(pending, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, pending.ID).Return(true, nil)
+
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil).Times(2)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{
+		{UserID: payer.ID, Amount: decimal.NewFromInt(25)},
+		{UserID: pending.ID, Amount: decimal.NewFromInt(25)},
+	}, nil)
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	expense, err := es.CreateExpense(ctx, req)
+	assert.NoError(t, err)
+	assert.NotNil(t, expense)
+	assert.Equal(t, 2, len(expense.Splits))
+}
+
+func setupGroupExpenseList(t *testing.T) (service.ExpenseService, *MockExpenseRepositoryES, *models.Group) {
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	viewer := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, viewer.UUID).Return(viewer, nil)
+	expenseRepo.On("GetGroupExpenses", mock.Anything, group.ID, 0, 10, false).Return([]*models.Expense{
+		{ID: 1, GroupID: group.ID, Amount: decimal.NewFromInt(90)},
+		{ID: 2, GroupID: group.ID, Amount: decimal.NewFromInt(40)},
+	}, nil)
+	expenseRepo.On("CountGroupExpenses", mock.Anything, group.ID, false).Return(12, nil)
+	expenseRepo.On("GetSplitsForExpenses", mock.Anything, []int64{1, 2}).Return(map[int64][]*models.ExpenseSplit{
+		1: {
+			{UserID: 1, Amount: decimal.NewFromInt(30), User: &models.User{ID: 1, Name: "Alice"}},
+			{UserID: 2, Amount: decimal.NewFromInt(30), User: viewer},
+			{UserID: 3, Amount: decimal.NewFromInt(30), User: &models.User{ID: 3, Name: "Carol"}},
+		},
+		2: {
+			{UserID: 1, Amount: decimal.NewFromInt(20), User: &models.User{ID: 1, Name: "Alice"}},
+			{UserID: 3, Amount: decimal.NewFromInt(20), User: &models.User{ID: 3, Name: "Carol"}},
+		},
+	}, nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, logger)
+	return es, expenseRepo, group
+}
+
+func TestExpenseService_GetGroupExpenses_IncludeSplits(t *testing.T) {
+	es, expenseRepo, group := setupGroupExpenseList(t)
+
+	expenses, total, err := es.GetGroupExpenses(context.Background(), group.UUID, 1, 10, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, total)
+	assert.Len(t, expenses[0].Splits, 3)
+	assert.NotNil(t, expenses[0].Splits[0].User)
+	assert.Nil(t, expenses[0].SplitsSummary)
+	assert.Len(t, expenses[1].Splits, 2)
+
+	// The whole page is loaded with a single query
+	expenseRepo.AssertNumberOfCalls(t, "GetSplitsForExpenses", 1)
+	expenseRepo.AssertNotCalled(t, "GetExpenseSplits", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_GetGroupExpenses_IncludeNone(t *testing.T) {
+	es, expenseRepo, group := setupGroupExpenseList(t)
+
+	expenses, _, err := es.GetGroupExpenses(context.Background(), group.UUID, 1, 10, &models.ExpenseListOptions{Include: models.SplitIncludeNone})
+	assert.NoError(t, err)
+	assert.Len(t, expenses, 2)
+
+	payload, err := json.Marshal(expenses[0])
+	assert.NoError(t, err)
+	assert.NotContains(t, string(payload), `"splits"`)
+	assert.NotContains(t, string(payload), `"splits_summary"`)
+	expenseRepo.AssertNumberOfCalls(t, "GetSplitsForExpenses", 0)
+}
+
+func TestExpenseService_GetGroupExpenses_IncludeSummary(t *testing.T) {
+	es, expenseRepo, group := setupGroupExpenseList(t)
+
+	expenses, _, err := es.GetGroupExpenses(context.Background(), group.UUID, 1, 10, &models.ExpenseListOptions{
+		Include:    models.SplitIncludeSummary,
+		ViewerUUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb",
+	})
+	assert.NoError(t, err)
+
+	assert.Nil(t, expenses[0].Splits)
+	assert.Equal(t, 3, expenses[0].SplitsSummary.ParticipantCount)
+	assert.True(t, expenses[0].SplitsSummary.ViewerShare.Equal(decimal.NewFromInt(30)))
+
+	// The viewer does not take part in the second expense
+	assert.Equal(t, 2, expenses[1].SplitsSummary.ParticipantCount)
+	assert.Nil(t, expenses[1].SplitsSummary.ViewerShare)
+
+	payload, err := json.Marshal(expenses[0])
+	assert.NoError(t, err)
+	assert.NotContains(t, string(payload), `"splits":`)
+	assert.Contains(t, string(payload), `"participant_count":3`)
+	expenseRepo.AssertNumberOfCalls(t, "GetSplitsForExpenses", 1)
+}
+
+func TestExpenseService_GetGroupExpenses_SummaryRequiresViewer(t *testing.T) {
+	es, expenseRepo, group := setupGroupExpenseList(t)
+
+	_, _, err := es.GetGroupExpenses(context.Background(), group.UUID, 1, 10, &models.ExpenseListOptions{Include: models.SplitIncludeSummary})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeRequired, appErr.Code)
+	expenseRepo.AssertNotCalled(t, "GetGroupExpenses", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_GetTopGroupExpenses(t *testing.T) {
+	es, expenseRepo, group := setupGroupExpenseList(t)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	// A date-only to_date covers the whole day
+	endOfTo := to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	expenseRepo.On("GetTopGroupExpenses", mock.Anything, group.ID, from, endOfTo, 5, false).Return([]*models.Expense{
+		{ID: 1, GroupID: group.ID, Amount: decimal.NewFromInt(90)},
+		{ID: 2, GroupID: group.ID, Amount: decimal.NewFromInt(40)},
+	}, nil)
+
+	expenses, err := es.GetTopGroupExpenses(context.Background(), group.UUID, &models.TopExpensesFilter{FromDate: from, ToDate: to, Limit: 5})
+	assert.NoError(t, err)
+	assert.Len(t, expenses, 2)
+	assert.Len(t, expenses[0].Splits, 3)
+	assert.Len(t, expenses[1].Splits, 2)
+	expenseRepo.AssertNumberOfCalls(t, "GetSplitsForExpenses", 1)
+}
+
+func TestExpenseService_GetTopGroupExpenses_CapsLimit(t *testing.T) {
+	es, expenseRepo, group := setupGroupExpenseList(t)
+
+	expenseRepo.On("GetTopGroupExpenses", mock.Anything, group.ID, time.Time{}, time.Time{}, 100, false).Return([]*models.Expense{}, nil)
+	expenseRepo.On("GetTopGroupExpenses", mock.Anything, group.ID, time.Time{}, time.Time{}, 10, false).Return([]*models.Expense{}, nil)
+
+	expenses, err := es.GetTopGroupExpenses(context.Background(), group.UUID, &models.TopExpensesFilter{Limit: 500})
+	assert.NoError(t, err)
+	assert.Empty(t, expenses)
+
+	_, err = es.GetTopGroupExpenses(context.Background(), group.UUID, &models.TopExpensesFilter{})
+	assert.NoError(t, err)
+	expenseRepo.AssertNumberOfCalls(t, "GetTopGroupExpenses", 2)
+	expenseRepo.AssertNumberOfCalls(t, "GetSplitsForExpenses", 0)
+
+	_, err = es.GetTopGroupExpenses(context.Background(), group.UUID, &models.TopExpensesFilter{FromDate: time.Now(), ToDate: time.Now().AddDate(0, 0, -1)})
+	assert.Error(t, err)
+}
+
+func TestExpenseService_GetExpenseByUUID(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	es := service.NewExpenseService(expenseRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	found := &models.Expense{
+		ID:     7,
+		UUID:   "dddddddd-dddd-4ddd-8ddd-dddddddddddd",
+		Amount: decimal.NewFromInt(60),
+		Group:  &models.Group{ID: 10, Name: "Trip"},
+		Payer:  &models.User{ID: 1, Name: "Alice"},
+	}
+	missing := "eeeeeeee-eeee-4eee-8eee-eeeeeeeeeeee"
+
+	expenseRepo.On("GetByUUID", mock.Anything, found.UUID).Return(found, nil)
+	expenseRepo.On("GetByUUID", mock.Anything, missing).Return(nil, errors.NewNotFoundError("Expense"))
+	expenseRepo.On("GetExpenseSplits", mock.Anything, found.ID).Return([]*models.ExpenseSplit{
+		{UserID: 1, Amount: decimal.NewFromInt(30)},
+		{UserID: 2, Amount: decimal.NewFromInt(30)},
+	}, nil)
+	expenseRepo.On("GetExpenseItems", mock.Anything, found.ID).Return([]*models.ExpenseItem{}, nil)
+
+	expense, err := es.GetExpenseByUUID(ctx, found.UUID)
+	assert.NoError(t, err)
+	assert.Len(t, expense.Splits, 2)
+	assert.Equal(t, "Trip", expense.Group.Name)
+	assert.Equal(t, "Alice", expense.Payer.Name)
+
+	_, err = es.GetExpenseByUUID(ctx, missing)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeNotFound, appErr.Code)
+	assert.Equal(t, http.StatusNotFound, appErr.Status)
+
+	_, err = es.GetExpenseByUUID(ctx, "not-a-uuid")
+	appErr, ok = err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalid, appErr.Code)
+}
+
+// balanceLedger tracks UpdateBalance calls on a mock so tests can compare end states
+type balanceLedger map[int64]decimal.Decimal
+
+func (l balanceLedger) track(balanceRepo *MockBalanceRepositoryES) {
+	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "USD").
+		Run(func(args mock.Arguments) {
+			userID := args.Get(3).(int64)
+			l[userID] = l[userID].Add(args.Get(4).(decimal.Decimal))
+		}).Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+}
+
+func TestExpenseService_UpdateExpense_RecalculatesBalances(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	users := []*models.User{
+		{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"},
+		{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"},
+		{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"},
+	}
+
+	newService := func(ledger balanceLedger) (service.ExpenseService, *MockExpenseRepositoryES) {
+		expenseRepo := new(MockExpenseRepositoryES)
+		groupRepo := new(MockGroupRepositoryES)
+		userRepo := new(MockUserRepositoryES)
+		balanceRepo := new(MockBalanceRepositoryES)
+		db := new(MockDBES)
+
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		for _, user := range users {
+			userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+			groupRepo.On("IsMember", mock.Anything, group.ID, user.ID).Return(true, nil)
+		}
+		expenseRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		expenseRepo.On("Update", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		expenseRepo.On("DeleteExpenseSplits", mock.Anything, mock.Anything, int64(1), mock.Anything).Return(nil)
+		expenseRepo.On("DeleteExpenseItems", mock.Anything, mock.Anything, int64(1)).Return(nil)
+		ledger.track(balanceRepo)
+		db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+
+		es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+		return es, expenseRepo
+	}
+
+	exactSplits := []models.CreateExpenseSplitRequest{
+		{UserUUID: users[0].UUID, Amount: decimal.NewFromInt(50)},
+		{UserUUID: users[1].UUID, Amount: decimal.NewFromInt(40)},
+		{UserUUID: users[2].UUID, Amount: decimal.NewFromInt(30)},
+	}
+
+	// Create 90 USD equal, then edit it into 120 USD exact
+	edited := balanceLedger{}
+	es, expenseRepo := newService(edited)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil).Once()
+
+	created, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  users[0].UUID,
+		Amount:      decimal.NewFromInt(90),
+		Currency:    "USD",
+		Description: "Dinner",
+		SplitType:   models.SplitTypeEqual,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: users[0].UUID}, {UserUUID: users[1].UUID}, {UserUUID: users[2].UUID},
+		},
+	})
+	assert.NoError(t, err)
+
+	created.ID = 1
+	stored := *created
+	expenseRepo.On("GetByUUID", mock.Anything, created.UUID).Return(created, nil)
+	expenseRepo.On("GetByIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(&stored, nil)
+	expenseRepo.On("GetExpenseSplitsForUpdate", mock.Anything, mock.Anything, int64(1)).Return([]*models.ExpenseSplit{
+		{UserID: 1, Amount: decimal.NewFromInt(30)},
+		{UserID: 2, Amount: decimal.NewFromInt(30)},
+		{UserID: 3, Amount: decimal.NewFromInt(30)},
+	}, nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+
+	updated, err := es.UpdateExpense(ctx, created.UUID, &models.UpdateExpenseRequest{
+		Amount:      decimal.NewFromInt(120),
+		Currency:    "USD",
+		Description: "Dinner and drinks",
+		SplitType:   models.SplitTypeExact,
+		Splits:      exactSplits,
+	})
+	assert.NoError(t, err)
+	assert.True(t, updated.Amount.Equal(decimal.NewFromInt(120)))
+	assert.Equal(t, models.SplitTypeExact, updated.SplitType)
+
+	// Create 120 USD exact from scratch
+	fresh := balanceLedger{}
+	es, expenseRepo = newService(fresh)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil)
+	_, err = es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  users[0].UUID,
+		Amount:      decimal.NewFromInt(120),
+		Currency:    "USD",
+		Description: "Dinner and drinks",
+		SplitType:   models.SplitTypeExact,
+		Splits:      exactSplits,
+	})
+	assert.NoError(t, err)
+
+	for _, user := range users {
+		assert.True(t, edited[user.ID].Equal(fresh[user.ID]), "user %d: edited %s, fresh %s", user.ID, edited[user.ID], fresh[user.ID])
+	}
+	assert.True(t, edited[1].Equal(decimal.NewFromInt(-70)))
+}
+
+func TestExpenseService_UpdateExpense_InvalidSplitsLeaveStateUntouched(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	expense := &models.Expense{ID: 1, UUID: "dddddddd-dddd-4ddd-8ddd-dddddddddddd", GroupID: 10, PaidBy: 1, Amount: decimal.NewFromInt(90), Currency: "USD",
+		BaseAmount: decimal.NewFromInt(90), BaseCurrency: "USD", ExchangeRate: decimal.NewFromInt(1)}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	expenseRepo.On("GetByUUID", mock.Anything, expense.UUID).Return(expense, nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, expense.ID).Return([]*models.ExpenseSplit{{UserID: 1, Amount: decimal.NewFromInt(90)}}, nil)
+	userRepo.On("GetByUUID", mock.Anything, alice.UUID).Return(alice, nil)
+	groupRepo.On("IsMember", mock.Anything, int64(10), alice.ID).Return(true, nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	// Exact splits that do not add up to the new amount
+	_, err := es.UpdateExpense(ctx, expense.UUID, &models.UpdateExpenseRequest{
+		Amount:      decimal.NewFromInt(120),
+		Description: "Dinner",
+		SplitType:   models.SplitTypeExact,
+		Splits:      []models.CreateExpenseSplitRequest{{UserUUID: alice.UUID, Amount: decimal.NewFromInt(100)}},
+	})
+	assert.Error(t, err)
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+	balanceRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.True(t, expense.Amount.Equal(decimal.NewFromInt(90)))
+}
+
+func TestExpenseService_UpdateExpense_ReversesTheLockedExpense(t *testing.T) {
+	ctx := context.Background()
+	users := []*models.User{
+		{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"},
+		{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"},
+		{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"},
+	}
+	exactSplits := []models.CreateExpenseSplitRequest{
+		{UserUUID: users[0].UUID, Amount: decimal.NewFromInt(50)},
+		{UserUUID: users[1].UUID, Amount: decimal.NewFromInt(40)},
+		{UserUUID: users[2].UUID, Amount: decimal.NewFromInt(30)},
+	}
+
+	// The update reads the expense as 90 split three ways, but another update
+	// turns it into 60 split three ways before this one takes the lock
+	newExpense := func(amount int64) *models.Expense {
+		return &models.Expense{ID: 1, UUID: "dddddddd-dddd-4ddd-8ddd-dddddddddddd", GroupID: 10, PaidBy: 1, Amount: decimal.NewFromInt(amount), Currency: "USD",
+			BaseAmount: decimal.NewFromInt(amount), BaseCurrency: "USD", ExchangeRate: decimal.NewFromInt(1), SplitType: models.SplitTypeEqual}
+	}
+	equalSplits := func(share int64) []*models.ExpenseSplit {
+		return []*models.ExpenseSplit{
+			{UserID: 1, Amount: decimal.NewFromInt(share)},
+			{UserID: 2, Amount: decimal.NewFromInt(share)},
+			{UserID: 3, Amount: decimal.NewFromInt(share)},
+		}
+	}
+
+	newService := func(locked *models.Expense, ledger balanceLedger) (service.ExpenseService, *MockExpenseRepositoryES, *MockBalanceRepositoryES) {
+		expenseRepo := new(MockExpenseRepositoryES)
+		groupRepo := new(MockGroupRepositoryES)
+		userRepo := new(MockUserRepositoryES)
+		balanceRepo := new(MockBalanceRepositoryES)
+		db := new(MockDBES)
+
+		stale := newExpense(90)
+		expenseRepo.On("GetByUUID", mock.Anything, stale.UUID).Return(stale, nil)
+		expenseRepo.On("GetExpenseSplits", mock.Anything, stale.ID).Return(equalSplits(30), nil)
+		expenseRepo.On("GetByIDForUpdate", mock.Anything, mock.Anything, stale.ID).Return(locked, nil)
+		expenseRepo.On("GetExpenseSplitsForUpdate", mock.Anything, mock.Anything, stale.ID).Return(equalSplits(20), nil)
+		expenseRepo.On("DeleteExpenseSplits", mock.Anything, mock.Anything, stale.ID, mock.Anything).Return(nil)
+		expenseRepo.On("DeleteExpenseItems", mock.Anything, mock.Anything, stale.ID).Return(nil)
+		expenseRepo.On("Update", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		for _, user := range users {
+			userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+			groupRepo.On("IsMember", mock.Anything, int64(10), user.ID).Return(true, nil)
+		}
+		ledger.track(balanceRepo)
+		db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+
+		es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+		return es, expenseRepo, balanceRepo
+	}
+	update := &models.UpdateExpenseRequest{
+		Amount:      decimal.NewFromInt(120),
+		Currency:    "USD",
+		Description: "Dinner and drinks",
+		SplitType:   models.SplitTypeExact,
+		Splits:      exactSplits,
+	}
+
+	t.Run("updated concurrently", func(t *testing.T) {
+		ledger := balanceLedger{}
+		es, _, _ := newService(newExpense(60), ledger)
+
+		_, err := es.UpdateExpense(ctx, "dddddddd-dddd-4ddd-8ddd-dddddddddddd", update)
+		assert.NoError(t, err)
+
+		// The 60 split three ways is what gets reversed: Alice was owed 40,
+		// Bob and Carol owed 20, and the new split is 50/40/30 of 120
+		assert.True(t, ledger[1].Equal(decimal.NewFromInt(-30)), "alice: %s", ledger[1])
+		assert.True(t, ledger[2].Equal(decimal.NewFromInt(20)), "bob: %s", ledger[2])
+		assert.True(t, ledger[3].Equal(decimal.NewFromInt(10)), "carol: %s", ledger[3])
+	})
+
+	t.Run("deleted concurrently", func(t *testing.T) {
+		deleted := newExpense(90)
+		deletedAt := time.Now()
+		deleted.DeletedAt = &deletedAt
+		es, expenseRepo, balanceRepo := newService(deleted, balanceLedger{})
+
+		_, err := es.UpdateExpense(ctx, deleted.UUID, update)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeAlreadyDeleted, appErr.Code)
+		// The delete that won already reversed the balances
+		balanceRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		expenseRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestExpenseService_DeleteExpense_RestoresBalances(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("GetByID", mock.Anything, group.ID).Return(group, nil)
+	for _, user := range []*models.User{alice, bob} {
+		userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, user.ID).Return(true, nil)
+	}
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	expenseRepo.On("Delete", mock.Anything, mock.Anything, int64(1), (*int64)(nil)).Return(true, nil)
+	db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	// Existing balances from earlier activity in the group
+	ledger := balanceLedger{1: decimal.NewFromInt(-15), 2: decimal.NewFromInt(15)}
+	ledger.track(balanceRepo)
+	before := balanceLedger{1: ledger[1], 2: ledger[2]}
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{}, nil).Once()
+	created, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  bob.UUID,
+		Amount:      decimal.NewFromInt(45),
+		Currency:    "USD",
+		Description: "Groceries",
+		SplitType:   models.SplitTypeExact,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: alice.UUID, Amount: decimal.NewFromInt(25)},
+			{UserUUID: bob.UUID, Amount: decimal.NewFromInt(20)},
+		},
+	})
+	assert.NoError(t, err)
+	assert.False(t, ledger[1].Equal(before[1]))
+
+	created.ID = 1
+	expenseRepo.On("GetByUUIDIncludingDeleted", mock.Anything, created.UUID).Return(created, nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(1)).Return([]*models.ExpenseSplit{
+		{UserID: alice.ID, Amount: decimal.NewFromInt(25)},
+		{UserID: bob.ID, Amount: decimal.NewFromInt(20)},
+	}, nil)
+
+	assert.NoError(t, es.DeleteExpense(ctx, created.UUID))
+	for userID, balance := range before {
+		assert.True(t, ledger[userID].Equal(balance), "user %d: %s after delete, %s before", userID, ledger[userID], balance)
+	}
+	expenseRepo.AssertCalled(t, "Delete", mock.Anything, mock.Anything, int64(1), (*int64)(nil))
+	// Splits stay with the deleted expense for history
+	expenseRepo.AssertNotCalled(t, "DeleteExpenseSplits", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_DeleteExpense_RecordsActingUser(t *testing.T) {
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	expense := &models.Expense{ID: 1, UUID: "eeeeeeee-eeee-4eee-8eee-eeeeeeeeeeee", GroupID: group.ID, PaidBy: 2, BaseAmount: decimal.NewFromInt(45), BaseCurrency: "USD"}
+	caller := &auth.Identity{UserID: 1, UserUUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	db := new(MockDBES)
+	expenseRepo.On("GetByUUIDIncludingDeleted", mock.Anything, expense.UUID).Return(expense, nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, expense.ID).Return([]*models.ExpenseSplit{}, nil)
+	expenseRepo.On("Delete", mock.Anything, mock.Anything, expense.ID, mock.MatchedBy(func(deletedBy *int64) bool {
+		return deletedBy != nil && *deletedBy == caller.UserID
+	})).Return(true, nil)
+	groupRepo.On("GetByID", mock.Anything, group.ID).Return(group, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, caller.UserID).Return(true, nil)
+	db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	balanceRepo := new(MockBalanceRepositoryES)
+	balanceLedger{}.track(balanceRepo)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, new(MockUserRepositoryES), balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	assert.NoError(t, es.DeleteExpense(auth.WithIdentity(context.Background(), caller), expense.UUID))
+	expenseRepo.AssertExpectations(t)
+}
+
+func TestExpenseService_DeleteExpense_AlreadyDeleted(t *testing.T) {
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	deletedAt := time.Now()
+
+	t.Run("deleted before the call", func(t *testing.T) {
+		expense := &models.Expense{ID: 1, UUID: "eeeeeeee-eeee-4eee-8eee-eeeeeeeeeeee", GroupID: group.ID, DeletedAt: &deletedAt}
+		expenseRepo := new(MockExpenseRepositoryES)
+		balanceRepo := new(MockBalanceRepositoryES)
+		db := new(MockDBES)
+		expenseRepo.On("GetByUUIDIncludingDeleted", mock.Anything, expense.UUID).Return(expense, nil)
+
+		es := service.NewExpenseService(expenseRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+		err := es.DeleteExpense(context.Background(), expense.UUID)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeAlreadyDeleted, appErr.Code)
+		assert.Equal(t, http.StatusConflict, appErr.Status)
+		db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("deleted concurrently", func(t *testing.T) {
+		expense := &models.Expense{ID: 1, UUID: "eeeeeeee-eeee-4eee-8eee-eeeeeeeeeeee", GroupID: group.ID, PaidBy: 2, BaseAmount: decimal.NewFromInt(45), BaseCurrency: "USD"}
+		expenseRepo := new(MockExpenseRepositoryES)
+		groupRepo := new(MockGroupRepositoryES)
+		balanceRepo := new(MockBalanceRepositoryES)
+		db := new(MockDBES)
+		expenseRepo.On("GetByUUIDIncludingDeleted", mock.Anything, expense.UUID).Return(expense, nil)
+		expenseRepo.On("GetExpenseSplits", mock.Anything, expense.ID).Return([]*models.ExpenseSplit{
+			{UserID: 1, Amount: decimal.NewFromInt(25)},
+			{UserID: 2, Amount: decimal.NewFromInt(20)},
+		}, nil)
+		expenseRepo.On("Delete", mock.Anything, mock.Anything, expense.ID, mock.Anything).Return(false, nil)
+		groupRepo.On("GetByID", mock.Anything, group.ID).Return(group, nil)
+		db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+
+		es := service.NewExpenseService(expenseRepo, groupRepo, new(MockUserRepositoryES), balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+		err := es.DeleteExpense(context.Background(), expense.UUID)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeAlreadyDeleted, appErr.Code)
+		// The balances were already reversed by the delete that won
+		balanceRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestExpenseService_DeleteExpense_NotFound(t *testing.T) {
+	expenseRepo := new(MockExpenseRepositoryES)
+	db := new(MockDBES)
+	missing := "eeeeeeee-eeee-4eee-8eee-eeeeeeeeeeee"
+	expenseRepo.On("GetByUUIDIncludingDeleted", mock.Anything, missing).Return(nil, errors.NewNotFoundError("Expense"))
+
+	es := service.NewExpenseService(expenseRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	err := es.DeleteExpense(context.Background(), missing)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeNotFound, appErr.Code)
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
 }
@@ -0,0 +1,92 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/controller"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func setupGroupExport(t *testing.T) (*MockExpenseRepositoryES, *MockSettlementRepository, *MockGroupRepositoryES, service.ExportService, *models.Group) {
+	expenseRepo := new(MockExpenseRepositoryES)
+	settlementRepo := new(MockSettlementRepository)
+	groupRepo := new(MockGroupRepositoryES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+
+	return expenseRepo, settlementRepo, groupRepo, service.NewExportService(expenseRepo, settlementRepo, groupRepo, zaptest.NewLogger(t)), group
+}
+
+func TestExportService_ExportGroupCSV(t *testing.T) {
+	expenseRepo, settlementRepo, _, es, group := setupGroupExport(t)
+
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice", Email: "alice@example.com"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob", Email: "bob@example.com"}
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	expenseRepo.On("GetGroupSplitUsers", mock.Anything, group.ID).Return([]*models.User{alice, bob}, nil)
+	expenseRepo.On("IterateGroupExpenses", mock.Anything, group.ID, time.Time{}, mock.Anything, mock.Anything).Return([][]*models.Expense{{
+		{ID: 1, UUID: "e1", Amount: decimal.NewFromInt(90), Currency: "USD", Description: `Dinner, "the good one"`,
+			Category: "food", SplitType: models.SplitTypeExact, ExpenseDate: day, Payer: alice},
+	}}, nil)
+	expenseRepo.On("GetSplitsForExpenses", mock.Anything, []int64{1}).Return(map[int64][]*models.ExpenseSplit{
+		1: {{ExpenseID: 1, UserID: 1, Amount: decimal.NewFromInt(60)}, {ExpenseID: 1, UserID: 2, Amount: decimal.NewFromInt(30)}},
+	}, nil)
+	settlementRepo.On("List", mock.Anything, mock.AnythingOfType("*models.SettlementFilter")).Return([]*models.Settlement{
+		{UUID: "s1", Amount: decimal.NewFromInt(30), Currency: "USD", CreatedAt: day, FromUser: bob, ToUser: alice},
+	}, 1, nil)
+
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := es.ExportGroupCSV(context.Background(), group.UUID, &models.ExportFilter{ToDate: to}, &buf)
+	assert.NoError(t, err)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"type", "date", "uuid", "description", "category", "split_type", "amount", "currency", "paid_by", "paid_to", "alice@example.com", "bob@example.com"},
+		{"expense", "2024-03-01", "e1", `Dinner, "the good one"`, "food", "exact", "90.00", "USD", "alice@example.com", "", "60.00", "30.00"},
+		{"settlement", "2024-03-01", "s1", "", "", "", "30.00", "USD", "bob@example.com", "alice@example.com", "", ""},
+	}, records)
+
+	// to_date includes the whole day
+	expenseRepo.AssertCalled(t, "IterateGroupExpenses", mock.Anything, group.ID, time.Time{},
+		to.AddDate(0, 0, 1).Add(-time.Nanosecond), mock.Anything)
+	settlementRepo.AssertCalled(t, "List", mock.Anything, mock.MatchedBy(func(f *models.SettlementFilter) bool {
+		return f.GroupUUID == group.UUID && f.SortDir == models.SortAsc
+	}))
+}
+
+func TestExportController_GroupNotFoundIsJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	groupRepo := new(MockGroupRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, mock.Anything).Return(nil, errors.NewNotFoundError("Group"))
+	es := service.NewExportService(new(MockExpenseRepositoryES), new(MockSettlementRepository), groupRepo, zaptest.NewLogger(t))
+
+	router := gin.New()
+	router.GET("/api/v1/groups/:uuid/export", controller.NewExportController(es, zaptest.NewLogger(t)).ExportGroup)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/groups/11111111-1111-4111-8111-111111111111/export?format=csv", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/groups/11111111-1111-4111-8111-111111111111/export?format=xlsx", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
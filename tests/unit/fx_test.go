@@ -0,0 +1,248 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/fx"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestStaticProvider_Rate(t *testing.T) {
+	provider := fx.NewStaticProvider("usd", map[string]decimal.Decimal{
+		"EUR": decimal.RequireFromString("0.8"),
+		"gbp": decimal.RequireFromString("0.5"),
+	})
+
+	rate, err := provider.Rate(context.Background(), "eur", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", rate.From)
+	assert.Equal(t, "USD", rate.To)
+	assert.Equal(t, "1.25", rate.Rate.String())
+	assert.False(t, rate.AsOf.IsZero())
+
+	// Pairs without the base currency go through it
+	rate, err = provider.Rate(context.Background(), "EUR", "GBP")
+	require.NoError(t, err)
+	assert.Equal(t, "0.625", rate.Rate.String())
+
+	rate, err = provider.Rate(context.Background(), "JPY", "JPY")
+	require.NoError(t, err)
+	assert.True(t, rate.Rate.Equal(decimal.NewFromInt(1)))
+
+	_, err = provider.Rate(context.Background(), "JPY", "USD")
+	require.Error(t, err)
+	assert.Equal(t, errors.ErrCodeRateUnavailable, err.(*errors.AppError).Code)
+}
+
+func TestHTTPProvider_Rate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/latest", r.URL.Path)
+		if r.URL.Query().Get("from") == "XXX" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"amount":1.0,"base":"EUR","date":"2024-03-01","rates":{"USD":1.0812}}`))
+	}))
+	defer server.Close()
+
+	provider := fx.NewHTTPProvider(server.URL+"/", 0)
+
+	rate, err := provider.Rate(context.Background(), "eur", "usd")
+	require.NoError(t, err)
+	assert.Equal(t, "1.0812", rate.Rate.String())
+	assert.Equal(t, "2024-03-01", rate.AsOf.Format("2006-01-02"))
+
+	_, err = provider.Rate(context.Background(), "XXX", "USD")
+	require.Error(t, err)
+	assert.Equal(t, errors.ErrCodeRateUnavailable, err.(*errors.AppError).Code)
+}
+
+func TestBalanceService_GetGroupBalanceSheet_ConvertTo(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", DefaultCurrency: "USD"}
+	alice := &models.User{ID: 1, Name: "Alice"}
+	bob := &models.User{ID: 2, Name: "Bob"}
+
+	gr := new(MockGroupRepository2)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	br := new(MockBalanceRepository2)
+	br.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{
+		{UserID: alice.ID, User: alice, Currency: "EUR", Balance: decimal.NewFromInt(-20)},
+		{UserID: bob.ID, User: bob, Currency: "EUR", Balance: decimal.NewFromInt(20)},
+		{UserID: alice.ID, User: alice, Currency: "USD", Balance: decimal.NewFromInt(40)},
+		{UserID: bob.ID, User: bob, Currency: "USD", Balance: decimal.NewFromInt(-40)},
+	}, nil)
+
+	rates := fx.NewStaticProvider("USD", map[string]decimal.Decimal{"EUR": decimal.RequireFromString("0.8")})
+	s := service.NewBalanceService(br, gr, new(MockUserRepository2), nil, nil, nil, nil, new(MockDB2), rates, zaptest.NewLogger(t))
+
+	sheet, err := s.GetGroupBalanceSheet(ctx, models.GroupUUID(group.UUID), "", "usd")
+	require.NoError(t, err)
+	require.NotNil(t, sheet.Conversion)
+	assert.Equal(t, "USD", sheet.Conversion.Currency)
+	assert.Len(t, sheet.Conversion.Rates, 2)
+
+	// Original figures are kept next to the converted ones
+	eur := sheet.Sections[0]
+	assert.Equal(t, "1.25", eur.ExchangeRate.Rate.String())
+	assert.True(t, eur.Balances[0].Balance.Equal(decimal.NewFromInt(-20)))
+	assert.True(t, eur.Balances[0].ConvertedBalance.Equal(decimal.NewFromInt(-25)))
+
+	// Alice owes 40 USD and is owed 20 EUR, roughly 15 USD overall
+	require.Len(t, sheet.Conversion.Balances, 2)
+	assert.Equal(t, alice, sheet.Conversion.Balances[0].User)
+	assert.True(t, sheet.Conversion.Balances[0].Balance.Equal(decimal.NewFromInt(15)))
+	assert.True(t, sheet.Conversion.Balances[1].Balance.Equal(decimal.NewFromInt(-15)))
+
+	// A currency without a rate fails instead of converting to zero
+	_, err = s.GetGroupBalanceSheet(ctx, models.GroupUUID(group.UUID), "", "JPY")
+	require.Error(t, err)
+	assert.Equal(t, errors.ErrCodeRateUnavailable, err.(*errors.AppError).Code)
+}
+
+func TestExpenseService_CreateExpense_ConvertsToGroupCurrency(t *testing.T) {
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip", DefaultCurrency: "USD"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"}
+
+	tests := []struct {
+		name       string
+		rates      fx.ExchangeRateProvider
+		explicit   *decimal.Decimal
+		wantRate   string
+		wantBase   string
+		wantSplits []string
+	}{
+		{
+			name:     "provider rate",
+			rates:    fx.NewStaticProvider("USD", map[string]decimal.Decimal{"EUR": decimal.RequireFromString("0.8")}),
+			wantRate: "1.25",
+			wantBase: "125",
+			// 33.34, 33.33 and 33.33 EUR
+			wantSplits: []string{"41.68", "41.66", "41.66"},
+		},
+		{
+			name:     "explicit rate",
+			explicit: decimalPtr("1.1"),
+			wantRate: "1.1",
+			wantBase: "110",
+			// 36.67 + 36.66 + 36.66 falls a cent short; the largest split takes it
+			wantSplits: []string{"36.68", "36.66", "36.66"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenseRepo := new(MockExpenseRepositoryES)
+			groupRepo := new(MockGroupRepositoryES)
+			userRepo := new(MockUserRepositoryES)
+			balanceRepo := new(MockBalanceRepositoryES)
+			db := new(MockDBES)
+
+			groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			for _, user := range []*models.User{alice, bob, carol} {
+				userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+				groupRepo.On("IsMember", mock.Anything, group.ID, user.ID).Return(true, nil)
+			}
+
+			var splits []*models.ExpenseSplit
+			expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+			expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Run(func(args mock.Arguments) {
+				splits = append(splits, args.Get(2).(*models.ExpenseSplit))
+			}).Return(nil)
+			expenseRepo.On("GetExpenseSplits", mock.Anything, mock.Anything).Return([]*models.ExpenseSplit{}, nil)
+
+			// Balances only ever move in the group's currency
+			balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+			balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, alice.ID, mock.Anything, "USD").Return(nil)
+			db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+			es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, tt.rates, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+			expense, err := es.CreateExpense(context.Background(), &models.CreateExpenseRequest{
+				GroupUUID:    group.UUID,
+				PaidByUUID:   alice.UUID,
+				Amount:       decimal.NewFromInt(100),
+				Currency:     "EUR",
+				Description:  "Museum",
+				SplitType:    models.SplitTypeEqual,
+				ExchangeRate: tt.explicit,
+				Splits: []models.CreateExpenseSplitRequest{
+					{UserUUID: alice.UUID}, {UserUUID: bob.UUID}, {UserUUID: carol.UUID},
+				},
+			})
+			require.NoError(t, err)
+
+			// The receipt's amount and currency are kept next to the conversion
+			assert.Equal(t, "100", expense.Amount.String())
+			assert.Equal(t, "EUR", expense.Currency)
+			assert.Equal(t, "USD", expense.BaseCurrency)
+			assert.Equal(t, tt.wantRate, expense.ExchangeRate.String())
+			assert.Equal(t, tt.wantBase, expense.BaseAmount.String())
+
+			require.Len(t, splits, 3)
+			total := decimal.Zero
+			for i, split := range splits {
+				assert.Equal(t, tt.wantSplits[i], split.Amount.StringFixed(2))
+				total = total.Add(split.Amount)
+			}
+			assert.True(t, total.Equal(expense.BaseAmount))
+			// The payer is credited the converted total
+			paid := mock.MatchedBy(func(delta decimal.Decimal) bool { return delta.Equal(expense.BaseAmount.Neg()) })
+			balanceRepo.AssertCalled(t, "UpdateBalance", mock.Anything, mock.Anything, group.ID, alice.ID, paid, "USD")
+		})
+	}
+}
+
+func TestExpenseService_CreateExpense_RejectsInvalidExchangeRate(t *testing.T) {
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip", DefaultCurrency: "USD"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+
+	for _, rate := range []string{"0", "-1.5"} {
+		t.Run(rate, func(t *testing.T) {
+			groupRepo := new(MockGroupRepositoryES)
+			groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			db := new(MockDBES)
+
+			es := service.NewExpenseService(new(MockExpenseRepositoryES), groupRepo, new(MockUserRepositoryES), new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+			expense, err := es.CreateExpense(context.Background(), &models.CreateExpenseRequest{
+				GroupUUID:    group.UUID,
+				PaidByUUID:   payer.UUID,
+				Amount:       decimal.NewFromInt(100),
+				Currency:     "EUR",
+				Description:  "Museum",
+				SplitType:    models.SplitTypeEqual,
+				ExchangeRate: decimalPtr(rate),
+			})
+			assert.Nil(t, expense)
+			appErr, ok := err.(*errors.AppError)
+			if assert.True(t, ok) {
+				assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+				assert.Equal(t, "exchange_rate", appErr.Details["field"])
+			}
+			db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func decimalPtr(value string) *decimal.Decimal {
+	d := decimal.RequireFromString(value)
+	return &d
+}
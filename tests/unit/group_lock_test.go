@@ -0,0 +1,151 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// MockGroupLockRepository is a mock implementation of GroupLockRepository
+type MockGroupLockRepository struct {
+	mock.Mock
+}
+
+func (m *MockGroupLockRepository) GetForUpdate(ctx context.Context, tx *database.Tx, groupID int64) (*models.GroupLock, error) {
+	args := m.Called(ctx, tx, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GroupLock), args.Error(1)
+}
+
+func (m *MockGroupLockRepository) GetActive(ctx context.Context, tx *database.Tx, groupID int64) (*models.GroupLock, error) {
+	args := m.Called(ctx, tx, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GroupLock), args.Error(1)
+}
+
+func (m *MockGroupLockRepository) Upsert(ctx context.Context, tx *database.Tx, lock *models.GroupLock) error {
+	args := m.Called(ctx, tx, lock)
+	return args.Error(0)
+}
+
+func (m *MockGroupLockRepository) Delete(ctx context.Context, tx *database.Tx, groupID int64, holder string) error {
+	args := m.Called(ctx, tx, groupID, holder)
+	return args.Error(0)
+}
+
+// newUnlockedGroupLockRepo returns a lock repository reporting every group as unlocked
+func newUnlockedGroupLockRepo() *MockGroupLockRepository {
+	lockRepo := new(MockGroupLockRepository)
+	lockRepo.On("GetActive", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	return lockRepo
+}
+
+func TestGroupLockService_Acquire_ConflictWithActiveLock(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	lockRepo := new(MockGroupLockRepository)
+	db := new(MockDBES)
+
+	held := &models.GroupLock{GroupID: 10, Holder: "other", Purpose: models.GroupLockSettleUp, ExpiresAt: time.Now().Add(20 * time.Second)}
+	lockRepo.On("GetForUpdate", mock.Anything, mock.Anything, int64(10)).Return(held, nil)
+	db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	ls := service.NewGroupLockService(lockRepo, db, 30*time.Second, logger)
+
+	lock, err := ls.Acquire(ctx, 10, models.GroupLockReconcile)
+	assert.Nil(t, lock)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeGroupLocked, appErr.Code)
+	assert.Equal(t, http.StatusLocked, appErr.Status)
+	assert.Equal(t, "settle_up", appErr.Details["purpose"])
+	assert.Equal(t, held.ExpiresAt.UTC().Format(time.RFC3339), appErr.Details["expires_at"])
+	lockRepo.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGroupLockService_Acquire_TakesOverExpiredLock(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	lockRepo := new(MockGroupLockRepository)
+	db := new(MockDBES)
+
+	expired := &models.GroupLock{GroupID: 10, Holder: "crashed", Purpose: models.GroupLockSettleUp, ExpiresAt: time.Now().Add(-time.Second)}
+	lockRepo.On("GetForUpdate", mock.Anything, mock.Anything, int64(10)).Return(expired, nil)
+	lockRepo.On("Upsert", mock.Anything, mock.Anything, mock.MatchedBy(func(l *models.GroupLock) bool {
+		return l.GroupID == 10 && l.Holder != "crashed" && l.Purpose == models.GroupLockReconcile
+	})).Return(nil)
+	lockRepo.On("Delete", mock.Anything, mock.Anything, int64(10), mock.Anything).Return(nil)
+	db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	ls := service.NewGroupLockService(lockRepo, db, 30*time.Second, logger)
+
+	lock, err := ls.Acquire(ctx, 10, models.GroupLockReconcile)
+	assert.NoError(t, err)
+	assert.True(t, lock.IsActive(time.Now()))
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), lock.ExpiresAt, 2*time.Second)
+
+	assert.NoError(t, ls.Release(ctx, lock))
+	lockRepo.AssertCalled(t, "Delete", mock.Anything, mock.Anything, int64(10), lock.Holder)
+}
+
+func TestExpenseService_CreateExpense_RejectedWhileGroupLocked(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	lockRepo := new(MockGroupLockRepository)
+	db := new(MockDBES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, payer.ID).Return(true, nil)
+	lockRepo.On("GetActive", mock.Anything, mock.Anything, group.ID).Return(&models.GroupLock{
+		GroupID:   group.ID,
+		Purpose:   models.GroupLockSettleUp,
+		ExpiresAt: time.Now().Add(10 * time.Second),
+	}, nil)
+	db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, new(MockBalanceRepositoryES), newBalanceHistoryRepo(), lockRepo, db, nil, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	expense, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(30),
+		Currency:    "USD",
+		Description: "Taxi",
+		SplitType:   models.SplitTypeEqual,
+		Splits:      []models.CreateExpenseSplitRequest{{UserUUID: payer.UUID}},
+	})
+
+	assert.Nil(t, expense)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeGroupLocked, appErr.Code)
+	assert.Equal(t, "settle_up", appErr.Details["purpose"])
+	expenseRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,807 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestGroupService_CreateGroup_PendingCreator(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	db := new(MockDBES)
+
+	creator := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Email: "alice@example.com", IsPending: true}
+	userRepo.On("GetByUUID", mock.Anything, creator.UUID).Return(creator, nil)
+
+	gs := service.NewGroupService(groupRepo, userRepo, nil, nil, db, 50, events.NopEmitter{}, logger)
+
+	group, err := gs.CreateGroup(ctx, &models.CreateGroupRequest{Name: "Trip"}, creator.UUID)
+	assert.Nil(t, group)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodePendingUser, appErr.Code)
+	assert.Equal(t, http.StatusConflict, appErr.Status)
+	assert.Equal(t, "alice@example.com", appErr.Details["email"])
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+	groupRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGroupService_BootstrapGroup_MixedMembers(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	db := new(MockDBES)
+
+	creator := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice", Email: "alice@example.com"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob", Email: "bob@example.com"}
+
+	userRepo.On("GetByEmail", mock.Anything, "alice@example.com").Return(creator, nil)
+	userRepo.On("GetByEmail", mock.Anything, "bob@example.com").Return(bob, nil)
+	userRepo.On("GetByEmail", mock.Anything, "carol@example.com").Return(nil, errors.NewNotFoundError("User"))
+	userRepo.On("Create", mock.Anything, mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		return u.Email == "carol@example.com" && u.IsPending
+	})).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.User).ID = 3
+	}).Return(nil)
+	groupRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Group")).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Group).ID = 10
+	}).Return(nil)
+	groupRepo.On("AddMember", mock.Anything, mock.Anything, int64(10), mock.AnythingOfType("int64"), mock.Anything).Return(nil)
+	db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	gs := service.NewGroupService(groupRepo, userRepo, nil, nil, db, 50, events.NopEmitter{}, logger)
+
+	result, err := gs.BootstrapGroup(ctx, &models.BootstrapGroupRequest{
+		Name:         "Trip",
+		CreatorEmail: "alice@example.com",
+		Members: []models.BootstrapMemberRequest{
+			{Name: "Bob", Email: "bob@example.com"},
+			{Name: "Carol", Email: "carol@example.com"},
+			{Name: "Alice", Email: "Alice@Example.com"},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "UTC", result.Group.Timezone)
+	assert.Equal(t, "USD", result.Group.DefaultCurrency)
+	assert.Len(t, result.Group.Members, 3)
+	assert.Equal(t, models.BootstrapMemberExisting, result.Members[0].Status)
+	assert.Equal(t, models.BootstrapMemberExisting, result.Members[1].Status)
+	assert.Equal(t, models.BootstrapMemberCreated, result.Members[2].Status)
+	assert.True(t, result.Members[2].User.IsPending)
+	groupRepo.AssertNumberOfCalls(t, "AddMember", 3)
+	groupRepo.AssertCalled(t, "AddMember", mock.Anything, mock.Anything, int64(10), creator.ID, models.GroupRoleAdmin)
+	groupRepo.AssertCalled(t, "AddMember", mock.Anything, mock.Anything, int64(10), bob.ID, models.GroupRoleMember)
+	userRepo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestGroupService_BootstrapGroup_DuplicateEmails(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	db := new(MockDBES)
+
+	creator := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice", Email: "alice@example.com"}
+	userRepo.On("GetByUUID", mock.Anything, creator.UUID).Return(creator, nil)
+
+	gs := service.NewGroupService(groupRepo, userRepo, nil, nil, db, 50, events.NopEmitter{}, logger)
+
+	result, err := gs.BootstrapGroup(ctx, &models.BootstrapGroupRequest{
+		Name:        "Trip",
+		CreatorUUID: creator.UUID,
+		Members: []models.BootstrapMemberRequest{
+			{Name: "Bob", Email: "bob@example.com"},
+			{Name: "Robert", Email: "BOB@example.com"},
+			{Name: "C", Email: "not-an-email"},
+		},
+	})
+
+	assert.Nil(t, result)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+	assert.Equal(t, "Duplicate of members[0].email", appErr.Details["members[1].email"])
+	assert.Contains(t, appErr.Details, "members[2].name")
+	assert.Contains(t, appErr.Details, "members[2].email")
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+	userRepo.AssertNotCalled(t, "GetByEmail", mock.Anything, mock.Anything)
+}
+
+func TestGroupService_UpdateGroup_Timezone(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	creator := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	groupRepo := new(MockGroupRepositoryES)
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip", Timezone: "UTC", CreatedBy: creator.ID}
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("Update", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Group")).Return(nil)
+	groupRepo.On("GetMembers", mock.Anything, group.ID).Return([]*models.User{creator}, nil)
+	groupRepo.On("GetMemberRole", mock.Anything, group.ID, creator.ID).Return(models.GroupRoleAdmin, nil)
+	userRepo := new(MockUserRepositoryES)
+	userRepo.On("GetByUUID", mock.Anything, creator.UUID).Return(creator, nil)
+
+	gs := service.NewGroupService(groupRepo, userRepo, nil, nil, new(MockDBES), 50, events.NopEmitter{}, logger)
+
+	updated, err := gs.UpdateGroup(ctx, group.UUID, &models.UpdateGroupRequest{Timezone: "Asia/Kolkata", ActingUserUUID: creator.UUID})
+	assert.NoError(t, err)
+	assert.Equal(t, "Asia/Kolkata", updated.Timezone)
+	assert.Equal(t, "Trip", updated.Name)
+
+	_, err = gs.UpdateGroup(ctx, group.UUID, &models.UpdateGroupRequest{Timezone: "IST", ActingUserUUID: creator.UUID})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalid, appErr.Code)
+	groupRepo.AssertNumberOfCalls(t, "Update", 1)
+}
+
+func TestGroupService_UpdateGroup_DefaultCurrency(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	creator := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	groupRepo := new(MockGroupRepositoryES)
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip", Timezone: "UTC", DefaultCurrency: "USD", CreatedBy: creator.ID}
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("Update", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Group")).Return(nil)
+	groupRepo.On("GetMembers", mock.Anything, group.ID).Return([]*models.User{creator}, nil)
+	groupRepo.On("GetMemberRole", mock.Anything, group.ID, creator.ID).Return(models.GroupRoleAdmin, nil)
+	userRepo := new(MockUserRepositoryES)
+	userRepo.On("GetByUUID", mock.Anything, creator.UUID).Return(creator, nil)
+
+	gs := service.NewGroupService(groupRepo, userRepo, nil, nil, new(MockDBES), 50, events.NopEmitter{}, logger)
+
+	updated, err := gs.UpdateGroup(ctx, group.UUID, &models.UpdateGroupRequest{DefaultCurrency: "eur", ActingUserUUID: creator.UUID})
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", updated.DefaultCurrency)
+	assert.Equal(t, "UTC", updated.Timezone)
+
+	_, err = gs.UpdateGroup(ctx, group.UUID, &models.UpdateGroupRequest{DefaultCurrency: "XYZ", ActingUserUUID: creator.UUID})
+	assert.Error(t, err)
+	groupRepo.AssertNumberOfCalls(t, "Update", 1)
+}
+
+func TestGroupService_UpdateGroup_Rename(t *testing.T) {
+	ctx := context.Background()
+
+	creator := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	member := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	outsider := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	newService := func() (service.GroupService, *MockGroupRepositoryES) {
+		group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trpi", Description: "Goa, March", Timezone: "UTC", CreatedBy: creator.ID}
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, creator.ID).Return(models.GroupRoleAdmin, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, member.ID).Return(models.GroupRoleMember, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, outsider.ID).Return(models.GroupRole(""), errors.NewNotFoundError("Group membership"))
+		groupRepo.On("Update", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Group")).Return(nil)
+		groupRepo.On("GetMembers", mock.Anything, group.ID).Return([]*models.User{creator, member}, nil)
+		userRepo := new(MockUserRepositoryES)
+		for _, user := range []*models.User{creator, member, outsider} {
+			userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+		}
+		return service.NewGroupService(groupRepo, userRepo, nil, nil, new(MockDBES), 50, events.NopEmitter{}, zaptest.NewLogger(t)), groupRepo
+	}
+
+	t.Run("admin renames and keeps the description", func(t *testing.T) {
+		gs, groupRepo := newService()
+		updated, err := gs.UpdateGroup(ctx, "11111111-1111-4111-8111-111111111111", &models.UpdateGroupRequest{Name: "Trip", ActingUserUUID: creator.UUID})
+		assert.NoError(t, err)
+		assert.Equal(t, "Trip", updated.Name)
+		assert.Equal(t, "Goa, March", updated.Description)
+		assert.Len(t, updated.Members, 2)
+		groupRepo.AssertCalled(t, "Update", mock.Anything, mock.Anything, mock.MatchedBy(func(group *models.Group) bool {
+			return group.Name == "Trip" && group.Description == "Goa, March"
+		}))
+	})
+
+	for name, caller := range map[string]*models.User{"member": member, "outsider": outsider} {
+		t.Run(name+" is forbidden", func(t *testing.T) {
+			gs, groupRepo := newService()
+			_, err := gs.UpdateGroup(ctx, "11111111-1111-4111-8111-111111111111", &models.UpdateGroupRequest{Name: "Mine now", ActingUserUUID: caller.UUID})
+			appErr, ok := err.(*errors.AppError)
+			assert.True(t, ok)
+			assert.Equal(t, errors.ErrCodeForbidden, appErr.Code)
+			groupRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("invalid name", func(t *testing.T) {
+		gs, groupRepo := newService()
+		_, err := gs.UpdateGroup(ctx, "11111111-1111-4111-8111-111111111111", &models.UpdateGroupRequest{Name: strings.Repeat("x", 300), ActingUserUUID: creator.UUID})
+		assert.Error(t, err)
+		groupRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("missing caller", func(t *testing.T) {
+		gs, _ := newService()
+		_, err := gs.UpdateGroup(ctx, "11111111-1111-4111-8111-111111111111", &models.UpdateGroupRequest{Name: "Trip"})
+		assert.Error(t, err)
+	})
+}
+
+func TestGroupService_ArchiveGroup(t *testing.T) {
+	ctx := context.Background()
+
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	groupUUID := "11111111-1111-4111-8111-111111111111"
+
+	newService := func(balances []*models.Balance) (service.GroupService, *MockGroupRepositoryES) {
+		group := &models.Group{ID: 10, UUID: groupUUID, Name: "Trip"}
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, groupUUID).Return(group, nil)
+		groupRepo.On("GetMembers", mock.Anything, group.ID).Return([]*models.User{alice, bob}, nil)
+		groupRepo.On("Archive", mock.Anything, mock.Anything, group.ID).Return(true, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, alice.ID).Return(models.GroupRoleAdmin, nil)
+		userRepo := new(MockUserRepositoryES)
+		userRepo.On("GetByUUID", mock.Anything, alice.UUID).Return(alice, nil)
+		balanceRepo := new(MockBalanceRepository2)
+		balanceRepo.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return(balances, nil)
+		return service.NewGroupService(groupRepo, userRepo, nil, balanceRepo, new(MockDBES), 50, events.NopEmitter{}, zaptest.NewLogger(t)), groupRepo
+	}
+
+	t.Run("refuses while members have unsettled balances", func(t *testing.T) {
+		gs, groupRepo := newService([]*models.Balance{
+			{UserID: alice.ID, User: alice, Currency: "USD", Balance: decimal.NewFromInt(-30)},
+			{UserID: bob.ID, User: bob, Currency: "USD", Balance: decimal.NewFromInt(30)},
+			{UserID: bob.ID, User: bob, Currency: "EUR", Balance: decimal.Zero},
+		})
+
+		_, err := gs.ArchiveGroup(ctx, models.GroupUUID(groupUUID), models.UserUUID(alice.UUID))
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+		assert.Contains(t, appErr.Message, "Alice")
+		assert.Contains(t, appErr.Message, "Bob")
+		assert.Equal(t, "-30.00 USD", appErr.Details[alice.UUID])
+		assert.Equal(t, "30.00 USD", appErr.Details[bob.UUID])
+		groupRepo.AssertNotCalled(t, "Archive", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("archives a settled group", func(t *testing.T) {
+		gs, groupRepo := newService([]*models.Balance{
+			{UserID: alice.ID, User: alice, Currency: "USD", Balance: decimal.Zero},
+			{UserID: bob.ID, User: bob, Currency: "USD", Balance: decimal.Zero},
+		})
+
+		group, err := gs.ArchiveGroup(ctx, models.GroupUUID(groupUUID), models.UserUUID(alice.UUID))
+		assert.NoError(t, err)
+		assert.Len(t, group.Members, 2)
+		groupRepo.AssertCalled(t, "Archive", mock.Anything, mock.Anything, int64(10))
+	})
+}
+
+func TestGroupService_UnarchiveGroup_NotArchived(t *testing.T) {
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	admin := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	groupRepo := new(MockGroupRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("GetMemberRole", mock.Anything, group.ID, admin.ID).Return(models.GroupRoleAdmin, nil)
+	groupRepo.On("Unarchive", mock.Anything, mock.Anything, group.ID).Return(false, nil)
+	userRepo := new(MockUserRepositoryES)
+	userRepo.On("GetByUUID", mock.Anything, admin.UUID).Return(admin, nil)
+
+	gs := service.NewGroupService(groupRepo, userRepo, nil, nil, new(MockDBES), 50, events.NopEmitter{}, zaptest.NewLogger(t))
+
+	_, err := gs.UnarchiveGroup(context.Background(), models.GroupUUID(group.UUID), models.UserUUID(admin.UUID))
+	assert.Error(t, err)
+}
+
+func TestArchivedGroup_RejectsNewExpensesAndSettlements(t *testing.T) {
+	ctx := context.Background()
+
+	archivedAt := time.Now()
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", ArchivedAt: &archivedAt}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	other := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	assertArchived := func(t *testing.T, err error) {
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeGroupArchived, appErr.Code)
+			assert.Equal(t, http.StatusConflict, appErr.Status)
+		}
+	}
+
+	t.Run("expense", func(t *testing.T) {
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		expenseRepo := new(MockExpenseRepositoryES)
+
+		es := service.NewExpenseService(expenseRepo, groupRepo, new(MockUserRepositoryES), new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+		_, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+			GroupUUID:   group.UUID,
+			PaidByUUID:  payer.UUID,
+			Amount:      decimal.NewFromInt(60),
+			Currency:    "USD",
+			Description: "Taxi",
+			SplitType:   models.SplitTypeEqual,
+			Splits:      []models.CreateExpenseSplitRequest{{UserUUID: payer.UUID}, {UserUUID: other.UUID}},
+		})
+		assertArchived(t, err)
+		expenseRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("settlement", func(t *testing.T) {
+		groupRepo := new(MockGroupRepository2)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		settlementRepo := new(MockSettlementRepository)
+
+		s := service.NewSettlementService(settlementRepo, groupRepo, new(MockUserRepository2), new(MockBalanceRepository2), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB2), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+		_, err := s.CreateSettlement(ctx, &models.CreateSettlementRequest{
+			GroupUUID:    models.GroupUUID(group.UUID),
+			FromUserUUID: models.UserUUID(payer.UUID),
+			ToUserUUID:   models.UserUUID(other.UUID),
+			Amount:       decimal.NewFromInt(30),
+			Currency:     "USD",
+		})
+		assertArchived(t, err)
+		settlementRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGroupService_RemoveMember_RequiresSettledBalance(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	newService := func(balances []*models.Balance) (service.GroupService, *MockGroupRepositoryES) {
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		groupRepo.On("RemoveMember", mock.Anything, mock.Anything, group.ID, bob.ID).Return(nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, alice.ID).Return(models.GroupRoleAdmin, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, bob.ID).Return(models.GroupRoleMember, nil)
+		userRepo := new(MockUserRepositoryES)
+		userRepo.On("GetByUUID", mock.Anything, alice.UUID).Return(alice, nil)
+		userRepo.On("GetByUUID", mock.Anything, bob.UUID).Return(bob, nil)
+		balanceRepo := new(MockBalanceRepository2)
+		balanceRepo.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return(balances, nil)
+		db := new(MockDBES)
+		db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+		return service.NewGroupService(groupRepo, userRepo, nil, balanceRepo, db, 50, events.NopEmitter{}, zaptest.NewLogger(t)), groupRepo
+	}
+
+	t.Run("refuses while the member owes money", func(t *testing.T) {
+		gs, groupRepo := newService([]*models.Balance{
+			{UserID: alice.ID, Currency: "USD", Balance: decimal.NewFromInt(-30)},
+			{UserID: bob.ID, Currency: "USD", Balance: decimal.NewFromInt(30)},
+			{UserID: bob.ID, Currency: "EUR", Balance: decimal.Zero},
+		})
+
+		err := gs.RemoveMember(ctx, models.GroupUUID(group.UUID), models.UserUUID(bob.UUID), models.UserUUID(alice.UUID))
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+			assert.Contains(t, appErr.Message, "30.00 USD")
+			assert.NotContains(t, appErr.Message, "EUR")
+			assert.Equal(t, "30.00", appErr.Details["USD"])
+		}
+		groupRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("removes a settled member", func(t *testing.T) {
+		gs, groupRepo := newService([]*models.Balance{
+			{UserID: alice.ID, Currency: "USD", Balance: decimal.NewFromInt(-30)},
+			{UserID: bob.ID, Currency: "USD", Balance: decimal.Zero},
+		})
+
+		err := gs.RemoveMember(ctx, models.GroupUUID(group.UUID), models.UserUUID(bob.UUID), models.UserUUID(alice.UUID))
+		assert.NoError(t, err)
+		groupRepo.AssertCalled(t, "RemoveMember", mock.Anything, mock.Anything, group.ID, bob.ID)
+	})
+}
+
+func TestGroupService_GetGroupMembers_WithBalances(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", DefaultCurrency: "EUR"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	joined := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	groupRepo := new(MockGroupRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("GetMemberships", mock.Anything, group.ID).Return([]*models.GroupMember{
+		{GroupID: group.ID, UserID: alice.ID, User: alice, Role: models.GroupRoleAdmin, JoinedAt: joined},
+		{GroupID: group.ID, UserID: bob.ID, User: bob, Role: models.GroupRoleMember, JoinedAt: joined.Add(time.Hour)},
+	}, nil)
+	balanceRepo := new(MockBalanceRepositoryES)
+	balanceRepo.On("GetGroupBalances", mock.Anything, group.ID, "EUR").Return([]*models.Balance{
+		{GroupID: group.ID, UserID: alice.ID, Currency: "EUR", Balance: decimal.NewFromInt(-25)},
+	}, nil)
+
+	gs := service.NewGroupService(groupRepo, new(MockUserRepositoryES), nil, balanceRepo, new(MockDBES), 50, events.NopEmitter{}, zaptest.NewLogger(t))
+
+	members, err := gs.GetGroupMembers(ctx, group.UUID)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 2) {
+		assert.Equal(t, alice, members[0].User)
+		assert.Equal(t, models.GroupRoleAdmin, members[0].Role)
+		assert.Equal(t, joined, members[0].JoinedAt)
+		assert.True(t, members[0].Balance.Equal(decimal.NewFromInt(-25)))
+		assert.Equal(t, "EUR", members[0].Currency)
+		// Bob has no balance row yet
+		assert.True(t, members[1].Balance.IsZero())
+		assert.Equal(t, "EUR", members[1].Currency)
+	}
+	balanceRepo.AssertNumberOfCalls(t, "GetGroupBalances", 1)
+}
+
+func TestGroupService_GetGroupSummary(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	earlier := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	latest := time.Date(2026, 7, 9, 18, 30, 0, 0, time.UTC)
+
+	groupRepo := new(MockGroupRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	groupRepo.On("CountMembers", mock.Anything, group.ID).Return(3, nil)
+	expenseRepo := new(MockExpenseRepositoryES)
+	expenseRepo.On("GetGroupCurrencyTotals", mock.Anything, group.ID).Return([]*models.ExpenseCurrencyTotal{
+		{Currency: "EUR", ExpenseCount: 2, TotalAmount: decimal.NewFromInt(80), LastExpenseAt: latest},
+		{Currency: "USD", ExpenseCount: 5, TotalAmount: decimal.NewFromInt(250), LastExpenseAt: earlier},
+	}, nil)
+	balanceRepo := new(MockBalanceRepository2)
+	balanceRepo.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{
+		{GroupID: group.ID, UserID: alice.ID, User: alice, Currency: "USD", Balance: decimal.NewFromInt(-40)},
+		{GroupID: group.ID, UserID: bob.ID, User: bob, Currency: "USD", Balance: decimal.NewFromInt(40)},
+	}, nil)
+
+	gs := service.NewGroupService(groupRepo, new(MockUserRepositoryES), expenseRepo, balanceRepo, new(MockDBES), 50, events.NopEmitter{}, zaptest.NewLogger(t))
+
+	summary, err := gs.GetGroupSummary(ctx, group.UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, group, summary.Group)
+	assert.Equal(t, 3, summary.MemberCount)
+	assert.Equal(t, 7, summary.ExpenseCount)
+	assert.Len(t, summary.Totals, 2)
+	if assert.NotNil(t, summary.LastActivity) {
+		assert.True(t, summary.LastActivity.Equal(latest))
+	}
+	if assert.Len(t, summary.Balances, 2) {
+		assert.Equal(t, alice, summary.Balances[0].User)
+		assert.True(t, summary.Balances[1].Balance.Equal(decimal.NewFromInt(40)))
+	}
+
+	t.Run("no expenses yet", func(t *testing.T) {
+		expenseRepo := new(MockExpenseRepositoryES)
+		expenseRepo.On("GetGroupCurrencyTotals", mock.Anything, group.ID).Return([]*models.ExpenseCurrencyTotal{}, nil)
+		balanceRepo := new(MockBalanceRepository2)
+		balanceRepo.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{}, nil)
+
+		gs := service.NewGroupService(groupRepo, new(MockUserRepositoryES), expenseRepo, balanceRepo, new(MockDBES), 50, events.NopEmitter{}, zaptest.NewLogger(t))
+
+		summary, err := gs.GetGroupSummary(ctx, group.UUID)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, summary.ExpenseCount)
+		assert.Nil(t, summary.LastActivity)
+		assert.Empty(t, summary.Balances)
+	})
+}
+
+func TestGroupService_AddMembers(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+	unknownUUID := "dddddddd-dddd-4ddd-8ddd-dddddddddddd"
+
+	newService := func() (service.GroupService, *MockGroupRepositoryES) {
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		groupRepo.On("GetMembers", mock.Anything, group.ID).Return([]*models.User{alice}, nil)
+		groupRepo.On("AddMember", mock.Anything, mock.Anything, group.ID, mock.Anything, models.GroupRoleMember).Return(nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, alice.ID).Return(models.GroupRoleAdmin, nil)
+		userRepo := new(MockUserRepositoryES)
+		for _, user := range []*models.User{alice, bob, carol} {
+			userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+		}
+		userRepo.On("GetByUUID", mock.Anything, unknownUUID).Return(nil, errors.NewNotFoundError("User"))
+		db := new(MockDBES)
+		db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+		return service.NewGroupService(groupRepo, userRepo, nil, nil, db, 50, events.NopEmitter{}, zaptest.NewLogger(t)), groupRepo
+	}
+
+	t.Run("adds new users and skips existing members", func(t *testing.T) {
+		gs, groupRepo := newService()
+
+		result, err := gs.AddMembers(ctx, group.UUID, &models.AddMembersRequest{UserUUIDs: []string{alice.UUID, bob.UUID, carol.UUID}, ActingUserUUID: alice.UUID})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.AddedCount)
+		if assert.Len(t, result.Members, 3) {
+			assert.Equal(t, models.AddMemberAlreadyMember, result.Members[0].Status)
+			assert.Equal(t, models.AddMemberAdded, result.Members[1].Status)
+			assert.Equal(t, models.AddMemberAdded, result.Members[2].Status)
+		}
+		groupRepo.AssertNumberOfCalls(t, "AddMember", 2)
+		groupRepo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything, group.ID, alice.ID, mock.Anything)
+	})
+
+	t.Run("rejects the whole batch when an entry is bad", func(t *testing.T) {
+		gs, groupRepo := newService()
+
+		_, err := gs.AddMembers(ctx, group.UUID, &models.AddMembersRequest{UserUUIDs: []string{bob.UUID, "not-a-uuid", unknownUUID, bob.UUID}, ActingUserUUID: alice.UUID})
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+			assert.Len(t, appErr.Details, 3)
+			assert.Contains(t, appErr.Details, "user_uuids[1]")
+			assert.Equal(t, "User not found", appErr.Details["user_uuids[2]"])
+			assert.Equal(t, "Duplicate of user_uuids[0]", appErr.Details["user_uuids[3]"])
+		}
+		groupRepo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("caps the batch size", func(t *testing.T) {
+		gs, _ := newService()
+
+		uuids := make([]string, 51)
+		for i := range uuids {
+			uuids[i] = bob.UUID
+		}
+		_, err := gs.AddMembers(ctx, group.UUID, &models.AddMembersRequest{UserUUIDs: uuids, ActingUserUUID: alice.UUID})
+		assert.Error(t, err)
+	})
+}
+
+func TestGroupService_MemberRoles(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	admin := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	member := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	newcomer := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	newService := func(admins int) (service.GroupService, *MockGroupRepositoryES) {
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, admin.ID).Return(models.GroupRoleAdmin, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, member.ID).Return(models.GroupRoleMember, nil)
+		groupRepo.On("CountAdminsForUpdate", mock.Anything, mock.Anything, group.ID).Return(admins, nil)
+		groupRepo.On("SetMemberRole", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything).Return(nil)
+		groupRepo.On("RemoveMember", mock.Anything, mock.Anything, group.ID, mock.Anything).Return(nil)
+		userRepo := new(MockUserRepositoryES)
+		for _, user := range []*models.User{admin, member, newcomer} {
+			userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+		}
+		balanceRepo := new(MockBalanceRepository2)
+		balanceRepo.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{}, nil)
+		db := new(MockDBES)
+		db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+		return service.NewGroupService(groupRepo, userRepo, nil, balanceRepo, db, 50, events.NopEmitter{}, zaptest.NewLogger(t)), groupRepo
+	}
+
+	assertCode := func(t *testing.T, err error, code string) {
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, code, appErr.Code)
+		}
+	}
+
+	t.Run("admin promotes a member", func(t *testing.T) {
+		gs, groupRepo := newService(1)
+
+		promoted, err := gs.UpdateMemberRole(ctx, models.GroupUUID(group.UUID), models.UserUUID(member.UUID),
+			&models.UpdateMemberRoleRequest{Role: models.GroupRoleAdmin, ActingUserUUID: admin.UUID})
+		assert.NoError(t, err)
+		assert.Equal(t, models.GroupRoleAdmin, promoted.Role)
+		groupRepo.AssertCalled(t, "SetMemberRole", mock.Anything, mock.Anything, group.ID, member.ID, models.GroupRoleAdmin)
+	})
+
+	t.Run("member cannot change roles", func(t *testing.T) {
+		gs, groupRepo := newService(1)
+
+		_, err := gs.UpdateMemberRole(ctx, models.GroupUUID(group.UUID), models.UserUUID(member.UUID),
+			&models.UpdateMemberRoleRequest{Role: models.GroupRoleAdmin, ActingUserUUID: member.UUID})
+		assertCode(t, err, errors.ErrCodeForbidden)
+		groupRepo.AssertNotCalled(t, "SetMemberRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		gs, _ := newService(1)
+
+		_, err := gs.UpdateMemberRole(ctx, models.GroupUUID(group.UUID), models.UserUUID(member.UUID),
+			&models.UpdateMemberRoleRequest{Role: "owner", ActingUserUUID: admin.UUID})
+		assertCode(t, err, errors.ErrCodeInvalid)
+	})
+
+	t.Run("last admin cannot step down", func(t *testing.T) {
+		gs, groupRepo := newService(1)
+
+		_, err := gs.UpdateMemberRole(ctx, models.GroupUUID(group.UUID), models.UserUUID(admin.UUID),
+			&models.UpdateMemberRoleRequest{Role: models.GroupRoleMember, ActingUserUUID: admin.UUID})
+		assertCode(t, err, errors.ErrCodeValidation)
+		groupRepo.AssertNotCalled(t, "SetMemberRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("last admin cannot be removed", func(t *testing.T) {
+		gs, groupRepo := newService(1)
+
+		err := gs.RemoveMember(ctx, models.GroupUUID(group.UUID), models.UserUUID(admin.UUID), models.UserUUID(admin.UUID))
+		assertCode(t, err, errors.ErrCodeValidation)
+		groupRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("admin can leave when another admin remains", func(t *testing.T) {
+		gs, groupRepo := newService(2)
+
+		err := gs.RemoveMember(ctx, models.GroupUUID(group.UUID), models.UserUUID(admin.UUID), models.UserUUID(admin.UUID))
+		assert.NoError(t, err)
+		groupRepo.AssertCalled(t, "RemoveMember", mock.Anything, mock.Anything, group.ID, admin.ID)
+	})
+
+	t.Run("member cannot remove or add members", func(t *testing.T) {
+		gs, groupRepo := newService(1)
+
+		err := gs.RemoveMember(ctx, models.GroupUUID(group.UUID), models.UserUUID(admin.UUID), models.UserUUID(member.UUID))
+		assertCode(t, err, errors.ErrCodeForbidden)
+
+		err = gs.AddMember(ctx, group.UUID, &models.AddMemberRequest{UserUUID: newcomer.UUID, ActingUserUUID: member.UUID})
+		assertCode(t, err, errors.ErrCodeForbidden)
+		groupRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGroupService_LeaveGroup(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	admin := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	member := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	outsider := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	newService := func(balances []*models.Balance) (service.GroupService, *MockGroupRepositoryES) {
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, admin.ID).Return(models.GroupRoleAdmin, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, member.ID).Return(models.GroupRoleMember, nil)
+		groupRepo.On("GetMemberRole", mock.Anything, group.ID, outsider.ID).Return(models.GroupRole(""), errors.NewNotFoundError("Group membership"))
+		groupRepo.On("CountAdminsForUpdate", mock.Anything, mock.Anything, group.ID).Return(1, nil)
+		groupRepo.On("RemoveMember", mock.Anything, mock.Anything, group.ID, mock.Anything).Return(nil)
+		userRepo := new(MockUserRepositoryES)
+		for _, user := range []*models.User{admin, member, outsider} {
+			userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+		}
+		balanceRepo := new(MockBalanceRepository2)
+		balanceRepo.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return(balances, nil)
+		db := new(MockDBES)
+		db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+		return service.NewGroupService(groupRepo, userRepo, nil, balanceRepo, db, 50, events.NopEmitter{}, zaptest.NewLogger(t)), groupRepo
+	}
+
+	t.Run("settled member leaves", func(t *testing.T) {
+		gs, groupRepo := newService([]*models.Balance{{UserID: member.ID, Currency: "USD", Balance: decimal.Zero}})
+
+		err := gs.LeaveGroup(ctx, models.GroupUUID(group.UUID), &models.LeaveGroupRequest{UserUUID: member.UUID})
+		assert.NoError(t, err)
+		groupRepo.AssertCalled(t, "RemoveMember", mock.Anything, mock.Anything, group.ID, member.ID)
+	})
+
+	t.Run("outstanding balance points at simplify-debts", func(t *testing.T) {
+		gs, groupRepo := newService([]*models.Balance{
+			{UserID: admin.ID, Currency: "USD", Balance: decimal.NewFromInt(-25)},
+			{UserID: member.ID, Currency: "USD", Balance: decimal.NewFromInt(25)},
+		})
+
+		err := gs.LeaveGroup(ctx, models.GroupUUID(group.UUID), &models.LeaveGroupRequest{UserUUID: member.UUID})
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+			assert.Contains(t, appErr.Message, "25.00 USD")
+			assert.Equal(t, "25.00", appErr.Details["USD"])
+			assert.Equal(t, "/api/v1/groups/"+group.UUID+"/simplify-debts", appErr.Details["simplify_debts"])
+		}
+		groupRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("last admin must hand over first", func(t *testing.T) {
+		gs, groupRepo := newService([]*models.Balance{})
+
+		err := gs.LeaveGroup(ctx, models.GroupUUID(group.UUID), &models.LeaveGroupRequest{UserUUID: admin.UUID})
+		assert.Error(t, err)
+		groupRepo.AssertNotCalled(t, "RemoveMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("non-member", func(t *testing.T) {
+		gs, _ := newService([]*models.Balance{})
+
+		err := gs.LeaveGroup(ctx, models.GroupUUID(group.UUID), &models.LeaveGroupRequest{UserUUID: outsider.UUID})
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+		}
+	})
+}
+
+func TestGroupService_TransferOwnership(t *testing.T) {
+	ctx := context.Background()
+
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"}
+
+	newService := func() (service.GroupService, *MockGroupRepositoryES, *models.Group) {
+		group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip", CreatedBy: alice.ID}
+		groupRepo := new(MockGroupRepositoryES)
+		groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, bob.ID).Return(true, nil)
+		groupRepo.On("IsMember", mock.Anything, group.ID, carol.ID).Return(false, nil)
+		groupRepo.On("SetOwner", mock.Anything, mock.Anything, group.ID, mock.Anything).Return(nil)
+		groupRepo.On("SetMemberRole", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything).Return(nil)
+		groupRepo.On("GetMembers", mock.Anything, group.ID).Return([]*models.User{alice, bob}, nil)
+		userRepo := new(MockUserRepositoryES)
+		for _, u := range []*models.User{alice, bob, carol} {
+			userRepo.On("GetByUUID", mock.Anything, u.UUID).Return(u, nil)
+		}
+		db := new(MockDBES)
+		db.On("WithTransaction", mock.Anything, mock.Anything).Return(nil)
+		return service.NewGroupService(groupRepo, userRepo, nil, nil, db, 50, events.NopEmitter{}, zaptest.NewLogger(t)), groupRepo, group
+	}
+
+	t.Run("owner hands the group to a member", func(t *testing.T) {
+		gs, groupRepo, group := newService()
+
+		updated, err := gs.TransferOwnership(ctx, models.GroupUUID(group.UUID), &models.TransferOwnershipRequest{NewOwnerUUID: bob.UUID, ActingUserUUID: alice.UUID})
+		assert.NoError(t, err)
+		assert.Equal(t, bob.ID, updated.CreatedBy)
+		assert.Len(t, updated.Members, 2)
+		groupRepo.AssertCalled(t, "SetOwner", mock.Anything, mock.Anything, group.ID, bob.ID)
+		groupRepo.AssertCalled(t, "SetMemberRole", mock.Anything, mock.Anything, group.ID, bob.ID, models.GroupRoleAdmin)
+	})
+
+	t.Run("only the owner may transfer", func(t *testing.T) {
+		gs, groupRepo, group := newService()
+
+		_, err := gs.TransferOwnership(ctx, models.GroupUUID(group.UUID), &models.TransferOwnershipRequest{NewOwnerUUID: bob.UUID, ActingUserUUID: bob.UUID})
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, http.StatusForbidden, appErr.Status)
+		}
+		groupRepo.AssertNotCalled(t, "SetOwner", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("new owner must be a member", func(t *testing.T) {
+		gs, groupRepo, group := newService()
+
+		_, err := gs.TransferOwnership(ctx, models.GroupUUID(group.UUID), &models.TransferOwnershipRequest{NewOwnerUUID: carol.UUID, ActingUserUUID: alice.UUID})
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+			assert.Equal(t, "Carol is not a member of this group", appErr.Message)
+			assert.Equal(t, carol.UUID, appErr.Details["new_owner_uuid"])
+		}
+		groupRepo.AssertNotCalled(t, "SetOwner", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
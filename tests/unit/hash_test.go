@@ -0,0 +1,90 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"expense-split-tracker/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func jsonFingerprint(body string) utils.RequestFingerprint {
+	return utils.RequestFingerprint{
+		Method:      "POST",
+		Path:        "/api/v1/settlements",
+		ContentType: "application/json; charset=utf-8",
+		Body:        []byte(body),
+	}
+}
+
+func TestHashRequest_EquivalentJSONBodiesHashEqual(t *testing.T) {
+	base, err := utils.HashRequest(jsonFingerprint(`{"amount":10,"currency":"USD"}`))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(base, "v2:"))
+
+	equivalent := []string{
+		`{"currency":"USD","amount":10}`,
+		"{\n  \"amount\": 10,\n  \"currency\": \"USD\"\n}\n",
+		` { "currency" : "USD" , "amount" : 10 } `,
+	}
+	for _, body := range equivalent {
+		hash, err := utils.HashRequest(jsonFingerprint(body))
+		assert.NoError(t, err)
+		assert.Equal(t, base, hash, body)
+	}
+
+	different, err := utils.HashRequest(jsonFingerprint(`{"amount":11,"currency":"USD"}`))
+	assert.NoError(t, err)
+	assert.NotEqual(t, base, different)
+}
+
+func TestCanonicalizeJSON_PreservesDecimalText(t *testing.T) {
+	canonical, err := utils.CanonicalizeJSON([]byte(`{"splits":[{"amount":33.30}],"amount": 100.10000000000000001}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":100.10000000000000001,"splits":[{"amount":33.30}]}`, string(canonical))
+
+	// 10 and 10.0 are different textual amounts and stay distinct
+	a, _ := utils.HashRequest(jsonFingerprint(`{"amount":10}`))
+	b, _ := utils.HashRequest(jsonFingerprint(`{"amount":10.0}`))
+	assert.NotEqual(t, a, b)
+}
+
+func TestHashRequest_NonJSONHashesRawBytes(t *testing.T) {
+	req := utils.RequestFingerprint{Method: "POST", Path: "/api/v1/expenses", ContentType: "text/plain", Body: []byte(`{"a":1,"b":2}`)}
+	reordered := req
+	reordered.Body = []byte(`{"b":2,"a":1}`)
+
+	a, _ := utils.HashRequest(req)
+	b, _ := utils.HashRequest(reordered)
+	assert.NotEqual(t, a, b)
+}
+
+func TestRequestHashMatches_LegacyV1Hash(t *testing.T) {
+	req := jsonFingerprint(`{"amount":10,"currency":"USD"}`)
+
+	legacyData, _ := json.Marshal(map[string]interface{}{
+		"method": req.Method,
+		"path":   req.Path,
+		"query":  req.Query,
+		"body":   string(req.Body),
+	})
+	legacy := fmt.Sprintf("%x", sha256.Sum256(legacyData))
+
+	matches, err := utils.RequestHashMatches(legacy, req)
+	assert.NoError(t, err)
+	assert.True(t, matches)
+
+	// v1 hashes compare raw bytes, so a reordered body still mismatches them
+	matches, err = utils.RequestHashMatches(legacy, jsonFingerprint(`{"currency":"USD","amount":10}`))
+	assert.NoError(t, err)
+	assert.False(t, matches)
+
+	current, _ := utils.HashRequest(req)
+	matches, err = utils.RequestHashMatches(current, jsonFingerprint(`{"currency":"USD","amount":10}`))
+	assert.NoError(t, err)
+	assert.True(t, matches)
+}
@@ -0,0 +1,57 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"expense-split-tracker/internal/routes"
+	"expense-split-tracker/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+type fakeHealthChecker struct{ err error }
+
+func (f fakeHealthChecker) Health() error { return f.err }
+
+func getHealth(t *testing.T, dbErr error, path string) (int, map[string]interface{}) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.SetupRoutes(router, &service.Services{}, fakeHealthChecker{err: dbErr}, zaptest.NewLogger(t))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func TestHealth_DatabaseReachable(t *testing.T) {
+	status, body := getHealth(t, nil, "/health")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", body["status"])
+	assert.Equal(t, map[string]interface{}{"database": "ok"}, body["components"])
+}
+
+func TestHealth_DatabaseUnreachable(t *testing.T) {
+	dbErr := fmt.Errorf("database health check failed: %w", fmt.Errorf("dial tcp: connection refused"))
+
+	status, body := getHealth(t, dbErr, "/health")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "unavailable", body["status"])
+	assert.Equal(t, map[string]interface{}{"database": "unreachable"}, body["components"])
+
+	// Liveness does not depend on the database
+	status, body = getHealth(t, dbErr, "/health/live")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", body["status"])
+	assert.NotContains(t, body, "components")
+}
@@ -0,0 +1,297 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/auth"
+	"expense-split-tracker/internal/config"
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/middleware"
+	"expense-split-tracker/internal/repository"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+// memoryIdempotencyRepository mimics the unique (caller, key_value)
+// constraint on idempotency_keys
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*repository.IdempotencyRecord
+
+	cleanups int32
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{records: make(map[string]*repository.IdempotencyRecord)}
+}
+
+func (r *memoryIdempotencyRepository) Reserve(ctx context.Context, caller, key, requestHash string, expiresAt int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.records[caller+"|"+key]; exists {
+		return false, nil
+	}
+	r.records[caller+"|"+key] = &repository.IdempotencyRecord{Caller: caller, KeyValue: key, RequestHash: requestHash, Status: repository.IdempotencyStatusProcessing, ExpiresAt: expiresAt}
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, caller, key string, responseData, responseHeaders []byte, statusCode int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, exists := r.records[caller+"|"+key]; exists && record.Status == repository.IdempotencyStatusProcessing {
+		record.Status = repository.IdempotencyStatusCompleted
+		record.ResponseData = responseData
+		record.ResponseHeaders = responseHeaders
+		record.StatusCode = statusCode
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, caller, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, exists := r.records[caller+"|"+key]; exists && record.Status == repository.IdempotencyStatusProcessing {
+		delete(r.records, caller+"|"+key)
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) GetByKey(ctx context.Context, caller, key string) (*repository.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, exists := r.records[caller+"|"+key]
+	if !exists {
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpired(ctx context.Context, tx *database.Tx) error {
+	atomic.AddInt32(&r.cleanups, 1)
+	return nil
+}
+
+const testIdempotencyKey = "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+func newIdempotencyRouter(t *testing.T, wait time.Duration, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{Features: config.FeatureConfig{IdempotencyTTL: time.Hour, IdempotencyWait: wait}}
+	router := gin.New()
+	router.Use(middleware.NewIdempotencyMiddleware(newMemoryIdempotencyRepository(), cfg, zaptest.NewLogger(t)).Handle())
+	router.POST("/api/v1/expenses", handler)
+	return router
+}
+
+func postExpense(router *gin.Engine) *httptest.ResponseRecorder {
+	return postExpenseAs(router, nil, `{"amount":"10.00"}`)
+}
+
+// postExpenseAs sends the request on behalf of identity, or anonymously when nil
+func postExpenseAs(router *gin.Engine, identity *auth.Identity, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, testIdempotencyKey)
+	if identity != nil {
+		req = req.WithContext(auth.WithIdentity(req.Context(), identity))
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// postConcurrently sends two identical requests, the second once the first
+// is inside the handler
+func postConcurrently(router *gin.Engine, started <-chan struct{}) (first, second *httptest.ResponseRecorder) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		first = postExpense(router)
+	}()
+	go func() {
+		defer wg.Done()
+		<-started
+		second = postExpense(router)
+	}()
+	wg.Wait()
+	return first, second
+}
+
+func TestIdempotency_ConcurrentRequestReplaysResponse(t *testing.T) {
+	var executions int32
+	started := make(chan struct{})
+	router := newIdempotencyRouter(t, 2*time.Second, func(c *gin.Context) {
+		if atomic.AddInt32(&executions, 1) == 1 {
+			close(started)
+		}
+		time.Sleep(150 * time.Millisecond)
+		response.Created(c, gin.H{"uuid": "expense-1"})
+	})
+
+	first, second := postConcurrently(router, started)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&executions))
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get("X-Idempotent-Replayed"))
+}
+
+func TestIdempotency_ConcurrentRequestTimesOutWhileProcessing(t *testing.T) {
+	var executions int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := newIdempotencyRouter(t, 50*time.Millisecond, func(c *gin.Context) {
+		if atomic.AddInt32(&executions, 1) == 1 {
+			close(started)
+		}
+		<-release
+		response.Created(c, gin.H{"uuid": "expense-1"})
+	})
+
+	var second *httptest.ResponseRecorder
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-started
+		second = postExpense(router)
+		close(release)
+	}()
+	first := postExpense(router)
+	<-done
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&executions))
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusConflict, second.Code)
+	assert.Equal(t, "1", second.Header().Get(middleware.RetryAfterHeader))
+
+	var resp response.APIResponse
+	assert.NoError(t, json.Unmarshal(second.Body.Bytes(), &resp))
+	assert.Equal(t, errors.ErrCodeProcessing, resp.Error.Code)
+}
+
+func TestIdempotency_FailedRequestReleasesKey(t *testing.T) {
+	var executions int32
+	router := newIdempotencyRouter(t, time.Second, func(c *gin.Context) {
+		if atomic.AddInt32(&executions, 1) == 1 {
+			response.InternalError(c, "boom")
+			return
+		}
+		response.Created(c, gin.H{"uuid": "expense-1"})
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, postExpense(router).Code)
+	assert.Equal(t, http.StatusCreated, postExpense(router).Code)
+
+	replay := postExpense(router)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("X-Idempotent-Replayed"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&executions))
+}
+
+func TestIdempotency_CleanupRunsOnStartupAndStopsOnCancel(t *testing.T) {
+	repo := newMemoryIdempotencyRepository()
+	cfg := &config.Config{Features: config.FeatureConfig{IdempotencyCleanupInterval: time.Hour}}
+	m := middleware.NewIdempotencyMiddleware(repo, cfg, zaptest.NewLogger(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.CleanupExpiredKeys(ctx)
+	}()
+
+	// The first cleanup does not wait for the hourly tick
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&repo.cleanups) == 1 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cleanup did not stop after its context was cancelled")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&repo.cleanups))
+}
+
+func TestIdempotency_KeysAreScopedPerCaller(t *testing.T) {
+	alice := &auth.Identity{UserID: 1, UserUUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &auth.Identity{UserID: 2, UserUUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	var executions int32
+	router := newIdempotencyRouter(t, time.Second, func(c *gin.Context) {
+		n := atomic.AddInt32(&executions, 1)
+		response.Created(c, gin.H{"execution": n})
+	})
+
+	first := postExpenseAs(router, alice, `{"amount":"10.00"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	// Bob picked the same key for a different request: it runs on its own
+	// instead of replaying Alice's response or failing as a reused key
+	other := postExpenseAs(router, bob, `{"amount":"99.00"}`)
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Empty(t, other.Header().Get("X-Idempotent-Replayed"))
+	assert.NotEqual(t, first.Body.String(), other.Body.String())
+
+	replay := postExpenseAs(router, alice, `{"amount":"10.00"}`)
+	assert.Equal(t, "true", replay.Header().Get("X-Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&executions))
+}
+
+func TestIdempotency_ClientIDScopesAnonymousCallers(t *testing.T) {
+	var executions int32
+	router := newIdempotencyRouter(t, time.Second, func(c *gin.Context) {
+		atomic.AddInt32(&executions, 1)
+		response.Created(c, gin.H{"uuid": "expense-1"})
+	})
+
+	post := func(clientID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/expenses", bytes.NewBufferString(`{"amount":"10.00"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.IdempotencyKeyHeader, testIdempotencyKey)
+		req.Header.Set(middleware.ClientIDHeader, clientID)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Empty(t, post("mobile-app-1").Header().Get("X-Idempotent-Replayed"))
+	assert.Empty(t, post("web-app-7").Header().Get("X-Idempotent-Replayed"))
+	assert.Equal(t, "true", post("mobile-app-1").Header().Get("X-Idempotent-Replayed"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&executions))
+}
+
+func TestIdempotency_ReplaysStoredHeaders(t *testing.T) {
+	router := newIdempotencyRouter(t, time.Second, func(c *gin.Context) {
+		c.Header("Location", "/api/v1/expenses/expense-1")
+		c.Header(middleware.RetryAfterHeader, "5")
+		response.Created(c, gin.H{"uuid": "expense-1"})
+	})
+
+	first := postExpense(router)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	replay := postExpense(router)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("X-Idempotent-Replayed"))
+	assert.Equal(t, "/api/v1/expenses/expense-1", replay.Header().Get("Location"))
+	assert.Equal(t, first.Header().Get("Content-Type"), replay.Header().Get("Content-Type"))
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+	// Headers about the original request are not replayed
+	assert.Empty(t, replay.Header().Get(middleware.RetryAfterHeader))
+}
@@ -0,0 +1,155 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// Mock for Insights service dependencies
+
+type MockInsightsRepository struct{ mock.Mock }
+
+func (m *MockInsightsRepository) aggregates(args mock.Arguments) ([]*models.InsightAggregate, error) {
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.InsightAggregate), args.Error(1)
+}
+
+func (m *MockInsightsRepository) GetPaidByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	return m.aggregates(m.Called(ctx, userID, from, to))
+}
+
+func (m *MockInsightsRepository) GetShareByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	return m.aggregates(m.Called(ctx, userID, from, to))
+}
+
+func (m *MockInsightsRepository) GetGroupSpendByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	return m.aggregates(m.Called(ctx, userID, from, to))
+}
+
+func (m *MockInsightsRepository) GetSettlementsSentByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	return m.aggregates(m.Called(ctx, userID, from, to))
+}
+
+func (m *MockInsightsRepository) GetSettlementsReceivedByMonth(ctx context.Context, userID int64, from, to time.Time) ([]*models.InsightAggregate, error) {
+	return m.aggregates(m.Called(ctx, userID, from, to))
+}
+
+func insightAggregate(groupID int64, groupName, month string, amount int64) *models.InsightAggregate {
+	return &models.InsightAggregate{
+		GroupID:   groupID,
+		GroupUUID: groupName + "-uuid",
+		GroupName: groupName,
+		Month:     month,
+		Currency:  "USD",
+		Amount:    decimal.NewFromInt(amount),
+		Count:     1,
+	}
+}
+
+func TestInsightsService_GetUserInsights_RatioMath(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	insightsRepo := new(MockInsightsRepository)
+	userRepo := new(MockUserRepositoryES)
+	is := service.NewInsightsService(insightsRepo, userRepo, logger)
+
+	userUUID := "11111111-1111-4111-8111-111111111111"
+	userRepo.On("GetByUUID", ctx, userUUID).Return(&models.User{ID: 1, UUID: userUUID}, nil)
+
+	// The window covers January to June in every timezone
+	from := time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	insightsRepo.On("GetShareByMonth", ctx, int64(1), from, to).Return([]*models.InsightAggregate{
+		insightAggregate(10, "Trip", "2024-06", 60),
+		insightAggregate(20, "Flat", "2024-06", 40),
+		insightAggregate(10, "Trip", "2024-05", 80),
+		// Still December in a group ahead of UTC, so outside the window
+		{GroupID: 30, GroupName: "Tokyo", Month: "2023-12", Currency: "JPY", Amount: decimal.NewFromInt(900), Count: 1},
+	}, nil)
+	insightsRepo.On("GetPaidByMonth", ctx, int64(1), from, to).Return([]*models.InsightAggregate{
+		insightAggregate(10, "Trip", "2024-06", 150),
+	}, nil)
+	insightsRepo.On("GetGroupSpendByMonth", ctx, int64(1), from, to).Return([]*models.InsightAggregate{
+		insightAggregate(10, "Trip", "2024-06", 150),
+		insightAggregate(20, "Flat", "2024-06", 50),
+		insightAggregate(10, "Trip", "2024-05", 160),
+	}, nil)
+	insightsRepo.On("GetSettlementsSentByMonth", ctx, int64(1), from, to).Return([]*models.InsightAggregate{
+		insightAggregate(10, "Trip", "2024-05", 20),
+	}, nil)
+	insightsRepo.On("GetSettlementsReceivedByMonth", ctx, int64(1), from, to).Return([]*models.InsightAggregate{}, nil)
+
+	insights, err := is.GetUserInsights(ctx, userUUID, "2024-06")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06", insights.Month)
+	assert.Equal(t, []string{"2024-01", "2024-02", "2024-03", "2024-04", "2024-05", "2024-06"}, insights.Months)
+	assert.Len(t, insights.Currencies, 1)
+
+	usd := insights.Currencies[0]
+	assert.Equal(t, "USD", usd.Currency)
+	assert.True(t, usd.TotalSpent.Equal(decimal.NewFromInt(100)))
+	assert.True(t, usd.TotalPaid.Equal(decimal.NewFromInt(150)))
+	assert.True(t, usd.GroupSpend.Equal(decimal.NewFromInt(200)))
+	assert.True(t, usd.ShareOfSpend.Equal(decimal.NewFromFloat(0.5)))
+
+	// 180 spent - 150 paid - 20 settled = 10 owed, against an average share of 30
+	assert.True(t, usd.Outstanding.Equal(decimal.NewFromInt(10)))
+	assert.True(t, usd.SettleUpLagMonths.Equal(decimal.NewFromFloat(0.33)))
+
+	assert.Equal(t, "2024-05", usd.Trend.PreviousMonth)
+	assert.True(t, usd.Trend.Change.Equal(decimal.NewFromInt(20)))
+	assert.True(t, usd.Trend.ChangePercent.Equal(decimal.NewFromInt(25)))
+
+	assert.Len(t, usd.TopGroups, 2)
+	assert.Equal(t, "Trip", usd.TopGroups[0].Group.Name)
+	assert.True(t, usd.TopGroups[0].Paid.Equal(decimal.NewFromInt(150)))
+	assert.Equal(t, "Flat", usd.TopGroups[1].Group.Name)
+
+	assert.Len(t, usd.Series, 6)
+	assert.True(t, usd.Series[4].SettledOut.Equal(decimal.NewFromInt(20)))
+}
+
+func TestInsightsService_GetUserInsights_NoActivity(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	ctx := context.Background()
+
+	insightsRepo := new(MockInsightsRepository)
+	userRepo := new(MockUserRepositoryES)
+	is := service.NewInsightsService(insightsRepo, userRepo, logger)
+
+	userUUID := "11111111-1111-4111-8111-111111111111"
+	userRepo.On("GetByUUID", ctx, userUUID).Return(&models.User{ID: 1, UUID: userUUID}, nil)
+	for _, method := range []string{"GetShareByMonth", "GetPaidByMonth", "GetGroupSpendByMonth", "GetSettlementsSentByMonth", "GetSettlementsReceivedByMonth"} {
+		insightsRepo.On(method, ctx, int64(1), mock.Anything, mock.Anything).Return(nil, nil)
+	}
+
+	insights, err := is.GetUserInsights(ctx, userUUID, "2024-06")
+	assert.NoError(t, err)
+	assert.NotNil(t, insights.Currencies)
+	assert.Empty(t, insights.Currencies)
+	assert.Len(t, insights.Months, 6)
+}
+
+func TestInsightsService_GetUserInsights_InvalidMonth(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	is := service.NewInsightsService(new(MockInsightsRepository), new(MockUserRepositoryES), logger)
+
+	_, err := is.GetUserInsights(context.Background(), "11111111-1111-4111-8111-111111111111", "June 2024")
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalid, appErr.Code)
+}
@@ -0,0 +1,52 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/logging"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLoggingNewConfig_Level(t *testing.T) {
+	config, err := logging.NewConfig("debug", "production")
+	assert.NoError(t, err)
+	assert.Equal(t, zapcore.DebugLevel, config.Level.Level())
+
+	config, err = logging.NewConfig("WARN", "production")
+	assert.NoError(t, err)
+	assert.Equal(t, zapcore.WarnLevel, config.Level.Level())
+
+	_, err = logging.NewConfig("verbose", "production")
+	assert.EqualError(t, err, `invalid LOG_LEVEL: unrecognized level: "verbose"`)
+
+	_, err = logging.New("verbose", "development")
+	assert.Error(t, err)
+}
+
+func TestLoggingNewConfig_EncoderByEnvironment(t *testing.T) {
+	production, err := logging.NewConfig("info", "production")
+	assert.NoError(t, err)
+	assert.Equal(t, "json", production.Encoding)
+
+	development, err := logging.NewConfig("info", "development")
+	assert.NoError(t, err)
+	assert.Equal(t, "console", development.Encoding)
+	assert.True(t, development.Development)
+
+	// Both keep ISO8601 timestamps
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), Message: "hello"}
+	for _, config := range []struct {
+		name    string
+		encoder zapcore.Encoder
+	}{
+		{"production", zapcore.NewJSONEncoder(production.EncoderConfig)},
+		{"development", zapcore.NewConsoleEncoder(development.EncoderConfig)},
+	} {
+		buf, err := config.encoder.EncodeEntry(entry, nil)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "2024-03-05T14:30:00.000Z", config.name)
+	}
+}
@@ -0,0 +1,71 @@
+package unit
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePool struct{ stats sql.DBStats }
+
+func (p fakePool) Stats() sql.DBStats { return p.stats }
+
+func scrape(t *testing.T, registry *metrics.Registry) string {
+	rec := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4"))
+	return rec.Body.String()
+}
+
+func TestMetricsMiddleware_RecordsRouteTemplates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := metrics.NewRegistry(nil)
+	router := gin.New()
+	router.Use(middleware.MetricsMiddleware(registry))
+	router.GET("/api/v1/groups/:uuid", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/api/v1/groups/a", "/api/v1/groups/b", "/wp-admin"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, registry)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/v1/groups/:uuid",status="200"} 2`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `http_request_duration_seconds_bucket{method="GET",route="/api/v1/groups/:uuid",le="+Inf"} 2`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/api/v1/groups/:uuid"} 2`)
+	assert.NotContains(t, body, "/api/v1/groups/a")
+}
+
+func TestMetricsRegistry_DomainCountersAndPoolStats(t *testing.T) {
+	registry := metrics.NewRegistry(fakePool{stats: sql.DBStats{MaxOpenConnections: 25, OpenConnections: 4, InUse: 3, Idle: 1, WaitCount: 7}})
+
+	registry.ExpensesCreated(1)
+	registry.ExpensesCreated(3)
+	registry.SettlementsCreated(2)
+	registry.DebtSimplificationRun(metrics.SimplificationPreview)
+	registry.DebtSimplificationRun(metrics.SimplificationPreview)
+	registry.DebtSimplificationRun(metrics.SimplificationExecute)
+
+	body := scrape(t, registry)
+	assert.Contains(t, body, "# TYPE expenses_created_total counter\nexpenses_created_total 4\n")
+	assert.Contains(t, body, "settlements_created_total 2\n")
+	assert.Contains(t, body, `debt_simplification_runs_total{mode="preview"} 2`)
+	assert.Contains(t, body, `debt_simplification_runs_total{mode="execute"} 1`)
+	assert.Contains(t, body, "# TYPE db_open_connections gauge\ndb_open_connections 4\n")
+	assert.Contains(t, body, "db_in_use_connections 3\n")
+	assert.Contains(t, body, "db_wait_count_total 7\n")
+
+	// The standard client collectors come along
+	assert.Contains(t, body, "# TYPE go_goroutines gauge")
+	assert.Contains(t, scrape(t, registry), `promhttp_metric_handler_requests_total{code="200"} 1`)
+}
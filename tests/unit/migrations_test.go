@@ -0,0 +1,24 @@
+package unit
+
+import (
+	"testing"
+
+	"expense-split-tracker/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations_EmbeddedInOrder(t *testing.T) {
+	migrations, err := database.Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	assert.Equal(t, "initial_schema", migrations[0].Name)
+	for i, migration := range migrations {
+		// Versions are contiguous so a missing file is caught here
+		assert.Equal(t, i+1, migration.Version, migration.Name)
+		assert.NotEmpty(t, migration.Up, migration.Name)
+		assert.NotEmpty(t, migration.Down, migration.Name)
+	}
+}
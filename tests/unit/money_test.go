@@ -0,0 +1,121 @@
+package unit
+
+import (
+	"testing"
+
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitEvenly(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount string
+		n      int
+		places int32
+		want   []string
+	}{
+		{name: "one cent across three", amount: "0.01", n: 3, places: 2, want: []string{"0.01", "0", "0"}},
+		{name: "hundred across three", amount: "100", n: 3, places: 2, want: []string{"33.34", "33.33", "33.33"}},
+		{name: "hundred across seven", amount: "100", n: 7, places: 2, want: []string{"14.29", "14.29", "14.29", "14.29", "14.28", "14.28", "14.28"}},
+		{name: "twenty cents across seven", amount: "0.20", n: 7, places: 2, want: []string{"0.03", "0.03", "0.03", "0.03", "0.03", "0.03", "0.02"}},
+		{name: "even split", amount: "90", n: 3, places: 2, want: []string{"30", "30", "30"}},
+		{name: "whole yen", amount: "1000", n: 3, places: 0, want: []string{"334", "333", "333"}},
+		{name: "dinar fils", amount: "1", n: 3, places: 3, want: []string{"0.334", "0.333", "0.333"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount := decimal.RequireFromString(tt.amount)
+			parts := utils.SplitEvenly(amount, tt.n, tt.places)
+			assert.Len(t, parts, tt.n)
+
+			sum := decimal.Zero
+			min, max := parts[0], parts[0]
+			for i, part := range parts {
+				assert.True(t, part.Equal(decimal.RequireFromString(tt.want[i])), "part %d: got %s want %s", i, part, tt.want[i])
+				assert.False(t, part.IsNegative())
+				sum = sum.Add(part)
+				min = decimal.Min(min, part)
+				max = decimal.Max(max, part)
+			}
+			assert.True(t, sum.Equal(amount), "sum %s != %s", sum, amount)
+			assert.True(t, max.Sub(min).LessThanOrEqual(decimal.New(1, -tt.places)))
+		})
+	}
+}
+
+func TestSplitByWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		weights []string
+		places  int32
+		want    []string
+	}{
+		{name: "equal weights spread from the first", amount: "100", weights: []string{"1", "1", "1"}, places: 2, want: []string{"33.34", "33.33", "33.33"}},
+		{name: "largest remainder gets the cent", amount: "10", weights: []string{"1", "2"}, places: 2, want: []string{"3.33", "6.67"}},
+		{name: "several leftover cents", amount: "0.10", weights: []string{"1", "1", "1", "1", "1", "1", "1"}, places: 2, want: []string{"0.02", "0.02", "0.02", "0.01", "0.01", "0.01", "0.01"}},
+		{name: "percentages", amount: "10", weights: []string{"33.33", "33.33", "33.34"}, places: 2, want: []string{"3.33", "3.33", "3.34"}},
+		{name: "whole yen", amount: "1000", weights: []string{"1", "1", "1"}, places: 0, want: []string{"334", "333", "333"}},
+		{name: "yen by percentage", amount: "100", weights: []string{"12.5", "12.5", "75"}, places: 0, want: []string{"13", "12", "75"}},
+		{name: "three decimal currency", amount: "1", weights: []string{"1", "2"}, places: 3, want: []string{"0.333", "0.667"}},
+		{name: "exact", amount: "1000", weights: []string{"2", "1", "1"}, places: 2, want: []string{"500", "250", "250"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount := decimal.RequireFromString(tt.amount)
+			weights := make([]decimal.Decimal, len(tt.weights))
+			for i, weight := range tt.weights {
+				weights[i] = decimal.RequireFromString(weight)
+			}
+
+			parts := utils.SplitByWeights(amount, weights, tt.places)
+			assert.Len(t, parts, len(tt.want))
+
+			sum := decimal.Zero
+			for i, part := range parts {
+				assert.True(t, part.Equal(decimal.RequireFromString(tt.want[i])), "part %d: got %s want %s", i, part, tt.want[i])
+				assert.LessOrEqual(t, -part.Exponent(), tt.places, "part %d has %s", i, part)
+				sum = sum.Add(part)
+			}
+			assert.True(t, sum.Equal(amount), "sum %s != %s", sum, amount)
+		})
+	}
+
+	assert.Nil(t, utils.SplitByWeights(decimal.NewFromInt(10), nil, 2))
+}
+
+func TestValidateAmount_Precision(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		message  string
+	}{
+		{amount: "10.99", currency: "USD"},
+		{amount: "10.90", currency: "EUR"},
+		{amount: "10.990", currency: ""},
+		{amount: "1500", currency: "jpy"},
+		{amount: "10.999", currency: "USD", message: "USD amounts can have at most 2 decimal places"},
+		{amount: "10.999", currency: "", message: "Amount can have at most 2 decimal places"},
+		{amount: "1500.5", currency: "JPY", message: "JPY amounts can have at most 0 decimal places"},
+	}
+
+	for _, tt := range tests {
+		err := utils.ValidateAmount(decimal.RequireFromString(tt.amount), tt.currency)
+		if tt.message == "" {
+			assert.NoError(t, err, tt.amount)
+			continue
+		}
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok, tt.amount) {
+			assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+			assert.Equal(t, tt.message, appErr.Message)
+			assert.Equal(t, "amount", appErr.Details["field"])
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package unit
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/notification"
+	"expense-split-tracker/internal/outbox"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeNotifier records every message it is asked to send
+type fakeNotifier struct {
+	mu       sync.Mutex
+	messages []notification.Message
+	sent     chan struct{}
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{sent: make(chan struct{}, 16)}
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, message notification.Message) error {
+	f.mu.Lock()
+	f.messages = append(f.messages, message)
+	f.mu.Unlock()
+	f.sent <- struct{}{}
+	return nil
+}
+
+// wait blocks until n messages have been sent and returns them by recipient
+func (f *fakeNotifier) wait(t *testing.T, n int) []notification.Message {
+	for i := 0; i < n; i++ {
+		select {
+		case <-f.sent:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d notifications sent", i, n)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	messages := append([]notification.Message(nil), f.messages...)
+	sort.Slice(messages, func(i, j int) bool { return messages[i].To < messages[j].To })
+	return messages
+}
+
+// notificationDirectory resolves the users and group of setupThreeWayDinner
+type notificationDirectory struct{}
+
+func (notificationDirectory) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	users := map[int64]*models.User{
+		1: {ID: 1, Name: "Alice", Email: "alice@example.com"},
+		2: {ID: 2, Name: "Bob", Email: "bob@example.com"},
+		3: {ID: 3, Name: "Carol", Email: "carol@example.com"},
+	}
+	if user, ok := users[id]; ok {
+		return user, nil
+	}
+	return nil, errors.NewNotFoundError("User")
+}
+
+type notificationGroups struct{}
+
+func (notificationGroups) GetByID(ctx context.Context, id int64) (*models.Group, error) {
+	return &models.Group{ID: id, Name: "Trip"}, nil
+}
+
+func runNotificationDispatcher(t *testing.T, notifier notification.Notifier) *notification.Dispatcher {
+	dispatcher := notification.NewDispatcher(notifier, notificationDirectory{}, notificationGroups{}, notification.Options{}, zaptest.NewLogger(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return dispatcher
+}
+
+func TestNotifications_EqualSplitNotifiesParticipantsButNotPayer(t *testing.T) {
+	notifier := newFakeNotifier()
+	dispatcher := runNotificationDispatcher(t, notifier)
+
+	// The expense reaches the notifier through the outbox once committed
+	store := &memoryOutbox{}
+	emitter := events.NewDispatcher(zaptest.NewLogger(t))
+	emitter.UseRecorder(outbox.NewWriter(store))
+	relay := outbox.NewRelay(store, outbox.Options{}, zaptest.NewLogger(t))
+	relay.Subscribe("notifications", dispatcher.Consume)
+
+	es, req := setupThreeWayDinnerWith(t, nil, emitter)
+	_, err := es.CreateExpense(context.Background(), req)
+	require.NoError(t, err)
+
+	published, err := relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, published)
+
+	messages := notifier.wait(t, 2)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "bob@example.com", messages[0].To)
+	assert.Equal(t, "carol@example.com", messages[1].To)
+
+	for _, message := range messages {
+		assert.Equal(t, `You owe 30.00 USD for "Dinner" in Trip`, message.Subject)
+		assert.Contains(t, message.Body, "Alice paid 90.00 USD for \"Dinner\" in Trip.")
+		assert.Contains(t, message.Body, "Your share is 30.00 USD.")
+	}
+}
+
+func TestNotifications_SettlementNotifiesReceiver(t *testing.T) {
+	notifier := newFakeNotifier()
+	dispatcher := runNotificationDispatcher(t, notifier)
+
+	settlement := &models.Settlement{
+		ID: 5, GroupID: 10, FromUserID: 2, ToUserID: 1,
+		Amount: decimal.NewFromInt(30), Currency: "USD", Status: models.SettlementStatusPending,
+	}
+	row, _, err := outbox.Encode(events.SettlementCreated{Settlement: settlement})
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.Consume(context.Background(), row))
+
+	messages := notifier.wait(t, 1)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "alice@example.com", messages[0].To)
+	assert.Equal(t, "Bob paid you 30.00 USD in Trip", messages[0].Subject)
+	assert.Contains(t, messages[0].Body, "Please confirm or reject it")
+}
+
+func TestNotifications_OtherEventsAreIgnored(t *testing.T) {
+	notifier := newFakeNotifier()
+	dispatcher := runNotificationDispatcher(t, notifier)
+
+	row, _, err := outbox.Encode(events.MemberAdded{GroupID: 10, UserID: 2})
+	require.NoError(t, err)
+	assert.NoError(t, dispatcher.Consume(context.Background(), row))
+	assert.Empty(t, notifier.messages)
+}
@@ -0,0 +1,189 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"sort"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/outbox"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// memoryOutbox is an in-memory outbox repository
+type memoryOutbox struct {
+	rows   []*models.OutboxEvent
+	nextID int64
+}
+
+func (m *memoryOutbox) Append(ctx context.Context, tx *database.Tx, events []*models.OutboxEvent) error {
+	for _, event := range events {
+		m.nextID++
+		event.ID = m.nextID
+		m.rows = append(m.rows, event)
+	}
+	return nil
+}
+
+func (m *memoryOutbox) GetPending(ctx context.Context, limit int) ([]*models.OutboxEvent, error) {
+	var pending []*models.OutboxEvent
+	for _, row := range m.rows {
+		if row.DispatchedAt == nil && len(pending) < limit {
+			copied := *row
+			pending = append(pending, &copied)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	return pending, nil
+}
+
+func (m *memoryOutbox) MarkDispatched(ctx context.Context, id int64) error {
+	now := time.Now()
+	m.find(id).DispatchedAt = &now
+	return nil
+}
+
+func (m *memoryOutbox) RecordFailure(ctx context.Context, id int64, message string) error {
+	row := m.find(id)
+	row.Attempts++
+	row.LastError = &message
+	return nil
+}
+
+func (m *memoryOutbox) find(id int64) *models.OutboxEvent {
+	for _, row := range m.rows {
+		if row.ID == id {
+			return row
+		}
+	}
+	return nil
+}
+
+func (m *memoryOutbox) add(groupID int64, eventType string) {
+	_ = m.Append(context.Background(), nil, []*models.OutboxEvent{{GroupID: groupID, EventType: eventType, Payload: json.RawMessage(`{}`)}})
+}
+
+// publishedLog records what a consumer received, failing selected ids
+type publishedLog struct {
+	ids  []int64
+	fail map[int64]bool
+}
+
+func (p *publishedLog) consume(ctx context.Context, event *models.OutboxEvent) error {
+	if p.fail[event.ID] {
+		return stderrors.New("consumer unavailable")
+	}
+	p.ids = append(p.ids, event.ID)
+	return nil
+}
+
+func TestOutboxWriter_RecordsExpenseInTransaction(t *testing.T) {
+	store := &memoryOutbox{}
+	dispatcher := events.NewDispatcher(zaptest.NewLogger(t))
+	dispatcher.UseRecorder(outbox.NewWriter(store))
+
+	es, req := setupThreeWayDinnerWith(t, nil, dispatcher)
+	_, err := es.CreateExpense(context.Background(), req)
+	require.NoError(t, err)
+
+	// Balance adjustments are derived and stay out of the outbox
+	require.Len(t, store.rows, 1)
+	row := store.rows[0]
+	assert.Equal(t, "expense.created", row.EventType)
+	assert.Equal(t, int64(10), row.GroupID)
+
+	var expense models.Expense
+	require.NoError(t, json.Unmarshal(row.Payload, &expense))
+	assert.Equal(t, "Dinner", expense.Description)
+	assert.True(t, expense.Amount.Equal(decimal.NewFromInt(90)))
+	assert.Len(t, expense.Splits, 3)
+}
+
+func TestOutboxEncode_MemberEvents(t *testing.T) {
+	row, ok, err := outbox.Encode(events.MemberRoleChanged{GroupID: 4, UserID: 9, Role: models.GroupRoleAdmin})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "group.member_role_changed", row.EventType)
+	assert.JSONEq(t, `{"group_id":4,"user_id":9,"role":"admin"}`, string(row.Payload))
+
+	_, ok, err = outbox.Encode(events.BalanceAdjusted{GroupID: 4, UserID: 9, Currency: "USD", Delta: decimal.NewFromInt(1)})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestOutboxRelay_PublishesInOrderAndMarksDispatched(t *testing.T) {
+	store := &memoryOutbox{}
+	store.add(1, "expense.created")
+	store.add(2, "settlement.created")
+	store.add(1, "expense.deleted")
+
+	relay := outbox.NewRelay(store, outbox.Options{BatchSize: 10}, zaptest.NewLogger(t))
+	first, second := &publishedLog{}, &publishedLog{}
+	relay.Subscribe("first", first.consume)
+	relay.Subscribe("second", second.consume)
+
+	published, err := relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, published)
+	assert.Equal(t, []int64{1, 2, 3}, first.ids)
+	assert.Equal(t, []int64{1, 2, 3}, second.ids)
+
+	pending, _ := store.GetPending(context.Background(), 10)
+	assert.Empty(t, pending)
+}
+
+func TestOutboxRelay_FailureHoldsBackItsGroupOnly(t *testing.T) {
+	store := &memoryOutbox{}
+	store.add(1, "expense.created")
+	store.add(2, "expense.created")
+	store.add(1, "expense.deleted")
+
+	relay := outbox.NewRelay(store, outbox.Options{BatchSize: 10}, zaptest.NewLogger(t))
+	consumer := &publishedLog{fail: map[int64]bool{1: true}}
+	relay.Subscribe("webhooks", consumer.consume)
+
+	published, err := relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []int64{2}, consumer.ids, "event 3 must wait for event 1 of the same group")
+	assert.Equal(t, 1, store.find(1).Attempts)
+	require.NotNil(t, store.find(1).LastError)
+	assert.Contains(t, *store.find(1).LastError, "webhooks")
+
+	// Once the consumer recovers the group resumes in commit order
+	consumer.fail = nil
+	published, err = relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []int64{2, 1, 3}, consumer.ids)
+}
+
+func TestOutboxRelay_GivesUpAfterMaxAttempts(t *testing.T) {
+	store := &memoryOutbox{}
+	store.add(1, "expense.created")
+	store.add(1, "expense.deleted")
+
+	relay := outbox.NewRelay(store, outbox.Options{BatchSize: 10, MaxAttempts: 2}, zaptest.NewLogger(t))
+	consumer := &publishedLog{fail: map[int64]bool{1: true}}
+	relay.Subscribe("webhooks", consumer.consume)
+
+	published, err := relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	published, err = relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []int64{2}, consumer.ids)
+	assert.Equal(t, 2, store.find(1).Attempts)
+	assert.NotNil(t, store.find(1).DispatchedAt)
+}
@@ -0,0 +1,185 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"expense-split-tracker/internal/controller"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+type MockExpenseServiceHandler struct{ mock.Mock }
+
+func (m *MockExpenseServiceHandler) CreateExpense(ctx context.Context, req *models.CreateExpenseRequest) (*models.Expense, error) {
+	return nil, nil
+}
+
+func (m *MockExpenseServiceHandler) ValidateExpense(ctx context.Context, req *models.CreateExpenseRequest) error {
+	return nil
+}
+
+func (m *MockExpenseServiceHandler) DuplicateExpense(ctx context.Context, uuid string, req *models.DuplicateExpenseRequest) (*models.Expense, error) {
+	return nil, nil
+}
+
+func (m *MockExpenseServiceHandler) GetExpenseByUUID(ctx context.Context, uuid string) (*models.Expense, error) {
+	return nil, nil
+}
+
+func (m *MockExpenseServiceHandler) UpdateExpense(ctx context.Context, uuid string, req *models.UpdateExpenseRequest) (*models.Expense, error) {
+	return nil, nil
+}
+
+func (m *MockExpenseServiceHandler) DeleteExpense(ctx context.Context, uuid string) error {
+	return nil
+}
+
+func (m *MockExpenseServiceHandler) SetReceipt(ctx context.Context, uuid string, req *models.SetReceiptRequest) (*models.Expense, error) {
+	return nil, nil
+}
+
+func (m *MockExpenseServiceHandler) ListExpenses(ctx context.Context, filter *models.ExpenseFilter) (*models.ExpenseListResponse, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*models.ExpenseListResponse), args.Error(1)
+}
+
+func (m *MockExpenseServiceHandler) GetGroupExpenses(ctx context.Context, groupUUID string, page, limit int, opts *models.ExpenseListOptions) ([]*models.Expense, int, error) {
+	args := m.Called(ctx, groupUUID, page, limit, opts)
+	return args.Get(0).([]*models.Expense), args.Int(1), args.Error(2)
+}
+
+func (m *MockExpenseServiceHandler) GetTopGroupExpenses(ctx context.Context, groupUUID string, filter *models.TopExpensesFilter) ([]*models.Expense, error) {
+	return nil, nil
+}
+
+func (m *MockExpenseServiceHandler) GetUserExpenses(ctx context.Context, userUUID string, page, limit int, opts *models.ExpenseListOptions) ([]*models.Expense, int, error) {
+	return nil, 0, nil
+}
+
+func (m *MockExpenseServiceHandler) ImportExpenses(ctx context.Context, groupUUID string, file io.Reader, dryRun bool) (*models.ExpenseImportResult, error) {
+	args := m.Called(ctx, groupUUID, mock.Anything, dryRun)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExpenseImportResult), args.Error(1)
+}
+
+func serveLinks(t *testing.T, target string, register func(*gin.Engine)) *response.Links {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	register(router)
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Host = "api.example.com"
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var body response.APIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	if !assert.NotNil(t, body.Meta) {
+		return &response.Links{}
+	}
+	return body.Meta.Links
+}
+
+func TestPaginationLinks_ListExpensesPreservesFilters(t *testing.T) {
+	svc := new(MockExpenseServiceHandler)
+	svc.On("ListExpenses", mock.Anything, mock.Anything).Return(&models.ExpenseListResponse{
+		Expenses:   []*models.Expense{},
+		TotalCount: 35,
+		Page:       2,
+		Limit:      10,
+	}, nil)
+	ec := controller.NewExpenseController(svc, zaptest.NewLogger(t))
+
+	links := serveLinks(t, "/api/v1/expenses?group_uuid=11111111-1111-4111-8111-111111111111&from_date=2024-01-01&description=caf%C3%A9+bar&page=2&limit=10", func(r *gin.Engine) {
+		r.GET("/api/v1/expenses", ec.ListExpenses)
+	})
+
+	base := "http://api.example.com/api/v1/expenses?group_uuid=11111111-1111-4111-8111-111111111111&from_date=2024-01-01&description=caf%C3%A9+bar&limit=10"
+	assert.Equal(t, base+"&page=1", links.First)
+	assert.Equal(t, base+"&page=1", links.Prev)
+	assert.Equal(t, base+"&page=3", links.Next)
+}
+
+func TestPaginationLinks_LastPageOmitsNext(t *testing.T) {
+	svc := new(MockExpenseServiceHandler)
+	svc.On("ListExpenses", mock.Anything, mock.Anything).Return(&models.ExpenseListResponse{
+		Expenses:   []*models.Expense{},
+		TotalCount: 35,
+		Page:       4,
+		Limit:      10,
+	}, nil)
+	ec := controller.NewExpenseController(svc, zaptest.NewLogger(t))
+
+	links := serveLinks(t, "/api/v1/expenses?currency=EUR&page=4", func(r *gin.Engine) {
+		r.GET("/api/v1/expenses", ec.ListExpenses)
+	})
+
+	assert.Equal(t, "http://api.example.com/api/v1/expenses?currency=EUR&page=3", links.Prev)
+	assert.Empty(t, links.Next)
+}
+
+func TestPaginationLinks_FirstPageOmitsPrev(t *testing.T) {
+	svc := new(MockExpenseServiceHandler)
+	svc.On("GetGroupExpenses", mock.Anything, "11111111-1111-4111-8111-111111111111", 1, 2, mock.Anything).Return([]*models.Expense{{ID: 1}, {ID: 2}}, 5, nil)
+	ec := controller.NewExpenseController(svc, zaptest.NewLogger(t))
+
+	links := serveLinks(t, "/api/v1/groups/11111111-1111-4111-8111-111111111111/expenses?limit=2&include=none", func(r *gin.Engine) {
+		r.GET("/api/v1/groups/:uuid/expenses", ec.GetGroupExpenses)
+	})
+
+	base := "http://api.example.com/api/v1/groups/11111111-1111-4111-8111-111111111111/expenses?limit=2&include=none"
+	assert.Equal(t, base+"&page=1", links.First)
+	assert.Empty(t, links.Prev)
+	assert.Equal(t, base+"&page=2", links.Next)
+}
+
+func TestPaginationMeta_GroupExpensesReportsTotals(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := new(MockExpenseServiceHandler)
+	svc.On("GetGroupExpenses", mock.Anything, "11111111-1111-4111-8111-111111111111", 3, 2, mock.Anything).Return([]*models.Expense{{ID: 5}}, 5, nil)
+	ec := controller.NewExpenseController(svc, zaptest.NewLogger(t))
+
+	router := gin.New()
+	router.GET("/api/v1/groups/:uuid/expenses", ec.GetGroupExpenses)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/groups/11111111-1111-4111-8111-111111111111/expenses?page=3&limit=2", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var body response.APIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, 5, body.Meta.Total)
+	assert.Equal(t, 3, body.Meta.TotalPages)
+	assert.Empty(t, body.Meta.Links.Next)
+}
+
+func TestPaginationLinks_CursorMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/api/v1/groups/g/ledger?from_date=2024-01-01&cursor=abc&limit=20", nil)
+	ctx.Request.Host = "api.example.com"
+	ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+
+	links := response.BuildLinks(ctx, response.Pagination{CursorParam: "cursor", NextCursor: "def", PrevCursor: "xyz"})
+	base := "https://api.example.com/api/v1/groups/g/ledger?from_date=2024-01-01&limit=20"
+	assert.Equal(t, base, links.First)
+	assert.Equal(t, base+"&cursor=xyz", links.Prev)
+	assert.Equal(t, base+"&cursor=def", links.Next)
+
+	last := response.BuildLinks(ctx, response.Pagination{CursorParam: "cursor"})
+	assert.Empty(t, last.Next)
+	assert.Empty(t, last.Prev)
+}
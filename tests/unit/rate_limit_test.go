@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/auth"
+	"expense-split-tracker/internal/middleware"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestMemoryRateLimiter_BurstThenLimited(t *testing.T) {
+	limiter := middleware.NewMemoryRateLimiter(middleware.RateLimit{PerMinute: 60, Burst: 3})
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(ctx, "ip:1.2.3.4")
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := limiter.Allow(ctx, "ip:1.2.3.4")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.True(t, result.RetryAfter > 0 && result.RetryAfter <= time.Second)
+
+	// Other clients have their own bucket
+	result, err = limiter.Allow(ctx, "ip:5.6.7.8")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := middleware.NewMemoryRateLimiter(middleware.RateLimit{PerMinute: 60, Burst: 1})
+	router := gin.New()
+	router.Use(middleware.NewRateLimitMiddleware(limiter, zaptest.NewLogger(t)).Handle())
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/api/v1/expenses", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/api/v1/expenses")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get(middleware.RateLimitLimitHeader))
+	assert.Equal(t, "1", rec.Header().Get(middleware.RateLimitBurstHeader))
+	assert.Equal(t, "0", rec.Header().Get(middleware.RateLimitRemainingHeader))
+
+	rec = send(http.MethodPost, "/api/v1/expenses")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get(middleware.RetryAfterHeader))
+
+	var resp response.APIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, errors.ErrCodeRateLimited, resp.Error.Code)
+
+	// Health checks are never limited
+	for i := 0; i < 3; i++ {
+		rec = send(http.MethodGet, "/health")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get(middleware.RateLimitLimitHeader))
+	}
+}
+
+func TestRateLimitMiddleware_KeysOnClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := middleware.NewMemoryRateLimiter(middleware.RateLimit{PerMinute: 60, Burst: 1})
+	router := gin.New()
+	// Even with an identity already resolved, the bucket follows the IP
+	router.Use(func(c *gin.Context) {
+		identity := &auth.Identity{UserID: 1, UserUUID: models.UserUUID(c.GetHeader(middleware.UserUUIDHeader))}
+		c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), identity))
+		c.Next()
+	})
+	router.Use(middleware.NewRateLimitMiddleware(limiter, zaptest.NewLogger(t)).Handle())
+	router.GET("/api/v1/groups", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(remoteAddr, userUUID string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/groups", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(middleware.UserUUIDHeader, userUUID)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("10.0.0.1:1000", "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"))
+	// A fresh user header does not buy a fresh bucket
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.1:1001", "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"))
+	// Another client is unaffected
+	assert.Equal(t, http.StatusOK, send("10.0.0.2:1000", "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"))
+}
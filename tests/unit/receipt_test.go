@@ -0,0 +1,69 @@
+package unit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestValidateReceiptURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://receipts.example.com/2024/03/dinner.jpg", true},
+		{"http://example.com/r?id=42", true},
+		{"ftp://example.com/receipt.pdf", false},
+		{"javascript:alert(1)", false},
+		{"example.com/receipt.jpg", false},
+		{"https://", false},
+		{"https://example.com/" + strings.Repeat("a", 2048), false},
+	}
+
+	for _, tt := range tests {
+		err := utils.ValidateReceiptURL(tt.url)
+		if tt.valid {
+			assert.NoError(t, err, tt.url)
+		} else {
+			assert.Error(t, err, tt.url)
+		}
+	}
+}
+
+func TestExpenseService_SetReceipt(t *testing.T) {
+	ctx := context.Background()
+	expenseUUID := "dddddddd-dddd-4ddd-8ddd-dddddddddddd"
+	receipt := "https://receipts.example.com/dinner.jpg"
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	expenseRepo.On("GetByUUID", mock.Anything, expenseUUID).Return(&models.Expense{ID: 7, UUID: expenseUUID}, nil)
+	expenseRepo.On("UpdateReceiptURL", mock.Anything, mock.Anything, int64(7), receipt).Return(nil)
+	expenseRepo.On("GetExpenseSplits", mock.Anything, int64(7)).Return([]*models.ExpenseSplit{}, nil)
+	expenseRepo.On("GetExpenseItems", mock.Anything, int64(7)).Return([]*models.ExpenseItem{}, nil)
+	db := new(MockDBES)
+
+	es := service.NewExpenseService(expenseRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES),
+		newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	_, err := es.SetReceipt(ctx, expenseUUID, &models.SetReceiptRequest{ReceiptURL: " " + receipt + " "})
+	assert.NoError(t, err)
+	expenseRepo.AssertCalled(t, "UpdateReceiptURL", mock.Anything, mock.Anything, int64(7), receipt)
+
+	_, err = es.SetReceipt(ctx, expenseUUID, &models.SetReceiptRequest{ReceiptURL: "not a url"})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalid, appErr.Code)
+	expenseRepo.AssertNumberOfCalls(t, "UpdateReceiptURL", 1)
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+}
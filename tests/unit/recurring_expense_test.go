@@ -0,0 +1,197 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+type MockRecurringExpenseRepository struct{ mock.Mock }
+
+func (m *MockRecurringExpenseRepository) Create(ctx context.Context, tx *database.Tx, recurring *models.RecurringExpense) error {
+	args := m.Called(ctx, tx, recurring)
+	return args.Error(0)
+}
+
+func (m *MockRecurringExpenseRepository) GetByUUID(ctx context.Context, uuid string) (*models.RecurringExpense, error) {
+	args := m.Called(ctx, uuid)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RecurringExpense), args.Error(1)
+}
+
+func (m *MockRecurringExpenseRepository) GetGroupRecurringExpenses(ctx context.Context, groupID int64) ([]*models.RecurringExpense, error) {
+	args := m.Called(ctx, groupID)
+	return args.Get(0).([]*models.RecurringExpense), args.Error(1)
+}
+
+func (m *MockRecurringExpenseRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]*models.RecurringExpense, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]*models.RecurringExpense), args.Error(1)
+}
+
+func (m *MockRecurringExpenseRepository) Update(ctx context.Context, tx *database.Tx, recurring *models.RecurringExpense) error {
+	args := m.Called(ctx, tx, recurring)
+	return args.Error(0)
+}
+
+func (m *MockRecurringExpenseRepository) Advance(ctx context.Context, tx *database.Tx, id int64, runAt, nextRunAt time.Time) (bool, error) {
+	args := m.Called(ctx, tx, id, runAt, nextRunAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRecurringExpenseRepository) Delete(ctx context.Context, tx *database.Tx, id int64) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+// MockExpenseServiceRE records the expenses created by recurring runs
+type MockExpenseServiceRE struct{ MockExpenseServiceHandler }
+
+func (m *MockExpenseServiceRE) CreateExpense(ctx context.Context, req *models.CreateExpenseRequest) (*models.Expense, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Expense), args.Error(1)
+}
+
+func (m *MockExpenseServiceRE) ValidateExpense(ctx context.Context, req *models.CreateExpenseRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func TestRecurrenceFrequency_Next(t *testing.T) {
+	anchor := time.Date(2025, time.January, 31, 9, 30, 0, 0, time.UTC)
+
+	weekly := models.FrequencyWeekly.Next(anchor, anchor, time.UTC)
+	assert.Equal(t, time.Date(2025, time.February, 7, 9, 30, 0, 0, time.UTC), weekly)
+
+	// Short months clamp to their last day without losing the anchor day
+	feb := models.FrequencyMonthly.Next(anchor, anchor, time.UTC)
+	assert.Equal(t, time.Date(2025, time.February, 28, 9, 30, 0, 0, time.UTC), feb)
+	mar := models.FrequencyMonthly.Next(feb, anchor, time.UTC)
+	assert.Equal(t, time.Date(2025, time.March, 31, 9, 30, 0, 0, time.UTC), mar)
+
+	// Runs keep their wall-clock time across DST changes
+	loc, err := time.LoadLocation("America/New_York")
+	assert.NoError(t, err)
+	winter := time.Date(2025, time.March, 5, 8, 0, 0, 0, loc)
+	assert.Equal(t, 8, models.FrequencyWeekly.Next(winter, winter, loc).Hour())
+
+	assert.Error(t, models.RecurrenceFrequency("daily").Validate())
+}
+
+func newDueRecurringExpense(nextRunAt time.Time) *models.RecurringExpense {
+	return &models.RecurringExpense{
+		ID:          5,
+		UUID:        "eeeeeeee-eeee-4eee-8eee-eeeeeeeeeeee",
+		GroupID:     1,
+		Amount:      decimal.NewFromInt(1200),
+		Currency:    "USD",
+		Description: "Rent",
+		Category:    "housing",
+		SplitType:   models.SplitTypeEqual,
+		Frequency:   models.FrequencyMonthly,
+		StartsAt:    nextRunAt,
+		NextRunAt:   nextRunAt,
+		Active:      true,
+		Group:       &models.Group{ID: 1, UUID: "11111111-1111-4111-8111-111111111111", Timezone: "UTC"},
+		Payer:       &models.User{ID: 2, UUID: "22222222-2222-4222-8222-222222222222"},
+	}
+}
+
+func TestRecurringExpenseService_RunDueCatchesUpIdempotently(t *testing.T) {
+	ctx := context.Background()
+	first := time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)
+	second := time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC)
+	third := time.Date(2025, time.March, 31, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	recurringRepo := new(MockRecurringExpenseRepository)
+	recurringRepo.On("GetDue", mock.Anything, now, mock.Anything).Return([]*models.RecurringExpense{newDueRecurringExpense(first)}, nil)
+	recurringRepo.On("Advance", mock.Anything, mock.Anything, int64(5), first, second).Return(true, nil)
+	recurringRepo.On("Advance", mock.Anything, mock.Anything, int64(5), second, third).Return(true, nil)
+
+	isRun := func(runAt time.Time) interface{} {
+		return mock.MatchedBy(func(req *models.CreateExpenseRequest) bool {
+			return req.Recurrence != nil && req.Recurrence.RecurringExpenseID == 5 && req.Recurrence.RunAt.Equal(runAt) &&
+				req.ExpenseDate.Equal(runAt) && req.GroupUUID == "11111111-1111-4111-8111-111111111111"
+		})
+	}
+	expenseService := new(MockExpenseServiceRE)
+	// The January run was created before a restart; only the schedule was left behind
+	expenseService.On("CreateExpense", mock.Anything, isRun(first)).Return(nil, errors.NewAlreadyExistsError("Expense for this recurring run"))
+	expenseService.On("CreateExpense", mock.Anything, isRun(second)).Return(&models.Expense{ID: 9}, nil)
+
+	rs := service.NewRecurringExpenseService(recurringRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), expenseService, zaptest.NewLogger(t))
+
+	created, err := rs.RunDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created)
+	expenseService.AssertNumberOfCalls(t, "CreateExpense", 2)
+	recurringRepo.AssertNumberOfCalls(t, "Advance", 2)
+}
+
+func TestRecurringExpenseService_RunDueRetriesLockedGroup(t *testing.T) {
+	ctx := context.Background()
+	runAt := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	now := runAt.Add(time.Hour)
+
+	recurringRepo := new(MockRecurringExpenseRepository)
+	recurringRepo.On("GetDue", mock.Anything, now, mock.Anything).Return([]*models.RecurringExpense{newDueRecurringExpense(runAt)}, nil)
+
+	expenseService := new(MockExpenseServiceRE)
+	expenseService.On("CreateExpense", mock.Anything, mock.Anything).Return(nil, errors.NewGroupLockedError("settle_up", now.Format(time.RFC3339)))
+
+	rs := service.NewRecurringExpenseService(recurringRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), expenseService, zaptest.NewLogger(t))
+
+	created, err := rs.RunDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, created)
+	// The run stays due for the next tick
+	recurringRepo.AssertNotCalled(t, "Advance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRecurringExpenseService_CreateValidatesTemplate(t *testing.T) {
+	ctx := context.Background()
+	groupUUID := "11111111-1111-4111-8111-111111111111"
+
+	groupRepo := new(MockGroupRepositoryES)
+	groupRepo.On("GetByUUID", mock.Anything, groupUUID).Return(&models.Group{ID: 1, UUID: groupUUID}, nil)
+	recurringRepo := new(MockRecurringExpenseRepository)
+	expenseService := new(MockExpenseServiceRE)
+	expenseService.On("ValidateExpense", mock.Anything, mock.Anything).Return(errors.NewInvalidSplitError("Sum of split amounts must equal total expense amount"))
+
+	rs := service.NewRecurringExpenseService(recurringRepo, groupRepo, new(MockUserRepositoryES), expenseService, zaptest.NewLogger(t))
+
+	req := &models.CreateRecurringExpenseRequest{
+		PaidByUUID:  "22222222-2222-4222-8222-222222222222",
+		Amount:      decimal.NewFromInt(100),
+		Description: "Internet",
+		SplitType:   models.SplitTypeExact,
+		Frequency:   "daily",
+	}
+	_, err := rs.CreateRecurringExpense(ctx, models.GroupUUID(groupUUID), req)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalid, appErr.Code)
+
+	req.Frequency = models.FrequencyMonthly
+	_, err = rs.CreateRecurringExpense(ctx, models.GroupUUID(groupUUID), req)
+	appErr, ok = err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidSplit, appErr.Code)
+	recurringRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
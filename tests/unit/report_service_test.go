@@ -0,0 +1,274 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+// Mock for Report service dependencies
+
+type MockReportRepository struct{ mock.Mock }
+
+func (m *MockReportRepository) GetCategoryTotals(ctx context.Context, groupID int64, filter *models.CategoryReportFilter) ([]*models.CategoryAggregate, error) {
+	args := m.Called(ctx, groupID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.CategoryAggregate), args.Error(1)
+}
+
+func (m *MockReportRepository) stats(args mock.Arguments) ([]*models.StatsAggregate, error) {
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.StatsAggregate), args.Error(1)
+}
+
+func (m *MockReportRepository) GetUserPaidTotals(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.StatsAggregate, error) {
+	return m.stats(m.Called(ctx, userID, filter))
+}
+
+func (m *MockReportRepository) GetUserShareTotals(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.StatsAggregate, error) {
+	return m.stats(m.Called(ctx, userID, filter))
+}
+
+func (m *MockReportRepository) GetUserExpenseActivity(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.ExpenseActivity, error) {
+	args := m.Called(ctx, userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ExpenseActivity), args.Error(1)
+}
+
+func (m *MockReportRepository) GetUserLargestExpense(ctx context.Context, userID int64, currency string, filter *models.UserStatsFilter) (*models.LargestExpense, error) {
+	args := m.Called(ctx, userID, currency, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LargestExpense), args.Error(1)
+}
+
+func (m *MockReportRepository) GetUserSettlementTotals(ctx context.Context, userID int64, filter *models.UserStatsFilter) ([]*models.SettlementStatsAggregate, error) {
+	args := m.Called(ctx, userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.SettlementStatsAggregate), args.Error(1)
+}
+
+func (m *MockReportRepository) GetUserActivityGroups(ctx context.Context, userID int64) ([]*models.Group, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Group), args.Error(1)
+}
+
+func setupCategoryReport(t *testing.T) (*MockReportRepository, service.ReportService, *models.Group) {
+	reportRepo := new(MockReportRepository)
+	groupRepo := new(MockGroupRepositoryES)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+
+	return reportRepo, service.NewReportService(reportRepo, groupRepo, new(MockUserRepositoryES), zaptest.NewLogger(t)), group
+}
+
+func TestReportService_GetCategoryReport(t *testing.T) {
+	reportRepo, rs, group := setupCategoryReport(t)
+
+	reportRepo.On("GetCategoryTotals", mock.Anything, group.ID, mock.Anything).Return([]*models.CategoryAggregate{
+		{Category: "travel", Currency: "USD", Amount: decimal.NewFromInt(10), Count: 1},
+		{Category: "food", Currency: "USD", Amount: decimal.NewFromInt(10), Count: 2},
+		{Category: models.DefaultExpenseCategory, Currency: "USD", Amount: decimal.NewFromInt(10), Count: 1},
+		{Category: "lodging", Currency: "USD", Amount: decimal.NewFromInt(70), Count: 1},
+		{Category: "food", Currency: "EUR", Amount: decimal.RequireFromString("12.50"), Count: 1},
+	}, nil)
+
+	report, err := rs.GetCategoryReport(context.Background(), group.UUID, &models.CategoryReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, report.Currencies, 2)
+	assert.Equal(t, "Trip", report.Group.Name)
+
+	eur := report.Currencies[0]
+	assert.Equal(t, "EUR", eur.Currency)
+	require.Len(t, eur.Categories, 1)
+	assert.True(t, decimal.NewFromInt(100).Equal(eur.Categories[0].Percentage))
+
+	usd := report.Currencies[1]
+	assert.Equal(t, "USD", usd.Currency)
+	assert.True(t, decimal.NewFromInt(100).Equal(usd.Total))
+	assert.Equal(t, 5, usd.Count)
+
+	// Largest first, ties by name; expenses without a category have their own bucket
+	var categories []string
+	for _, category := range usd.Categories {
+		categories = append(categories, category.Category)
+	}
+	assert.Equal(t, []string{"lodging", "food", "travel", models.DefaultExpenseCategory}, categories)
+	assert.True(t, decimal.NewFromInt(70).Equal(usd.Categories[0].Percentage))
+	assert.Equal(t, 2, usd.Categories[1].Count)
+}
+
+func TestReportService_GetCategoryReport_PercentagesSumTo100(t *testing.T) {
+	reportRepo, rs, group := setupCategoryReport(t)
+
+	reportRepo.On("GetCategoryTotals", mock.Anything, group.ID, mock.Anything).Return([]*models.CategoryAggregate{
+		{Category: "food", Currency: "USD", Amount: decimal.NewFromInt(10), Count: 1},
+		{Category: "travel", Currency: "USD", Amount: decimal.NewFromInt(10), Count: 1},
+		{Category: "fun", Currency: "USD", Amount: decimal.NewFromInt(10), Count: 1},
+		{Category: "misc", Currency: "USD", Amount: decimal.RequireFromString("0.07"), Count: 1},
+	}, nil)
+
+	report, err := rs.GetCategoryReport(context.Background(), group.UUID, &models.CategoryReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, report.Currencies, 1)
+
+	categories := report.Currencies[0].Categories
+	sum := decimal.Zero
+	for _, category := range categories {
+		sum = sum.Add(category.Percentage)
+	}
+	// Each percentage is rounded to 0.01, so the sum is off by at most half a cent per category
+	tolerance := decimal.RequireFromString("0.005").Mul(decimal.NewFromInt(int64(len(categories))))
+	assert.True(t, sum.Sub(decimal.NewFromInt(100)).Abs().LessThanOrEqual(tolerance), "percentages sum to %s", sum)
+}
+
+func TestReportService_GetCategoryReport_Filters(t *testing.T) {
+	reportRepo, rs, group := setupCategoryReport(t)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	reportRepo.On("GetCategoryTotals", mock.Anything, group.ID, mock.MatchedBy(func(filter *models.CategoryReportFilter) bool {
+		// The currency is normalized and a date-only to_date covers the whole day
+		return filter.Currency == "USD" && filter.FromDate.Equal(from) && filter.ToDate.Equal(to.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	})).Return(nil, nil)
+
+	report, err := rs.GetCategoryReport(context.Background(), group.UUID, &models.CategoryReportFilter{FromDate: from, ToDate: to, Currency: "usd"})
+	require.NoError(t, err)
+	assert.Empty(t, report.Currencies)
+
+	_, err = rs.GetCategoryReport(context.Background(), group.UUID, &models.CategoryReportFilter{FromDate: to, ToDate: from})
+	assert.Equal(t, errors.ErrCodeValidation, err.(*errors.AppError).Code)
+
+	_, err = rs.GetCategoryReport(context.Background(), group.UUID, &models.CategoryReportFilter{Currency: "dollars"})
+	assert.Error(t, err)
+}
+
+func TestReportService_GetUserStats(t *testing.T) {
+	reportRepo := new(MockReportRepository)
+	userRepo := new(MockUserRepositoryES)
+	rs := service.NewReportService(reportRepo, new(MockGroupRepositoryES), userRepo, zaptest.NewLogger(t))
+
+	user := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	reportRepo.On("GetUserActivityGroups", mock.Anything, user.ID).Return([]*models.Group{{ID: 10}}, nil)
+	reportRepo.On("GetUserPaidTotals", mock.Anything, user.ID, mock.Anything).Return([]*models.StatsAggregate{
+		{Currency: "USD", Amount: decimal.NewFromInt(100), Count: 2},
+	}, nil)
+	reportRepo.On("GetUserShareTotals", mock.Anything, user.ID, mock.Anything).Return([]*models.StatsAggregate{
+		{Currency: "USD", Amount: decimal.RequireFromString("70.01"), Count: 4},
+	}, nil)
+	reportRepo.On("GetUserExpenseActivity", mock.Anything, user.ID, mock.Anything).Return([]*models.ExpenseActivity{
+		{Currency: "USD", Count: 5, FirstExpenseDate: from.AddDate(0, 0, 10)},
+	}, nil)
+	reportRepo.On("GetUserSettlementTotals", mock.Anything, user.ID, mock.Anything).Return([]*models.SettlementStatsAggregate{
+		{Currency: "EUR", ReceivedAmount: decimal.NewFromInt(20), ReceivedCount: 1, SentAmount: decimal.Zero},
+		{Currency: "USD", SentAmount: decimal.NewFromInt(15), SentCount: 1, ReceivedAmount: decimal.Zero},
+	}, nil)
+	largest := &models.LargestExpense{UUID: "eeeeeeee-eeee-4eee-8eee-eeeeeeeeeeee", Amount: decimal.NewFromInt(60), GroupName: "Trip"}
+	reportRepo.On("GetUserLargestExpense", mock.Anything, user.ID, "USD", mock.Anything).Return(largest, nil)
+
+	stats, err := rs.GetUserStats(context.Background(), user.UUID, &models.UserStatsFilter{FromDate: from, ToDate: to})
+	require.NoError(t, err)
+	require.Len(t, stats.Currencies, 2)
+
+	// Currencies with only settlements have no largest expense and zero averages
+	eur := stats.Currencies[0]
+	assert.Equal(t, "EUR", eur.Currency)
+	assert.Equal(t, 1, eur.SettlementsReceived)
+	assert.Nil(t, eur.LargestExpense)
+	assert.True(t, eur.AveragePaidPerMonth.IsZero())
+
+	usd := stats.Currencies[1]
+	assert.True(t, decimal.NewFromInt(100).Equal(usd.TotalPaid))
+	assert.True(t, decimal.RequireFromString("70.01").Equal(usd.TotalShare))
+	assert.Equal(t, 5, usd.ExpenseCount)
+	assert.Equal(t, 1, usd.SettlementsSent)
+	assert.True(t, decimal.NewFromInt(15).Equal(usd.SettlementsSentAmount))
+	assert.Equal(t, largest, usd.LargestExpense)
+
+	// January to March is three months; averages are rounded to cents
+	assert.Equal(t, 3, usd.Months)
+	assert.Equal(t, "33.33", usd.AveragePaidPerMonth.String())
+	assert.Equal(t, "23.34", usd.AverageSharePerMonth.String())
+	reportRepo.AssertNotCalled(t, "GetUserLargestExpense", mock.Anything, user.ID, "EUR", mock.Anything)
+}
+
+func TestReportService_GetUserStats_DatesFollowGroupTimezones(t *testing.T) {
+	reportRepo := new(MockReportRepository)
+	userRepo := new(MockUserRepositoryES)
+	rs := service.NewReportService(reportRepo, new(MockGroupRepositoryES), userRepo, zaptest.NewLogger(t))
+
+	user := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	userRepo.On("GetByUUID", mock.Anything, user.UUID).Return(user, nil)
+	reportRepo.On("GetUserActivityGroups", mock.Anything, user.ID).Return([]*models.Group{
+		{ID: 10, Timezone: "Asia/Kolkata"},
+		{ID: 20},
+	}, nil)
+
+	// Each group gets the dates as days in its own timezone
+	want := []*models.GroupDateRange{
+		{GroupID: 10, FromDate: time.Date(2023, 12, 31, 18, 30, 0, 0, time.UTC), ToDate: time.Date(2024, 1, 31, 18, 30, 0, 0, time.UTC).Add(-time.Nanosecond)},
+		{GroupID: 20, FromDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ToDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)},
+	}
+	var query *models.UserStatsFilter
+	reportRepo.On("GetUserPaidTotals", mock.Anything, user.ID, mock.Anything).Run(func(args mock.Arguments) {
+		query = args.Get(2).(*models.UserStatsFilter)
+	}).Return([]*models.StatsAggregate{}, nil)
+	reportRepo.On("GetUserShareTotals", mock.Anything, user.ID, mock.Anything).Return([]*models.StatsAggregate{}, nil)
+	reportRepo.On("GetUserExpenseActivity", mock.Anything, user.ID, mock.Anything).Return([]*models.ExpenseActivity{}, nil)
+	reportRepo.On("GetUserSettlementTotals", mock.Anything, user.ID, mock.Anything).Return([]*models.SettlementStatsAggregate{}, nil)
+
+	filter := &models.UserStatsFilter{FromDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ToDate: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}
+	_, err := rs.GetUserStats(context.Background(), user.UUID, filter)
+	require.NoError(t, err)
+	require.NotNil(t, query)
+	require.Len(t, query.GroupRanges, 2)
+	for i, groupRange := range query.GroupRanges {
+		assert.Equal(t, want[i].GroupID, groupRange.GroupID)
+		assert.True(t, want[i].FromDate.Equal(groupRange.FromDate), "from %s", groupRange.FromDate)
+		assert.True(t, want[i].ToDate.Equal(groupRange.ToDate), "to %s", groupRange.ToDate)
+	}
+
+	// Timestamps are instants, so they need no per-group ranges
+	_, err = rs.GetUserStats(context.Background(), user.UUID, &models.UserStatsFilter{FromDate: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.Empty(t, query.GroupRanges)
+	reportRepo.AssertNumberOfCalls(t, "GetUserActivityGroups", 1)
+}
+
+func TestReportService_GetUserStats_InvalidInput(t *testing.T) {
+	rs := service.NewReportService(new(MockReportRepository), new(MockGroupRepositoryES), new(MockUserRepositoryES), zaptest.NewLogger(t))
+
+	_, err := rs.GetUserStats(context.Background(), "not-a-uuid", &models.UserStatsFilter{})
+	assert.Error(t, err)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	_, err = rs.GetUserStats(context.Background(), "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", &models.UserStatsFilter{FromDate: from, ToDate: from.AddDate(0, 0, -1)})
+	assert.Equal(t, errors.ErrCodeValidation, err.(*errors.AppError).Code)
+}
@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"expense-split-tracker/internal/logging"
+	"expense-split-tracker/internal/middleware"
+	"expense-split-tracker/internal/utils"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newRequestIDRouter(logger *zap.Logger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.StructuredLoggingMiddleware(logger))
+	router.GET("/api/v1/groups/:uuid", func(c *gin.Context) {
+		// Stands in for a service logging with the request context
+		logging.FromContext(c.Request.Context(), logger).Info("Group lookup failed")
+		response.Error(c, errors.NewNotFoundError("Group"))
+	})
+	return router
+}
+
+func TestRequestID_FlowsToResponseAndLogs(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	router := newRequestIDRouter(zap.New(core))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/groups/abc", nil)
+	req.Header.Set(middleware.RequestIDHeader, "client-req-42")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "client-req-42", rec.Header().Get(middleware.RequestIDHeader))
+
+	var resp response.APIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "client-req-42", resp.Error.RequestID)
+
+	entries := logs.All()
+	if assert.Len(t, entries, 2) {
+		for _, entry := range entries {
+			assert.Equal(t, "client-req-42", entry.ContextMap()["request_id"], entry.Message)
+		}
+	}
+}
+
+func TestRequestID_GeneratedWhenMissingOrInvalid(t *testing.T) {
+	router := newRequestIDRouter(zap.NewNop())
+
+	for _, incoming := range []string{"", "not a valid id\n"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/groups/abc", nil)
+		if incoming != "" {
+			req.Header.Set(middleware.RequestIDHeader, incoming)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		requestID := rec.Header().Get(middleware.RequestIDHeader)
+		assert.True(t, utils.IsValidUUID(requestID), "got %q", requestID)
+
+		var resp response.APIResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, requestID, resp.Error.RequestID)
+	}
+}
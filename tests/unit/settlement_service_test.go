@@ -2,12 +2,19 @@ package unit
 
 import (
 	"context"
+	"net/http"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"expense-split-tracker/internal/auth"
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -51,6 +58,16 @@ func (m *MockSettlementRepository) List(ctx context.Context, filter *models.Sett
 	return args.Get(0).([]*models.Settlement), args.Int(1), args.Error(2)
 }
 
+func (m *MockSettlementRepository) Void(ctx context.Context, tx *database.Tx, id int64, voidedBy *int64) (bool, error) {
+	args := m.Called(ctx, tx, id, voidedBy)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSettlementRepository) UpdateStatus(ctx context.Context, tx *database.Tx, id int64, from, to models.SettlementStatus) (bool, error) {
+	args := m.Called(ctx, tx, id, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockSettlementRepository) GetGroupSettlements(ctx context.Context, groupID int64, offset, limit int) ([]*models.Settlement, error) {
 	args := m.Called(ctx, groupID, offset, limit)
 	return args.Get(0).([]*models.Settlement), args.Error(1)
@@ -61,13 +78,95 @@ func (m *MockSettlementRepository) GetUserSettlements(ctx context.Context, userI
 	return args.Get(0).([]*models.Settlement), args.Error(1)
 }
 
+func (m *MockSettlementRepository) CountGroupSettlements(ctx context.Context, groupID int64) (int, error) {
+	args := m.Called(ctx, groupID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSettlementRepository) CountUserSettlements(ctx context.Context, userID int64) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+// IterateGroupSettlements hands each configured batch to fn in order
+func (m *MockSettlementRepository) IterateGroupSettlements(ctx context.Context, groupID int64, batchSize int, fn func([]*models.Settlement) error) error {
+	args := m.Called(ctx, groupID, batchSize)
+	for _, batch := range args.Get(0).([][]*models.Settlement) {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func (m *MockBalanceRepository2) UpdateBalance(ctx context.Context, tx *database.Tx, groupID, userID int64, amount decimal.Decimal, currency string) error {
 	args := m.Called(ctx, tx, groupID, userID, amount, currency)
 	return args.Error(0)
 }
 
+func (m *MockBalanceRepository2) UpdateDebt(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, amount decimal.Decimal, currency string) error {
+	args := m.Called(ctx, tx, groupID, debtorID, creditorID, amount, currency)
+	return args.Error(0)
+}
+
+func (m *MockBalanceRepository2) GetDebtForUpdate(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, currency string) (decimal.Decimal, error) {
+	args := m.Called(ctx, tx, groupID, debtorID, creditorID, currency)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockBalanceRepository2) GetGroupDebts(ctx context.Context, groupID int64, currency string) ([]*models.DebtRelationship, error) {
+	args := m.Called(ctx, groupID, currency)
+	return args.Get(0).([]*models.DebtRelationship), args.Error(1)
+}
+
+func (m *MockBalanceRepository2) GetGroupDebtsAllCurrencies(ctx context.Context, groupID int64) ([]*models.DebtRelationship, error) {
+	args := m.Called(ctx, groupID)
+	return args.Get(0).([]*models.DebtRelationship), args.Error(1)
+}
+
+func (m *MockBalanceRepository2) GetPairDebts(ctx context.Context, groupID, userID, otherUserID int64) ([]*models.PairDebt, error) {
+	args := m.Called(ctx, groupID, userID, otherUserID)
+	return args.Get(0).([]*models.PairDebt), args.Error(1)
+}
+
+func (m *MockBalanceRepository2) ClearGroupDebts(ctx context.Context, tx *database.Tx, groupID int64, currency string) error {
+	args := m.Called(ctx, tx, groupID, currency)
+	return args.Error(0)
+}
+
+func (m *MockBalanceRepository2) ZeroGroupBalances(ctx context.Context, tx *database.Tx, groupID int64) error {
+	args := m.Called(ctx, tx, groupID)
+	return args.Error(0)
+}
+
+func (m *MockBalanceRepository2) ZeroGroupDebts(ctx context.Context, tx *database.Tx, groupID int64) error {
+	args := m.Called(ctx, tx, groupID)
+	return args.Error(0)
+}
+
+func (m *MockBalanceRepository2) GetUserExpenseTotals(ctx context.Context, groupID, userID int64, currency string) (*models.ExpenseTotals, error) {
+	args := m.Called(ctx, groupID, userID, currency)
+	return args.Get(0).(*models.ExpenseTotals), args.Error(1)
+}
+
+func (m *MockBalanceRepository2) GetUserSettlementTotals(ctx context.Context, groupID, userID int64, currency string) (*models.SettlementTotals, error) {
+	args := m.Called(ctx, groupID, userID, currency)
+	return args.Get(0).(*models.SettlementTotals), args.Error(1)
+}
+
+func (m *MockBalanceRepository2) GetUserExpenseTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.ExpenseTotals, error) {
+	args := m.Called(ctx, groupID, userID)
+	return args.Get(0).([]*models.ExpenseTotals), args.Error(1)
+}
+
+func (m *MockBalanceRepository2) GetUserSettlementTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.SettlementTotals, error) {
+	args := m.Called(ctx, groupID, userID)
+	return args.Get(0).([]*models.SettlementTotals), args.Error(1)
+}
+
 func (m *MockBalanceRepository2) Upsert(ctx context.Context, tx *database.Tx, balance *models.Balance) error {
-	return nil
+	args := m.Called(ctx, tx, balance)
+	return args.Error(0)
 }
 func (m *MockBalanceRepository2) GetByGroupAndUser(ctx context.Context, groupID, userID int64, currency string) (*models.Balance, error) {
 	args := m.Called(ctx, groupID, userID, currency)
@@ -76,11 +175,27 @@ func (m *MockBalanceRepository2) GetByGroupAndUser(ctx context.Context, groupID,
 	}
 	return args.Get(0).(*models.Balance), args.Error(1)
 }
+func (m *MockBalanceRepository2) GetByGroupAndUserForUpdate(ctx context.Context, tx *database.Tx, groupID, userID int64, currency string) (*models.Balance, error) {
+	args := m.Called(ctx, tx, groupID, userID, currency)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Balance), args.Error(1)
+}
 func (m *MockBalanceRepository2) GetGroupBalances(ctx context.Context, groupID int64, currency string) ([]*models.Balance, error) {
 	return nil, nil
 }
+func (m *MockBalanceRepository2) GetGroupBalancesAllCurrencies(ctx context.Context, groupID int64) ([]*models.Balance, error) {
+	args := m.Called(ctx, groupID)
+	return args.Get(0).([]*models.Balance), args.Error(1)
+}
+func (m *MockBalanceRepository2) GetGroupBalancesForUpdate(ctx context.Context, tx *database.Tx, groupID int64, currency string) ([]*models.Balance, error) {
+	args := m.Called(ctx, tx, groupID, currency)
+	return args.Get(0).([]*models.Balance), args.Error(1)
+}
 func (m *MockBalanceRepository2) GetUserBalances(ctx context.Context, userID int64) ([]*models.Balance, error) {
-	return nil, nil
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Balance), args.Error(1)
 }
 
 func (m *MockGroupRepository2) GetByUUID(ctx context.Context, uuid string) (*models.Group, error) {
@@ -90,19 +205,36 @@ func (m *MockGroupRepository2) GetByUUID(ctx context.Context, uuid string) (*mod
 	}
 	return args.Get(0).(*models.Group), args.Error(1)
 }
+
+func (m *MockGroupRepository2) Update(ctx context.Context, tx *database.Tx, group *models.Group) error {
+	args := m.Called(ctx, tx, group)
+	return args.Error(0)
+}
 func (m *MockGroupRepository2) Create(ctx context.Context, tx *database.Tx, group *models.Group) error {
 	return nil
 }
 func (m *MockGroupRepository2) GetByID(ctx context.Context, id int64) (*models.Group, error) {
 	return nil, nil
 }
-func (m *MockGroupRepository2) List(ctx context.Context, offset, limit int) ([]*models.Group, error) {
+func (m *MockGroupRepository2) List(ctx context.Context, offset, limit int, includeArchived bool) ([]*models.Group, error) {
 	return nil, nil
 }
-func (m *MockGroupRepository2) GetUserGroups(ctx context.Context, userID int64, offset, limit int) ([]*models.Group, error) {
+func (m *MockGroupRepository2) GetUserGroups(ctx context.Context, userID int64, offset, limit int, includeArchived bool) ([]*models.Group, error) {
 	return nil, nil
 }
-func (m *MockGroupRepository2) AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
+func (m *MockGroupRepository2) Count(ctx context.Context, includeArchived bool) (int, error) {
+	return 0, nil
+}
+func (m *MockGroupRepository2) CountUserGroups(ctx context.Context, userID int64, includeArchived bool) (int, error) {
+	return 0, nil
+}
+func (m *MockGroupRepository2) Archive(ctx context.Context, tx *database.Tx, id int64) (bool, error) {
+	return true, nil
+}
+func (m *MockGroupRepository2) Unarchive(ctx context.Context, tx *database.Tx, id int64) (bool, error) {
+	return true, nil
+}
+func (m *MockGroupRepository2) AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error {
 	return nil
 }
 func (m *MockGroupRepository2) RemoveMember(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
@@ -111,6 +243,39 @@ func (m *MockGroupRepository2) RemoveMember(ctx context.Context, tx *database.Tx
 func (m *MockGroupRepository2) GetMembers(ctx context.Context, groupID int64) ([]*models.User, error) {
 	return nil, nil
 }
+func (m *MockGroupRepository2) GetMemberships(ctx context.Context, groupID int64) ([]*models.GroupMember, error) {
+	return nil, nil
+}
+func (m *MockGroupRepository2) AreMembers(ctx context.Context, groupID int64, userIDs []int64) (map[int64]bool, error) {
+	members := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		isMember, err := m.IsMember(ctx, groupID, id)
+		if err != nil {
+			return nil, err
+		}
+		members[id] = isMember
+	}
+	return members, nil
+}
+func (m *MockGroupRepository2) GetMemberRole(ctx context.Context, groupID, userID int64) (models.GroupRole, error) {
+	return models.GroupRoleMember, nil
+}
+func (m *MockGroupRepository2) SetOwner(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
+	return nil
+}
+func (m *MockGroupRepository2) SetMemberRole(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error {
+	return nil
+}
+func (m *MockGroupRepository2) CountAdminsForUpdate(ctx context.Context, tx *database.Tx, groupID int64) (int, error) {
+	return 1, nil
+}
+func (m *MockGroupRepository2) GetSharedGroups(ctx context.Context, userID, otherUserID int64) ([]*models.Group, error) {
+	args := m.Called(ctx, userID, otherUserID)
+	return args.Get(0).([]*models.Group), args.Error(1)
+}
+func (m *MockGroupRepository2) CountMembers(ctx context.Context, groupID int64) (int, error) {
+	return 0, nil
+}
 func (m *MockGroupRepository2) IsMember(ctx context.Context, groupID, userID int64) (bool, error) {
 	args := m.Called(ctx, groupID, userID)
 	return args.Bool(0), args.Error(1)
@@ -132,12 +297,39 @@ func (m *MockUserRepository2) GetByID(ctx context.Context, id int64) (*models.Us
 func (m *MockUserRepository2) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	return nil, nil
 }
+func (m *MockUserRepository2) GetByUUIDs(ctx context.Context, uuids []string) ([]*models.User, error) {
+	users := []*models.User{}
+	for _, uuid := range uuids {
+		user, err := m.GetByUUID(ctx, uuid)
+		if err != nil {
+			if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrCodeNotFound {
+				continue
+			}
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
 func (m *MockUserRepository2) List(ctx context.Context, offset, limit int) ([]*models.User, error) {
 	return nil, nil
 }
 
-func (m *MockDB2) WithTransaction(fn func(tx *database.Tx) error) error {
-	args := m.Called(fn)
+func (m *MockUserRepository2) Search(ctx context.Context, query string, offset, limit int) ([]*models.User, error) {
+	return nil, nil
+}
+
+func (m *MockUserRepository2) CountSearch(ctx context.Context, query string) (int, error) {
+	return 0, nil
+}
+
+func (m *MockUserRepository2) Count(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockDB2) WithTransaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	args := m.Called(ctx, fn)
 	if err := fn(nil); err != nil {
 		return err
 	}
@@ -154,9 +346,9 @@ func TestSettlementService_CreateSettlement_Success(t *testing.T) {
 	balanceRepo := new(MockBalanceRepository2)
 	db := new(MockDB2)
 
-	group := &models.Group{ID: 10, UUID: "11111111-1111-1111-1111-111111111111"}
-	fromUser := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}
-	toUser := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"}
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	fromUser := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	toUser := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
 	currency := "USD"
 
 	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
@@ -164,22 +356,24 @@ func TestSettlementService_CreateSettlement_Success(t *testing.T) {
 	userRepo.On("GetByUUID", mock.Anything, toUser.UUID).Return(toUser, nil)
 	groupRepo.On("IsMember", mock.Anything, group.ID, fromUser.ID).Return(true, nil)
 	groupRepo.On("IsMember", mock.Anything, group.ID, toUser.ID).Return(true, nil)
-	balanceRepo.On("GetByGroupAndUser", mock.Anything, group.ID, fromUser.ID, currency).Return(&models.Balance{GroupID: group.ID, UserID: fromUser.ID, Balance: decimal.NewFromInt(100), Currency: currency}, nil)
+	balanceRepo.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, fromUser.ID, toUser.ID, currency).Return(decimal.NewFromInt(100), nil)
+	balanceRepo.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, group.ID, toUser.ID, currency).Return(&models.Balance{Balance: decimal.NewFromInt(-100)}, nil)
 
 	settlementRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Settlement")).Return(nil)
 	settlementRepo.On("GetByUUID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Settlement{}, nil)
 
 	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, fromUser.ID, decimal.NewFromInt(50).Neg(), currency).Return(nil)
 	balanceRepo.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, toUser.ID, decimal.NewFromInt(50), currency).Return(nil)
+	balanceRepo.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, currency).Return(nil)
 
-	db.On("WithTransaction", mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
 
-	s := service.NewSettlementService(settlementRepo, groupRepo, userRepo, balanceRepo, db, logger)
+	s := service.NewSettlementService(settlementRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, logger)
 
 	res, err := s.CreateSettlement(ctx, &models.CreateSettlementRequest{
-		GroupUUID:    group.UUID,
-		FromUserUUID: fromUser.UUID,
-		ToUserUUID:   toUser.UUID,
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(fromUser.UUID),
+		ToUserUUID:   models.UserUUID(toUser.UUID),
 		Amount:       decimal.NewFromInt(50),
 		Currency:     currency,
 	})
@@ -197,23 +391,24 @@ func TestSettlementService_CreateSettlement_AmountExceedsDebt(t *testing.T) {
 	br := new(MockBalanceRepository2)
 	db := new(MockDB2)
 
-	group := &models.Group{ID: 10, UUID: "11111111-1111-1111-1111-111111111111"}
-	fromUser := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}
-	toUser := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"}
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	fromUser := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	toUser := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
 
 	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
 	ur.On("GetByUUID", mock.Anything, fromUser.UUID).Return(fromUser, nil)
 	ur.On("GetByUUID", mock.Anything, toUser.UUID).Return(toUser, nil)
 	gr.On("IsMember", mock.Anything, group.ID, fromUser.ID).Return(true, nil)
 	gr.On("IsMember", mock.Anything, group.ID, toUser.ID).Return(true, nil)
-	br.On("GetByGroupAndUser", mock.Anything, group.ID, fromUser.ID, "USD").Return(&models.Balance{GroupID: group.ID, UserID: fromUser.ID, Balance: decimal.NewFromInt(20), Currency: "USD"}, nil)
+	br.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, fromUser.ID, toUser.ID, "USD").Return(decimal.NewFromInt(20), nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
 
-	s := service.NewSettlementService(sr, gr, ur, br, db, logger)
+	s := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, logger)
 
 	res, err := s.CreateSettlement(ctx, &models.CreateSettlementRequest{
-		GroupUUID:    group.UUID,
-		FromUserUUID: fromUser.UUID,
-		ToUserUUID:   toUser.UUID,
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(fromUser.UUID),
+		ToUserUUID:   models.UserUUID(toUser.UUID),
 		Amount:       decimal.NewFromInt(50),
 		Currency:     "USD",
 	})
@@ -226,12 +421,12 @@ func TestSettlementService_CreateSettlement_SameUser(t *testing.T) {
 	ctx := context.Background()
 	logger := zaptest.NewLogger(t)
 
-	s := service.NewSettlementService(new(MockSettlementRepository), new(MockGroupRepository2), new(MockUserRepository2), new(MockBalanceRepository2), new(MockDB2), logger)
+	s := service.NewSettlementService(new(MockSettlementRepository), new(MockGroupRepository2), new(MockUserRepository2), new(MockBalanceRepository2), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB2), events.NopEmitter{}, metrics.Nop{}, logger)
 
 	res, err := s.CreateSettlement(ctx, &models.CreateSettlementRequest{
-		GroupUUID:    "11111111-1111-1111-1111-111111111111",
-		FromUserUUID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
-		ToUserUUID:   "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+		GroupUUID:    "11111111-1111-4111-8111-111111111111",
+		FromUserUUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa",
+		ToUserUUID:   "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa",
 		Amount:       decimal.NewFromInt(10),
 		Currency:     "USD",
 	})
@@ -239,3 +434,732 @@ func TestSettlementService_CreateSettlement_SameUser(t *testing.T) {
 	assert.Nil(t, res)
 	assert.Contains(t, err.Error(), "cannot be the same")
 }
+
+func TestSettlementService_CreateSettlement_CommitFailure(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	sr := new(MockSettlementRepository)
+	gr := new(MockGroupRepository2)
+	ur := new(MockUserRepository2)
+	br := new(MockBalanceRepository2)
+	db := new(MockDB2)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	fromUser := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	toUser := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	ur.On("GetByUUID", mock.Anything, fromUser.UUID).Return(fromUser, nil)
+	ur.On("GetByUUID", mock.Anything, toUser.UUID).Return(toUser, nil)
+	gr.On("IsMember", mock.Anything, group.ID, fromUser.ID).Return(true, nil)
+	gr.On("IsMember", mock.Anything, group.ID, toUser.ID).Return(true, nil)
+	br.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, fromUser.ID, toUser.ID, "USD").Return(decimal.NewFromInt(100), nil)
+	br.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, group.ID, toUser.ID, "USD").Return(&models.Balance{Balance: decimal.NewFromInt(-100)}, nil)
+
+	sr.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Settlement")).Return(nil)
+	br.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	br.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+
+	// fn succeeds but the commit itself fails
+	commitErr := errors.NewDatabaseError(nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(commitErr)
+
+	s := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	res, err := s.CreateSettlement(ctx, &models.CreateSettlementRequest{
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(fromUser.UUID),
+		ToUserUUID:   models.UserUUID(toUser.UUID),
+		Amount:       decimal.NewFromInt(50),
+		Currency:     "USD",
+	})
+	assert.Equal(t, commitErr, err)
+	assert.Nil(t, res)
+	sr.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+}
+
+func TestSettlementService_CreateSettlement_RejectsSubCentAmount(t *testing.T) {
+	sr := new(MockSettlementRepository)
+	s := service.NewSettlementService(sr, new(MockGroupRepository2), new(MockUserRepository2), new(MockBalanceRepository2), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB2), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	settlement, err := s.CreateSettlement(context.Background(), &models.CreateSettlementRequest{
+		GroupUUID:    "11111111-1111-4111-8111-111111111111",
+		FromUserUUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa",
+		ToUserUUID:   "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb",
+		Amount:       decimal.RequireFromString("10.999"),
+		Currency:     "USD",
+	})
+	assert.Nil(t, settlement)
+	appErr, ok := err.(*errors.AppError)
+	if assert.True(t, ok) {
+		assert.Equal(t, "USD amounts can have at most 2 decimal places", appErr.Message)
+	}
+	sr.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestSettlementService_ValidateSettlementAmounts runs every combination of the
+// payer's debt to the receiver and the receiver's overall balance through both
+// creating and confirming a settlement, which must agree
+func TestSettlementService_ValidateSettlementAmounts(t *testing.T) {
+	cases := []struct {
+		name           string
+		owed           string
+		toBalance      string
+		amount         int64
+		currency       string
+		allowOvershoot bool
+		wantCode       string
+	}{
+		{name: "settles the pairwise debt exactly", owed: "50", toBalance: "-50", amount: 50},
+		{name: "partial payment", owed: "80", toBalance: "-80", amount: 30},
+		{name: "recipient lands within tolerance", owed: "50", toBalance: "-49.99", amount: 50},
+		{name: "pays more than owed to this receiver", owed: "20", toBalance: "-50", amount: 30, wantCode: errors.ErrCodeInsufficientFund},
+		{name: "owes this receiver nothing", owed: "0", toBalance: "-50", amount: 10, wantCode: errors.ErrCodeInsufficientFund},
+		{name: "receiver owes the payer", owed: "-15", toBalance: "15", amount: 10, wantCode: errors.ErrCodeInsufficientFund},
+		{name: "receiver owes the payer and the group owes the receiver", owed: "-15", toBalance: "-40", amount: 10, wantCode: errors.ErrCodeInsufficientFund},
+		{name: "payer has no balance in this currency", owed: "0", toBalance: "0", amount: 10, currency: "EUR", wantCode: errors.ErrCodeCurrencyMismatch},
+		{name: "recipient flips from owed to owing", owed: "50", toBalance: "-10", amount: 50, wantCode: errors.ErrCodeOvershoot},
+		{name: "recipient already settled", owed: "50", toBalance: "0", amount: 10, wantCode: errors.ErrCodeOvershoot},
+		{name: "recipient already owing", owed: "50", toBalance: "5", amount: 10, wantCode: errors.ErrCodeOvershoot},
+		{name: "pairwise check runs before the overshoot check", owed: "20", toBalance: "5", amount: 30, wantCode: errors.ErrCodeInsufficientFund},
+		{name: "overshoot explicitly allowed", owed: "50", toBalance: "-10", amount: 50, allowOvershoot: true},
+		{name: "overshoot allowed for a settled recipient", owed: "50", toBalance: "0", amount: 10, allowOvershoot: true},
+		{name: "overshoot allowed for an owing recipient", owed: "50", toBalance: "5", amount: 10, allowOvershoot: true},
+		{name: "allow overshoot keeps pairwise check", owed: "20", toBalance: "-10", amount: 50, allowOvershoot: true, wantCode: errors.ErrCodeInsufficientFund},
+		{name: "allow overshoot keeps the reverse debt check", owed: "-15", toBalance: "15", amount: 10, allowOvershoot: true, wantCode: errors.ErrCodeInsufficientFund},
+	}
+
+	for _, tc := range cases {
+		for _, path := range []string{"create", "confirm"} {
+			t.Run(tc.name+"/"+path, func(t *testing.T) {
+				ctx := context.Background()
+				logger := zaptest.NewLogger(t)
+				currency := tc.currency
+				if currency == "" {
+					currency = "USD"
+				}
+
+				sr := new(MockSettlementRepository)
+				gr := new(MockGroupRepository2)
+				ur := new(MockUserRepository2)
+				br := new(MockBalanceRepository2)
+				db := new(MockDB2)
+
+				group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+				fromUser := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+				toUser := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+				pending := newPendingSettlement()
+				pending.Amount = decimal.NewFromInt(tc.amount)
+				pending.Currency = currency
+
+				gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+				ur.On("GetByUUID", mock.Anything, fromUser.UUID).Return(fromUser, nil)
+				ur.On("GetByUUID", mock.Anything, toUser.UUID).Return(toUser, nil)
+				gr.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+				br.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{
+					{GroupID: group.ID, UserID: fromUser.ID, Currency: "USD", Balance: decimal.RequireFromString(tc.owed)},
+					{GroupID: group.ID, UserID: toUser.ID, Currency: "USD", Balance: decimal.RequireFromString(tc.owed).Neg()},
+				}, nil).Maybe()
+				br.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, fromUser.ID, toUser.ID, currency).Return(decimal.RequireFromString(tc.owed), nil)
+				br.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, group.ID, toUser.ID, currency).Return(&models.Balance{Balance: decimal.RequireFromString(tc.toBalance)}, nil)
+				br.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+				br.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, fromUser.ID, toUser.ID, decimal.NewFromInt(tc.amount).Neg(), "USD").Return(nil)
+				sr.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Settlement")).Return(nil)
+				sr.On("GetByUUID", mock.Anything, pending.UUID).Return(pending, nil)
+				sr.On("GetByUUID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Settlement{}, nil)
+				sr.On("UpdateStatus", mock.Anything, mock.Anything, pending.ID, models.SettlementStatusPending, models.SettlementStatusConfirmed).Return(true, nil)
+				db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+				s := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, logger)
+
+				var err error
+				if path == "create" {
+					_, err = s.CreateSettlement(ctx, &models.CreateSettlementRequest{
+						GroupUUID:      models.GroupUUID(group.UUID),
+						FromUserUUID:   models.UserUUID(fromUser.UUID),
+						ToUserUUID:     models.UserUUID(toUser.UUID),
+						Amount:         decimal.NewFromInt(tc.amount),
+						Currency:       currency,
+						AllowOvershoot: tc.allowOvershoot,
+					})
+				} else {
+					_, err = s.ConfirmSettlement(ctx, pending.UUID, &models.RespondSettlementRequest{
+						UserUUID:       models.UserUUID(toUser.UUID),
+						AllowOvershoot: tc.allowOvershoot,
+					})
+				}
+
+				if tc.wantCode == "" {
+					assert.NoError(t, err)
+					br.AssertCalled(t, "UpdateDebt", mock.Anything, mock.Anything, group.ID, fromUser.ID, toUser.ID, decimal.NewFromInt(tc.amount).Neg(), "USD")
+					return
+				}
+
+				appErr, ok := err.(*errors.AppError)
+				if !assert.True(t, ok) {
+					return
+				}
+				assert.Equal(t, tc.wantCode, appErr.Code)
+				if tc.wantCode == errors.ErrCodeCurrencyMismatch {
+					assert.Equal(t, "USD", appErr.Details["currencies_in_use"])
+				}
+				assert.Equal(t, http.StatusBadRequest, appErr.Status)
+				sr.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+				sr.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				br.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	}
+}
+
+func TestSettlementService_CreateSettlement_PairwiseDebt(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	sr := new(MockSettlementRepository)
+	gr := new(MockGroupRepository2)
+	ur := new(MockUserRepository2)
+	br := new(MockBalanceRepository2)
+	db := new(MockDB2)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	// Alice owes Bob 100 overall but owes Carol only 20
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	ur.On("GetByUUID", mock.Anything, alice.UUID).Return(alice, nil)
+	ur.On("GetByUUID", mock.Anything, carol.UUID).Return(carol, nil)
+	gr.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+	br.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, alice.ID, carol.ID, "USD").Return(decimal.NewFromInt(20), nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	s := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	_, err := s.CreateSettlement(ctx, &models.CreateSettlementRequest{
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(alice.UUID),
+		ToUserUUID:   models.UserUUID(carol.UUID),
+		Amount:       decimal.NewFromInt(70),
+		Currency:     "USD",
+	})
+
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInsufficientFund, appErr.Code)
+	assert.Equal(t, "20", appErr.Details["available"])
+	assert.Equal(t, "70", appErr.Details["required"])
+	br.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSettlementService_CreateSettlement_OvershootDetails(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	sr := new(MockSettlementRepository)
+	gr := new(MockGroupRepository2)
+	ur := new(MockUserRepository2)
+	br := new(MockBalanceRepository2)
+	db := new(MockDB2)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	// Alice owes Bob 70, but Bob owes the rest of the group more than he is
+	// owed, so he is only 20 in credit overall
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	ur.On("GetByUUID", mock.Anything, alice.UUID).Return(alice, nil)
+	ur.On("GetByUUID", mock.Anything, bob.UUID).Return(bob, nil)
+	gr.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+	br.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, alice.ID, bob.ID, "USD").Return(decimal.NewFromInt(70), nil)
+	br.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, group.ID, bob.ID, "USD").Return(&models.Balance{Balance: decimal.NewFromInt(-20)}, nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	s := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, logger)
+
+	_, err := s.CreateSettlement(ctx, &models.CreateSettlementRequest{
+		GroupUUID:    models.GroupUUID(group.UUID),
+		FromUserUUID: models.UserUUID(alice.UUID),
+		ToUserUUID:   models.UserUUID(bob.UUID),
+		Amount:       decimal.NewFromInt(70),
+		Currency:     "USD",
+	})
+
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeOvershoot, appErr.Code)
+	assert.Equal(t, "-20.00", appErr.Details["recipient_balance_before"])
+	assert.Equal(t, "50.00", appErr.Details["recipient_balance_after"])
+	sr.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// serialDB runs transactions one at a time, standing in for the row locks a
+// SELECT ... FOR UPDATE holds until commit
+type serialDB struct{ mu sync.Mutex }
+
+func (d *serialDB) WithTransaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return fn(nil)
+}
+
+// ledgerBalanceRepository keeps one pairwise debt in memory so concurrent
+// settlements see each other's updates
+type ledgerBalanceRepository struct {
+	*MockBalanceRepository2
+	owed decimal.Decimal
+}
+
+func (r *ledgerBalanceRepository) GetDebtForUpdate(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, currency string) (decimal.Decimal, error) {
+	return r.owed, nil
+}
+
+func (r *ledgerBalanceRepository) GetByGroupAndUserForUpdate(ctx context.Context, tx *database.Tx, groupID, userID int64, currency string) (*models.Balance, error) {
+	return &models.Balance{GroupID: groupID, UserID: userID, Balance: r.owed.Neg(), Currency: currency}, nil
+}
+
+func (r *ledgerBalanceRepository) UpdateDebt(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, amount decimal.Decimal, currency string) error {
+	r.owed = r.owed.Add(amount)
+	return nil
+}
+
+func TestSettlementService_CreateSettlement_ConcurrentDoubleSpend(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	fromUser := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	toUser := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	gr := new(MockGroupRepository2)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	gr.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+	ur := new(MockUserRepository2)
+	ur.On("GetByUUID", mock.Anything, fromUser.UUID).Return(fromUser, nil)
+	ur.On("GetByUUID", mock.Anything, toUser.UUID).Return(toUser, nil)
+	sr := new(MockSettlementRepository)
+	sr.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Settlement")).Return(nil)
+	sr.On("GetByUUID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Settlement{}, nil)
+	br := &ledgerBalanceRepository{MockBalanceRepository2: new(MockBalanceRepository2), owed: decimal.NewFromInt(50)}
+	br.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	// Settled balance rows stay behind at zero, so the loser is still told it owes nothing in USD
+	br.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{
+		{GroupID: group.ID, UserID: fromUser.ID, Currency: "USD"},
+		{GroupID: group.ID, UserID: toUser.ID, Currency: "USD"},
+	}, nil)
+
+	s := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), &serialDB{}, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	// Both requests pay off the whole debt; only one of them may go through
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.CreateSettlement(ctx, &models.CreateSettlementRequest{
+				GroupUUID:    models.GroupUUID(group.UUID),
+				FromUserUUID: models.UserUUID(fromUser.UUID),
+				ToUserUUID:   models.UserUUID(toUser.UUID),
+				Amount:       decimal.NewFromInt(50),
+				Currency:     "USD",
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeInsufficientFund, appErr.Code)
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.True(t, br.owed.IsZero())
+	sr.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestSettlementService_VoidSettlement_RestoresBalances(t *testing.T) {
+	ctx := context.Background()
+
+	settlement := &models.Settlement{
+		ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", GroupID: 10,
+		FromUserID: 1, ToUserID: 2, Amount: decimal.NewFromInt(50), Currency: "USD", Status: models.SettlementStatusConfirmed,
+	}
+	now := time.Now()
+	voided := *settlement
+	voided.VoidedAt = &now
+
+	sr := new(MockSettlementRepository)
+	sr.On("GetByUUID", mock.Anything, settlement.UUID).Return(settlement, nil).Once()
+	sr.On("GetByUUID", mock.Anything, settlement.UUID).Return(&voided, nil)
+	sr.On("Void", mock.Anything, mock.Anything, settlement.ID, mock.Anything).Return(true, nil)
+	br := new(MockBalanceRepository2)
+	br.On("UpdateBalance", mock.Anything, mock.Anything, int64(10), int64(1), decimal.NewFromInt(50), "USD").Return(nil)
+	br.On("UpdateBalance", mock.Anything, mock.Anything, int64(10), int64(2), decimal.NewFromInt(50).Neg(), "USD").Return(nil)
+	br.On("UpdateDebt", mock.Anything, mock.Anything, int64(10), int64(1), int64(2), decimal.NewFromInt(50), "USD").Return(nil)
+	db := new(MockDB2)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	s := service.NewSettlementService(sr, new(MockGroupRepository2), new(MockUserRepository2), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	res, err := s.VoidSettlement(ctx, settlement.UUID)
+	assert.NoError(t, err)
+	assert.NotNil(t, res.VoidedAt)
+	br.AssertNumberOfCalls(t, "UpdateBalance", 2)
+}
+
+func TestSettlementService_VoidSettlement_AlreadyVoided(t *testing.T) {
+	ctx := context.Background()
+
+	settlement := &models.Settlement{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", GroupID: 10, Amount: decimal.NewFromInt(50), Currency: "USD", Status: models.SettlementStatusConfirmed}
+	now := time.Now()
+	voided := *settlement
+	voided.VoidedAt = &now
+
+	sr := new(MockSettlementRepository)
+	sr.On("GetByUUID", mock.Anything, "dddddddd-dddd-4ddd-8ddd-dddddddddddd").Return(&voided, nil)
+	sr.On("GetByUUID", mock.Anything, settlement.UUID).Return(settlement, nil)
+	// A concurrent void got there first
+	sr.On("Void", mock.Anything, mock.Anything, settlement.ID, mock.Anything).Return(false, nil)
+	br := new(MockBalanceRepository2)
+	db := new(MockDB2)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	s := service.NewSettlementService(sr, new(MockGroupRepository2), new(MockUserRepository2), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	for _, uuid := range []string{"dddddddd-dddd-4ddd-8ddd-dddddddddddd", settlement.UUID} {
+		_, err := s.VoidSettlement(ctx, uuid)
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.Equal(t, errors.ErrCodeAlreadyVoided, appErr.Code)
+		assert.Equal(t, http.StatusConflict, appErr.Status)
+	}
+	br.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSettlementService_CreateSettlement_RequireConfirmation(t *testing.T) {
+	ctx := context.Background()
+
+	sr := new(MockSettlementRepository)
+	gr := new(MockGroupRepository2)
+	ur := new(MockUserRepository2)
+	br := new(MockBalanceRepository2)
+	db := new(MockDB2)
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	fromUser := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	toUser := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	ur.On("GetByUUID", mock.Anything, fromUser.UUID).Return(fromUser, nil)
+	ur.On("GetByUUID", mock.Anything, toUser.UUID).Return(toUser, nil)
+	gr.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+	br.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, fromUser.ID, toUser.ID, "USD").Return(decimal.NewFromInt(100), nil)
+	br.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, group.ID, toUser.ID, "USD").Return(&models.Balance{Balance: decimal.NewFromInt(-100)}, nil)
+	var created *models.Settlement
+	sr.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Settlement")).
+		Run(func(args mock.Arguments) { created = args.Get(2).(*models.Settlement) }).Return(nil)
+	sr.On("GetByUUID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Settlement{}, nil)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	s := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	_, err := s.CreateSettlement(ctx, &models.CreateSettlementRequest{
+		GroupUUID:           models.GroupUUID(group.UUID),
+		FromUserUUID:        models.UserUUID(fromUser.UUID),
+		ToUserUUID:          models.UserUUID(toUser.UUID),
+		Amount:              decimal.NewFromInt(50),
+		RequireConfirmation: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.SettlementStatusPending, created.Status)
+	br.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSettlementService_CreateSettlement_Method(t *testing.T) {
+	cases := []struct {
+		name     string
+		method   models.SettlementMethod
+		want     models.SettlementMethod
+		wantCode string
+	}{
+		{name: "empty defaults to other", method: "", want: models.SettlementMethodOther},
+		{name: "known method kept", method: models.SettlementMethodUPI, want: models.SettlementMethodUPI},
+		{name: "unknown method rejected", method: "cheque", wantCode: errors.ErrCodeInvalid},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			sr := new(MockSettlementRepository)
+			gr := new(MockGroupRepository2)
+			ur := new(MockUserRepository2)
+			br := new(MockBalanceRepository2)
+			db := new(MockDB2)
+
+			group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+			fromUser := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+			toUser := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+
+			gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			ur.On("GetByUUID", mock.Anything, fromUser.UUID).Return(fromUser, nil)
+			ur.On("GetByUUID", mock.Anything, toUser.UUID).Return(toUser, nil)
+			gr.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+			br.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, fromUser.ID, toUser.ID, "USD").Return(decimal.NewFromInt(100), nil)
+			br.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, group.ID, toUser.ID, "USD").Return(&models.Balance{Balance: decimal.NewFromInt(-100)}, nil)
+			br.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+			br.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+			var created *models.Settlement
+			sr.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Settlement")).
+				Run(func(args mock.Arguments) { created = args.Get(2).(*models.Settlement) }).Return(nil)
+			sr.On("GetByUUID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Settlement{}, nil)
+			db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+			s := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+			_, err := s.CreateSettlement(ctx, &models.CreateSettlementRequest{
+				GroupUUID:    models.GroupUUID(group.UUID),
+				FromUserUUID: models.UserUUID(fromUser.UUID),
+				ToUserUUID:   models.UserUUID(toUser.UUID),
+				Amount:       decimal.NewFromInt(50),
+				Method:       tc.method,
+			})
+
+			if tc.wantCode != "" {
+				appErr, ok := err.(*errors.AppError)
+				assert.True(t, ok)
+				assert.Equal(t, tc.wantCode, appErr.Code)
+				assert.Equal(t, "method", appErr.Details["field"])
+				sr.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, created.Method)
+		})
+	}
+}
+
+func newPendingSettlement() *models.Settlement {
+	return &models.Settlement{
+		ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", GroupID: 10,
+		FromUserID: 1, ToUserID: 2, Amount: decimal.NewFromInt(50), Currency: "USD", Status: models.SettlementStatusPending,
+		FromUser: &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"},
+		ToUser:   &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"},
+	}
+}
+
+func TestSettlementService_ConfirmSettlement(t *testing.T) {
+	ctx := context.Background()
+	settlement := newPendingSettlement()
+
+	sr := new(MockSettlementRepository)
+	sr.On("GetByUUID", mock.Anything, settlement.UUID).Return(settlement, nil)
+	sr.On("UpdateStatus", mock.Anything, mock.Anything, settlement.ID, models.SettlementStatusPending, models.SettlementStatusConfirmed).Return(true, nil)
+	br := new(MockBalanceRepository2)
+	br.On("GetDebtForUpdate", mock.Anything, mock.Anything, int64(10), int64(1), int64(2), "USD").Return(decimal.NewFromInt(50), nil)
+	br.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, int64(10), int64(2), "USD").Return(&models.Balance{Balance: decimal.NewFromInt(-20)}, nil)
+	br.On("UpdateBalance", mock.Anything, mock.Anything, int64(10), int64(1), decimal.NewFromInt(50).Neg(), "USD").Return(nil)
+	br.On("UpdateBalance", mock.Anything, mock.Anything, int64(10), int64(2), decimal.NewFromInt(50), "USD").Return(nil)
+	br.On("UpdateDebt", mock.Anything, mock.Anything, int64(10), int64(1), int64(2), decimal.NewFromInt(50).Neg(), "USD").Return(nil)
+	db := new(MockDB2)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	s := service.NewSettlementService(sr, new(MockGroupRepository2), new(MockUserRepository2), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	// Only the receiver may respond
+	_, err := s.ConfirmSettlement(ctx, settlement.UUID, &models.RespondSettlementRequest{UserUUID: models.UserUUID(settlement.FromUser.UUID)})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, appErr.Status)
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+
+	// The receiver would end up owing 30, so confirming needs their say-so
+	_, err = s.ConfirmSettlement(ctx, settlement.UUID, &models.RespondSettlementRequest{UserUUID: models.UserUUID(settlement.ToUser.UUID)})
+	appErr, ok = err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeOvershoot, appErr.Code)
+	br.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = s.ConfirmSettlement(ctx, settlement.UUID, &models.RespondSettlementRequest{UserUUID: models.UserUUID(settlement.ToUser.UUID), AllowOvershoot: true})
+	assert.NoError(t, err)
+	br.AssertNumberOfCalls(t, "UpdateBalance", 2)
+}
+
+func TestSettlementService_ConfirmSettlement_CallerMustBeReceiver(t *testing.T) {
+	settlement := newPendingSettlement()
+	payer := &auth.Identity{UserID: settlement.FromUserID, UserUUID: models.UserUUID(settlement.FromUser.UUID)}
+	receiver := &auth.Identity{UserID: settlement.ToUserID, UserUUID: models.UserUUID(settlement.ToUser.UUID)}
+
+	sr := new(MockSettlementRepository)
+	sr.On("GetByUUID", mock.Anything, settlement.UUID).Return(settlement, nil)
+	sr.On("UpdateStatus", mock.Anything, mock.Anything, settlement.ID, models.SettlementStatusPending, models.SettlementStatusConfirmed).Return(true, nil)
+	gr := new(MockGroupRepository2)
+	gr.On("IsMember", mock.Anything, settlement.GroupID, mock.Anything).Return(true, nil)
+	br := new(MockBalanceRepository2)
+	br.On("GetDebtForUpdate", mock.Anything, mock.Anything, int64(10), int64(1), int64(2), "USD").Return(decimal.NewFromInt(50), nil)
+	br.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, int64(10), int64(2), "USD").Return(&models.Balance{Balance: decimal.NewFromInt(-50)}, nil)
+	br.On("UpdateBalance", mock.Anything, mock.Anything, int64(10), mock.Anything, mock.Anything, "USD").Return(nil)
+	br.On("UpdateDebt", mock.Anything, mock.Anything, int64(10), int64(1), int64(2), mock.Anything, "USD").Return(nil)
+	db := new(MockDB2)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	s := service.NewSettlementService(sr, gr, new(MockUserRepository2), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+	req := &models.RespondSettlementRequest{UserUUID: receiver.UserUUID}
+
+	// The payer names the receiver in the body but is not the receiver
+	_, err := s.ConfirmSettlement(auth.WithIdentity(context.Background(), payer), settlement.UUID, req)
+	appErr, ok := err.(*errors.AppError)
+	if assert.True(t, ok) {
+		assert.Equal(t, errors.ErrCodeForbidden, appErr.Code)
+	}
+	_, err = s.RejectSettlement(auth.WithIdentity(context.Background(), payer), settlement.UUID, req)
+	appErr, ok = err.(*errors.AppError)
+	if assert.True(t, ok) {
+		assert.Equal(t, errors.ErrCodeForbidden, appErr.Code)
+	}
+	db.AssertNotCalled(t, "WithTransaction", mock.Anything, mock.Anything)
+	sr.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	_, err = s.ConfirmSettlement(auth.WithIdentity(context.Background(), receiver), settlement.UUID, req)
+	assert.NoError(t, err)
+}
+
+func TestSettlementService_RejectSettlement(t *testing.T) {
+	ctx := context.Background()
+	settlement := newPendingSettlement()
+	rejected := *settlement
+	rejected.Status = models.SettlementStatusRejected
+
+	sr := new(MockSettlementRepository)
+	sr.On("GetByUUID", mock.Anything, settlement.UUID).Return(settlement, nil).Once()
+	sr.On("GetByUUID", mock.Anything, settlement.UUID).Return(&rejected, nil)
+	sr.On("UpdateStatus", mock.Anything, mock.Anything, settlement.ID, models.SettlementStatusPending, models.SettlementStatusRejected).Return(true, nil)
+	br := new(MockBalanceRepository2)
+
+	s := service.NewSettlementService(sr, new(MockGroupRepository2), new(MockUserRepository2), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB2), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	res, err := s.RejectSettlement(ctx, settlement.UUID, &models.RespondSettlementRequest{UserUUID: models.UserUUID(settlement.ToUser.UUID)})
+	assert.NoError(t, err)
+	assert.Equal(t, models.SettlementStatusRejected, res.Status)
+	br.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// A settlement that is no longer pending cannot be responded to again
+	_, err = s.RejectSettlement(ctx, settlement.UUID, &models.RespondSettlementRequest{UserUUID: models.UserUUID(settlement.ToUser.UUID)})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeNotPending, appErr.Code)
+}
+
+func TestSettlementService_ExecuteDebtSimplification(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+
+	gr := new(MockGroupRepository2)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	br := new(MockBalanceRepository2)
+	br.On("GetGroupBalancesForUpdate", mock.Anything, mock.Anything, group.ID, "USD").Return([]*models.Balance{
+		{GroupID: group.ID, UserID: alice.ID, User: alice, Balance: decimal.NewFromInt(50)},
+		{GroupID: group.ID, UserID: bob.ID, User: bob, Balance: decimal.NewFromInt(-30)},
+		{GroupID: group.ID, UserID: carol.ID, User: carol, Balance: decimal.NewFromInt(-20)},
+	}, nil)
+	br.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	br.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+	br.On("ClearGroupDebts", mock.Anything, mock.Anything, group.ID, "USD").Return(nil)
+	sr := new(MockSettlementRepository)
+	sr.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Settlement")).Return(nil)
+	sr.On("GetByUUID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Settlement{}, nil)
+	db := new(MockDB2)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	s := service.NewSettlementService(sr, gr, new(MockUserRepository2), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	// A stale preview records nothing
+	_, err := s.ExecuteDebtSimplification(ctx, group.UUID, &models.ExecuteSimplificationRequest{ExpectedHash: "stale"})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeBalancesChanged, appErr.Code)
+	assert.Equal(t, http.StatusConflict, appErr.Status)
+	sr.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+
+	settlements, err := s.ExecuteDebtSimplification(ctx, group.UUID, &models.ExecuteSimplificationRequest{ExpectedHash: appErr.Details["current_hash"]})
+	assert.NoError(t, err)
+	assert.Len(t, settlements, 2)
+	sr.AssertNumberOfCalls(t, "Create", 2)
+	br.AssertCalled(t, "UpdateBalance", mock.Anything, mock.Anything, group.ID, alice.ID, decimal.NewFromInt(30).Neg(), "USD")
+	br.AssertCalled(t, "UpdateBalance", mock.Anything, mock.Anything, group.ID, alice.ID, decimal.NewFromInt(20).Neg(), "USD")
+	br.AssertCalled(t, "UpdateBalance", mock.Anything, mock.Anything, group.ID, bob.ID, decimal.NewFromInt(30), "USD")
+	br.AssertCalled(t, "UpdateBalance", mock.Anything, mock.Anything, group.ID, carol.ID, decimal.NewFromInt(20), "USD")
+	br.AssertNumberOfCalls(t, "ClearGroupDebts", 1)
+}
+
+func TestSettlementService_SettleAllForUser(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc"}
+	dave := &models.User{ID: 4, UUID: "dddddddd-dddd-4ddd-8ddd-dddddddddddd"}
+
+	gr := new(MockGroupRepository2)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	gr.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+	ur := new(MockUserRepository2)
+	ur.On("GetByUUID", mock.Anything, alice.UUID).Return(alice, nil)
+	ur.On("GetByUUID", mock.Anything, dave.UUID).Return(dave, nil)
+
+	// Alice owes Bob 30 and Carol 20 and is owed 5 by Dave
+	br := new(MockBalanceRepository2)
+	br.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, group.ID, alice.ID, "USD").Return(&models.Balance{Balance: decimal.NewFromInt(45)}, nil)
+	br.On("GetByGroupAndUserForUpdate", mock.Anything, mock.Anything, group.ID, dave.ID, "USD").Return(&models.Balance{Balance: decimal.NewFromInt(-5)}, nil)
+	br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return([]*models.DebtRelationship{
+		{Debtor: alice, Creditor: bob, Amount: decimal.NewFromInt(30), Currency: "USD"},
+		{Debtor: alice, Creditor: carol, Amount: decimal.NewFromInt(20), Currency: "USD"},
+		{Debtor: dave, Creditor: alice, Amount: decimal.NewFromInt(5), Currency: "USD"},
+	}, nil)
+	br.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, alice.ID, bob.ID, "USD").Return(decimal.NewFromInt(30), nil)
+	br.On("GetDebtForUpdate", mock.Anything, mock.Anything, group.ID, alice.ID, carol.ID, "USD").Return(decimal.NewFromInt(20), nil)
+	br.On("UpdateBalance", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, "USD").Return(nil)
+	br.On("UpdateDebt", mock.Anything, mock.Anything, group.ID, mock.Anything, mock.Anything, mock.Anything, "USD").Return(nil)
+	sr := new(MockSettlementRepository)
+	var created []*models.Settlement
+	sr.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Settlement")).
+		Run(func(args mock.Arguments) { created = append(created, args.Get(2).(*models.Settlement)) }).Return(nil)
+	sr.On("GetByUUID", mock.Anything, mock.AnythingOfType("string")).Return(&models.Settlement{}, nil)
+	db := new(MockDB2)
+	db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+
+	s := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	settlements, err := s.SettleAllForUser(ctx, models.GroupUUID(group.UUID), models.UserUUID(alice.UUID), "")
+	assert.NoError(t, err)
+	assert.Len(t, settlements, 2)
+	if assert.Len(t, created, 2) {
+		assert.Equal(t, bob.ID, created[0].ToUserID)
+		assert.True(t, created[0].Amount.Equal(decimal.NewFromInt(30)))
+		assert.Equal(t, carol.ID, created[1].ToUserID)
+		assert.True(t, created[1].Amount.Equal(decimal.NewFromInt(20)))
+	}
+	br.AssertCalled(t, "UpdateDebt", mock.Anything, mock.Anything, group.ID, alice.ID, bob.ID, decimal.NewFromInt(30).Neg(), "USD")
+	br.AssertCalled(t, "UpdateDebt", mock.Anything, mock.Anything, group.ID, alice.ID, carol.ID, decimal.NewFromInt(20).Neg(), "USD")
+
+	// Dave is owed money overall, so there is nothing for him to pay
+	_, err = s.SettleAllForUser(ctx, models.GroupUUID(group.UUID), models.UserUUID(dave.UUID), "USD")
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+	assert.Contains(t, appErr.Message, "owed")
+	assert.Len(t, created, 2)
+}
@@ -2,11 +2,15 @@ package unit
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
 	"expense-split-tracker/internal/models"
 	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -31,10 +35,21 @@ func (m *MockBalanceRepository3) Upsert(ctx context.Context, tx *database.Tx, ba
 func (m *MockBalanceRepository3) GetByGroupAndUser(ctx context.Context, groupID, userID int64, currency string) (*models.Balance, error) {
 	return nil, nil
 }
+func (m *MockBalanceRepository3) GetByGroupAndUserForUpdate(ctx context.Context, tx *database.Tx, groupID, userID int64, currency string) (*models.Balance, error) {
+	return nil, nil
+}
 func (m *MockBalanceRepository3) GetGroupBalances(ctx context.Context, groupID int64, currency string) ([]*models.Balance, error) {
 	args := m.Called(ctx, groupID, currency)
 	return args.Get(0).([]*models.Balance), args.Error(1)
 }
+func (m *MockBalanceRepository3) GetGroupBalancesAllCurrencies(ctx context.Context, groupID int64) ([]*models.Balance, error) {
+	args := m.Called(ctx, groupID)
+	return args.Get(0).([]*models.Balance), args.Error(1)
+}
+func (m *MockBalanceRepository3) GetGroupBalancesForUpdate(ctx context.Context, tx *database.Tx, groupID int64, currency string) ([]*models.Balance, error) {
+	args := m.Called(ctx, tx, groupID, currency)
+	return args.Get(0).([]*models.Balance), args.Error(1)
+}
 func (m *MockBalanceRepository3) GetUserBalances(ctx context.Context, userID int64) ([]*models.Balance, error) {
 	return nil, nil
 }
@@ -42,6 +57,56 @@ func (m *MockBalanceRepository3) UpdateBalance(ctx context.Context, tx *database
 	return nil
 }
 
+func (m *MockBalanceRepository3) UpdateDebt(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, amount decimal.Decimal, currency string) error {
+	return nil
+}
+
+func (m *MockBalanceRepository3) GetDebtForUpdate(ctx context.Context, tx *database.Tx, groupID, debtorID, creditorID int64, currency string) (decimal.Decimal, error) {
+	return decimal.Zero, nil
+}
+
+func (m *MockBalanceRepository3) GetGroupDebts(ctx context.Context, groupID int64, currency string) ([]*models.DebtRelationship, error) {
+	args := m.Called(ctx, groupID, currency)
+	return args.Get(0).([]*models.DebtRelationship), args.Error(1)
+}
+
+func (m *MockBalanceRepository3) GetGroupDebtsAllCurrencies(ctx context.Context, groupID int64) ([]*models.DebtRelationship, error) {
+	return nil, nil
+}
+
+func (m *MockBalanceRepository3) GetPairDebts(ctx context.Context, groupID, userID, otherUserID int64) ([]*models.PairDebt, error) {
+	args := m.Called(ctx, groupID, userID, otherUserID)
+	return args.Get(0).([]*models.PairDebt), args.Error(1)
+}
+
+func (m *MockBalanceRepository3) ClearGroupDebts(ctx context.Context, tx *database.Tx, groupID int64, currency string) error {
+	return nil
+}
+
+func (m *MockBalanceRepository3) ZeroGroupBalances(ctx context.Context, tx *database.Tx, groupID int64) error {
+	return nil
+}
+
+func (m *MockBalanceRepository3) ZeroGroupDebts(ctx context.Context, tx *database.Tx, groupID int64) error {
+	return nil
+}
+
+func (m *MockBalanceRepository3) GetUserExpenseTotals(ctx context.Context, groupID, userID int64, currency string) (*models.ExpenseTotals, error) {
+	return &models.ExpenseTotals{}, nil
+}
+
+func (m *MockBalanceRepository3) GetUserSettlementTotals(ctx context.Context, groupID, userID int64, currency string) (*models.SettlementTotals, error) {
+	return &models.SettlementTotals{}, nil
+}
+
+func (m *MockBalanceRepository3) GetUserExpenseTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.ExpenseTotals, error) {
+	return nil, nil
+}
+
+func (m *MockBalanceRepository3) GetUserSettlementTotalsByCurrency(ctx context.Context, groupID, userID int64) ([]*models.SettlementTotals, error) {
+	return nil, nil
+}
+
 // GroupRepository methods
 func (m *MockGroupRepository3) Create(ctx context.Context, tx *database.Tx, group *models.Group) error {
 	return nil
@@ -53,13 +118,28 @@ func (m *MockGroupRepository3) GetByUUID(ctx context.Context, uuid string) (*mod
 	args := m.Called(ctx, uuid)
 	return args.Get(0).(*models.Group), args.Error(1)
 }
-func (m *MockGroupRepository3) List(ctx context.Context, offset, limit int) ([]*models.Group, error) {
+func (m *MockGroupRepository3) Update(ctx context.Context, tx *database.Tx, group *models.Group) error {
+	return nil
+}
+func (m *MockGroupRepository3) List(ctx context.Context, offset, limit int, includeArchived bool) ([]*models.Group, error) {
 	return nil, nil
 }
-func (m *MockGroupRepository3) GetUserGroups(ctx context.Context, userID int64, offset, limit int) ([]*models.Group, error) {
+func (m *MockGroupRepository3) GetUserGroups(ctx context.Context, userID int64, offset, limit int, includeArchived bool) ([]*models.Group, error) {
 	return nil, nil
 }
-func (m *MockGroupRepository3) AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
+func (m *MockGroupRepository3) Count(ctx context.Context, includeArchived bool) (int, error) {
+	return 0, nil
+}
+func (m *MockGroupRepository3) CountUserGroups(ctx context.Context, userID int64, includeArchived bool) (int, error) {
+	return 0, nil
+}
+func (m *MockGroupRepository3) Archive(ctx context.Context, tx *database.Tx, id int64) (bool, error) {
+	return true, nil
+}
+func (m *MockGroupRepository3) Unarchive(ctx context.Context, tx *database.Tx, id int64) (bool, error) {
+	return true, nil
+}
+func (m *MockGroupRepository3) AddMember(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error {
 	return nil
 }
 func (m *MockGroupRepository3) RemoveMember(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
@@ -68,6 +148,35 @@ func (m *MockGroupRepository3) RemoveMember(ctx context.Context, tx *database.Tx
 func (m *MockGroupRepository3) GetMembers(ctx context.Context, groupID int64) ([]*models.User, error) {
 	return nil, nil
 }
+func (m *MockGroupRepository3) GetMemberships(ctx context.Context, groupID int64) ([]*models.GroupMember, error) {
+	return nil, nil
+}
+func (m *MockGroupRepository3) AreMembers(ctx context.Context, groupID int64, userIDs []int64) (map[int64]bool, error) {
+	members := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		members[id] = true
+	}
+	return members, nil
+}
+func (m *MockGroupRepository3) GetMemberRole(ctx context.Context, groupID, userID int64) (models.GroupRole, error) {
+	return models.GroupRoleMember, nil
+}
+func (m *MockGroupRepository3) SetOwner(ctx context.Context, tx *database.Tx, groupID, userID int64) error {
+	return nil
+}
+func (m *MockGroupRepository3) SetMemberRole(ctx context.Context, tx *database.Tx, groupID, userID int64, role models.GroupRole) error {
+	return nil
+}
+func (m *MockGroupRepository3) CountAdminsForUpdate(ctx context.Context, tx *database.Tx, groupID int64) (int, error) {
+	return 1, nil
+}
+func (m *MockGroupRepository3) GetSharedGroups(ctx context.Context, userID, otherUserID int64) ([]*models.Group, error) {
+	args := m.Called(ctx, userID, otherUserID)
+	return args.Get(0).([]*models.Group), args.Error(1)
+}
+func (m *MockGroupRepository3) CountMembers(ctx context.Context, groupID int64) (int, error) {
+	return 0, nil
+}
 func (m *MockGroupRepository3) IsMember(ctx context.Context, groupID, userID int64) (bool, error) {
 	return true, nil
 }
@@ -85,12 +194,28 @@ func (m *MockSettlementRepository3) GetByUUID(ctx context.Context, uuid string)
 func (m *MockSettlementRepository3) List(ctx context.Context, filter *models.SettlementFilter) ([]*models.Settlement, int, error) {
 	return nil, 0, nil
 }
+func (m *MockSettlementRepository3) Void(ctx context.Context, tx *database.Tx, id int64, voidedBy *int64) (bool, error) {
+	return true, nil
+}
+func (m *MockSettlementRepository3) UpdateStatus(ctx context.Context, tx *database.Tx, id int64, from, to models.SettlementStatus) (bool, error) {
+	return true, nil
+}
 func (m *MockSettlementRepository3) GetGroupSettlements(ctx context.Context, groupID int64, offset, limit int) ([]*models.Settlement, error) {
 	return nil, nil
 }
 func (m *MockSettlementRepository3) GetUserSettlements(ctx context.Context, userID int64, offset, limit int) ([]*models.Settlement, error) {
 	return nil, nil
 }
+func (m *MockSettlementRepository3) CountGroupSettlements(ctx context.Context, groupID int64) (int, error) {
+	return 0, nil
+}
+func (m *MockSettlementRepository3) CountUserSettlements(ctx context.Context, userID int64) (int, error) {
+	return 0, nil
+}
+
+func (m *MockSettlementRepository3) IterateGroupSettlements(ctx context.Context, groupID int64, batchSize int, fn func([]*models.Settlement) error) error {
+	return nil
+}
 
 // UserRepository methods
 func (m *MockUserRepository3) Create(ctx context.Context, tx *database.Tx, user *models.User) error {
@@ -105,21 +230,38 @@ func (m *MockUserRepository3) GetByUUID(ctx context.Context, uuid string) (*mode
 func (m *MockUserRepository3) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	return nil, nil
 }
+func (m *MockUserRepository3) GetByUUIDs(ctx context.Context, uuids []string) ([]*models.User, error) {
+	return nil, nil
+}
 func (m *MockUserRepository3) List(ctx context.Context, offset, limit int) ([]*models.User, error) {
 	return nil, nil
 }
 
+func (m *MockUserRepository3) Search(ctx context.Context, query string, offset, limit int) ([]*models.User, error) {
+	return nil, nil
+}
+
+func (m *MockUserRepository3) CountSearch(ctx context.Context, query string) (int, error) {
+	return 0, nil
+}
+
+func (m *MockUserRepository3) Count(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
 // DBTransactor
-func (m *MockDB3) WithTransaction(fn func(tx *database.Tx) error) error { return nil }
+func (m *MockDB3) WithTransaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	return nil
+}
 
 func TestSettlementService_SimplifyDebts_GeneratesSuggestions(t *testing.T) {
 	ctx := context.Background()
 	logger := zaptest.NewLogger(t)
 
-	group := &models.Group{ID: 10, UUID: "11111111-1111-1111-1111-111111111111"}
-	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa", Name: "Alice"}
-	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb", Name: "Bob"}
-	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-cccc-cccc-cccccccccccc", Name: "Carol"}
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"}
 
 	br := new(MockBalanceRepository3)
 	gr := new(MockGroupRepository3)
@@ -133,10 +275,14 @@ func TestSettlementService_SimplifyDebts_GeneratesSuggestions(t *testing.T) {
 		{GroupID: group.ID, UserID: bob.ID, User: bob, Balance: decimal.NewFromInt(-30)},     // owed 30
 		{GroupID: group.ID, UserID: carol.ID, User: carol, Balance: decimal.NewFromInt(-20)}, // owed 20
 	}, nil)
+	br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return([]*models.DebtRelationship{
+		{Debtor: alice, Creditor: bob, Amount: decimal.NewFromInt(30), Currency: "USD"},
+		{Debtor: alice, Creditor: carol, Amount: decimal.NewFromInt(20), Currency: "USD"},
+	}, nil)
 
-	settlementSvc := service.NewSettlementService(sr, gr, ur, br, db, logger)
+	settlementSvc := service.NewSettlementService(sr, gr, ur, br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, events.NopEmitter{}, metrics.Nop{}, logger)
 
-	result, err := settlementSvc.SimplifyDebts(ctx, group.UUID)
+	result, err := settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "USD"})
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, 2, len(result.Suggestions))
@@ -146,5 +292,357 @@ func TestSettlementService_SimplifyDebts_GeneratesSuggestions(t *testing.T) {
 		total = total.Add(s.Amount)
 	}
 	assert.True(t, total.Equal(decimal.NewFromInt(50)))
-	assert.GreaterOrEqual(t, result.OriginalTransactions, result.SimplifiedTransactions)
+	// Alice's two debts are already as simple as they get
+	assert.Equal(t, 2, result.OriginalTransactions)
+	assert.Equal(t, 2, result.SimplifiedTransactions)
+	assert.Equal(t, 0, result.Savings)
+}
+
+func TestSettlementService_SimplifyDebts_PerCurrency(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", DefaultCurrency: "EUR"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+
+	br := new(MockBalanceRepository3)
+	gr := new(MockGroupRepository3)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	// Alice owes Bob in euros, Bob owes Alice in dollars
+	br.On("GetGroupBalances", mock.Anything, group.ID, "EUR").Return([]*models.Balance{
+		{GroupID: group.ID, UserID: alice.ID, User: alice, Balance: decimal.NewFromInt(40), Currency: "EUR"},
+		{GroupID: group.ID, UserID: bob.ID, User: bob, Balance: decimal.NewFromInt(-40), Currency: "EUR"},
+	}, nil)
+	br.On("GetGroupBalances", mock.Anything, group.ID, "USD").Return([]*models.Balance{
+		{GroupID: group.ID, UserID: bob.ID, User: bob, Balance: decimal.NewFromInt(15), Currency: "USD"},
+		{GroupID: group.ID, UserID: alice.ID, User: alice, Balance: decimal.NewFromInt(-15), Currency: "USD"},
+	}, nil)
+	br.On("GetGroupDebts", mock.Anything, group.ID, "EUR").Return([]*models.DebtRelationship{
+		{Debtor: alice, Creditor: bob, Amount: decimal.NewFromInt(40), Currency: "EUR"},
+	}, nil)
+	br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return([]*models.DebtRelationship{
+		{Debtor: bob, Creditor: alice, Amount: decimal.NewFromInt(15), Currency: "USD"},
+	}, nil)
+
+	settlementSvc := service.NewSettlementService(new(MockSettlementRepository3), gr, new(MockUserRepository3), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB3), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	// Without a currency the group's default one is picked
+	result, err := settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, result.Suggestions, 1)
+	assert.Equal(t, alice, result.Suggestions[0].FromUser)
+	assert.Equal(t, "EUR", result.Suggestions[0].Currency)
+
+	result, err = settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "usd"})
+	assert.NoError(t, err)
+	assert.Len(t, result.Suggestions, 1)
+	assert.Equal(t, bob, result.Suggestions[0].FromUser)
+	assert.True(t, result.Suggestions[0].Amount.Equal(decimal.NewFromInt(15)))
+
+	_, err = settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "XYZ"})
+	assert.Error(t, err)
+
+	balanceSvc := service.NewBalanceService(br, gr, new(MockUserRepository3), new(MockSettlementRepository3), nil, nil, nil, new(MockDB3), nil, zaptest.NewLogger(t))
+	sheet, err := balanceSvc.GetGroupBalanceSheet(ctx, models.GroupUUID(group.UUID), "eur", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", sheet.Currency)
+	assert.True(t, sheet.Summary.TotalPositive.Equal(decimal.NewFromInt(40)))
+
+	relationships, err := balanceSvc.GetDebtRelationships(ctx, models.GroupUUID(group.UUID), "USD")
+	assert.NoError(t, err)
+	assert.Len(t, relationships, 1)
+	assert.Equal(t, alice, relationships[0].Creditor)
+	assert.Equal(t, "USD", relationships[0].Currency)
+}
+
+func TestSettlementService_SimplifyDebts_CountsOutstandingDebts(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"}
+	debt := func(debtor, creditor *models.User, amount int64) *models.DebtRelationship {
+		return &models.DebtRelationship{Debtor: debtor, Creditor: creditor, Amount: decimal.NewFromInt(amount), Currency: "USD"}
+	}
+
+	tests := []struct {
+		name       string
+		balances   [3]int64
+		debts      []*models.DebtRelationship
+		original   int
+		simplified int
+		savings    int
+	}{
+		{
+			// Alice pays Carol directly instead of through Bob
+			name:     "chain",
+			balances: [3]int64{10, 0, -10},
+			debts:    []*models.DebtRelationship{debt(alice, bob, 10), debt(bob, carol, 10)},
+			original: 2, simplified: 1, savings: 1,
+		},
+		{
+			name:     "cycle",
+			balances: [3]int64{0, 0, 0},
+			debts:    []*models.DebtRelationship{debt(alice, bob, 10), debt(bob, carol, 10), debt(carol, alice, 10)},
+			original: 3, simplified: 0, savings: 3,
+		},
+		{
+			// Two debtors each owing the one creditor cannot be simplified
+			name:     "nothing to save",
+			balances: [3]int64{20, 30, -50},
+			debts:    []*models.DebtRelationship{debt(bob, carol, 30), debt(alice, carol, 20)},
+			original: 2, simplified: 2, savings: 0,
+		},
+		{
+			// Without pairwise debts the count falls back to non-zero balances minus one
+			name:     "no pairwise debts",
+			balances: [3]int64{20, 30, -50},
+			debts:    []*models.DebtRelationship{},
+			original: 2, simplified: 2, savings: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gr := new(MockGroupRepository3)
+			gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			br := new(MockBalanceRepository3)
+			var balances []*models.Balance
+			for i, user := range []*models.User{alice, bob, carol} {
+				balances = append(balances, &models.Balance{GroupID: group.ID, UserID: user.ID, User: user, Balance: decimal.NewFromInt(tt.balances[i])})
+			}
+			br.On("GetGroupBalances", mock.Anything, group.ID, "USD").Return(balances, nil)
+			br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return(tt.debts, nil)
+			settlementSvc := service.NewSettlementService(new(MockSettlementRepository3), gr, new(MockUserRepository3), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB3), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+			result, err := settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "USD"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.original, result.OriginalTransactions)
+			assert.Equal(t, tt.simplified, result.SimplifiedTransactions)
+			assert.Equal(t, tt.savings, result.Savings)
+		})
+	}
+}
+
+// assertSettlesBalances checks that paying every suggestion brings each balance to zero
+func assertSettlesBalances(t *testing.T, balances []*models.Balance, suggestions []*models.SettlementSuggestion) {
+	remaining := make(map[int64]decimal.Decimal)
+	for _, balance := range balances {
+		remaining[balance.User.ID] = balance.Balance
+	}
+	for _, suggestion := range suggestions {
+		assert.True(t, suggestion.Amount.GreaterThan(decimal.Zero))
+		remaining[suggestion.FromUser.ID] = remaining[suggestion.FromUser.ID].Sub(suggestion.Amount)
+		remaining[suggestion.ToUser.ID] = remaining[suggestion.ToUser.ID].Add(suggestion.Amount)
+	}
+	for userID, balance := range remaining {
+		assert.True(t, balance.IsZero(), "user %d left with %s", userID, balance)
+	}
+}
+
+func TestSettlementService_SimplifyDebts_OptimalBeatsGreedy(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	// Alice and Bob owe exactly what Erin is owed, and Carol what Dave is owed,
+	// but greedy starts by pairing Carol with Erin and needs four transfers
+	var balances []*models.Balance
+	for i, amount := range []int64{20, 30, 40, -40, -50} {
+		user := &models.User{ID: int64(i + 1), UUID: fmt.Sprintf("%08d-aaaa-4aaa-8aaa-aaaaaaaaaaaa", i+1)}
+		balances = append(balances, &models.Balance{GroupID: group.ID, UserID: user.ID, User: user, Balance: decimal.NewFromInt(amount)})
+	}
+
+	gr := new(MockGroupRepository3)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	br := new(MockBalanceRepository3)
+	br.On("GetGroupBalances", mock.Anything, group.ID, "USD").Return(balances, nil)
+	br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return([]*models.DebtRelationship{}, nil)
+	settlementSvc := service.NewSettlementService(new(MockSettlementRepository3), gr, new(MockUserRepository3), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB3), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	greedy, err := settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "USD"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.SimplificationModeGreedy, greedy.Algorithm)
+	assert.Equal(t, 4, greedy.SimplifiedTransactions)
+	assertSettlesBalances(t, balances, greedy.Suggestions)
+
+	optimal, err := settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "USD", Mode: models.SimplificationModeOptimal})
+	assert.NoError(t, err)
+	assert.Equal(t, models.SimplificationModeOptimal, optimal.Algorithm)
+	assert.Equal(t, 3, optimal.SimplifiedTransactions)
+	assertSettlesBalances(t, balances, optimal.Suggestions)
+	assert.NotEqual(t, greedy.Hash, optimal.Hash)
+
+	_, err = settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "USD", Mode: "fastest"})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, "mode", appErr.Details["field"])
+}
+
+func TestSettlementService_SimplifyDebts_OptimalFallsBackForLargeGroups(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	// Eight debtors and eight creditors, one more balance than optimal searches
+	var balances []*models.Balance
+	for i := 0; i < 16; i++ {
+		amount := decimal.NewFromInt(int64(10 + i/2))
+		if i%2 == 1 {
+			amount = amount.Neg()
+		}
+		user := &models.User{ID: int64(i + 1), UUID: fmt.Sprintf("%08d-aaaa-4aaa-8aaa-aaaaaaaaaaaa", i+1)}
+		balances = append(balances, &models.Balance{GroupID: group.ID, UserID: user.ID, User: user, Balance: amount})
+	}
+
+	gr := new(MockGroupRepository3)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	br := new(MockBalanceRepository3)
+	br.On("GetGroupBalances", mock.Anything, group.ID, "USD").Return(balances, nil)
+	br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return([]*models.DebtRelationship{}, nil)
+	settlementSvc := service.NewSettlementService(new(MockSettlementRepository3), gr, new(MockUserRepository3), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB3), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	result, err := settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "USD", Mode: models.SimplificationModeOptimal})
+	assert.NoError(t, err)
+	assert.Equal(t, models.SimplificationModeGreedy, result.Algorithm)
+	assertSettlesBalances(t, balances, result.Suggestions)
+
+	// Without the last pair it is small enough to search
+	br.ExpectedCalls = nil
+	br.On("GetGroupBalances", mock.Anything, group.ID, "USD").Return(balances[:14], nil)
+	br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return([]*models.DebtRelationship{}, nil)
+	result, err = settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "USD", Mode: models.SimplificationModeOptimal})
+	assert.NoError(t, err)
+	assert.Equal(t, models.SimplificationModeOptimal, result.Algorithm)
+	assert.Equal(t, 7, result.SimplifiedTransactions)
+	assertSettlesBalances(t, balances[:14], result.Suggestions)
+}
+
+func TestSettlementService_SimplifyDebts_MinAmountAndRounding(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	carol := &models.User{ID: 3, UUID: "cccccccc-cccc-4ccc-8ccc-cccccccccccc", Name: "Carol"}
+	minAmount := func(amount string) *decimal.Decimal {
+		d := decimal.RequireFromString(amount)
+		return &d
+	}
+
+	tests := []struct {
+		name      string
+		balances  [3]string
+		minAmount *decimal.Decimal
+		amounts   []string
+		residual  string
+	}{
+		{
+			// Bob's sub-cent debt is dropped and the drift lands on Alice's payment
+			name:     "sub-cent drift",
+			balances: [3]string{"10.004", "0.003", "-10.007"},
+			amounts:  []string{"10.01"},
+			residual: "0.01",
+		},
+		{
+			name:      "below min_amount",
+			balances:  [3]string{"50", "0.40", "-50.40"},
+			minAmount: minAmount("1"),
+			amounts:   []string{"50.40"},
+			residual:  "0.40",
+		},
+		{
+			// Nothing is left to absorb the residual, so it stays on the balances
+			name:      "everything dropped",
+			balances:  [3]string{"0.30", "0", "-0.30"},
+			minAmount: minAmount("1"),
+			amounts:   nil,
+			residual:  "0.30",
+		},
+		{
+			name:     "whole cents",
+			balances: [3]string{"50", "0.40", "-50.40"},
+			amounts:  []string{"50", "0.40"},
+			residual: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gr := new(MockGroupRepository3)
+			gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+			br := new(MockBalanceRepository3)
+			var balances []*models.Balance
+			totalDebt := decimal.Zero
+			for i, user := range []*models.User{alice, bob, carol} {
+				amount := decimal.RequireFromString(tt.balances[i])
+				if amount.IsPositive() {
+					totalDebt = totalDebt.Add(amount)
+				}
+				balances = append(balances, &models.Balance{GroupID: group.ID, UserID: user.ID, User: user, Balance: amount})
+			}
+			br.On("GetGroupBalances", mock.Anything, group.ID, "USD").Return(balances, nil)
+			br.On("GetGroupDebts", mock.Anything, group.ID, "USD").Return([]*models.DebtRelationship{}, nil)
+			settlementSvc := service.NewSettlementService(new(MockSettlementRepository3), gr, new(MockUserRepository3), br, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB3), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+			result, err := settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "USD", MinAmount: tt.minAmount})
+			assert.NoError(t, err)
+			assert.True(t, result.Residual.Equal(decimal.RequireFromString(tt.residual)), "residual %s", result.Residual)
+
+			if assert.Len(t, result.Suggestions, len(tt.amounts)) {
+				paid := decimal.Zero
+				for i, suggestion := range result.Suggestions {
+					assert.True(t, suggestion.Amount.Equal(decimal.RequireFromString(tt.amounts[i])), "suggestion %d is %s", i, suggestion.Amount)
+					assert.LessOrEqual(t, -suggestion.Amount.Exponent(), int32(2))
+					paid = paid.Add(suggestion.Amount)
+				}
+				if len(tt.amounts) > 0 {
+					// The suggestions cover the total debt within one cent
+					assert.True(t, paid.Sub(totalDebt).Abs().LessThan(decimal.RequireFromString("0.01")), "paid %s of %s", paid, totalDebt)
+				}
+			}
+		})
+	}
+
+	gr := new(MockGroupRepository3)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	settlementSvc := service.NewSettlementService(new(MockSettlementRepository3), gr, new(MockUserRepository3), new(MockBalanceRepository3), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDB3), events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+	_, err := settlementSvc.SimplifyDebts(ctx, group.UUID, &models.SimplifyDebtsRequest{Currency: "USD", MinAmount: minAmount("-1")})
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Contains(t, appErr.Message, "min_amount")
+}
+
+func TestBalanceService_GetGroupBalanceSheet_AllCurrencies(t *testing.T) {
+	ctx := context.Background()
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111"}
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+
+	gr := new(MockGroupRepository3)
+	gr.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	br := new(MockBalanceRepository3)
+	br.On("GetGroupBalancesAllCurrencies", mock.Anything, group.ID).Return([]*models.Balance{
+		{GroupID: group.ID, UserID: alice.ID, User: alice, Balance: decimal.NewFromInt(40), Currency: "EUR"},
+		{GroupID: group.ID, UserID: bob.ID, User: bob, Balance: decimal.NewFromInt(-40), Currency: "EUR"},
+		{GroupID: group.ID, UserID: bob.ID, User: bob, Balance: decimal.NewFromInt(15), Currency: "USD"},
+		{GroupID: group.ID, UserID: alice.ID, User: alice, Balance: decimal.NewFromInt(-15), Currency: "USD"},
+	}, nil)
+
+	balanceSvc := service.NewBalanceService(br, gr, new(MockUserRepository3), new(MockSettlementRepository3), nil, nil, nil, new(MockDB3), nil, zaptest.NewLogger(t))
+
+	sheet, err := balanceSvc.GetGroupBalanceSheet(ctx, models.GroupUUID(group.UUID), "", "")
+	assert.NoError(t, err)
+	assert.Empty(t, sheet.Currency)
+	assert.Nil(t, sheet.Summary)
+	assert.Len(t, sheet.Sections, 2)
+
+	eur, usd := sheet.Sections[0], sheet.Sections[1]
+	assert.Equal(t, "EUR", eur.Currency)
+	assert.Len(t, eur.Balances, 2)
+	assert.True(t, eur.Summary.TotalPositive.Equal(decimal.NewFromInt(40)))
+	assert.Equal(t, "USD", usd.Currency)
+	assert.Equal(t, bob, usd.Balances[0].User)
+	assert.True(t, usd.Summary.TotalNegative.Equal(decimal.NewFromInt(15)))
+	assert.True(t, usd.Summary.NetBalance.IsZero())
 }
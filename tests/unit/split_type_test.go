@@ -0,0 +1,75 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"expense-split-tracker/internal/controller"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+	"expense-split-tracker/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSplitType_UnmarshalRejectsUnknownValue(t *testing.T) {
+	var req models.CreateExpenseRequest
+	err := json.Unmarshal([]byte(`{"split_type":"equall"}`), &req)
+
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalid, appErr.Code)
+	assert.Equal(t, "split_type", appErr.Details["field"])
+	assert.Equal(t, "equal,exact,percentage,shares", appErr.Details["allowed"])
+
+	for _, splitType := range models.AllSplitTypes() {
+		assert.NoError(t, json.Unmarshal([]byte(`{"split_type":"`+string(splitType)+`"}`), &req))
+		assert.Equal(t, splitType, req.SplitType)
+	}
+}
+
+func TestExpenseController_CreateExpense_RejectsSplitTypeTypo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := new(MockExpenseServiceHandler)
+	ec := controller.NewExpenseController(svc, zaptest.NewLogger(t))
+
+	router := gin.New()
+	router.POST("/api/v1/expenses", ec.CreateExpense)
+
+	body := `{"group_uuid":"11111111-1111-4111-8111-111111111111","paid_by_uuid":"aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa",` +
+		`"amount":"30","description":"Taxi","split_type":"equall","splits":[{"user_uuid":"aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/expenses", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp response.APIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, errors.ErrCodeInvalid, resp.Error.Code)
+	assert.Equal(t, "split_type", resp.Error.Details["field"])
+	svc.AssertNotCalled(t, "CreateExpense", mock.Anything, mock.Anything)
+}
+
+func TestExpenseService_ListExpenses_RejectsSplitTypeTypo(t *testing.T) {
+	expenseRepo := new(MockExpenseRepositoryES)
+	es := service.NewExpenseService(expenseRepo, new(MockGroupRepositoryES), new(MockUserRepositoryES), new(MockBalanceRepositoryES), newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), new(MockDBES), nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	result, err := es.ListExpenses(context.Background(), &models.ExpenseFilter{SplitType: "equall", Page: 1, Limit: 10})
+	assert.Nil(t, result)
+	appErr, ok := err.(*errors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, appErr.Status)
+	assert.Equal(t, "split_type", appErr.Details["field"])
+	expenseRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
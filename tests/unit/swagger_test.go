@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"expense-split-tracker/internal/config"
+	"expense-split-tracker/internal/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getSwagger(path string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.SetupSwaggerRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestSwagger_ServesGeneratedSpec(t *testing.T) {
+	rec := getSwagger("/swagger/doc.json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "application/json")
+
+	var spec struct {
+		Swagger string                            `json:"swagger"`
+		Info    struct{ Title string }            `json:"info"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+	assert.Equal(t, "2.0", spec.Swagger)
+	assert.Equal(t, "Expense Split Tracker API", spec.Info.Title)
+
+	// Endpoints documented end to end, including health and by-email
+	for path, method := range map[string]string{
+		"/health":                               "get",
+		"/health/live":                          "get",
+		"/api/v1/users/by-email":                "get",
+		"/api/v1/expenses":                      "post",
+		"/api/v1/groups/{uuid}/balances/verify": "get",
+		"/api/v1/groups/{uuid}/webhooks":        "post",
+	} {
+		assert.Contains(t, spec.Paths[path], method, "%s %s", method, path)
+	}
+}
+
+func TestSwagger_ServesUI(t *testing.T) {
+	rec := getSwagger("/swagger/index.html")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `url: "doc.json"`)
+	assert.Contains(t, rec.Body.String(), "<title>Expense Split Tracker API</title>")
+	assert.NotContains(t, rec.Body.String(), "unpkg.com")
+
+	rec = getSwagger("/swagger/")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/swagger/index.html", rec.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusNotFound, getSwagger("/swagger/unknown.js").Code)
+}
+
+func TestSwagger_ServesEmbeddedAssets(t *testing.T) {
+	for path, contentType := range map[string]string{
+		"/swagger/swagger-ui.css":       "text/css",
+		"/swagger/swagger-ui-bundle.js": "application/javascript",
+	} {
+		rec := getSwagger(path)
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Contains(t, rec.Header().Get("Content-Type"), contentType, path)
+		assert.NotEmpty(t, rec.Body.Bytes(), path)
+	}
+}
+
+func TestSwagger_DisabledInProductionUnlessEnabled(t *testing.T) {
+	assert.True(t, config.ServerConfig{Env: "development"}.ServeSwagger())
+	assert.False(t, config.ServerConfig{Env: "production"}.ServeSwagger())
+	assert.True(t, config.ServerConfig{Env: "production", SwaggerEnabled: true}.ServeSwagger())
+}
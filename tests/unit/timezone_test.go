@@ -0,0 +1,44 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTimezone(t *testing.T) {
+	assert.NoError(t, utils.ValidateTimezone("Asia/Kolkata"))
+	assert.NoError(t, utils.ValidateTimezone("UTC"))
+	assert.Error(t, utils.ValidateTimezone(""))
+	assert.Error(t, utils.ValidateTimezone("Local"))
+	assert.Error(t, utils.ValidateTimezone("Mars/Olympus_Mons"))
+}
+
+func TestGroupTimezone_LateDinnerLandsInLocalDayAndMonth(t *testing.T) {
+	group := &models.Group{Timezone: "America/New_York"}
+	loc := group.Location()
+
+	// 23:30 on 31 January in New York is already 1 February in UTC
+	dinner := time.Date(2024, time.January, 31, 23, 30, 0, 0, loc).UTC()
+	assert.Equal(t, "2024-02-01", utils.LocalDate(dinner, time.UTC))
+
+	assert.Equal(t, "2024-01-31", utils.LocalDate(dinner, loc))
+	assert.Equal(t, "2024-01", utils.LocalMonth(dinner, loc))
+
+	dayStart, dayEnd := utils.DayRange(2024, time.January, 31, loc)
+	assert.True(t, !dinner.Before(dayStart) && dinner.Before(dayEnd))
+	assert.Equal(t, time.Date(2024, time.January, 31, 5, 0, 0, 0, time.UTC), dayStart)
+
+	monthStart, monthEnd := utils.MonthRange(2024, time.January, loc)
+	assert.True(t, !dinner.Before(monthStart) && dinner.Before(monthEnd))
+	assert.Equal(t, time.Date(2024, time.February, 1, 5, 0, 0, 0, time.UTC), monthEnd)
+}
+
+func TestGroupTimezone_DefaultsToUTC(t *testing.T) {
+	assert.Equal(t, time.UTC, (&models.Group{}).Location())
+	assert.Equal(t, time.UTC, (&models.Group{Timezone: "Not/AZone"}).Location())
+}
@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"expense-split-tracker/internal/database"
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/metrics"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/pkg/errors"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// recordingTransactor hands every WithTransaction call its own Tx and counts
+// how each one ended, so tests can check that writes stayed inside a single
+// transaction that was rolled back
+type recordingTransactor struct {
+	txs        []*database.Tx
+	ctxs       []context.Context
+	committed  int
+	rolledBack int
+}
+
+func (d *recordingTransactor) WithTransaction(ctx context.Context, fn func(*database.Tx) error) error {
+	tx := &database.Tx{}
+	d.txs = append(d.txs, tx)
+	d.ctxs = append(d.ctxs, ctx)
+	if err := fn(tx); err != nil {
+		d.rolledBack++
+		return err
+	}
+	d.committed++
+	return nil
+}
+
+// requestKey marks the request context so tests can recognise it downstream
+type requestKey struct{}
+
+func TestExpenseService_CreateExpense_FailureRollsBackSingleTransaction(t *testing.T) {
+	ctx := context.WithValue(context.Background(), requestKey{}, "request")
+
+	expenseRepo := new(MockExpenseRepositoryES)
+	groupRepo := new(MockGroupRepositoryES)
+	userRepo := new(MockUserRepositoryES)
+	balanceRepo := new(MockBalanceRepositoryES)
+	db := &recordingTransactor{}
+
+	group := &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"}
+	payer := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+
+	groupRepo.On("GetByUUID", mock.Anything, group.UUID).Return(group, nil)
+	userRepo.On("GetByUUID", mock.Anything, payer.UUID).Return(payer, nil)
+	userRepo.On("GetByUUID", mock.Anything, bob.UUID).Return(bob, nil)
+	groupRepo.On("IsMember", mock.Anything, group.ID, mock.Anything).Return(true, nil)
+
+	// The second split fails after the expense row and first split were written
+	expenseRepo.On("Create", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Expense")).Return(nil)
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(nil).Once()
+	expenseRepo.On("CreateSplit", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ExpenseSplit")).Return(errors.NewDatabaseError(assert.AnError)).Once()
+
+	es := service.NewExpenseService(expenseRepo, groupRepo, userRepo, balanceRepo, newBalanceHistoryRepo(), newUnlockedGroupLockRepo(), db, nil, events.NopEmitter{}, metrics.Nop{}, zaptest.NewLogger(t))
+
+	expense, err := es.CreateExpense(ctx, &models.CreateExpenseRequest{
+		GroupUUID:   group.UUID,
+		PaidByUUID:  payer.UUID,
+		Amount:      decimal.NewFromInt(50),
+		Currency:    "USD",
+		Description: "Taxi",
+		SplitType:   models.SplitTypeEqual,
+		Splits: []models.CreateExpenseSplitRequest{
+			{UserUUID: payer.UUID},
+			{UserUUID: bob.UUID},
+		},
+	})
+	assert.Error(t, err)
+	assert.Nil(t, expense)
+
+	// Exactly one transaction, rolled back, none left open
+	if assert.Len(t, db.txs, 1) {
+		assert.Equal(t, 1, db.rolledBack)
+		assert.Equal(t, 0, db.committed)
+
+		// The transaction runs under the request's context, so a client that
+		// disconnects cancels it
+		assert.Equal(t, "request", db.ctxs[0].Value(requestKey{}))
+
+		// Every row written belongs to the rolled-back transaction, so none persist
+		for _, call := range expenseRepo.Calls {
+			switch call.Method {
+			case "Create", "CreateSplit":
+				assert.Same(t, db.txs[0], call.Arguments.Get(1), call.Method)
+			}
+		}
+	}
+	balanceRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	expenseRepo.AssertNotCalled(t, "GetExpenseSplits", mock.Anything, mock.Anything)
+}
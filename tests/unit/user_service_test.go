@@ -37,6 +37,11 @@ func (m *MockUserRepository) GetByUUID(ctx context.Context, uuid string) (*model
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetByUUIDs(ctx context.Context, uuids []string) ([]*models.User, error) {
+	args := m.Called(ctx, uuids)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
@@ -60,13 +65,28 @@ func (m *MockUserRepository) List(ctx context.Context, offset, limit int) ([]*mo
 	return args.Get(0).([]*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) Search(ctx context.Context, query string, offset, limit int) ([]*models.User, error) {
+	args := m.Called(ctx, query, offset, limit)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) CountSearch(ctx context.Context, query string) (int, error) {
+	args := m.Called(ctx, query)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) Count(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
 // MockDB is a mock implementation of service.DBTransactor
 type MockDB struct {
 	mock.Mock
 }
 
-func (m *MockDB) WithTransaction(fn func(tx *database.Tx) error) error {
-	args := m.Called(fn)
+func (m *MockDB) WithTransaction(ctx context.Context, fn func(tx *database.Tx) error) error {
+	args := m.Called(ctx, fn)
 	// Execute the function with nil transaction for testing
 	if err := fn(nil); err != nil {
 		return err
@@ -99,7 +119,7 @@ func TestUserService_CreateUser(t *testing.T) {
 				})).Return(nil)
 
 				// Mock transaction
-				db.On("WithTransaction", mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+				db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
 			},
 			expectedUser: &models.User{
 				Name:  "John Doe",
@@ -124,6 +144,22 @@ func TestUserService_CreateUser(t *testing.T) {
 			},
 			expectedError: "User with this email already exists",
 		},
+		{
+			name: "concurrent signup with the same email",
+			request: &models.CreateUserRequest{
+				Name:  "Jane Doe",
+				Email: "jane@example.com",
+			},
+			setupMocks: func(repo *MockUserRepository, db *MockDB) {
+				// The check passes but the insert loses the race on the unique email
+				repo.On("GetByEmail", mock.Anything, "jane@example.com").
+					Return(nil, errors.NewNotFoundError("User"))
+				repo.On("Create", mock.Anything, (*database.Tx)(nil), mock.AnythingOfType("*models.User")).
+					Return(errors.NewDuplicateFieldError("User", "email"))
+				db.On("WithTransaction", mock.Anything, mock.AnythingOfType("func(*database.Tx) error")).Return(nil)
+			},
+			expectedError: "[ALREADY_EXISTS] User with this email already exists",
+		},
 		{
 			name: "invalid email",
 			request: &models.CreateUserRequest{
@@ -258,3 +294,70 @@ func TestUserService_GetUserByUUID(t *testing.T) {
 		})
 	}
 }
+
+func TestUserService_SearchUsers(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	priya := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Priya", Email: "priya@example.com"}
+
+	t.Run("matches name or email", func(t *testing.T) {
+		repo := new(MockUserRepository)
+		repo.On("Search", mock.Anything, "pri", 10, 10).Return([]*models.User{priya}, nil)
+		repo.On("CountSearch", mock.Anything, "pri").Return(11, nil)
+		userService := service.NewUserService(repo, new(MockDB), logger)
+
+		users, total, err := userService.SearchUsers(ctx, "  pri ", 2, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []*models.User{priya}, users)
+		assert.Equal(t, 11, total)
+		repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("short query lists all users", func(t *testing.T) {
+		repo := new(MockUserRepository)
+		repo.On("List", mock.Anything, 0, 10).Return([]*models.User{priya}, nil)
+		repo.On("Count", mock.Anything).Return(1, nil)
+		userService := service.NewUserService(repo, new(MockDB), logger)
+
+		users, total, err := userService.SearchUsers(ctx, "p", 1, 10)
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Equal(t, 1, total)
+		repo.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_LookupUsers(t *testing.T) {
+	ctx := context.Background()
+	logger := zaptest.NewLogger(t)
+
+	alice := &models.User{ID: 1, UUID: "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", Name: "Alice"}
+	bob := &models.User{ID: 2, UUID: "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", Name: "Bob"}
+	unknown := "cccccccc-cccc-4ccc-8ccc-cccccccccccc"
+
+	t.Run("returns users in request order", func(t *testing.T) {
+		uuids := []string{bob.UUID, unknown, alice.UUID, bob.UUID}
+		repo := new(MockUserRepository)
+		repo.On("GetByUUIDs", mock.Anything, uuids).Return([]*models.User{alice, bob}, nil)
+		userService := service.NewUserService(repo, new(MockDB), logger)
+
+		users, err := userService.LookupUsers(ctx, uuids)
+		assert.NoError(t, err)
+		assert.Equal(t, []*models.User{bob, alice}, users)
+		repo.AssertNumberOfCalls(t, "GetByUUIDs", 1)
+	})
+
+	t.Run("rejects invalid UUIDs", func(t *testing.T) {
+		repo := new(MockUserRepository)
+		userService := service.NewUserService(repo, new(MockDB), logger)
+
+		_, err := userService.LookupUsers(ctx, []string{alice.UUID, "nope"})
+		appErr, ok := err.(*errors.AppError)
+		if assert.True(t, ok) {
+			assert.Equal(t, errors.ErrCodeValidation, appErr.Code)
+			assert.Equal(t, "Invalid UUID", appErr.Details["uuids[1]"])
+		}
+		repo.AssertNotCalled(t, "GetByUUIDs", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,61 @@
+package unit
+
+import (
+	"reflect"
+	"testing"
+
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/service"
+	"expense-split-tracker/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUUID_GenerateIsVersion4(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		assert.True(t, utils.IsValidUUID(utils.GenerateUUID()))
+	}
+}
+
+func TestUUID_StrictValidation(t *testing.T) {
+	assert.True(t, utils.IsValidUUID("550e8400-e29b-41d4-a716-446655440000"))
+
+	// Version 1 and nil UUIDs are rejected
+	assert.False(t, utils.IsValidUUID("11111111-1111-1111-1111-111111111111"))
+	assert.False(t, utils.IsValidUUID("00000000-0000-0000-0000-000000000000"))
+	// Non-RFC 4122 variant
+	assert.False(t, utils.IsValidUUID("550e8400-e29b-41d4-c716-446655440000"))
+	// Non-canonical forms accepted by uuid.Parse
+	assert.False(t, utils.IsValidUUID("{550e8400-e29b-41d4-a716-446655440000}"))
+	assert.False(t, utils.IsValidUUID("urn:uuid:550e8400-e29b-41d4-a716-446655440000"))
+	assert.False(t, utils.IsValidUUID("550e8400e29b41d4a716446655440000"))
+}
+
+// Swapping group and user UUIDs in the refactored call sites no longer compiles:
+// a GroupUUID is not assignable to a UserUUID parameter and vice versa.
+func TestUUID_TypedParametersRejectTransposition(t *testing.T) {
+	groupType := reflect.TypeOf(models.GroupUUID(""))
+	userType := reflect.TypeOf(models.UserUUID(""))
+
+	assert.False(t, groupType.AssignableTo(userType))
+	assert.False(t, userType.AssignableTo(groupType))
+	assert.False(t, reflect.TypeOf("").AssignableTo(groupType))
+
+	getUserBalance, ok := reflect.TypeOf((*service.BalanceService)(nil)).Elem().MethodByName("GetUserBalance")
+	assert.True(t, ok)
+	assert.Equal(t, groupType, getUserBalance.Type.In(1))
+	assert.Equal(t, userType, getUserBalance.Type.In(2))
+
+	removeMember, ok := reflect.TypeOf((*service.GroupService)(nil)).Elem().MethodByName("RemoveMember")
+	assert.True(t, ok)
+	assert.Equal(t, groupType, removeMember.Type.In(1))
+	assert.Equal(t, userType, removeMember.Type.In(2))
+
+	request := reflect.TypeOf(models.CreateSettlementRequest{})
+	from, _ := request.FieldByName("FromUserUUID")
+	to, _ := request.FieldByName("ToUserUUID")
+	group, _ := request.FieldByName("GroupUUID")
+	assert.Equal(t, userType, from.Type)
+	assert.Equal(t, userType, to.Type)
+	assert.Equal(t, groupType, group.Type)
+}
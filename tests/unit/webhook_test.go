@@ -0,0 +1,428 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"expense-split-tracker/internal/events"
+	"expense-split-tracker/internal/models"
+	"expense-split-tracker/internal/outbox"
+	"expense-split-tracker/internal/webhook"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+const webhookTestSecret = "0123456789abcdef-secret"
+
+// staticWebhookRepository serves a fixed set of webhooks for every group and
+// keeps the last recorded state of every delivery and each attempt
+type staticWebhookRepository struct {
+	webhooks []*models.Webhook
+
+	mu         sync.Mutex
+	deliveries []models.WebhookDelivery
+	attempts   []models.WebhookDeliveryAttempt
+	updated    chan models.WebhookDelivery
+}
+
+func (r *staticWebhookRepository) GetActiveGroupWebhooks(ctx context.Context, groupID int64) ([]*models.Webhook, error) {
+	return r.webhooks, nil
+}
+
+func (r *staticWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, *delivery)
+	delivery.ID = int64(len(r.deliveries))
+	r.deliveries[delivery.ID-1].ID = delivery.ID
+	return nil
+}
+
+func (r *staticWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	r.deliveries[delivery.ID-1] = *delivery
+	r.mu.Unlock()
+	if r.updated != nil {
+		r.updated <- *delivery
+	}
+	return nil
+}
+
+func (r *staticWebhookRepository) CreateDeliveryAttempt(ctx context.Context, attempt *models.WebhookDeliveryAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, *attempt)
+	return nil
+}
+
+// attemptsOf returns the recorded attempts of a delivery in order
+func (r *staticWebhookRepository) attemptsOf(deliveryID int64) []models.WebhookDeliveryAttempt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var attempts []models.WebhookDeliveryAttempt
+	for _, attempt := range r.attempts {
+		if attempt.DeliveryID == deliveryID {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts
+}
+
+func newStaticWebhookRepository(webhooks ...*models.Webhook) *staticWebhookRepository {
+	return &staticWebhookRepository{webhooks: webhooks, updated: make(chan models.WebhookDelivery, 64)}
+}
+
+// webhookReceiver records deliveries and fails the first failures of them
+type webhookReceiver struct {
+	mu       sync.Mutex
+	failures int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func newWebhookReceiver(failures int) *webhookReceiver {
+	return &webhookReceiver{failures: failures}
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header.Clone())
+	fail := len(r.bodies) <= r.failures
+	r.mu.Unlock()
+
+	if fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (r *webhookReceiver) attempts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.bodies)
+}
+
+func testWebhook(url string, eventTypes ...models.WebhookEventType) *models.Webhook {
+	return testWebhookWithID(7, url, eventTypes...)
+}
+
+func testWebhookWithID(id int64, url string, eventTypes ...models.WebhookEventType) *models.Webhook {
+	return &models.Webhook{
+		ID:         id,
+		UUID:       "77777777-7777-4777-8777-777777777777",
+		GroupID:    10,
+		URL:        url,
+		Secret:     webhookTestSecret,
+		EventTypes: eventTypes,
+		Active:     true,
+		Group:      &models.Group{ID: 10, UUID: "11111111-1111-4111-8111-111111111111", Name: "Trip"},
+	}
+}
+
+// runDispatcher runs dispatcher until the test ends or the returned stop is called
+func runDispatcher(t *testing.T, dispatcher *webhook.Dispatcher) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+// waitForStatus returns the first recorded delivery state with status,
+// skipping the updates before it
+func waitForStatus(t *testing.T, repo *staticWebhookRepository, status models.WebhookDeliveryStatus) models.WebhookDelivery {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case delivery := <-repo.updated:
+			if delivery.Status == status {
+				return delivery
+			}
+		case <-timeout:
+			t.Fatalf("no delivery became %s", status)
+			return models.WebhookDelivery{}
+		}
+	}
+}
+
+func consumeEvent(t *testing.T, dispatcher *webhook.Dispatcher, eventType models.WebhookEventType, payload string) {
+	t.Helper()
+	event := &models.OutboxEvent{ID: 1, GroupID: 10, EventType: string(eventType), Payload: json.RawMessage(payload)}
+	require.NoError(t, dispatcher.Consume(context.Background(), event))
+}
+
+func TestWebhookDispatcher_SignsDeliveries(t *testing.T) {
+	receiver := newWebhookReceiver(0)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	repo := newStaticWebhookRepository(testWebhook(server.URL, models.WebhookEventExpenseCreated))
+	dispatcher := webhook.NewDispatcher(repo, webhook.Options{MaxAttempts: 1}, zaptest.NewLogger(t))
+	runDispatcher(t, dispatcher)
+
+	consumeEvent(t, dispatcher, models.WebhookEventExpenseCreated, `{"amount":"90"}`)
+	delivery := waitForStatus(t, repo, models.WebhookDeliveryDelivered)
+	require.Equal(t, 1, receiver.attempts())
+
+	body := receiver.bodies[0]
+	assert.Equal(t, webhook.Sign(webhookTestSecret, body), receiver.headers[0].Get(webhook.SignatureHeader))
+	assert.NotEqual(t, webhook.Sign("another-secret-value", body), receiver.headers[0].Get(webhook.SignatureHeader))
+	assert.Equal(t, "expense.created", receiver.headers[0].Get(webhook.EventHeader))
+	assert.Equal(t, delivery.UUID, receiver.headers[0].Get(webhook.DeliveryHeader))
+}
+
+func TestWebhookDispatcher_RetriesUntilSuccess(t *testing.T) {
+	receiver := newWebhookReceiver(2)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	repo := newStaticWebhookRepository(testWebhook(server.URL, models.WebhookEventSettlementCreated))
+	dispatcher := webhook.NewDispatcher(repo, webhook.Options{MaxAttempts: 5, Backoff: time.Millisecond}, zaptest.NewLogger(t))
+	runDispatcher(t, dispatcher)
+
+	consumeEvent(t, dispatcher, models.WebhookEventSettlementCreated, `{}`)
+	delivery := waitForStatus(t, repo, models.WebhookDeliveryDelivered)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Nil(t, delivery.LastError)
+	assert.Equal(t, 3, receiver.attempts())
+
+	// Every attempt carries the same body and signature
+	for i := 1; i < len(receiver.bodies); i++ {
+		assert.Equal(t, receiver.bodies[0], receiver.bodies[i])
+		assert.Equal(t, receiver.headers[0].Get(webhook.SignatureHeader), receiver.headers[i].Get(webhook.SignatureHeader))
+	}
+
+	// Each attempt is recorded with its response and latency
+	attempts := repo.attemptsOf(delivery.ID)
+	require.Len(t, attempts, 3)
+	for i, attempt := range attempts {
+		assert.Equal(t, i+1, attempt.Attempt)
+		assert.GreaterOrEqual(t, attempt.LatencyMS, int64(0))
+		require.NotNil(t, attempt.StatusCode)
+	}
+	assert.Equal(t, models.WebhookAttemptFailed, attempts[0].Outcome)
+	assert.Equal(t, http.StatusServiceUnavailable, *attempts[1].StatusCode)
+	require.NotNil(t, attempts[1].Error)
+	assert.Contains(t, *attempts[1].Error, "503")
+	assert.Equal(t, models.WebhookAttemptDelivered, attempts[2].Outcome)
+	assert.Equal(t, http.StatusNoContent, *attempts[2].StatusCode)
+	assert.Nil(t, attempts[2].Error)
+}
+
+func TestWebhookDispatcher_QueuesCommittedEventsForSubscribedWebhooks(t *testing.T) {
+	subscribed := newWebhookReceiver(0)
+	subscribedServer := httptest.NewServer(subscribed)
+	defer subscribedServer.Close()
+
+	other := newWebhookReceiver(0)
+	otherServer := httptest.NewServer(other)
+	defer otherServer.Close()
+
+	repo := newStaticWebhookRepository(
+		testWebhookWithID(7, subscribedServer.URL, models.WebhookEventExpenseCreated),
+		testWebhookWithID(8, otherServer.URL, models.WebhookEventSettlementCreated),
+	)
+	dispatcher := webhook.NewDispatcher(repo, webhook.Options{MaxAttempts: 1}, zaptest.NewLogger(t))
+	runDispatcher(t, dispatcher)
+
+	expense := &models.Expense{ID: 1, UUID: "eeeeeeee-eeee-4eee-8eee-eeeeeeeeeeee", GroupID: 10, Amount: decimal.NewFromInt(90), Currency: "USD", Description: "Dinner"}
+	splits := []*models.ExpenseSplit{{ExpenseID: 1, UserID: 1, Amount: decimal.NewFromInt(45)}, {ExpenseID: 1, UserID: 2, Amount: decimal.NewFromInt(45)}}
+
+	// Events webhooks cannot subscribe to are ignored
+	for _, event := range []events.Event{events.MemberAdded{GroupID: 10, UserID: 3}, events.ExpenseCreated{Expense: expense, Splits: splits}} {
+		row, _, err := outbox.Encode(event)
+		require.NoError(t, err)
+		assert.NoError(t, dispatcher.Consume(context.Background(), row))
+	}
+
+	waitForStatus(t, repo, models.WebhookDeliveryDelivered)
+
+	var payload struct {
+		Event string              `json:"event"`
+		Group models.WebhookGroup `json:"group"`
+		Data  models.Expense      `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(subscribed.bodies[0], &payload))
+	assert.Equal(t, "expense.created", payload.Event)
+	assert.Equal(t, "Trip", payload.Group.Name)
+	assert.Equal(t, expense.UUID, payload.Data.UUID)
+	assert.Len(t, payload.Data.Splits, 2)
+	assert.Equal(t, 0, other.attempts())
+}
+
+func TestWebhookDispatcher_DeadLettersAfterFinalRetry(t *testing.T) {
+	receiver := newWebhookReceiver(10)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	repo := newStaticWebhookRepository(testWebhook(server.URL, models.WebhookEventSettlementCreated))
+	dispatcher := webhook.NewDispatcher(repo, webhook.Options{MaxAttempts: 3, Backoff: time.Millisecond}, zaptest.NewLogger(t))
+	runDispatcher(t, dispatcher)
+
+	consumeEvent(t, dispatcher, models.WebhookEventSettlementCreated, `{"amount":"30"}`)
+
+	delivery := waitForStatus(t, repo, models.WebhookDeliveryDeadLettered)
+	assert.Equal(t, 3, delivery.Attempts)
+	require.NotNil(t, delivery.LastError)
+	assert.Contains(t, *delivery.LastError, "503")
+	assert.Equal(t, 3, receiver.attempts())
+
+	attempts := repo.attemptsOf(delivery.ID)
+	require.Len(t, attempts, 3)
+	for _, attempt := range attempts {
+		assert.Equal(t, models.WebhookAttemptFailed, attempt.Outcome)
+	}
+
+	// The recorded payload is the body that was posted
+	assert.JSONEq(t, string(receiver.bodies[0]), string(delivery.Payload))
+	assert.Equal(t, delivery.UUID, receiver.headers[0].Get(webhook.DeliveryHeader))
+}
+
+func TestWebhookDispatcher_FailingWebhookDoesNotHoldUpOthers(t *testing.T) {
+	failing := newWebhookReceiver(100)
+	failingServer := httptest.NewServer(failing)
+	defer failingServer.Close()
+
+	healthy := newWebhookReceiver(0)
+	healthyServer := httptest.NewServer(healthy)
+	defer healthyServer.Close()
+
+	// A single worker and a retry far in the future: the failing webhook
+	// waits for its retry without keeping the worker
+	repo := newStaticWebhookRepository(
+		testWebhookWithID(7, failingServer.URL, models.WebhookEventExpenseCreated),
+		testWebhookWithID(8, healthyServer.URL, models.WebhookEventExpenseCreated),
+	)
+	dispatcher := webhook.NewDispatcher(repo, webhook.Options{MaxAttempts: 3, Backoff: time.Hour, Workers: 1}, zaptest.NewLogger(t))
+	runDispatcher(t, dispatcher)
+
+	consumeEvent(t, dispatcher, models.WebhookEventExpenseCreated, `{}`)
+	consumeEvent(t, dispatcher, models.WebhookEventExpenseCreated, `{}`)
+
+	for i := 0; i < 2; i++ {
+		delivered := waitForStatus(t, repo, models.WebhookDeliveryDelivered)
+		assert.Equal(t, int64(8), delivered.WebhookID)
+	}
+	assert.Equal(t, 2, healthy.attempts())
+
+	// The failing webhook tried its first delivery once and is waiting to
+	// retry it before sending the second
+	assert.Equal(t, 1, failing.attempts())
+}
+
+func TestWebhookDispatcher_DeliversToEachWebhookInOrder(t *testing.T) {
+	receiver := newWebhookReceiver(1)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	repo := newStaticWebhookRepository(testWebhook(server.URL, models.WebhookEventExpenseCreated))
+	dispatcher := webhook.NewDispatcher(repo, webhook.Options{MaxAttempts: 3, Backoff: 20 * time.Millisecond, Workers: 4}, zaptest.NewLogger(t))
+	runDispatcher(t, dispatcher)
+
+	for _, n := range []string{"1", "2", "3"} {
+		consumeEvent(t, dispatcher, models.WebhookEventExpenseCreated, `{"n":`+n+`}`)
+	}
+	for i := 0; i < 3; i++ {
+		waitForStatus(t, repo, models.WebhookDeliveryDelivered)
+	}
+
+	// The first delivery failed once; the others waited for its retry
+	var order []int
+	for _, body := range receiver.bodies {
+		var payload struct {
+			Data struct {
+				N int `json:"n"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &payload))
+		order = append(order, payload.Data.N)
+	}
+	assert.Equal(t, []int{1, 1, 2, 3}, order)
+}
+
+func TestWebhookDispatcher_StopDeadLettersWaitingDeliveries(t *testing.T) {
+	receiver := newWebhookReceiver(100)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	repo := newStaticWebhookRepository(testWebhook(server.URL, models.WebhookEventExpenseDeleted))
+	dispatcher := webhook.NewDispatcher(repo, webhook.Options{MaxAttempts: 3, Backoff: time.Hour}, zaptest.NewLogger(t))
+	stop := runDispatcher(t, dispatcher)
+
+	consumeEvent(t, dispatcher, models.WebhookEventExpenseDeleted, `{}`)
+	waiting := waitForStatus(t, repo, models.WebhookDeliveryPending)
+	assert.Equal(t, 1, waiting.Attempts)
+
+	stop()
+	deadLettered := waitForStatus(t, repo, models.WebhookDeliveryDeadLettered)
+	assert.Equal(t, waiting.UUID, deadLettered.UUID)
+	require.NotNil(t, deadLettered.LastError)
+	assert.Contains(t, *deadLettered.LastError, "stopped")
+}
+
+func TestWebhookDispatcher_RedeliverSendsOriginalBody(t *testing.T) {
+	receiver := newWebhookReceiver(2)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	hook := testWebhook(server.URL, models.WebhookEventExpenseDeleted)
+	repo := newStaticWebhookRepository(hook)
+	dispatcher := webhook.NewDispatcher(repo, webhook.Options{MaxAttempts: 2, Backoff: time.Millisecond}, zaptest.NewLogger(t))
+	runDispatcher(t, dispatcher)
+
+	consumeEvent(t, dispatcher, models.WebhookEventExpenseDeleted, `{}`)
+	deadLettered := waitForStatus(t, repo, models.WebhookDeliveryDeadLettered)
+
+	require.NoError(t, dispatcher.Redeliver(context.Background(), hook, &deadLettered))
+	assert.Equal(t, models.WebhookDeliveryPending, waitForStatus(t, repo, models.WebhookDeliveryPending).Status)
+
+	delivered := waitForStatus(t, repo, models.WebhookDeliveryDelivered)
+	assert.Equal(t, 3, delivered.Attempts)
+	assert.Nil(t, delivered.LastError)
+	assert.Len(t, repo.attemptsOf(delivered.ID), 3)
+
+	require.Equal(t, 3, receiver.attempts())
+	assert.Equal(t, receiver.bodies[0], receiver.bodies[2])
+	assert.Equal(t, receiver.headers[0].Get(webhook.DeliveryHeader), receiver.headers[2].Get(webhook.DeliveryHeader))
+}
+
+func TestWebhookDispatcher_ConsumeFailsWhenQueueFull(t *testing.T) {
+	dispatcher := webhook.NewDispatcher(&staticWebhookRepository{}, webhook.Options{QueueSize: 1}, zaptest.NewLogger(t))
+	event := &models.OutboxEvent{ID: 1, GroupID: 10, EventType: "settlement.created", Payload: json.RawMessage(`{}`)}
+
+	assert.NoError(t, dispatcher.Consume(context.Background(), event))
+	// The relay keeps the event pending and offers it again later
+	assert.Error(t, dispatcher.Consume(context.Background(), event))
+}
+
+func TestWebhook_SubscribesOnlyWhenActive(t *testing.T) {
+	hook := testWebhook("https://example.com/hook", models.WebhookEventExpenseCreated)
+	assert.True(t, hook.Subscribes(models.WebhookEventExpenseCreated))
+	assert.False(t, hook.Subscribes(models.WebhookEventExpenseDeleted))
+
+	hook.Active = false
+	assert.False(t, hook.Subscribes(models.WebhookEventExpenseCreated))
+
+	assert.Error(t, models.WebhookEventType("group.created").Validate())
+}